@@ -17,11 +17,25 @@ func main() {
 
 	switch command {
 	case "dev":
-		cli.RunDev()
+		cli.RunDev(os.Args[2:])
 	case "build":
-		cli.RunBuild()
+		cli.RunBuild(os.Args[2:])
 	case "start":
-		cli.RunStart()
+		cli.RunStart(os.Args[2:])
+	case "config":
+		cli.RunConfig(os.Args[2:])
+	case "test":
+		cli.RunTest(os.Args[2:])
+	case "doctor":
+		cli.RunDoctor(os.Args[2:])
+	case "analyze":
+		cli.RunAnalyze(os.Args[2:])
+	case "generate":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: golem generate functions")
+			os.Exit(1)
+		}
+		cli.RunGenerate(os.Args[2])
 	case "new":
 		if len(os.Args) < 3 {
 			fmt.Println("Usage: golem new <project-name>")
@@ -49,6 +63,11 @@ Commands:
   dev      Start development server with hot reload
   build    Build production-ready application  
   start    Start production server
+  config   Print, validate or describe the configuration
+  test     Run the Go tests and the js/wasm tests in Node or a browser
+  doctor   Check Go, its js/wasm support, optional tools, ports and config
+  analyze  Report the size of the build and how it changed since the last one
+  generate Generate code, e.g. "golem generate functions"
   new      Create new Golem project
   version  Show version information
   help     Show this help message
@@ -56,6 +75,19 @@ Commands:
 Examples:
   golem new my-app
   golem dev
+  golem dev --port 8080 --open
   golem build
-  golem start`)
+  golem build --static
+  golem build --docker-build
+  golem generate functions
+  golem test
+  golem test --runner browser ./dom -run TestRender
+  golem doctor
+  golem analyze
+  golem analyze --max-growth 5
+  golem start
+  golem start --env staging
+  golem config --env production
+  golem config validate
+  golem config schema`)
 }
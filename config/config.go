@@ -0,0 +1,35 @@
+// Package config gives build hooks and other tools their settings in the
+// project's golem.config.json, which they keep under plugins.<name>:
+//
+//	"plugins": {
+//	  "i18n": { "locales": ["en", "fr"], "fallback": "en" }
+//	}
+//
+// The tool decodes its section into a struct of its own:
+//
+//	var settings struct {
+//		Locales  []string `json:"locales"`
+//		Fallback string   `json:"fallback"`
+//	}
+//	if err := config.DecodeSection("i18n", &settings); err != nil {
+//		log.Fatal(err)
+//	}
+package config
+
+import (
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// DecodeSection decodes the plugins.<name> section of the configuration of
+// the project in the working directory into target, a pointer to a struct
+// of the plugin's settings. The file of the environment named by GOLEM_ENV,
+// which golem sets for build hooks, is layered over the configuration. Keys
+// target has no field for and values of the wrong type are errors; target
+// is left as is when the project has no such section.
+func DecodeSection(name string, target interface{}) error {
+	cfg, err := config.LoadProject(".", config.Environment("", ""))
+	if err != nil {
+		return err
+	}
+	return cfg.DecodeSection(name, target)
+}
@@ -507,3 +507,32 @@ func InjectStyles(css string) {
 	styleElement.Set("innerHTML", css)
 	head.Call("appendChild", styleElement)
 }
+
+// injected holds the ids of the stylesheets InjectOnce added
+var injected = make(map[string]bool)
+
+// InjectOnce adds a stylesheet to the document the first time it is called
+// with id, e.g. the styles of a .golem component. It adopts a constructed
+// stylesheet where supported, which a Content-Security-Policy without
+// 'unsafe-inline' allows, and appends a <style> element otherwise.
+func InjectOnce(id, css string) {
+	if injected[id] {
+		return
+	}
+	injected[id] = true
+
+	doc := js.Global().Get("document")
+	if constructor := js.Global().Get("CSSStyleSheet"); constructor.Truthy() && doc.Get("adoptedStyleSheets").Truthy() {
+		sheet := constructor.New()
+		sheet.Call("replaceSync", css)
+		adopted := js.Global().Get("Array").Call("from", doc.Get("adoptedStyleSheets"))
+		adopted.Call("push", sheet)
+		doc.Set("adoptedStyleSheets", adopted)
+		return
+	}
+
+	styleElement := doc.Call("createElement", "style")
+	styleElement.Set("id", id)
+	styleElement.Set("textContent", css)
+	doc.Get("head").Call("appendChild", styleElement)
+}
@@ -271,3 +271,7 @@ func GenerateClassName(prefix string) string {
 func InjectStyles(css string) {
 	fmt.Printf("CSS injection only available in WebAssembly build: %s\n", css)
 }
+
+// InjectOnce does nothing outside the browser, where components rendered
+// to HTML at build time still call it
+func InjectOnce(id, css string) {}
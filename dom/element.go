@@ -47,15 +47,14 @@ func NewElement(tagType string, args ...interface{}) *Element {
 			}
 		case *Element:
 			children = append(children, v)
+		case []*Element:
+			children = append(children, v...)
 		case string:
-			// Text content
-			textElement := &Element{
-				Type:          "text",
-				Props:         map[string]interface{}{"textContent": v},
-				Children:      make([]*Element, 0),
-				EventHandlers: make(map[string]js.Func),
-			}
-			children = append(children, textElement)
+			children = append(children, newText(v))
+		case nil:
+		default:
+			// Other values, e.g. numbers in .golem templates, render as text
+			children = append(children, newText(fmt.Sprint(v)))
 		}
 	}
 
@@ -101,6 +100,16 @@ func createEventHandler(event EventAttribute) (js.Func, bool) {
 	return js.Func{}, false
 }
 
+// newText creates a text node
+func newText(text string) *Element {
+	return &Element{
+		Type:          "text",
+		Props:         map[string]interface{}{"textContent": text},
+		Children:      make([]*Element, 0),
+		EventHandlers: make(map[string]js.Func),
+	}
+}
+
 // AddChild adds a child element
 func (e *Element) AddChild(child *Element) {
 	e.Children = append(e.Children, child)
@@ -37,15 +37,14 @@ func NewElement(tagType string, args ...interface{}) *Element {
 			}
 		case *Element:
 			children = append(children, v)
+		case []*Element:
+			children = append(children, v...)
 		case string:
-			// Text content
-			textElement := &Element{
-				Type:          "text",
-				Props:         map[string]interface{}{"textContent": v},
-				Children:      make([]*Element, 0),
-				EventHandlers: make(map[string]func()),
-			}
-			children = append(children, textElement)
+			children = append(children, newText(v))
+		case nil:
+		default:
+			// Other values, e.g. numbers in .golem templates, render as text
+			children = append(children, newText(fmt.Sprint(v)))
 		}
 	}
 
@@ -57,6 +56,16 @@ func NewElement(tagType string, args ...interface{}) *Element {
 	}
 }
 
+// newText creates a text node
+func newText(text string) *Element {
+	return &Element{
+		Type:          "text",
+		Props:         map[string]interface{}{"textContent": text},
+		Children:      make([]*Element, 0),
+		EventHandlers: make(map[string]func()),
+	}
+}
+
 // AddChild adds a child element
 func (e *Element) AddChild(child *Element) {
 	e.Children = append(e.Children, child)
@@ -85,6 +94,10 @@ func Text(text interface{}) Attribute {
 	return Attribute{Name: "textContent", Value: fmt.Sprintf("%v", text)}
 }
 
+func On(event string, handler interface{}) Attribute {
+	return Attribute{Name: "on" + event, Value: handler}
+}
+
 func OnClick(handler func()) Attribute {
 	return Attribute{Name: "onclick", Value: handler}
 }
@@ -0,0 +1,69 @@
+package dom
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// voidElements have no closing tag and no children
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// RenderHTML renders an element tree to HTML markup, e.g. to prerender a
+// page at build time. Event handlers are left out; the app attaches them
+// when it renders over the markup in the browser.
+func RenderHTML(element *Element) string {
+	var out strings.Builder
+	writeHTML(&out, element)
+	return out.String()
+}
+
+func writeHTML(out *strings.Builder, e *Element) {
+	if e == nil {
+		return
+	}
+	if e.Type == "text" {
+		out.WriteString(html.EscapeString(fmt.Sprint(e.Props["textContent"])))
+		return
+	}
+
+	out.WriteString("<" + e.Type)
+	names := make([]string, 0, len(e.Props))
+	for name := range e.Props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := e.Props[name]
+		switch v := value.(type) {
+		case bool:
+			if v {
+				out.WriteString(" " + name)
+			}
+			continue
+		case nil:
+			continue
+		}
+		if name == "textContent" || reflect.ValueOf(value).Kind() == reflect.Func {
+			continue
+		}
+		fmt.Fprintf(out, ` %s="%s"`, name, html.EscapeString(fmt.Sprint(value)))
+	}
+	out.WriteString(">")
+
+	if voidElements[e.Type] {
+		return
+	}
+	if text, ok := e.Props["textContent"]; ok {
+		out.WriteString(html.EscapeString(fmt.Sprint(text)))
+	}
+	for _, child := range e.Children {
+		writeHTML(out, child)
+	}
+	out.WriteString("</" + e.Type + ">")
+}
@@ -0,0 +1,70 @@
+// Package host runs the server binary of a golem app. Only the main package
+// "golem generate functions" writes imports it, so server functions
+// importing github.com/Nu11ified/golem/functions do not link in the servers.
+package host
+
+import (
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/dev"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// Main runs the server binary generated by "golem generate functions" with
+// the functions its packages registered: a function worker for the dev
+// server when started with the "worker" argument, the production server
+// otherwise
+func Main() {
+	cfg, err := loadConfig()
+	if err == nil {
+		err = cfg.RequireSecrets()
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	functions.SetSecrets(cfg)
+
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		if err := dev.RunWorker(cfg); err != nil {
+			log.Fatalf("Function worker failed: %v", err)
+		}
+		return
+	}
+
+	prodServer := server.NewServer(cfg)
+	if embedded.assets != nil {
+		prodServer.SetAssets(embedded.assets)
+	}
+	if err := prodServer.Run(); err != nil {
+		log.Fatalf("Production server failed: %v", err)
+	}
+}
+
+// embedded holds the build output and configuration compiled into single
+// binaries
+var embedded struct {
+	assets fs.FS
+	config []byte
+}
+
+// Embed makes Main serve the build output from assets and use config, the
+// configuration of the build, instead of the files next to the binary. The
+// main package generated by golem build --single-binary calls it.
+func Embed(assets fs.FS, config []byte) {
+	embedded.assets = assets
+	embedded.config = config
+}
+
+// loadConfig returns the embedded configuration, or the project's
+// golem.config.json, .yaml or .toml with the file of the environment named
+// by GOLEM_ENV, production by default, layered over it
+func loadConfig() (*config.Config, error) {
+	if embedded.config != nil {
+		return config.Parse(embedded.config)
+	}
+	return config.LoadProject(".", config.Environment("", "production"))
+}
@@ -1,12 +1,241 @@
 package functions
 
 import (
+	"context"
+	"log/slog"
+	"time"
+
 	"github.com/Nu11ified/golem/internal/functions"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Register allows user packages to register their functions with the framework
-func Register(serviceName, functionName string, fn interface{}) error {
-	return functions.RegisterGlobalFunction(serviceName, functionName, fn)
+func Register(serviceName, functionName string, fn interface{}, opts ...RegisterOption) error {
+	return functions.RegisterGlobalFunction(serviceName, functionName, fn, opts...)
+}
+
+// RegisterOption configures a function at registration
+type RegisterOption = functions.RegisterOption
+
+// Identity is the authenticated caller of a function
+type Identity = functions.Identity
+
+// IdentityResolver derives the caller's identity from call metadata
+type IdentityResolver = functions.IdentityResolver
+
+// WithDescription sets the documentation shown for a function
+func WithDescription(description string) RegisterOption {
+	return functions.WithDescription(description)
+}
+
+// RequireAuthenticated only allows authenticated callers
+func RequireAuthenticated() RegisterOption {
+	return functions.RequireAuthenticated()
+}
+
+// RequireRoles only allows callers having at least one of roles
+func RequireRoles(roles ...string) RegisterOption {
+	return functions.RequireRoles(roles...)
+}
+
+// RequirePermissions only allows callers having all of permissions
+func RequirePermissions(permissions ...string) RegisterOption {
+	return functions.RequirePermissions(permissions...)
+}
+
+// WithTimeout limits how long one call of the function may run
+func WithTimeout(timeout time.Duration) RegisterOption {
+	return functions.WithTimeout(timeout)
+}
+
+// WithMaxConcurrency limits how many calls of the function may run at once
+func WithMaxConcurrency(n int) RegisterOption {
+	return functions.WithMaxConcurrency(n)
+}
+
+// WithCache caches the results of a pure function for ttl, keyed by its
+// arguments; the caller's identity is not part of the key
+func WithCache(ttl time.Duration) RegisterOption {
+	return functions.WithCache(ttl)
+}
+
+// InvalidateCache removes the cached result of a call with the given args
+func InvalidateCache(serviceName, functionName string, args ...interface{}) error {
+	return functions.GetGlobalRegistry().InvalidateCache(serviceName, functionName, args...)
+}
+
+// InvalidateFunctionCache removes every cached result of a function
+func InvalidateFunctionCache(serviceName, functionName string) {
+	functions.GetGlobalRegistry().InvalidateFunctionCache(serviceName, functionName)
+}
+
+// SetIdentityResolver sets how callers of protected functions are identified
+func SetIdentityResolver(resolver IdentityResolver) {
+	functions.SetGlobalIdentityResolver(resolver)
+}
+
+// WithIdentity returns a context carrying the caller's identity, e.g. from
+// an interceptor that validates a token
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return functions.WithIdentity(ctx, identity)
+}
+
+// GetIdentity returns the caller's identity, if authenticated
+func GetIdentity(ctx context.Context) (*Identity, bool) {
+	return functions.IdentityFromContext(ctx)
+}
+
+// CallInfo describes a function call passing through interceptors
+type CallInfo = functions.CallInfo
+
+// Handler executes a function call
+type Handler = functions.Handler
+
+// Interceptor wraps every server function call
+type Interceptor = functions.Interceptor
+
+// Use adds interceptors that wrap every call of the registered functions,
+// e.g. for logging, authentication or metrics
+func Use(interceptors ...Interceptor) {
+	functions.UseGlobalInterceptor(interceptors...)
+}
+
+// HealthCheck reports whether a dependency, such as a database, is usable
+type HealthCheck = functions.HealthCheck
+
+// AddHealthCheck registers a named check the production server runs for
+// /readyz, e.g. pinging the database; the server reports not ready while a
+// check fails
+func AddHealthCheck(name string, check HealthCheck) {
+	functions.AddGlobalHealthCheck(name, check)
+}
+
+// ErrNotStreaming is returned by Emit outside of a streaming call
+var ErrNotStreaming = functions.ErrNotStreaming
+
+// Emit pushes a partial result to the client when the function was invoked
+// with a streaming call; otherwise it returns ErrNotStreaming
+func Emit(ctx context.Context, value interface{}) error {
+	return functions.Emit(ctx, value)
+}
+
+// Error is a structured error with a code, details and a retryable flag that
+// the client reconstructs as a typed error
+type Error = functions.Error
+
+// Error codes understood by the client
+const (
+	CodeUnknown            = functions.CodeUnknown
+	CodeInvalidArgument    = functions.CodeInvalidArgument
+	CodeNotFound           = functions.CodeNotFound
+	CodeAlreadyExists      = functions.CodeAlreadyExists
+	CodePermissionDenied   = functions.CodePermissionDenied
+	CodeUnauthenticated    = functions.CodeUnauthenticated
+	CodeResourceExhausted  = functions.CodeResourceExhausted
+	CodeFailedPrecondition = functions.CodeFailedPrecondition
+	CodeUnavailable        = functions.CodeUnavailable
+	CodeDeadlineExceeded   = functions.CodeDeadlineExceeded
+	CodeCanceled           = functions.CodeCanceled
+	CodeInternal           = functions.CodeInternal
+)
+
+// NewError creates a structured error to return from a server function
+func NewError(code, message string) *Error {
+	return functions.NewError(code, message)
+}
+
+// Errorf creates a structured error with a formatted message
+func Errorf(code, format string, args ...interface{}) *Error {
+	return functions.Errorf(code, format, args...)
+}
+
+// Metadata is the request metadata (headers, cookies, auth) of a call
+type Metadata = functions.Metadata
+
+// GetMetadata returns the metadata of the call a server function is serving;
+// the function must accept a context.Context as its first parameter
+func GetMetadata(ctx context.Context) *Metadata {
+	return functions.MetadataFromContext(ctx)
+}
+
+// Request describes the HTTP or gRPC request a call arrived with
+type Request = functions.Request
+
+// GetRequest returns the transport request of the call a server function is
+// serving: remote address, user agent, headers and so on
+func GetRequest(ctx context.Context) (*Request, bool) {
+	return functions.RequestFromContext(ctx)
+}
+
+// GetRequestID returns the ID of the request a server function is serving,
+// the X-Request-ID the client sent or one the server generated
+func GetRequestID(ctx context.Context) string {
+	return functions.RequestIDFromContext(ctx)
+}
+
+// GetLogger returns the server's structured logger, adding the request ID
+// and trace ID of ctx to every record so a function's logs join those of
+// its request
+func GetLogger(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := functions.RequestIDFromContext(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		logger = logger.With("trace_id", span.TraceID().String())
+	}
+	return logger
+}
+
+// Session holds the values the server keeps for one browser across calls,
+// such as the signed in user
+type Session = functions.Session
+
+// SessionStore keeps the data of sessions by ID
+type SessionStore = functions.SessionStore
+
+// RedisClient is the part of a Redis client a Redis session store needs
+type RedisClient = functions.RedisClient
+
+// ErrNoSession is returned by SetSession in calls without a session
+var ErrNoSession = functions.ErrNoSession
+
+// GetSession returns the session of the browser a server function is
+// serving. Calls over HTTP have one, which calls over the WebSocket or
+// after the first Emit can only read; native gRPC calls have none.
+func GetSession(ctx context.Context) (*Session, bool) {
+	return functions.SessionFromContext(ctx)
+}
+
+// SetSession stores value under key in the session of the call, e.g. the
+// ID of the user who just signed in
+func SetSession(ctx context.Context, key string, value interface{}) error {
+	session, ok := functions.SessionFromContext(ctx)
+	if !ok {
+		return ErrNoSession
+	}
+	return session.Set(key, value)
+}
+
+// SetSessionStore keeps sessions in store instead of the store of the
+// session config, e.g. NewRedisSessionStore to share them between replicas
+func SetSessionStore(store SessionStore) {
+	functions.SetGlobalSessionStore(store)
+}
+
+// NewRedisSessionStore returns a store keeping sessions in Redis with client
+func NewRedisSessionStore(client RedisClient) SessionStore {
+	return functions.NewRedisSessionStore(client)
+}
+
+// Pusher sends messages to a client connected over the multiplexed WebSocket
+type Pusher = functions.Pusher
+
+// GetPusher returns the pusher of the connection the call arrived on, for
+// sending the client updates on the same socket. It is absent for calls
+// made over plain HTTP or gRPC.
+func GetPusher(ctx context.Context) (*Pusher, bool) {
+	return functions.PusherFromContext(ctx)
 }
 
 // GetRegistry returns the current function registry for use by the framework
@@ -19,3 +248,12 @@ func HasFunctions() bool {
 	registry := functions.GetGlobalRegistry()
 	return len(registry.ListFunctions("")) > 0
 }
+
+// Secret returns the secret name to server functions: the environment
+// variable of that name, or else its value in golem.secrets.env or the
+// secrets file of the server's environment. The app cannot read secrets,
+// and builds never include them. It returns "" for secrets that are not
+// set.
+func Secret(name string) string {
+	return functions.Secret(name)
+}
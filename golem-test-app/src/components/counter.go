@@ -14,6 +14,9 @@ func CounterComponent() *dom.Element {
 	// 1. Define reactive state for the counter
 	count := state.NewObservable(0)
 
+	// Keep the count across hot reloads during development
+	state.Preserve("counter", count)
+
 	// 2. Create a paragraph element that will contain the count text.
 	// We hold onto this element to update it directly.
 	pElement := dom.P(
@@ -12,11 +12,19 @@ import (
 	"github.com/Nu11ified/golem/state"
 )
 
+// UserProfile mirrors the result of the GetUserProfile server function
+type UserProfile struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
 type ServerDemoState struct {
 	Name          string
 	HelloResponse string
 	UserID        string
-	UserProfile   map[string]interface{}
+	UserProfile   *UserProfile
 	IsLoading     bool
 	ErrorMessage  string
 	LastCallTime  string
@@ -93,7 +101,7 @@ func ServerDemoComponent() *dom.Element {
 			}
 
 			fmt.Printf("📡 Making gRPC call to GetUserProfile with userID: %d\n", userID)
-			result, err := grpc.CallMap(ctx, "server", "GetUserProfile", userID)
+			result, err := grpc.CallTyped[UserProfile](ctx, "server", "GetUserProfile", userID)
 
 			updateState(func(s *ServerDemoState) {
 				s.IsLoading = false
@@ -104,7 +112,7 @@ func ServerDemoComponent() *dom.Element {
 					s.UserProfile = nil
 				} else {
 					fmt.Printf("✅ GetUserProfile response: %+v\n", result)
-					s.UserProfile = result
+					s.UserProfile = &result
 					s.ErrorMessage = ""
 				}
 			})
@@ -151,9 +159,9 @@ func ServerDemoComponent() *dom.Element {
 			userProfileDisplay = dom.Div(
 				dom.Class("user-profile"),
 				dom.H4(dom.Text("👤 User Profile:")),
-				dom.P(dom.Text(fmt.Sprintf("ID: %v", state.UserProfile["id"]))),
-				dom.P(dom.Text(fmt.Sprintf("Name: %v", state.UserProfile["name"]))),
-				dom.P(dom.Text(fmt.Sprintf("Email: %v", state.UserProfile["email"]))),
+				dom.P(dom.Text(fmt.Sprintf("ID: %d", state.UserProfile.ID))),
+				dom.P(dom.Text(fmt.Sprintf("Name: %s", state.UserProfile.Name))),
+				dom.P(dom.Text(fmt.Sprintf("Email: %s", state.UserProfile.Email))),
 			)
 		} else {
 			userProfileDisplay = dom.Div()
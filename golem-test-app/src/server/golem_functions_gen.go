@@ -0,0 +1,11 @@
+// Code generated by golem generate functions. DO NOT EDIT.
+
+package server
+
+import "github.com/Nu11ified/golem/functions"
+
+func init() {
+	functions.Register("server", "Calculate", Calculate, functions.WithDescription("Calculate performs basic math operations (for demo purposes)"))
+	functions.Register("server", "GetUserProfile", GetUserProfile, functions.WithDescription("GetUserProfile fetches user profile data"))
+	functions.Register("server", "Hello", Hello, functions.WithDescription("Hello is a server function that can be called from the client"))
+}
@@ -3,18 +3,8 @@ package server
 import (
 	"context"
 	"fmt"
-
-	"github.com/Nu11ified/golem/functions"
 )
 
-// Register all server functions during package initialization
-func init() {
-	// Register each function with the global registry
-	functions.Register("server", "Hello", Hello)
-	functions.Register("server", "GetUserProfile", GetUserProfile)
-	functions.Register("server", "Calculate", Calculate)
-}
-
 // Hello is a server function that can be called from the client
 func Hello(name string) string {
 	return fmt.Sprintf("Hello, %s! This message is from the Go server.", name)
@@ -0,0 +1,97 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// BatchCall is a single function invocation within a batch
+type BatchCall struct {
+	ServiceName  string        `json:"serviceName"`
+	FunctionName string        `json:"functionName"`
+	Args         []interface{} `json:"args"`
+}
+
+// BatchResult is the outcome of one call of a batch
+type BatchResult struct {
+	Data interface{}
+	Err  error
+}
+
+// NewBatchCall describes a call for use with Batch
+func NewBatchCall(serviceName, functionName string, args ...interface{}) BatchCall {
+	return BatchCall{ServiceName: serviceName, FunctionName: functionName, Args: args}
+}
+
+// Batch runs several calls in a single HTTP round trip, executed in order on
+// the server. Results are returned in the order of calls; the error is only
+// set if the batch as a whole failed.
+func (c *Client) Batch(ctx context.Context, calls ...BatchCall) ([]BatchResult, error) {
+	return c.batch(ctx, false, calls)
+}
+
+// BatchParallel is like Batch but lets the server run the calls concurrently
+func (c *Client) BatchParallel(ctx context.Context, calls ...BatchCall) ([]BatchResult, error) {
+	return c.batch(ctx, true, calls)
+}
+
+// batch sends the calls through the interceptor chain to /api/functions/batch
+func (c *Client) batch(ctx context.Context, parallel bool, calls []BatchCall) ([]BatchResult, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	info := c.newCallInfo(ctx)
+	info.Batch = calls
+
+	send := func(ctx context.Context, info *CallInfo) (interface{}, error) {
+		jsonData, err := json.Marshal(map[string]interface{}{
+			"calls":    info.Batch,
+			"parallel": parallel,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal batch: %w", err)
+		}
+		// Only an explicit key is sent: the server deduplicates each call of
+		// the batch separately, so a generated key would merge repeated calls
+		headers := info.Headers
+		if key := IdempotencyKey(ctx); key != "" {
+			headers = withHeader(headers, idempotencyKeyHeader, key)
+		}
+		return c.withRetry(ctx, func() (interface{}, error) {
+			return c.makeRequest(ctx, "/api/functions/batch", jsonData, headers)
+		})
+	}
+
+	raw, err := c.chain(0, send)(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, ok := raw.([]interface{})
+	if !ok || len(entries) != len(calls) {
+		return nil, fmt.Errorf("unexpected batch response: %T", raw)
+	}
+
+	results := make([]BatchResult, len(entries))
+	for i, entry := range entries {
+		fields, _ := entry.(map[string]interface{})
+		if success, _ := fields["success"].(bool); success {
+			results[i] = BatchResult{Data: fields["result"]}
+			continue
+		}
+		results[i] = BatchResult{Err: errorFromEnvelope(fields, 0)}
+	}
+	return results, nil
+}
+
+// Batch is a convenience function for batched calls with the default client
+func Batch(ctx context.Context, calls ...BatchCall) ([]BatchResult, error) {
+	if defaultClient == nil {
+		defaultClient = NewClient("")
+	}
+	return defaultClient.Batch(ctx, calls...)
+}
@@ -0,0 +1,255 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall/js"
+)
+
+// BidiStream is a persistent WebSocket connection to a bidirectional
+// streaming server function
+type BidiStream struct {
+	socket   js.Value
+	messages chan StreamMessage
+	funcs    []js.Func
+
+	// Socket callbacks run on the JS event loop and must not block, so
+	// received values are queued and delivered in order by pump
+	queue []StreamMessage
+	done  bool
+	wake  chan struct{}
+	mutex sync.Mutex
+}
+
+// socketEnvelope mirrors the JSON envelope used by /api/functions/ws
+type socketEnvelope struct {
+	Type         string          `json:"type"`
+	ServiceName  string          `json:"serviceName,omitempty"`
+	FunctionName string          `json:"functionName,omitempty"`
+	Args         []interface{}   `json:"args,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// OpenStream connects to a server function with the signature
+// func(ctx, in <-chan T, args...) (<-chan U, error). Values passed to Send
+// arrive on in; values the function sends arrive on Receive.
+func (c *Client) OpenStream(ctx context.Context, serviceName, functionName string, args ...interface{}) (*BidiStream, error) {
+	socket := js.Global().Get("WebSocket").New(websocketURL(c.baseURL, "/api/functions/ws"))
+
+	stream := &BidiStream{
+		socket:   socket,
+		messages: make(chan StreamMessage, 16),
+		wake:     make(chan struct{}, 1),
+	}
+	go stream.pump()
+
+	opened := make(chan error, 1)
+	onOpen := js.FuncOf(func(this js.Value, jsArgs []js.Value) interface{} {
+		opened <- nil
+		return nil
+	})
+	onError := js.FuncOf(func(this js.Value, jsArgs []js.Value) interface{} {
+		select {
+		case opened <- fmt.Errorf("websocket connection failed"):
+		default:
+		}
+		return nil
+	})
+	onMessage := js.FuncOf(func(this js.Value, jsArgs []js.Value) interface{} {
+		if len(jsArgs) > 0 {
+			stream.handleMessage(jsArgs[0].Get("data").String())
+		}
+		return nil
+	})
+	onClose := js.FuncOf(func(this js.Value, jsArgs []js.Value) interface{} {
+		stream.finish(nil)
+		return nil
+	})
+	stream.funcs = []js.Func{onOpen, onError, onMessage, onClose}
+
+	socket.Set("onopen", onOpen)
+	socket.Set("onerror", onError)
+	socket.Set("onmessage", onMessage)
+	socket.Set("onclose", onClose)
+
+	select {
+	case err := <-opened:
+		if err != nil {
+			stream.Close()
+			return nil, err
+		}
+	case <-ctx.Done():
+		stream.Close()
+		return nil, ctx.Err()
+	}
+
+	if err := stream.write(socketEnvelope{
+		Type:         "call",
+		ServiceName:  serviceName,
+		FunctionName: functionName,
+		Args:         args,
+	}); err != nil {
+		stream.Close()
+		return nil, err
+	}
+
+	// Closing the context tears down the socket
+	go func() {
+		<-ctx.Done()
+		stream.Close()
+	}()
+
+	return stream, nil
+}
+
+// Send delivers a value to the server function's receive channel
+func (s *BidiStream) Send(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream message: %w", err)
+	}
+	return s.write(socketEnvelope{Type: "message", Data: data})
+}
+
+// CloseSend closes the server function's receive channel while keeping the
+// socket open for the remaining results
+func (s *BidiStream) CloseSend() error {
+	return s.write(socketEnvelope{Type: "close"})
+}
+
+// Receive returns the channel of values sent by the server function. It is
+// closed when the function closes its channel or the socket closes.
+func (s *BidiStream) Receive() <-chan StreamMessage {
+	return s.messages
+}
+
+// Close closes the socket
+func (s *BidiStream) Close() error {
+	s.socket.Call("close")
+	s.finish(nil)
+	return nil
+}
+
+// write sends a JSON envelope over the socket
+func (s *BidiStream) write(msg socketEnvelope) error {
+	if s.socket.Get("readyState").Int() != 1 {
+		return fmt.Errorf("stream is closed")
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.socket.Call("send", string(data))
+	return nil
+}
+
+// handleMessage dispatches a server envelope
+func (s *BidiStream) handleMessage(raw string) {
+	var msg socketEnvelope
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		s.finish(fmt.Errorf("response parsing error: %w", err))
+		return
+	}
+
+	switch msg.Type {
+	case "message":
+		var data interface{}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			s.finish(fmt.Errorf("response parsing error: %w", err))
+			return
+		}
+		s.enqueue(StreamMessage{Data: data})
+	case "error":
+		s.finish(fmt.Errorf("server error: %s", msg.Error))
+	case "end":
+		s.finish(nil)
+	}
+}
+
+// enqueue queues a message for delivery
+func (s *BidiStream) enqueue(msg StreamMessage) {
+	s.mutex.Lock()
+	if s.done {
+		s.mutex.Unlock()
+		return
+	}
+	s.queue = append(s.queue, msg)
+	s.mutex.Unlock()
+	s.signal()
+}
+
+// finish marks the stream done, optionally queueing a final error
+func (s *BidiStream) finish(err error) {
+	s.mutex.Lock()
+	if s.done {
+		s.mutex.Unlock()
+		return
+	}
+	if err != nil {
+		s.queue = append(s.queue, StreamMessage{Err: err})
+	}
+	s.done = true
+	s.mutex.Unlock()
+	s.signal()
+
+	for _, fn := range s.funcs {
+		fn.Release()
+	}
+}
+
+// signal wakes the pump without blocking
+func (s *BidiStream) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pump delivers queued messages in order and closes the channel when done
+func (s *BidiStream) pump() {
+	for {
+		s.mutex.Lock()
+		if len(s.queue) > 0 {
+			msg := s.queue[0]
+			s.queue = s.queue[1:]
+			s.mutex.Unlock()
+			s.messages <- msg
+			continue
+		}
+		if s.done {
+			s.mutex.Unlock()
+			close(s.messages)
+			return
+		}
+		s.mutex.Unlock()
+		<-s.wake
+	}
+}
+
+// websocketURL converts an HTTP base URL (or the page origin) to a ws:// URL
+func websocketURL(baseURL, path string) string {
+	if baseURL == "" {
+		baseURL = js.Global().Get("location").Get("origin").String()
+	}
+	switch {
+	case strings.HasPrefix(baseURL, "https://"):
+		baseURL = "wss://" + strings.TrimPrefix(baseURL, "https://")
+	case strings.HasPrefix(baseURL, "http://"):
+		baseURL = "ws://" + strings.TrimPrefix(baseURL, "http://")
+	}
+	return strings.TrimSuffix(baseURL, "/") + path
+}
+
+// OpenStream is a convenience function for bidirectional streams with the default client
+func OpenStream(ctx context.Context, serviceName, functionName string, args ...interface{}) (*BidiStream, error) {
+	if defaultClient == nil {
+		defaultClient = NewClient("")
+	}
+	return defaultClient.OpenStream(ctx, serviceName, functionName, args...)
+}
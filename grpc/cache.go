@@ -0,0 +1,262 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures a client response cache
+type CacheOptions struct {
+	// TTL is how long a result is served without contacting the server
+	TTL time.Duration
+	// StaleWhileRevalidate is how long after TTL a stale result is still
+	// served while a fresh one is fetched in the background
+	StaleWhileRevalidate time.Duration
+	// Functions limits caching to "service.function" names; empty caches all
+	Functions []string
+	// OnRevalidate is called when a background revalidation stores a new result
+	OnRevalidate func(info *CallInfo, result interface{})
+}
+
+// Cache memoizes function results keyed by service, function and arguments.
+// Install it with Client.Use(cache.Interceptor()) or Client.EnableCache.
+type Cache struct {
+	options  CacheOptions
+	mutex    sync.Mutex
+	entries  map[string]*cacheEntry
+	inflight map[string]*cacheFetch
+}
+
+type cacheEntry struct {
+	service  string
+	function string
+	value    interface{}
+	storedAt time.Time
+	ttl      time.Duration
+}
+
+type cacheFetch struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type cacheContextKey struct{}
+
+// cacheDirective overrides cache behaviour for a single call
+type cacheDirective struct {
+	bypass bool
+	ttl    time.Duration
+}
+
+// NewCache creates a response cache
+func NewCache(options CacheOptions) *Cache {
+	return &Cache{
+		options:  options,
+		entries:  make(map[string]*cacheEntry),
+		inflight: make(map[string]*cacheFetch),
+	}
+}
+
+// EnableCache installs a response cache on the client and returns it
+func (c *Client) EnableCache(options CacheOptions) *Cache {
+	cache := NewCache(options)
+	c.Use(cache.Interceptor())
+	return cache
+}
+
+// WithoutCache returns a context whose calls always go to the server
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheContextKey{}, cacheDirective{bypass: true})
+}
+
+// WithCacheTTL returns a context whose results are cached for ttl instead
+// of the cache's default TTL
+func WithCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, cacheContextKey{}, cacheDirective{ttl: ttl})
+}
+
+// Interceptor returns the interceptor that serves calls from the cache
+func (c *Cache) Interceptor() Interceptor {
+	return func(ctx context.Context, info *CallInfo, next Invoker) (interface{}, error) {
+		directive, _ := ctx.Value(cacheContextKey{}).(cacheDirective)
+		if info.Batch != nil || directive.bypass || !c.caches(info) {
+			return next(ctx, info)
+		}
+
+		ttl := c.options.TTL
+		if directive.ttl > 0 {
+			ttl = directive.ttl
+		}
+
+		key, err := cacheKey(info.ServiceName, info.FunctionName, info.Args)
+		if err != nil {
+			return next(ctx, info)
+		}
+
+		c.mutex.Lock()
+		entry, ok := c.entries[key]
+		c.mutex.Unlock()
+
+		if ok {
+			age := time.Since(entry.storedAt)
+			if age < entry.ttl {
+				return entry.value, nil
+			}
+			if age < entry.ttl+c.options.StaleWhileRevalidate {
+				go c.revalidate(context.WithoutCancel(ctx), key, ttl, info, next)
+				return entry.value, nil
+			}
+		}
+
+		return c.fetch(ctx, key, ttl, info, next)
+	}
+}
+
+// Get returns a cached result, fresh or stale, if one exists
+func (c *Cache) Get(serviceName, functionName string, args ...interface{}) (interface{}, bool) {
+	key, err := cacheKey(serviceName, functionName, args)
+	if err != nil {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.storedAt) >= entry.ttl+c.options.StaleWhileRevalidate {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Set stores a result, e.g. after a mutation returned the updated value
+func (c *Cache) Set(value interface{}, serviceName, functionName string, args ...interface{}) {
+	key, err := cacheKey(serviceName, functionName, args)
+	if err != nil {
+		return
+	}
+	c.store(key, serviceName, functionName, value, c.options.TTL)
+}
+
+// Invalidate removes the cached result for one set of arguments
+func (c *Cache) Invalidate(serviceName, functionName string, args ...interface{}) {
+	key, err := cacheKey(serviceName, functionName, args)
+	if err != nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, key)
+}
+
+// InvalidateFunction removes every cached result of a function
+func (c *Cache) InvalidateFunction(serviceName, functionName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.service == serviceName && entry.function == functionName {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Clear removes all cached results
+func (c *Cache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+}
+
+// caches reports whether the call's function is eligible for caching
+func (c *Cache) caches(info *CallInfo) bool {
+	if len(c.options.Functions) == 0 {
+		return true
+	}
+	name := info.ServiceName + "." + info.FunctionName
+	for _, fn := range c.options.Functions {
+		if fn == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch calls the server, sharing one request between concurrent callers
+func (c *Cache) fetch(ctx context.Context, key string, ttl time.Duration, info *CallInfo, next Invoker) (interface{}, error) {
+	c.mutex.Lock()
+	if pending, ok := c.inflight[key]; ok {
+		c.mutex.Unlock()
+		select {
+		case <-pending.done:
+			return pending.value, pending.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	pending := &cacheFetch{done: make(chan struct{})}
+	c.inflight[key] = pending
+	c.mutex.Unlock()
+
+	pending.value, pending.err = next(ctx, info)
+
+	c.mutex.Lock()
+	delete(c.inflight, key)
+	c.mutex.Unlock()
+	close(pending.done)
+
+	if pending.err == nil {
+		c.store(key, info.ServiceName, info.FunctionName, pending.value, ttl)
+	}
+	return pending.value, pending.err
+}
+
+// revalidate refreshes a stale entry in the background
+func (c *Cache) revalidate(ctx context.Context, key string, ttl time.Duration, info *CallInfo, next Invoker) {
+	c.mutex.Lock()
+	_, busy := c.inflight[key]
+	c.mutex.Unlock()
+	if busy {
+		return
+	}
+
+	value, err := c.fetch(ctx, key, ttl, info, next)
+	if err == nil && c.options.OnRevalidate != nil {
+		c.options.OnRevalidate(info, value)
+	}
+}
+
+// store records a result in the cache
+func (c *Cache) store(key, serviceName, functionName string, value interface{}, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[key] = &cacheEntry{
+		service:  serviceName,
+		function: functionName,
+		value:    value,
+		storedAt: time.Now(),
+		ttl:      ttl,
+	}
+}
+
+// cacheKey builds the cache key for a call
+func cacheKey(serviceName, functionName string, args []interface{}) (string, error) {
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	var key strings.Builder
+	key.WriteString(serviceName)
+	key.WriteByte('.')
+	key.WriteString(functionName)
+	key.WriteByte(':')
+	key.Write(encoded)
+	return key.String(), nil
+}
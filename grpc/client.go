@@ -6,21 +6,48 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"syscall/js"
 	"time"
 )
 
 // Client provides seamless server function calling from frontend
 type Client struct {
-	baseURL string
-	timeout time.Duration
+	baseURL      string
+	timeout      time.Duration
+	headers      map[string]string
+	interceptors []Interceptor
+	retryPolicy  *RetryPolicy
+	credentials  string
+	mux          *muxTransport
+	protobuf     bool
 }
 
+// CallInfo describes an outgoing server function call. Interceptors may
+// modify it, e.g. to add headers, before passing it on.
+type CallInfo struct {
+	ServiceName  string
+	FunctionName string
+	Args         []interface{}
+	Headers      map[string]string
+	Batch        []BatchCall // set instead of the fields above for Batch calls
+}
+
+// Invoker performs a server function call
+type Invoker func(ctx context.Context, info *CallInfo) (interface{}, error)
+
+// Interceptor wraps every Call made by a client. It must call next to
+// continue the chain and may inspect or replace the result and error.
+type Interceptor func(ctx context.Context, info *CallInfo, next Invoker) (interface{}, error)
+
 // NewClient creates a new client for calling server functions
 func NewClient(baseURL string) *Client {
 	return &Client{
-		baseURL: baseURL,
-		timeout: 30 * time.Second,
+		baseURL:      baseURL,
+		timeout:      30 * time.Second,
+		headers:      make(map[string]string),
+		interceptors: make([]Interceptor, 0),
+		credentials:  "same-origin",
 	}
 }
 
@@ -29,27 +56,138 @@ func (c *Client) SetTimeout(timeout time.Duration) {
 	c.timeout = timeout
 }
 
+// SetHeader sets a header sent with every request
+func (c *Client) SetHeader(key, value string) {
+	c.headers[key] = value
+}
+
+// SetCredentials sets the fetch credentials mode: "same-origin" (default)
+// sends cookies to the app's own server, "include" also to other origins
+// (which must then answer with an explicit Access-Control-Allow-Origin)
+func (c *Client) SetCredentials(mode string) {
+	c.credentials = mode
+}
+
+// Use adds interceptors; they run in the order they were added
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
 // Call invokes a server function with automatic argument marshaling
 func (c *Client) Call(ctx context.Context, serviceName, functionName string, args ...interface{}) (interface{}, error) {
-	// Create the request payload
+	info := c.newCallInfo(ctx)
+	info.ServiceName = serviceName
+	info.FunctionName = functionName
+	info.Args = args
+
+	return c.chain(0, c.invoke)(ctx, info)
+}
+
+// newCallInfo creates call info carrying the client's default headers and
+// the metadata attached to ctx
+func (c *Client) newCallInfo(ctx context.Context) *CallInfo {
+	return &CallInfo{Headers: c.callHeaders(ctx)}
+}
+
+// callHeaders merges the client's headers with the metadata of ctx
+func (c *Client) callHeaders(ctx context.Context) map[string]string {
+	headers := make(map[string]string, len(c.headers))
+	for key, value := range c.headers {
+		headers[key] = value
+	}
+	if md, ok := ctx.Value(metadataKey{}).(map[string]string); ok {
+		for key, value := range md {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+type metadataKey struct{}
+
+// WithMetadata returns a context whose calls send the given metadata as
+// request headers, readable on the server with functions.GetMetadata
+func WithMetadata(ctx context.Context, md map[string]string) context.Context {
+	merged := make(map[string]string)
+	if existing, ok := ctx.Value(metadataKey{}).(map[string]string); ok {
+		for key, value := range existing {
+			merged[key] = value
+		}
+	}
+	for key, value := range md {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// chain returns the invoker for the interceptor at index, ending with final
+func (c *Client) chain(index int, final Invoker) Invoker {
+	if index >= len(c.interceptors) {
+		return final
+	}
+	return func(ctx context.Context, info *CallInfo) (interface{}, error) {
+		return c.interceptors[index](ctx, info, c.chain(index+1, final))
+	}
+}
+
+// invoke marshals the call and sends it to the server
+func (c *Client) invoke(ctx context.Context, info *CallInfo) (interface{}, error) {
+	jsonData, err := marshalCall(info)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := info.Headers
+	if key := c.idempotencyKey(ctx); key != "" {
+		ctx = WithIdempotencyKey(ctx, key)
+		headers = withHeader(headers, idempotencyKeyHeader, key)
+	}
+
+	// Send the call over the WebSocket when connected, otherwise with
+	// fetch, retrying transient failures
+	return c.withRetry(ctx, func() (interface{}, error) {
+		if c.socketAvailable(ctx) {
+			return c.callSocket(ctx, info, headers)
+		}
+		if c.protobuf && ctx.Value(progressKey{}) == nil {
+			return c.makeProtobufRequest(ctx, info, headers)
+		}
+		return c.makeRequest(ctx, "/api/functions", jsonData, headers)
+	})
+}
+
+// marshalCall encodes the JSON request payload of a call
+func marshalCall(info *CallInfo) ([]byte, error) {
 	requestData := map[string]interface{}{
-		"functionName": functionName,
-		"serviceName":  serviceName,
-		"args":         args,
+		"functionName": info.FunctionName,
+		"serviceName":  info.ServiceName,
+		"args":         info.Args,
 	}
 
-	// Convert to JSON
 	jsonData, err := json.Marshal(requestData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
+	return jsonData, nil
+}
 
-	// Make the HTTP request using fetch
-	return c.makeRequest(ctx, jsonData)
+// withHeader returns a copy of headers with key set to value
+func withHeader(headers map[string]string, key, value string) map[string]string {
+	copied := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
 }
 
 // makeRequest performs the actual HTTP request using JavaScript fetch
-func (c *Client) makeRequest(ctx context.Context, jsonData []byte) (interface{}, error) {
+func (c *Client) makeRequest(ctx context.Context, path string, jsonData []byte, extraHeaders map[string]string) (interface{}, error) {
+	// fetch cannot report upload progress, so progress calls use XHR
+	if onProgress, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		return c.makeXHRRequest(ctx, path, jsonData, extraHeaders, onProgress)
+	}
+
 	// Create a promise-based approach
 	resultChan := make(chan fetchResult, 1)
 
@@ -57,18 +195,27 @@ func (c *Client) makeRequest(ctx context.Context, jsonData []byte) (interface{},
 	options := js.Global().Get("Object").New()
 	options.Set("method", "POST")
 	options.Set("mode", "cors")
+	options.Set("credentials", c.credentials)
 
 	// Set headers
 	headers := js.Global().Get("Object").New()
 	headers.Set("Content-Type", "application/json")
 	headers.Set("Accept", "application/json")
+	headers.Set(timeoutHeader, formatTimeout(c.remainingTimeout(ctx)))
+	for key, value := range extraHeaders {
+		headers.Set(key, value)
+	}
 	options.Set("headers", headers)
 
 	// Set body
 	options.Set("body", string(jsonData))
 
+	// Abort the fetch on cancellation or timeout so the server sees it too
+	controller := js.Global().Get("AbortController").New()
+	options.Set("signal", controller.Get("signal"))
+
 	// Build the URL
-	url := fmt.Sprintf("%s/api/functions", c.baseURL)
+	url := c.baseURL + path
 
 	// Debug logging
 	fmt.Printf("🌐 gRPC Client Debug:\n")
@@ -97,9 +244,12 @@ func (c *Client) makeRequest(ctx context.Context, jsonData []byte) (interface{},
 	catchFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		defer catchFunc.Release() // Release after callback completes
 		if len(args) > 0 {
-			err := fmt.Errorf("fetch error: %s", args[0].String())
+			err := &TransportError{Message: fmt.Sprintf("fetch error: %s", args[0].String())}
 			fmt.Printf("❌ Fetch error: %v\n", err)
-			resultChan <- fetchResult{error: err}
+			select {
+			case resultChan <- fetchResult{error: err}:
+			default: // already settled or abandoned
+			}
 		}
 		return nil
 	})
@@ -117,11 +267,69 @@ func (c *Client) makeRequest(ctx context.Context, jsonData []byte) (interface{},
 		return result.data, nil
 	case <-ctx.Done():
 		fmt.Printf("❌ Context cancelled: %v\n", ctx.Err())
+		controller.Call("abort")
 		return nil, ctx.Err()
 	case <-time.After(c.timeout):
 		fmt.Printf("❌ Request timeout after %v\n", c.timeout)
-		return nil, fmt.Errorf("request timeout after %v", c.timeout)
+		controller.Call("abort")
+		return nil, &TransportError{Timeout: true, Message: fmt.Sprintf("request timeout after %v", c.timeout)}
+	}
+}
+
+// timeoutHeader carries the time left until the call's deadline, in
+// milliseconds, so the server can stop work the client no longer waits for
+const timeoutHeader = "X-Golem-Timeout"
+
+// remainingTimeout returns how long the client will wait for a call
+func (c *Client) remainingTimeout(ctx context.Context) time.Duration {
+	timeout := c.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
+
+// formatTimeout encodes a timeout header value, rounding up to 1ms
+func formatTimeout(timeout time.Duration) string {
+	ms := (timeout + time.Millisecond - 1) / time.Millisecond
+	if ms < 1 {
+		ms = 1
 	}
+	return strconv.FormatInt(int64(ms), 10)
+}
+
+// abortOnDone attaches an AbortController signal to fetch options and aborts
+// the request when ctx is done. The returned function stops watching ctx and
+// must be called once the request has finished.
+func abortOnDone(ctx context.Context, options js.Value) func() {
+	controller := js.Global().Get("AbortController").New()
+	options.Set("signal", controller.Get("signal"))
+
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			controller.Call("abort")
+		case <-finished:
+		}
+	}()
+
+	return func() { close(finished) }
+}
+
+// TransportError reports that a request did not reach the server function or
+// got a non-2xx HTTP response, as opposed to an error returned by the function
+type TransportError struct {
+	StatusCode int  // HTTP status, 0 if no response was received
+	Timeout    bool // the client-side timeout expired
+	Message    string
+}
+
+// Error implements the error interface
+func (e *TransportError) Error() string {
+	return e.Message
 }
 
 type fetchResult struct {
@@ -131,15 +339,11 @@ type fetchResult struct {
 
 // processResponse processes the fetch response synchronously
 func (c *Client) processResponse(response js.Value, resultChan chan<- fetchResult) {
-	// Check if response is ok
-	if !response.Get("ok").Bool() {
-		status := response.Get("status").Int()
-		statusText := response.Get("statusText").String()
-		resultChan <- fetchResult{error: fmt.Errorf("HTTP %d: %s", status, statusText)}
-		return
-	}
+	ok := response.Get("ok").Bool()
+	status := response.Get("status").Int()
+	transportErr := &TransportError{StatusCode: status, Message: fmt.Sprintf("HTTP %d: %s", status, response.Get("statusText").String())}
 
-	// Get response text
+	// Get response JSON; failed calls carry a structured error envelope
 	textPromise := response.Call("json")
 
 	// Handle text promise with proper function lifecycle
@@ -151,22 +355,11 @@ func (c *Client) processResponse(response js.Value, resultChan chan<- fetchResul
 
 			// Convert JS object to Go map
 			result := jsValueToInterface(jsonResponse)
-
-			// Check if the response indicates success
-			if respMap, ok := result.(map[string]interface{}); ok {
-				if success, exists := respMap["success"]; exists && success == true {
-					if resultData, exists := respMap["result"]; exists {
-						resultChan <- fetchResult{data: resultData}
-						return nil
-					}
-				}
-				if errorMsg, exists := respMap["error"]; exists {
-					resultChan <- fetchResult{error: fmt.Errorf("server error: %v", errorMsg)}
-					return nil
-				}
+			if !ok {
+				resultChan <- parseErrorResponse(result, transportErr)
+				return nil
 			}
-
-			resultChan <- fetchResult{data: result}
+			resultChan <- parseResult(result)
 		}
 		return nil
 	})
@@ -174,6 +367,10 @@ func (c *Client) processResponse(response js.Value, resultChan chan<- fetchResul
 	var catchFunc js.Func
 	catchFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		defer catchFunc.Release()
+		if !ok {
+			resultChan <- fetchResult{error: transportErr}
+			return nil
+		}
 		if len(args) > 0 {
 			err := fmt.Errorf("response parsing error: %s", args[0].String())
 			resultChan <- fetchResult{error: err}
@@ -184,6 +381,32 @@ func (c *Client) processResponse(response js.Value, resultChan chan<- fetchResul
 	textPromise.Call("then", thenFunc).Call("catch", catchFunc)
 }
 
+// parseErrorResponse turns a non-2xx response body into a structured error,
+// falling back to transportErr when it isn't a golem error envelope
+func parseErrorResponse(result interface{}, transportErr *TransportError) fetchResult {
+	if respMap, ok := result.(map[string]interface{}); ok {
+		if _, exists := respMap["code"]; exists {
+			return fetchResult{error: errorFromEnvelope(respMap, transportErr.StatusCode)}
+		}
+	}
+	return fetchResult{error: transportErr}
+}
+
+// parseResult unwraps the {"success", "result", "error"} response envelope
+func parseResult(result interface{}) fetchResult {
+	if respMap, ok := result.(map[string]interface{}); ok {
+		if success, exists := respMap["success"]; exists && success == true {
+			if resultData, exists := respMap["result"]; exists {
+				return fetchResult{data: resultData}
+			}
+		}
+		if _, exists := respMap["error"]; exists {
+			return fetchResult{error: errorFromEnvelope(respMap, 0)}
+		}
+	}
+	return fetchResult{data: result}
+}
+
 // jsValueToInterface converts a JavaScript value to a Go interface{}
 func jsValueToInterface(val js.Value) interface{} {
 	switch val.Type() {
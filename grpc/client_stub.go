@@ -5,15 +5,32 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
 )
 
 // Stub implementations for non-WASM builds
 type Client struct {
-	baseURL string
-	headers map[string]string
-	timeout int
+	baseURL      string
+	headers      map[string]string
+	timeout      int
+	interceptors []Interceptor
 }
 
+type CallInfo struct {
+	ServiceName  string
+	FunctionName string
+	Args         []interface{}
+	Headers      map[string]string
+	Batch        []BatchCall
+}
+
+type Invoker func(ctx context.Context, info *CallInfo) (interface{}, error)
+
+type Interceptor func(ctx context.Context, info *CallInfo, next Invoker) (interface{}, error)
+
 func NewClient(baseURL string) *Client {
 	return &Client{
 		baseURL: baseURL,
@@ -30,6 +47,10 @@ func (c *Client) SetTimeout(timeout int) {
 	c.timeout = timeout
 }
 
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
 func (c *Client) Call(ctx context.Context, serviceName, methodName string, req interface{}) (interface{}, error) {
 	return nil, fmt.Errorf("gRPC client only available in WebAssembly build")
 }
@@ -152,3 +173,170 @@ func Call(ctx context.Context, name string, args ...interface{}) (interface{}, e
 func CallWithResult(ctx context.Context, name string, target interface{}, args ...interface{}) error {
 	return fmt.Errorf("server functions only available in WebAssembly build")
 }
+
+type StatusError struct {
+	Code    codes.Code
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("grpc error: code = %s desc = %s", e.Code, e.Message)
+}
+
+type GRPCWebClient struct {
+	baseURL string
+	timeout time.Duration
+	headers map[string]string
+}
+
+func NewGRPCWebClient(baseURL string) *GRPCWebClient {
+	return &GRPCWebClient{
+		baseURL: baseURL,
+		timeout: 30 * time.Second,
+		headers: make(map[string]string),
+	}
+}
+
+func (c *GRPCWebClient) SetTimeout(timeout time.Duration) { c.timeout = timeout }
+func (c *GRPCWebClient) SetHeader(key, value string)      { c.headers[key] = value }
+
+func (c *GRPCWebClient) Invoke(ctx context.Context, method string, req, resp proto.Message) error {
+	return fmt.Errorf("gRPC-Web client only available in WebAssembly build")
+}
+
+type StreamMessage struct {
+	Data  interface{}
+	Final bool
+	Err   error
+}
+
+func (c *Client) CallStream(ctx context.Context, serviceName, functionName string, args ...interface{}) (<-chan StreamMessage, error) {
+	return nil, fmt.Errorf("streaming only available in WebAssembly build")
+}
+
+func CallStream(ctx context.Context, serviceName, functionName string, args ...interface{}) (<-chan StreamMessage, error) {
+	return nil, fmt.Errorf("streaming only available in WebAssembly build")
+}
+
+type BidiStream struct{}
+
+func (c *Client) OpenStream(ctx context.Context, serviceName, functionName string, args ...interface{}) (*BidiStream, error) {
+	return nil, fmt.Errorf("streaming only available in WebAssembly build")
+}
+
+func (s *BidiStream) Send(value interface{}) error {
+	return fmt.Errorf("streaming only available in WebAssembly build")
+}
+
+func (s *BidiStream) CloseSend() error {
+	return fmt.Errorf("streaming only available in WebAssembly build")
+}
+
+func (s *BidiStream) Receive() <-chan StreamMessage {
+	messages := make(chan StreamMessage)
+	close(messages)
+	return messages
+}
+
+func (s *BidiStream) Close() error { return nil }
+
+func OpenStream(ctx context.Context, serviceName, functionName string, args ...interface{}) (*BidiStream, error) {
+	return nil, fmt.Errorf("streaming only available in WebAssembly build")
+}
+
+type PushHandler func(data interface{})
+
+func (c *Client) EnableWebSocket()                         {}
+func (c *Client) OnPush(topic string, handler PushHandler) {}
+func (c *Client) EnableProtobuf()                          {}
+
+type TransportError struct {
+	StatusCode int
+	Timeout    bool
+	Message    string
+}
+
+func (e *TransportError) Error() string { return e.Message }
+
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {}
+
+func WithRetry(ctx context.Context, policy RetryPolicy) context.Context { return ctx }
+
+type BatchCall struct {
+	ServiceName  string        `json:"serviceName"`
+	FunctionName string        `json:"functionName"`
+	Args         []interface{} `json:"args"`
+}
+
+type BatchResult struct {
+	Data interface{}
+	Err  error
+}
+
+func NewBatchCall(serviceName, functionName string, args ...interface{}) BatchCall {
+	return BatchCall{ServiceName: serviceName, FunctionName: functionName, Args: args}
+}
+
+func (c *Client) Batch(ctx context.Context, calls ...BatchCall) ([]BatchResult, error) {
+	return nil, fmt.Errorf("server functions only available in WebAssembly build")
+}
+
+func (c *Client) BatchParallel(ctx context.Context, calls ...BatchCall) ([]BatchResult, error) {
+	return nil, fmt.Errorf("server functions only available in WebAssembly build")
+}
+
+func Batch(ctx context.Context, calls ...BatchCall) ([]BatchResult, error) {
+	return nil, fmt.Errorf("server functions only available in WebAssembly build")
+}
+
+func (c *Client) EnableOffline(options OfflineOptions) *OfflineQueue {
+	return NewOfflineQueue(func(ctx context.Context, info *CallInfo) (interface{}, error) {
+		return nil, fmt.Errorf("offline queue only available in WebAssembly build")
+	}, options)
+}
+
+func IsOnline() bool { return true }
+
+type Progress struct {
+	Upload bool
+	Loaded int64
+	Total  int64
+}
+
+type ProgressFunc func(progress Progress)
+
+func WithProgress(ctx context.Context, onProgress ProgressFunc) context.Context { return ctx }
+
+func CallTyped[T any](ctx context.Context, serviceName, functionName string, args ...interface{}) (T, error) {
+	var typed T
+	return typed, fmt.Errorf("server functions only available in WebAssembly build")
+}
+
+func CallTypedWith[T any](c *Client, ctx context.Context, serviceName, functionName string, args ...interface{}) (T, error) {
+	var typed T
+	return typed, fmt.Errorf("server functions only available in WebAssembly build")
+}
+
+func DecodeResult(result interface{}, target interface{}) error {
+	return fmt.Errorf("server functions only available in WebAssembly build")
+}
+
+func (c *Client) SetCredentials(mode string) {}
+
+func WithMetadata(ctx context.Context, md map[string]string) context.Context { return ctx }
@@ -0,0 +1,52 @@
+package grpc
+
+import "fmt"
+
+// Error codes sent by the server, see functions.Error
+const (
+	CodeUnknown            = "unknown"
+	CodeInvalidArgument    = "invalid_argument"
+	CodeNotFound           = "not_found"
+	CodeAlreadyExists      = "already_exists"
+	CodePermissionDenied   = "permission_denied"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeResourceExhausted  = "resource_exhausted"
+	CodeFailedPrecondition = "failed_precondition"
+	CodeUnavailable        = "unavailable"
+	CodeDeadlineExceeded   = "deadline_exceeded"
+	CodeCanceled           = "canceled"
+	CodeInternal           = "internal"
+)
+
+// Error is a structured error returned by a server function. Use errors.As
+// to branch on its Code or read its Details.
+type Error struct {
+	Code       string
+	Message    string
+	Details    map[string]interface{}
+	Retryable  bool
+	StatusCode int // HTTP status of the response, 0 for errors inside a batch or stream
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("server error: %s", e.Message)
+}
+
+// errorFromEnvelope rebuilds a structured error from the JSON error envelope
+func errorFromEnvelope(envelope map[string]interface{}, statusCode int) *Error {
+	err := &Error{Code: CodeUnknown, StatusCode: statusCode}
+	if code, ok := envelope["code"].(string); ok && code != "" {
+		err.Code = code
+	}
+	if message, ok := envelope["error"].(string); ok {
+		err.Message = message
+	} else {
+		err.Message = fmt.Sprintf("%v", envelope["error"])
+	}
+	if details, ok := envelope["details"].(map[string]interface{}); ok {
+		err.Details = details
+	}
+	err.Retryable, _ = envelope["retryable"].(bool)
+	return err
+}
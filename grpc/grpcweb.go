@@ -0,0 +1,303 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall/js"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// gRPC-Web frame flags
+const (
+	grpcWebDataFrame    byte = 0x00
+	grpcWebTrailerFrame byte = 0x80
+)
+
+// StatusError is a non-OK gRPC status returned by a gRPC-Web backend
+type StatusError struct {
+	Code    codes.Code
+	Message string
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("grpc error: code = %s desc = %s", e.Code, e.Message)
+}
+
+// GRPCWebClient speaks the gRPC-Web protocol (application/grpc-web+proto), so
+// it can call any standard gRPC-Web backend such as Envoy or grpcwebproxy
+type GRPCWebClient struct {
+	baseURL string
+	timeout time.Duration
+	headers map[string]string
+}
+
+// NewGRPCWebClient creates a gRPC-Web client for the given backend URL
+func NewGRPCWebClient(baseURL string) *GRPCWebClient {
+	return &GRPCWebClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		timeout: 30 * time.Second,
+		headers: make(map[string]string),
+	}
+}
+
+// SetTimeout sets the default call timeout used when ctx has no deadline
+func (c *GRPCWebClient) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// SetHeader sets a metadata header sent with every call
+func (c *GRPCWebClient) SetHeader(key, value string) {
+	c.headers[key] = value
+}
+
+// Invoke performs a unary call. method is the full method name, e.g.
+// "/golem.functions.FunctionService/Call".
+func (c *GRPCWebClient) Invoke(ctx context.Context, method string, req, resp proto.Message) error {
+	payload, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	response, body, err := c.fetch(ctx, method, encodeGRPCWebFrame(grpcWebDataFrame, payload))
+	if err != nil {
+		return err
+	}
+
+	message, trailers, err := parseGRPCWebBody(body)
+	if err != nil {
+		return err
+	}
+
+	// Trailers-only responses carry the status in the HTTP headers
+	if trailers.Get("grpc-status") == "" {
+		trailers = readResponseHeaders(response)
+	}
+	if err := statusFromMetadata(trailers, response.Get("status").Int()); err != nil {
+		return err
+	}
+
+	if message == nil {
+		return &StatusError{Code: codes.Internal, Message: "response contained no message"}
+	}
+	if err := proto.Unmarshal(message, resp); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// fetch posts a framed request and returns the response and its full body
+func (c *GRPCWebClient) fetch(ctx context.Context, method string, frame []byte) (js.Value, []byte, error) {
+	headers := js.Global().Get("Headers").New()
+	headers.Call("set", "Content-Type", "application/grpc-web+proto")
+	headers.Call("set", "Accept", "application/grpc-web+proto")
+	headers.Call("set", "X-Grpc-Web", "1")
+	headers.Call("set", "X-User-Agent", "golem-grpc-web")
+	if deadline, ok := ctx.Deadline(); ok {
+		headers.Call("set", "grpc-timeout", encodeGRPCTimeout(time.Until(deadline)))
+	}
+	for key, value := range c.headers {
+		headers.Call("set", key, value)
+	}
+
+	body := js.Global().Get("Uint8Array").New(len(frame))
+	js.CopyBytesToJS(body, frame)
+
+	options := js.Global().Get("Object").New()
+	options.Set("method", "POST")
+	options.Set("mode", "cors")
+	options.Set("headers", headers)
+	options.Set("body", body)
+	defer abortOnDone(ctx, options)()
+
+	response, err := awaitPromise(ctx, js.Global().Call("fetch", c.baseURL+method, options))
+	if err != nil {
+		return js.Value{}, nil, &StatusError{Code: codes.Unavailable, Message: err.Error()}
+	}
+
+	buffer, err := awaitPromise(ctx, response.Call("arrayBuffer"))
+	if err != nil {
+		return js.Value{}, nil, &StatusError{Code: codes.Unavailable, Message: err.Error()}
+	}
+
+	data := js.Global().Get("Uint8Array").New(buffer)
+	bodyBytes := make([]byte, data.Get("length").Int())
+	js.CopyBytesToGo(bodyBytes, data)
+
+	return response, bodyBytes, nil
+}
+
+// awaitPromise blocks until a JavaScript promise settles or ctx is done
+func awaitPromise(ctx context.Context, promise js.Value) (js.Value, error) {
+	type settled struct {
+		value js.Value
+		err   error
+	}
+	done := make(chan settled, 1)
+
+	var thenFunc, catchFunc js.Func
+	thenFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		defer thenFunc.Release()
+		defer catchFunc.Release()
+		var value js.Value
+		if len(args) > 0 {
+			value = args[0]
+		}
+		done <- settled{value: value}
+		return nil
+	})
+	catchFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		defer thenFunc.Release()
+		defer catchFunc.Release()
+		reason := "promise rejected"
+		if len(args) > 0 {
+			reason = args[0].Call("toString").String()
+		}
+		done <- settled{err: fmt.Errorf("%s", reason)}
+		return nil
+	})
+	promise.Call("then", thenFunc, catchFunc)
+
+	select {
+	case result := <-done:
+		return result.value, result.err
+	case <-ctx.Done():
+		return js.Value{}, ctx.Err()
+	}
+}
+
+// encodeGRPCWebFrame prefixes a payload with the 5-byte gRPC-Web frame header
+func encodeGRPCWebFrame(flag byte, payload []byte) []byte {
+	frame := make([]byte, 5+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+	return frame
+}
+
+// parseGRPCWebBody splits a response body into its message and trailers
+func parseGRPCWebBody(body []byte) ([]byte, textproto.MIMEHeader, error) {
+	var message []byte
+	trailers := make(textproto.MIMEHeader)
+
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return nil, nil, &StatusError{Code: codes.Internal, Message: "truncated gRPC-Web frame header"}
+		}
+
+		flag := body[0]
+		length := binary.BigEndian.Uint32(body[1:5])
+		if uint64(len(body)-5) < uint64(length) {
+			return nil, nil, &StatusError{Code: codes.Internal, Message: "truncated gRPC-Web frame"}
+		}
+		payload := body[5 : 5+length]
+		body = body[5+length:]
+
+		if flag&grpcWebTrailerFrame != 0 {
+			for key, values := range parseTrailerBlock(payload) {
+				trailers[key] = append(trailers[key], values...)
+			}
+			continue
+		}
+		if flag != grpcWebDataFrame {
+			return nil, nil, &StatusError{Code: codes.Internal, Message: fmt.Sprintf("unsupported gRPC-Web frame flag 0x%02x", flag)}
+		}
+		message = payload
+	}
+
+	return message, trailers, nil
+}
+
+// parseTrailerBlock parses "key: value\r\n" lines from a trailer frame
+func parseTrailerBlock(block []byte) textproto.MIMEHeader {
+	trailers := make(textproto.MIMEHeader)
+	for _, line := range bytes.Split(block, []byte("\r\n")) {
+		key, value, found := strings.Cut(string(line), ":")
+		if !found {
+			continue
+		}
+		trailers.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return trailers
+}
+
+// readResponseHeaders copies the fetch response headers into a MIMEHeader
+func readResponseHeaders(response js.Value) textproto.MIMEHeader {
+	headers := make(textproto.MIMEHeader)
+	for _, key := range []string{"grpc-status", "grpc-message"} {
+		value := response.Get("headers").Call("get", key)
+		if value.Type() == js.TypeString {
+			headers.Set(key, value.String())
+		}
+	}
+	return headers
+}
+
+// statusFromMetadata converts grpc-status/grpc-message into an error
+func statusFromMetadata(md textproto.MIMEHeader, httpStatus int) error {
+	rawStatus := md.Get("grpc-status")
+	if rawStatus == "" {
+		if httpStatus >= 200 && httpStatus < 300 {
+			return &StatusError{Code: codes.Internal, Message: "missing grpc-status in response"}
+		}
+		return &StatusError{Code: codeFromHTTPStatus(httpStatus), Message: fmt.Sprintf("HTTP %d", httpStatus)}
+	}
+
+	code, err := strconv.Atoi(rawStatus)
+	if err != nil {
+		return &StatusError{Code: codes.Unknown, Message: fmt.Sprintf("invalid grpc-status %q", rawStatus)}
+	}
+	if codes.Code(code) == codes.OK {
+		return nil
+	}
+
+	message, err := url.PathUnescape(md.Get("grpc-message"))
+	if err != nil {
+		message = md.Get("grpc-message")
+	}
+
+	return &StatusError{Code: codes.Code(code), Message: message}
+}
+
+// codeFromHTTPStatus maps HTTP failures to gRPC codes per the gRPC HTTP mapping
+func codeFromHTTPStatus(status int) codes.Code {
+	switch status {
+	case 400:
+		return codes.Internal
+	case 401:
+		return codes.Unauthenticated
+	case 403:
+		return codes.PermissionDenied
+	case 404:
+		return codes.Unimplemented
+	case 429, 502, 503, 504:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// encodeGRPCTimeout formats a duration as a grpc-timeout header value
+func encodeGRPCTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "1n"
+	}
+	return fmt.Sprintf("%dm", (timeout+time.Millisecond-1)/time.Millisecond)
+}
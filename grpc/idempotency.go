@@ -0,0 +1,41 @@
+package grpc
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+)
+
+type idempotentKey struct{}
+type idempotencyKeyKey struct{}
+
+// idempotencyKeyHeader carries the idempotency key of a call to the server
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// WithIdempotent marks calls made with ctx as safe to repeat, allowing them
+// to be retried after any transport failure. Other calls are only retried
+// when the server is known not to have run them (HTTP 429 and 503).
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// WithIdempotencyKey returns a context whose calls carry key as their
+// Idempotency-Key. The server runs a call with a given key only once within
+// its idempotency window, so such calls are retried like idempotent ones.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// NewIdempotencyKey returns a random key for WithIdempotencyKey
+func NewIdempotencyKey() string {
+	key := make([]byte, 16)
+	cryptorand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+// IdempotencyKey returns the key set on ctx with WithIdempotencyKey, "" when
+// there is none
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
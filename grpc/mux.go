@@ -0,0 +1,289 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"syscall/js"
+	"time"
+)
+
+// muxRedialDelay is how long a client waits before reconnecting a socket
+// that failed or closed; calls use fetch meanwhile
+const muxRedialDelay = 5 * time.Second
+
+// PushHandler receives a message a server function pushed to the client
+type PushHandler func(data interface{})
+
+// muxEnvelope mirrors the JSON envelope used by /api/functions/mux
+type muxEnvelope struct {
+	ID           uint64                 `json:"id,omitempty"`
+	Type         string                 `json:"type"`
+	ServiceName  string                 `json:"serviceName,omitempty"`
+	FunctionName string                 `json:"functionName,omitempty"`
+	Args         []interface{}          `json:"args,omitempty"`
+	Metadata     map[string]string      `json:"metadata,omitempty"`
+	Timeout      int64                  `json:"timeout,omitempty"`
+	Topic        string                 `json:"topic,omitempty"`
+	Result       json.RawMessage        `json:"result,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+	Code         string                 `json:"code,omitempty"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+	Retryable    bool                   `json:"retryable,omitempty"`
+}
+
+// muxTransport multiplexes a client's calls over one persistent WebSocket
+type muxTransport struct {
+	url      string
+	socket   js.Value
+	open     bool
+	dialing  bool
+	retryAt  time.Time
+	funcs    []js.Func
+	nextID   uint64
+	pending  map[uint64]chan fetchResult
+	handlers map[string][]PushHandler
+
+	// Socket callbacks run on the JS event loop and must not block, so
+	// pushed messages are queued and delivered in order by pump
+	pushes []muxEnvelope
+	wake   chan struct{}
+	mutex  sync.Mutex
+}
+
+// EnableWebSocket makes the client send calls over one persistent WebSocket
+// instead of a fetch per call, and receive messages pushed by server
+// functions. The socket connects in the background; while it is not open
+// calls fall back to fetch, and it reconnects after closing.
+func (c *Client) EnableWebSocket() {
+	if c.mux != nil {
+		return
+	}
+	c.mux = &muxTransport{
+		url:      websocketURL(c.baseURL, "/api/functions/mux"),
+		pending:  make(map[uint64]chan fetchResult),
+		handlers: make(map[string][]PushHandler),
+		wake:     make(chan struct{}, 1),
+	}
+	go c.mux.pump()
+	c.mux.connected()
+}
+
+// OnPush registers handler for messages pushed on topic, enabling the
+// WebSocket transport if needed
+func (c *Client) OnPush(topic string, handler PushHandler) {
+	c.EnableWebSocket()
+	c.mux.mutex.Lock()
+	c.mux.handlers[topic] = append(c.mux.handlers[topic], handler)
+	c.mux.mutex.Unlock()
+}
+
+// socketAvailable reports whether a call made with ctx can use the socket.
+// Progress reporting needs XHR, so those calls always use HTTP.
+func (c *Client) socketAvailable(ctx context.Context) bool {
+	if c.mux == nil {
+		return false
+	}
+	if _, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		return false
+	}
+	return c.mux.connected()
+}
+
+// connected reports whether the socket is open, dialing it when it is not
+func (m *muxTransport) connected() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.open {
+		return true
+	}
+	if !m.dialing && time.Now().After(m.retryAt) {
+		m.dial()
+	}
+	return false
+}
+
+// dial opens the socket; the caller holds the mutex
+func (m *muxTransport) dial() {
+	m.dialing = true
+	socket := js.Global().Get("WebSocket").New(m.url)
+	m.socket = socket
+
+	onOpen := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		m.mutex.Lock()
+		m.open, m.dialing = true, false
+		m.mutex.Unlock()
+		return nil
+	})
+	onMessage := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) > 0 {
+			m.handleMessage(args[0].Get("data").String())
+		}
+		return nil
+	})
+	onClose := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		m.closed(socket)
+		return nil
+	})
+	m.funcs = []js.Func{onOpen, onMessage, onClose}
+
+	socket.Set("onopen", onOpen)
+	socket.Set("onmessage", onMessage)
+	socket.Set("onclose", onClose)
+}
+
+// closed fails the calls in flight on socket and schedules a reconnect
+func (m *muxTransport) closed(socket js.Value) {
+	m.mutex.Lock()
+	if !m.socket.Equal(socket) {
+		m.mutex.Unlock()
+		return
+	}
+	pending := m.pending
+	funcs := m.funcs
+	m.pending = make(map[uint64]chan fetchResult)
+	m.funcs = nil
+	m.open, m.dialing = false, false
+	m.retryAt = time.Now().Add(muxRedialDelay)
+	m.mutex.Unlock()
+
+	for _, result := range pending {
+		result <- fetchResult{error: &TransportError{Message: "websocket closed"}}
+	}
+	for _, event := range []string{"onopen", "onmessage", "onclose"} {
+		socket.Set(event, js.Null())
+	}
+	for _, fn := range funcs {
+		fn.Release()
+	}
+}
+
+// callSocket sends a call over the socket and waits for its result
+func (c *Client) callSocket(ctx context.Context, info *CallInfo, headers map[string]string) (interface{}, error) {
+	m := c.mux
+	result := make(chan fetchResult, 1)
+
+	m.mutex.Lock()
+	if !m.open {
+		m.mutex.Unlock()
+		return nil, &TransportError{Message: "websocket closed"}
+	}
+	m.nextID++
+	id := m.nextID
+	m.pending[id] = result
+	socket := m.socket
+	m.mutex.Unlock()
+
+	forget := func() {
+		m.mutex.Lock()
+		delete(m.pending, id)
+		m.mutex.Unlock()
+	}
+
+	timeout, _ := strconv.ParseInt(formatTimeout(c.remainingTimeout(ctx)), 10, 64)
+	data, err := json.Marshal(muxEnvelope{
+		ID:           id,
+		Type:         "call",
+		ServiceName:  info.ServiceName,
+		FunctionName: info.FunctionName,
+		Args:         info.Args,
+		Metadata:     headers,
+		Timeout:      timeout,
+	})
+	if err != nil {
+		forget()
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	socket.Call("send", string(data))
+
+	cancel := func() {
+		forget()
+		if data, err := json.Marshal(muxEnvelope{ID: id, Type: "cancel"}); err == nil && socket.Get("readyState").Int() == 1 {
+			socket.Call("send", string(data))
+		}
+	}
+
+	select {
+	case r := <-result:
+		return r.data, r.error
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	case <-time.After(c.timeout):
+		cancel()
+		return nil, &TransportError{Timeout: true, Message: fmt.Sprintf("request timeout after %v", c.timeout)}
+	}
+}
+
+// handleMessage dispatches a server envelope
+func (m *muxTransport) handleMessage(raw string) {
+	var msg muxEnvelope
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return
+	}
+
+	if msg.Type == "push" {
+		m.mutex.Lock()
+		m.pushes = append(m.pushes, msg)
+		m.mutex.Unlock()
+		select {
+		case m.wake <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	m.mutex.Lock()
+	result, ok := m.pending[msg.ID]
+	delete(m.pending, msg.ID)
+	m.mutex.Unlock()
+	if !ok {
+		return // cancelled or timed out
+	}
+
+	switch msg.Type {
+	case "result":
+		var data interface{}
+		if err := json.Unmarshal(msg.Result, &data); err != nil {
+			result <- fetchResult{error: fmt.Errorf("response parsing error: %w", err)}
+			return
+		}
+		result <- fetchResult{data: data}
+	case "error":
+		result <- fetchResult{error: errorFromEnvelope(map[string]interface{}{
+			"error":     msg.Error,
+			"code":      msg.Code,
+			"details":   msg.Details,
+			"retryable": msg.Retryable,
+		}, 0)}
+	}
+}
+
+// pump delivers pushed messages to their handlers in order
+func (m *muxTransport) pump() {
+	for range m.wake {
+		for {
+			m.mutex.Lock()
+			if len(m.pushes) == 0 {
+				m.mutex.Unlock()
+				break
+			}
+			msg := m.pushes[0]
+			m.pushes = m.pushes[1:]
+			handlers := append([]PushHandler(nil), m.handlers[msg.Topic]...)
+			m.mutex.Unlock()
+
+			var data interface{}
+			if err := json.Unmarshal(msg.Result, &data); err != nil {
+				continue
+			}
+			for _, handler := range handlers {
+				handler(data)
+			}
+		}
+	}
+}
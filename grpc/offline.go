@@ -0,0 +1,39 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"syscall/js"
+)
+
+// EnableOffline installs an offline queue on the client and returns it.
+// Previously persisted calls are loaded and replayed if the browser is online.
+func (c *Client) EnableOffline(options OfflineOptions) *OfflineQueue {
+	q := NewOfflineQueue(func(ctx context.Context, info *CallInfo) (interface{}, error) {
+		return c.chain(0, c.invoke)(ctx, info)
+	}, options)
+
+	onOnline := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go q.Replay(context.Background())
+		return nil
+	})
+	js.Global().Call("addEventListener", "online", onOnline)
+	q.stop = func() {
+		js.Global().Call("removeEventListener", "online", onOnline)
+		onOnline.Release()
+	}
+
+	c.Use(q.Interceptor)
+
+	if q.options.Online() && len(q.Pending()) > 0 {
+		go q.Replay(context.Background())
+	}
+	return q
+}
+
+// IsOnline reports the browser's navigator.onLine status
+func IsOnline() bool {
+	online := js.Global().Get("navigator").Get("onLine")
+	return online.Type() != js.TypeBoolean || online.Bool()
+}
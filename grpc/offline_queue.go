@@ -0,0 +1,240 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Nu11ified/golem/state"
+)
+
+// ErrQueued is returned for mutating calls made while offline. The call has
+// been stored and will be replayed when the browser is back online.
+var ErrQueued = errors.New("call queued until the browser is back online")
+
+// ConflictResolution tells the offline queue what to do with a replayed call
+// the server rejected
+type ConflictResolution int
+
+const (
+	// ConflictDiscard drops the call and continues with the rest of the queue
+	ConflictDiscard ConflictResolution = iota
+	// ConflictRetry keeps the call at the head of the queue and stops replaying
+	ConflictRetry
+)
+
+// QueuedCall is a call stored by the offline queue
+type QueuedCall struct {
+	ID           string            `json:"id"`
+	ServiceName  string            `json:"serviceName"`
+	FunctionName string            `json:"functionName"`
+	Args         []interface{}     `json:"args"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	QueuedAt     time.Time         `json:"queuedAt"`
+	// IdempotencyKey is sent with every replay of the call, so the server
+	// runs it once even when the response to a replay is lost
+	IdempotencyKey string `json:"idempotencyKey"`
+}
+
+// OfflineOptions configures the offline queue
+type OfflineOptions struct {
+	// StorageKey is the persistence key of the queue (default "golem:offline-queue")
+	StorageKey string
+	// Persistence stores the queue across reloads (default state.NewPersistence())
+	Persistence *state.Persistence
+	// IsMutation reports whether a call should be queued while offline. By
+	// default every call not marked with WithIdempotent is a mutation.
+	IsMutation func(ctx context.Context, info *CallInfo) bool
+	// OnConflict decides what happens when the server rejects a replayed call
+	OnConflict func(call QueuedCall, err error) ConflictResolution
+	// OnReplay is called with the result of each successfully replayed call
+	OnReplay func(call QueuedCall, result interface{})
+	// Online reports whether calls can reach the server (default IsOnline)
+	Online func() bool
+}
+
+// OfflineQueue stores mutating calls made while offline and replays them in
+// order once the browser reconnects
+type OfflineQueue struct {
+	invoke    Invoker
+	options   OfflineOptions
+	mutex     sync.Mutex
+	calls     []QueuedCall
+	replaying bool
+	sequence  int
+	stop      func()
+}
+
+type replayKey struct{}
+
+// NewOfflineQueue returns an offline queue replaying calls with invoke,
+// loading the calls persisted earlier. Client.EnableOffline installs one on
+// a client; its Interceptor queues the calls.
+func NewOfflineQueue(invoke Invoker, options OfflineOptions) *OfflineQueue {
+	if options.StorageKey == "" {
+		options.StorageKey = "golem:offline-queue"
+	}
+	if options.Persistence == nil {
+		options.Persistence = state.NewPersistence()
+	}
+	if options.IsMutation == nil {
+		options.IsMutation = func(ctx context.Context, info *CallInfo) bool {
+			idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+			return !idempotent
+		}
+	}
+	if options.Online == nil {
+		options.Online = IsOnline
+	}
+
+	q := &OfflineQueue{invoke: invoke, options: options}
+	if err := options.Persistence.LoadState(options.StorageKey, &q.calls); err != nil {
+		q.calls = nil
+	}
+	return q
+}
+
+// Interceptor queues mutating calls while offline. Calls are also queued
+// while earlier calls are still waiting, so the server sees them in order.
+func (q *OfflineQueue) Interceptor(ctx context.Context, info *CallInfo, next Invoker) (interface{}, error) {
+	if ctx.Value(replayKey{}) != nil || info.Batch != nil || !q.options.IsMutation(ctx, info) {
+		return next(ctx, info)
+	}
+
+	q.mutex.Lock()
+	pending := len(q.calls) > 0
+	q.mutex.Unlock()
+
+	if q.options.Online() && !pending {
+		return next(ctx, info)
+	}
+
+	q.enqueue(ctx, info)
+	if q.options.Online() {
+		go q.Replay(context.Background())
+	}
+	return nil, ErrQueued
+}
+
+// Pending returns a copy of the calls waiting to be replayed
+func (q *OfflineQueue) Pending() []QueuedCall {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return append([]QueuedCall(nil), q.calls...)
+}
+
+// Clear drops every queued call
+func (q *OfflineQueue) Clear() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.calls = nil
+	q.persist()
+}
+
+// Replay sends queued calls to the server in order. It stops at the first
+// call that could not reach the server, leaving it and later calls queued.
+func (q *OfflineQueue) Replay(ctx context.Context) error {
+	q.mutex.Lock()
+	if q.replaying {
+		q.mutex.Unlock()
+		return nil
+	}
+	q.replaying = true
+	q.mutex.Unlock()
+
+	defer func() {
+		q.mutex.Lock()
+		q.replaying = false
+		q.mutex.Unlock()
+	}()
+
+	replayCtx := context.WithValue(ctx, replayKey{}, true)
+	for {
+		q.mutex.Lock()
+		if len(q.calls) == 0 {
+			q.mutex.Unlock()
+			return nil
+		}
+		call := q.calls[0]
+		q.mutex.Unlock()
+
+		info := &CallInfo{
+			ServiceName:  call.ServiceName,
+			FunctionName: call.FunctionName,
+			Args:         call.Args,
+			Headers:      call.Headers,
+		}
+		callCtx := replayCtx
+		if call.IdempotencyKey != "" {
+			callCtx = WithIdempotencyKey(replayCtx, call.IdempotencyKey)
+		}
+		result, err := q.invoke(callCtx, info)
+
+		var transportErr *TransportError
+		if errors.As(err, &transportErr) && transportErr.StatusCode == 0 {
+			return fmt.Errorf("failed to replay %s.%s: %w", call.ServiceName, call.FunctionName, err)
+		}
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			resolution := ConflictDiscard
+			if q.options.OnConflict != nil {
+				resolution = q.options.OnConflict(call, err)
+			}
+			if resolution == ConflictRetry {
+				return fmt.Errorf("replay of %s.%s deferred: %w", call.ServiceName, call.FunctionName, err)
+			}
+		} else if q.options.OnReplay != nil {
+			q.options.OnReplay(call, result)
+		}
+
+		q.mutex.Lock()
+		if len(q.calls) > 0 && q.calls[0].ID == call.ID {
+			q.calls = q.calls[1:]
+			q.persist()
+		}
+		q.mutex.Unlock()
+	}
+}
+
+// Close stops listening for reconnects
+func (q *OfflineQueue) Close() {
+	if q.stop != nil {
+		q.stop()
+	}
+}
+
+// enqueue stores a call at the end of the queue with the idempotency key of
+// ctx, or a new one, so its replays share a key
+func (q *OfflineQueue) enqueue(ctx context.Context, info *CallInfo) {
+	key := IdempotencyKey(ctx)
+	if key == "" {
+		key = NewIdempotencyKey()
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.sequence++
+	q.calls = append(q.calls, QueuedCall{
+		ID:             fmt.Sprintf("%d-%d", time.Now().UnixNano(), q.sequence),
+		ServiceName:    info.ServiceName,
+		FunctionName:   info.FunctionName,
+		Args:           info.Args,
+		Headers:        info.Headers,
+		QueuedAt:       time.Now(),
+		IdempotencyKey: key,
+	})
+	q.persist()
+}
+
+// persist saves the queue; the caller must hold the mutex
+func (q *OfflineQueue) persist() {
+	if err := q.options.Persistence.SaveState(q.options.StorageKey, q.calls); err != nil {
+		fmt.Printf("❌ Failed to persist offline queue: %v\n", err)
+	}
+}
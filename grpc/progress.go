@@ -0,0 +1,117 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+	"time"
+)
+
+// Progress reports how much of a request or response body has been transferred
+type Progress struct {
+	Upload bool  // true while sending the request, false while receiving the response
+	Loaded int64 // bytes transferred so far
+	Total  int64 // total bytes, 0 when unknown
+}
+
+// ProgressFunc receives progress updates for a call
+type ProgressFunc func(progress Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose calls report upload and download
+// progress to onProgress
+func WithProgress(ctx context.Context, onProgress ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, onProgress)
+}
+
+// makeXHRRequest performs a request with XMLHttpRequest, which unlike fetch
+// exposes upload progress events
+func (c *Client) makeXHRRequest(ctx context.Context, path string, jsonData []byte, extraHeaders map[string]string, onProgress ProgressFunc) (interface{}, error) {
+	resultChan := make(chan fetchResult, 1)
+	settle := func(result fetchResult) {
+		select {
+		case resultChan <- result:
+		default: // already settled
+		}
+	}
+
+	xhr := js.Global().Get("XMLHttpRequest").New()
+	xhr.Call("open", "POST", c.baseURL+path)
+	xhr.Set("withCredentials", c.credentials == "include")
+	xhr.Call("setRequestHeader", "Content-Type", "application/json")
+	xhr.Call("setRequestHeader", "Accept", "application/json")
+	xhr.Call("setRequestHeader", timeoutHeader, formatTimeout(c.remainingTimeout(ctx)))
+	for key, value := range extraHeaders {
+		xhr.Call("setRequestHeader", key, value)
+	}
+
+	progressHandler := func(upload bool) js.Func {
+		return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			event := args[0]
+			progress := Progress{Upload: upload, Loaded: int64(event.Get("loaded").Float())}
+			if event.Get("lengthComputable").Bool() {
+				progress.Total = int64(event.Get("total").Float())
+			}
+			onProgress(progress)
+			return nil
+		})
+	}
+	onUpload := progressHandler(true)
+	onDownload := progressHandler(false)
+
+	onLoad := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		status := xhr.Get("status").Int()
+		var result interface{}
+		parseErr := json.Unmarshal([]byte(xhr.Get("responseText").String()), &result)
+
+		if status < 200 || status >= 300 {
+			settle(parseErrorResponse(result, &TransportError{StatusCode: status, Message: fmt.Sprintf("HTTP %d: %s", status, xhr.Get("statusText").String())}))
+			return nil
+		}
+		if parseErr != nil {
+			settle(fetchResult{error: fmt.Errorf("response parsing error: %w", parseErr)})
+			return nil
+		}
+		settle(parseResult(result))
+		return nil
+	})
+	onError := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		settle(fetchResult{error: &TransportError{Message: "network error"}})
+		return nil
+	})
+
+	xhr.Get("upload").Set("onprogress", onUpload)
+	xhr.Set("onprogress", onDownload)
+	xhr.Set("onload", onLoad)
+	xhr.Set("onerror", onError)
+
+	defer func() {
+		// Detach handlers before releasing them so a late event cannot call
+		// a released function
+		xhr.Get("upload").Set("onprogress", js.Null())
+		for _, event := range []string{"onprogress", "onload", "onerror"} {
+			xhr.Set(event, js.Null())
+		}
+		onUpload.Release()
+		onDownload.Release()
+		onLoad.Release()
+		onError.Release()
+	}()
+
+	xhr.Call("send", string(jsonData))
+
+	select {
+	case result := <-resultChan:
+		return result.data, result.error
+	case <-ctx.Done():
+		xhr.Call("abort")
+		return nil, ctx.Err()
+	case <-time.After(c.timeout):
+		xhr.Call("abort")
+		return nil, &TransportError{Timeout: true, Message: fmt.Sprintf("request timeout after %v", c.timeout)}
+	}
+}
@@ -0,0 +1,179 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall/js"
+	"time"
+
+	pb "github.com/Nu11ified/golem/proto/gen/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// protobufContentType is the binary payload mode of /api/functions
+const protobufContentType = "application/x-protobuf"
+
+// jsonValueURL marks arguments and results encoded as JSON
+const jsonValueURL = "type.googleapis.com/google.protobuf.Value"
+
+// EnableProtobuf makes the client send calls as protobuf instead of JSON.
+// []byte and proto.Message arguments and results then travel as raw
+// protobuf rather than base64 or JSON; []byte results are returned as
+// []byte and message results as *anypb.Any. Servers without binary support
+// are detected on the first call, after which the client uses JSON.
+func (c *Client) EnableProtobuf() {
+	c.protobuf = true
+}
+
+// encodeProtobufArg encodes a call argument for the binary payload mode
+func encodeProtobufArg(arg interface{}) (*anypb.Any, error) {
+	switch value := arg.(type) {
+	case []byte:
+		return anypb.New(wrapperspb.Bytes(value))
+	case proto.Message:
+		return anypb.New(value)
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return &anypb.Any{TypeUrl: jsonValueURL, Value: data}, nil
+	}
+}
+
+// decodeProtobufResult decodes the result of a binary call
+func decodeProtobufResult(result *anypb.Any) (interface{}, error) {
+	switch result.GetTypeUrl() {
+	case "", jsonValueURL:
+		var data interface{}
+		if len(result.GetValue()) == 0 {
+			return nil, nil
+		}
+		if err := json.Unmarshal(result.GetValue(), &data); err != nil {
+			return nil, fmt.Errorf("response parsing error: %w", err)
+		}
+		return data, nil
+	case "type.googleapis.com/google.protobuf.BytesValue":
+		var wrapped wrapperspb.BytesValue
+		if err := result.UnmarshalTo(&wrapped); err != nil {
+			return nil, fmt.Errorf("response parsing error: %w", err)
+		}
+		return wrapped.Value, nil
+	default:
+		return result, nil
+	}
+}
+
+// makeProtobufRequest performs a call in the binary payload mode
+func (c *Client) makeProtobufRequest(ctx context.Context, info *CallInfo, extraHeaders map[string]string) (interface{}, error) {
+	req := &pb.FunctionRequest{ServiceName: info.ServiceName, FunctionName: info.FunctionName}
+	for i, arg := range info.Args {
+		encoded, err := encodeProtobufArg(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal argument %d: %w", i, err)
+		}
+		req.Args = append(req.Args, encoded)
+	}
+	payload, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	timeout := c.remainingTimeout(ctx)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	headers := js.Global().Get("Object").New()
+	headers.Set("Content-Type", protobufContentType)
+	headers.Set("Accept", protobufContentType+", application/json")
+	headers.Set(timeoutHeader, formatTimeout(timeout))
+	for key, value := range extraHeaders {
+		headers.Set(key, value)
+	}
+
+	body := js.Global().Get("Uint8Array").New(len(payload))
+	js.CopyBytesToJS(body, payload)
+
+	options := js.Global().Get("Object").New()
+	options.Set("method", "POST")
+	options.Set("mode", "cors")
+	options.Set("credentials", c.credentials)
+	options.Set("headers", headers)
+	options.Set("body", body)
+	defer abortOnDone(ctx, options)()
+
+	response, err := awaitPromise(ctx, js.Global().Call("fetch", c.baseURL+"/api/functions", options))
+	if err != nil {
+		return nil, c.protobufTransportError(ctx, timeout, err)
+	}
+	status := response.Get("status").Int()
+	transportErr := &TransportError{StatusCode: status, Message: fmt.Sprintf("HTTP %d: %s", status, response.Get("statusText").String())}
+
+	// Servers without binary support answer in JSON, failing to parse the body
+	contentType := response.Get("headers").Call("get", "Content-Type")
+	if contentType.Type() != js.TypeString || !strings.HasPrefix(contentType.String(), protobufContentType) {
+		if status == 400 {
+			c.protobuf = false
+			jsonData, err := marshalCall(info)
+			if err != nil {
+				return nil, err
+			}
+			return c.makeRequest(ctx, "/api/functions", jsonData, extraHeaders)
+		}
+		parsed, err := awaitPromise(ctx, response.Call("json"))
+		if err != nil {
+			return nil, transportErr
+		}
+		result := jsValueToInterface(parsed)
+		if !response.Get("ok").Bool() {
+			failed := parseErrorResponse(result, transportErr)
+			return nil, failed.error
+		}
+		parsedResult := parseResult(result)
+		return parsedResult.data, parsedResult.error
+	}
+
+	buffer, err := awaitPromise(ctx, response.Call("arrayBuffer"))
+	if err != nil {
+		return nil, c.protobufTransportError(ctx, timeout, err)
+	}
+	data := js.Global().Get("Uint8Array").New(buffer)
+	responseBytes := make([]byte, data.Get("length").Int())
+	js.CopyBytesToGo(responseBytes, data)
+
+	var resp pb.FunctionResponse
+	if err := proto.Unmarshal(responseBytes, &resp); err != nil {
+		return nil, fmt.Errorf("response parsing error: %w", err)
+	}
+	if !resp.Success {
+		envelope := map[string]interface{}{
+			"error":     resp.Error,
+			"code":      resp.Metadata["code"],
+			"retryable": resp.Metadata["retryable"] == "true",
+		}
+		var details map[string]interface{}
+		if json.Unmarshal([]byte(resp.Metadata["details"]), &details) == nil {
+			envelope["details"] = details
+		}
+		return nil, errorFromEnvelope(envelope, status)
+	}
+	return decodeProtobufResult(resp.Result)
+}
+
+// protobufTransportError reports a failed binary request, distinguishing the
+// client's timeout from cancellation and network errors
+func (c *Client) protobufTransportError(ctx context.Context, timeout time.Duration, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return &TransportError{Timeout: true, Message: fmt.Sprintf("request timeout after %v", timeout)}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return &TransportError{Message: fmt.Sprintf("fetch error: %v", err)}
+}
@@ -0,0 +1,123 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how transient failures are retried
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts including the first; <= 1 disables retries
+	InitialBackoff time.Duration // delay before the first retry
+	MaxBackoff     time.Duration // upper bound for the delay
+	Multiplier     float64       // backoff growth factor per attempt
+	Jitter         float64       // fraction of the delay randomized, 0..1
+}
+
+// DefaultRetryPolicy retries up to three times with exponential backoff
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+type retryPolicyKey struct{}
+
+// SetRetryPolicy sets the retry policy for all calls made by the client;
+// nil disables retries
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// WithRetry overrides the client's retry policy for calls made with ctx
+func WithRetry(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// idempotencyKey returns the key set with WithIdempotencyKey. Calls that may
+// be retried get a new key, shared by all attempts, so the server never runs
+// a mutation twice because an earlier attempt's response was lost.
+func (c *Client) idempotencyKey(ctx context.Context) string {
+	if key, ok := ctx.Value(idempotencyKeyKey{}).(string); ok {
+		return key
+	}
+
+	policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy)
+	if !ok && c.retryPolicy != nil {
+		policy, ok = *c.retryPolicy, true
+	}
+	if !ok || policy.MaxAttempts <= 1 {
+		return ""
+	}
+	return NewIdempotencyKey()
+}
+
+// withRetry runs attempt according to the effective retry policy
+func (c *Client) withRetry(ctx context.Context, attempt func() (interface{}, error)) (interface{}, error) {
+	policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy)
+	if !ok {
+		if c.retryPolicy == nil {
+			return attempt()
+		}
+		policy = *c.retryPolicy
+	}
+	idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+	if IdempotencyKey(ctx) != "" {
+		idempotent = true
+	}
+
+	backoff := policy.InitialBackoff
+	for n := 1; ; n++ {
+		result, err := attempt()
+		if err == nil || n >= policy.MaxAttempts || !isRetryable(err, idempotent) {
+			return result, err
+		}
+
+		delay := backoff
+		if policy.Jitter > 0 {
+			delay -= time.Duration(rand.Float64() * policy.Jitter * float64(delay))
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if policy.Multiplier > 0 {
+			backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		}
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// isRetryable reports whether a failed call may be attempted again
+func isRetryable(err error, idempotent bool) bool {
+	var serverErr *Error
+	if errors.As(err, &serverErr) {
+		return serverErr.Retryable // the server says whether retrying is safe
+	}
+
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		return false
+	}
+
+	switch transportErr.StatusCode {
+	case 429, 503:
+		return true
+	case 0, 408, 502, 504:
+		// The server may have started executing the function
+		return idempotent
+	default:
+		return false
+	}
+}
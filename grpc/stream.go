@@ -0,0 +1,172 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+// StreamMessage is a single value delivered by a streaming call. Partial
+// results emitted by the server function arrive first; the function's return
+// value arrives last with Final set. A failed call delivers Err.
+type StreamMessage struct {
+	Data  interface{}
+	Final bool
+	Err   error
+}
+
+// CallStream invokes a server function and delivers its partial results over
+// a channel, which is closed once the final result or an error has been sent
+func (c *Client) CallStream(ctx context.Context, serviceName, functionName string, args ...interface{}) (<-chan StreamMessage, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"functionName": functionName,
+		"serviceName":  serviceName,
+		"args":         args,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	headers := js.Global().Get("Object").New()
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Accept", "text/event-stream")
+	if deadline, ok := ctx.Deadline(); ok {
+		headers.Set(timeoutHeader, formatTimeout(time.Until(deadline)))
+	}
+	for key, value := range c.callHeaders(ctx) {
+		headers.Set(key, value)
+	}
+
+	options := js.Global().Get("Object").New()
+	options.Set("method", "POST")
+	options.Set("mode", "cors")
+	options.Set("credentials", c.credentials)
+	options.Set("headers", headers)
+	options.Set("body", string(jsonData))
+	stopAbort := abortOnDone(ctx, options)
+
+	url := fmt.Sprintf("%s/api/functions/stream", c.baseURL)
+	response, err := awaitPromise(ctx, js.Global().Call("fetch", url, options))
+	if err != nil {
+		stopAbort()
+		return nil, &TransportError{Message: fmt.Sprintf("fetch error: %v", err)}
+	}
+	if !response.Get("ok").Bool() {
+		stopAbort()
+		status := response.Get("status").Int()
+		return nil, &TransportError{StatusCode: status, Message: fmt.Sprintf("HTTP %d: %s", status, response.Get("statusText").String())}
+	}
+
+	messages := make(chan StreamMessage, 16)
+	go func() {
+		defer stopAbort()
+		c.readStream(ctx, response.Get("body").Call("getReader"), messages)
+	}()
+	return messages, nil
+}
+
+// readStream reads Server-Sent Events from a fetch body reader
+func (c *Client) readStream(ctx context.Context, reader js.Value, messages chan<- StreamMessage) {
+	defer close(messages)
+
+	deliver := func(msg StreamMessage) bool {
+		select {
+		case messages <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	decoder := js.Global().Get("TextDecoder").New()
+	decodeOptions := js.Global().Get("Object").New()
+	decodeOptions.Set("stream", true)
+
+	var buffer strings.Builder
+	for {
+		chunk, err := awaitPromise(ctx, reader.Call("read"))
+		if err != nil {
+			reader.Call("cancel")
+			deliver(StreamMessage{Err: err})
+			return
+		}
+		if chunk.Get("done").Bool() {
+			deliver(StreamMessage{Err: fmt.Errorf("stream ended without a result")})
+			return
+		}
+
+		buffer.WriteString(decoder.Call("decode", chunk.Get("value"), decodeOptions).String())
+
+		// Dispatch every complete event; keep the incomplete tail buffered
+		pending := buffer.String()
+		for {
+			block, rest, found := strings.Cut(pending, "\n\n")
+			if !found {
+				break
+			}
+			pending = rest
+
+			msg, ok := parseStreamEvent(block)
+			if !ok {
+				continue
+			}
+			if !deliver(msg) {
+				reader.Call("cancel")
+				return
+			}
+			if msg.Final || msg.Err != nil {
+				reader.Call("cancel")
+				return
+			}
+		}
+		buffer.Reset()
+		buffer.WriteString(pending)
+	}
+}
+
+// parseStreamEvent converts an SSE event block into a StreamMessage
+func parseStreamEvent(block string) (StreamMessage, bool) {
+	event := "message"
+	var data strings.Builder
+	for _, line := range strings.Split(block, "\n") {
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	if data.Len() == 0 {
+		return StreamMessage{}, false
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal([]byte(data.String()), &payload); err != nil {
+		return StreamMessage{Err: fmt.Errorf("response parsing error: %w", err)}, true
+	}
+
+	switch event {
+	case "error":
+		if m, ok := payload.(map[string]interface{}); ok {
+			return StreamMessage{Err: errorFromEnvelope(m, 0)}, true
+		}
+		return StreamMessage{Err: fmt.Errorf("server error: %v", payload)}, true
+	case "result":
+		return StreamMessage{Data: payload, Final: true}, true
+	default:
+		return StreamMessage{Data: payload}, true
+	}
+}
+
+// CallStream is a convenience function for streaming calls with the default client
+func CallStream(ctx context.Context, serviceName, functionName string, args ...interface{}) (<-chan StreamMessage, error) {
+	if defaultClient == nil {
+		defaultClient = NewClient("")
+	}
+	return defaultClient.CallStream(ctx, serviceName, functionName, args...)
+}
@@ -0,0 +1,46 @@
+//go:build js && wasm
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CallTyped calls a server function with the default client and decodes the
+// result into T, e.g. CallTyped[UserProfile](ctx, "server", "GetUserProfile", 1)
+func CallTyped[T any](ctx context.Context, serviceName, functionName string, args ...interface{}) (T, error) {
+	if defaultClient == nil {
+		defaultClient = NewClient("")
+	}
+	return CallTypedWith[T](defaultClient, ctx, serviceName, functionName, args...)
+}
+
+// CallTypedWith is like CallTyped but uses the given client
+func CallTypedWith[T any](c *Client, ctx context.Context, serviceName, functionName string, args ...interface{}) (T, error) {
+	var typed T
+
+	result, err := c.Call(ctx, serviceName, functionName, args...)
+	if err != nil {
+		return typed, err
+	}
+
+	if err := DecodeResult(result, &typed); err != nil {
+		return typed, err
+	}
+	return typed, nil
+}
+
+// DecodeResult decodes a generic call result (maps, slices and float64s as
+// produced by Call) into target, which must be a pointer
+func DecodeResult(result interface{}, target interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to decode result into %T: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// AnalyzeDir holds the size report of the latest build and of the one
+// before, and the package sizes of the WebAssembly binaries
+var AnalyzeDir = filepath.Join(CacheDir, "analyze")
+
+// Files of AnalyzeDir
+const (
+	reportFile         = "report.json"
+	previousReportFile = "previous.json"
+	packagesSuffix     = ".packages.json"
+)
+
+// Report is the size of a build: its WebAssembly binaries, the stylesheets
+// of its components and every file it serves
+type Report struct {
+	Build  string       `json:"build"` // hash of the files of the build, to tell builds apart
+	Time   time.Time    `json:"time"`
+	Wasm   []WasmReport `json:"wasm"`
+	CSS    []CSSReport  `json:"css"`
+	Assets []Asset      `json:"assets"`
+}
+
+// WasmReport is the size of a WebAssembly binary of the build
+type WasmReport struct {
+	Name     string           `json:"name"` // name before hashing, e.g. app.wasm
+	File     string           `json:"file"` // served name, e.g. app.1a2b3c4d.wasm
+	Size     int64            `json:"size"`
+	Sections map[string]int64 `json:"sections"`
+	Packages map[string]int64 `json:"packages,omitempty"` // code per Go package, before wasm-opt
+}
+
+// CSSReport is the size of the stylesheet a component injects
+type CSSReport struct {
+	Component string `json:"component"`
+	Size      int64  `json:"size"`
+}
+
+// Asset is a file the build serves, with the size of its precompressed
+// variants, 0 without them
+type Asset struct {
+	Name   string `json:"name"` // name before hashing, relative to the output
+	File   string `json:"file"`
+	Size   int64  `json:"size"`
+	Gzip   int64  `json:"gzip,omitempty"`
+	Brotli int64  `json:"brotli,omitempty"`
+}
+
+// AssetSize is the size of the served files
+func (r *Report) AssetSize() int64 {
+	var total int64
+	for _, asset := range r.Assets {
+		total += asset.Size
+	}
+	return total
+}
+
+// CSSSize is the size of the component stylesheets
+func (r *Report) CSSSize() int64 {
+	var total int64
+	for _, css := range r.CSS {
+		total += css.Size
+	}
+	return total
+}
+
+// Analyze measures the build in the output of cfg and the stylesheets of
+// the components under src
+func Analyze(cfg *config.Config) (*Report, error) {
+	if _, err := os.Stat(cfg.Output); err != nil {
+		return nil, fmt.Errorf("no build in %s, run 'golem build' first", cfg.Output)
+	}
+
+	// Served names map back to the names before hashing
+	names := make(map[string]string)
+	if data, err := os.ReadFile(filepath.Join(cfg.Output, AssetManifestFile)); err == nil {
+		var manifest map[string]string
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", AssetManifestFile, err)
+		}
+		for name, hashed := range manifest {
+			names[hashed] = name
+		}
+	}
+
+	report := &Report{Time: time.Now()}
+	build := sha256.New()
+	sourceDir := filepath.Join(cfg.Output, "src")
+	err := filepath.WalkDir(cfg.Output, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path == sourceDir {
+				return filepath.SkipDir // sources copied for the build, never served
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(cfg.Output, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		ext := filepath.Ext(rel)
+		// The precompressed variants are counted with their file, and the
+		// server binary is not served
+		if ext == ".gz" || ext == ".br" || rel == "server" {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		asset := Asset{Name: rel, File: rel, Size: info.Size()}
+		if name, ok := names[rel]; ok {
+			asset.Name = name
+		}
+		if info, err := os.Stat(path + ".gz"); err == nil {
+			asset.Gzip = info.Size()
+		}
+		if info, err := os.Stat(path + ".br"); err == nil {
+			asset.Brotli = info.Size()
+		}
+		report.Assets = append(report.Assets, asset)
+		fmt.Fprintf(build, "%s %d\n", rel, asset.Size)
+
+		if ext == ".wasm" {
+			wasm, err := analyzeWasm(path, asset)
+			if err != nil {
+				return fmt.Errorf("failed to analyze %s: %w", rel, err)
+			}
+			report.Wasm = append(report.Wasm, *wasm)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Build = hex.EncodeToString(build.Sum(nil))[:16]
+
+	report.CSS, err = analyzeCSS("src")
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// analyzeWasm measures a WebAssembly binary of the build, with the package
+// sizes the build recorded before wasm-opt stripped the function names
+func analyzeWasm(path string, asset Asset) (*WasmReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sizes, err := ParseWasmSizes(data)
+	if err != nil {
+		return nil, err
+	}
+	wasm := &WasmReport{Name: asset.Name, File: asset.File, Size: asset.Size, Sections: sizes.Sections}
+	if recorded, err := os.ReadFile(filepath.Join(AnalyzeDir, asset.Name+packagesSuffix)); err == nil {
+		if err := json.Unmarshal(recorded, &wasm.Packages); err != nil {
+			return nil, fmt.Errorf("failed to read the package sizes: %w", err)
+		}
+	}
+	return wasm, nil
+}
+
+// analyzeCSS measures the stylesheets of the components under dir
+func analyzeCSS(dir string) ([]CSSReport, error) {
+	var reports []CSSReport
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, ComponentExt) {
+			return nil
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		stylesheet, err := ComponentStylesheet(path, source)
+		if err != nil {
+			return err
+		}
+		if stylesheet != "" {
+			reports = append(reports, CSSReport{Component: filepath.ToSlash(path), Size: int64(len(stylesheet))})
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// recordPackages records the code size of each Go package of the
+// WebAssembly binary name, built from the main package in dir, for golem
+// analyze. It runs before wasm-opt strips the function names.
+func (b *Builder) recordPackages(name, dir, path string, env []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sizes, err := ParseWasmSizes(data)
+	if err != nil {
+		return err
+	}
+	if sizes.Functions == nil {
+		if err := os.Remove(filepath.Join(AnalyzeDir, name+packagesSuffix)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// The linker names the functions of the main package main.*
+	cmd := exec.Command("go", "list", "-deps", "-f", `{{if eq .Name "main"}}main{{else}}{{.ImportPath}}{{end}}`, ".")
+	cmd.Dir = dir
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("go list: %v", err)
+	}
+	packages := PackageSizes(sizes.Functions, strings.Fields(string(output)))
+
+	encoded, err := json.MarshalIndent(packages, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(AnalyzeDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(AnalyzeDir, name+packagesSuffix), encoded, 0644)
+}
+
+// storeReport stores the report of the build and notes its size in the
+// summary, with the change since the previous build
+func (b *Builder) storeReport() error {
+	report, err := Analyze(b.config)
+	if err != nil {
+		return err
+	}
+	if err := StoreReport(report); err != nil {
+		return err
+	}
+	line := "size: " + FormatSize(report.AssetSize())
+	if previous, err := PreviousReport(); err == nil && previous != nil {
+		if delta := report.AssetSize() - previous.AssetSize(); delta != 0 {
+			sign := "+"
+			if delta < 0 {
+				sign, delta = "-", -delta
+			}
+			line += " (" + sign + FormatSize(delta) + " since the previous build)"
+		}
+	}
+	b.note(line)
+	return nil
+}
+
+// StoreReport saves report as that of the latest build. The report it
+// replaces becomes the previous one, unless it is of the same build.
+func StoreReport(report *Report) error {
+	if err := os.MkdirAll(AnalyzeDir, 0755); err != nil {
+		return err
+	}
+	latest := filepath.Join(AnalyzeDir, reportFile)
+	if stored, err := loadReport(latest); err == nil && stored.Build != report.Build {
+		if err := os.Rename(latest, filepath.Join(AnalyzeDir, previousReportFile)); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(latest, append(data, '\n'), 0644)
+}
+
+// PreviousReport returns the report of the build before the latest one,
+// nil when there was none
+func PreviousReport() (*Report, error) {
+	report, err := loadReport(filepath.Join(AnalyzeDir, previousReportFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return report, err
+}
+
+func loadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// SizeChange is how the size of a part of the build changed between two
+// builds, with Before or After 0 for parts added or removed
+type SizeChange struct {
+	Name   string
+	Before int64
+	After  int64
+}
+
+// Delta is the bytes the part grew by
+func (c SizeChange) Delta() int64 {
+	return c.After - c.Before
+}
+
+// CompareReports lists the parts of the build whose size changed from
+// previous to current, the largest changes first: the total, each file and
+// each package of the WebAssembly binaries, and the component stylesheets
+func CompareReports(previous, current *Report) []SizeChange {
+	before := previous.sizes()
+	after := current.sizes()
+	var changes []SizeChange
+	for name, size := range after {
+		if before[name] != size {
+			changes = append(changes, SizeChange{Name: name, Before: before[name], After: size})
+		}
+	}
+	for name, size := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, SizeChange{Name: name, Before: size})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := abs(changes[i].Delta()), abs(changes[j].Delta())
+		if a != b {
+			return a > b
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// sizes flattens a report into the sizes CompareReports compares
+func (r *Report) sizes() map[string]int64 {
+	sizes := map[string]int64{"total": r.AssetSize(), "css": r.CSSSize()}
+	for _, asset := range r.Assets {
+		sizes[asset.Name] = asset.Size
+	}
+	for _, wasm := range r.Wasm {
+		for pkg, size := range wasm.Packages {
+			sizes[wasm.Name+" "+pkg] = size
+		}
+	}
+	return sizes
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,81 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// validateApps checks that every app of apps has a file-safe name shared
+// with no chunk, a main file under src, the sources copied into the build,
+// and a URL prefix of its own
+func validateApps(cfg *config.Config) error {
+	paths := make(map[string]string)
+	for _, name := range cfg.AppNames() {
+		app := cfg.Apps[name]
+		if !chunkName.MatchString(name) || name == "app" {
+			return fmt.Errorf("invalid app name %q, use letters, digits, - and _ (and not \"app\")", name)
+		}
+		if _, ok := cfg.Build.Chunks[name]; ok {
+			return fmt.Errorf("app %s: a chunk has the same name, and so the same .wasm file", name)
+		}
+		if app.Entry == "" {
+			return fmt.Errorf("app %s: set its entry, e.g. src/%s/main.go", name, name)
+		}
+		if dir := filepath.ToSlash(filepath.Clean(filepath.Dir(app.Entry))); !strings.HasPrefix(dir, "src/") {
+			return fmt.Errorf("app %s: %q is not a file under src", name, app.Entry)
+		}
+
+		prefix := cfg.AppPath(name)
+		if strings.Trim(app.Path, "/") == "" && app.Path != "" {
+			return fmt.Errorf("app %s: the main app is served at /, choose another path", name)
+		}
+		if strings.Contains(prefix, "/../") || strings.Contains(prefix, "/./") || strings.HasPrefix(prefix, "/api/") {
+			return fmt.Errorf("app %s: path %q has to be a plain URL prefix outside /api/", name, app.Path)
+		}
+		if other, ok := paths[prefix]; ok {
+			return fmt.Errorf("apps %s and %s are both served at %s", other, name, prefix)
+		}
+		paths[prefix] = name
+	}
+	return nil
+}
+
+// appDir returns the directory of the output holding the page of the
+// named app, e.g. "admin"
+func appDir(cfg *config.Config, name string) string {
+	return filepath.FromSlash(strings.Trim(cfg.AppPath(name), "/"))
+}
+
+// appRoot returns the relative URL of the output root from the page of
+// the named app, e.g. "../" for /admin/, or "" for the main app
+func appRoot(cfg *config.Config, name string) string {
+	if name == "" {
+		return ""
+	}
+	return strings.Repeat("../", strings.Count(strings.Trim(cfg.AppPath(name), "/"), "/")+1)
+}
+
+// writeAppPages writes the page of every app to its directory of the output
+func (b *Builder) writeAppPages() error {
+	for _, name := range b.config.AppNames() {
+		html, err := b.renderPage(name, "")
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(b.config.Output, appDir(b.config, name))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(html), 0644); err != nil {
+			return err
+		}
+	}
+	if len(b.config.Apps) > 0 {
+		b.note(fmt.Sprintf("apps: %s served at %s", strings.Join(b.config.AppNames(), ", "), strings.Join(b.config.AppPaths(), ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package build
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// AssetManifestFile maps the names of the built assets to the content-hashed
+// names they are served under, e.g. "app.wasm" to "app.1a2b3c4d.wasm"
+const AssetManifestFile = server.AssetManifestFile
+
+// hashedAssets are the generated files renamed after their content, along
+// with the chunks and apps. Public files keep their names, and the pages
+// link the others.
+var hashedAssets = []string{"app.wasm", "wasm_exec.js"}
+
+// HashedName inserts a hash of data before the extension of name, so the
+// name changes whenever the content does and can be cached forever
+func HashedName(name string, data []byte) string {
+	sum := sha256.Sum256(data)
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:])[:8] + ext
+}
+
+// Integrity returns the subresource integrity value of data, which
+// browsers check before running a script or using a fetched file
+func Integrity(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// hashAssets renames the generated assets after their content, computes
+// their integrity values and writes the asset manifest
+func (b *Builder) hashAssets() error {
+	b.assets = make(map[string]string)
+	b.integrity = make(map[string]string)
+	names := append([]string(nil), hashedAssets...)
+	for _, chunk := range ChunkNames(b.config) {
+		names = append(names, chunk+".wasm")
+	}
+	for _, app := range b.config.AppNames() {
+		names = append(names, app+".wasm")
+	}
+	for _, name := range names {
+		path := filepath.Join(b.config.Output, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		hashed := HashedName(name, data)
+		if err := os.Rename(path, filepath.Join(b.config.Output, hashed)); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", name, err)
+		}
+		b.assets[name] = hashed
+		b.integrity[name] = Integrity(data)
+	}
+
+	manifest, err := json.MarshalIndent(b.assets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(b.config.Output, AssetManifestFile), append(manifest, '\n'), 0644)
+}
+
+// asset returns the name a generated asset is served under
+func (b *Builder) asset(name string) string {
+	if hashed, ok := b.assets[name]; ok {
+		return hashed
+	}
+	return name
+}
+
+// integrityAttrs returns the attributes making the browser check a
+// generated asset against its integrity value
+func (b *Builder) integrityAttrs(name string) string {
+	if value, ok := b.integrity[name]; ok {
+		return ` integrity="` + value + `" crossorigin="anonymous"`
+	}
+	return ""
+}
@@ -1,18 +1,29 @@
 package build
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/router"
 )
 
 // Builder handles building Golem applications
 type Builder struct {
-	config *config.Config
+	config    *config.Config
+	functions []functions.GeneratedPackage
+	assets    map[string]string // content-hashed names of the generated assets
+	integrity map[string]string // subresource integrity values of the generated assets
+	pages     []string          // generated pages, whose inline elements the header policy allows
+	summary   []string          // lines printed once the build succeeds
+	reused    []string          // steps restored from the build cache
+	mu        sync.Mutex        // guards summary and reused while steps run in parallel
 }
 
 // NewBuilder creates a new Builder instance
@@ -25,34 +36,117 @@ func NewBuilder(config *config.Config) *Builder {
 // Build compiles the Golem application for production
 func (b *Builder) Build() error {
 	fmt.Println("📦 Preparing build...")
+	if b.config.Build.Static && b.config.Build.Routes == "" {
+		return fmt.Errorf(`a static build prerenders the routes of the package set as "routes" in the build section of golem.config.json`)
+	}
+	if err := validateChunks(b.config); err != nil {
+		return err
+	}
+	if err := validateApps(b.config); err != nil {
+		return err
+	}
+	if b.config.Build.Static && b.config.Build.SingleBinary {
+		return fmt.Errorf("a static build has no server binary to embed the output into, choose static or singleBinary")
+	}
+	if err := validateHooks(b.config); err != nil {
+		return err
+	}
+	if err := RunHooks(b.config, "build", "pre"); err != nil {
+		return err
+	}
 
 	// Clean build directory
 	if err := b.cleanBuildDir(); err != nil {
 		return fmt.Errorf("failed to clean build directory: %v", err)
 	}
 
+	// Generate server function registrations before copying the sources
+	fmt.Println("🧬 Generating server function registrations...")
+	generated, err := GenerateFunctions(b.config)
+	if err != nil {
+		return fmt.Errorf("failed to generate server functions: %v", err)
+	}
+	b.functions = generated
+
+	// Load .env files and compile the public variables into the app. A
+	// profile reads its own file, e.g. .env.staging.
+	mode := "production"
+	if b.config.Build.Profile != "" {
+		mode = b.config.Build.Profile
+	}
+	loaded, err := LoadEnv(mode)
+	if err != nil {
+		return fmt.Errorf("failed to load env files: %v", err)
+	}
+	if len(loaded) > 0 {
+		fmt.Printf("🌱 Loaded %s\n", strings.Join(loaded, ", "))
+	}
+	if b.config.Build.Server != "" {
+		os.Setenv(ServerURLEnv, b.config.Build.Server)
+	}
+	if _, err := GenerateEnv(b.config); err != nil {
+		return fmt.Errorf("failed to generate env package: %v", err)
+	}
+
 	// Parse .golem files and generate Go code
+	if err := RunHooks(b.config, "sources", "pre"); err != nil {
+		return err
+	}
 	fmt.Println("🔄 Parsing .golem files...")
 	if err := b.parseGolemFiles(); err != nil {
 		return fmt.Errorf("failed to parse .golem files: %v", err)
 	}
-
-	// Generate type definitions
-	fmt.Println("🔧 Generating type definitions...")
-	if err := b.generateTypes(); err != nil {
-		return fmt.Errorf("failed to generate types: %v", err)
+	if err := RunHooks(b.config, "sources", "post"); err != nil {
+		return err
 	}
 
-	// Build WebAssembly binary
-	fmt.Println("⚡ Building WebAssembly...")
-	if err := b.buildWasm(); err != nil {
-		return fmt.Errorf("failed to build WASM: %v", err)
+	// Compile the app, its chunks and the server and generate the route
+	// files side by side; none of them reads what another writes
+	steps := []Step{
+		{Name: "types", Message: "🔧 Generating type definitions...", Run: func(context.Context) error { return b.generateTypes() }},
+		{Name: "app.wasm", Message: "⚡ Building WebAssembly...", Run: func(ctx context.Context) error {
+			return b.compileWasm(ctx, "app.wasm", filepath.Dir(b.config.EntryFile()))
+		}},
+		{Name: "wasm_exec.js", Run: func(context.Context) error { return b.copyWasmExec() }},
+	}
+	for _, name := range b.config.AppNames() {
+		steps = append(steps, Step{Name: name + ".wasm", Message: "🪟 Building app " + name + "...", Run: func(ctx context.Context) error {
+			return b.compileWasm(ctx, name+".wasm", filepath.Dir(b.config.Apps[name].Entry))
+		}})
+	}
+	for _, name := range ChunkNames(b.config) {
+		steps = append(steps, Step{Name: name + ".wasm", Message: "🧩 Building chunk " + name + "...", Run: func(ctx context.Context) error {
+			return b.compileWasm(ctx, name+".wasm", b.config.Build.Chunks[name])
+		}})
+	}
+	// Single binaries are built once the output is complete
+	if !b.config.Build.SingleBinary {
+		steps = append(steps, Step{Name: "server", Message: "🔌 Building gRPC server...", Run: b.buildServer})
+	}
+	if b.config.Build.Routes != "" {
+		steps = append(steps, Step{Name: "routes", Message: "🗺️  Generating sitemap and route manifest...", Run: b.generateRouteFiles})
+	}
+	if b.config.Build.Debug {
+		fmt.Println("🐞 Debug build: optimizations and inlining are disabled")
+		b.note("debug: unoptimized, with function names kept")
+	}
+	if err := RunHooks(b.config, "compile", "pre"); err != nil {
+		return err
+	}
+	if err := RunParallel(b.jobs(), steps); err != nil {
+		return err
+	}
+	if err := RunHooks(b.config, "compile", "post"); err != nil {
+		return err
 	}
 
-	// Build gRPC server
-	fmt.Println("🔌 Building gRPC server...")
-	if err := b.buildServer(); err != nil {
-		return fmt.Errorf("failed to build server: %v", err)
+	// Name assets after their content so they can be cached forever
+	if err := RunHooks(b.config, "pages", "pre"); err != nil {
+		return err
+	}
+	fmt.Println("🔖 Hashing asset names...")
+	if err := b.hashAssets(); err != nil {
+		return fmt.Errorf("failed to hash assets: %v", err)
 	}
 
 	// Generate static assets
@@ -61,6 +155,85 @@ func (b *Builder) Build() error {
 		return fmt.Errorf("failed to generate static files: %v", err)
 	}
 
+	// Write a prerendered page for every route
+	if b.config.Build.Static {
+		fmt.Println("🖨️  Prerendering routes...")
+		if err := b.exportStatic(); err != nil {
+			return fmt.Errorf("failed to prerender routes: %v", err)
+		}
+	}
+
+	if b.config.Build.CSP != nil {
+		fmt.Println("🛡️  Writing Content-Security-Policy header...")
+		if err := b.writeCSPHeader(); err != nil {
+			return fmt.Errorf("failed to write Content-Security-Policy: %v", err)
+		}
+	}
+
+	if err := RunHooks(b.config, "pages", "post"); err != nil {
+		return err
+	}
+
+	// Copy public files last, so generated files take precedence
+	if err := RunHooks(b.config, "public", "pre"); err != nil {
+		return err
+	}
+	copied, err := CopyPublic(b.config, b.config.Output)
+	if err != nil {
+		return err
+	}
+	if len(copied) > 0 {
+		fmt.Printf("🗂️  Copied %d public files\n", len(copied))
+	}
+	if err := RunHooks(b.config, "public", "post"); err != nil {
+		return err
+	}
+
+	// Precache every page and asset, once they are in place
+	if b.config.Build.PWA != nil {
+		fmt.Println("📲 Generating web app manifest and service worker...")
+		if err := b.generatePWA(); err != nil {
+			return fmt.Errorf("failed to generate PWA files: %v", err)
+		}
+	}
+
+	// Compress last, once every served file is in place
+	if err := RunHooks(b.config, "compress", "pre"); err != nil {
+		return err
+	}
+	fmt.Println("🗜️  Precompressing assets...")
+	if err := b.precompress(); err != nil {
+		return fmt.Errorf("failed to precompress assets: %v", err)
+	}
+	if err := RunHooks(b.config, "compress", "post"); err != nil {
+		return err
+	}
+
+	if b.config.Build.SingleBinary {
+		fmt.Println("🔌 Building server binary with the app embedded...")
+		if err := b.buildSingleBinary(); err != nil {
+			return fmt.Errorf("failed to build single binary: %v", err)
+		}
+	}
+
+	if err := RunHooks(b.config, "build", "post"); err != nil {
+		return err
+	}
+
+	// golem analyze compares the next builds against this one
+	if err := b.storeReport(); err != nil {
+		fmt.Printf("Warning: failed to store the size report: %v\n", err)
+	}
+
+	if len(b.reused) > 0 {
+		b.summary = append(b.summary, "cache: reused "+strings.Join(b.reused, ", ")+" from "+CacheDir)
+	}
+	if len(b.summary) > 0 {
+		fmt.Println("📊 Build summary:")
+		for _, line := range b.summary {
+			fmt.Println("   " + line)
+		}
+	}
 	return nil
 }
 
@@ -72,13 +245,19 @@ func (b *Builder) cleanBuildDir() error {
 	return os.MkdirAll(buildDir, 0755)
 }
 
+// parseGolemFiles generates the Go code of the .golem components next to
+// them and copies the sources to the build directory
 func (b *Builder) parseGolemFiles() error {
-	// This would implement the .golem file parser
-	// For now, we'll just copy files to build directory
-
 	srcDir := "src"
 	buildSrcDir := filepath.Join(b.config.Output, "src")
 
+	generated, err := GenerateComponents(srcDir)
+	if err != nil {
+		return err
+	}
+	if len(generated) > 0 {
+		b.note(fmt.Sprintf("components: %d .golem files compiled", len(generated)))
+	}
 	return b.copyDir(srcDir, buildSrcDir)
 }
 
@@ -107,56 +286,149 @@ export interface UserProfile {
 	return os.WriteFile(filepath.Join(typesDir, "server.d.ts"), []byte(typeDef), 0644)
 }
 
-func (b *Builder) buildWasm() error {
-	// Build the WebAssembly binary
+// compileWasm builds the main package in dir, relative to the project, into
+// the WebAssembly binary name of the output and optimizes it
+func (b *Builder) compileWasm(ctx context.Context, name, dir string) error {
 	// Use absolute path for the output
 	workingDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %v", err)
 	}
-	outputPath := filepath.Join(workingDir, b.config.Output, "app.wasm")
+	outputPath := filepath.Join(workingDir, b.config.Output, name)
 
-	cmd := exec.Command("go", "build", "-o", outputPath)
-	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
-	cmd.Dir = filepath.Join(b.config.Output, "src/app")
+	define, err := b.goBuildFlags()
+	if err != nil {
+		return err
+	}
+	env := append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	wasmOpt, _ := exec.LookPath("wasm-opt")
+	key := StepKey(b.config, filepath.Join(b.config.Output, dir), env, ".", "wasm-opt "+wasmOpt)
+	if b.restoreStep(name, key, outputPath) {
+		if info, err := os.Stat(outputPath); err == nil {
+			b.note(name + ": " + FormatSize(info.Size()) + " (cached)")
+		}
+		return nil
+	}
+
+	args := append([]string{"build", "-o", outputPath}, define...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Env = env
+	cmd.Dir = filepath.Join(b.config.Output, dir)
 
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		return fmt.Errorf("WASM build failed: %v\nOutput: %s", err, output)
 	}
 
-	// Copy wasm_exec.js
-	return b.copyWasmExec()
+	if err := b.recordPackages(name, cmd.Dir, outputPath, env); err != nil {
+		fmt.Printf("Warning: failed to record the package sizes of %s: %v\n", name, err)
+	}
+	unoptimized, err := b.optimizeWasm(ctx, outputPath)
+	if err != nil {
+		return err
+	}
+	b.storeStep(name, key, outputPath)
+	if info, err := os.Stat(outputPath); err == nil {
+		line := name + ": " + FormatSize(info.Size())
+		if unoptimized > 0 {
+			line += fmt.Sprintf(" (%s before wasm-opt, -%.0f%%)", FormatSize(unoptimized), 100*(1-float64(info.Size())/float64(unoptimized)))
+		}
+		b.note(line)
+	}
+	return nil
 }
 
-func (b *Builder) buildServer() error {
-	// Build the gRPC server binary
-	// Use absolute path for the output
-	workingDir, err := os.Getwd()
+func (b *Builder) buildServer(ctx context.Context) error {
+	if len(b.functions) == 0 {
+		fmt.Println("   No server functions, skipping server binary")
+		return nil
+	}
+	if b.config.Build.Static {
+		fmt.Println("⚠️  Static build: skipping the server binary, so calls to server functions will fail on a static host")
+		return nil
+	}
+
+	// Build the server binary from the generated main package, which links
+	// in the registrations of every server package
+	outputPath := filepath.Join(b.config.Output, "server")
+	define, err := b.goBuildFlags()
 	if err != nil {
-		return fmt.Errorf("failed to get working directory: %v", err)
+		return err
+	}
+	key := StepKey(b.config, ".", os.Environ(), functions.HostMainFile)
+	if b.restoreStep("server", key, outputPath) {
+		return nil
 	}
-	outputPath := filepath.Join(workingDir, b.config.Output, "server")
 
-	cmd := exec.Command("go", "build", "-o", outputPath)
-	cmd.Dir = filepath.Join(b.config.Output, "src/server")
+	args := append([]string{"build", "-o", outputPath}, define...)
+	cmd := exec.CommandContext(ctx, "go", append(args, functions.HostMainFile)...)
 
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		return fmt.Errorf("server build failed: %v\nOutput: %s", err, output)
 	}
 
+	b.storeStep("server", key, outputPath)
 	return nil
 }
 
 func (b *Builder) generateStaticFiles() error {
-	// Generate index.html
-	html := `<!DOCTYPE html>
+	html, err := b.renderPage("", "")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(b.config.Output, "index.html"), []byte(html), 0644); err != nil {
+		return err
+	}
+	return b.writeAppPages()
+}
+
+// renderPage renders the page of the named app, "" for the main one, around
+// content, the markup shown until the app starts, with its
+// Content-Security-Policy when build.csp is set
+func (b *Builder) renderPage(app, content string) (string, error) {
+	html, err := b.pageHTML(app, content)
+	if err != nil {
+		return "", err
+	}
+	return b.applyCSP(html), nil
+}
+
+// pageHTML renders the page of the named app from the project's template
+// when it has one
+func (b *Builder) pageHTML(app, content string) (string, error) {
+	page := b.page(app, content)
+	if source, err := os.ReadFile(PageTemplate(b.config)); err == nil {
+		return renderPageTemplate(PageTemplate(b.config), string(source), page)
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read page template: %w", err)
+	}
+
+	baseTag := ""
+	if page.Base != "" {
+		baseTag = "\n    " + page.Base
+	}
+	headTags := ""
+	if page.Head != "" {
+		headTags = "\n    " + page.Head
+	}
+	if content == "" {
+		content = "Loading..."
+	}
+
+	return `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>` + b.config.ProjectName + `</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">` + baseTag + `
+    <title>` + page.Title + `</title>
+    ` + page.Preload + headTags + `
     <style>
         body { font-family: system-ui, sans-serif; margin: 0; padding: 20px; }
         .app { max-width: 800px; margin: 0 auto; }
@@ -169,19 +441,35 @@ func (b *Builder) generateStaticFiles() error {
     </style>
 </head>
 <body>
-    <div id="app">Loading...</div>
-    <script src="wasm_exec.js"></script>
-    <script>
-        const go = new Go();
-        WebAssembly.instantiateStreaming(fetch("app.wasm"), go.importObject)
-            .then((result) => {
-                go.run(result.instance);
-            });
-    </script>
+    <div id="app">` + content + `</div>
+    ` + page.Loader + `
 </body>
-</html>`
+</html>`, nil
+}
+
+// generateRouteFiles runs the configured route generator package, which
+// registers the app's routes on a router and calls WriteRouteFiles with the
+// output directory passed as its first argument
+func (b *Builder) generateRouteFiles(ctx context.Context) error {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %v", err)
+	}
+	outputDir := filepath.Join(workingDir, b.config.Output)
+
+	cmd := exec.CommandContext(ctx, "go", "run", b.config.Build.Routes, outputDir)
+	if b.config.Build.Static {
+		cmd.Env = append(os.Environ(), router.PrerenderEnv+"=1")
+	}
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("route generator failed: %v\nOutput: %s", err, output)
+	}
 
-	return os.WriteFile(filepath.Join(b.config.Output, "index.html"), []byte(html), 0644)
+	return nil
 }
 
 func (b *Builder) copyWasmExec() error {
@@ -0,0 +1,154 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// CacheDir holds the outputs of expensive build steps with a hash of their
+// inputs, so a build whose inputs are unchanged reuses them
+const CacheDir = ".golem/cache"
+
+// cacheKeyFile holds the input hash of a cached step
+const cacheKeyFile = "key"
+
+// StepKey hashes the inputs of a build step compiling pattern in dir: the
+// Go version, the configuration, the environment variables go build reads,
+// extra values and the Go files of every non-standard package pattern
+// depends on. It returns "" when the inputs cannot be listed, which
+// disables the cache for the step.
+func StepKey(config *config.Config, dir string, env []string, pattern string, extra ...string) string {
+	hash := sha256.New()
+
+	version, err := exec.Command("go", "env", "GOVERSION", "GOMODCACHE").Output()
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(string(version)), "\n")
+	moduleCache := ""
+	if len(lines) == 2 {
+		moduleCache = lines[1]
+	}
+	fmt.Fprintf(hash, "go %s\n", lines[0])
+
+	cfg, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	fmt.Fprintf(hash, "config %s\n", cfg)
+	for _, name := range []string{"GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED"} {
+		fmt.Fprintf(hash, "env %s=%s\n", name, lookupEnv(env, name))
+	}
+	for _, value := range extra {
+		fmt.Fprintf(hash, "extra %s\n", value)
+	}
+
+	cmd := exec.Command("go", "list", "-deps", "-f", `{{if not .Standard}}{{.Dir}}{{"\t"}}{{join .GoFiles " "}}{{"\t"}}{{join .EmbedFiles " "}}{{end}}`, pattern)
+	cmd.Dir = dir
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	// The last line is the package built, ending in a tab without embedded
+	// files, so only the newlines are trimmed
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		pkgDir := fields[0]
+		fmt.Fprintf(hash, "package %s\n", pkgDir)
+
+		// Module cache directories are named after their version and never change
+		if moduleCache != "" && strings.HasPrefix(pkgDir, moduleCache+string(filepath.Separator)) {
+			continue
+		}
+		for _, file := range append(strings.Fields(fields[1]), strings.Fields(fields[2])...) {
+			content, err := os.ReadFile(filepath.Join(pkgDir, file))
+			if err != nil {
+				return ""
+			}
+			fmt.Fprintf(hash, "file %s %d\n", file, len(content))
+			hash.Write(content)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// lookupEnv returns the last value of name in env, as exec does
+func lookupEnv(env []string, name string) string {
+	value := ""
+	for _, entry := range env {
+		if key, v, found := strings.Cut(entry, "="); found && key == name {
+			value = v
+		}
+	}
+	return value
+}
+
+// restoreStep copies the cached output of a step to path when it was built
+// from inputs with the same key. It reports whether it did.
+func (b *Builder) restoreStep(step, key, path string) bool {
+	if key == "" || b.config.Build.NoCache {
+		return false
+	}
+	dir := filepath.Join(CacheDir, step)
+	if cached, err := os.ReadFile(filepath.Join(dir, cacheKeyFile)); err != nil || string(cached) != key {
+		return false
+	}
+	if err := copyWithMode(filepath.Join(dir, filepath.Base(path)), path); err != nil {
+		return false
+	}
+
+	fmt.Printf("♻️  Reusing %s, its inputs are unchanged\n", step)
+	b.mu.Lock()
+	b.reused = append(b.reused, step)
+	b.mu.Unlock()
+	return true
+}
+
+// storeStep caches the output of a step built from inputs with key
+func (b *Builder) storeStep(step, key, path string) {
+	if key == "" {
+		return
+	}
+	dir := filepath.Join(CacheDir, step)
+	if err := os.RemoveAll(dir); err != nil {
+		fmt.Printf("Warning: failed to cache %s: %v\n", step, err)
+		return
+	}
+	if err := copyWithMode(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+		fmt.Printf("Warning: failed to cache %s: %v\n", step, err)
+		return
+	}
+	// The key goes last, so an interrupted store is never reused
+	if err := os.WriteFile(filepath.Join(dir, cacheKeyFile), []byte(key), 0644); err != nil {
+		fmt.Printf("Warning: failed to cache %s: %v\n", step, err)
+	}
+}
+
+// copyWithMode copies a file with its permissions, so binaries stay
+// executable
+func copyWithMode(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
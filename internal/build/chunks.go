@@ -0,0 +1,60 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// chunkName matches the names chunks may have, which become file names
+var chunkName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ChunkNames returns the names of the chunks in build.chunks, sorted
+func ChunkNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Build.Chunks))
+	for name := range cfg.Build.Chunks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateChunks checks that every chunk has a file-safe name and a main
+// package under src, the sources copied into the build
+func validateChunks(cfg *config.Config) error {
+	for _, name := range ChunkNames(cfg) {
+		if !chunkName.MatchString(name) || name == "app" {
+			return fmt.Errorf("invalid chunk name %q, use letters, digits, - and _ (and not \"app\")", name)
+		}
+		dir := filepath.ToSlash(filepath.Clean(cfg.Build.Chunks[name]))
+		if !strings.HasPrefix(dir, "src/") {
+			return fmt.Errorf("chunk %s: %q is not a directory under src", name, cfg.Build.Chunks[name])
+		}
+	}
+	return nil
+}
+
+// chunkLoader returns the script telling the router the file names and
+// integrity values of the chunks, or "" for apps without chunks
+func (b *Builder) chunkLoader() string {
+	names := ChunkNames(b.config)
+	if len(names) == 0 {
+		return ""
+	}
+	urls := make(map[string]string, len(names))
+	integrity := make(map[string]string, len(names))
+	for _, name := range names {
+		urls[name] = b.asset(name + ".wasm")
+		if value, ok := b.integrity[name+".wasm"]; ok {
+			integrity[name] = value
+		}
+	}
+	urlData, _ := json.Marshal(urls)
+	integrityData, _ := json.Marshal(integrity)
+	return "<script>globalThis.golemChunkURLs = " + string(urlData) + "; globalThis.golemChunkIntegrity = " + string(integrityData) + ";</script>\n    "
+}
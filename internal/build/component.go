@@ -0,0 +1,1230 @@
+package build
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"html"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ComponentExt is the extension of single-file components. A component
+// holds a <template> with the markup it renders, an optional <script> with
+// the Go code run before each render, and optional <style> blocks, which
+// apply to the component only when marked scoped.
+const ComponentExt = ".golem"
+
+// componentGenSuffix ends the names of the Go files generated from
+// components
+const componentGenSuffix = "_golem_gen.go"
+
+// ComponentFile returns the Go file generated next to a component, e.g.
+// card_golem_gen.go for card.golem
+func ComponentFile(path string) string {
+	return strings.TrimSuffix(path, ComponentExt) + componentGenSuffix
+}
+
+// IsComponentFile reports whether file was generated from a component
+func IsComponentFile(file string) bool {
+	return strings.HasSuffix(file, componentGenSuffix)
+}
+
+// ParseError is an error at a position of a component
+type ParseError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Template directives
+const (
+	directiveIf   = "g-if"   // renders the element when the expression is true
+	directiveElse = "g-else" // renders the element when the g-if before it is false
+	directiveFor  = "g-for"  // renders the element for each iteration of a range clause
+)
+
+// componentVoidElements have no closing tag in templates
+var componentVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// GenerateComponents generates the Go file of every component under dir
+// and removes the generated files whose component is gone. It returns the
+// generated files, and the parse errors of all components joined.
+func GenerateComponents(dir string) ([]string, error) {
+	var generated []string
+	var errs []error
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		switch {
+		case strings.HasSuffix(path, ComponentExt):
+			file, err := GenerateComponent(path)
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+			generated = append(generated, file)
+		case strings.HasSuffix(path, componentGenSuffix):
+			source := strings.TrimSuffix(path, componentGenSuffix) + ComponentExt
+			if _, err := os.Stat(source); os.IsNotExist(err) {
+				return os.Remove(path)
+			}
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return generated, errors.Join(errs...)
+}
+
+// GenerateComponent generates the Go file of a component and returns its
+// path. The file is only written when its content changes.
+func GenerateComponent(path string) (string, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	code, err := CompileComponent(path, componentPackage(filepath.Dir(path)), source)
+	if err != nil {
+		return "", err
+	}
+
+	target := ComponentFile(path)
+	if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, code) {
+		return target, nil
+	}
+	return target, os.WriteFile(target, code, 0644)
+}
+
+// componentPackage returns the package name of the Go files in dir, or the
+// name of dir when it has none
+func componentPackage(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err == nil {
+			return parsed.Name.Name
+		}
+	}
+	abs, _ := filepath.Abs(dir)
+	return goIdentifier(filepath.Base(abs), false)
+}
+
+// componentName returns the Go function name of a component file, e.g.
+// UserCard for user-card.golem
+func componentName(path string) string {
+	return goIdentifier(strings.TrimSuffix(filepath.Base(path), ComponentExt), true)
+}
+
+// goIdentifier turns a file name into a Go identifier, dropping the
+// characters identifiers cannot hold
+func goIdentifier(name string, exported bool) string {
+	var id strings.Builder
+	upper := exported
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = exported
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		id.WriteRune(r)
+	}
+	return id.String()
+}
+
+// component is a parsed .golem file
+type component struct {
+	template      *templateNode
+	props         string // parameters of the component function
+	propsPos      int
+	imports       string // import declarations of the script
+	importsPos    int
+	setup         string // statements of the script
+	setupPos      int
+	styles        []componentStyle
+	scoped        bool // whether a style block is scoped
+	templateFound bool
+}
+
+// componentStyle is a <style> block of a component
+type componentStyle struct {
+	css    string
+	scoped bool
+}
+
+// templateNode is an element or a text of a template
+type templateNode struct {
+	tag      string // "" for text
+	attrs    []templateAttr
+	children []*templateNode
+	parts    []textPart // text content
+	pos      int
+}
+
+// templateAttr is an attribute of a template element
+type templateAttr struct {
+	name     string
+	parts    []textPart // value, a single expression part for name={expr}
+	hasValue bool
+	pos      int
+}
+
+// textPart is literal text or a {Go expression}
+type textPart struct {
+	text string
+	expr bool
+	pos  int
+}
+
+// attr returns the attribute of n named name
+func (n *templateNode) attr(name string) *templateAttr {
+	for i := range n.attrs {
+		if n.attrs[i].name == name {
+			return &n.attrs[i]
+		}
+	}
+	return nil
+}
+
+// componentParser parses the source of a component
+type componentParser struct {
+	file  string // path in errors
+	base  string // file name in //line directives, relative to the generated file
+	src   string
+	lines []int // offsets of the line starts
+}
+
+func newComponentParser(path string, source []byte) *componentParser {
+	p := &componentParser{file: filepath.ToSlash(path), base: filepath.Base(path), src: string(source), lines: []int{0}}
+	for i, c := range source {
+		if c == '\n' {
+			p.lines = append(p.lines, i+1)
+		}
+	}
+	return p
+}
+
+// position returns the line and column of an offset
+func (p *componentParser) position(offset int) (int, int) {
+	line := sort.Search(len(p.lines), func(i int) bool { return p.lines[i] > offset })
+	return line, offset - p.lines[line-1] + 1
+}
+
+func (p *componentParser) errorf(offset int, format string, args ...interface{}) error {
+	line, column := p.position(offset)
+	return &ParseError{File: p.file, Line: line, Column: column, Message: fmt.Sprintf(format, args...)}
+}
+
+// goError turns the first error of parsing prefix+code as Go into an error
+// at its position in the component, where code starts at offset
+func (p *componentParser) goError(err error, prefix, code string, offset int) error {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) || len(list) == 0 {
+		return p.errorf(offset, "%v", err)
+	}
+	at := list[0].Pos.Offset - len(prefix)
+	return p.errorf(offset+max(0, min(at, len(code))), "%s", list[0].Msg)
+}
+
+// parse splits the component into its blocks
+func (p *componentParser) parse() (*component, error) {
+	c := &component{}
+	i := 0
+	for {
+		i = skipSpace(p.src, i)
+		if i == len(p.src) {
+			break
+		}
+		if strings.HasPrefix(p.src[i:], "<!--") {
+			end := strings.Index(p.src[i:], "-->")
+			if end < 0 {
+				return nil, p.errorf(i, "comment is never closed")
+			}
+			i += end + len("-->")
+			continue
+		}
+		if p.src[i] != '<' {
+			return nil, p.errorf(i, "unexpected text outside of <template>, <script> and <style>")
+		}
+
+		tag, attrs, contentStart, selfClosing, err := p.parseStartTag(i)
+		if err != nil {
+			return nil, err
+		}
+		if selfClosing {
+			return nil, p.errorf(i, "<%s> needs a closing </%s>", tag, tag)
+		}
+		closing := "</" + tag + ">"
+		var end int
+		switch tag {
+		case "template":
+			end = p.templateEnd(contentStart)
+		case "script", "style":
+			end = strings.Index(p.src[contentStart:], closing)
+			if end >= 0 {
+				end += contentStart
+			}
+		default:
+			return nil, p.errorf(i, "unknown block <%s>, expected <template>, <script> or <style>", tag)
+		}
+		if end < 0 {
+			return nil, p.errorf(i, "<%s> is never closed", tag)
+		}
+
+		if err := p.parseBlock(c, tag, attrs, i, contentStart, end); err != nil {
+			return nil, err
+		}
+		i = end + len(closing)
+	}
+
+	if !c.templateFound {
+		return nil, p.errorf(0, "component has no <template>")
+	}
+	return c, nil
+}
+
+// parseBlock parses the content of a top-level block into c
+func (p *componentParser) parseBlock(c *component, tag string, attrs []templateAttr, start, contentStart, end int) error {
+	switch tag {
+	case "template":
+		if c.templateFound {
+			return p.errorf(start, "component has more than one <template>")
+		}
+		c.templateFound = true
+		for _, attr := range attrs {
+			if attr.name != "props" {
+				return p.errorf(attr.pos, "unknown attribute %s of <template>, expected props", attr.name)
+			}
+			c.props, c.propsPos = attrText(attr), attr.pos
+			if len(attr.parts) > 0 {
+				c.propsPos = attr.parts[0].pos
+			}
+		}
+		if c.props != "" {
+			prefix := "package p\nfunc _("
+			if _, err := parser.ParseFile(token.NewFileSet(), "", prefix+c.props+") {}", 0); err != nil {
+				return p.goError(err, prefix, c.props, c.propsPos)
+			}
+		}
+
+		nodes, _, err := p.parseNodes(contentStart, end, "", start)
+		if err != nil {
+			return err
+		}
+		var roots []*templateNode
+		for _, node := range nodes {
+			if node.tag == "" {
+				return p.errorf(node.pos, "text outside of the root element")
+			}
+			roots = append(roots, node)
+		}
+		if len(roots) != 1 {
+			return p.errorf(start, "<template> needs exactly one root element, found %d", len(roots))
+		}
+		for _, directive := range []string{directiveIf, directiveElse, directiveFor} {
+			if attr := roots[0].attr(directive); attr != nil {
+				return p.errorf(attr.pos, "the root element cannot have %s", directive)
+			}
+		}
+		c.template = roots[0]
+
+	case "script":
+		if c.setup != "" || c.imports != "" {
+			return p.errorf(start, "component has more than one <script>")
+		}
+		if len(attrs) > 0 {
+			return p.errorf(attrs[0].pos, "unknown attribute %s of <script>", attrs[0].name)
+		}
+		split := scriptImportsEnd(p.src[contentStart:end])
+		c.imports, c.importsPos = p.src[contentStart:contentStart+split], contentStart
+		c.setup, c.setupPos = p.src[contentStart+split:end], contentStart+split
+
+		prefix := "package p\n"
+		if _, err := parser.ParseFile(token.NewFileSet(), "", prefix+c.imports, parser.ImportsOnly); err != nil {
+			return p.goError(err, prefix, c.imports, c.importsPos)
+		}
+		prefix = "package p\nfunc _() {"
+		if _, err := parser.ParseFile(token.NewFileSet(), "", prefix+c.setup+"\n}", 0); err != nil {
+			return p.goError(err, prefix, c.setup, c.setupPos)
+		}
+
+	case "style":
+		scoped := false
+		for _, attr := range attrs {
+			if attr.name != "scoped" || attr.hasValue {
+				return p.errorf(attr.pos, "unknown attribute %s of <style>, expected scoped", attr.name)
+			}
+			scoped = true
+		}
+		c.scoped = c.scoped || scoped
+		c.styles = append(c.styles, componentStyle{css: p.src[contentStart:end], scoped: scoped})
+	}
+	return nil
+}
+
+// attrText returns the value of an attribute as written
+func attrText(attr templateAttr) string {
+	var text strings.Builder
+	for _, part := range attr.parts {
+		if part.expr {
+			text.WriteString("{" + part.text + "}")
+		} else {
+			text.WriteString(part.text)
+		}
+	}
+	return text.String()
+}
+
+// templateEnd returns the offset of the </template> closing the template
+// whose content starts at i, skipping nested templates, or -1
+func (p *componentParser) templateEnd(i int) int {
+	depth := 1
+	for i < len(p.src) {
+		next := strings.Index(p.src[i:], "template")
+		if next < 0 {
+			return -1
+		}
+		i += next
+		switch {
+		case i >= 2 && p.src[i-2:i] == "</":
+			depth--
+			if depth == 0 {
+				return i - 2
+			}
+		case i >= 1 && p.src[i-1] == '<':
+			depth++
+		}
+		i += len("template")
+	}
+	return -1
+}
+
+// scriptImportsEnd returns the length of the import declarations at the
+// start of a script
+func scriptImportsEnd(script string) int {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(script))
+	var s scanner.Scanner
+	s.Init(file, []byte(script), nil, scanner.ScanComments)
+
+	end := 0
+	depth := 0
+	inImport := false
+	for {
+		pos, tok, _ := s.Scan()
+		offset := file.Offset(pos)
+		switch {
+		case tok == token.EOF:
+			return end
+		case tok == token.COMMENT:
+			continue
+		case tok == token.IMPORT && depth == 0 && !inImport:
+			inImport = true
+		case !inImport:
+			return end
+		case tok == token.LPAREN:
+			depth++
+		case tok == token.RPAREN:
+			depth--
+		case tok == token.SEMICOLON && depth == 0:
+			inImport = false
+			end = offset
+			if offset < len(script) && script[offset] == ';' {
+				end++
+			}
+		}
+	}
+}
+
+// parseStartTag parses the tag starting at i, returning its name and
+// attributes, the offset after it and whether it closes itself
+func (p *componentParser) parseStartTag(i int) (string, []templateAttr, int, bool, error) {
+	start := i
+	i++
+	nameStart := i
+	for i < len(p.src) && isNameChar(p.src[i]) {
+		i++
+	}
+	tag := p.src[nameStart:i]
+	if tag == "" {
+		return "", nil, 0, false, p.errorf(start, "expected a tag name after <")
+	}
+
+	var attrs []templateAttr
+	for {
+		i = skipSpace(p.src, i)
+		if i >= len(p.src) {
+			return "", nil, 0, false, p.errorf(start, "<%s is never closed with >", tag)
+		}
+		switch {
+		case p.src[i] == '>':
+			return tag, attrs, i + 1, false, nil
+		case strings.HasPrefix(p.src[i:], "/>"):
+			return tag, attrs, i + 2, true, nil
+		}
+
+		attr := templateAttr{pos: i}
+		for i < len(p.src) && isNameChar(p.src[i]) {
+			i++
+		}
+		attr.name = p.src[attr.pos:i]
+		if attr.name == "" {
+			return "", nil, 0, false, p.errorf(i, "unexpected %q in <%s>", p.src[i], tag)
+		}
+		for _, existing := range attrs {
+			if existing.name == attr.name {
+				return "", nil, 0, false, p.errorf(attr.pos, "duplicate attribute %s", attr.name)
+			}
+		}
+
+		if i < len(p.src) && p.src[i] == '=' {
+			attr.hasValue = true
+			i++
+			if i >= len(p.src) {
+				return "", nil, 0, false, p.errorf(attr.pos, "attribute %s has no value", attr.name)
+			}
+			switch quote := p.src[i]; quote {
+			case '"', '\'':
+				end := strings.IndexByte(p.src[i+1:], quote)
+				if end < 0 {
+					return "", nil, 0, false, p.errorf(i, "attribute %s is never closed with %c", attr.name, quote)
+				}
+				parts, err := p.parseText(i+1, i+1+end, false)
+				if err != nil {
+					return "", nil, 0, false, err
+				}
+				attr.parts = parts
+				i += end + 2
+			case '{':
+				end, err := p.exprEnd(i)
+				if err != nil {
+					return "", nil, 0, false, err
+				}
+				attr.parts = []textPart{{text: p.src[i+1 : end], expr: true, pos: i + 1}}
+				i = end + 1
+			default:
+				return "", nil, 0, false, p.errorf(i, `attribute %s needs a quoted value or a {Go expression}`, attr.name)
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+}
+
+// parseNodes parses the nodes from i up to the closing tag of parent, or
+// up to end at the top level, and returns them with the offset after them
+func (p *componentParser) parseNodes(i, end int, parent string, parentPos int) ([]*templateNode, int, error) {
+	var nodes []*templateNode
+	for i < end {
+		switch {
+		case strings.HasPrefix(p.src[i:], "<!--"):
+			close := strings.Index(p.src[i:end], "-->")
+			if close < 0 {
+				return nil, 0, p.errorf(i, "comment is never closed")
+			}
+			i += close + len("-->")
+
+		case strings.HasPrefix(p.src[i:], "</"):
+			close := strings.IndexByte(p.src[i:end], '>')
+			if close < 0 {
+				return nil, 0, p.errorf(i, "closing tag is never closed with >")
+			}
+			tag := strings.TrimSpace(p.src[i+2 : i+close])
+			if parent == "" {
+				return nil, 0, p.errorf(i, "unexpected </%s>", tag)
+			}
+			if tag != parent {
+				return nil, 0, p.errorf(i, "expected </%s>, found </%s>", parent, tag)
+			}
+			return nodes, i + close + 1, nil
+
+		case p.src[i] == '<' && i+1 < end && isNameChar(p.src[i+1]):
+			tag, attrs, next, selfClosing, err := p.parseStartTag(i)
+			if err != nil {
+				return nil, 0, err
+			}
+			if tag == "script" || tag == "style" {
+				return nil, 0, p.errorf(i, "<%s> belongs outside of the <template>", tag)
+			}
+			node := &templateNode{tag: tag, attrs: attrs, pos: i}
+			i = next
+			if !selfClosing && !componentVoidElements[tag] {
+				node.children, i, err = p.parseNodes(i, end, tag, node.pos)
+				if err != nil {
+					return nil, 0, err
+				}
+			}
+			nodes = append(nodes, node)
+
+		default:
+			textEnd, err := p.textEnd(i, end)
+			if err != nil {
+				return nil, 0, err
+			}
+			parts, err := p.parseText(i, textEnd, true)
+			if err != nil {
+				return nil, 0, err
+			}
+			if len(parts) > 0 {
+				nodes = append(nodes, &templateNode{parts: parts, pos: i})
+			}
+			i = textEnd
+		}
+	}
+	if parent != "" {
+		return nil, 0, p.errorf(parentPos, "<%s> is never closed", parent)
+	}
+	return nodes, i, nil
+}
+
+// textEnd returns the offset of the tag ending the text at i
+func (p *componentParser) textEnd(i, end int) (int, error) {
+	for i < end {
+		switch p.src[i] {
+		case '{':
+			close, err := p.exprEnd(i)
+			if err != nil {
+				return 0, err
+			}
+			i = close + 1
+		case '<':
+			return i, nil
+		default:
+			i++
+		}
+	}
+	return end, nil
+}
+
+// parseText splits text into literal parts and {expression} parts. Text
+// content has its whitespace condensed as browsers display it, and loses
+// the whitespace around line breaks at its start and end.
+func (p *componentParser) parseText(i, end int, content bool) ([]textPart, error) {
+	var parts []textPart
+	literalStart := i
+	addLiteral := func(to int) {
+		if to > literalStart {
+			parts = append(parts, textPart{text: html.UnescapeString(p.src[literalStart:to]), pos: literalStart})
+		}
+	}
+	for i < end {
+		if p.src[i] != '{' {
+			i++
+			continue
+		}
+		close, err := p.exprEnd(i)
+		if err != nil {
+			return nil, err
+		}
+		addLiteral(i)
+		expr := p.src[i+1 : close]
+		if strings.TrimSpace(expr) == "" {
+			return nil, p.errorf(i, "empty expression {}")
+		}
+		parts = append(parts, textPart{text: expr, expr: true, pos: i + 1})
+		i = close + 1
+		literalStart = i
+	}
+	addLiteral(end)
+
+	if !content {
+		return parts, nil
+	}
+	for index := range parts {
+		if !parts[index].expr {
+			parts[index].text = condenseSpace(parts[index].text, index == 0, index == len(parts)-1)
+		}
+	}
+	kept := parts[:0]
+	for _, part := range parts {
+		if part.expr || part.text != "" {
+			kept = append(kept, part)
+		}
+	}
+	return kept, nil
+}
+
+// condenseSpace collapses runs of whitespace into one space, dropping runs
+// with a line break at the start or end of a text
+func condenseSpace(text string, first, last bool) string {
+	var out strings.Builder
+	i := 0
+	for i < len(text) {
+		if !isSpace(text[i]) {
+			out.WriteByte(text[i])
+			i++
+			continue
+		}
+		start := i
+		i = skipSpace(text, i)
+		run := text[start:i]
+		if strings.Contains(run, "\n") && ((first && start == 0) || (last && i == len(text))) {
+			continue
+		}
+		out.WriteByte(' ')
+	}
+	return out.String()
+}
+
+// exprEnd returns the offset of the } closing the expression opened at i,
+// skipping braces in Go strings and nested blocks
+func (p *componentParser) exprEnd(i int) (int, error) {
+	start := i
+	depth := 0
+	for i < len(p.src) {
+		switch c := p.src[i]; c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		case '"', '\'', '`':
+			i++
+			for i < len(p.src) && p.src[i] != c {
+				if p.src[i] == '\\' && c != '`' {
+					i++
+				}
+				i++
+			}
+		}
+		i++
+	}
+	return 0, p.errorf(start, "expression is never closed with }")
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isNameChar(c byte) bool {
+	return c == '-' || c == '_' || c == ':' || c == '@' || c == '.' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// ComponentStylesheet returns the stylesheet the component at path injects
+// when it first renders, "" without style blocks
+func ComponentStylesheet(path string, source []byte) (string, error) {
+	p := newComponentParser(path, source)
+	c, err := p.parse()
+	if err != nil {
+		return "", err
+	}
+	_, stylesheet := p.stylesheet(c)
+	return stylesheet, nil
+}
+
+// stylesheet joins the style blocks of c, with the scoped ones limited to
+// the elements carrying scope, the attribute of the component
+func (p *componentParser) stylesheet(c *component) (scope, stylesheet string) {
+	if c.scoped {
+		sum := sha256.Sum256([]byte(p.file))
+		scope = "data-g-" + hex.EncodeToString(sum[:])[:6]
+	}
+	var b strings.Builder
+	for _, style := range c.styles {
+		css := style.css
+		if style.scoped {
+			css = scopeCSS(css, "["+scope+"]")
+		}
+		b.WriteString(strings.TrimSpace(css) + "\n")
+	}
+	return scope, b.String()
+}
+
+// CompileComponent compiles the source of the component at path into the
+// Go source of a function of package pkg named after the file, which
+// returns the component's element tree. Errors are *ParseError values.
+func CompileComponent(path, pkg string, source []byte) ([]byte, error) {
+	p := newComponentParser(path, source)
+	c, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	name := componentName(path)
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return nil, p.errorf(0, "file name %s does not start with a letter, which the component function needs", filepath.Base(path))
+	}
+
+	g := &componentGenerator{p: p}
+	var stylesheet string
+	g.scope, stylesheet = p.stylesheet(c)
+
+	root, err := g.node(c.template)
+	if err != nil {
+		return nil, err
+	}
+
+	var code strings.Builder
+	code.WriteString("// Code generated by golem from " + p.base + ". DO NOT EDIT.\n\n")
+	code.WriteString("package " + pkg + "\n\n")
+	code.WriteString("import (\n")
+	imports, err := p.imports(c)
+	if err != nil {
+		return nil, err
+	}
+	if g.fmt && !imports["fmt"] {
+		code.WriteString("\t\"fmt\"\n\n")
+	}
+	if len(c.styles) > 0 && !imports["github.com/Nu11ified/golem/css"] {
+		code.WriteString("\t\"github.com/Nu11ified/golem/css\"\n")
+	}
+	if !imports["github.com/Nu11ified/golem/dom"] {
+		code.WriteString("\t\"github.com/Nu11ified/golem/dom\"\n")
+	}
+	code.WriteString(")\n\n")
+	if strings.TrimSpace(c.imports) != "" {
+		code.WriteString(p.lineDirective(c.imports, c.importsPos) + "\n\n")
+	}
+
+	inject := ""
+	if len(c.styles) > 0 {
+		sum := sha256.Sum256([]byte(p.file))
+		styleConst := goIdentifier(name, false) + "Styles"
+		fmt.Fprintf(&code, "// %s is the stylesheet of %s\nconst %s = %s\n\n", styleConst, p.base, styleConst, goString(stylesheet))
+		inject = fmt.Sprintf("\tcss.InjectOnce(%q, %s)\n", "g-"+hex.EncodeToString(sum[:])[:6], styleConst)
+	}
+
+	fmt.Fprintf(&code, "// %s renders %s\nfunc %s(%s) *dom.Element {\n", name, p.base, name, c.props)
+	code.WriteString(inject)
+	code.WriteString(p.setupStatements(c.setup, c.setupPos))
+	code.WriteString("\treturn " + root + "\n}\n")
+
+	formatted, err := format.Source([]byte(code.String()))
+	if err != nil {
+		return nil, p.errorf(0, "generated code does not compile, check the expressions of the template: %v", err)
+	}
+	// A /*line*/ comment positions the character right after it, and
+	// formatting moves some before the comma of an argument list
+	formatted = misplacedLineComment.ReplaceAll(formatted, []byte(", $1"))
+	formatted = lineCommentSpace.ReplaceAll(formatted, []byte("$1"))
+	return formatted, nil
+}
+
+var (
+	misplacedLineComment = regexp.MustCompile(` (/\*line [^*]*\*/),`)
+	lineCommentSpace     = regexp.MustCompile(`(/\*line [^*]*\*/)[ \t]+`)
+)
+
+// lineDirective returns code starting at offset of the component, preceded
+// by a //line directive so the compiler reports its errors there
+func (p *componentParser) lineDirective(code string, offset int) string {
+	trimmed := strings.TrimLeft(code, " \t\r\n")
+	line, _ := p.position(offset + len(code) - len(trimmed))
+	return fmt.Sprintf("//line %s:%d\n%s", p.base, line, strings.TrimRight(trimmed, " \t\r\n"))
+}
+
+// setupStatements returns the statements of a script, each with its //line
+// directive, since formatting may remove the blank lines between them
+func (p *componentParser) setupStatements(setup string, offset int) string {
+	if strings.TrimSpace(setup) == "" {
+		return ""
+	}
+	prefix := "package p\nfunc _() {"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", prefix+setup+"\n}", parser.ParseComments)
+	if err != nil || len(file.Decls) == 0 {
+		return p.lineDirective(setup, offset) + "\n"
+	}
+	body := file.Decls[0].(*ast.FuncDecl).Body.List
+
+	var out strings.Builder
+	start := 0
+	for i := range body {
+		end := len(setup)
+		if i+1 < len(body) {
+			end = fset.Position(body[i+1].Pos()).Offset - len(prefix)
+		}
+		out.WriteString(p.lineDirective(setup[start:end], offset+start) + "\n")
+		start = end
+	}
+	return out.String()
+}
+
+// imports returns the import paths of the script, and fails when one of
+// the packages the generated code uses is imported under another name
+func (p *componentParser) imports(c *component) (map[string]bool, error) {
+	paths := make(map[string]bool)
+	if strings.TrimSpace(c.imports) == "" {
+		return paths, nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+c.imports, parser.ImportsOnly)
+	if err != nil {
+		return nil, p.goError(err, "package p\n", c.imports, c.importsPos)
+	}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		paths[path] = true
+		if spec.Name != nil {
+			switch path {
+			case "fmt", "github.com/Nu11ified/golem/css", "github.com/Nu11ified/golem/dom":
+				return nil, p.errorf(c.importsPos, "import %s without a name, the generated code refers to it as %s", path, filepath.Base(path))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// goString returns a Go string literal of s, raw when possible
+func goString(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// componentGenerator turns template nodes into Go expressions
+type componentGenerator struct {
+	p     *componentParser
+	scope string // attribute of scoped styles, e.g. data-g-1a2b3c
+	fmt   bool   // whether the code uses fmt
+}
+
+// expr returns a Go expression of the template, marked with its position
+// so compile errors point into the component
+func (g *componentGenerator) expr(part textPart) (string, error) {
+	if _, err := parser.ParseExpr(part.text); err != nil {
+		return "", g.p.goError(err, "", part.text, part.pos)
+	}
+	line, column := g.p.position(part.pos)
+	return fmt.Sprintf("/*line %s:%d:%d*/%s", g.p.base, line, column, part.text), nil
+}
+
+// text returns a Go string expression joining text parts
+func (g *componentGenerator) text(parts []textPart) (string, error) {
+	values := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if !part.expr {
+			values = append(values, strconv.Quote(part.text))
+			continue
+		}
+		expr, err := g.expr(part)
+		if err != nil {
+			return "", err
+		}
+		g.fmt = true
+		values = append(values, "fmt.Sprint("+expr+")")
+	}
+	if len(values) == 0 {
+		return `""`, nil
+	}
+	return strings.Join(values, " + "), nil
+}
+
+// value returns the Go value of an attribute: the expression itself for
+// name={expr}, true without a value, and a string otherwise
+func (g *componentGenerator) value(attr templateAttr) (string, error) {
+	if !attr.hasValue {
+		return "true", nil
+	}
+	if len(attr.parts) == 1 && attr.parts[0].expr {
+		return g.expr(attr.parts[0])
+	}
+	return g.text(attr.parts)
+}
+
+// node returns the Go expression of an element or text, without its
+// g-if and g-for directives
+func (g *componentGenerator) node(n *templateNode) (string, error) {
+	if n.tag == "" {
+		if len(n.parts) == 1 && n.parts[0].expr {
+			return g.expr(n.parts[0]) // elements, slices and other values pass as is
+		}
+		return g.text(n.parts)
+	}
+
+	var args []string
+	for _, attr := range n.attrs {
+		switch {
+		case attr.name == directiveIf || attr.name == directiveElse || attr.name == directiveFor:
+			continue
+		case strings.HasPrefix(attr.name, "g-"):
+			return "", g.p.errorf(attr.pos, "unknown directive %s, expected %s, %s or %s", attr.name, directiveIf, directiveElse, directiveFor)
+		case strings.HasPrefix(attr.name, "@"):
+			if isComponentTag(n.tag) {
+				return "", g.p.errorf(attr.pos, "components take no event handlers, pass %s as an attribute", strings.TrimPrefix(attr.name, "@"))
+			}
+			if len(attr.parts) != 1 || !attr.parts[0].expr {
+				return "", g.p.errorf(attr.pos, "event handler %s needs a {Go expression}", attr.name)
+			}
+			handler, err := g.expr(attr.parts[0])
+			if err != nil {
+				return "", err
+			}
+			args = append(args, fmt.Sprintf("dom.On(%q, %s)", strings.TrimPrefix(attr.name, "@"), handler))
+		default:
+			value, err := g.value(attr)
+			if err != nil {
+				return "", err
+			}
+			if isComponentTag(n.tag) {
+				args = append(args, value) // attributes are the arguments, in order
+			} else {
+				args = append(args, fmt.Sprintf("dom.Attribute{Name: %q, Value: %s}", attr.name, value))
+			}
+		}
+	}
+
+	if isComponentTag(n.tag) {
+		if len(n.children) > 0 {
+			return "", g.p.errorf(n.pos, "component <%s> takes no children", n.tag)
+		}
+		return n.tag + "(" + strings.Join(args, ", ") + ")", nil
+	}
+
+	if g.scope != "" {
+		args = append(args, fmt.Sprintf("dom.Attribute{Name: %q, Value: \"\"}", g.scope))
+	}
+	children, err := g.children(n.children)
+	if err != nil {
+		return "", err
+	}
+	args = append(args, children...)
+	if len(args) == 0 {
+		return fmt.Sprintf("dom.NewElement(%q)", n.tag), nil
+	}
+	return fmt.Sprintf("dom.NewElement(%q,\n%s,\n)", n.tag, strings.Join(args, ",\n")), nil
+}
+
+// children returns the Go expressions of nodes, applying their g-if,
+// g-else and g-for directives
+func (g *componentGenerator) children(nodes []*templateNode) ([]string, error) {
+	var exprs []string
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[i]
+		if n.tag != "" {
+			if attr := n.attr(directiveElse); attr != nil {
+				return nil, g.p.errorf(attr.pos, "%s needs an element with %s right before it", directiveElse, directiveIf)
+			}
+		}
+		expr, err := g.loop(n)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.tag != "" && n.attr(directiveIf) != nil && n.attr(directiveFor) == nil {
+			cond, err := g.directive(n, directiveIf)
+			if err != nil {
+				return nil, err
+			}
+			otherwise := "nil"
+			if i+1 < len(nodes) && nodes[i+1].tag != "" && nodes[i+1].attr(directiveElse) != nil {
+				i++
+				if attr := nodes[i].attr(directiveElse); attr.hasValue {
+					return nil, g.p.errorf(attr.pos, "%s takes no value", directiveElse)
+				}
+				if otherwise, err = g.loop(nodes[i]); err != nil {
+					return nil, err
+				}
+			}
+			expr = fmt.Sprintf("func() interface{} {\nif %s {\nreturn %s\n}\nreturn %s\n}()", cond, expr, otherwise)
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs, nil
+}
+
+// loop returns the Go expression of a node with its g-for directive, whose
+// g-if then applies to each iteration
+func (g *componentGenerator) loop(n *templateNode) (string, error) {
+	expr, err := g.node(n)
+	if err != nil || n.tag == "" || n.attr(directiveFor) == nil {
+		return expr, err
+	}
+
+	attr := n.attr(directiveFor)
+	if len(attr.parts) != 1 || !attr.parts[0].expr {
+		return "", g.p.errorf(attr.pos, "%s needs a {range clause}, e.g. %s={i, item := range items}", directiveFor, directiveFor)
+	}
+	clause := attr.parts[0]
+	prefix := "package p\nfunc _() {\nfor "
+	if _, err := parser.ParseFile(token.NewFileSet(), "", prefix+clause.text+" {}\n}", 0); err != nil {
+		return "", g.p.goError(err, prefix, clause.text, clause.pos)
+	}
+	line, column := g.p.position(clause.pos)
+
+	add := "elements = append(elements, " + expr + ")"
+	if n.attr(directiveIf) != nil {
+		cond, err := g.directive(n, directiveIf)
+		if err != nil {
+			return "", err
+		}
+		add = "if " + cond + " {\n" + add + "\n}"
+	}
+	return fmt.Sprintf("func() []*dom.Element {\nvar elements []*dom.Element\nfor /*line %s:%d:%d*/%s {\n%s\n}\nreturn elements\n}()",
+		g.p.base, line, column, clause.text, add), nil
+}
+
+// directive returns the expression of a directive of n
+func (g *componentGenerator) directive(n *templateNode, name string) (string, error) {
+	attr := n.attr(name)
+	if len(attr.parts) != 1 || !attr.parts[0].expr {
+		return "", g.p.errorf(attr.pos, "%s needs a {Go expression}", name)
+	}
+	return g.expr(attr.parts[0])
+}
+
+// isComponentTag reports whether a tag renders a component, e.g. <Card>
+// or <components.Card>, rather than an HTML element
+func isComponentTag(tag string) bool {
+	name := tag[strings.LastIndexByte(tag, '.')+1:]
+	return name != "" && unicode.IsUpper(rune(name[0]))
+}
+
+// scopeCSS limits the rules of a stylesheet to elements with attr, e.g.
+// "[data-g-1a2b3c]", by adding it to the last part of every selector.
+// Rules nested in @media, @supports, @container and @layer are scoped too;
+// @keyframes, @font-face and other at-rules are kept as is.
+func scopeCSS(css, attr string) string {
+	var out strings.Builder
+	i := 0
+	for i < len(css) {
+		if strings.HasPrefix(css[i:], "/*") {
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				out.WriteString(css[i:])
+				break
+			}
+			out.WriteString(css[i : i+2+end+2])
+			i += 2 + end + 2
+			continue
+		}
+
+		// The prelude runs up to the block or the ; ending an at-rule
+		preludeEnd := i
+		for preludeEnd < len(css) && css[preludeEnd] != '{' && css[preludeEnd] != ';' && css[preludeEnd] != '}' {
+			preludeEnd++
+		}
+		prelude := css[i:preludeEnd]
+		if preludeEnd == len(css) || css[preludeEnd] != '{' {
+			out.WriteString(css[i:min(preludeEnd+1, len(css))])
+			i = preludeEnd + 1
+			continue
+		}
+		blockEnd := cssBlockEnd(css, preludeEnd)
+		body := css[preludeEnd+1 : blockEnd]
+
+		trimmed := strings.TrimSpace(prelude)
+		switch {
+		case strings.HasPrefix(trimmed, "@media"), strings.HasPrefix(trimmed, "@supports"),
+			strings.HasPrefix(trimmed, "@container"), strings.HasPrefix(trimmed, "@layer"):
+			out.WriteString(prelude + "{" + scopeCSS(body, attr) + "}")
+		case strings.HasPrefix(trimmed, "@"):
+			out.WriteString(prelude + "{" + body + "}")
+		default:
+			leading := prelude[:len(prelude)-len(strings.TrimLeft(prelude, " \t\r\n"))]
+			selectors := splitSelectors(trimmed)
+			for index, selector := range selectors {
+				selectors[index] = scopeSelector(strings.TrimSpace(selector), attr)
+			}
+			out.WriteString(leading + strings.Join(selectors, ", ") + " {" + body + "}")
+		}
+		i = blockEnd + 1
+	}
+	return out.String()
+}
+
+// cssBlockEnd returns the offset of the } closing the block opened at i
+func cssBlockEnd(css string, i int) int {
+	depth := 0
+	for ; i < len(css); i++ {
+		switch c := css[i]; c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"', '\'':
+			for i++; i < len(css) && css[i] != c; i++ {
+				if css[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return len(css)
+}
+
+// splitSelectors splits a selector list at the commas outside of
+// parentheses and brackets
+func splitSelectors(list string) []string {
+	var selectors []string
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				selectors = append(selectors, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(selectors, list[start:])
+}
+
+// scopeSelector adds attr to the last compound of a selector, before its
+// pseudo-element if it has one
+func scopeSelector(selector, attr string) string {
+	// The last compound starts after the last combinator
+	start, depth := 0, 0
+	for i := 0; i < len(selector); i++ {
+		switch c := selector[i]; {
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0 && (c == ' ' || c == '>' || c == '+' || c == '~'):
+			start = i + 1
+		}
+	}
+	insert := len(selector)
+	if pseudo := strings.Index(selector[start:], "::"); pseudo >= 0 {
+		insert = start + pseudo
+	}
+	return selector[:insert] + attr + selector[insert:]
+}
@@ -0,0 +1,132 @@
+package build
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// compressibleExts are the file types worth precompressing; images, fonts
+// and archives are compressed already
+var compressibleExts = map[string]bool{
+	".wasm": true, ".js": true, ".mjs": true, ".css": true, ".html": true,
+	".json": true, ".svg": true, ".xml": true, ".txt": true, ".map": true,
+}
+
+// minCompressSize is the size below which compressing saves too little to
+// be worth a second request path
+const minCompressSize = 1024
+
+// precompress writes .gz and, when the brotli command is installed, .br
+// variants next to the compressible files of the output, which the
+// production server sends to clients accepting those encodings. Variants
+// that are not smaller than the original are dropped.
+func (b *Builder) precompress() error {
+	brotli, err := exec.LookPath("brotli")
+	if err != nil {
+		fmt.Println("⏭️  Skipping .br files: brotli not found in PATH (install brotli to emit them)")
+		brotli = ""
+	}
+
+	sourceDir := filepath.Join(b.config.Output, "src")
+	var original, gzipped, brotlied int64
+	err = filepath.Walk(b.config.Output, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == sourceDir {
+				return filepath.SkipDir // sources copied for the build, never served
+			}
+			return nil
+		}
+		if !compressibleExts[strings.ToLower(filepath.Ext(path))] || info.Size() < minCompressSize {
+			return nil
+		}
+
+		size, err := gzipFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to gzip %s: %w", path, err)
+		}
+		original += info.Size()
+		gzipped += keepIfSmaller(path+".gz", size, info.Size())
+
+		if brotli != "" {
+			size, err := brotliFile(brotli, path)
+			if err != nil {
+				return fmt.Errorf("failed to compress %s with brotli: %w", path, err)
+			}
+			brotlied += keepIfSmaller(path+".br", size, info.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if original > 0 {
+		line := fmt.Sprintf("precompressed: %s, %s gzip", FormatSize(original), FormatSize(gzipped))
+		if brotli != "" {
+			line += fmt.Sprintf(", %s brotli", FormatSize(brotlied))
+		}
+		b.note(line)
+	}
+	return nil
+}
+
+// keepIfSmaller removes a compressed variant that saves nothing and returns
+// the size a client downloads
+func keepIfSmaller(variant string, size, original int64) int64 {
+	if size >= original {
+		os.Remove(variant)
+		return original
+	}
+	return size
+}
+
+// gzipFile writes path.gz at the best compression and returns its size
+func gzipFile(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	writer, err := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// brotliFile writes path.br at the best quality and returns its size
+func brotliFile(brotli, path string) (int64, error) {
+	output, err := exec.Command(brotli, "--force", "--quality=11", "--output="+path+".br", path).CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	info, err := os.Stat(path + ".br")
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
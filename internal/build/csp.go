@@ -0,0 +1,136 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// inlineScripts and inlineStyles match the inline <script> and <style>
+// elements of a page, whose content the policy allows by hash
+var (
+	inlineScripts = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+	inlineStyles  = regexp.MustCompile(`(?is)<style\b[^>]*>(.*?)</style>`)
+	scriptSrc     = regexp.MustCompile(`(?i)\ssrc\s*=`)
+	headTag       = regexp.MustCompile(`(?i)<head\b[^>]*>`)
+	charsetTag    = regexp.MustCompile(`(?i)<meta\s+charset\b[^>]*>`)
+)
+
+// cspDirectives are the directives of the generated policy, in order
+var cspDirectives = []string{"default-src", "script-src", "style-src", "connect-src", "img-src", "object-src", "base-uri", "form-action"}
+
+// cspHash returns the CSP source allowing an inline element with content
+func cspHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+// ContentSecurityPolicy returns the policy of a page: scripts and styles
+// from the app's origin and the inline ones of html, WebAssembly
+// compilation, and calls to the app's origin and to serverURL, the
+// build.server of the app, when set. sources adds sources by directive.
+func ContentSecurityPolicy(html, serverURL string, sources map[string][]string) string {
+	policy := map[string][]string{
+		"default-src": {"'self'"},
+		"script-src":  {"'self'", "'wasm-unsafe-eval'"},
+		"style-src":   {"'self'"},
+		"connect-src": {"'self'"},
+		"img-src":     {"'self'", "data:"},
+		"object-src":  {"'none'"},
+		"base-uri":    {"'self'"},
+		"form-action": {"'self'"},
+	}
+	for _, match := range inlineScripts.FindAllStringSubmatch(html, -1) {
+		if !scriptSrc.MatchString(match[1]) {
+			policy["script-src"] = appendSource(policy["script-src"], cspHash(match[2]))
+		}
+	}
+	for _, match := range inlineStyles.FindAllStringSubmatch(html, -1) {
+		policy["style-src"] = appendSource(policy["style-src"], cspHash(match[1]))
+	}
+
+	// Server functions are called over HTTP and WebSocket
+	if parsed, err := url.Parse(serverURL); err == nil && parsed.Host != "" {
+		origin := parsed.Scheme + "://" + parsed.Host
+		policy["connect-src"] = appendSource(policy["connect-src"], origin)
+		if ws := strings.Replace(origin, "http", "ws", 1); ws != origin {
+			policy["connect-src"] = appendSource(policy["connect-src"], ws)
+		}
+	}
+
+	directives := append([]string(nil), cspDirectives...)
+	extra := make([]string, 0, len(sources))
+	for directive := range sources {
+		if _, ok := policy[directive]; !ok {
+			extra = append(extra, directive)
+		}
+	}
+	sort.Strings(extra)
+	directives = append(directives, extra...)
+
+	parts := make([]string, 0, len(directives))
+	for _, directive := range directives {
+		values := policy[directive]
+		for _, source := range sources[directive] {
+			values = appendSource(values, source)
+		}
+		parts = append(parts, strings.TrimSpace(directive+" "+strings.Join(values, " ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// appendSource adds source to sources unless it is already there
+func appendSource(sources []string, source string) []string {
+	for _, existing := range sources {
+		if existing == source {
+			return sources
+		}
+	}
+	return append(sources, source)
+}
+
+// applyCSP adds the policy of a page to it as a <meta> tag at the start of
+// its <head>, after the charset and before any script runs, and keeps the page for the header
+// policy
+func (b *Builder) applyCSP(html string) string {
+	if b.config.Build.CSP == nil {
+		return html
+	}
+	policy := ContentSecurityPolicy(html, b.config.Build.Server, b.config.Build.CSP.Sources)
+	b.pages = append(b.pages, html)
+
+	// The charset has to stay within the first bytes of the page
+	head := charsetTag.FindStringIndex(html)
+	if head == nil {
+		head = headTag.FindStringIndex(html)
+	}
+	if head == nil {
+		fmt.Println("   Warning: the page has no <head>, its policy is only sent as a header")
+		return html
+	}
+	meta := "\n    " + `<meta http-equiv="Content-Security-Policy" content="` + policy + `">`
+	return html[:head[1]] + meta + html[head[1]:]
+}
+
+// writeCSPHeader writes the policy allowing every generated page for
+// golem start to send as a header. The header also carries the directives
+// browsers ignore in a <meta> tag.
+func (b *Builder) writeCSPHeader() error {
+	policy := ContentSecurityPolicy(strings.Join(b.pages, "\n"), b.config.Build.Server, b.config.Build.CSP.Sources)
+	if _, ok := b.config.Build.CSP.Sources["frame-ancestors"]; !ok {
+		policy += "; frame-ancestors 'self'"
+	}
+	if err := os.WriteFile(filepath.Join(b.config.Output, server.CSPFile), []byte(policy+"\n"), 0644); err != nil {
+		return err
+	}
+	b.note(fmt.Sprintf("csp: %d inline scripts and styles allowed by hash", strings.Count(policy, "'sha256-")))
+	return nil
+}
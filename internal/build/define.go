@@ -0,0 +1,60 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// defineFlags returns the go build flags setting the string variables of
+// build.define, e.g. "main.Version" or "myapp/src/app.APIBaseURL", through
+// the linker's -X flag. Keys name the variable by its package import path.
+func defineFlags(cfg *config.Config) ([]string, error) {
+	if len(cfg.Build.Define) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(cfg.Build.Define))
+	for name := range cfg.Build.Define {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		dot := strings.LastIndex(name, ".")
+		if dot <= 0 || dot == len(name)-1 || strings.ContainsAny(name, "= \t") {
+			return nil, fmt.Errorf(`invalid build.define key %q, expected "<import path>.<variable>"`, name)
+		}
+		args = append(args, "-X", quoteFlag(name+"="+cfg.Build.Define[name]))
+	}
+	return []string{"-ldflags", strings.Join(args, " ")}, nil
+}
+
+// goBuildFlags returns the flags of every go build of the app, the chunks
+// and the server: the build.define variables, and for debug builds no
+// optimizations or inlining, so debuggers see the code as written
+func (b *Builder) goBuildFlags() ([]string, error) {
+	flags, err := defineFlags(b.config)
+	if err != nil {
+		return nil, err
+	}
+	if b.config.Build.Debug {
+		flags = append(flags, "-gcflags=all=-N -l")
+	}
+	return flags, nil
+}
+
+// quoteFlag quotes a word of -ldflags when it contains spaces or quotes, the
+// way the go command splits flag lists
+func quoteFlag(word string) string {
+	if !strings.ContainsAny(word, " \t\n\r'\"") {
+		return word
+	}
+	if !strings.Contains(word, "'") {
+		return "'" + word + "'"
+	}
+	return `"` + word + `"`
+}
@@ -0,0 +1,204 @@
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// DockerFile and DockerIgnoreFile are written to the project by golem build
+// --docker
+const (
+	DockerFile       = "Dockerfile"
+	DockerIgnoreFile = ".dockerignore"
+)
+
+// dockerHeader marks the files golem build --docker may rewrite. Files
+// without it are the project's own and are left alone.
+const dockerHeader = "# Generated by golem build --docker. Remove this line to keep golem from rewriting this file."
+
+// DefaultDockerRuntime is the base image of the runtime stage. It has no
+// shell or libc, which the statically linked server does not need, and
+// runs as an unprivileged user.
+const DefaultDockerRuntime = "gcr.io/distroless/static-debian12:nonroot"
+
+// dockerIgnore keeps the build outputs, the repository and the secrets out
+// of the build context; the image rebuilds the outputs anyway
+var dockerIgnore = []string{".git", ".golem", "node_modules", "golem.secrets*.env", DockerFile, DockerIgnoreFile}
+
+// Dockerfile returns a multi-stage Dockerfile for the app of cfg. The build
+// stage compiles the app with golem build --single-binary, applying
+// profile when set, using the golang image of goVersion. The runtime stage
+// only holds the server binary and exposes the HTTP and gRPC ports.
+func Dockerfile(cfg *config.Config, goVersion, profile string) string {
+	if goVersion == "" {
+		goVersion = "1"
+	}
+	runtime := cfg.Build.Docker.Runtime
+	if runtime == "" {
+		runtime = DefaultDockerRuntime
+	}
+	output := cfg.Output
+	if output == "" {
+		output = ".golem/build"
+	}
+	httpPort := cfg.Server.Port
+	if httpPort == 0 {
+		httpPort = server.HTTPPort
+	}
+	grpcPort := cfg.Server.GRPC.Port
+	if grpcPort == 0 {
+		grpcPort = server.GRPCPort
+	}
+
+	command := "go run -mod=mod github.com/Nu11ified/golem/cmd/golem build --single-binary"
+	if profile != "" {
+		command += " --profile " + profile
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, dockerHeader)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "# Build stage: app.wasm and the server binary with the app embedded")
+	fmt.Fprintf(&b, "FROM golang:%s AS build\n", goVersion)
+	fmt.Fprintln(&b, "WORKDIR /app")
+	fmt.Fprintln(&b, "ENV CGO_ENABLED=0")
+	fmt.Fprintln(&b, "COPY go.mod go.sum* ./")
+	fmt.Fprintln(&b, "RUN go mod download")
+	fmt.Fprintln(&b, "COPY . .")
+	fmt.Fprintf(&b, "RUN %s\n", command)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "# Runtime stage: the server binary alone")
+	fmt.Fprintf(&b, "FROM %s\n", runtime)
+	fmt.Fprintf(&b, "COPY --from=build %s /server\n", path.Join("/app", filepath.ToSlash(output), "server"))
+	fmt.Fprintf(&b, "EXPOSE %d %d\n", httpPort, grpcPort)
+	fmt.Fprintln(&b, `ENTRYPOINT ["/server"]`)
+	return b.String()
+}
+
+// DockerImage returns the tag of the image of cfg, build.docker.image or
+// <projectName>:<version>
+func DockerImage(cfg *config.Config) string {
+	if cfg.Build.Docker.Image != "" {
+		return cfg.Build.Docker.Image
+	}
+	version := cfg.Version
+	if version == "" {
+		version = "latest"
+	}
+	return strings.ToLower(cfg.ProjectName) + ":" + version
+}
+
+// WriteDocker writes the Dockerfile and .dockerignore of the app to the
+// project directory, keeping files the project wrote itself
+func WriteDocker(cfg *config.Config, profile string) error {
+	goVersion, replaced, err := readGoMod("go.mod")
+	if err != nil {
+		return fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	for _, module := range replaced {
+		fmt.Printf("   Warning: go.mod replaces %s with a local directory, which the image build cannot see\n", module)
+	}
+
+	written, err := writeGenerated(DockerFile, Dockerfile(cfg, goVersion, profile))
+	if err != nil {
+		return err
+	}
+	if written {
+		fmt.Printf("🐳 Wrote %s\n", DockerFile)
+	} else {
+		fmt.Printf("🐳 Keeping %s, which golem did not generate\n", DockerFile)
+	}
+
+	ignore := dockerHeader + "\n" + strings.Join(dockerIgnore, "\n") + "\n"
+	if _, err := writeGenerated(DockerIgnoreFile, ignore); err != nil {
+		return err
+	}
+	return nil
+}
+
+// BuildDockerImage builds the image of the project's Dockerfile with the
+// docker CLI, tagged with tag
+func BuildDockerImage(tag string) error {
+	docker, err := exec.LookPath("docker")
+	if err != nil {
+		return fmt.Errorf("docker not found in PATH, build the image with 'docker build -t %s .'", tag)
+	}
+	fmt.Printf("🐳 Building image %s...\n", tag)
+	cmd := exec.Command(docker, "build", "-t", tag, ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker build failed: %w", err)
+	}
+	return nil
+}
+
+// writeGenerated writes content to file unless the file exists without
+// dockerHeader, and reports whether it did
+func writeGenerated(file, content string) (bool, error) {
+	existing, err := os.ReadFile(file)
+	if err == nil && !strings.HasPrefix(string(existing), dockerHeader) {
+		return false, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return true, nil
+}
+
+// readGoMod returns the Go version of a go.mod file, e.g. "1.23", or of
+// its toolchain when set, and the modules it replaces with local
+// directories
+func readGoMod(file string) (string, []string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	// The golang image is tagged by minor version
+	minor := func(version string) string {
+		parts := strings.SplitN(strings.TrimSpace(version), ".", 3)
+		return strings.Join(parts[:min(len(parts), 2)], ".")
+	}
+
+	var version, toolchain string
+	var replaced []string
+	inReplace := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "go "):
+			version = minor(strings.TrimPrefix(line, "go "))
+		case strings.HasPrefix(line, "toolchain go"):
+			toolchain = minor(strings.TrimPrefix(line, "toolchain go"))
+		case line == "replace (":
+			inReplace = true
+		case inReplace && line == ")":
+			inReplace = false
+		case inReplace || strings.HasPrefix(line, "replace "):
+			from, to, ok := strings.Cut(strings.TrimPrefix(line, "replace "), "=>")
+			module, target := strings.Fields(from), strings.TrimSpace(to)
+			if ok && len(module) > 0 && (strings.HasPrefix(target, ".") || filepath.IsAbs(target)) {
+				replaced = append(replaced, module[0])
+			}
+		}
+	}
+	// The go command switches to the toolchain, so the image starts with it
+	if toolchain != "" {
+		version = toolchain
+	}
+	return version, replaced, scanner.Err()
+}
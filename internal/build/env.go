@@ -0,0 +1,166 @@
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// PublicEnvPrefix marks the environment variables compiled into the app.
+// Other variables are only visible to server functions.
+const PublicEnvPrefix = "GOLEM_PUBLIC_"
+
+// ServerURLEnv holds build.server, the URL of the server the app calls,
+// which the app reads as env.ServerUrl
+const ServerURLEnv = PublicEnvPrefix + "SERVER_URL"
+
+// EnvFile is the name of the generated file holding the public variables
+const EnvFile = "golem_env_gen.go"
+
+// LoadEnv loads .env and then .env.<mode> into the process environment, so
+// server functions see the variables through os.Getenv. Variables already
+// set in the environment win over the files. It returns the files loaded.
+func LoadEnv(mode string) ([]string, error) {
+	var loaded []string
+	values := make(map[string]string)
+	for _, file := range []string{".env", ".env." + mode} {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := ParseEnv(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		for key, value := range parsed {
+			values[key] = value
+		}
+		loaded = append(loaded, file)
+	}
+
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); !set {
+			os.Setenv(key, value)
+		}
+	}
+	return loaded, nil
+}
+
+// ParseEnv parses the KEY=VALUE lines of a .env file, as config.ParseEnv
+// does for the secrets files
+func ParseEnv(data string) (map[string]string, error) {
+	return config.ParseEnv(data)
+}
+
+// PublicEnv returns the GOLEM_PUBLIC_ variables of the process environment
+func PublicEnv() map[string]string {
+	public := make(map[string]string)
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(key, PublicEnvPrefix) && len(key) > len(PublicEnvPrefix) {
+			public[key] = value
+		}
+	}
+	return public
+}
+
+// EnvDir returns the directory of the generated env package
+func EnvDir(cfg *config.Config) string {
+	if cfg.Build.Env != "" {
+		return cfg.Build.Env
+	}
+	return "src/env"
+}
+
+// GenerateEnv writes the public variables as constants of the env package,
+// which the app imports to read them. Nothing is generated for a project
+// without public variables that has no env package yet. It returns the
+// path of the generated file, or "" when none was written.
+func GenerateEnv(cfg *config.Config) (string, error) {
+	dir := EnvDir(cfg)
+	file := filepath.Join(dir, EnvFile)
+	public := PublicEnv()
+	if _, err := os.Stat(file); len(public) == 0 && os.IsNotExist(err) {
+		return "", nil
+	}
+
+	source, err := generateEnvSource(filepath.Base(dir), public)
+	if err != nil {
+		return "", err
+	}
+	if existing, err := os.ReadFile(file); err == nil && bytes.Equal(existing, source) {
+		return file, nil // unchanged, so the app is not rebuilt
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, source, 0644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// generateEnvSource renders the env package
+func generateEnvSource(packageName string, public map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(public))
+	for key := range public {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by golem from the GOLEM_PUBLIC_ environment variables. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "// Package %s holds the GOLEM_PUBLIC_ environment variables of the build\n", packageName)
+	fmt.Fprintf(&src, "package %s\n\n", packageName)
+
+	if len(keys) > 0 {
+		src.WriteString("const (\n")
+		for _, key := range keys {
+			fmt.Fprintf(&src, "\t// %s is %s\n\t%s = %s\n", envConstName(key), key, envConstName(key), strconv.Quote(public[key]))
+		}
+		src.WriteString(")\n\n")
+	}
+
+	src.WriteString("// values maps the full name of each variable to its value\nvar values = map[string]string{\n")
+	for _, key := range keys {
+		fmt.Fprintf(&src, "\t%s: %s,\n", strconv.Quote(key), envConstName(key))
+	}
+	src.WriteString("}\n\n")
+	src.WriteString("// Get returns a variable by its full name, e.g. \"GOLEM_PUBLIC_API_URL\",\n// or \"\" when it was not set at build time\n")
+	src.WriteString("func Get(name string) string {\n\treturn values[name]\n}\n")
+
+	return format.Source(src.Bytes())
+}
+
+// envConstName converts GOLEM_PUBLIC_API_URL to ApiUrl
+func envConstName(key string) string {
+	var name strings.Builder
+	for _, word := range strings.Split(strings.TrimPrefix(key, PublicEnvPrefix), "_") {
+		word = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return -1
+		}, word)
+		if word == "" {
+			continue
+		}
+		name.WriteString(strings.ToUpper(word[:1]) + strings.ToLower(word[1:]))
+	}
+	result := name.String()
+	if result == "" || (result[0] >= '0' && result[0] <= '9') {
+		result = "Var" + result
+	}
+	return result
+}
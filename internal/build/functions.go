@@ -0,0 +1,28 @@
+package build
+
+import (
+	"os"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// GenerateFunctions writes the registration files of the project's server
+// functions and the main package of the server binary. It generates nothing
+// when the project has no server functions directory.
+func GenerateFunctions(cfg *config.Config) ([]functions.GeneratedPackage, error) {
+	serverDir := FunctionsDir(cfg)
+	if _, err := os.Stat(serverDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return functions.GenerateRegistrations(serverDir)
+}
+
+// FunctionsDir returns the directory of the project's server functions
+func FunctionsDir(cfg *config.Config) string {
+	if cfg.Server.Functions != "" {
+		return cfg.Server.Functions
+	}
+	return "src/server"
+}
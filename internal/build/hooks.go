@@ -0,0 +1,114 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// HookPhases are the build phases hooks run around, in the order golem
+// build runs them:
+//
+//   - build: the whole build, pre hooks run before the output is cleaned
+//   - sources: generating .golem components and copying src, e.g. to
+//     generate Go code such as compiled translations
+//   - compile: building the WebAssembly binaries, the server and the routes
+//   - pages: hashing the assets and writing the pages
+//   - public: copying the public directory, e.g. to generate icons into it
+//   - compress: precompressing the output
+var HookPhases = []string{"build", "sources", "compile", "pages", "public", "compress"}
+
+// Hook environment variables describe the build to hook commands
+const (
+	HookPhaseEnv   = "GOLEM_HOOK_PHASE"    // phase the hook runs around
+	HookStageEnv   = "GOLEM_HOOK_STAGE"    // "pre" or "post"
+	HookOutputEnv  = "GOLEM_OUTPUT"        // absolute path of the build output
+	HookProfileEnv = "GOLEM_BUILD_PROFILE" // build profile, "" without one
+)
+
+// validateHooks checks that every hook runs around a known phase and runs
+// exactly one command
+func validateHooks(cfg *config.Config) error {
+	phases := make([]string, 0, len(cfg.Build.Hooks))
+	for phase := range cfg.Build.Hooks {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	for _, phase := range phases {
+		if !knownPhase(phase) {
+			return fmt.Errorf("unknown build hook phase %q, expected one of %s", phase, strings.Join(HookPhases, ", "))
+		}
+		hooks := cfg.Build.Hooks[phase]
+		for _, hook := range append(append([]config.BuildHook(nil), hooks.Pre...), hooks.Post...) {
+			if (hook.Run == "") == (hook.Go == "") {
+				return fmt.Errorf("build hook of phase %s: set either run or go", phase)
+			}
+		}
+	}
+	return nil
+}
+
+// knownPhase reports whether phase is one of HookPhases
+func knownPhase(phase string) bool {
+	for _, known := range HookPhases {
+		if known == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// RunHooks runs the hooks of a phase of cfg in the project directory, in
+// order, stage being "pre" or "post". The first failing hook fails the
+// phase.
+func RunHooks(cfg *config.Config, phase, stage string) error {
+	hooks := cfg.Build.Hooks[phase].Pre
+	if stage == "post" {
+		hooks = cfg.Build.Hooks[phase].Post
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	output, err := filepath.Abs(cfg.Output)
+	if err != nil {
+		return err
+	}
+	env := append(os.Environ(),
+		HookPhaseEnv+"="+phase,
+		HookStageEnv+"="+stage,
+		HookOutputEnv+"="+output,
+		HookProfileEnv+"="+cfg.Build.Profile,
+		config.EnvVar+"="+cfg.Environment(), // for the sections of plugins
+	)
+	for _, hook := range hooks {
+		command := hook.Run
+		var cmd *exec.Cmd
+		switch {
+		case hook.Go != "":
+			command = "go run " + hook.Go
+			cmd = exec.Command("go", "run", hook.Go)
+		case runtime.GOOS == "windows":
+			cmd = exec.Command("cmd", "/C", hook.Run)
+		default:
+			cmd = exec.Command("sh", "-c", hook.Run)
+		}
+		cmd.Env = env
+
+		fmt.Printf("🪝 %s %s: %s\n", stage, phase, command)
+		out, err := cmd.CombinedOutput()
+		if len(out) > 0 {
+			fmt.Println("   " + strings.ReplaceAll(strings.TrimRight(string(out), "\n"), "\n", "\n   "))
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s hook %q failed: %w", stage, phase, command, err)
+		}
+	}
+	return nil
+}
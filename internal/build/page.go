@@ -0,0 +1,121 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// page holds the parts of the production page a project's template places,
+// e.g. {{.Title}} or {{.Loader}}
+type page struct {
+	Title     string            // project name
+	App       string            // name of the app of the page, "" for the main one
+	Base      string            // <base> tag of history mode apps, "" in hash mode
+	Preload   string            // <link rel="preload"> tags fetching the assets early
+	Head      string            // tags linking the web app manifest of PWA builds
+	WasmExec  string            // URL of wasm_exec.js
+	Wasm      string            // URL of app.wasm, or <app>.wasm
+	Loader    string            // scripts loading wasm_exec.js and running the app, and the chunk file names
+	Content   string            // markup shown in the app root until the app starts
+	Assets    map[string]string // content-hashed name of each generated asset
+	Integrity map[string]string // integrity value of each generated asset, e.g. "sha384-..."
+}
+
+// PageTemplate returns the path of the project's page template
+func PageTemplate(cfg *config.Config) string {
+	if cfg.Build.Template != "" {
+		return cfg.Build.Template
+	}
+	return "index.html"
+}
+
+// page returns the parts of the production page of the named app, "" for
+// the main one
+func (b *Builder) page(app, content string) page {
+	wasm := "app.wasm"
+	if app != "" {
+		wasm = app + ".wasm"
+	}
+	// App pages sit in directories of the output, below the assets
+	root := appRoot(b.config, app)
+	p := page{
+		Title:     b.config.ProjectName,
+		App:       app,
+		WasmExec:  root + b.asset("wasm_exec.js"),
+		Wasm:      root + b.asset(wasm),
+		Content:   content,
+		Assets:    b.assets,
+		Integrity: b.integrity,
+	}
+
+	// History-mode apps are served from nested paths, so anchor relative URLs
+	if b.config.Router.IsHistoryMode() {
+		base := b.config.Router.BaseHref()
+		if app != "" {
+			base += strings.TrimPrefix(b.config.AppPath(app), "/")
+		}
+		p.Base = `<base href="` + base + `">`
+	}
+
+	// The loader fetches the app with fetch(), so the preload has to be a
+	// CORS fetch too for the browser to reuse it, with the same integrity
+	wasmIntegrity, execIntegrity := b.integrityAttrs(wasm), b.integrityAttrs("wasm_exec.js")
+	if wasmIntegrity == "" {
+		wasmIntegrity = " crossorigin"
+	}
+	p.Preload = `<link rel="preload" href="` + p.Wasm + `" as="fetch" type="application/wasm"` + wasmIntegrity + `>
+    <link rel="preload" href="` + p.WasmExec + `" as="script"` + execIntegrity + `>`
+
+	fetchOptions := ""
+	if value, ok := b.integrity[wasm]; ok {
+		fetchOptions = `, { integrity: "` + value + `" }`
+	}
+	// Chunks and the service worker belong to the main app
+	chunks, pwa := "", ""
+	if app == "" {
+		p.Head = b.pwaHead()
+		chunks, pwa = b.chunkLoader(), b.pwaLoader()
+	}
+	p.Loader = chunks + `<script src="` + p.WasmExec + `"` + execIntegrity + `></script>
+    <script>
+        const go = new Go();
+        WebAssembly.instantiateStreaming(fetch("` + p.Wasm + `"` + fetchOptions + `), go.importObject)
+            .then((result) => {
+                go.run(result.instance);
+            });
+    </script>` + pwa
+	return p
+}
+
+// renderPageTemplate renders a project's page template. Templates that load
+// neither {{.Loader}} nor {{.Wasm}} get the loader before </body>, so the
+// app always starts, and templates without {{.Head}} get it before </head>.
+func renderPageTemplate(name, source string, p page) (string, error) {
+	tmpl, err := template.New(name).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	var html strings.Builder
+	if err := tmpl.Execute(&html, p); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	result := html.String()
+
+	if p.Head != "" && !strings.Contains(source, ".Head") {
+		if end := strings.Index(strings.ToLower(result), "</head>"); end >= 0 {
+			result = result[:end] + "    " + p.Head + "\n" + result[end:]
+		}
+	}
+	if !strings.Contains(source, ".Loader") && !strings.Contains(source, ".Wasm") {
+		if end := strings.LastIndex(strings.ToLower(result), "</body>"); end >= 0 {
+			result = result[:end] + p.Loader + "\n" + result[end:]
+		} else {
+			result += p.Loader
+		}
+	}
+	return result, nil
+}
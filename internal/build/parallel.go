@@ -0,0 +1,68 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Step is a step of the build that does not depend on the others running
+// alongside it. Run stops when its context is cancelled.
+type Step struct {
+	Name    string
+	Message string // printed when the step starts
+	Run     func(ctx context.Context) error
+}
+
+// jobs returns how many build steps may run at once
+func (b *Builder) jobs() int {
+	if b.config.Build.Jobs > 0 {
+		return b.config.Build.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// RunParallel runs steps on at most jobs goroutines. When a step fails, the
+// steps running are cancelled and those not started are skipped; the errors
+// of all failed steps are returned together, in the order of steps, without
+// those of the steps cancelled.
+func RunParallel(jobs int, steps []Step) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make([]error, len(steps))
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if step.Message != "" {
+				fmt.Println(step.Message)
+			}
+			if err := step.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				errs[i] = fmt.Errorf("%s: %w", step.Name, err)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// note adds a line to the build summary; steps running in parallel call it
+func (b *Builder) note(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.summary = append(b.summary, line)
+}
@@ -0,0 +1,66 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// PublicDir returns the directory of the files served at the web root as is
+func PublicDir(cfg *config.Config) string {
+	if cfg.Build.Public != "" {
+		return cfg.Build.Public
+	}
+	return "public"
+}
+
+// CopyPublic copies the public directory verbatim into dst, keeping file
+// names so the files are never fingerprinted and can be linked by fixed
+// URLs, like /robots.txt or /favicon.ico. Files already in dst were
+// generated by the build and win. It returns the paths copied, relative
+// to dst and slash separated.
+func CopyPublic(cfg *config.Config, dst string) ([]string, error) {
+	src := PublicDir(cfg)
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+
+	var copied []string
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dst, relPath)
+		if _, err := os.Stat(dstPath); err == nil {
+			fmt.Printf("Warning: %s is shadowed by the generated %s\n", path, filepath.ToSlash(relPath))
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dstPath, data, 0644); err != nil {
+			return err
+		}
+		copied = append(copied, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return copied, nil
+}
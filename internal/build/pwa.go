@@ -0,0 +1,229 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestFile is the web app manifest of PWA builds
+const ManifestFile = "manifest.webmanifest"
+
+// ServiceWorkerFile is the service worker of PWA builds. It keeps its name,
+// since browsers check the same URL for updates.
+const ServiceWorkerFile = "sw.js"
+
+// precachedExtensions are the files of the output the service worker
+// caches on install, so the app starts offline
+var precachedExtensions = map[string]bool{".html": true, ".js": true, ".wasm": true, ".css": true}
+
+// pwaHead returns the tags linking the web app manifest, or "" when
+// build.pwa is not set
+func (b *Builder) pwaHead() string {
+	pwa := b.config.Build.PWA
+	if pwa == nil {
+		return ""
+	}
+	head := `<link rel="manifest" href="` + ManifestFile + `">`
+	if pwa.ThemeColor != "" {
+		head += "\n    " + `<meta name="theme-color" content="` + pwa.ThemeColor + `">`
+	}
+	return head
+}
+
+// pwaLoader returns the script registering the service worker, or "" when
+// build.pwa is not set. Once a new version is installed it dispatches a
+// "golem:update" event on window, whose detail.apply() activates the new
+// version and reloads the page.
+func (b *Builder) pwaLoader() string {
+	if b.config.Build.PWA == nil {
+		return ""
+	}
+	return `
+    <script>
+        if ("serviceWorker" in navigator) {
+            const controlled = !!navigator.serviceWorker.controller;
+            let reloading = false;
+            navigator.serviceWorker.addEventListener("controllerchange", () => {
+                if (controlled && !reloading) {
+                    reloading = true;
+                    location.reload();
+                }
+            });
+            window.addEventListener("load", () => {
+                navigator.serviceWorker.register("` + ServiceWorkerFile + `").then((registration) => {
+                    const notify = (worker) => {
+                        window.dispatchEvent(new CustomEvent("golem:update", {
+                            detail: { apply: () => worker.postMessage("golem:skip-waiting") },
+                        }));
+                    };
+                    if (registration.waiting && controlled) {
+                        notify(registration.waiting);
+                    }
+                    registration.addEventListener("updatefound", () => {
+                        const worker = registration.installing;
+                        worker.addEventListener("statechange", () => {
+                            if (worker.state === "installed" && navigator.serviceWorker.controller) {
+                                notify(worker);
+                            }
+                        });
+                    });
+                });
+            });
+        }
+    </script>`
+}
+
+// generatePWA writes the web app manifest and the service worker, which
+// precaches the pages, scripts, stylesheets and WebAssembly of the output
+// and the manifest's icons
+func (b *Builder) generatePWA() error {
+	pwa := b.config.Build.PWA
+
+	manifest := map[string]interface{}{
+		"name":       pwa.Name,
+		"short_name": pwa.ShortName,
+		"start_url":  pwa.StartURL,
+		"scope":      ".",
+		"display":    pwa.Display,
+	}
+	if pwa.Name == "" {
+		manifest["name"] = b.config.ProjectName
+	}
+	if pwa.ShortName == "" {
+		manifest["short_name"] = manifest["name"]
+	}
+	if pwa.StartURL == "" {
+		manifest["start_url"] = "."
+	}
+	if pwa.Display == "" {
+		manifest["display"] = "standalone"
+	}
+	for key, value := range map[string]string{"description": pwa.Description, "theme_color": pwa.ThemeColor, "background_color": pwa.BackgroundColor} {
+		if value != "" {
+			manifest[key] = value
+		}
+	}
+	if len(pwa.Icons) > 0 {
+		manifest["icons"] = pwa.Icons
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(b.config.Output, ManifestFile), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	precache := []string{ManifestFile}
+	for _, icon := range pwa.Icons {
+		if _, err := os.Stat(filepath.Join(b.config.Output, filepath.FromSlash(icon.Src))); err != nil {
+			return fmt.Errorf("icon %s is not in the output, put it in %s/", icon.Src, PublicDir(b.config))
+		}
+		precache = append(precache, strings.TrimPrefix(icon.Src, "/"))
+	}
+	err = filepath.WalkDir(b.config.Output, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(b.config.Output, path)
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if rel == "src" {
+				return filepath.SkipDir // sources are only copied to build the app
+			}
+			return nil
+		}
+		if precachedExtensions[filepath.Ext(rel)] && rel != ServiceWorkerFile {
+			precache = append(precache, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(precache)
+
+	// The cache is named after the content of the precached files, so a
+	// deploy changing any of them installs as a new version
+	hash := sha256.New()
+	for _, name := range precache {
+		content, err := os.ReadFile(filepath.Join(b.config.Output, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s %d\n", name, len(content))
+		hash.Write(content)
+	}
+	version := hex.EncodeToString(hash.Sum(nil))[:8]
+
+	list, err := json.MarshalIndent(precache, "", "  ")
+	if err != nil {
+		return err
+	}
+	worker := "// Generated by golem build. DO NOT EDIT.\n" +
+		`const CACHE = "golem-` + version + "\";\nconst PRECACHE = " + string(list) + ";\n" + serviceWorker
+	if err := os.WriteFile(filepath.Join(b.config.Output, ServiceWorkerFile), []byte(worker), 0644); err != nil {
+		return err
+	}
+
+	b.note(fmt.Sprintf("pwa: %d files precached as version %s", len(precache), version))
+	return nil
+}
+
+// serviceWorker serves the precached files cache-first. Navigations fall
+// back to the network, and offline to the cached app shell. A new version
+// waits until the page asks it to take over.
+const serviceWorker = `const PRECACHED = new Set(PRECACHE.map((path) => new URL(path, self.registration.scope).href));
+
+self.addEventListener("install", (event) => {
+    event.waitUntil(caches.open(CACHE).then((cache) => cache.addAll(PRECACHE)));
+});
+
+self.addEventListener("activate", (event) => {
+    event.waitUntil(
+        caches.keys()
+            .then((keys) => Promise.all(keys.filter((key) => key.startsWith("golem-") && key !== CACHE).map((key) => caches.delete(key))))
+            .then(() => self.clients.claim())
+    );
+});
+
+self.addEventListener("message", (event) => {
+    if (event.data === "golem:skip-waiting") {
+        self.skipWaiting();
+    }
+});
+
+self.addEventListener("fetch", (event) => {
+    const request = event.request;
+    if (request.method !== "GET") {
+        return;
+    }
+    const url = new URL(request.url);
+    url.hash = "";
+
+    if (PRECACHED.has(url.href)) {
+        event.respondWith(caches.match(url.href, { cacheName: CACHE }).then((cached) => cached || fetch(request)));
+        return;
+    }
+    if (request.mode !== "navigate" || !url.href.startsWith(self.registration.scope)) {
+        return;
+    }
+
+    // Pages: prerendered ones from the cache, others from the network,
+    // and the app shell when offline
+    url.search = "";
+    const page = url.pathname.endsWith("/") ? url.href + "index.html" : url.href + "/index.html";
+    event.respondWith(
+        caches.match(page, { cacheName: CACHE }).then((cached) => cached ||
+            fetch(request).catch(() => caches.match(new URL("index.html", self.registration.scope).href, { cacheName: CACHE })
+                .then((shell) => shell || Promise.reject(new Error("offline")))))
+    );
+});
+`
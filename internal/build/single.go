@@ -0,0 +1,127 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// EmbedFile is the file of the server's main package embedding the build
+// output into single binaries
+const EmbedFile = "golem_embed_gen.go"
+
+// embedSource registers the embedded output and configuration with the
+// server before main runs
+const embedSource = `// Code generated by golem build --single-binary. DO NOT EDIT.
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/Nu11ified/golem/functions/host"
+)
+
+//go:embed all:assets
+var assets embed.FS
+
+//go:embed golem.config.json
+var config []byte
+
+func init() {
+	output, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	host.Embed(output, config)
+}
+`
+
+// buildSingleBinary builds the server binary with the build output and the
+// configuration of the build embedded, so it runs on its own
+func (b *Builder) buildSingleBinary() error {
+	serverDir := filepath.Dir(functions.HostMainFile)
+	if _, err := os.Stat(functions.HostMainFile); os.IsNotExist(err) {
+		// Without server functions the binary only serves the app
+		if err := functions.WriteHostMain(nil); err != nil {
+			return err
+		}
+	}
+
+	// go:embed only reaches files under the package directory
+	assetsDir := filepath.Join(serverDir, "assets")
+	configFile := filepath.Join(serverDir, "golem.config.json")
+	embedFile := filepath.Join(serverDir, EmbedFile)
+	defer os.RemoveAll(assetsDir)
+	defer os.Remove(configFile)
+	defer os.Remove(embedFile)
+
+	if err := os.RemoveAll(assetsDir); err != nil {
+		return err
+	}
+	err := filepath.Walk(b.config.Output, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(b.config.Output, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel == "src" {
+				return filepath.SkipDir // sources are only copied to build the app
+			}
+			return nil
+		}
+		if rel == "server" {
+			return nil
+		}
+		return b.copyFile(path, filepath.Join(assetsDir, rel))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy the output to embed: %w", err)
+	}
+
+	// Secrets stay out of the binary: settings keep their ${NAME}
+	// references, and a session secret from the environment of the build is
+	// left out. The server reads the variables where it runs.
+	embedded, err := b.config.WithoutSecrets()
+	if err != nil {
+		return err
+	}
+	if b.config.FromEnv("session.secret") {
+		embedded.Session.Secret = ""
+	}
+	cfg, err := json.MarshalIndent(embedded, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configFile, cfg, 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(embedFile, []byte(embedSource), 0644); err != nil {
+		return err
+	}
+
+	define, err := b.goBuildFlags()
+	if err != nil {
+		return err
+	}
+	outputPath := filepath.Join(b.config.Output, "server")
+	args := append([]string{"build", "-o", outputPath}, define...)
+	cmd := exec.Command("go", append(args, functions.HostMainFile, embedFile)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("server build failed: %v\nOutput: %s", err, output)
+	}
+
+	if info, err := os.Stat(outputPath); err == nil {
+		b.note("server: " + FormatSize(info.Size()) + " with the app embedded")
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nu11ified/golem/router"
+)
+
+// exportStatic writes the page of every route prerendered by the route
+// generator, so any static host serves each URL with its content before
+// the app starts and renders over it. History-mode apps also get a
+// 404.html, which static hosts serve for URLs without a page.
+func (b *Builder) exportStatic() error {
+	file := filepath.Join(b.config.Output, router.PrerenderFile)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("route generator wrote no %s: %w", router.PrerenderFile, err)
+	}
+	defer os.Remove(file)
+
+	var pages map[string]string
+	if err := json.Unmarshal(data, &pages); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", router.PrerenderFile, err)
+	}
+
+	history := b.config.Router.IsHistoryMode()
+	written := 0
+	for path, content := range pages {
+		if !history && path != "/" {
+			continue // every hash-mode URL loads index.html
+		}
+		html, err := b.renderPage("", content)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(b.config.Output, filepath.FromSlash(strings.Trim(path, "/")), "index.html")
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(html), 0644); err != nil {
+			return err
+		}
+		written++
+	}
+	if !history && len(pages) > 1 {
+		fmt.Println("   Hash routing: only / is prerendered, switch to history mode to prerender every route")
+	}
+
+	if history {
+		html, err := b.renderPage("", "")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(b.config.Output, "404.html"), []byte(html), 0644); err != nil {
+			return err
+		}
+	}
+
+	// Sources are only copied to build the app
+	if err := os.RemoveAll(filepath.Join(b.config.Output, "src")); err != nil {
+		return err
+	}
+
+	b.note(fmt.Sprintf("static: %d prerendered pages", written))
+	return nil
+}
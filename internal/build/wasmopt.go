@@ -0,0 +1,76 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// goWasmFeatures are the WebAssembly features the Go compiler emits, which
+// wasm-opt has to accept to validate app.wasm
+var goWasmFeatures = []string{"sign-ext", "nontrapping-float-to-int"}
+
+// optimizeWasm shrinks a WebAssembly binary with binaryen's wasm-opt when
+// wasm.optimizeSize is set, keeping the unoptimized binary when wasm-opt
+// is missing or fails. It returns the size before optimizing, or 0 when
+// the binary was left as is, and the error of ctx when the build was
+// cancelled.
+func (b *Builder) optimizeWasm(ctx context.Context, wasmPath string) (int64, error) {
+	if !b.config.Wasm.OptimizeSize {
+		return 0, nil
+	}
+
+	name := filepath.Base(wasmPath)
+	if b.config.Build.Debug {
+		fmt.Printf("⏭️  Skipping wasm-opt: debug builds keep the function names of %s\n", name)
+		return 0, nil
+	}
+	wasmOpt, err := exec.LookPath("wasm-opt")
+	if err != nil {
+		fmt.Printf("⏭️  Skipping wasm-opt: not found in PATH (install binaryen to shrink %s)\n", name)
+		return 0, nil
+	}
+
+	before, err := os.Stat(wasmPath)
+	if err != nil {
+		return 0, err
+	}
+
+	fmt.Printf("🪶 Optimizing %s with wasm-opt...\n", name)
+	optimized := wasmPath + ".opt"
+	args := []string{"-Oz", "--strip-debug", "--strip-producers"}
+	features := append(append([]string(nil), goWasmFeatures...), b.config.Wasm.EnableFeatures...)
+	for _, feature := range features {
+		args = append(args, "--enable-"+strings.TrimPrefix(feature, "--enable-"))
+	}
+	args = append(args, wasmPath, "-o", optimized)
+
+	output, err := exec.CommandContext(ctx, wasmOpt, args...).CombinedOutput()
+	if err != nil {
+		os.Remove(optimized)
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		fmt.Printf("Warning: wasm-opt failed, keeping the unoptimized %s: %v\n%s\n", name, err, strings.TrimSpace(string(output)))
+		return 0, nil
+	}
+	if err := os.Rename(optimized, wasmPath); err != nil {
+		return 0, fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+	return before.Size(), nil
+}
+
+// FormatSize formats a file size for the build summary and reports
+func FormatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f kB", float64(bytes)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
@@ -0,0 +1,261 @@
+package build
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// WebAssembly section ids
+const (
+	wasmCustomSection = 0
+	wasmImportSection = 2
+	wasmCodeSection   = 10
+)
+
+// wasmSectionNames name the sections of a WebAssembly binary in reports
+var wasmSectionNames = map[byte]string{
+	0: "custom", 1: "type", 2: "import", 3: "function", 4: "table", 5: "memory", 6: "global",
+	7: "export", 8: "start", 9: "element", 10: "code", 11: "data", 12: "data count",
+}
+
+// WasmSizes is how the bytes of a WebAssembly binary are spent
+type WasmSizes struct {
+	Sections  map[string]int64 // bytes per section, custom sections by name
+	Functions map[string]int64 // code bytes per function name, nil without a name section
+}
+
+// ParseWasmSizes measures the sections of a WebAssembly binary and, when
+// it has a name section, as Go binaries do until wasm-opt strips it, the
+// code of each function
+func ParseWasmSizes(data []byte) (*WasmSizes, error) {
+	if len(data) < 8 || string(data[:4]) != "\x00asm" {
+		return nil, errors.New("not a WebAssembly binary")
+	}
+	sizes := &WasmSizes{Sections: make(map[string]int64)}
+	var imported uint64 // function imports, which come before the functions of the code section
+	var bodies []int64
+	var names map[uint64]string
+
+	r := &wasmReader{data: data, pos: 8}
+	for r.pos < len(data) {
+		start := r.pos
+		id := r.byte()
+		size := r.uleb()
+		if r.err != nil || uint64(len(data)-r.pos) < size {
+			return nil, fmt.Errorf("truncated section at offset %d", start)
+		}
+		end := r.pos + int(size)
+		content := &wasmReader{data: data[:end], pos: r.pos}
+
+		name := wasmSectionNames[id]
+		switch id {
+		case wasmCustomSection:
+			custom := content.name()
+			name = "custom " + custom
+			if custom == "name" {
+				names = content.functionNames()
+			}
+		case wasmImportSection:
+			imported = content.functionImports()
+		case wasmCodeSection:
+			bodies = content.bodySizes()
+		}
+		if content.err != nil {
+			return nil, fmt.Errorf("%s section: %w", name, content.err)
+		}
+		if name == "" {
+			name = fmt.Sprintf("section %d", id)
+		}
+		sizes.Sections[name] += int64(end - start)
+		r.pos = end
+	}
+
+	if len(names) > 0 {
+		sizes.Functions = make(map[string]int64, len(bodies))
+		for i, size := range bodies {
+			if name, ok := names[imported+uint64(i)]; ok {
+				sizes.Functions[name] += size
+			} else {
+				sizes.Functions[OtherPackage] += size
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// OtherPackage collects the code no Go package is found for, such as the
+// runtime's assembly and the functions the linker generates
+const OtherPackage = "(other)"
+
+// wasmSymbolChars matches the characters the Go linker replaces with _ in
+// the function names of WebAssembly binaries
+var wasmSymbolChars = regexp.MustCompile(`[^\w.]`)
+
+// PackageSizes adds up the code of functions by Go package, from the
+// import paths of the packages linked in. The linker writes
+// "github.com/x/y.(*T).M" as "github.com_x_y.__T_.M", so names are matched
+// against the import paths written the same way.
+func PackageSizes(functions map[string]int64, packages []string) map[string]int64 {
+	paths := make(map[string]string, len(packages))
+	for _, pkg := range packages {
+		paths[wasmSymbolChars.ReplaceAllString(pkg, "_")] = pkg
+	}
+	sizes := make(map[string]int64)
+	for name, size := range functions {
+		pkg := OtherPackage
+		for i := len(name) - 1; i > 0; i-- {
+			if name[i] != '.' {
+				continue
+			}
+			if path, ok := paths[name[:i]]; ok {
+				pkg = path
+				break
+			}
+		}
+		sizes[pkg] += size
+	}
+	return sizes
+}
+
+// PackageSize is the code size of a Go package
+type PackageSize struct {
+	Package string `json:"package"`
+	Size    int64  `json:"size"`
+}
+
+// SortPackages returns packages from the largest to the smallest
+func SortPackages(packages map[string]int64) []PackageSize {
+	sorted := make([]PackageSize, 0, len(packages))
+	for pkg, size := range packages {
+		sorted = append(sorted, PackageSize{Package: pkg, Size: size})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Size != sorted[j].Size {
+			return sorted[i].Size > sorted[j].Size
+		}
+		return sorted[i].Package < sorted[j].Package
+	})
+	return sorted
+}
+
+// wasmReader decodes the values of a WebAssembly binary, keeping the
+// first error
+type wasmReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *wasmReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos >= len(r.data) {
+		r.err = errors.New("unexpected end")
+		return 0
+	}
+	r.pos++
+	return r.data[r.pos-1]
+}
+
+func (r *wasmReader) uleb() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.err = errors.New("invalid integer")
+		return 0
+	}
+	r.pos += n
+	return value
+}
+
+func (r *wasmReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.data)-r.pos) < n {
+		r.err = errors.New("unexpected end")
+		return nil
+	}
+	r.pos += int(n)
+	return r.data[r.pos-int(n) : r.pos]
+}
+
+func (r *wasmReader) name() string {
+	return string(r.bytes(r.uleb()))
+}
+
+// functionImports counts the function imports of an import section
+func (r *wasmReader) functionImports() uint64 {
+	var functions uint64
+	count := r.uleb()
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		r.name() // module
+		r.name() // field
+		switch kind := r.byte(); kind {
+		case 0: // function: type index
+			r.uleb()
+			functions++
+		case 1: // table: element type and limits
+			r.byte()
+			r.limits()
+		case 2: // memory: limits
+			r.limits()
+		case 3: // global: value type and mutability
+			r.byte()
+			r.byte()
+		case 4: // tag: attribute and type index
+			r.byte()
+			r.uleb()
+		default:
+			r.err = fmt.Errorf("unknown import kind %d", kind)
+		}
+	}
+	return functions
+}
+
+func (r *wasmReader) limits() {
+	flags := r.byte()
+	r.uleb()
+	if flags&1 != 0 {
+		r.uleb()
+	}
+}
+
+// bodySizes returns the size of each function body of a code section,
+// with its length prefix
+func (r *wasmReader) bodySizes() []int64 {
+	count := r.uleb()
+	var sizes []int64
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		start := r.pos
+		r.bytes(r.uleb())
+		sizes = append(sizes, int64(r.pos-start))
+	}
+	return sizes
+}
+
+// functionNames decodes the function names subsection of a name section
+func (r *wasmReader) functionNames() map[uint64]string {
+	for r.pos < len(r.data) && r.err == nil {
+		id := r.byte()
+		sub := &wasmReader{data: r.bytes(r.uleb())}
+		if r.err != nil || id != 1 {
+			continue
+		}
+		count := sub.uleb()
+		names := make(map[uint64]string, count)
+		for i := uint64(0); i < count && sub.err == nil; i++ {
+			index := sub.uleb()
+			names[index] = sub.name()
+		}
+		r.err = sub.err
+		return names
+	}
+	return nil
+}
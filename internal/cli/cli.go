@@ -1,40 +1,154 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/Nu11ified/golem/internal/build"
 	"github.com/Nu11ified/golem/internal/config"
 	"github.com/Nu11ified/golem/internal/dev"
+	"github.com/Nu11ified/golem/internal/doctor"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/gotest"
 	"github.com/Nu11ified/golem/internal/server"
 )
 
-// RunDev starts the development server with hot reload
-func RunDev() {
+// RunDev starts the development server with hot reload. Its flags override
+// the dev section of the config.
+func RunDev(args []string) {
+	flags := flag.NewFlagSet("dev", flag.ExitOnError)
+	port := flags.Int("port", 0, "port to serve on, the next free one is used when it is taken")
+	open := flags.Bool("open", false, "open the app in the browser once the server is ready")
+	mocks := flags.String("mocks", "", "answer server function calls from the JSON fixtures in this directory")
+	debug := flags.Bool("debug", false, "build the app without optimizations for browser debuggers")
+	env := flags.String("env", "", "environment whose golem.config.<env>.json is layered over the config (default $GOLEM_ENV or development)")
+	flags.Parse(args)
+
 	fmt.Println("🚀 Starting Golem development server...")
 
-	config, err := loadConfig()
+	// The config is loaded again when its files change, with the flags
+	// still overriding it
+	load := func() (*config.Config, error) {
+		config, err := loadConfig(*env, "development")
+		if err != nil {
+			return nil, err
+		}
+		if *port != 0 {
+			config.Dev.Port = *port
+		}
+		if *open {
+			config.Dev.Open = true
+		}
+		if *mocks != "" {
+			config.Dev.Mocks = *mocks
+		}
+		if *debug {
+			config.Dev.Debug = true
+		}
+		return config, nil
+	}
+
+	config, err := load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
-
-	devServer := dev.NewServer(config)
-	if err := devServer.Start(); err != nil {
+	if err := dev.Run(config, load); err != nil {
 		log.Fatalf("Failed to start dev server: %v", err)
 	}
 }
 
+// RunGenerate runs a code generator
+func RunGenerate(target string) {
+	if target != "functions" {
+		log.Fatalf("Unknown generator %q, expected: functions", target)
+	}
+
+	config, err := loadConfig("", "development")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	generated, err := build.GenerateFunctions(config)
+	if err != nil {
+		log.Fatalf("Failed to generate server functions: %v", err)
+	}
+
+	for _, pkg := range generated {
+		fmt.Printf("   %s: %d functions\n", filepath.Join(pkg.Dir, functions.GeneratedFile), len(pkg.Functions))
+	}
+	fmt.Printf("✅ Generated registrations for %d packages\n", len(generated))
+}
+
 // RunBuild builds the production-ready application
-func RunBuild() {
+func RunBuild(args []string) {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	static := flags.Bool("static", false, "prerender every route and leave out the server, for static hosts")
+	profile := flags.String("profile", "", "build profile of golem.config.json to apply, e.g. staging")
+	singleBinary := flags.Bool("single-binary", false, "embed the app into the server binary, deployable as one file")
+	noCache := flags.Bool("no-cache", false, "rebuild every step instead of reusing the outputs in .golem/cache")
+	jobs := flags.Int("jobs", 0, "build steps run at once (default the number of CPUs)")
+	debug := flags.Bool("debug", false, "build without optimizations and wasm-opt, to debug production builds")
+	docker := flags.Bool("docker", false, "write a multi-stage Dockerfile building the app into a minimal image")
+	dockerBuild := flags.Bool("docker-build", false, "write the Dockerfile and build the image with docker")
+	env := flags.String("env", "", "environment whose golem.config.<env>.json is layered over the config (default $GOLEM_ENV or production)")
+	flags.Parse(args)
+
 	fmt.Println("🔨 Building Golem application...")
 
-	config, err := loadConfig()
+	config, err := loadConfig(*env, "production")
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if *static {
+		config.Build.Static = true
+	}
+	if *singleBinary {
+		config.Build.SingleBinary = true
+	}
+	if *noCache {
+		config.Build.NoCache = true
+	}
+	if *jobs > 0 {
+		config.Build.Jobs = *jobs
+	}
+	if *debug {
+		config.Build.Debug = true
+	}
+	if *profile == "" {
+		*profile = config.Build.Profile
+	}
+	if *profile != "" {
+		if err := config.ApplyProfile(*profile); err != nil {
+			log.Fatalf("Failed to apply build profile: %v", err)
+		}
+		fmt.Printf("🎛️  Using build profile %s\n", *profile)
+	}
+
+	// The image builds the app itself, so nothing is built here
+	if *docker || *dockerBuild {
+		if config.Build.Static {
+			log.Fatalf("--docker builds a server image, deploy static builds to a static host")
+		}
+		if err := build.WriteDocker(config, *profile); err != nil {
+			log.Fatalf("Failed to write the Dockerfile: %v", err)
+		}
+		if *dockerBuild {
+			if err := build.BuildDockerImage(build.DockerImage(config)); err != nil {
+				log.Fatalf("Image build failed: %v", err)
+			}
+			fmt.Printf("✅ Built image %s\n", build.DockerImage(config))
+		}
+		return
+	}
 
 	builder := build.NewBuilder(config)
 	if err := builder.Build(); err != nil {
@@ -45,18 +159,337 @@ func RunBuild() {
 }
 
 // RunStart starts the production server
-func RunStart() {
+func RunStart(args []string) {
+	flags := flag.NewFlagSet("start", flag.ExitOnError)
+	env := flags.String("env", "", "environment whose golem.config.<env>.json is layered over the config (default $GOLEM_ENV or production)")
+	flags.Parse(args)
+
 	fmt.Println("🌟 Starting Golem production server...")
 
-	config, err := loadConfig()
+	// The server binary loads the config of the same environment
+	os.Setenv(config.EnvVar, config.Environment(*env, "production"))
+	config, err := loadConfig(*env, "production")
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Server functions read .env and .env.production through os.Getenv
+	if _, err := build.LoadEnv("production"); err != nil {
+		log.Fatalf("Failed to load env files: %v", err)
+	}
+
+	// Prefer the server binary built with the app's functions
+	binary := filepath.Join(config.Output, "server")
+	if _, err := os.Stat(binary); err == nil {
+		runHost(exec.Command(binary))
+		return
+	}
+	log.Printf("Warning: %s not found, serving without server functions. Run 'golem build' first.", binary)
+
 	prodServer := server.NewServer(config)
-	if err := prodServer.Start(); err != nil {
-		log.Fatalf("Failed to start production server: %v", err)
+	if err := prodServer.Run(); err != nil {
+		log.Fatalf("Production server failed: %v", err)
+	}
+}
+
+// RunConfig runs the config subcommands: print, the default, validate and
+// schema
+func RunConfig(args []string) {
+	command := "print"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "print":
+		printConfig(args)
+	case "validate":
+		validateConfig(args)
+	case "schema":
+		printSchema()
+	default:
+		log.Fatalf("Unknown config command %q, expected print, validate or schema", command)
+	}
+}
+
+// printConfig prints the config the other commands use: the project's file
+// with that of the environment layered over it, the GOLEM_ variables and
+// the defaults applied. Secrets are left out.
+func printConfig(args []string) {
+	flags := flag.NewFlagSet("config print", flag.ExitOnError)
+	env := flags.String("env", "", "environment whose golem.config.<env>.json is layered over the config (default $GOLEM_ENV)")
+	flags.Parse(args)
+
+	loaded, err := config.LoadProject(".", config.Environment(*env, ""))
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	// Settings show the secrets they reference, and values written in the
+	// file itself are hidden
+	cfg, err := loaded.WithoutSecrets()
+	if err != nil {
+		log.Fatalf("Failed to print config: %v", err)
+	}
+	if cfg.Session.Secret != "" && !strings.HasPrefix(cfg.Session.Secret, "${") {
+		cfg.Session.Secret = "<redacted>"
+	}
+	for name, value := range cfg.Server.Tracing.Headers {
+		if !strings.HasPrefix(value, "${") {
+			cfg.Server.Tracing.Headers[name] = "<redacted>"
+		}
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to print config: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "# %s\n", strings.Join(cfg.Files(), " + "))
+	fmt.Println(string(data))
+}
+
+// validateConfig checks the project's config against the schema, alone and
+// with the file of each environment layered over it, or only that of --env
+func validateConfig(args []string) {
+	flags := flag.NewFlagSet("config validate", flag.ExitOnError)
+	env := flags.String("env", "", "only validate the config of this environment")
+	flags.Parse(args)
+
+	envs := []string{""}
+	if *env != "" {
+		envs = []string{*env}
+	} else {
+		found, err := config.Environments(".")
+		if err != nil {
+			log.Fatalf("Failed to find config files: %v", err)
+		}
+		envs = append(envs, found...)
+	}
+
+	failed := false
+	for _, name := range envs {
+		cfg, err := config.LoadProject(".", name)
+		if errors.Is(err, config.ErrNotFound) {
+			log.Fatalf("golem.config.json not found. Run 'golem new <project-name>' to create a new project")
+		}
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✅ %s is valid\n", strings.Join(cfg.Files(), " + "))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// printSchema prints the JSON Schema of the config, as published at
+// config.SchemaURL
+func printSchema() {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(config.Schema()); err != nil {
+		log.Fatalf("Failed to print schema: %v", err)
+	}
+}
+
+// RunTest runs the project's tests: those built for this machine with go
+// test, and those only built for js/wasm compiled to WebAssembly and run in
+// Node or a headless browser. --wasm and --native only run one of them.
+// Packages and go test flags follow golem's own flags, e.g. "golem test
+// --runner browser ./dom -run TestRender". go test runs the WebAssembly
+// test binaries with "golem test --exec=<runner>".
+func RunTest(args []string) {
+	if len(args) > 0 && strings.HasPrefix(args[0], "--exec=") {
+		code, err := gotest.Exec(strings.TrimPrefix(args[0], "--exec="), args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(code)
+	}
+
+	// golem's flags come first, the rest are packages and go test flags
+	opts := gotest.Options{Native: true, Wasm: true}
+golemFlags:
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		switch name {
+		case "-runner", "--runner":
+			if !hasValue {
+				if len(args) < 2 {
+					log.Fatalf("--runner needs a value: %s or %s", gotest.RunnerNode, gotest.RunnerBrowser)
+				}
+				value, args = args[1], args[1:]
+			}
+			opts.Runner = value
+		case "-wasm", "--wasm":
+			opts.Native = false
+		case "-native", "--native":
+			opts.Wasm = false
+		default:
+			break golemFlags
+		}
+		args = args[1:]
 	}
+	opts.Args = args
+	if !opts.Native && !opts.Wasm {
+		log.Fatalf("--wasm and --native leave no tests to run")
+	}
+
+	golem, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the golem binary: %v", err)
+	}
+	opts.Exec = golem + " test --exec"
+
+	if err := gotest.Run(opts); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✅ All tests passed")
+}
+
+// RunAnalyze reports the size of the last build: its WebAssembly binaries
+// by Go package, the stylesheets of its components and every file it
+// serves, compared with the build before it
+func RunAnalyze(args []string) {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	top := flags.Int("top", 10, "packages listed per WebAssembly binary")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	maxGrowth := flags.Float64("max-growth", 0, "exit with status 1 when the build grew by more than this percentage since the previous one")
+	env := flags.String("env", "", "environment whose golem.config.<env>.json is layered over the config (default $GOLEM_ENV or production)")
+	flags.Parse(args)
+
+	config, err := loadConfig(*env, "production")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	report, err := build.Analyze(config)
+	if err != nil {
+		log.Fatalf("Failed to analyze the build: %v", err)
+	}
+	if err := build.StoreReport(report); err != nil {
+		log.Fatalf("Failed to store the report: %v", err)
+	}
+	previous, err := build.PreviousReport()
+	if err != nil {
+		log.Fatalf("Failed to load the previous report: %v", err)
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to print the report: %v", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printReport(config.Output, report, previous, *top)
+	}
+
+	if *maxGrowth > 0 && previous != nil && previous.AssetSize() > 0 {
+		growth := 100 * float64(report.AssetSize()-previous.AssetSize()) / float64(previous.AssetSize())
+		if growth > *maxGrowth {
+			fmt.Printf("❌ The build grew by %.1f%%, more than the %.1f%% allowed\n", growth, *maxGrowth)
+			os.Exit(1)
+		}
+	}
+}
+
+// printReport prints a size report, with what changed since previous when
+// there is one
+func printReport(output string, report, previous *build.Report, top int) {
+	fmt.Printf("📊 Size of the build in %s\n", output)
+
+	for _, wasm := range report.Wasm {
+		fmt.Printf("\n🧱 %s (%s): %s\n", wasm.Name, wasm.File, build.FormatSize(wasm.Size))
+		fmt.Printf("   code %s, data %s\n", build.FormatSize(wasm.Sections["code"]), build.FormatSize(wasm.Sections["data"]))
+		if len(wasm.Packages) == 0 {
+			fmt.Println("   No package sizes recorded, run 'golem build' to record them")
+			continue
+		}
+		packages := build.SortPackages(wasm.Packages)
+		for i, pkg := range packages {
+			if i == top {
+				var rest int64
+				for _, pkg := range packages[top:] {
+					rest += pkg.Size
+				}
+				fmt.Printf("   %-50s %10s\n", fmt.Sprintf("%d more packages", len(packages)-top), build.FormatSize(rest))
+				break
+			}
+			fmt.Printf("   %-50s %10s\n", pkg.Package, build.FormatSize(pkg.Size))
+		}
+	}
+
+	fmt.Printf("\n🎨 Component CSS: %s\n", build.FormatSize(report.CSSSize()))
+	for _, css := range report.CSS {
+		fmt.Printf("   %-50s %10s\n", css.Component, build.FormatSize(css.Size))
+	}
+
+	var gzipped int64
+	fmt.Printf("\n🗂️  Assets: %d files, %s\n", len(report.Assets), build.FormatSize(report.AssetSize()))
+	for _, asset := range report.Assets {
+		line := fmt.Sprintf("   %-50s %10s", asset.File, build.FormatSize(asset.Size))
+		if asset.Gzip > 0 {
+			line += "  gzip " + build.FormatSize(asset.Gzip)
+			gzipped += asset.Gzip
+		} else {
+			gzipped += asset.Size
+		}
+		if asset.Brotli > 0 {
+			line += "  br " + build.FormatSize(asset.Brotli)
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("   %-50s %10s  gzip %s\n", "total", build.FormatSize(report.AssetSize()), build.FormatSize(gzipped))
+
+	if previous == nil {
+		fmt.Println("\nNo previous build to compare with, the next analyze compares with this one")
+		return
+	}
+	changes := build.CompareReports(previous, report)
+	fmt.Printf("\n📈 Since the previous build (%s):\n", previous.Time.Format("2006-01-02 15:04"))
+	if len(changes) == 0 {
+		fmt.Println("   No size changes")
+		return
+	}
+	for i, change := range changes {
+		if i == top {
+			fmt.Printf("   %d more changes\n", len(changes)-top)
+			break
+		}
+		delta := change.Delta()
+		sign := "+"
+		if delta < 0 {
+			sign, delta = "-", -delta
+		}
+		line := fmt.Sprintf("   %-50s %10s → %-10s %s%s", change.Name, build.FormatSize(change.Before), build.FormatSize(change.After), sign, build.FormatSize(delta))
+		if change.Before > 0 {
+			line += fmt.Sprintf(" (%+.1f%%)", 100*float64(change.Delta())/float64(change.Before))
+		}
+		fmt.Println(line)
+	}
+}
+
+// RunDoctor checks the environment golem needs and prints the fix of each
+// problem. It exits with status 1 when golem cannot build or serve apps.
+func RunDoctor(args []string) {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	flags.Parse(args)
+
+	fmt.Println("🩺 Checking the Golem environment...")
+	checks := doctor.Run(".")
+	for _, check := range checks {
+		fmt.Printf("%s %s: %s\n", check.Status, check.Name, check.Detail)
+		if check.Status != doctor.OK && check.Fix != "" {
+			fmt.Printf("   → %s\n", check.Fix)
+		}
+	}
+	if doctor.Failed(checks) {
+		fmt.Println("❌ Fix the problems above to build and serve Golem apps")
+		os.Exit(1)
+	}
+	fmt.Println("✅ Ready to build Golem apps")
 }
 
 // RunNew creates a new Golem project
@@ -72,13 +505,49 @@ func RunNew(projectName string) {
 	fmt.Printf("   golem dev\n")
 }
 
-func loadConfig() (*config.Config, error) {
-	configPath := "golem.config.json"
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("golem.config.json not found. Run 'golem new <project-name>' to create a new project")
+// runHost runs the server binary in the foreground and exits with its
+// status. SIGINT and SIGTERM are passed on to the server, which shuts down
+// gracefully, and golem waits for it to exit.
+func runHost(cmd *exec.Cmd) {
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	if err := cmd.Start(); err != nil {
+		log.Fatalf("Failed to run server: %v", err)
 	}
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
 
-	return config.Load(configPath)
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		log.Fatalf("Failed to run server: %v", err)
+	}
+}
+
+// loadConfig loads the project's config, with the file of the environment
+// named by the --env flag, GOLEM_ENV or fallback layered over it
+func loadConfig(env, fallback string) (*config.Config, error) {
+	cfg, err := config.LoadProject(".", config.Environment(env, fallback))
+	if errors.Is(err, config.ErrNotFound) {
+		return nil, fmt.Errorf("golem.config.json not found. Run 'golem new <project-name>' to create a new project")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if files := cfg.Files(); len(files) > 1 {
+		fmt.Printf("⚙️  Config: %s\n", strings.Join(files, " + "))
+	}
+	return cfg, nil
 }
 
 func createProject(projectName string) error {
@@ -96,6 +565,7 @@ func createProject(projectName string) error {
 		filepath.Join(projectName, "src", "app"),
 		filepath.Join(projectName, "src", "components"),
 		filepath.Join(projectName, "src", "server"),
+		filepath.Join(projectName, "public"),
 	}
 
 	for _, dir := range dirs {
@@ -3,6 +3,8 @@ package cli
 import (
 	"os"
 	"path/filepath"
+
+	"github.com/Nu11ified/golem/internal/config"
 )
 
 // createTemplateFiles creates the initial template files for a new project
@@ -14,6 +16,7 @@ func createTemplateFiles(projectName string) error {
 		"src/app/main.go":          getMainGolemTemplate(projectName),
 		"src/components/Button.go": getButtonComponentTemplate(),
 		"src/server/hello.go":      getServerFunctionTemplate(),
+		"public/robots.txt":        "User-agent: *\nAllow: /\n",
 		".gitignore":               getGitignoreTemplate(),
 		"README.md":                getReadmeTemplate(projectName),
 		"LICENSE":                  getLicenseTemplate(),
@@ -31,6 +34,7 @@ func createTemplateFiles(projectName string) error {
 
 func getConfigTemplate(projectName string) string {
 	return `{
+  "$schema": "` + config.SchemaURL + `",
   "projectName": "` + projectName + `",
   "version": "0.1.0",
   "entry": "src/app/main.go",
@@ -55,6 +59,10 @@ func getConfigTemplate(projectName string) string {
   "wasm": {
     "optimizeSize": true,
     "enableFeatures": ["bulk-memory", "mutable-globals"]
+  },
+  "router": {
+    "mode": "hash",
+    "base": "/"
   }
 }`
 }
@@ -233,6 +241,9 @@ func getGitignoreTemplate() string {
 .golem/
 golem
 
+# Secrets referenced from golem.config.json as ${NAME}
+golem.secrets*.env
+
 # Go
 vendor/
 *.exe
@@ -267,7 +278,11 @@ Thumbs.db
 *.swo
 
 # Logs
-*.log`
+*.log
+
+# Local environment, may hold secrets
+.env.local
+.env.*.local`
 }
 
 func getReadmeTemplate(projectName string) string {
@@ -2,45 +2,332 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 // Config represents the Golem project configuration
 type Config struct {
-	ProjectName string       `json:"projectName"`
-	Version     string       `json:"version"`
-	Entry       string       `json:"entry"`
-	Output      string       `json:"output"`
-	Dev         DevConfig    `json:"dev"`
-	Build       BuildConfig  `json:"build"`
-	Server      ServerConfig `json:"server"`
-	Wasm        WasmConfig   `json:"wasm"`
+	ProjectName string        `json:"projectName"`
+	Version     string        `json:"version"`
+	Entry       string        `json:"entry"`
+	Output      string        `json:"output"`
+	Dev         DevConfig     `json:"dev"`
+	Build       BuildConfig   `json:"build"`
+	Server      ServerConfig  `json:"server"`
+	Wasm        WasmConfig    `json:"wasm"`
+	Router      RouterConfig  `json:"router"`
+	CORS        CORSConfig    `json:"cors"`
+	Session     SessionConfig `json:"session"`
+
+	Apps     map[string]AppConfig    `json:"apps"`     // apps built and served next to the main one, keyed by name, e.g. "admin"
+	Profiles map[string]BuildProfile `json:"profiles"` // build settings per environment, selected with golem build --profile
+
+	Extra map[string]json.RawMessage `json:"plugins"` // settings of build hooks and other tools, by plugin name, read with DecodeSection
+
+	env         map[string]string   // GOLEM_ variables applied, by setting path
+	files       []string            // files the configuration was loaded from, base first
+	environment string              // environment whose file was layered over the base
+	secrets     map[string]string   // values of the secrets files, by name
+	references  map[string]string   // settings referencing secrets as written, by path
+	missing     map[string][]string // secrets referenced but not set, by setting path
+}
+
+// CORSConfig sets which other origins may call the /api/ endpoints from a
+// browser. Without origins, the production server only serves the app's own
+// origin and the dev server every origin.
+type CORSConfig struct {
+	Origins     []string `json:"origins"`     // e.g. "https://app.example.com", "https://*.example.com" or "*"
+	Methods     []string `json:"methods"`     // default GET, POST and OPTIONS
+	Headers     []string `json:"headers"`     // request headers allowed, default those the browser asks for
+	Credentials bool     `json:"credentials"` // let browsers send cookies and Authorization, not with "*"
+	MaxAge      int      `json:"maxAge"`      // seconds browsers cache a preflight, default 600
+}
+
+// SessionConfig sets where the sessions of server functions are kept and
+// how their cookie is sent. A store set with functions.SetSessionStore, such
+// as a Redis one, replaces store.
+type SessionConfig struct {
+	Store    string `json:"store"`    // "memory" (default) or "file"
+	Dir      string `json:"dir"`      // directory of the file store, default .golem/sessions
+	Secret   string `json:"secret"`   // signs the cookie, at least 32 characters, default $GOLEM_SESSION_SECRET
+	Cookie   string `json:"cookie"`   // cookie name, default golem_session
+	MaxAge   string `json:"maxAge"`   // duration a session lasts after it last changed, default 24h
+	Secure   *bool  `json:"secure"`   // HTTPS-only cookie, default whether the request was made over HTTPS
+	SameSite string `json:"sameSite"` // "lax" (default), "strict" or "none"
+}
+
+// AppConfig describes an app built and served next to the main one, e.g.
+// an admin panel. It gets its own WebAssembly binary and page.
+type AppConfig struct {
+	Entry string `json:"entry"` // main file, under src, e.g. src/admin/main.go
+	Path  string `json:"path"`  // URL prefix the app is served under, default /<name>/
+}
+
+// EntryFile returns the main file of the main app
+func (c *Config) EntryFile() string {
+	if c.Entry != "" {
+		return c.Entry
+	}
+	return "src/app/main.go"
+}
+
+// AppNames returns the names of the apps in apps, sorted
+func (c *Config) AppNames() []string {
+	names := make([]string, 0, len(c.Apps))
+	for name := range c.Apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AppPath returns the URL prefix of the named app, starting and ending in
+// a slash, e.g. "/admin/"
+func (c *Config) AppPath(name string) string {
+	prefix := strings.Trim(c.Apps[name].Path, "/")
+	if prefix == "" {
+		prefix = name
+	}
+	return "/" + prefix + "/"
+}
+
+// AppPaths returns the URL prefixes of the apps, in the order of AppNames
+func (c *Config) AppPaths() []string {
+	paths := make([]string, 0, len(c.Apps))
+	for _, name := range c.AppNames() {
+		paths = append(paths, c.AppPath(name))
+	}
+	return paths
+}
+
+// FallbackEnabled reports whether unknown paths load the page of their app,
+// server.fallback.enabled or whether the router uses history mode
+func (c *Config) FallbackEnabled() bool {
+	if c.Server.Fallback.Enabled != nil {
+		return *c.Server.Fallback.Enabled
+	}
+	return c.Router.IsHistoryMode()
 }
 
 // DevConfig holds development server configuration
 type DevConfig struct {
-	Port      int      `json:"port"`
-	HotReload bool     `json:"hotReload"`
-	Watch     []string `json:"watch"`
+	Port        int        `json:"port"`
+	HotReload   bool       `json:"hotReload"`
+	Watch       []string   `json:"watch"`
+	Ignore      []string   `json:"ignore"`      // globs the watcher skips, added to .golem/, node_modules/, tests and editor temp files
+	HTTPS       bool       `json:"https"`       // serve over TLS, for secure contexts
+	TLS         *TLSConfig `json:"tls"`         // certificate for https, generated when not set
+	Open        bool       `json:"open"`        // open the app in the browser on start
+	QuietAssets bool       `json:"quietAssets"` // leave static assets out of the request log
+	Template    string     `json:"template"`    // template of the dev page, default index.dev.html
+
+	Faults map[string]FaultConfig `json:"faults"` // keyed by "service.function", "*" for every function
+	Mocks  string                 `json:"mocks"`  // directory of JSON fixtures answering function calls instead of the server package
+	Debug  bool                   `json:"debug"`  // build the app without optimizations for browser debuggers
+}
+
+// FaultConfig injects latency and failures into server function calls
+// during development
+type FaultConfig struct {
+	Delay     string  `json:"delay"`     // duration added before each call, e.g. "500ms"
+	Jitter    string  `json:"jitter"`    // random extra delay up to this duration
+	ErrorRate float64 `json:"errorRate"` // fraction of calls that fail, 0 to 1
+	ErrorCode string  `json:"errorCode"` // code of the injected errors, default "unavailable"
 }
 
 // BuildConfig holds build configuration
 type BuildConfig struct {
-	Minify    bool   `json:"minify"`
-	Target    string `json:"target"`
-	Sourcemap bool   `json:"sourcemap"`
+	Minify       bool                  `json:"minify"`
+	Target       string                `json:"target"`
+	Sourcemap    bool                  `json:"sourcemap"`
+	Routes       string                `json:"routes"`       // package run to emit sitemap.xml and routes.json
+	Env          string                `json:"env"`          // package generated with the GOLEM_PUBLIC_ variables, default src/env
+	Public       string                `json:"public"`       // directory served at the web root and copied as is, default public
+	Template     string                `json:"template"`     // template of the production page, default index.html
+	Static       bool                  `json:"static"`       // prerender the routes and leave out the server, for static hosts
+	Define       map[string]string     `json:"define"`       // string variables set at link time, keyed by "<import path>.<variable>"
+	Server       string                `json:"server"`       // URL of the server the app calls, compiled in as GOLEM_PUBLIC_SERVER_URL
+	Profile      string                `json:"profile"`      // profile applied when golem build gets no --profile
+	Chunks       map[string]string     `json:"chunks"`       // WebAssembly chunks loaded by lazy routes, name to main package directory under src
+	PWA          *PWAConfig            `json:"pwa"`          // web app manifest and offline service worker, off when not set
+	SingleBinary bool                  `json:"singleBinary"` // embed the output into the server binary
+	NoCache      bool                  `json:"noCache"`      // rebuild every step instead of reusing .golem/cache
+	Jobs         int                   `json:"jobs"`         // build steps run at once, default the number of CPUs
+	Debug        bool                  `json:"debug"`        // build without optimizations and wasm-opt, for debuggers
+	CSP          *CSPConfig            `json:"csp"`          // Content-Security-Policy of the production page, off when not set
+	Docker       DockerConfig          `json:"docker"`       // image written by golem build --docker
+	Hooks        map[string]BuildHooks `json:"hooks"`        // commands run before and after a build phase, keyed by phase
+}
+
+// BuildHooks are the commands run before and after a build phase
+type BuildHooks struct {
+	Pre  []BuildHook `json:"pre"`
+	Post []BuildHook `json:"post"`
+}
+
+// BuildHook is a command run by golem build: a shell command, or a Go
+// package run with go run, which may import the project's packages
+type BuildHook struct {
+	Run string `json:"run"` // e.g. "npx svgo -f assets/icons"
+	Go  string `json:"go"`  // e.g. "./tools/i18n"
+}
+
+// DockerConfig sets up the Dockerfile golem build --docker writes
+type DockerConfig struct {
+	Image   string `json:"image"`   // tag of the image built by --docker-build, default <projectName>:<version>
+	Runtime string `json:"runtime"` // base image of the runtime stage, default gcr.io/distroless/static-debian12:nonroot
+}
+
+// CSPConfig extends the Content-Security-Policy golem build generates for
+// the production page
+type CSPConfig struct {
+	Sources map[string][]string `json:"sources"` // extra sources keyed by directive, e.g. "img-src": ["https://cdn.example.com"]
+}
+
+// PWAConfig describes the app in its web app manifest, which makes it
+// installable
+type PWAConfig struct {
+	Name            string    `json:"name"` // default projectName
+	ShortName       string    `json:"shortName"`
+	Description     string    `json:"description"`
+	ThemeColor      string    `json:"themeColor"`
+	BackgroundColor string    `json:"backgroundColor"`
+	Display         string    `json:"display"`  // default standalone
+	StartURL        string    `json:"startUrl"` // default the base of the app
+	Icons           []PWAIcon `json:"icons"`    // files of the public directory
+}
+
+// PWAIcon is an icon of the web app manifest
+type PWAIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes,omitempty"` // e.g. "192x192"
+	Type    string `json:"type,omitempty"`  // e.g. "image/png"
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// BuildProfile overrides the build settings for one environment, e.g.
+// "staging". Fields left out keep the value of the build section.
+type BuildProfile struct {
+	Output    string            `json:"output"`
+	Define    map[string]string `json:"define"` // merged over build.define
+	Minify    *bool             `json:"minify"`
+	Sourcemap *bool             `json:"sourcemap"`
+	Target    string            `json:"target"`
+	Server    string            `json:"server"`
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	GRPC      GRPCConfig `json:"grpc"`
-	Functions string     `json:"functions"`
+	Host              string                    `json:"host"` // address the production servers bind, default every interface
+	Port              int                       `json:"port"` // HTTP port of the production server, default 8080; PORT overrides it
+	GRPC              GRPCConfig                `json:"grpc"`
+	Functions         string                    `json:"functions"`
+	Limits            map[string]FunctionLimits `json:"limits"`            // keyed by "service.function"
+	IdempotencyWindow string                    `json:"idempotencyWindow"` // duration results of keyed calls are kept, "0" disables
+	Docs              bool                      `json:"docs"`              // serve the OpenAPI document and /api/docs in production
+	Metrics           bool                      `json:"metrics"`           // serve Prometheus metrics at /metrics in production
+	ShutdownTimeout   string                    `json:"shutdownTimeout"`   // duration in-flight requests get to finish on SIGINT or SIGTERM, default 30s
+	Cache             CacheConfig               `json:"cache"`             // Cache-Control headers of the production server
+	Fallback          FallbackConfig            `json:"fallback"`          // deep links answered with the page of their app
+	Log               LogConfig                 `json:"log"`               // structured logs of the production server
+	RateLimit         RateLimitConfig           `json:"rateLimit"`         // calls per client IP to /api/functions
+	MaxBodySize       int64                     `json:"maxBodySize"`       // bytes of an /api/ request body, default 4 MB, -1 for no limit
+	Proxy             []ProxyRule               `json:"proxy"`             // other backends served under a path prefix
+	TLS               *TLSConfig                `json:"tls"`               // serve HTTPS, and with it HTTP/2, with this certificate
+	H2C               bool                      `json:"h2c"`               // accept HTTP/2 without TLS, e.g. from a proxy speaking h2c
+	Preload           *bool                     `json:"preload"`           // send the preload tags of pages as Link headers, default true
+	EarlyHints        bool                      `json:"earlyHints"`        // send the Link headers of pages in a 103 Early Hints response first
+	Tracing           TracingConfig             `json:"tracing"`           // OpenTelemetry traces of requests and function calls
+}
+
+// TracingConfig exports OpenTelemetry traces of the production server to
+// an OTLP collector. The standard OTEL_EXPORTER_OTLP_* variables apply.
+type TracingConfig struct {
+	Enabled     bool              `json:"enabled"`
+	Protocol    string            `json:"protocol"`    // "http/protobuf" (default) or "grpc"
+	Endpoint    string            `json:"endpoint"`    // collector URL, default http://localhost:4318, or :4317 with grpc
+	Headers     map[string]string `json:"headers"`     // sent with every export, e.g. the API key of a tracing service
+	SampleRatio *float64          `json:"sampleRatio"` // share of traces started here that are recorded, default 1
+	ServiceName string            `json:"serviceName"` // default projectName
+}
+
+// ProxyRule forwards the requests under a path prefix to another backend,
+// such as an auth service or a legacy API
+type ProxyRule struct {
+	Path        string `json:"path"`        // URL path prefix, e.g. "/auth/"
+	Target      string `json:"target"`      // upstream URL, e.g. "http://localhost:9000"
+	StripPrefix bool   `json:"stripPrefix"` // forward /auth/login as /login
+	Timeout     string `json:"timeout"`     // duration the upstream gets to answer, default 30s
+}
+
+// RateLimitConfig limits how fast each client may call the server
+// functions. Clients over the limit get 429 Too Many Requests.
+type RateLimitConfig struct {
+	Requests   int    `json:"requests"`   // calls per window, 0 disables the limit
+	Window     string `json:"window"`     // duration, default "1m"
+	Burst      int    `json:"burst"`      // calls a client may make at once, default requests
+	TrustProxy bool   `json:"trustProxy"` // identify clients by the X-Forwarded-For of a reverse proxy
+}
+
+// LogConfig sets the format and level of the production server's logs
+type LogConfig struct {
+	Format   string `json:"format"`   // "text" (default) or "json", one object per line for log aggregation
+	Level    string `json:"level"`    // "debug", "info" (default), "warn" or "error"
+	Requests *bool  `json:"requests"` // log every HTTP request, default true
+}
+
+// FallbackConfig sets which requests the servers answer with the page of
+// the app, so deep links to client-side routes load it instead of a 404.
+// /api/ and paths with an extension, such as a missing .js file, never are.
+type FallbackConfig struct {
+	Enabled *bool    `json:"enabled"` // default on with history routing, off with hash routing
+	Exclude []string `json:"exclude"` // URL paths that 404 when missing, "/files/" for a prefix or patterns like "/docs/*"
+}
+
+// CacheConfig sets the Cache-Control header the production server sends
+// for each kind of response. Empty values keep the defaults.
+type CacheConfig struct {
+	Assets string            `json:"assets"` // content-hashed assets, default "public, max-age=31536000, immutable"
+	Pages  string            `json:"pages"`  // HTML pages, default "no-cache"
+	Files  string            `json:"files"`  // other static files, such as public ones, default "no-cache"
+	API    string            `json:"api"`    // server function responses, default "no-store"
+	Paths  map[string]string `json:"paths"`  // values for URL paths matching a pattern, e.g. "/fonts/*", over the others
+}
+
+// FunctionLimits bounds the execution of a server function
+type FunctionLimits struct {
+	Timeout       string `json:"timeout"` // duration, e.g. "5s"
+	MaxConcurrent int    `json:"maxConcurrent"`
 }
 
 // GRPCConfig holds gRPC server configuration
 type GRPCConfig struct {
-	Port       int  `json:"port"`
-	Reflection bool `json:"reflection"`
+	Port           int            `json:"port"`
+	Reflection     bool           `json:"reflection"`     // expose the reflection service for grpcurl, evans, ...
+	MaxRecvMsgSize int            `json:"maxRecvMsgSize"` // bytes, default 4MB
+	MaxSendMsgSize int            `json:"maxSendMsgSize"` // bytes
+	Keepalive      *GRPCKeepalive `json:"keepalive"`
+	TLS            *TLSConfig     `json:"tls"`
+}
+
+// GRPCKeepalive configures gRPC connection keepalive; durations like "30s"
+type GRPCKeepalive struct {
+	Time                string `json:"time"`              // ping idle clients after this long
+	Timeout             string `json:"timeout"`           // close if a ping is not answered in time
+	MaxConnectionIdle   string `json:"maxConnectionIdle"` // close idle connections
+	MinTime             string `json:"minTime"`           // minimum interval allowed between client pings
+	PermitWithoutStream bool   `json:"permitWithoutStream"`
+}
+
+// TLSConfig points to a certificate and its private key in PEM files
+type TLSConfig struct {
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
 }
 
 // WasmConfig holds WebAssembly build configuration
@@ -49,17 +336,164 @@ type WasmConfig struct {
 	EnableFeatures []string `json:"enableFeatures"`
 }
 
-// Load loads the configuration from a JSON file
-func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+// RouterConfig holds client-side routing configuration
+type RouterConfig struct {
+	Mode string `json:"mode"` // "hash" (default) or "history"
+	Base string `json:"base"` // base path the app is served under in history mode
+}
+
+// IsHistoryMode reports whether the app uses HTML5 history routing,
+// which requires the server to fall back to index.html for deep links
+func (r RouterConfig) IsHistoryMode() bool {
+	return strings.EqualFold(r.Mode, "history")
+}
+
+// BaseHref returns the value for the <base href> tag, always ending in a slash
+func (r RouterConfig) BaseHref() string {
+	base := strings.Trim(r.Base, "/")
+	if base == "" {
+		return "/"
+	}
+	return "/" + base + "/"
+}
+
+// ApplyProfile overrides the build settings with those of the named profile
+func (c *Config) ApplyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for known := range c.Profiles {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown build profile %q, golem.config.json defines no profiles", name)
+		}
+		return fmt.Errorf("unknown build profile %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+
+	if profile.Output != "" {
+		c.Output = profile.Output
+	}
+	if len(profile.Define) > 0 {
+		define := make(map[string]string, len(c.Build.Define)+len(profile.Define))
+		for name, value := range c.Build.Define {
+			define[name] = value
+		}
+		for name, value := range profile.Define {
+			define[name] = value
+		}
+		c.Build.Define = define
+	}
+	if profile.Minify != nil {
+		c.Build.Minify = *profile.Minify
+	}
+	if profile.Sourcemap != nil {
+		c.Build.Sourcemap = *profile.Sourcemap
+	}
+	if profile.Target != "" {
+		c.Build.Target = profile.Target
+	}
+	if profile.Server != "" {
+		c.Build.Server = profile.Server
+	}
+	c.Build.Profile = name
+	return nil
+}
+
+// Defaults of the fields whose zero value the servers and the build cannot
+// use as is
+const (
+	DefaultOutput   = ".golem/build"
+	DefaultDevPort  = 3000
+	DefaultGRPCPort = 50051
+)
+
+// Load loads the configuration from a JSON, YAML or TOML file, as its
+// extension says, with the files of overlays layered over it in order.
+// Settings reference the secrets of the environment variables and of the
+// golem.secrets.env next to the file as ${NAME}.
+func Load(path string, overlays ...string) (*Config, error) {
+	return load(path, overlays, "")
+}
+
+// load loads the configuration like Load does, with the secrets files of
+// env
+func load(path string, overlays []string, env string) (*Config, error) {
+	secrets, err := LoadSecrets(filepath.Dir(path), env)
 	if err != nil {
 		return nil, err
 	}
+	data, err := readJSON(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(overlays) > 0 {
+		data, err = mergeFiles(path, data, overlays)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := append([]string{path}, overlays...)
+	config, err := parse(data, secrets)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", strings.Join(files, " + "), err)
+	}
+	config.files = files
+	return config, nil
+}
+
+// Parse parses a JSON configuration, e.g. one embedded into a binary, and
+// overrides its settings with the GOLEM_ environment variables. Secrets are
+// read from the environment variables their ${NAME} references name. Keys
+// no setting reads, values of the wrong type and invalid settings are all
+// reported at once in a *ValidationError.
+func Parse(data []byte) (*Config, error) {
+	return parse(data, nil)
+}
 
+// parse parses a JSON configuration like Parse does, with the values of
+// the secrets files
+func parse(data []byte, secrets map[string]string) (*Config, error) {
+	found, err := checkJSON(data)
+	if err != nil {
+		return nil, err
+	}
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, err
+		// checkJSON reported the values of the wrong type with their path,
+		// and the others are still decoded
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return nil, err
+		}
 	}
 
+	found = append(found, config.resolveSecrets(secrets)...)
+	found = append(found, config.applyEnv(os.LookupEnv)...)
+	config.applyDefaults()
+	for _, problem := range config.validate() {
+		// Settings waiting for their secret are checked once it is set
+		if !found.covers(problem.Path) && !config.missingSecret(problem.Path) {
+			found = append(found, problem)
+		}
+	}
+	if err := found.err(); err != nil {
+		return nil, err
+	}
 	return &config, nil
 }
+
+// applyDefaults fills in the documented defaults of the settings left out
+func (c *Config) applyDefaults() {
+	if c.Output == "" {
+		c.Output = DefaultOutput
+	}
+	if c.Dev.Port == 0 {
+		c.Dev.Port = DefaultDevPort
+	}
+	if c.Server.GRPC.Port == 0 {
+		c.Server.GRPC.Port = DefaultGRPCPort
+	}
+}
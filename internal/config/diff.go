@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Changes returns the paths of the settings that differ between two
+// configurations, sorted, e.g. ["dev.port", "server.proxy"]. Lists are
+// compared as a whole and objects setting by setting.
+func Changes(old, new *Config) []string {
+	var changed []string
+	diffSettings("", settingsOf(old), settingsOf(new), &changed)
+	return changed
+}
+
+// settingsOf returns the settings of c as decoded JSON
+func settingsOf(c *Config) interface{} {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil
+	}
+	var settings interface{}
+	json.Unmarshal(data, &settings)
+	return settings
+}
+
+// diffSettings appends the paths under path where a and b differ
+func diffSettings(path string, a, b interface{}, changed *[]string) {
+	aObject, aIsObject := a.(map[string]interface{})
+	bObject, bIsObject := b.(map[string]interface{})
+	if !aIsObject || !bIsObject {
+		if !reflect.DeepEqual(a, b) {
+			*changed = append(*changed, path)
+		}
+		return
+	}
+
+	keys := make(map[string]bool, len(aObject)+len(bObject))
+	for key := range aObject {
+		keys[key] = true
+	}
+	for key := range bObject {
+		keys[key] = true
+	}
+	for _, key := range sortedKeys(keys) {
+		diffSettings(joinPath(path, key), aObject[key], bObject[key], changed)
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix starts the environment variables overriding settings of
+// golem.config.json. The rest of a variable's name is the JSON path of its
+// setting in upper snake case: GOLEM_DEV_PORT sets dev.port and
+// GOLEM_SERVER_GRPC_PORT server.grpc.port.
+const EnvPrefix = "GOLEM_"
+
+// EnvName returns the environment variable overriding the setting at path,
+// e.g. GOLEM_SERVER_SHUTDOWN_TIMEOUT for "server.shutdownTimeout"
+func EnvName(path string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	previous := rune(0)
+	for _, r := range path {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && (unicode.IsLower(previous) || unicode.IsDigit(previous)):
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		previous = r
+	}
+	return b.String()
+}
+
+// applyEnv overrides the settings of c with the variables lookup finds for
+// them. Lists take comma-separated values and maps of strings
+// comma-separated key=value pairs; lists and maps of sections, such as
+// server.proxy, can only be set in the file. Empty variables are ignored.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) problems {
+	env := &envOverrides{lookup: lookup, paths: make(map[string]string)}
+	env.applyStruct(reflect.ValueOf(c).Elem(), "")
+	c.env = env.paths
+	return env.found
+}
+
+// FromEnv reports whether an environment variable overrode the setting at
+// path, e.g. "session.secret"
+func (c *Config) FromEnv(path string) bool {
+	_, ok := c.env[path]
+	return ok
+}
+
+// envOverrides applies the variables lookup finds to a configuration
+type envOverrides struct {
+	lookup func(string) (string, bool)
+	found  problems
+	paths  map[string]string // variables applied by setting path
+}
+
+// applyStruct overrides the fields of the struct v at path, reporting
+// whether a variable set one of them
+func (e *envOverrides) applyStruct(v reflect.Value, path string) bool {
+	set := false
+	for name, field := range jsonFields(v.Type()) {
+		if e.applyField(v.FieldByIndex(field.Index), joinPath(path, name)) {
+			set = true
+		}
+	}
+	return set
+}
+
+// applyField overrides the setting v at path with its variable
+func (e *envOverrides) applyField(v reflect.Value, path string) bool {
+	t := v.Type()
+	switch {
+	case t.Kind() == reflect.Struct:
+		return e.applyStruct(v, path)
+	case t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct:
+		// Optional sections only appear when a variable sets one of their
+		// settings
+		section := reflect.New(t.Elem())
+		if !v.IsNil() {
+			section.Elem().Set(v.Elem())
+		}
+		if !e.applyStruct(section.Elem(), path) {
+			return false
+		}
+		v.Set(section)
+		return true
+	}
+
+	name := EnvName(path)
+	value, ok := e.lookup(name)
+	if !ok || value == "" {
+		return false
+	}
+
+	target := v
+	if t.Kind() == reflect.Pointer {
+		target = reflect.New(t.Elem()).Elem()
+	}
+	if err := setEnvValue(target, value); err != nil {
+		e.found.add(path, "%s=%q %v", name, value, err)
+		return false
+	}
+	if t.Kind() == reflect.Pointer {
+		v.Set(target.Addr())
+	}
+	e.paths[path] = name
+	return true
+}
+
+// setEnvValue parses value into the setting v
+func setEnvValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("is not true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not an integer")
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("is not a number")
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot be set from the environment, set it in golem.config.json")
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot be set from the environment, set it in golem.config.json")
+		}
+		entries := make(map[string]string)
+		for _, pair := range strings.Split(value, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, item, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return fmt.Errorf("is not a list of key=value pairs")
+			}
+			entries[strings.TrimSpace(key)] = strings.TrimSpace(item)
+		}
+		v.Set(reflect.ValueOf(entries))
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the names the project configuration may have, in the order
+// they are looked for. The extension picks the format: JSON, YAML or TOML,
+// the latter two allowing comments.
+var FileNames = []string{"golem.config.json", "golem.config.yaml", "golem.config.yml", "golem.config.toml"}
+
+// ErrNotFound is returned by Find in a directory without a configuration
+var ErrNotFound = errors.New("no golem.config.json, golem.config.yaml or golem.config.toml found")
+
+// Find returns the path of the configuration file of the project in dir. A
+// directory with several of them is an error, since only one would apply.
+func Find(dir string) (string, error) {
+	var found []string
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", ErrNotFound
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("found both %s, keep only one", strings.Join(found, " and "))
+}
+
+// toJSON converts a YAML or TOML configuration, named by its path, to the
+// JSON Parse reads, so every format is checked the same way
+func toJSON(path string, data []byte) ([]byte, error) {
+	var document interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		document = stringKeys(document)
+	case ".toml":
+		var table map[string]interface{}
+		if _, err := toml.Decode(string(data), &table); err != nil {
+			return nil, err
+		}
+		document = table
+	default:
+		return data, nil
+	}
+	return json.Marshal(document)
+}
+
+// stringKeys converts the mappings of a YAML document with keys other than
+// strings, such as numbers, to objects JSON can hold
+func stringKeys(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = stringKeys(item)
+		}
+		return value
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			object[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return object
+	case []interface{}:
+		for i, item := range value {
+			value[i] = stringKeys(item)
+		}
+		return value
+	}
+	return value
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// EnvVar selects the environment whose configuration file, such as
+// golem.config.production.json, is layered over the base one
+const EnvVar = "GOLEM_ENV"
+
+// environmentName is the form of environment names, which are part of file
+// names
+var environmentName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Environment returns the environment selected by name, a command-line
+// flag, or else by GOLEM_ENV, or else fallback
+func Environment(name, fallback string) string {
+	if name != "" {
+		return name
+	}
+	if env := os.Getenv(EnvVar); env != "" {
+		return env
+	}
+	return fallback
+}
+
+// FindEnv returns the path of the configuration file of env in dir, e.g.
+// golem.config.staging.yaml, or "" when the environment has none
+func FindEnv(dir, env string) (string, error) {
+	if !environmentName.MatchString(env) {
+		return "", fmt.Errorf("invalid environment %q, use letters, digits, - and _", env)
+	}
+	var found []string
+	for _, name := range FileNames {
+		ext := filepath.Ext(name)
+		path := filepath.Join(dir, strings.TrimSuffix(name, ext)+"."+env+ext)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("found both %s, keep only one", strings.Join(found, " and "))
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// Environments returns the environments with a configuration file in dir,
+// sorted by name
+func Environments(dir string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, name := range FileNames {
+		ext := filepath.Ext(name)
+		paths, err := filepath.Glob(filepath.Join(dir, strings.TrimSuffix(name, ext)+".*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			env := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), strings.TrimSuffix(name, ext)+"."), ext)
+			if environmentName.MatchString(env) {
+				seen[env] = true
+			}
+		}
+	}
+	return sortedKeys(seen), nil
+}
+
+// LoadProject loads the configuration of the project in dir, with the file
+// of env layered over it when there is one
+func LoadProject(dir, env string) (*Config, error) {
+	path, err := Find(dir)
+	if err != nil {
+		return nil, err
+	}
+	var overlays []string
+	if env != "" {
+		overlay, err := FindEnv(dir, env)
+		if err != nil {
+			return nil, err
+		}
+		if overlay != "" {
+			overlays = append(overlays, overlay)
+		}
+	}
+
+	config, err := load(path, overlays, env)
+	if err != nil {
+		return nil, err
+	}
+	config.environment = env
+	return config, nil
+}
+
+// Files returns the files the configuration was loaded from, the base one
+// first
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// Environment returns the environment the configuration was loaded for,
+// "" when none was selected
+func (c *Config) Environment() string {
+	return c.environment
+}
+
+// readJSON reads a configuration file as JSON, whatever its format
+func readJSON(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = toJSON(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// mergeFiles layers the files of overlays over base, the JSON of the file
+// at path
+func mergeFiles(path string, base []byte, overlays []string) ([]byte, error) {
+	merged, err := decodeLayer(path, base)
+	if err != nil {
+		return nil, err
+	}
+	for _, overlay := range overlays {
+		data, err := readJSON(overlay)
+		if err != nil {
+			return nil, err
+		}
+		layer, err := decodeLayer(overlay, data)
+		if err != nil {
+			return nil, err
+		}
+		merged = merge(merged, layer)
+	}
+	return json.Marshal(merged)
+}
+
+// decodeLayer decodes the JSON of the file at path, keeping its numbers as
+// written
+func decodeLayer(path string, data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var layer interface{}
+	if err := decoder.Decode(&layer); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, syntaxError(data, err))
+	}
+	return layer, nil
+}
+
+// merge layers overlay over base. Objects are merged key by key, all the
+// way down; any other value of overlay, arrays included, replaces that of
+// base. A null in overlay removes the setting, which then takes its
+// default.
+func merge(base, overlay interface{}) interface{} {
+	baseObject, ok := base.(map[string]interface{})
+	overlayObject, overlayIsObject := overlay.(map[string]interface{})
+	if overlay == nil {
+		return base
+	}
+	if !ok || !overlayIsObject {
+		return overlay
+	}
+
+	merged := make(map[string]interface{}, len(baseObject)+len(overlayObject))
+	for key, value := range baseObject {
+		merged[key] = value
+	}
+	for key, value := range overlayObject {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = merge(merged[key], value)
+	}
+	return merged
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DecodeSection decodes plugins.<name>, the settings a plugin keeps in the
+// configuration, into target, a pointer to a struct of them with json tags.
+// Keys target has no field for and values of the wrong type are reported
+// at their path, like those of the rest of the configuration. target is
+// left as is when the configuration has no such section.
+func (c *Config) DecodeSection(name string, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("cannot decode plugins.%s into %T, expected a pointer", name, target)
+	}
+	data, ok := c.Extra[name]
+	if !ok || string(data) == "null" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var section interface{}
+	if err := decoder.Decode(&section); err != nil {
+		return err
+	}
+	path := joinPath("plugins", name)
+	var found problems
+	checkSchema(section, schemaOf(v.Type().Elem(), path, nil), path, false, &found)
+	if err := found.err(); err != nil {
+		if len(c.files) > 0 {
+			return fmt.Errorf("%s: %w", strings.Join(c.files, " + "), err)
+		}
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
@@ -0,0 +1,375 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaURL is where the JSON Schema of the configuration is published, for
+// the "$schema" key of golem.config.json
+const SchemaURL = "https://raw.githubusercontent.com/Nu11ified/golem/main/golem.schema.json"
+
+// JSONSchema is the subset of JSON Schema (draft 2020-12) describing the
+// configuration. Editors use it for completion and checks; Parse and
+// Validate check configurations against it.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"` // schema of the values of a map
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Format               string                 `json:"format,omitempty"` // "duration" for Go durations, "uri"
+	Pattern              string                 `json:"pattern,omitempty"`
+	MinLength            int                    `json:"minLength,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+
+	closed bool // objects without other properties than Properties
+}
+
+// MarshalJSON writes "additionalProperties": false for closed objects
+func (s *JSONSchema) MarshalJSON() ([]byte, error) {
+	type plain JSONSchema
+	if !s.closed {
+		return json.Marshal((*plain)(s))
+	}
+	return json.Marshal(struct {
+		*plain
+		AdditionalProperties bool `json:"additionalProperties"`
+	}{(*plain)(s), false})
+}
+
+// durationPattern matches Go durations such as 500ms, 30s or 1h30m
+const durationPattern = `^(0|([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+$`
+
+// constraints restrict the values of settings beyond their type, by path;
+// "*" stands for any key of a map or item of a list
+var constraints = map[string]func(*JSONSchema){
+	"projectName":      required,
+	"dev.port":         between(0, 65535),
+	"server.port":      between(0, 65535),
+	"server.grpc.port": between(0, 65535),
+	"router.mode":      oneOf("hash", "history"),
+	"apps.*.entry":     required,
+	"build.jobs":       atLeast(0),
+
+	"dev.faults.*.delay":     duration,
+	"dev.faults.*.jitter":    duration,
+	"dev.faults.*.errorRate": between(0, 1),
+	"dev.tls.certFile":       required,
+	"dev.tls.keyFile":        required,
+
+	"server.shutdownTimeout":                  duration,
+	"server.idempotencyWindow":                duration,
+	"server.limits.*.timeout":                 duration,
+	"server.limits.*.maxConcurrent":           atLeast(0),
+	"server.grpc.maxRecvMsgSize":              atLeast(0),
+	"server.grpc.maxSendMsgSize":              atLeast(0),
+	"server.grpc.keepalive.time":              duration,
+	"server.grpc.keepalive.timeout":           duration,
+	"server.grpc.keepalive.maxConnectionIdle": duration,
+	"server.grpc.keepalive.minTime":           duration,
+	"server.grpc.tls.certFile":                required,
+	"server.grpc.tls.keyFile":                 required,
+	"server.tls.certFile":                     required,
+	"server.tls.keyFile":                      required,
+	"server.log.format":                       oneOf("text", "json"),
+	"server.log.level":                        oneOf("debug", "info", "warn", "error"),
+	"server.rateLimit.requests":               atLeast(0),
+	"server.rateLimit.burst":                  atLeast(0),
+	"server.rateLimit.window":                 duration,
+	"server.maxBodySize":                      atLeast(-1),
+	"server.proxy.*.path":                     required,
+	"server.proxy.*.target":                   func(s *JSONSchema) { required(s); s.Format = "uri" },
+	"server.proxy.*.timeout":                  duration,
+	"server.tracing.protocol":                 oneOf("http/protobuf", "grpc"),
+	"server.tracing.sampleRatio":              between(0, 1),
+
+	"cors.maxAge":      atLeast(0),
+	"session.store":    oneOf("memory", "file"),
+	"session.sameSite": oneOf("lax", "strict", "none"),
+	"session.maxAge":   duration,
+	"session.secret":   func(s *JSONSchema) { s.MinLength = 32 },
+}
+
+func required(s *JSONSchema) { s.MinLength = 1 }
+
+func duration(s *JSONSchema) { s.Format, s.Pattern = "duration", durationPattern }
+
+func oneOf(values ...string) func(*JSONSchema) {
+	return func(s *JSONSchema) { s.Enum = values }
+}
+
+func atLeast(minimum float64) func(*JSONSchema) {
+	return func(s *JSONSchema) { s.Minimum = &minimum }
+}
+
+func between(minimum, maximum float64) func(*JSONSchema) {
+	return func(s *JSONSchema) { s.Minimum, s.Maximum = &minimum, &maximum }
+}
+
+//go:embed config.go
+var configSource string
+
+var (
+	schemaOnce sync.Once
+	schema     *JSONSchema
+)
+
+// Schema returns the JSON Schema of the configuration, described with the
+// comments of its fields
+func Schema() *JSONSchema {
+	schemaOnce.Do(func() {
+		schema = schemaOf(reflect.TypeOf(Config{}), "", fieldDocs())
+		schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+		schema.ID = SchemaURL
+		schema.Title = "Golem configuration"
+		schema.Description = "golem.config.json, .yaml or .toml, and the files of its environments"
+		schema.Properties["$schema"] = &JSONSchema{Type: "string", Description: "JSON Schema of the file, for editors"}
+		schema.Required = []string{"projectName"}
+	})
+	return schema
+}
+
+// schemaOf returns the schema of the setting of type t at path
+func schemaOf(t reflect.Type, path string, docs map[string]map[string]string) *JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Raw values, such as the sections of plugins, may be anything
+	if t == reflect.TypeOf(json.RawMessage(nil)) {
+		return &JSONSchema{}
+	}
+
+	var s *JSONSchema
+	switch t.Kind() {
+	case reflect.Struct:
+		s = &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema), closed: true}
+		for name, field := range jsonFields(t) {
+			property := schemaOf(field.Type, joinPath(path, name), docs)
+			if doc := docs[t.Name()][field.Name]; doc != "" {
+				property.Description = doc
+			}
+			s.Properties[name] = property
+		}
+	case reflect.Map:
+		s = &JSONSchema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), joinPath(path, "*"), docs)}
+	case reflect.Slice:
+		s = &JSONSchema{Type: "array", Items: schemaOf(t.Elem(), joinPath(path, "*"), docs)}
+	case reflect.String:
+		s = &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		s = &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		s = &JSONSchema{Type: "integer"}
+	case reflect.Float64:
+		s = &JSONSchema{Type: "number"}
+	default:
+		s = &JSONSchema{}
+	}
+
+	if s.Type == "object" && s.Description == "" {
+		s.Description = docs[t.Name()][""]
+	}
+	if constrain, ok := constraints[path]; ok {
+		constrain(s)
+	}
+	return s
+}
+
+// fieldDocs returns the comments of the types of config.go and their
+// fields, by type and field name; "" holds the comment of the type
+func fieldDocs() map[string]map[string]string {
+	docs := make(map[string]map[string]string)
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", configSource, parser.ParseComments)
+	if err != nil {
+		return docs
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			fields := map[string]string{"": typeDoc(typeSpec.Name.Name, gen.Doc)}
+			for _, field := range structType.Fields.List {
+				doc := field.Comment
+				if doc == nil {
+					doc = field.Doc
+				}
+				for _, name := range field.Names {
+					if doc != nil {
+						fields[name.Name] = strings.TrimSpace(strings.Join(strings.Fields(doc.Text()), " "))
+					}
+				}
+			}
+			docs[typeSpec.Name.Name] = fields
+		}
+	}
+	return docs
+}
+
+// typeDoc turns the comment of a type, such as "LogConfig sets the format
+// of the logs", into a description: "Sets the format of the logs"
+func typeDoc(name string, doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	text := strings.Join(strings.Fields(doc.Text()), " ")
+	text = strings.TrimPrefix(text, name+" ")
+	if text == "" {
+		return ""
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
+
+// checkSchema checks the decoded JSON value at path against schema s. The
+// structure of a file, its keys and the types of its values, is checked
+// before it is decoded; the values themselves once the environment
+// variables and defaults are applied, when the settings are final.
+func checkSchema(value interface{}, s *JSONSchema, path string, values bool, found *problems) {
+	if value == nil {
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			found.add(path, "expected an object, got %s", jsonKind(value))
+			return
+		}
+		for _, key := range sortedKeys(object) {
+			property, ok := s.Properties[key]
+			if !ok {
+				property, ok = foldedProperty(s.Properties, key)
+			}
+			if !ok && s.AdditionalProperties != nil {
+				checkSchema(object[key], s.AdditionalProperties, fmt.Sprintf("%s[%q]", path, key), values, found)
+				continue
+			}
+			if !ok {
+				if suggestion := closest(key, sortedKeys(s.Properties)); suggestion != "" {
+					found.add(joinPath(path, key), "unknown field, did you mean %q?", suggestion)
+				} else {
+					found.add(joinPath(path, key), "unknown field")
+				}
+				continue
+			}
+			checkSchema(object[key], property, joinPath(path, key), values, found)
+		}
+		if values {
+			for _, name := range s.Required {
+				if _, ok := object[name]; !ok {
+					found.add(joinPath(path, name), "required")
+				}
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			found.add(path, "expected an array, got %s", jsonKind(value))
+			return
+		}
+		for i, item := range array {
+			checkSchema(item, s.Items, fmt.Sprintf("%s[%d]", path, i), values, found)
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			found.add(path, "expected a string, got %s", jsonKind(value))
+		} else if values {
+			checkString(text, s, path, found)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			found.add(path, "expected true or false, got %s", jsonKind(value))
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			found.add(path, "expected %s, got %s", map[string]string{"integer": "an integer", "number": "a number"}[s.Type], jsonKind(value))
+			return
+		}
+		if s.Type == "integer" {
+			if _, err := strconv.ParseInt(number.String(), 10, 64); err != nil {
+				found.add(path, "expected an integer, got %s", number)
+				return
+			}
+		}
+		if values {
+			n, _ := number.Float64()
+			checkNumber(n, s, path, found)
+		}
+	}
+}
+
+// checkString checks a string setting; empty ones take their default
+func checkString(text string, s *JSONSchema, path string, found *problems) {
+	if text == "" {
+		if s.MinLength == 1 {
+			found.add(path, "required")
+		}
+		return
+	}
+	if len(text) < s.MinLength {
+		found.add(path, "too short, expected at least %d characters", s.MinLength)
+	}
+	if len(s.Enum) > 0 {
+		known := false
+		for _, option := range s.Enum {
+			known = known || strings.EqualFold(text, option)
+		}
+		if !known {
+			found.add(path, "%q is not one of %s", text, strings.Join(s.Enum, ", "))
+		}
+	}
+	if s.Format == "duration" {
+		if d, err := time.ParseDuration(text); err != nil || d < 0 {
+			found.add(path, "%q is not a duration, expected e.g. 30s or 5m", text)
+		}
+	}
+}
+
+// checkNumber checks a number setting against its range
+func checkNumber(n float64, s *JSONSchema, path string, found *problems) {
+	switch {
+	case s.Minimum != nil && s.Maximum != nil && (n < *s.Minimum || n > *s.Maximum):
+		found.add(path, "%v is out of range, expected %v to %v", n, *s.Minimum, *s.Maximum)
+	case s.Minimum != nil && n < *s.Minimum:
+		found.add(path, "%v is below %v", n, *s.Minimum)
+	case s.Maximum != nil && n > *s.Maximum:
+		found.add(path, "%v is above %v", n, *s.Maximum)
+	}
+}
+
+// foldedProperty finds the property of key as encoding/json does, ignoring
+// case
+func foldedProperty(properties map[string]*JSONSchema, key string) (*JSONSchema, bool) {
+	for name, property := range properties {
+		if strings.EqualFold(name, key) {
+			return property, true
+		}
+	}
+	return nil, false
+}
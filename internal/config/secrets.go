@@ -0,0 +1,280 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SecretsFile holds the secrets of a project as KEY=VALUE lines, next to
+// its configuration. Settings reference them as ${KEY}; the file of an
+// environment, such as golem.secrets.production.env, is read over it. Keep
+// both out of version control.
+const SecretsFile = "golem.secrets.env"
+
+// publicEnvPrefix marks the variables compiled into the app, which cannot
+// hold secrets
+const publicEnvPrefix = "GOLEM_PUBLIC_"
+
+// secretReference matches a reference to a secret, e.g. ${STRIPE_KEY}
+var secretReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// secretSections are the settings which may reference secrets: those of
+// the servers, which never reach the app or the build output
+var secretSections = []string{"server", "session"}
+
+// SecretsFiles returns the secrets files of env in dir, the base one
+// first; env is "" for the base one alone
+func SecretsFiles(dir, env string) []string {
+	files := []string{filepath.Join(dir, SecretsFile)}
+	if env != "" {
+		ext := filepath.Ext(SecretsFile)
+		files = append(files, filepath.Join(dir, strings.TrimSuffix(SecretsFile, ext)+"."+env+ext))
+	}
+	return files
+}
+
+// LoadSecrets reads the secrets files of env in dir, those of env winning.
+// Missing files hold no secrets.
+func LoadSecrets(dir, env string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	for _, file := range SecretsFiles(dir, env) {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values, err := ParseEnv(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for name, value := range values {
+			if strings.HasPrefix(name, publicEnvPrefix) {
+				return nil, fmt.Errorf("%s: %s is compiled into the app, put it in .env instead", file, name)
+			}
+			secrets[name] = value
+		}
+	}
+	return secrets, nil
+}
+
+// Secret returns the secret name: the environment variable of that name,
+// or else the value of the secrets files the configuration was loaded with
+func (c *Config) Secret(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := c.secrets[name]
+	return value, ok
+}
+
+// resolveSecrets replaces the ${NAME} references of the settings with their
+// secrets, remembering the references so the configuration can be written
+// out without the secrets. Settings whose secrets are not set are left
+// empty until RequireSecrets reports them.
+func (c *Config) resolveSecrets(secrets map[string]string) problems {
+	var found problems
+	c.secrets = secrets
+	c.references = make(map[string]string)
+	c.missing = make(map[string][]string)
+	eachString(reflect.ValueOf(c).Elem(), "", func(path string, v reflect.Value) {
+		text := v.String()
+		if !secretReference.MatchString(text) {
+			return
+		}
+		if !inSecretSection(path) {
+			found.add(path, "secrets can only be referenced from the %s settings, which never reach the app", strings.Join(secretSections, " and "))
+			return
+		}
+
+		c.references[path] = text
+		var missing []string
+		resolved := secretReference.ReplaceAllStringFunc(text, func(reference string) string {
+			name := secretReference.FindStringSubmatch(reference)[1]
+			value, ok := c.Secret(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			c.missing[path] = missing
+			resolved = ""
+		}
+		v.SetString(resolved)
+	})
+	return found
+}
+
+// inSecretSection reports whether the setting at path may reference secrets
+func inSecretSection(path string) bool {
+	for _, section := range secretSections {
+		if path == section || strings.HasPrefix(path, section+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireSecrets reports the settings referencing secrets that are set
+// neither in the environment nor in the secrets files. The servers need
+// them all; commands that never read them, such as golem build, do not.
+func (c *Config) RequireSecrets() error {
+	var found problems
+	for _, path := range sortedKeys(c.missing) {
+		if c.overridden(path) {
+			continue
+		}
+		for _, name := range c.missing[path] {
+			found.add(path, "secret %s is not set, add it to %s or the environment", name, SecretsFile)
+		}
+	}
+	return found.err()
+}
+
+// missingSecret reports whether the setting at path, or the one holding
+// it, waits for a secret that is not set
+func (c *Config) missingSecret(path string) bool {
+	for setting := range c.missing {
+		if path == setting || strings.HasPrefix(path, setting+".") || strings.HasPrefix(path, setting+"[") {
+			return !c.overridden(setting)
+		}
+	}
+	return false
+}
+
+// overridden reports whether an environment variable set the setting at
+// path, or the list or map holding it
+func (c *Config) overridden(path string) bool {
+	for setting := range c.env {
+		if path == setting || strings.HasPrefix(path, setting+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutSecrets returns a copy of the configuration whose settings
+// reference their secrets again as ${NAME}, to print or embed it
+func (c *Config) WithoutSecrets() (*Config, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var copied Config
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	copied.env, copied.files, copied.environment = c.env, c.files, c.environment
+	eachString(reflect.ValueOf(&copied).Elem(), "", func(path string, v reflect.Value) {
+		if reference, ok := c.references[path]; ok && !c.overridden(path) {
+			v.SetString(reference)
+		}
+	})
+	return &copied, nil
+}
+
+// eachString calls f with the path and the settable value of every string
+// setting under v, the setting at path
+func eachString(v reflect.Value, path string, f func(path string, v reflect.Value)) {
+	switch v.Kind() {
+	case reflect.String:
+		f(path, v)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			eachString(v.Elem(), path, f)
+		}
+	case reflect.Struct:
+		for name, field := range jsonFields(v.Type()) {
+			eachString(v.FieldByIndex(field.Index), joinPath(path, name), f)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			eachString(v.Index(i), fmt.Sprintf("%s[%d]", path, i), f)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			// Map values are not settable, so a copy is set back
+			item := reflect.New(v.Type().Elem()).Elem()
+			item.Set(v.MapIndex(key))
+			eachString(item, fmt.Sprintf("%s[%q]", path, key.String()), f)
+			v.SetMapIndex(key, item)
+		}
+	}
+}
+
+// ParseEnv parses the KEY=VALUE lines of a .env file. Lines may start with
+// "export", values may be single quoted (literal) or double quoted (with
+// escapes like \n), and # starts a comment outside quotes.
+func ParseEnv(data string) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNumber)
+			}
+			unquoted, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNumber)
+			}
+			value = value[1 : end+1]
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// closingQuote returns the index of the double quote closing value
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
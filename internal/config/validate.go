@@ -0,0 +1,229 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldError is a problem with the value at Path, a JSON path such as
+// "server.grpc.port" or "server.proxy[0].target"
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError lists every problem found in a configuration, sorted by
+// path
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid config: " + e.Errors[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config, %d problems:", len(e.Errors))
+	for _, err := range e.Errors {
+		b.WriteString("\n  " + err.Error())
+	}
+	return b.String()
+}
+
+// problems collects the field errors of a configuration
+type problems []FieldError
+
+func (p *problems) add(path, format string, args ...interface{}) {
+	*p = append(*p, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the problems as a *ValidationError, nil without any
+func (p problems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	sort.SliceStable(p, func(i, j int) bool { return p[i].Path < p[j].Path })
+	return &ValidationError{Errors: p}
+}
+
+// covers reports whether one of the problems is about path or a value
+// containing it
+func (p problems) covers(path string) bool {
+	for _, problem := range p {
+		if path == problem.Path || strings.HasPrefix(path, problem.Path+".") || strings.HasPrefix(path, problem.Path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkJSON returns the keys of data that no setting of the schema has,
+// which encoding/json silently ignores, and the values of the wrong type.
+// Syntax errors are returned as an error, located by line and column.
+func checkJSON(data []byte) (problems, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, syntaxError(data, err)
+	}
+	if decoder.More() {
+		return nil, &ValidationError{Errors: []FieldError{{Message: "unexpected data after the top-level object"}}}
+	}
+
+	var found problems
+	checkSchema(raw, Schema(), "", false, &found)
+	return found, nil
+}
+
+// syntaxError locates a JSON syntax error by line and column
+func syntaxError(data []byte, err error) error {
+	var offset int64
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		offset = err.Offset
+	default:
+		return &ValidationError{Errors: []FieldError{{Message: err.Error()}}}
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := int(offset) - bytes.LastIndexByte(data[:offset], '\n') - 1
+	return &ValidationError{Errors: []FieldError{{Message: fmt.Sprintf("line %d, column %d: %v", line, column, err)}}}
+}
+
+// jsonFields returns the fields of struct type t by JSON key
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// closest returns the name nearest to key, a likely typo of it, or
+// "" when none is close
+func closest(key string, names []string) string {
+	best, bestDistance := "", len(key)/3+2
+	for _, name := range names {
+		if distance := editDistance(strings.ToLower(key), strings.ToLower(name)); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonKind names the JSON type of a decoded value
+func jsonKind(value interface{}) string {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return strconv.Quote(value)
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return "null"
+}
+
+// Validate reports every setting of c that is out of range, such as a port
+// above 65535, or that the servers and the build cannot use, such as a
+// duration they cannot parse. Load and Parse validate what they return.
+func (c *Config) Validate() error {
+	return c.validate().err()
+}
+
+func (c *Config) validate() problems {
+	var found problems
+
+	// The settings in effect are checked against the schema
+	data, err := json.Marshal(c)
+	if err != nil {
+		found.add("", "%v", err)
+		return found
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var settings interface{}
+	decoder.Decode(&settings)
+	checkSchema(settings, Schema(), "", true, &found)
+
+	if c.Server.Port != 0 && c.Server.Port == c.Server.GRPC.Port {
+		found.add("server.grpc.port", "%d is also server.port, expected another port", c.Server.GRPC.Port)
+	}
+	if c.Build.Profile != "" {
+		if _, ok := c.Profiles[c.Build.Profile]; !ok {
+			found.add("build.profile", "%q is not in profiles", c.Build.Profile)
+		}
+	}
+	for i, rule := range c.Server.Proxy {
+		path := fmt.Sprintf("server.proxy[%d]", i)
+		if rule.Path != "" && !strings.HasPrefix(rule.Path, "/") {
+			found.add(path+".path", "%q is not a URL path, expected e.g. /auth/", rule.Path)
+		}
+		if target, err := url.Parse(rule.Target); rule.Target != "" && (err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "") {
+			found.add(path+".target", "%q is not a URL, expected e.g. http://localhost:9000", rule.Target)
+		}
+	}
+	return found
+}
@@ -0,0 +1,51 @@
+package dev
+
+import (
+	"fmt"
+	"strings"
+)
+
+// printBrowserConsole prints console output forwarded by a browser. Go
+// panics and uncaught errors are shown as errors with their stack trace.
+func printBrowserConsole(message hubMessage) {
+	text := strings.TrimRight(message.Message, "\n")
+	level := message.Level
+	if strings.HasPrefix(text, "panic: ") || strings.HasPrefix(text, "fatal error: ") {
+		level = "error"
+	}
+	if message.Stack != "" && !strings.Contains(text, message.Stack) {
+		text += "\n" + strings.TrimRight(message.Stack, "\n")
+	}
+
+	// The first line carries the level; the rest are indented below it
+	lines := strings.Split(text, "\n")
+	prefix := "🌐 browser"
+	switch level {
+	case "warn":
+		prefix += " warn:"
+	case "error":
+		prefix += " error:"
+	default:
+		prefix += ":"
+	}
+
+	if colorEnabled() {
+		color := ansiDim
+		switch level {
+		case "warn":
+			color = ansiYellow
+		case "error":
+			color = ansiRed
+		}
+		prefix = color + prefix + ansiReset
+	}
+
+	fmt.Println(prefix, lines[0])
+	for _, line := range lines[1:] {
+		if line == "" {
+			fmt.Println()
+			continue
+		}
+		fmt.Println("   ", line)
+	}
+}
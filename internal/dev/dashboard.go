@@ -0,0 +1,237 @@
+package dev
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dashboardPath is where the dev dashboard is served
+const dashboardPath = "/_golem/"
+
+// routeCache keeps the route manifest of the app until the next app build
+type routeCache struct {
+	mutex    sync.Mutex
+	build    time.Time
+	manifest json.RawMessage
+	err      error
+}
+
+// handleDashboardStatus serves what the dashboard shows besides the server
+// functions, which it lists through the function API
+func (s *Server) handleDashboardStatus(w http.ResponseWriter, r *http.Request) {
+	status := map[string]interface{}{
+		"project": s.config.ProjectName,
+		"version": s.config.Version,
+		"builds":  s.diagnostics.lastBuilds(),
+		"clients": s.hub.count(),
+		"mode":    s.functionsMode(),
+	}
+	if info, err := os.Stat(filepath.Join(".golem/dev", "app.wasm")); err == nil {
+		status["bundleSize"] = info.Size()
+	}
+	diagnostics, _ := s.diagnostics.all()
+	status["diagnostics"] = diagnostics
+
+	if s.config.Build.Routes == "" {
+		status["routesHint"] = `Set "routes" in the build section of golem.config.json to list the app's routes here`
+	} else if manifest, err := s.routeManifest(); err != nil {
+		status["routesError"] = err.Error()
+	} else {
+		status["routes"] = manifest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// functionsMode describes how server functions are served
+func (s *Server) functionsMode() string {
+	switch {
+	case s.config.Dev.Mocks != "":
+		return "mocks from " + s.config.Dev.Mocks
+	case s.worker.Load() != nil:
+		return "worker process, reloaded on change"
+	default:
+		return "linked into the dev server"
+	}
+}
+
+// routeManifest runs the route generator configured in build.routes and
+// returns its routes.json, regenerating it once the app was rebuilt
+func (s *Server) routeManifest() (json.RawMessage, error) {
+	built := s.diagnostics.lastBuilds()[targetApp].Finished
+
+	s.routes.mutex.Lock()
+	defer s.routes.mutex.Unlock()
+	if s.routes.manifest != nil && s.routes.build.Equal(built) {
+		return s.routes.manifest, s.routes.err
+	}
+
+	dir, err := os.MkdirTemp("", "golem-routes")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.Command("go", "run", s.config.Build.Routes, dir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("route generator failed: %v\n%s", err, strings.TrimSpace(string(output)))
+	}
+	manifest, err := os.ReadFile(filepath.Join(dir, "routes.json"))
+	if err != nil {
+		return nil, fmt.Errorf("route generator wrote no routes.json: %w", err)
+	}
+
+	s.routes.build, s.routes.manifest = built, manifest
+	return manifest, nil
+}
+
+// handleDashboard serves the dev dashboard page
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != dashboardPath {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, dashboardPage)
+}
+
+// redirectAPIIndex sends browsers opening /api/ to the dashboard; API
+// clients still get the JSON description of the endpoints
+func redirectAPIIndex(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/" && r.Method == "GET" && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			http.Redirect(w, r, dashboardPath, http.StatusFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// dashboardPage lists the build, routes, server functions with a form to
+// call them, and connected browsers. It refreshes itself every few seconds.
+const dashboardPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Golem Dev Dashboard</title>
+    <style>
+        body { margin: 0; font: 14px/1.5 system-ui, sans-serif; background: #f4f4f5; color: #18181b; }
+        header { padding: 16px 32px; background: #18181b; color: #f4f4f5; }
+        header h1 { margin: 0; font-size: 18px; }
+        main { padding: 24px 32px; display: grid; gap: 24px; max-width: 1100px; }
+        section { background: #fff; border-radius: 8px; padding: 16px 24px; box-shadow: 0 1px 2px rgba(0,0,0,0.08); }
+        h2 { margin: 0 0 12px; font-size: 16px; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e4e4e7; vertical-align: top; }
+        code, textarea, pre { font-family: ui-monospace, Menlo, monospace; font-size: 13px; }
+        textarea { width: 100%; box-sizing: border-box; min-height: 48px; }
+        pre { background: #f4f4f5; padding: 8px; border-radius: 4px; white-space: pre-wrap; margin: 8px 0 0; }
+        .ok { color: #15803d; } .failed { color: #b91c1c; } .muted { color: #71717a; }
+        button { padding: 4px 12px; cursor: pointer; }
+    </style>
+</head>
+<body>
+    <header><h1 id="title">Golem Dev Dashboard</h1></header>
+    <main>
+        <section><h2>Build</h2><table id="build"></table></section>
+        <section><h2>Routes</h2><div id="routes"></div></section>
+        <section><h2>Server Functions</h2><p class="muted" id="mode"></p><div id="functions"></div></section>
+    </main>
+    <script>
+        function escapeHTML(text) {
+            const div = document.createElement('div');
+            div.textContent = String(text);
+            return div.innerHTML;
+        }
+
+        function formatSize(bytes) {
+            if (bytes >= 1 << 20) return (bytes / (1 << 20)).toFixed(1) + ' MB';
+            if (bytes >= 1 << 10) return (bytes / (1 << 10)).toFixed(1) + ' kB';
+            return bytes + ' B';
+        }
+
+        function buildRow(name, build) {
+            if (!build) return '<tr><th>' + name + '</th><td class="muted">not built</td></tr>';
+            const state = build.ok ? '<span class="ok">ok</span>' : '<span class="failed">failed</span>';
+            return '<tr><th>' + name + '</th><td>' + state + ' in ' + (build.duration / 1e6).toFixed(0) + ' ms, ' +
+                new Date(build.finished).toLocaleTimeString() + '</td></tr>';
+        }
+
+        async function refreshStatus() {
+            const status = await (await fetch('/_golem/status')).json();
+            document.getElementById('title').textContent = 'Golem Dev Dashboard: ' + status.project + ' ' + (status.version || '');
+            document.getElementById('mode').textContent = 'Served from: ' + status.mode;
+
+            let rows = buildRow('App', status.builds.app) + buildRow('Server functions', status.builds.functions);
+            if (status.bundleSize) rows += '<tr><th>Bundle size</th><td>' + formatSize(status.bundleSize) + ' (app.wasm)</td></tr>';
+            rows += '<tr><th>Hot reload clients</th><td>' + status.clients + ' connected</td></tr>';
+            status.diagnostics.forEach(function(d) {
+                rows += '<tr><th class="failed">Error</th><td><code>' + escapeHTML(d.file + ':' + d.line) + '</code> ' + escapeHTML(d.message) + '</td></tr>';
+            });
+            document.getElementById('build').innerHTML = rows;
+
+            const routes = document.getElementById('routes');
+            if (status.routes) {
+                routes.innerHTML = '<table><tr><th>Path</th><th>Name</th><th>URLs</th></tr>' + status.routes.routes.map(function(route) {
+                    return '<tr><td><code>' + escapeHTML(route.path) + '</code></td><td>' + escapeHTML(route.name || '') + '</td><td>' +
+                        (route.urls || []).map(function(url) { return '<a href="' + escapeHTML(url) + '">' + escapeHTML(url) + '</a>'; }).join(' ') + '</td></tr>';
+                }).join('') + '</table>';
+            } else {
+                routes.innerHTML = '<p class="muted">' + escapeHTML(status.routesError || status.routesHint) + '</p>';
+            }
+        }
+
+        async function loadFunctions() {
+            const list = await (await fetch('/api/functions/list')).json();
+            const container = document.getElementById('functions');
+            const functions = list.functions || [];
+            if (functions.length === 0) {
+                container.innerHTML = '<p class="muted">No server functions registered</p>';
+                return;
+            }
+            container.innerHTML = '<table><tr><th>Function</th><th>Arguments (JSON array)</th></tr>' + functions.map(function(fn, i) {
+                const args = (fn.arg_types || []).filter(function(type) { return type !== 'context.Context'; });
+                const name = fn.service_name + '.' + fn.name;
+                return '<tr><td><a href="/_golem/functions/' + encodeURIComponent(name) + '"><code>' + escapeHTML(name) + '</code></a><br><span class="muted">(' +
+                    escapeHTML(args.join(', ')) + ') ' + escapeHTML(fn.return_type || '') + '</span><br>' + escapeHTML(fn.description || '') +
+                    '</td><td><textarea id="args-' + i + '">[]</textarea><button data-index="' + i + '">Call</button><pre id="result-' + i + '" hidden></pre></td></tr>';
+            }).join('') + '</table>';
+
+            container.querySelectorAll('button').forEach(function(button) {
+                button.onclick = function() { callFunction(functions[button.dataset.index], button.dataset.index); };
+            });
+        }
+
+        async function callFunction(fn, index) {
+            const output = document.getElementById('result-' + index);
+            output.hidden = false;
+            const started = performance.now();
+            try {
+                const args = JSON.parse(document.getElementById('args-' + index).value || '[]');
+                const response = await fetch('/api/functions/' + fn.service_name + '/' + fn.name, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(args),
+                });
+                const body = await response.json();
+                output.textContent = response.status + ' in ' + (performance.now() - started).toFixed(0) + ' ms\n' + JSON.stringify(body, null, 2);
+            } catch (err) {
+                output.textContent = String(err);
+            }
+        }
+
+        refreshStatus();
+        loadFunctions();
+        setInterval(refreshStatus, 3000);
+    </script>
+</body>
+</html>`
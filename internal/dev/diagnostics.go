@@ -0,0 +1,234 @@
+package dev
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Build targets diagnostics are reported for
+const (
+	targetApp       = "app"
+	targetFunctions = "functions"
+)
+
+// Diagnostic is one compiler error from a dev build
+type Diagnostic struct {
+	Target  string `json:"target"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// diagnosticPattern matches "file.go:line:col: message" lines of go build
+// output, and of .golem components; the column is missing for some errors
+var diagnosticPattern = regexp.MustCompile(`^(.+?\.(?:go|golem)):(\d+)(?::(\d+))?: (.*)$`)
+
+// parseDiagnostics extracts the diagnostics from go build output. Indented
+// lines continue the previous message, and package headers ("# pkg") and
+// other lines are skipped. Paths are made absolute so they open from any
+// directory.
+func parseDiagnostics(target string, output string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "\t") && len(diagnostics) > 0 {
+			last := &diagnostics[len(diagnostics)-1]
+			last.Message += "\n" + strings.TrimSpace(line)
+			continue
+		}
+
+		match := diagnosticPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		file := match[1]
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		lineNumber, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		diagnostics = append(diagnostics, Diagnostic{
+			Target:  target,
+			File:    file,
+			Line:    lineNumber,
+			Column:  column,
+			Message: match[4],
+		})
+	}
+	return diagnostics
+}
+
+// ANSI escapes used when printing diagnostics to a terminal
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiCyan   = "\x1b[36m"
+	ansiYellow = "\x1b[33m"
+	ansiDim    = "\x1b[2m"
+)
+
+// colorEnabled reports whether stdout is a terminal that accepts colors
+func colorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatDiagnostic renders a diagnostic as "file:line:col: message". With
+// color, the location is a hyperlink to the file that terminals supporting
+// OSC 8 open on click; the plain location is still recognized by editors'
+// integrated terminals.
+func formatDiagnostic(d Diagnostic, color bool) string {
+	location := d.File
+	if wd, err := os.Getwd(); err == nil {
+		if relative, err := filepath.Rel(wd, d.File); err == nil && !strings.HasPrefix(relative, "..") {
+			location = relative
+		}
+	}
+	location += ":" + strconv.Itoa(d.Line)
+	if d.Column > 0 {
+		location += ":" + strconv.Itoa(d.Column)
+	}
+
+	message := strings.ReplaceAll(d.Message, "\n", "\n\t")
+	if !color {
+		return fmt.Sprintf("%s: %s", location, message)
+	}
+
+	link := "\x1b]8;;file://" + filepath.ToSlash(d.File) + "\x1b\\" + location + "\x1b]8;;\x1b\\"
+	return fmt.Sprintf("%s%s%s: %serror:%s %s", ansiBold+ansiCyan, link, ansiReset, ansiBold+ansiRed, ansiReset, message)
+}
+
+// printDiagnostics prints the diagnostics of a failed build
+func printDiagnostics(diagnostics []Diagnostic) {
+	color := colorEnabled()
+	for _, d := range diagnostics {
+		fmt.Println("   " + formatDiagnostic(d, color))
+	}
+	if color {
+		fmt.Printf("   %s%d error(s) in the %s build%s\n", ansiDim, len(diagnostics), diagnostics[0].Target, ansiReset)
+	}
+}
+
+// buildStatus is the outcome of the latest build of a target
+type buildStatus struct {
+	Finished time.Time     `json:"finished"`
+	Duration time.Duration `json:"duration"` // nanoseconds
+	OK       bool          `json:"ok"`
+}
+
+// diagnosticSet holds the outcome and diagnostics of the latest build of
+// each target
+type diagnosticSet struct {
+	mutex   sync.RWMutex
+	targets map[string][]Diagnostic
+	builds  map[string]buildStatus
+	updated time.Time
+}
+
+// set records a finished build of a target with its diagnostics
+func (ds *diagnosticSet) set(target string, started time.Time, ok bool, diagnostics []Diagnostic) {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+
+	if ds.targets == nil {
+		ds.targets = make(map[string][]Diagnostic)
+		ds.builds = make(map[string]buildStatus)
+	}
+	if len(diagnostics) == 0 {
+		delete(ds.targets, target)
+	} else {
+		ds.targets[target] = diagnostics
+	}
+	ds.updated = time.Now()
+	ds.builds[target] = buildStatus{Finished: ds.updated, Duration: ds.updated.Sub(started), OK: ok}
+}
+
+// lastBuilds returns the outcome of the latest build of each target
+func (ds *diagnosticSet) lastBuilds() map[string]buildStatus {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+
+	builds := make(map[string]buildStatus, len(ds.builds))
+	for target, status := range ds.builds {
+		builds[target] = status
+	}
+	return builds
+}
+
+// of returns the diagnostics of the latest build of a target
+func (ds *diagnosticSet) of(target string) []Diagnostic {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+	return ds.targets[target]
+}
+
+// all returns the diagnostics of every target ordered by file and line
+func (ds *diagnosticSet) all() ([]Diagnostic, time.Time) {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+
+	diagnostics := []Diagnostic{}
+	for _, list := range ds.targets {
+		diagnostics = append(diagnostics, list...)
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics, ds.updated
+}
+
+// reportBuild records the outcome of a target's build started at started.
+// For a failed build the compiler errors are printed colorized and left out
+// of the returned error; output that has none is kept in the error instead.
+func (s *Server) reportBuild(target string, started time.Time, output []byte, err error) error {
+	if err == nil {
+		s.diagnostics.set(target, started, true, nil)
+		return nil
+	}
+
+	diagnostics := parseDiagnostics(target, string(output))
+	s.diagnostics.set(target, started, false, diagnostics)
+	if len(diagnostics) == 0 {
+		return fmt.Errorf("%v\n%s", err, strings.TrimSpace(string(output)))
+	}
+	printDiagnostics(diagnostics)
+	return err
+}
+
+// handleDiagnostics serves the diagnostics of the latest dev builds as JSON
+// for editor integrations
+func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	diagnostics, updated := s.diagnostics.all()
+	response := map[string]interface{}{
+		"ok":          len(diagnostics) == 0,
+		"diagnostics": diagnostics,
+	}
+	if !updated.IsZero() {
+		response["updated"] = updated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
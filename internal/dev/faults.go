@@ -0,0 +1,99 @@
+package dev
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// allFunctions is the dev.faults key applying to every function
+const allFunctions = "*"
+
+// fault is a parsed dev.faults entry
+type fault struct {
+	delay     time.Duration
+	jitter    time.Duration
+	errorRate float64
+	errorCode string
+}
+
+// FaultInjector returns an interceptor that delays and fails function calls
+// as configured in dev.faults, so loading states, retries and error handling
+// can be exercised without changing server code. A function's own entry
+// replaces the "*" entry. It returns nil when no faults are configured.
+func FaultInjector(faults map[string]config.FaultConfig) (functions.Interceptor, error) {
+	if len(faults) == 0 {
+		return nil, nil
+	}
+
+	parsed := make(map[string]fault, len(faults))
+	for key, cfg := range faults {
+		f, err := parseFault(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dev.faults entry %s: %w", key, err)
+		}
+		parsed[key] = f
+	}
+
+	return func(ctx context.Context, info *functions.CallInfo, next functions.Handler) (*anypb.Any, error) {
+		f, exists := parsed[info.ServiceName+"."+info.FunctionName]
+		if !exists {
+			if f, exists = parsed[allFunctions]; !exists {
+				return next(ctx, info)
+			}
+		}
+
+		if delay := f.delay + randomDuration(f.jitter); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			}
+		}
+
+		if f.errorRate > 0 && rand.Float64() < f.errorRate {
+			return nil, functions.Errorf(f.errorCode, "injected failure of %s.%s (dev.faults)", info.ServiceName, info.FunctionName).
+				WithRetryable(true)
+		}
+		return next(ctx, info)
+	}, nil
+}
+
+// parseFault validates a dev.faults entry
+func parseFault(cfg config.FaultConfig) (fault, error) {
+	f := fault{errorRate: cfg.ErrorRate, errorCode: cfg.ErrorCode}
+	if f.errorCode == "" {
+		f.errorCode = functions.CodeUnavailable
+	}
+	if f.errorRate < 0 || f.errorRate > 1 {
+		return f, fmt.Errorf("errorRate must be between 0 and 1")
+	}
+
+	var err error
+	if cfg.Delay != "" {
+		if f.delay, err = time.ParseDuration(cfg.Delay); err != nil {
+			return f, fmt.Errorf("invalid delay: %w", err)
+		}
+	}
+	if cfg.Jitter != "" {
+		if f.jitter, err = time.ParseDuration(cfg.Jitter); err != nil {
+			return f, fmt.Errorf("invalid jitter: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// randomDuration returns a random duration in [0, max)
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
@@ -0,0 +1,368 @@
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// Hot reload message types sent to the browser
+const (
+	messageReload    = "reload"     // reload the page
+	messageCSSUpdate = "css-update" // refetch the stylesheet at Path
+	messageError     = "error"      // show the build errors in an overlay
+	messageConsole   = "console"    // console output sent by the browser
+)
+
+// hubPingInterval is how often browsers are pinged to detect dead
+// connections, e.g. of a laptop that went to sleep
+const hubPingInterval = 30 * time.Second
+
+// hubWriteTimeout bounds how long a message or ping may take to be sent
+const hubWriteTimeout = 5 * time.Second
+
+// hubMessage is a hot reload message
+type hubMessage struct {
+	Type        string       `json:"type"`
+	Path        string       `json:"path,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	Message     string       `json:"message,omitempty"`
+	Level       string       `json:"level,omitempty"` // console level
+	Stack       string       `json:"stack,omitempty"` // JavaScript stack of an uncaught error
+}
+
+// hubClient is a connected browser. Messages are queued so one slow client
+// never delays the others.
+type hubClient struct {
+	conn *websocket.Conn
+	send chan hubMessage
+}
+
+// reloadHub tracks the browsers connected for hot reload and broadcasts
+// messages to all of them
+type reloadHub struct {
+	mutex   sync.Mutex
+	clients map[*hubClient]struct{}
+}
+
+// newReloadHub creates an empty hub
+func newReloadHub() *reloadHub {
+	return &reloadHub{clients: make(map[*hubClient]struct{})}
+}
+
+// ServeHTTP accepts a browser's connection and serves it until it leaves
+func (h *reloadHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		log.Printf("could not upgrade to websocket: %v", err)
+		return
+	}
+	defer conn.Close(websocket.StatusInternalError, "internal error")
+
+	client := &hubClient{conn: conn, send: make(chan hubMessage, 16)}
+	h.mutex.Lock()
+	h.clients[client] = struct{}{}
+	h.mutex.Unlock()
+	defer func() {
+		h.mutex.Lock()
+		delete(h.clients, client)
+		h.mutex.Unlock()
+	}()
+
+	// Reading prints the browser's console output, handles the pongs
+	// answering pings and notices the close
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			var message hubMessage
+			if json.Unmarshal(data, &message) == nil && message.Type == messageConsole {
+				printBrowserConsole(message)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(hubPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message := <-client.send:
+			if err := client.write(ctx, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, hubWriteTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// write sends a message to the browser
+func (c *hubClient) write(ctx context.Context, message hubMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, hubWriteTimeout)
+	defer cancel()
+	return c.conn.Write(ctx, websocket.MessageText, data)
+}
+
+// broadcast queues a message for every connected browser, dropping it for
+// browsers too far behind to keep up
+func (h *reloadHub) broadcast(message hubMessage) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for client := range h.clients {
+		select {
+		case client.send <- message:
+		default:
+			log.Printf("Warning: Dropped %s message for a slow browser", message.Type)
+		}
+	}
+}
+
+// count returns the number of connected browsers
+func (h *reloadHub) count() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.clients)
+}
+
+// close disconnects every browser. The hot reload client reconnects and
+// reloads the page once the dev server is back.
+func (h *reloadHub) close() {
+	h.mutex.Lock()
+	clients := make([]*hubClient, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.conn.Close(websocket.StatusServiceRestart, "dev server restarting")
+		}()
+	}
+	wg.Wait()
+}
+
+// reload tells every browser to reload the page
+func (h *reloadHub) reload() {
+	h.broadcast(hubMessage{Type: messageReload})
+}
+
+// updateCSS tells every browser to refetch a stylesheet
+func (h *reloadHub) updateCSS(path string) {
+	h.broadcast(hubMessage{Type: messageCSSUpdate, Path: path})
+}
+
+// reportError shows a failed build in every browser
+func (h *reloadHub) reportError(message string, diagnostics []Diagnostic) {
+	h.broadcast(hubMessage{Type: messageError, Message: message, Diagnostics: diagnostics})
+}
+
+// hotReloadClient is the dev page's side of the hub: it reloads the page,
+// swaps updated stylesheets, shows build errors in an overlay and forwards
+// console output, including Go's, and uncaught errors to the terminal. It
+// reconnects when the dev server restarts and shows the connection state.
+const hotReloadClient = `
+    <script>
+        (function() {
+            // Hot reload WebSocket connection, over TLS when the page is
+            const url = (location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '/ws';
+            let ws;
+
+            function reload() {
+                // Save state registered with state.Preserve for the new build
+                if (window.__golemSnapshot) {
+                    try {
+                        window.__golemSnapshot();
+                    } catch (err) {
+                        console.warn('Could not save state before reload:', err);
+                    }
+                }
+                window.location.reload();
+            }
+
+            function updateCSS(path) {
+                const name = path.split('/').pop();
+                const links = Array.from(document.querySelectorAll('link[rel="stylesheet"]')).filter(function(link) {
+                    return new URL(link.href).pathname.split('/').pop() === name;
+                });
+                if (links.length === 0) {
+                    reload();
+                    return;
+                }
+                links.forEach(function(link) {
+                    const url = new URL(link.href);
+                    url.searchParams.set('t', Date.now());
+                    link.href = url.toString();
+                });
+            }
+
+            function escapeHTML(text) {
+                const div = document.createElement('div');
+                div.textContent = text;
+                return div.innerHTML;
+            }
+
+            function showError(message) {
+                let overlay = document.getElementById('golem-error-overlay');
+                if (!overlay) {
+                    overlay = document.createElement('div');
+                    overlay.id = 'golem-error-overlay';
+                    overlay.style.cssText = 'position:fixed;inset:0;z-index:2147483647;overflow:auto;padding:32px;' +
+                        'background:rgba(24,24,27,0.95);color:#f4f4f5;font:14px/1.5 ui-monospace,Menlo,monospace;white-space:pre-wrap';
+                    overlay.onclick = function() { overlay.remove(); };
+                    document.body.appendChild(overlay);
+                }
+                const errors = (message.diagnostics || []).map(function(d) {
+                    const location = d.file + ':' + d.line + (d.column ? ':' + d.column : '');
+                    return '<div style="margin-top:12px"><span style="color:#67e8f9">' + escapeHTML(location) + '</span>\n' +
+                        escapeHTML(d.message) + '</div>';
+                }).join('');
+                overlay.innerHTML = '<div style="color:#f87171;font-weight:bold">' + escapeHTML(message.message || 'Build failed') + '</div>' +
+                    errors + '<div style="margin-top:24px;color:#a1a1aa">Fix the errors to reload, or click to dismiss.</div>';
+            }
+
+            // showConnection shows the state of the connection in the dev
+            // banner, or in a badge on pages without one
+            function showConnection(connected, text) {
+                let banner = document.getElementById('golem-dev-banner');
+                if (!banner) {
+                    banner = document.getElementById('golem-connection-badge');
+                    if (!banner && connected) return;
+                    if (!banner) {
+                        banner = document.createElement('div');
+                        banner.id = 'golem-connection-badge';
+                        banner.style.cssText = 'position:fixed;right:12px;bottom:12px;z-index:2147483646;padding:6px 12px;' +
+                            'border-radius:4px;color:#fff;font:12px system-ui,sans-serif';
+                        document.body.appendChild(banner);
+                    }
+                    banner.style.display = connected ? 'none' : '';
+                }
+                banner.dataset.connected = connected;
+                banner.style.background = connected ? '' : '#dc3545';
+                const status = document.getElementById('golem-connection') || banner;
+                status.textContent = text;
+            }
+
+            // Forward console output and uncaught errors to the terminal;
+            // Go's stdout and stderr, panics included, go through console.log.
+            // Output is queued while the dev server is away.
+            const pending = [];
+            function forward(message) {
+                message.type = 'console';
+                if (ws && ws.readyState === WebSocket.OPEN) {
+                    ws.send(JSON.stringify(message));
+                } else if (pending.length < 100) {
+                    pending.push(message);
+                }
+            }
+
+            function format(value) {
+                if (value instanceof Error) {
+                    return value.stack || String(value);
+                }
+                if (typeof value === 'string') {
+                    return value;
+                }
+                try {
+                    return JSON.stringify(value);
+                } catch (err) {
+                    return String(value);
+                }
+            }
+            ['log', 'info', 'warn', 'error', 'debug'].forEach(function(level) {
+                const original = console[level];
+                console[level] = function() {
+                    original.apply(console, arguments);
+                    forward({ level: level, message: Array.prototype.map.call(arguments, format).join(' ') });
+                };
+            });
+            window.addEventListener('error', function(event) {
+                forward({ level: 'error', message: 'Uncaught ' + event.message, stack: event.error && event.error.stack });
+            });
+            window.addEventListener('unhandledrejection', function(event) {
+                forward({ level: 'error', message: 'Unhandled rejection: ' + format(event.reason), stack: event.reason && event.reason.stack });
+            });
+
+            function handle(event) {
+                const message = JSON.parse(event.data);
+                switch (message.type) {
+                case 'reload':
+                    reload();
+                    break;
+                case 'css-update':
+                    updateCSS(message.path);
+                    break;
+                case 'error':
+                    showError(message);
+                    break;
+                }
+            }
+
+            // Reconnect with exponential backoff when the dev server goes
+            // away, e.g. while it restarts, and reload once it is back since
+            // the app may have been rebuilt in the meantime
+            let attempts = 0;
+            let lost = false;
+            let retry;
+            function connect() {
+                clearTimeout(retry);
+                retry = undefined;
+                ws = new WebSocket(url);
+                ws.onopen = function() {
+                    if (lost) {
+                        showConnection(true, 'Dev server is back, reloading...');
+                        reload();
+                        return;
+                    }
+                    attempts = 0;
+                    showConnection(true, 'Hot Reload Enabled');
+                    pending.splice(0).forEach(function(message) { ws.send(JSON.stringify(message)); });
+                };
+                ws.onmessage = handle;
+                ws.onclose = function() {
+                    lost = true;
+                    const delay = Math.min(500 * Math.pow(2, attempts), 10000);
+                    attempts++;
+                    showConnection(false, 'Dev server disconnected, reconnecting in ' + Math.ceil(delay / 1000) + 's...');
+                    retry = setTimeout(connect, delay);
+                };
+            }
+
+            // Try again right away when the network or the tab comes back
+            function reconnectNow() {
+                if (retry !== undefined && document.visibilityState !== 'hidden') {
+                    connect();
+                }
+            }
+            window.addEventListener('online', reconnectNow);
+            document.addEventListener('visibilitychange', reconnectNow);
+
+            connect();
+        })();
+    </script>`
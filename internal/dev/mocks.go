@@ -0,0 +1,196 @@
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/functions"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// mockWildcard is a fixture arg matching any value
+const mockWildcard = "*"
+
+// mockFixture is one canned answer of a mocked function. Args, when set,
+// selects the calls it answers: "*" matches any value and objects match when
+// the fields they list match. Either Result or Error is returned.
+type mockFixture struct {
+	Args   []json.RawMessage `json:"args"`
+	Result json.RawMessage   `json:"result"`
+	Error  *functions.Error  `json:"error"`
+	Delay  string            `json:"delay"`
+}
+
+// registerMocks registers a function for each fixture file in dev.mocks,
+// named service.function.json, so calls are answered from fixtures instead
+// of the server package. It returns the interceptor serving the fixtures;
+// the functions answer the calls no fixture matches with not_found.
+func (s *Server) registerMocks() (functions.Interceptor, error) {
+	dir := s.config.Dev.Mocks
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s, expected service.function.json files", dir)
+	}
+
+	for _, file := range files {
+		key := strings.TrimSuffix(filepath.Base(file), ".json")
+		serviceName, functionName, found := strings.Cut(key, ".")
+		if !found {
+			return nil, fmt.Errorf("invalid fixture file %s, expected service.function.json", file)
+		}
+		if _, err := loadFixtures(file); err != nil {
+			return nil, err
+		}
+
+		// Only calls no fixture matches reach the function
+		fixtures := filepath.ToSlash(file)
+		placeholder := func() (interface{}, error) {
+			return nil, functions.Errorf(functions.CodeNotFound, "no fixture in %s matches the arguments", fixtures)
+		}
+		if err := s.registry.RegisterFunction(serviceName, functionName, placeholder,
+			functions.WithDescription("Mocked by "+filepath.ToSlash(file))); err != nil {
+			return nil, err
+		}
+	}
+	return MockInterceptor(dir), nil
+}
+
+// MockInterceptor answers calls from the fixtures in dir. Calls of functions
+// without a fixture file, or that no fixture matches, go on to the
+// function. Fixture files are read on every call, so edits apply without
+// restarting the dev server.
+func MockInterceptor(dir string) functions.Interceptor {
+	return func(ctx context.Context, info *functions.CallInfo, next functions.Handler) (*anypb.Any, error) {
+		file := filepath.Join(dir, info.ServiceName+"."+info.FunctionName+".json")
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return next(ctx, info)
+		}
+		fixtures, err := loadFixtures(file)
+		if err != nil {
+			return nil, functions.NewError(functions.CodeInternal, err.Error())
+		}
+
+		fixture := matchFixture(fixtures, info.Args)
+		if fixture == nil {
+			return next(ctx, info)
+		}
+
+		if fixture.Delay != "" {
+			delay, err := time.ParseDuration(fixture.Delay)
+			if err != nil {
+				return nil, functions.Errorf(functions.CodeInternal, "invalid delay in %s: %v", filepath.ToSlash(file), err)
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if fixture.Error != nil {
+			return nil, fixture.Error
+		}
+		result := fixture.Result
+		if result == nil {
+			result = json.RawMessage("null")
+		}
+		return &anypb.Any{TypeUrl: "type.googleapis.com/google.protobuf.Value", Value: result}, nil
+	}
+}
+
+// loadFixtures reads a fixture file holding one fixture or a list of them
+func loadFixtures(file string) ([]mockFixture, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var fixtures []mockFixture
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &fixtures)
+	} else {
+		var fixture mockFixture
+		err = json.Unmarshal(data, &fixture)
+		fixtures = append(fixtures, fixture)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", filepath.ToSlash(file), err)
+	}
+	return fixtures, nil
+}
+
+// matchFixture returns the first fixture matching the call's arguments
+func matchFixture(fixtures []mockFixture, args []*anypb.Any) *mockFixture {
+	for i := range fixtures {
+		fixture := &fixtures[i]
+		if fixture.Args == nil {
+			return fixture
+		}
+		if len(fixture.Args) != len(args) {
+			continue
+		}
+
+		matches := true
+		for j, expected := range fixture.Args {
+			if !matchArg(expected, args[j].GetValue()) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return fixture
+		}
+	}
+	return nil
+}
+
+// matchArg reports whether an argument matches a fixture's expected value
+func matchArg(expected json.RawMessage, actual []byte) bool {
+	var want, got interface{}
+	if json.Unmarshal(expected, &want) != nil || json.Unmarshal(actual, &got) != nil {
+		return false
+	}
+	return matchValue(want, got)
+}
+
+func matchValue(want, got interface{}) bool {
+	if want == mockWildcard {
+		return true
+	}
+
+	switch want := want.(type) {
+	case map[string]interface{}:
+		object, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range want {
+			if !matchValue(value, object[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		list, ok := got.([]interface{})
+		if !ok || len(list) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !matchValue(want[i], list[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(want, got)
+	}
+}
@@ -0,0 +1,86 @@
+package dev
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/build"
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// buildGraph holds the directories of the packages the app and the server
+// functions are built from, to rebuild only what a change affects
+type buildGraph struct {
+	app       map[string]bool
+	functions map[string]bool
+}
+
+// loadBuildGraph lists the packages of the app and the server functions. A
+// graph that cannot be listed, e.g. because of a syntax error, is nil and
+// changes fall back to rebuilding by location.
+func (s *Server) loadBuildGraph() buildGraph {
+	var graph buildGraph
+	appEnv := append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	appPatterns := []string{"./" + filepath.ToSlash(filepath.Dir(s.config.EntryFile()))}
+	for _, name := range build.ChunkNames(s.config) {
+		appPatterns = append(appPatterns, "./"+filepath.ToSlash(filepath.Clean(s.config.Build.Chunks[name])))
+	}
+	for _, name := range s.config.AppNames() {
+		appPatterns = append(appPatterns, "./"+filepath.ToSlash(filepath.Dir(s.config.Apps[name].Entry)))
+	}
+	graph.app, _ = packageDirs(appEnv, appPatterns...)
+
+	if s.config.Dev.Mocks == "" {
+		pattern := "./" + filepath.ToSlash(filepath.Dir(functions.HostMainFile))
+		if _, err := os.Stat(functions.HostMainFile); err != nil {
+			pattern = "./" + filepath.ToSlash(filepath.Clean(build.FunctionsDir(s.config))) + "/..."
+		}
+		graph.functions, _ = packageDirs(os.Environ(), pattern)
+	}
+	return graph
+}
+
+// packageDirs returns the directories of the non-standard packages the
+// patterns depend on, themselves included
+func packageDirs(env []string, patterns ...string) (map[string]bool, error) {
+	cmd := exec.Command("go", append([]string{"list", "-e", "-deps", "-f", "{{if not .Standard}}{{.Dir}}{{end}}"}, patterns...)...)
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]bool)
+	for _, dir := range strings.Split(string(output), "\n") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs[dir] = true
+		}
+	}
+	return dirs, nil
+}
+
+// affects reports which targets a changed Go source has to rebuild. Module
+// files affect both; files of packages neither target uses affect none.
+func (g buildGraph) affects(file, functionsDir string) (app, functions bool) {
+	if base := path.Base(file); base == "go.mod" || base == "go.sum" {
+		return true, true
+	}
+	inFunctionsDir := strings.HasPrefix(file, functionsDir+"/")
+
+	dir, err := filepath.Abs(filepath.Dir(filepath.FromSlash(file)))
+	if err != nil || g.app == nil {
+		app = !inFunctionsDir
+	} else {
+		app = g.app[dir]
+	}
+	if err != nil || g.functions == nil {
+		functions = inFunctionsDir
+	} else {
+		// New packages are not in the graph until something imports them
+		functions = g.functions[dir] || inFunctionsDir
+	}
+	return app, functions
+}
@@ -0,0 +1,313 @@
+package dev
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// playgroundPath is where the function playground is served; each function
+// has its page at playgroundPath + "service.function"
+const playgroundPath = dashboardPath + "functions/"
+
+// handlePlayground serves the function playground. The page builds a form
+// for the function named in its URL from the OpenAPI document of the
+// server functions, so it works the same for linked, worker and mocked
+// functions.
+func (s *Server) handlePlayground(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, playgroundPage)
+}
+
+// playgroundPage lists the server functions, or shows a form with an input
+// for each argument of one of them, calls it and shows the response
+const playgroundPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Golem Function Playground</title>
+    <style>
+        body { margin: 0; font: 14px/1.5 system-ui, sans-serif; background: #f4f4f5; color: #18181b; }
+        header { padding: 16px 32px; background: #18181b; color: #f4f4f5; display: flex; gap: 16px; align-items: baseline; }
+        header h1 { margin: 0; font-size: 18px; }
+        header a { color: #a1a1aa; }
+        main { padding: 24px 32px; display: grid; grid-template-columns: minmax(0, 1fr) minmax(0, 1fr); gap: 24px; max-width: 1400px; }
+        section { background: #fff; border-radius: 8px; padding: 16px 24px; box-shadow: 0 1px 2px rgba(0,0,0,0.08); }
+        section.wide { grid-column: 1 / -1; }
+        h2 { margin: 0 0 12px; font-size: 16px; }
+        label { display: block; margin: 12px 0 4px; font-weight: 600; }
+        label .type { font-weight: normal; color: #71717a; font-family: ui-monospace, Menlo, monospace; font-size: 12px; }
+        input[type=text], input[type=number], input[type=datetime-local], select, textarea { width: 100%; box-sizing: border-box; padding: 6px; font: inherit; }
+        textarea, pre, code { font-family: ui-monospace, Menlo, monospace; font-size: 13px; }
+        textarea { min-height: 64px; }
+        fieldset { border: 1px solid #e4e4e7; border-radius: 6px; margin: 8px 0; padding: 4px 12px 12px; }
+        legend { color: #71717a; font-size: 12px; }
+        pre { background: #f4f4f5; padding: 12px; border-radius: 4px; white-space: pre-wrap; word-break: break-all; margin: 8px 0 0; }
+        button { margin-top: 16px; padding: 6px 16px; cursor: pointer; }
+        .ok { color: #15803d; } .failed { color: #b91c1c; } .muted { color: #71717a; }
+        .required { color: #b91c1c; }
+        .null { font-weight: normal; margin-left: 8px; }
+        ul { padding-left: 20px; }
+    </style>
+</head>
+<body>
+    <header><h1 id="title">Function Playground</h1><a href="/_golem/">Dashboard</a><a href="/_golem/functions/">All functions</a></header>
+    <main id="main"><section class="wide muted">Loading...</section></main>
+    <script>
+        const base = '/_golem/functions/';
+        const name = decodeURIComponent(location.pathname.slice(base.length));
+        const main = document.getElementById('main');
+        let spec;
+
+        function escapeHTML(text) {
+            const div = document.createElement('div');
+            div.textContent = String(text);
+            return div.innerHTML;
+        }
+
+        function element(tag, attributes, children) {
+            const node = document.createElement(tag);
+            Object.keys(attributes || {}).forEach(function(key) {
+                if (key === 'text') node.textContent = attributes[key];
+                else node.setAttribute(key, attributes[key]);
+            });
+            (children || []).forEach(function(child) { node.appendChild(child); });
+            return node;
+        }
+
+        // resolve follows $ref to the component schemas
+        function resolve(schema) {
+            while (schema && schema.$ref) {
+                schema = spec.components.schemas[schema.$ref.split('/').pop()];
+            }
+            return schema || {};
+        }
+
+        // nonNull returns the schema of a nullable (pointer) type without
+        // the null alternative, or undefined for other schemas
+        function nonNull(schema) {
+            if (!schema.anyOf || schema.anyOf.length !== 2) return undefined;
+            const rest = schema.anyOf.filter(function(s) { return s.type !== 'null'; });
+            return rest.length === 1 ? rest[0] : undefined;
+        }
+
+        function describe(schema) {
+            if (schema.$ref) return schema.$ref.split('/').pop();
+            schema = resolve(schema);
+            const inner = nonNull(schema);
+            if (inner) return describe(inner) + ' | null';
+            if (schema.enum) return schema.enum.join(' | ');
+            if (schema.format) return schema.format;
+            if (schema.type === 'array') return describe(schema.items || {}) + '[]';
+            if (schema.type === 'object' && schema.additionalProperties) return 'map of ' + describe(schema.additionalProperties);
+            return schema.type || 'any JSON';
+        }
+
+        function sample(schema) {
+            schema = resolve(schema);
+            if (nonNull(schema)) return null;
+            switch (schema.type) {
+            case 'array': return [];
+            case 'object': return {};
+            case 'string': return '';
+            case 'integer': case 'number': return 0;
+            case 'boolean': return false;
+            }
+            return null;
+        }
+
+        // field returns an input for a value of schema and a function
+        // reading its value back
+        function field(schema, depth) {
+            const resolved = resolve(schema);
+
+            const inner = nonNull(resolved);
+            if (inner) {
+                const wrapped = field(inner, depth);
+                const isNull = element('input', { type: 'checkbox' });
+                const toggle = element('label', { class: 'null' }, [isNull, document.createTextNode(' null')]);
+                isNull.onchange = function() { wrapped.node.style.display = isNull.checked ? 'none' : ''; };
+                return { node: element('div', {}, [toggle, wrapped.node]), value: function() { return isNull.checked ? null : wrapped.value(); } };
+            }
+
+            if (resolved.enum) {
+                const select = element('select', {}, resolved.enum.map(function(option) { return element('option', { text: option }); }));
+                return { node: select, value: function() { return select.value; } };
+            }
+
+            switch (resolved.type) {
+            case 'boolean': {
+                const input = element('input', { type: 'checkbox' });
+                return { node: input, value: function() { return input.checked; } };
+            }
+            case 'integer':
+            case 'number': {
+                const input = element('input', { type: 'number', step: resolved.type === 'integer' ? '1' : 'any' });
+                if (resolved.minimum !== undefined) input.min = resolved.minimum;
+                if (resolved.maximum !== undefined) input.max = resolved.maximum;
+                return { node: input, value: function() { return input.value === '' ? 0 : Number(input.value); } };
+            }
+            case 'string': {
+                if (resolved.format === 'date-time') {
+                    const input = element('input', { type: 'datetime-local', step: '1' });
+                    return { node: input, value: function() { return input.value ? new Date(input.value).toISOString() : '0001-01-01T00:00:00Z'; } };
+                }
+                const input = element('input', { type: 'text' });
+                if (resolved.contentEncoding) input.placeholder = resolved.contentEncoding;
+                else if (resolved.format) input.placeholder = resolved.format;
+                if (resolved.maxLength !== undefined) input.maxLength = resolved.maxLength;
+                return { node: input, value: function() { return input.value; } };
+            }
+            case 'object':
+                if (resolved.properties && depth < 4) {
+                    const required = resolved.required || [];
+                    const fields = Object.keys(resolved.properties).map(function(key) {
+                        const child = field(resolved.properties[key], depth + 1);
+                        const label = element('label', {}, [document.createTextNode(key + ' ')]);
+                        if (required.indexOf(key) >= 0) label.appendChild(element('span', { class: 'required', text: '* ' }));
+                        label.appendChild(element('span', { class: 'type', text: describe(resolved.properties[key]) }));
+                        return { key: key, nodes: [label, child.node], value: child.value };
+                    });
+                    const fieldset = element('fieldset', {}, [element('legend', { text: schema.$ref ? describe(schema) : 'object' })]);
+                    fields.forEach(function(f) { f.nodes.forEach(function(n) { fieldset.appendChild(n); }); });
+                    return {
+                        node: fieldset,
+                        value: function() {
+                            const result = {};
+                            fields.forEach(function(f) { result[f.key] = f.value(); });
+                            return result;
+                        },
+                    };
+                }
+            }
+
+            // Arrays, maps and values of any type are entered as JSON
+            const textarea = element('textarea', {});
+            textarea.value = JSON.stringify(sample(resolved));
+            return {
+                node: textarea,
+                value: function() {
+                    try {
+                        return JSON.parse(textarea.value || 'null');
+                    } catch (err) {
+                        throw new Error('Invalid JSON in ' + describe(schema) + ' input: ' + err.message);
+                    }
+                },
+            };
+        }
+
+        function showIndex(operations, missing) {
+            document.title = 'Golem Function Playground';
+            const list = operations.map(function(op) {
+                return '<li><a href="' + base + encodeURIComponent(op.operationId) + '"><code>' + escapeHTML(op.operationId) + '</code></a> ' +
+                    '<span class="muted">' + escapeHTML(op.description || '') + '</span></li>';
+            }).join('');
+            main.innerHTML = '<section class="wide">' +
+                (missing ? '<p class="failed">No function named <code>' + escapeHTML(missing) + '</code> is registered.</p>' : '') +
+                '<h2>Server Functions</h2>' + (list ? '<ul>' + list + '</ul>' : '<p class="muted">No server functions registered</p>') + '</section>';
+        }
+
+        function showFunction(path, op, goTypes) {
+            document.title = op.operationId + ' - Golem Function Playground';
+            document.getElementById('title').textContent = op.operationId;
+
+            const body = op.requestBody.content['application/json'].schema;
+            const args = (body.prefixItems || []).map(function(schema, i) {
+                const input = field(schema, 0);
+                const type = goTypes[i] || describe(schema);
+                return {
+                    nodes: [element('label', {}, [document.createTextNode('Argument ' + (i + 1) + ' '), element('span', { class: 'type', text: type })]), input.node],
+                    value: input.value,
+                };
+            });
+
+            const form = element('section', {}, [element('h2', { text: 'Arguments' })]);
+            if (op.description) form.appendChild(element('p', { class: 'muted', text: op.description }));
+            if (args.length === 0) form.appendChild(element('p', { class: 'muted', text: 'This function takes no arguments' }));
+            args.forEach(function(arg) { arg.nodes.forEach(function(n) { form.appendChild(n); }); });
+
+            let token;
+            if (op.security) {
+                token = element('input', { type: 'text', placeholder: 'Bearer token' });
+                token.value = sessionStorage.getItem('golem-playground-token') || '';
+                form.appendChild(element('label', { text: 'Authorization' }));
+                form.appendChild(token);
+            }
+            const call = element('button', { text: 'Call ' + op.operationId });
+            form.appendChild(call);
+
+            const result = element('section', {}, [element('h2', { text: 'Response' }), element('p', { class: 'muted', text: 'Call the function to see its response' })]);
+            main.innerHTML = '';
+            main.appendChild(form);
+            main.appendChild(result);
+
+            call.onclick = async function() {
+                result.innerHTML = '<h2>Response</h2>';
+                let values;
+                try {
+                    values = args.map(function(arg) { return arg.value(); });
+                } catch (err) {
+                    result.appendChild(element('p', { class: 'failed', text: err.message }));
+                    return;
+                }
+
+                const headers = { 'Content-Type': 'application/json' };
+                if (token && token.value) {
+                    sessionStorage.setItem('golem-playground-token', token.value);
+                    headers['Authorization'] = 'Bearer ' + token.value.replace(/^Bearer\s+/i, '');
+                }
+                const request = JSON.stringify(values);
+                const started = performance.now();
+                try {
+                    const response = await fetch(path, { method: 'POST', headers: headers, body: request });
+                    const text = await response.text();
+                    let pretty = text;
+                    try { pretty = JSON.stringify(JSON.parse(text), null, 2); } catch (err) {}
+                    const elapsed = (performance.now() - started).toFixed(0);
+                    result.appendChild(element('p', { class: response.ok ? 'ok' : 'failed', text: response.status + ' ' + response.statusText + ' in ' + elapsed + ' ms' }));
+                    result.appendChild(element('pre', { text: pretty }));
+                } catch (err) {
+                    result.appendChild(element('p', { class: 'failed', text: String(err) }));
+                }
+
+                let curl = "curl -X POST " + location.origin + path + " -H 'Content-Type: application/json'";
+                if (headers['Authorization']) curl += " -H 'Authorization: " + headers['Authorization'] + "'";
+                curl += " -d '" + request.replace(/'/g, "'\\''") + "'";
+                result.appendChild(element('h2', { text: 'Request' }));
+                result.appendChild(element('pre', { text: curl }));
+            };
+        }
+
+        async function load() {
+            try {
+                const responses = await Promise.all([fetch('/api/openapi.json'), fetch('/api/functions/list')]);
+                spec = await responses[0].json();
+                const list = await responses[1].json();
+
+                const operations = Object.keys(spec.paths).sort().map(function(path) {
+                    const op = spec.paths[path].post;
+                    op.path = path;
+                    return op;
+                });
+                if (!name) {
+                    showIndex(operations);
+                    return;
+                }
+                const op = operations.find(function(op) { return op.operationId === name; });
+                if (!op) {
+                    showIndex(operations, name);
+                    return;
+                }
+
+                // Label arguments with their Go types
+                const info = (list.functions || []).find(function(fn) { return fn.service_name + '.' + fn.name === name; }) || {};
+                const goTypes = (info.arg_types || []).filter(function(type) { return type !== 'context.Context'; });
+                showFunction(op.path, op, goTypes);
+            } catch (err) {
+                main.innerHTML = '<section class="wide failed">Could not load the server functions: ' + escapeHTML(err) + '</section>';
+            }
+        }
+        load();
+    </script>
+</body>
+</html>`
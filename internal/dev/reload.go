@@ -0,0 +1,179 @@
+package dev
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// errRestart is returned by Start when a config change needs a new server
+var errRestart = errors.New("dev server restarting for a config change")
+
+// liveSettings are applied while the dev server runs; changes to any other
+// setting, such as the ports, restart it
+var liveSettings = []string{"dev.watch", "dev.ignore", "dev.hotReload", "server.proxy"}
+
+// Run runs the dev server with cfg and calls load again whenever the files
+// of the config change. Changes to liveSettings are applied in place; any
+// other change restarts the dev server with the new config. An invalid
+// config is reported and the running one kept.
+func Run(cfg *config.Config, load func() (*config.Config, error)) error {
+	restarted := false
+	for {
+		s := NewServer(cfg)
+		s.load = load
+		s.restarted = restarted
+		if err := s.Start(); !errors.Is(err, errRestart) {
+			return err
+		}
+		cfg, restarted = s.next, true
+	}
+}
+
+// configFiles returns the paths the config of the project may be read
+// from, the files of the config's environment and the secrets files
+// included
+func (s *Server) configFiles() []string {
+	files := append([]string(nil), config.FileNames...)
+	env := s.config.Environment()
+	if env != "" {
+		for _, name := range config.FileNames {
+			ext := filepath.Ext(name)
+			files = append(files, strings.TrimSuffix(name, ext)+"."+env+ext)
+		}
+	}
+	return append(files, config.SecretsFiles("", env)...)
+}
+
+// reloadConfig loads the changed config and applies it, returning the
+// settings applied in place
+func (s *Server) reloadConfig() []string {
+	next, err := s.load()
+	if err != nil {
+		log.Printf("❌ %v; still using the previous config", err)
+		s.hub.reportError("Invalid config, still using the previous one\n"+err.Error(), nil)
+		return nil
+	}
+
+	changed := config.Changes(s.config, next)
+	var restart []string
+	for _, path := range changed {
+		if !isLiveSetting(path) {
+			restart = append(restart, path)
+		}
+	}
+	if len(restart) > 0 {
+		fmt.Printf("🔁 %s changed, restarting the dev server...\n", strings.Join(restart, ", "))
+		s.restart(next)
+		return nil
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := s.setProxies(next.Server.Proxy); err != nil {
+		log.Printf("❌ %v; still using the previous config", err)
+		s.hub.reportError("Invalid config, still using the previous one\n"+err.Error(), nil)
+		return nil
+	}
+	s.config.Server.Proxy = next.Server.Proxy
+	s.config.Dev.Watch = next.Dev.Watch
+	s.config.Dev.Ignore = next.Dev.Ignore
+	if s.config.Dev.HotReload != next.Dev.HotReload {
+		// Browsers reload into the page with or without the client
+		s.config.Dev.HotReload = next.Dev.HotReload
+		if err := s.writeDevHTML(); err != nil {
+			log.Printf("❌ %v", err)
+		}
+		s.hub.reload()
+	}
+	fmt.Printf("⚙️  Applied %s\n", strings.Join(changed, ", "))
+	return changed
+}
+
+// isLiveSetting reports whether the setting at path is in liveSettings or
+// under one of them
+func isLiveSetting(path string) bool {
+	for _, live := range liveSettings {
+		if path == live || strings.HasPrefix(path, live+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// restarting reports whether the server is stopping for a restart
+func (s *Server) restarting() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// restart stops the server so Run starts it again with next
+func (s *Server) restart(next *config.Config) {
+	s.stopOnce.Do(func() {
+		s.next = next
+		close(s.stopping)
+	})
+}
+
+// onShutdown registers f to stop part of the server before it restarts. It
+// runs right away when the server is already stopping.
+func (s *Server) onShutdown(f func()) {
+	s.shutdownMutex.Lock()
+	if !s.stopped {
+		s.closers = append(s.closers, f)
+		s.shutdownMutex.Unlock()
+		return
+	}
+	s.shutdownMutex.Unlock()
+	f()
+}
+
+// shutdown runs the functions registered with onShutdown, the latest first
+func (s *Server) shutdown() {
+	s.shutdownMutex.Lock()
+	s.stopped = true
+	closers := s.closers
+	s.closers = nil
+	s.shutdownMutex.Unlock()
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		closers[i]()
+	}
+}
+
+// setProxies serves the paths of rules from their backends, like the
+// production server does, in place of the previous rules
+func (s *Server) setProxies(rules []config.ProxyRule) error {
+	mux := http.NewServeMux()
+	if err := server.RegisterProxies(mux, rules, slog.Default()); err != nil {
+		return err
+	}
+	s.proxies.Store(mux)
+	return nil
+}
+
+// proxyRequests forwards the requests under a server.proxy path to their
+// backend, and serves the others with next
+func (s *Server) proxyRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if proxies := s.proxies.Load(); proxies != nil {
+			if handler, pattern := proxies.Handler(r); pattern != "" {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
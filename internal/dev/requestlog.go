@@ -0,0 +1,117 @@
+package dev
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Kinds of requests shown in the request log
+const (
+	requestAPI    = "api"
+	requestWasm   = "wasm"
+	requestSocket = "ws"
+	requestPage   = "page"
+	requestAsset  = "asset"
+)
+
+// requestKind classifies a request for the request log
+func requestKind(r *http.Request) string {
+	switch {
+	case strings.EqualFold(r.Header.Get("Upgrade"), "websocket"):
+		return requestSocket
+	case strings.HasPrefix(r.URL.Path, "/api/"):
+		return requestAPI
+	case path.Ext(r.URL.Path) == ".wasm":
+		return requestWasm
+	case path.Ext(r.URL.Path) == "" || path.Ext(r.URL.Path) == ".html":
+		return requestPage
+	default:
+		return requestAsset
+	}
+}
+
+// loggingResponseWriter records the status and size of a response. It
+// passes flushes through for streamed calls and hijacks for WebSockets.
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *loggingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *loggingResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *loggingResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response does not support hijacking")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// logRequests logs the method, path, status, duration, size and kind of
+// every request once it completes, so the latency of function calls is
+// visible while developing. Static assets are left out with dev.quietAssets.
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind := requestKind(r)
+		if kind == requestAsset && s.config.Dev.QuietAssets {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		recorder := &loggingResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.Printf("%-5s %-6s %s %d %v %s", kind, r.Method, r.URL.RequestURI(), status,
+			time.Since(start).Round(10*time.Microsecond), formatBytes(recorder.bytes))
+	})
+}
+
+// formatBytes renders a response size for the request log
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fkB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
@@ -2,25 +2,50 @@ package dev
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/Nu11ified/golem/internal/build"
 	"github.com/Nu11ified/golem/internal/config"
 	"github.com/Nu11ified/golem/internal/functions"
-	"nhooyr.io/websocket"
+	"github.com/Nu11ified/golem/internal/server"
 )
 
 // Server represents the development server
 type Server struct {
 	config   *config.Config
 	registry *functions.Registry
+
+	worker       atomic.Pointer[functionWorker]
+	reloadMutex  sync.Mutex
+	workerBuilds int
+
+	hub         *reloadHub
+	diagnostics diagnosticSet
+	routes      routeCache
+	proxies     atomic.Pointer[http.ServeMux] // server.proxy rules
+
+	load          func() (*config.Config, error) // reloads the config when its files change
+	restarted     bool                           // started again for a config change
+	next          *config.Config                 // config to restart with
+	stopping      chan struct{}                  // closed to restart the server
+	stopOnce      sync.Once
+	shutdownMutex sync.Mutex
+	closers       []func()
+	stopped       bool
 }
 
 // NewServer creates a new development server
@@ -28,6 +53,8 @@ func NewServer(config *config.Config) *Server {
 	return &Server{
 		config:   config,
 		registry: functions.NewRegistry(),
+		hub:      newReloadHub(),
+		stopping: make(chan struct{}),
 	}
 }
 
@@ -35,18 +62,13 @@ func NewServer(config *config.Config) *Server {
 func (s *Server) Start() error {
 	port := s.config.Dev.Port
 
-	// Initialize function registry for development
-	if err := s.initializeFunctionRegistry(); err != nil {
-		log.Printf("Warning: Failed to initialize function registry: %v", err)
-	}
-
-	// Set up file watcher for hot reload
-	if s.config.Dev.HotReload {
-		go s.watchFiles()
+	// Load .env files for the server functions and the app's public variables
+	if err := s.loadEnv(); err != nil {
+		return err
 	}
 
-	// Start gRPC server in background for development
-	go s.startDevGRPCServer()
+	// Set up file watcher for hot reload and config changes
+	go s.watchFiles()
 
 	// Set up HTTP handlers
 	mux := http.NewServeMux()
@@ -54,9 +76,165 @@ func (s *Server) Start() error {
 	// Serve static files
 	mux.Handle("/", s.createStaticHandler())
 
+	// Server functions run in a worker process that is rebuilt when they
+	// change; without one, the functions linked into this binary are served.
+	// Mocks are served without building the server package at all.
+	var served http.Handler = mux
+	if s.config.Dev.Mocks == "" && s.startFunctionWorker() {
+		mux.Handle("/api/", s.workerProxy())
+		s.onShutdown(func() { s.worker.Load().stop() })
+	} else {
+		if err := s.initializeFunctionRegistry(); err != nil {
+			if s.config.Dev.Mocks != "" {
+				return err
+			}
+			log.Printf("Warning: Failed to initialize function registry: %v", err)
+		}
+		s.registerAPI(mux)
+
+		// Sessions of the functions served here; a worker keeps its own
+		var err error
+		if served, err = s.withSessions(mux); err != nil {
+			return err
+		}
+	}
+
+	// Start gRPC server in background for development
+	go s.startDevGRPCServer()
+
+	// Compiler errors of the latest builds, for editor integrations
+	mux.HandleFunc("/api/dev/diagnostics", s.handleDiagnostics)
+
+	// Dashboard of the build, routes, server functions and browsers
+	mux.HandleFunc(dashboardPath, s.handleDashboard)
+	mux.HandleFunc(dashboardPath+"status", s.handleDashboardStatus)
+	mux.HandleFunc(playgroundPath, s.handlePlayground)
+
+	// WebSocket endpoint for hot reload, which dev.hotReload may turn on
+	// while serving
+	mux.Handle("/ws", s.hub)
+	s.onShutdown(s.hub.close)
+
+	// Any origin may call the API in dev, unless cors says otherwise
+	cors := s.config.CORS
+	if len(cors.Origins) == 0 {
+		cors.Origins = []string{"*"}
+	}
+	api, err := server.CORS(cors, redirectAPIIndex(served))
+	if err != nil {
+		return err
+	}
+	if err := s.setProxies(s.config.Server.Proxy); err != nil {
+		return err
+	}
+	handler := s.logRequests(s.proxyRequests(api))
+
+	scheme := "http"
+	var certFile, keyFile string
+	if s.config.Dev.HTTPS {
+		if certFile, keyFile, err = s.devCertificate(); err != nil {
+			return err
+		}
+		scheme = "https"
+	}
+
+	listener, err := listenDev(port)
+	if err != nil {
+		return err
+	}
+	port = listener.Addr().(*net.TCPAddr).Port
+	url := fmt.Sprintf("%s://localhost:%d", scheme, port)
+
+	fmt.Printf("🌟 Golem dev server running at %s\n", url)
+	fmt.Println("📁 Serving files from:", s.config.Output)
+	fmt.Printf("🔗 API endpoints available at: %s/api/\n", url)
+	for _, name := range s.config.AppNames() {
+		fmt.Printf("🪟 App %s at: %s%s\n", name, url, s.config.AppPath(name))
+	}
+	fmt.Printf("🧭 Dev dashboard at: %s%s\n", url, dashboardPath)
+	fmt.Printf("🧪 Function playground at: %s%s\n", url, playgroundPath)
+
+	if s.config.Dev.HotReload {
+		fmt.Println("🔥 Hot reload enabled")
+	}
+	if s.config.Dev.Debug {
+		printDebugHelp()
+	}
+	if s.config.Dev.Open && !s.restarted {
+		openBrowser(url)
+	}
+
+	httpServer := &http.Server{Handler: handler}
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.config.Dev.HTTPS {
+			serveErr <- httpServer.ServeTLS(listener, certFile, keyFile)
+		} else {
+			serveErr <- httpServer.Serve(listener)
+		}
+	}()
+	select {
+	case err := <-serveErr:
+		return err
+	case <-s.stopping:
+	}
+
+	// Browsers reconnect and reload once the restarted server is back
+	httpServer.Close()
+	s.shutdown()
+	return errRestart
+}
+
+// devPortAttempts is how many consecutive ports are tried, starting with
+// the configured one, before the dev server gives up
+const devPortAttempts = 20
+
+// listenDev listens on port, or on the next free port when it is taken
+func listenDev(port int) (net.Listener, error) {
+	for attempt := 0; ; attempt++ {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port+attempt))
+		if err == nil {
+			if attempt > 0 {
+				fmt.Printf("⚠️  Port %d is in use, using port %d instead\n", port, port+attempt)
+			}
+			return listener, nil
+		}
+		if port == 0 || !errors.Is(err, syscall.EADDRINUSE) || attempt == devPortAttempts-1 {
+			return nil, fmt.Errorf("failed to listen on port %d: %w", port+attempt, err)
+		}
+	}
+}
+
+// openBrowser opens url in the default browser
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("Warning: Failed to open the browser: %v", err)
+		return
+	}
+	go cmd.Wait()
+}
+
+// registerAPI registers the function API endpoints served from the registry
+func (s *Server) registerAPI(mux *http.ServeMux) {
 	// API endpoint for function calls during development
 	grpcServer := functions.NewGRPCServer(s.registry)
 	mux.HandleFunc("/api/functions", grpcServer.HTTPHandler())
+	mux.HandleFunc("/api/functions/batch", grpcServer.BatchHandler())
+	mux.HandleFunc("/api/functions/stream", grpcServer.StreamHandler())
+	mux.HandleFunc("/api/functions/ws", grpcServer.WebSocketHandler())
+	mux.HandleFunc("/api/functions/mux", grpcServer.MuxHandler())
+	mux.HandleFunc("/api/functions/", grpcServer.HTTPHandler())
+	mux.HandleFunc("/api/openapi.json", grpcServer.OpenAPIHandler(s.config.ProjectName, s.config.Version))
+	mux.HandleFunc("/api/docs", functions.DocsHandler("/api/openapi.json"))
 
 	// API root endpoint - show available endpoints
 	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
@@ -65,15 +243,6 @@ func (s *Server) Start() error {
 			return
 		}
 
-		if r.Method == "OPTIONS" {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Content-Type", "application/json")
 
 		// Get registered functions for display
@@ -83,9 +252,17 @@ func (s *Server) Start() error {
 			"message": "Golem Development API",
 			"version": "0.1.0",
 			"endpoints": map[string]interface{}{
-				"GET /api/":               "This endpoint - API information",
-				"GET /api/functions/list": "List all registered server functions",
-				"POST /api/functions":     "Call a server function",
+				"GET /api/":                                "This endpoint - API information",
+				"GET /api/functions/list":                  "List all registered server functions",
+				"POST /api/functions":                      "Call a server function",
+				"POST /api/functions/batch":                "Call several server functions in one request",
+				"POST /api/functions/stream":               "Call a server function and stream partial results (SSE)",
+				"GET /api/functions/ws":                    "Bidirectional streaming calls over WebSocket",
+				"GET /api/functions/mux":                   "Multiplexed calls and server push over one WebSocket",
+				"POST /api/functions/{service}/{function}": "Call a server function with the args array as the body",
+				"GET /api/openapi.json":                    "OpenAPI document of the server functions",
+				"GET /api/docs":                            "Interactive API documentation",
+				"GET /api/dev/diagnostics":                 "Compiler errors of the latest dev builds",
 			},
 			"registered_functions": len(functions),
 			"functions":            functions,
@@ -113,15 +290,6 @@ func (s *Server) Start() error {
 
 	// List functions endpoint for development debugging
 	mux.HandleFunc("/api/functions/list", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "OPTIONS" {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Content-Type", "application/json")
 
 		functions := s.registry.ListFunctions("")
@@ -130,61 +298,50 @@ func (s *Server) Start() error {
 			"functions": functions,
 		})
 	})
+}
 
-	// WebSocket endpoint for hot reload
-	if s.config.Dev.HotReload {
-		mux.HandleFunc("/ws", s.handleWebSocket)
+func (s *Server) initializeFunctionRegistry() error {
+	// Register user functions from the server package, or fixtures in mock mode
+	var mocks functions.Interceptor
+	if s.config.Dev.Mocks != "" {
+		var err error
+		if mocks, err = s.registerMocks(); err != nil {
+			return fmt.Errorf("failed to load mocks: %w", err)
+		}
+	} else if err := s.registerUserFunctions(); err != nil {
+		log.Printf("Warning: Failed to register user functions: %v", err)
 	}
 
-	fmt.Printf("🌟 Golem dev server running at http://localhost:%d\n", port)
-	fmt.Println("📁 Serving files from:", s.config.Output)
-	fmt.Printf("🔗 API endpoints available at: http://localhost:%d/api/\n", port)
-
-	if s.config.Dev.HotReload {
-		fmt.Println("🔥 Hot reload enabled")
+	if err := server.ApplyFunctionLimits(s.registry, s.config.Server.Limits); err != nil {
+		return err
 	}
-
-	return http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
-}
-
-func (s *Server) initializeFunctionRegistry() error {
-	// Discover functions from the server directory
-	serverDir := s.config.Server.Functions
-	if serverDir == "" {
-		serverDir = "src/server"
+	if err := server.ApplyIdempotencyWindow(s.registry, s.config.Server.IdempotencyWindow); err != nil {
+		return err
 	}
 
-	if err := s.registry.DiscoverFunctions(serverDir); err != nil {
-		log.Printf("Warning: Failed to discover functions from %s: %v", serverDir, err)
+	injector, err := FaultInjector(s.config.Dev.Faults)
+	if err != nil {
+		return err
+	}
+	if injector != nil {
+		s.registry.Use(injector)
+		log.Printf("🐢 Injecting faults into server function calls (dev.faults)")
 	}
 
-	// Register user functions from the server package
-	if err := s.registerUserFunctions(); err != nil {
-		log.Printf("Warning: Failed to register user functions: %v", err)
+	// Fixtures answer after the other interceptors, in place of the functions
+	if mocks != nil {
+		s.registry.Use(mocks)
+		log.Printf("🎭 Answering server function calls from the fixtures in %s", s.config.Dev.Mocks)
 	}
 
 	log.Printf("🎯 Function registry initialized with %d functions", len(s.registry.ListFunctions("")))
 	return nil
 }
 
-// registerUserFunctions registers functions from the user's server package
+// registerUserFunctions registers the functions of the user's server packages.
+// They are compiled into this binary by "golem generate functions" and
+// registered with the global registry when their packages initialize.
 func (s *Server) registerUserFunctions() error {
-	// First, try to build and import server packages to trigger their init() functions
-	serverDir := s.config.Server.Functions
-	if serverDir == "" {
-		serverDir = "src/server"
-	}
-
-	// Build and import server packages (this will trigger init() functions)
-	if err := s.registry.BuildAndImportServerPackages(serverDir); err != nil {
-		log.Printf("Warning: Could not build server packages: %v", err)
-	}
-
-	// For development mode, register demo functions directly if they exist
-	if err := s.registerDemoFunctions(); err != nil {
-		log.Printf("Warning: Could not register demo functions: %v", err)
-	}
-
 	// Copy all functions from the global registry to this server's registry
 	if err := s.registry.RegisterFromGlobal(); err != nil {
 		return fmt.Errorf("failed to register functions from global registry: %w", err)
@@ -200,60 +357,6 @@ func (s *Server) registerUserFunctions() error {
 	return nil
 }
 
-// registerDemoFunctions registers demo functions directly for development
-func (s *Server) registerDemoFunctions() error {
-	// Define demo functions that match the ones in the server package
-	helloFunc := func(name string) string {
-		return fmt.Sprintf("Hello, %s! This message is from the Go server.", name)
-	}
-
-	getUserProfileFunc := func(userID int) (map[string]interface{}, error) {
-		if userID <= 0 {
-			return nil, fmt.Errorf("invalid user ID: %d", userID)
-		}
-		return map[string]interface{}{
-			"id":    userID,
-			"name":  "John Doe",
-			"email": "john@example.com",
-			"role":  "admin",
-		}, nil
-	}
-
-	calculateFunc := func(a, b float64, operation string) (float64, error) {
-		switch operation {
-		case "add":
-			return a + b, nil
-		case "subtract":
-			return a - b, nil
-		case "multiply":
-			return a * b, nil
-		case "divide":
-			if b == 0 {
-				return 0, fmt.Errorf("division by zero")
-			}
-			return a / b, nil
-		default:
-			return 0, fmt.Errorf("unknown operation: %s", operation)
-		}
-	}
-
-	// Register the demo functions
-	if err := s.registry.RegisterFunction("server", "Hello", helloFunc); err != nil {
-		return fmt.Errorf("failed to register Hello function: %w", err)
-	}
-
-	if err := s.registry.RegisterFunction("server", "GetUserProfile", getUserProfileFunc); err != nil {
-		return fmt.Errorf("failed to register GetUserProfile function: %w", err)
-	}
-
-	if err := s.registry.RegisterFunction("server", "Calculate", calculateFunc); err != nil {
-		return fmt.Errorf("failed to register Calculate function: %w", err)
-	}
-
-	log.Printf("Registered demo functions for development")
-	return nil
-}
-
 func (s *Server) startDevGRPCServer() {
 	port := s.config.Server.GRPC.Port
 	if port == 0 {
@@ -266,7 +369,30 @@ func (s *Server) startDevGRPCServer() {
 		return
 	}
 
-	grpcServer := functions.CreateGRPCServer(s.registry)
+	// Connections are forwarded to the current function worker, if any
+	if s.worker.Load() != nil {
+		s.onShutdown(func() { listener.Close() })
+		fmt.Printf("🔧 Dev gRPC server running at localhost:%d\n", port)
+		for {
+			conn, err := listener.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if err != nil {
+				log.Printf("Dev gRPC server error: %v", err)
+				return
+			}
+			go s.proxyGRPC(conn)
+		}
+	}
+
+	grpcServer, err := server.NewGRPCServer(s.registry, s.config.Server.GRPC)
+	if err != nil {
+		listener.Close()
+		log.Printf("Warning: Failed to start dev gRPC server: %v", err)
+		return
+	}
+	s.onShutdown(grpcServer.Stop)
 	fmt.Printf("🔧 Dev gRPC server running at localhost:%d\n", port)
 
 	if err := grpcServer.Serve(listener); err != nil {
@@ -280,24 +406,48 @@ func (s *Server) createStaticHandler() http.Handler {
 		log.Printf("Error generating dev files: %v", err)
 	}
 
-	// Serve from the development directory
+	// Serve from the development directory, then the public directory
 	devDir := ".golem/dev"
-	fs := http.FileServer(http.Dir(devDir))
+	var fs http.Handler = http.FileServer(layeredFS{http.Dir(devDir), http.Dir(build.PublicDir(s.config))})
+	// Serve the page of their app for client-side routes so deep links
+	// work in dev
+	fs = server.FallbackFS(os.DirFS(devDir), s.config, fs)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Add CORS headers for development
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
-		// Set proper MIME type for WASM files
+		// Set proper MIME type for WASM files, which always have to be
+		// revalidated so the browser and its debugger see the latest build
 		if filepath.Ext(r.URL.Path) == ".wasm" {
 			w.Header().Set("Content-Type", "application/wasm")
+			w.Header().Set("Cache-Control", "no-cache")
 		}
 
 		fs.ServeHTTP(w, r)
 	})
 }
 
+// layeredFS serves a file from the first file system that has it, so the
+// generated dev files take precedence over the project's public files
+type layeredFS []http.FileSystem
+
+// Open opens name from the first layer that has it
+func (l layeredFS) Open(name string) (http.File, error) {
+	var firstErr error
+	for _, layer := range l {
+		file, err := layer.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
 func (s *Server) generateDevFiles() error {
 	// Ensure dev directory exists
 	devDir := ".golem/dev"
@@ -306,39 +456,104 @@ func (s *Server) generateDevFiles() error {
 	}
 
 	// Generate development HTML with hot reload
-	html := s.generateDevHTML()
-	htmlPath := filepath.Join(devDir, "index.html")
-	if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
+	if err := s.writeDevHTML(); err != nil {
 		return err
 	}
 
 	// Copy/build WASM for development
+	if err := installWasmExec(devDir); err != nil {
+		return err
+	}
+	// Sources hooks generate code the app may import, e.g. translations
+	if err := build.RunHooks(s.config, "sources", "pre"); err != nil {
+		return err
+	}
+	if err := s.generateComponents(); err != nil {
+		return err
+	}
+	if err := build.RunHooks(s.config, "sources", "post"); err != nil {
+		return err
+	}
 	return s.buildDevWasm()
 }
 
-func (s *Server) generateDevHTML() string {
-	hotReloadScript := ""
-	if s.config.Dev.HotReload {
-		hotReloadScript = `
-    <script>
-        // Hot reload WebSocket connection
-        const ws = new WebSocket('ws://localhost:` + fmt.Sprintf("%d", s.config.Dev.Port) + `/ws');
-        ws.onmessage = function(event) {
-            if (event.data === 'reload') {
-                window.location.reload();
-            }
-        };
-    </script>`
+// generateComponents generates the Go code of every .golem component,
+// reporting parse errors like compile errors
+func (s *Server) generateComponents() error {
+	start := time.Now()
+	generated, err := build.GenerateComponents("src")
+	if reported := s.reportBuild(targetApp, start, []byte(fmt.Sprint(err)), err); reported != nil {
+		return fmt.Errorf("failed to compile components: %v", reported)
+	}
+	if len(generated) > 0 {
+		fmt.Printf("🧱 Compiled %d .golem components\n", len(generated))
+	}
+	return nil
+}
+
+// generateComponent regenerates the Go code of a changed .golem component
+// and returns the generated file. A deleted component loses its file.
+func (s *Server) generateComponent(file string) (string, error) {
+	generated := build.ComponentFile(filepath.FromSlash(file))
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		os.Remove(generated)
+		return filepath.ToSlash(generated), nil
+	}
+
+	start := time.Now()
+	_, err := build.GenerateComponent(filepath.FromSlash(file))
+	if reported := s.reportBuild(targetApp, start, []byte(fmt.Sprint(err)), err); reported != nil {
+		return "", reported
+	}
+	return filepath.ToSlash(generated), nil
+}
+
+// writeDevHTML writes the dev page of the main app and of every app in apps,
+// from the project's template when it has one
+func (s *Server) writeDevHTML() error {
+	html, err := s.generateDevHTML("")
+	if err != nil {
+		return err
 	}
+	if err := os.WriteFile(filepath.Join(".golem/dev", "index.html"), []byte(html), 0644); err != nil {
+		return err
+	}
+	for _, name := range s.config.AppNames() {
+		html, err := s.generateDevHTML(name)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(".golem/dev", filepath.FromSlash(strings.Trim(s.config.AppPath(name), "/")))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(html), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	cacheBuster := fmt.Sprintf("%d", time.Now().UnixNano())
+// generateDevHTML returns the dev page of the named app, "" for the main one
+func (s *Server) generateDevHTML(app string) (string, error) {
+	page := s.devPage(app)
+	if source, err := os.ReadFile(s.devTemplate()); err == nil {
+		return renderDevTemplate(s.devTemplate(), string(source), page)
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read dev page template: %w", err)
+	}
+
+	baseTag := ""
+	if page.Base != "" {
+		baseTag = "\n    " + page.Base
+	}
 
 	return `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>` + s.config.ProjectName + ` - Development</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">` + baseTag + `
+    <title>` + page.Title + ` - Development</title>
     <style>
         body { font-family: system-ui, sans-serif; margin: 0; padding: 20px; }
         .app { max-width: 800px; margin: 0 auto; }
@@ -356,59 +571,16 @@ func (s *Server) generateDevHTML() string {
     </style>
 </head>
 <body>
-    <div class="dev-banner">🔥 Development Mode - Hot Reload Enabled | gRPC Server Active</div>
+    <div class="dev-banner" id="golem-dev-banner">🔥 Development Mode - <span id="golem-connection">Hot Reload Enabled</span> | gRPC Server Active</div>
     <div id="app">Loading Golem app...</div>
-    <script src="wasm_exec.js?` + cacheBuster + `"></script>
-    <script>
-        const go = new Go();
-        // Shim for older wasm_exec.js files with newer Go compilers.
-        if (go.importObject.go && !go.importObject.gojs) {
-            console.log("Shimming go->gojs import object.");
-            go.importObject.gojs = go.importObject.go;
-            delete go.importObject.go;
-        }
-
-        console.log("Attempting to instantiate wasm with import object:", go.importObject);
-
-        const wasmModule = fetch("app.wasm?` + cacheBuster + `");
-        const instantiateWasm = async () => {
-            try {
-                let instance;
-                if (WebAssembly.instantiateStreaming) {
-                    instance = (await WebAssembly.instantiateStreaming(wasmModule, go.importObject)).instance;
-                } else {
-                    const response = await wasmModule;
-                    const bytes = await response.arrayBuffer();
-                    instance = (await WebAssembly.instantiate(bytes, go.importObject)).instance;
-                }
-                go.run(instance);
-            } catch (err) {
-                document.getElementById('app').innerHTML =
-                    '<h1>❌ Error loading WebAssembly</h1>' +
-                    '<h2>See browser developer console for details.</h2>' +
-                    '<pre>' + err.toString() + '</pre>';
-                console.error('WASM Instantiation Error:', err);
-                console.error('Import object passed to instantiate:', go.importObject);
-            }
-        };
-        instantiateWasm();
-    </script>` + hotReloadScript + `
+    ` + page.Loader + page.HotReload + `
 </body>
-</html>`
+</html>`, nil
 }
 
-func (s *Server) buildDevWasm() error {
-	// First, ensure server packages are discovered and import file is generated
-	serverDir := s.config.Server.Functions
-	if serverDir == "" {
-		serverDir = "src/server"
-	}
-
-	// Generate server imports file
-	if err := s.registry.BuildAndImportServerPackages(serverDir); err != nil {
-		log.Printf("Warning: Could not generate server imports: %v", err)
-	}
-
+// installWasmExec copies the Go runtime's wasm_exec.js into devDir once per
+// dev session; rebuilds only recompile the app
+func installWasmExec(devDir string) error {
 	// Find wasm_exec.js from Go installation.
 	var wasmExecSrc string
 	goRootCmd := exec.Command("go", "env", "GOROOT")
@@ -431,7 +603,6 @@ func (s *Server) buildDevWasm() error {
 		}
 	}
 
-	devDir := ".golem/dev"
 	wasmExecDest := filepath.Join(devDir, "wasm_exec.js")
 
 	// If we found the official file, use it. Otherwise, use the fallback.
@@ -514,119 +685,251 @@ if (typeof module !== 'undefined' && module.exports) {
 		}
 	}
 
-	// Build the WebAssembly file
-	fmt.Println("🔨 Building WebAssembly with server functions...")
-
-	// Set environment variables for WebAssembly build
-	env := append(os.Environ(),
-		"GOOS=js",
-		"GOARCH=wasm",
-	)
-
-	// Build command: go build -o app.wasm ./src/app/main.go
-	wasmOutput := filepath.Join(devDir, "app.wasm")
+	return nil
+}
 
-	// Create a temporary main.go that imports server packages
-	tempMainFile := filepath.Join(devDir, "main.go")
-	if err := s.createWasmMainFile(tempMainFile); err != nil {
-		return fmt.Errorf("failed to create WASM main file: %v", err)
+// loadEnv loads .env and .env.development and generates the env package
+// with the GOLEM_PUBLIC_ variables. Server functions inherit the variables.
+func (s *Server) loadEnv() error {
+	loaded, err := build.LoadEnv("development")
+	if err != nil {
+		return err
 	}
-
-	// Build the WASM file from the temporary main
-	buildArgs := []string{
-		"build",
-		"-o", wasmOutput,
-		tempMainFile,
+	if len(loaded) > 0 {
+		fmt.Printf("🌱 Loaded %s (restart golem dev after editing)\n", strings.Join(loaded, ", "))
 	}
+	if _, err := build.GenerateEnv(s.config); err != nil {
+		return fmt.Errorf("failed to generate env package: %w", err)
+	}
+	return nil
+}
 
-	// Execute go build
-	cmd := exec.Command("go", buildArgs...)
-	cmd.Dir = "."
-	cmd.Env = env
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("WebAssembly build failed: %v", err)
+// buildDevWasm compiles the app into .golem/dev/app.wasm. Builds share the Go
+// build cache, so only packages that changed are recompiled, and the new
+// binary replaces the old one atomically so the browser never loads a
+// partially written file.
+func (s *Server) buildDevWasm() error {
+	fmt.Println("🔨 Building WebAssembly...")
+	start := time.Now()
+
+	// Chunks and apps load from <name>.wasm next to app.wasm
+	targets := map[string]string{"app": filepath.Dir(s.config.EntryFile())}
+	names := []string{"app"}
+	for _, name := range build.ChunkNames(s.config) {
+		targets[name] = s.config.Build.Chunks[name]
+		names = append(names, name)
+	}
+	for _, name := range s.config.AppNames() {
+		targets[name] = filepath.Dir(s.config.Apps[name].Entry)
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		wasmOutput := filepath.Join(".golem/dev", name+".wasm")
+		partial := wasmOutput + ".tmp"
+
+		// Build command: go build -o app.wasm ./src/app
+		args := []string{"build", "-o", partial}
+		if s.config.Dev.Debug {
+			// Keep every function and variable as written for the debugger
+			args = append(args, "-gcflags=all=-N -l")
+		}
+		cmd := exec.Command("go", append(args, "./"+filepath.ToSlash(filepath.Clean(targets[name])))...)
+		cmd.Dir = "."
+		cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+
+		output, err := cmd.CombinedOutput()
+		if err := s.reportBuild(targetApp, start, output, err); err != nil {
+			os.Remove(partial)
+			if _, ok := s.config.Apps[name]; ok {
+				return fmt.Errorf("WebAssembly build of app %s failed: %v", name, err)
+			}
+			if name != "app" {
+				return fmt.Errorf("WebAssembly build of chunk %s failed: %v", name, err)
+			}
+			return fmt.Errorf("WebAssembly build failed: %v", err)
+		}
+		if err := os.Rename(partial, wasmOutput); err != nil {
+			return fmt.Errorf("failed to replace %s: %v", wasmOutput, err)
+		}
 	}
 
-	fmt.Println("✅ WebAssembly build completed with server functions")
+	fmt.Printf("✅ WebAssembly build completed in %v\n", time.Since(start).Round(time.Millisecond))
 	return nil
 }
 
-// createWasmMainFile creates a main.go file that imports both app and server packages
-func (s *Server) createWasmMainFile(mainFile string) error {
-	// Read the original main.go to get its content
-	originalMain, err := os.ReadFile("src/app/main.go")
-	if err != nil {
-		return fmt.Errorf("failed to read original main.go: %v", err)
+// printDebugHelp explains how to debug the app in the browser. Go does not
+// emit DWARF for js/wasm, so debuggers work with the function names of the
+// wasm name section, which the dev build keeps.
+func printDebugHelp() {
+	fmt.Println("🐞 Debug build: optimizations and inlining are disabled")
+	fmt.Println("   Open DevTools > Sources and pick app.wasm to set breakpoints; the call stack")
+	fmt.Println("   and panics show Go function names (e.g. main.App). Go does not emit DWARF for")
+	fmt.Println("   WebAssembly yet, so stepping happens on wasm instructions, not Go source lines.")
+}
+
+// watchFiles rebuilds what changed when files matching config.Dev.Watch
+// change: the server functions and the WebAssembly app are each rebuilt only
+// when a package they are built from changes. It then tells connected
+// browsers to reload, or shows them the build errors. Stylesheets are
+// swapped in place and other files only need a reload. Changes to the
+// config's files reload it, also when hot reload is disabled.
+func (s *Server) watchFiles() {
+	functionsDir := filepath.ToSlash(filepath.Clean(build.FunctionsDir(s.config)))
+	devTemplate := filepath.ToSlash(filepath.Clean(s.devTemplate()))
+	output := filepath.ToSlash(filepath.Clean(s.config.Output))
+	var configPaths []string
+	if s.load != nil {
+		configPaths = s.configFiles()
+	}
+	configFiles := make(map[string]bool, len(configPaths))
+	for _, file := range configPaths {
+		configFiles[file] = true
+	}
+
+	patterns := func() []string {
+		patterns := append([]string(nil), configPaths...)
+		if !s.config.Dev.HotReload {
+			return patterns
+		}
+		watched := append([]string(nil), s.config.Dev.Watch...)
+		if len(watched) == 0 {
+			watched = []string{"src/**/*.go", "src/**/*.golem"}
+		}
+		watched = append(watched, functionsDir+"/**/*.go", filepath.ToSlash(filepath.Clean(build.PublicDir(s.config)))+"/**/*", devTemplate)
+		log.Printf("🔍 Watching %s", strings.Join(watched, ", "))
+		return append(patterns, watched...)
+	}
+	if !s.config.Dev.HotReload && len(configFiles) == 0 {
+		return
 	}
 
-	// Get module name for proper imports
-	moduleName, err := functions.GetModuleName()
+	watcher, err := newFileWatcher(patterns(), func(file string) bool {
+		// Generated and built files change on every rebuild
+		if path.Base(file) == functions.GeneratedFile || build.IsComponentFile(file) || strings.HasPrefix(file, output+"/") || file == output {
+			return true
+		}
+		for _, pattern := range append(append([]string(nil), defaultWatchIgnore...), s.config.Dev.Ignore...) {
+			if MatchIgnore(pattern, file) {
+				return true
+			}
+		}
+		return false
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get module name: %v", err)
+		log.Printf("❌ Failed to watch files, restart golem dev to pick up changes: %v", err)
+		return
 	}
 
-	// Create a new main.go that imports server packages
-	content := fmt.Sprintf(`//go:build js && wasm
-
-// Auto-generated main.go for WASM build with server functions
-package main
-
-import (
-	_ "%s/src/server" // Import server package to trigger function registration
-)
-
-// Include the original main.go content below
-`, moduleName)
-
-	// Append the original main.go content, but remove its package declaration
-	originalContent := string(originalMain)
-	lines := strings.Split(originalContent, "\n")
-	var filteredLines []string
+	graph := s.loadBuildGraph()
+	watcher.Run(s.stopping, func(changed []string) {
+		var sources []string
+		configChanged := false
+		for _, file := range changed {
+			if configFiles[file] {
+				configChanged = true
+			} else {
+				sources = append(sources, file)
+			}
+		}
+		if configChanged {
+			applied := s.reloadConfig()
+			if s.restarting() {
+				return
+			}
+			if len(applied) > 0 {
+				watcher.watch(patterns())
+			}
+		}
+		if len(sources) == 0 || !s.config.Dev.HotReload {
+			return
+		}
+		changed = sources
+
+		var functionsChanged, appChanged, assetsChanged bool
+		var stylesheets, unused []string
+		for _, file := range changed {
+			switch {
+			case isGoSource(file):
+				app, functions := graph.affects(file, functionsDir)
+				appChanged = appChanged || app
+				functionsChanged = functionsChanged || functions
+				if !app && !functions {
+					unused = append(unused, file)
+				}
+			case path.Ext(file) == build.ComponentExt:
+				generated, err := s.generateComponent(file)
+				if err != nil {
+					s.hub.reportError("Component failed to compile", s.diagnostics.of(targetApp))
+					return
+				}
+				app, functions := graph.affects(generated, functionsDir)
+				appChanged = appChanged || app
+				functionsChanged = functionsChanged || functions
+				if !app && !functions {
+					unused = append(unused, file)
+				}
+			case file == devTemplate:
+				if err := s.writeDevHTML(); err != nil {
+					log.Printf("❌ %v", err)
+					s.hub.reportError(err.Error(), nil)
+					return
+				}
+				assetsChanged = true
+			case path.Ext(file) == ".css":
+				stylesheets = append(stylesheets, file) // swapped without a reload
+			default:
+				assetsChanged = true // served as is, a reload is enough
+			}
+		}
 
-	for i, line := range lines {
-		// Skip the package main line from original file
-		if i == 0 && strings.HasPrefix(strings.TrimSpace(line), "package main") {
-			continue
+		if len(unused) > 0 {
+			fmt.Printf("💤 %s changed, but neither the app nor the server functions use it\n", describeChanges(unused))
 		}
-		filteredLines = append(filteredLines, line)
-	}
 
-	content += strings.Join(filteredLines, "\n")
+		reload := assetsChanged
+		if functionsChanged && s.config.Dev.Mocks == "" {
+			if s.worker.Load() == nil {
+				log.Printf("Warning: Server functions changed; restart the dev server to serve them")
+			} else if s.reloadFunctions() {
+				reload = true
+			} else {
+				s.hub.reportError("Server functions failed to build", s.diagnostics.of(targetFunctions))
+			}
+		}
+		if appChanged {
+			fmt.Printf("🔄 %s changed, rebuilding...\n", describeChanges(changed))
+			if err := s.buildDevWasm(); err != nil {
+				log.Printf("❌ Rebuild failed: %v", err)
+				s.hub.reportError("WebAssembly build failed", s.diagnostics.of(targetApp))
+				return
+			}
+			reload = true
+		}
+		if appChanged || functionsChanged {
+			// Imports may have changed
+			graph = s.loadBuildGraph()
+		}
 
-	// Write the combined main.go
-	return os.WriteFile(mainFile, []byte(content), 0644)
+		if reload {
+			s.hub.reload()
+			return
+		}
+		for _, stylesheet := range stylesheets {
+			s.hub.updateCSS(stylesheet)
+		}
+	})
 }
 
-func (s *Server) watchFiles() {
-	// File watcher implementation for hot reload
-	// This would watch the files specified in config.Dev.Watch
-	log.Println("🔍 File watcher started")
-
-	// Placeholder - would implement actual file watching
-	// using fsnotify or similar
+// isGoSource reports whether a change to file requires recompiling the app
+func isGoSource(file string) bool {
+	return filepath.Ext(file) == ".go" || path.Base(file) == "go.mod" || path.Base(file) == "go.sum"
 }
 
-func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	// This is a basic WebSocket handler to prevent connection errors in the browser.
-	// Full hot-reload logic is not implemented yet.
-	c, err := websocket.Accept(w, r, nil)
-	if err != nil {
-		log.Printf("could not upgrade to websocket: %v", err)
-		return
-	}
-	defer c.Close(websocket.StatusInternalError, "internal error")
-
-	log.Println("WebSocket client connected.")
-
-	// Keep the connection open but do nothing.
-	// This prevents the connection from being immediately closed and causing errors.
-	for {
-		_, _, err := c.Read(r.Context())
-		if err != nil {
-			break
-		}
+// describeChanges names the changed files for the rebuild message
+func describeChanges(changed []string) string {
+	if len(changed) == 1 {
+		return changed[0]
 	}
+	return fmt.Sprintf("%d files", len(changed))
 }
@@ -0,0 +1,54 @@
+package dev
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// devSessionDir and devSessionSecret keep the sessions of golem dev, so
+// signed in users stay signed in as the functions are rebuilt
+const (
+	devSessionDir    = ".golem/dev/sessions"
+	devSessionSecret = ".golem/dev/session-secret"
+)
+
+// withSessions gives the function calls to next their session, kept in
+// files by default, with the session config
+func (s *Server) withSessions(next http.Handler) (http.Handler, error) {
+	cfg := s.config.Session
+	if cfg.Store == "" {
+		cfg.Store, cfg.Dir = "file", devSessionDir
+	}
+	if cfg.Secret == "" && os.Getenv(server.SessionSecretEnv) == "" {
+		cfg.Secret = sessionSecret()
+	}
+	sessions, err := server.NewSessions(cfg, s.registry, slog.Default())
+	if err != nil {
+		return nil, err
+	}
+	return sessions.Middleware(next), nil
+}
+
+// sessionSecret returns the secret of the dev sessions, generated on first
+// use; empty when it cannot be kept
+func sessionSecret() string {
+	if data, err := os.ReadFile(devSessionSecret); err == nil && len(data) >= 32 {
+		return string(data)
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	secret := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(devSessionSecret), 0755); err != nil {
+		return ""
+	}
+	if err := os.WriteFile(devSessionSecret, []byte(secret), 0600); err != nil {
+		return ""
+	}
+	return secret
+}
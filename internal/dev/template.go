@@ -0,0 +1,117 @@
+package dev
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// devPage holds the parts of the dev page a custom template places, e.g.
+// {{.Title}} or {{.Loader}}
+type devPage struct {
+	Title     string // project name
+	App       string // name of the app of the page, "" for the main one
+	Base      string // <base> tag of history mode apps, "" in hash mode
+	WasmExec  string // URL of wasm_exec.js
+	Wasm      string // URL of app.wasm, or <app>.wasm
+	Loader    string // scripts loading wasm_exec.js and running the app
+	HotReload string // hot reload client, "" when hot reload is disabled
+}
+
+// devTemplate returns the path of the project's dev page template
+func (s *Server) devTemplate() string {
+	if s.config.Dev.Template != "" {
+		return s.config.Dev.Template
+	}
+	return "index.dev.html"
+}
+
+// devPage returns the parts of the dev page of the named app, "" for the
+// main one
+func (s *Server) devPage(app string) devPage {
+	cacheBuster := fmt.Sprintf("%d", time.Now().UnixNano())
+	wasm, root, base := "app.wasm", "", s.config.Router.BaseHref()
+	if app != "" {
+		// App pages sit in directories below the assets
+		prefix := strings.Trim(s.config.AppPath(app), "/")
+		wasm, root, base = app+".wasm", strings.Repeat("../", strings.Count(prefix, "/")+1), base+prefix+"/"
+	}
+	page := devPage{
+		Title:    s.config.ProjectName,
+		App:      app,
+		WasmExec: root + "wasm_exec.js?" + cacheBuster,
+		Wasm:     root + wasm + "?" + cacheBuster,
+	}
+
+	// In history mode the page may be served from a nested path, so relative
+	// asset URLs have to be resolved against the app root
+	if s.config.Router.IsHistoryMode() {
+		page.Base = `<base href="` + base + `">`
+	}
+	if s.config.Dev.HotReload {
+		page.HotReload = hotReloadClient
+	}
+
+	page.Loader = `<script src="` + page.WasmExec + `"></script>
+    <script>
+        const go = new Go();
+        // Shim for older wasm_exec.js files with newer Go compilers.
+        if (go.importObject.go && !go.importObject.gojs) {
+            console.log("Shimming go->gojs import object.");
+            go.importObject.gojs = go.importObject.go;
+            delete go.importObject.go;
+        }
+
+        console.log("Attempting to instantiate wasm with import object:", go.importObject);
+
+        const wasmModule = fetch("` + page.Wasm + `");
+        const instantiateWasm = async () => {
+            try {
+                let instance;
+                if (WebAssembly.instantiateStreaming) {
+                    instance = (await WebAssembly.instantiateStreaming(wasmModule, go.importObject)).instance;
+                } else {
+                    const response = await wasmModule;
+                    const bytes = await response.arrayBuffer();
+                    instance = (await WebAssembly.instantiate(bytes, go.importObject)).instance;
+                }
+                go.run(instance);
+            } catch (err) {
+                (document.getElementById('app') || document.body).innerHTML =
+                    '<h1>❌ Error loading WebAssembly</h1>' +
+                    '<h2>See browser developer console for details.</h2>' +
+                    '<pre>' + err.toString() + '</pre>';
+                console.error('WASM Instantiation Error:', err);
+                console.error('Import object passed to instantiate:', go.importObject);
+            }
+        };
+        instantiateWasm();
+    </script>`
+	return page
+}
+
+// renderDevTemplate renders a project's dev page template. Templates that
+// do not place {{.HotReload}} get the hot reload client before </body>, so
+// a custom page never silently loses hot reload.
+func renderDevTemplate(name, source string, page devPage) (string, error) {
+	tmpl, err := template.New(name).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	var html strings.Builder
+	if err := tmpl.Execute(&html, page); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	result := html.String()
+
+	if page.HotReload != "" && !strings.Contains(source, ".HotReload") {
+		if end := strings.LastIndex(strings.ToLower(result), "</body>"); end >= 0 {
+			result = result[:end] + page.HotReload + "\n" + result[end:]
+		} else {
+			result += page.HotReload
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package dev
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// devCertDir is where the generated development certificate is kept
+const devCertDir = ".golem/dev/tls"
+
+// devCertificate returns the certificate and key files to serve https with:
+// the ones configured in dev.tls, or a self-signed certificate for
+// localhost generated on first use and reused while it is valid
+func (s *Server) devCertificate() (certFile, keyFile string, err error) {
+	if tlsConfig := s.config.Dev.TLS; tlsConfig != nil {
+		return tlsConfig.CertFile, tlsConfig.KeyFile, nil
+	}
+
+	certFile = filepath.Join(devCertDir, "cert.pem")
+	keyFile = filepath.Join(devCertDir, "key.pem")
+	if certificateValid(certFile, keyFile) {
+		return certFile, keyFile, nil
+	}
+
+	fmt.Println("🔐 Generating a self-signed certificate for localhost...")
+	if err := generateCertificate(certFile, keyFile); err != nil {
+		return "", "", fmt.Errorf("failed to generate dev certificate: %w", err)
+	}
+	return certFile, keyFile, nil
+}
+
+// certificateValid reports whether a generated certificate can be reused,
+// i.e. it loads and does not expire within a day
+func certificateValid(certFile, keyFile string) bool {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	return time.Now().Add(24 * time.Hour).Before(cert.NotAfter)
+}
+
+// generateCertificate writes a self-signed certificate for localhost and
+// the loopback addresses with its private key
+func generateCertificate(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Golem development"}, CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+}
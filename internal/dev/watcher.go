@@ -0,0 +1,322 @@
+package dev
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long changes must settle before they are reported,
+// so saving several files at once triggers a single rebuild
+const watchDebounce = 200 * time.Millisecond
+
+// fileWatcher reports changes to the files matching a set of glob patterns,
+// where "**" matches any number of directories. It is notified by the OS of
+// changes to every directory below the roots of the patterns, registering
+// the directories created and dropping those removed as they come and go.
+type fileWatcher struct {
+	patterns []string
+	ignore   func(path string) bool
+	notify   *fsnotify.Watcher
+	dirs     map[string]bool // directories registered with notify
+	files    map[string]bool // slash-separated paths of the files matching
+}
+
+// newFileWatcher creates a watcher for patterns, skipping paths ignore
+// matches; directory paths passed to ignore end in a slash
+func newFileWatcher(patterns []string, ignore func(path string) bool) (*fileWatcher, error) {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &fileWatcher{patterns: patterns, ignore: ignore, notify: notify}
+	w.register()
+	return w, nil
+}
+
+// watch replaces the patterns of the watcher. Files that start matching
+// are not reported as changed.
+func (w *fileWatcher) watch(patterns []string) {
+	w.patterns = patterns
+	w.register()
+}
+
+// Run calls onChange with the created, modified and deleted paths each time
+// changes settle, until stop is closed, then stops the notifications. Calls
+// never overlap: changes made while onChange runs are queued and reported
+// together once it returns.
+func (w *fileWatcher) Run(stop <-chan struct{}, onChange func(changed []string)) {
+	defer w.notify.Close()
+
+	var changed []string
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+			if files := w.changes(event); len(files) > 0 {
+				changed = mergePaths(changed, files)
+				// Wait until edits stop, collecting everything that changed
+				settled = time.After(watchDebounce)
+			}
+		case err, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("⚠️  Watching files: %v", err)
+		case <-settled:
+			onChange(changed)
+			changed, settled = nil, nil
+		}
+	}
+}
+
+// changes returns the watched files an event created, modified or deleted.
+// Directories created or removed register the directories below them, and
+// change the files they hold.
+func (w *fileWatcher) changes(event fsnotify.Event) []string {
+	if event.Op == fsnotify.Chmod {
+		return nil
+	}
+	file := filepath.ToSlash(filepath.Clean(event.Name))
+
+	isDir := w.dirs[filepath.Clean(event.Name)]
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			isDir = true
+		}
+	}
+	if isDir {
+		before := w.files
+		w.register()
+		var changed []string
+		for file := range w.files {
+			if !before[file] {
+				changed = append(changed, file)
+			}
+		}
+		for file := range before {
+			if !w.files[file] {
+				changed = append(changed, file)
+			}
+		}
+		sort.Strings(changed)
+		return changed
+	}
+
+	if w.ignored(file) || !w.matches(file) {
+		return nil
+	}
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		delete(w.files, file)
+	} else {
+		w.files[file] = true
+	}
+	return []string{file}
+}
+
+// register watches the directories holding the files matching a pattern:
+// every directory below the root of each pattern, and the directory of
+// each plain file path. Missing directories are watched through their
+// closest existing parent, to register them once created. It also lists
+// the files matching.
+func (w *fileWatcher) register() {
+	dirs := make(map[string]bool)
+	files := make(map[string]bool)
+	watchDir := func(dir string) {
+		for dir != "." && dir != string(filepath.Separator) {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		dirs[dir] = true
+	}
+
+	for _, root := range WatchRoots(w.patterns) {
+		watchDir(root)
+		filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			slashed := filepath.ToSlash(file)
+			if entry.IsDir() {
+				if file != root && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == "node_modules" || w.ignored(slashed+"/")) {
+					return filepath.SkipDir
+				}
+				dirs[file] = true
+				return nil
+			}
+			if !w.ignored(slashed) && w.matches(slashed) {
+				files[slashed] = true
+			}
+			return nil
+		})
+	}
+
+	// Plain file paths are watched through their directory, which sees
+	// editors replacing them
+	for _, pattern := range w.patterns {
+		if isPlainPath(pattern) {
+			file := path.Clean(filepath.ToSlash(pattern))
+			watchDir(filepath.Dir(filepath.FromSlash(file)))
+			if info, err := os.Stat(filepath.FromSlash(file)); err == nil && !info.IsDir() && !w.ignored(file) {
+				files[file] = true
+			}
+		}
+	}
+
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			w.notify.Remove(dir)
+		}
+	}
+	for dir := range dirs {
+		if err := w.notify.Add(dir); err != nil {
+			log.Printf("⚠️  Watching %s: %v", dir, err)
+			delete(dirs, dir)
+		}
+	}
+	w.dirs = dirs
+	w.files = files
+}
+
+// isPlainPath reports whether pattern names a single file, without wildcards
+func isPlainPath(pattern string) bool {
+	return !strings.ContainsAny(pattern, "*?[")
+}
+
+func (w *fileWatcher) ignored(file string) bool {
+	return w.ignore != nil && w.ignore(file)
+}
+
+func (w *fileWatcher) matches(file string) bool {
+	for _, pattern := range w.patterns {
+		if MatchGlob(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchRoots returns the directories to walk for patterns: the part of each
+// pattern before its first wildcard, without directories nested in another.
+// Plain file paths are watched through their directory and need no root.
+func WatchRoots(patterns []string) []string {
+	var roots []string
+	for _, pattern := range patterns {
+		if isPlainPath(pattern) {
+			continue
+		}
+		segments := strings.Split(path.Clean(filepath.ToSlash(pattern)), "/")
+		fixed := 0
+		for fixed < len(segments) && !strings.ContainsAny(segments[fixed], "*?[") {
+			fixed++
+		}
+		root := strings.Join(segments[:fixed], "/")
+		if root == "" {
+			root = "."
+		}
+		roots = append(roots, filepath.FromSlash(root))
+	}
+
+	sort.Strings(roots)
+	var unique []string
+	for _, root := range roots {
+		if len(unique) > 0 {
+			last := unique[len(unique)-1]
+			if root == last || last == "." || strings.HasPrefix(root, last+string(filepath.Separator)) {
+				continue
+			}
+		}
+		unique = append(unique, root)
+	}
+	return unique
+}
+
+// defaultWatchIgnore keeps generated files, dependencies, tests and editor
+// temp files from triggering rebuilds
+var defaultWatchIgnore = []string{
+	".golem/", ".git/", "node_modules/", "*_test.go",
+	"*.swp", "*.swo", "*~", ".#*", "#*#", "4913", ".DS_Store",
+}
+
+// MatchIgnore reports whether a slash-separated path matches an ignore
+// pattern, like .gitignore does: a pattern ending in a slash only matches
+// directories, and a pattern without a slash matches the name of the file
+// or of any directory above it, while other patterns match from the
+// project root. Directory paths end in a slash.
+func MatchIgnore(pattern, name string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	if strings.Contains(pattern, "/") {
+		if dirOnly {
+			return MatchGlob(pattern+"/**", name) && (strings.HasSuffix(name, "/") || !MatchGlob(pattern, name))
+		}
+		return MatchGlob(pattern, name) || MatchGlob(pattern+"/**", name)
+	}
+
+	segments := strings.Split(strings.TrimSuffix(name, "/"), "/")
+	if dirOnly && !strings.HasSuffix(name, "/") {
+		segments = segments[:len(segments)-1] // the file itself is no directory
+	}
+	for _, segment := range segments {
+		if ok, _ := path.Match(pattern, segment); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob reports whether a slash-separated path matches pattern. "**"
+// matches zero or more directories; other segments use path.Match.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(path.Clean(pattern), "/"), strings.Split(path.Clean(name), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// mergePaths returns the sorted union of two sorted path lists
+func mergePaths(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, file := range append(a, b...) {
+		if !seen[file] {
+			seen[file] = true
+			merged = append(merged, file)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,279 @@
+package dev
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/build"
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// workerHandshake starts the line a function worker prints once it serves
+const workerHandshake = "golem-worker"
+
+// functionWorker is a process serving the project's server functions. The
+// dev server proxies function calls to the current worker and replaces it
+// with a freshly built one when the functions change.
+type functionWorker struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	binary   string
+	httpAddr string
+	grpcAddr string
+	proxy    *httputil.ReverseProxy
+	inflight atomic.Int64
+}
+
+// RunWorker serves the server functions linked into this binary on loopback
+// ports for the dev server, until the dev server closes its stdin
+func RunWorker(cfg *config.Config) error {
+	s := NewServer(cfg)
+	if err := s.initializeFunctionRegistry(); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	s.registerAPI(mux)
+	handler, err := s.withSessions(mux)
+	if err != nil {
+		return err
+	}
+
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	grpcServer, err := server.NewGRPCServer(s.registry, cfg.Server.GRPC)
+	if err != nil {
+		return err
+	}
+
+	go http.Serve(httpListener, handler)
+	go grpcServer.Serve(grpcListener)
+	fmt.Printf("%s http=%s grpc=%s\n", workerHandshake, httpListener.Addr(), grpcListener.Addr())
+
+	// stdin also closes when the dev server dies, so workers never outlive it
+	io.Copy(io.Discard, os.Stdin)
+	grpcServer.Stop()
+	return nil
+}
+
+// startFunctionWorker starts the first function worker. It reports false when the project has no server
+// functions or they fail to build.
+func (s *Server) startFunctionWorker() bool {
+	worker, err := s.buildWorker()
+	if err != nil {
+		log.Printf("Warning: Failed to start server functions: %v", err)
+		return false
+	}
+	if worker == nil {
+		return false
+	}
+
+	s.worker.Store(worker)
+	return true
+}
+
+// buildWorker regenerates the function registrations, compiles them into a
+// worker binary and starts it. It returns nil without server functions.
+func (s *Server) buildWorker() (*functionWorker, error) {
+	generated, err := build.GenerateFunctions(s.config)
+	if err != nil || len(generated) == 0 {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(".golem/dev", 0755); err != nil {
+		return nil, err
+	}
+	s.workerBuilds++
+	binary := filepath.Join(".golem/dev", fmt.Sprintf("functions-%d", s.workerBuilds))
+
+	start := time.Now()
+	cmd := exec.Command("go", "build", "-o", binary, functions.HostMainFile)
+	output, err := cmd.CombinedOutput()
+	if err := s.reportBuild(targetFunctions, start, output, err); err != nil {
+		return nil, fmt.Errorf("failed to build server functions: %v", err)
+	}
+
+	worker, err := startWorker(binary, s.config.Environment())
+	if err != nil {
+		os.Remove(binary)
+		return nil, err
+	}
+	return worker, nil
+}
+
+// startWorker runs a worker binary with the config of env and waits until
+// it serves
+func startWorker(binary, env string) (*functionWorker, error) {
+	cmd := exec.Command(binary, "worker")
+	cmd.Env = append(os.Environ(), config.EnvVar+"="+env)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start worker: %w", err)
+	}
+
+	worker := &functionWorker{cmd: cmd, stdin: stdin, binary: binary}
+	ready := make(chan bool, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if rest, found := strings.CutPrefix(line, workerHandshake+" "); found && worker.httpAddr == "" {
+				for _, field := range strings.Fields(rest) {
+					key, value, _ := strings.Cut(field, "=")
+					switch key {
+					case "http":
+						worker.httpAddr = value
+					case "grpc":
+						worker.grpcAddr = value
+					}
+				}
+				ready <- true
+				continue
+			}
+			fmt.Println(line)
+		}
+		select {
+		case ready <- false:
+		default:
+		}
+	}()
+
+	select {
+	case ok := <-ready:
+		if !ok {
+			worker.stop()
+			return nil, fmt.Errorf("worker exited before serving")
+		}
+	case <-time.After(30 * time.Second):
+		worker.stop()
+		return nil, fmt.Errorf("worker did not start within 30s")
+	}
+
+	target, err := url.Parse("http://" + worker.httpAddr)
+	if err != nil {
+		worker.stop()
+		return nil, fmt.Errorf("invalid worker address %q: %w", worker.httpAddr, err)
+	}
+	worker.proxy = httputil.NewSingleHostReverseProxy(target)
+	worker.proxy.FlushInterval = -1 // stream SSE responses as they are written
+	return worker, nil
+}
+
+// stop asks the worker to exit, killing it if it does not
+func (w *functionWorker) stop() {
+	w.stdin.Close()
+
+	done := make(chan struct{})
+	go func() {
+		w.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		w.cmd.Process.Kill()
+		<-done
+	}
+	os.Remove(w.binary)
+}
+
+// retire stops a replaced worker once its in-flight calls have finished.
+// Long-lived streams are cut off after a grace period.
+func (w *functionWorker) retire() {
+	deadline := time.Now().Add(30 * time.Second)
+	for w.inflight.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	w.stop()
+}
+
+// workerProxy forwards API requests to the current function worker
+func (s *Server) workerProxy() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		worker := s.worker.Load()
+		worker.inflight.Add(1)
+		defer worker.inflight.Add(-1)
+		worker.proxy.ServeHTTP(w, r)
+	})
+}
+
+// proxyGRPC forwards a gRPC connection to the current function worker
+func (s *Server) proxyGRPC(conn net.Conn) {
+	defer conn.Close()
+
+	worker := s.worker.Load()
+	upstream, err := net.Dial("tcp", worker.grpcAddr)
+	if err != nil {
+		log.Printf("Warning: Failed to reach server functions: %v", err)
+		return
+	}
+	defer upstream.Close()
+
+	worker.inflight.Add(1)
+	defer worker.inflight.Add(-1)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// reloadFunctions rebuilds the server functions into a new worker and swaps
+// it in once it serves. Calls in flight finish on the previous worker, and
+// a failed build keeps the previous worker serving. It reports whether the
+// new version was swapped in.
+func (s *Server) reloadFunctions() bool {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	fmt.Println("🔄 Server functions changed, rebuilding...")
+	start := time.Now()
+	worker, err := s.buildWorker()
+	if err != nil {
+		log.Printf("❌ Server functions rebuild failed, still serving the previous version: %v", err)
+		return false
+	}
+	if worker == nil {
+		log.Printf("Warning: No server functions found, still serving the previous version")
+		return false
+	}
+
+	previous := s.worker.Swap(worker)
+	fmt.Printf("✅ Server functions reloaded in %v\n", time.Since(start).Round(time.Millisecond))
+	go previous.retire()
+	return true
+}
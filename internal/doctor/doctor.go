@@ -0,0 +1,258 @@
+// Package doctor checks the environment golem builds and serves apps in:
+// the Go toolchain and its js/wasm support, the optional tools some
+// features use, the ports the servers bind and the project's config. Each
+// problem comes with the fix to apply.
+package doctor
+
+import (
+	"errors"
+	"go/version"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// MinGoVersion is the oldest Go release that builds golem apps
+const MinGoVersion = "go1.23"
+
+// Status of a check
+type Status int
+
+const (
+	OK   Status = iota
+	Warn        // a feature is unavailable or may fail
+	Fail        // golem cannot build or serve apps
+)
+
+// Check is the result of one check, with Fix telling how to solve a
+// problem
+type Check struct {
+	Name   string
+	Status Status
+	Detail string
+	Fix    string
+}
+
+// Run checks the environment of the project in dir, in the order the
+// checks are printed in
+func Run(dir string) []Check {
+	var checks []Check
+	goVersion := checkGo()
+	checks = append(checks, goVersion)
+	if goVersion.Status != Fail {
+		checks = append(checks, checkWasmBuild(), checkWasmExec())
+	}
+	checks = append(checks, checkTools()...)
+
+	cfg, configChecks := checkConfig(dir)
+	checks = append(checks, configChecks...)
+	if cfg != nil {
+		checks = append(checks, checkPorts(cfg)...)
+	}
+	return checks
+}
+
+// Failed reports whether any of checks failed
+func Failed(checks []Check) bool {
+	for _, check := range checks {
+		if check.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGo checks go is installed and at least MinGoVersion
+func checkGo() Check {
+	check := Check{Name: "Go"}
+	output, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		check.Status = Fail
+		check.Detail = "go not found in PATH"
+		check.Fix = "install Go " + strings.TrimPrefix(MinGoVersion, "go") + " or newer from https://go.dev/dl and add its bin directory to PATH"
+		return check
+	}
+	goVersion := strings.TrimSpace(string(output))
+	check.Detail = goVersion
+	if version.IsValid(goVersion) && version.Compare(goVersion, MinGoVersion) < 0 {
+		check.Status = Fail
+		check.Detail += ", golem needs " + MinGoVersion + " or newer"
+		check.Fix = "upgrade Go from https://go.dev/dl"
+	}
+	return check
+}
+
+// checkWasmBuild compiles a program for js/wasm, which fails when the Go
+// installation lacks the js/wasm port, as some distribution packages do
+func checkWasmBuild() Check {
+	check := Check{Name: "GOOS=js GOARCH=wasm"}
+	tmp, err := os.MkdirTemp("", "golem-doctor-")
+	if err != nil {
+		check.Status = Fail
+		check.Detail = err.Error()
+		return check
+	}
+	defer os.RemoveAll(tmp)
+
+	main := filepath.Join(tmp, "main.go")
+	program := "package main\n\nimport \"syscall/js\"\n\nfunc main() { js.Global().Get(\"console\") }\n"
+	if err := os.WriteFile(main, []byte(program), 0644); err != nil {
+		check.Status = Fail
+		check.Detail = err.Error()
+		return check
+	}
+	cmd := exec.Command("go", "build", "-o", filepath.Join(tmp, "main.wasm"), main)
+	cmd.Dir = tmp
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm", "GOFLAGS=")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		check.Status = Fail
+		check.Detail = "building for js/wasm failed: " + firstLine(string(output), err)
+		check.Fix = "reinstall Go from https://go.dev/dl, the packages of some distributions leave out js/wasm"
+		return check
+	}
+	check.Detail = "builds WebAssembly"
+	return check
+}
+
+// checkWasmExec finds the wasm_exec.js the dev server and builds copy from
+// the Go installation
+func checkWasmExec() Check {
+	check := Check{Name: "wasm_exec.js"}
+	output, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		check.Status = Fail
+		check.Detail = "go env GOROOT: " + err.Error()
+		check.Fix = "make sure go runs from PATH"
+		return check
+	}
+	goRoot := strings.TrimSpace(string(output))
+	for _, path := range []string{
+		filepath.Join(goRoot, "lib", "wasm", "wasm_exec.js"),  // Go 1.21+
+		filepath.Join(goRoot, "misc", "wasm", "wasm_exec.js"), // Go < 1.21
+	} {
+		if _, err := os.Stat(path); err == nil {
+			check.Detail = path
+			return check
+		}
+	}
+	check.Status = Fail
+	check.Detail = "not found in " + goRoot
+	check.Fix = "reinstall Go from https://go.dev/dl, without wasm_exec.js the dev server falls back to an incomplete copy"
+	return check
+}
+
+// tools are the optional commands some features run, with what they are
+// for and how to install them
+var tools = []struct {
+	name, use, install string
+}{
+	{"tinygo", "compiles much smaller WebAssembly", "see https://tinygo.org/getting-started/install"},
+	{"wasm-opt", "shrinks app.wasm when wasm.optimizeSize is set", "install binaryen, e.g. brew install binaryen or apt install binaryen"},
+	{"node", "runs the js/wasm tests of golem test", "install Node.js from https://nodejs.org"},
+	{"brotli", "writes the .br files of production builds", "install brotli, e.g. brew install brotli or apt install brotli"},
+	{"docker", "builds images with golem build --docker-build", "install Docker from https://docs.docker.com/get-docker"},
+}
+
+// checkTools looks for the optional tools in PATH
+func checkTools() []Check {
+	var checks []Check
+	for _, tool := range tools {
+		check := Check{Name: tool.name}
+		if path, err := exec.LookPath(tool.name); err == nil {
+			check.Detail = path
+		} else {
+			check.Status = Warn
+			check.Detail = "not found, optional: " + tool.use
+			check.Fix = tool.install
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkConfig validates the project's config, alone and with the file of
+// each environment layered over it, and returns the base config
+func checkConfig(dir string) (*config.Config, []Check) {
+	cfg, err := config.LoadProject(dir, "")
+	if errors.Is(err, config.ErrNotFound) {
+		return nil, []Check{{
+			Name:   "Config",
+			Status: Warn,
+			Detail: "no golem.config.json found, the project checks are skipped",
+			Fix:    "run golem doctor in a project, or create one with golem new <project-name>",
+		}}
+	}
+	if err != nil {
+		return nil, []Check{{Name: "Config", Status: Fail, Detail: err.Error(), Fix: "fix the settings listed, golem config validate checks them again"}}
+	}
+	checks := []Check{{Name: "Config", Detail: strings.Join(cfg.Files(), " + ") + " is valid"}}
+
+	envs, err := config.Environments(dir)
+	if err != nil {
+		checks = append(checks, Check{Name: "Config", Status: Fail, Detail: err.Error()})
+	}
+	for _, env := range envs {
+		layered, err := config.LoadProject(dir, env)
+		if err != nil {
+			checks = append(checks, Check{Name: "Config " + env, Status: Fail, Detail: err.Error(), Fix: "fix the settings listed, golem config validate --env " + env + " checks them again"})
+			continue
+		}
+		checks = append(checks, Check{Name: "Config " + env, Detail: strings.Join(layered.Files(), " + ") + " is valid"})
+	}
+	return cfg, checks
+}
+
+// checkPorts checks the ports of the dev and production servers are free.
+// Taken ports are only warned about, since they may be golem's own.
+func checkPorts(cfg *config.Config) []Check {
+	var checks []Check
+	dev := ":" + strconv.Itoa(cfg.Dev.Port)
+	checks = append(checks, checkPort("Dev server port", dev, "golem dev picks the next free port, or set dev.port"))
+
+	addr, source, err := server.HTTPAddress(cfg)
+	if err != nil {
+		checks = append(checks, Check{Name: "Server port", Status: Fail, Detail: err.Error(), Fix: "set server.port or " + server.PortEnv + " to a port number"})
+	} else {
+		checks = append(checks, checkPort("Server port", addr, "stop the process using it, or set "+source+" to a free port"))
+	}
+	checks = append(checks, checkPort("gRPC port", server.GRPCAddress(cfg), "stop the process using it, or set server.grpc.port to a free port"))
+	return checks
+}
+
+// checkPort checks addr can be listened on
+func checkPort(name, addr, fix string) Check {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return Check{Name: name, Status: Warn, Detail: addr + " is not available: " + err.Error(), Fix: fix}
+	}
+	listener.Close()
+	return Check{Name: name, Detail: addr + " is free"}
+}
+
+// firstLine returns the first line of output, or err without output
+func firstLine(output string, err error) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return err.Error()
+	}
+	line, _, _ := strings.Cut(output, "\n")
+	return line
+}
+
+// String returns the symbol golem doctor prints the status with
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "✅"
+	case Warn:
+		return "⚠️ "
+	default:
+		return "❌"
+	}
+}
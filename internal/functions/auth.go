@@ -0,0 +1,167 @@
+package functions
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// authDirective declares a function's requirements in its doc comment, e.g.
+//
+//	//golem:auth role=admin,editor permission=users.write
+//
+// A bare //golem:auth only requires an authenticated caller.
+const authDirective = "golem:auth"
+
+// Identity is the authenticated caller of a function
+type Identity struct {
+	Subject     string
+	Roles       []string
+	Permissions []string
+}
+
+// HasRole reports whether the identity has role
+func (i *Identity) HasRole(role string) bool {
+	return slices.Contains(i.Roles, role)
+}
+
+// HasPermission reports whether the identity has permission
+func (i *Identity) HasPermission(permission string) bool {
+	return slices.Contains(i.Permissions, permission)
+}
+
+// AuthRequirement declares who may call a function. A caller must be
+// authenticated, have at least one of Roles (if any) and all Permissions.
+type AuthRequirement struct {
+	Roles       []string
+	Permissions []string
+}
+
+// IdentityResolver derives the caller's identity from call metadata, e.g. a
+// session cookie or bearer token. It returns nil for anonymous callers.
+type IdentityResolver func(ctx context.Context, md *Metadata) (*Identity, error)
+
+// RegisterOption configures a function at registration
+type RegisterOption func(meta *FunctionMeta)
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the caller's identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller's identity, if authenticated
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
+
+// RequireAuthenticated only allows authenticated callers
+func RequireAuthenticated() RegisterOption {
+	return func(meta *FunctionMeta) {
+		if meta.Auth == nil {
+			meta.Auth = &AuthRequirement{}
+		}
+	}
+}
+
+// RequireRoles only allows callers having at least one of roles
+func RequireRoles(roles ...string) RegisterOption {
+	return func(meta *FunctionMeta) {
+		RequireAuthenticated()(meta)
+		meta.Auth.Roles = append(meta.Auth.Roles, roles...)
+	}
+}
+
+// RequirePermissions only allows callers having all of permissions
+func RequirePermissions(permissions ...string) RegisterOption {
+	return func(meta *FunctionMeta) {
+		RequireAuthenticated()(meta)
+		meta.Auth.Permissions = append(meta.Auth.Permissions, permissions...)
+	}
+}
+
+// SetIdentityResolver sets how the registry identifies callers of functions
+// that declare an AuthRequirement when no identity is in the call context
+func (r *Registry) SetIdentityResolver(resolver IdentityResolver) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.identityResolver = resolver
+}
+
+// IdentityMiddleware resolves the caller's identity for every HTTP request,
+// making it available to server functions and authorization checks
+func IdentityMiddleware(resolve func(r *http.Request) (*Identity, error), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := resolve(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if identity != nil {
+			r = r.WithContext(WithIdentity(r.Context(), identity))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize enforces the function's AuthRequirement. It runs after all
+// interceptors, so they may establish the identity.
+func (r *Registry) authorize(ctx context.Context, info *CallInfo) (context.Context, error) {
+	requirement := info.Meta.Auth
+	if requirement == nil {
+		return ctx, nil
+	}
+
+	identity, ok := IdentityFromContext(ctx)
+	if !ok {
+		r.mutex.RLock()
+		resolver := r.identityResolver
+		r.mutex.RUnlock()
+
+		if resolver != nil {
+			resolved, err := resolver(ctx, MetadataFromContext(ctx))
+			if err != nil {
+				return ctx, Errorf(CodeUnauthenticated, "failed to identify caller: %v", err)
+			}
+			identity, ok = resolved, resolved != nil
+		}
+	}
+	if !ok {
+		return ctx, Errorf(CodeUnauthenticated, "%s.%s requires authentication", info.ServiceName, info.FunctionName)
+	}
+
+	if len(requirement.Roles) > 0 && !slices.ContainsFunc(requirement.Roles, identity.HasRole) {
+		return ctx, Errorf(CodePermissionDenied, "%s.%s requires one of the roles %s", info.ServiceName, info.FunctionName, strings.Join(requirement.Roles, ", "))
+	}
+	for _, permission := range requirement.Permissions {
+		if !identity.HasPermission(permission) {
+			return ctx, Errorf(CodePermissionDenied, "%s.%s requires the permission %s", info.ServiceName, info.FunctionName, permission)
+		}
+	}
+
+	return WithIdentity(ctx, identity), nil
+}
+
+// parseAuthDirective reads a //golem:auth line from a doc comment
+func parseAuthDirective(text string) (*AuthRequirement, bool) {
+	rest, found := strings.CutPrefix(text, authDirective)
+	if !found || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, false
+	}
+
+	requirement := &AuthRequirement{}
+	for _, field := range strings.Fields(rest) {
+		key, value, _ := strings.Cut(field, "=")
+		values := strings.Split(value, ",")
+		switch key {
+		case "role", "roles":
+			requirement.Roles = append(requirement.Roles, values...)
+		case "permission", "permissions":
+			requirement.Permissions = append(requirement.Permissions, values...)
+		}
+	}
+	return requirement, true
+}
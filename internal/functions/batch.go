@@ -0,0 +1,114 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// maxBatchSize bounds the number of calls accepted in one batch request
+const maxBatchSize = 100
+
+// batchRequest is the JSON body of POST /api/functions/batch
+type batchRequest struct {
+	Calls    []httpCall `json:"calls"`
+	Parallel bool       `json:"parallel"`
+}
+
+// batchResult is the outcome of a single call within a batch
+type batchResult struct {
+	Success   bool                   `json:"success"`
+	Result    interface{}            `json:"result,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Code      string                 `json:"code,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Retryable bool                   `json:"retryable,omitempty"`
+}
+
+// BatchHandler serves POST /api/functions/batch, running several function
+// calls in one round trip. Results are returned in request order; a failing
+// call does not affect the others.
+func (s *GRPCServer) BatchHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+			return
+		}
+
+		var req batchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"})
+			return
+		}
+		if len(req.Calls) > maxBatchSize {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("batch exceeds %d calls", maxBatchSize)})
+			return
+		}
+
+		ctx, cancel := requestContext(r)
+		defer cancel()
+
+		results := make([]batchResult, len(req.Calls))
+		run := func(i int) {
+			results[i] = s.runBatchCall(ctx, req.Calls[i])
+		}
+
+		if req.Parallel {
+			var wg sync.WaitGroup
+			for i := range req.Calls {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					run(i)
+				}(i)
+			}
+			wg.Wait()
+		} else {
+			for i := range req.Calls {
+				run(i)
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"result":  results,
+		})
+	}
+}
+
+// runBatchCall executes one call of a batch
+func (s *GRPCServer) runBatchCall(ctx context.Context, call httpCall) batchResult {
+	protoArgs, err := argsToAny(call.Args)
+	if err != nil {
+		return failedBatchResult(NewError(CodeInvalidArgument, err.Error()))
+	}
+
+	result, err := s.registry.CallFunction(ctx, call.ServiceName, call.FunctionName, protoArgs)
+	if err != nil {
+		return failedBatchResult(err)
+	}
+
+	var resultData interface{}
+	if err := json.Unmarshal(result.GetValue(), &resultData); err != nil {
+		return failedBatchResult(NewError(CodeInternal, "Failed to deserialize result"))
+	}
+	return batchResult{Success: true, Result: resultData}
+}
+
+// failedBatchResult reports a failed call within a batch
+func failedBatchResult(err error) batchResult {
+	structured := ToError(err)
+	return batchResult{
+		Error:     structured.Message,
+		Code:      structured.Code,
+		Details:   structured.Details,
+		Retryable: structured.Retryable,
+	}
+}
@@ -0,0 +1,290 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"nhooyr.io/websocket"
+)
+
+// bidiBuffer is the buffer size of the channels bridging a socket to a function
+const bidiBuffer = 16
+
+// socketMessage is the JSON envelope exchanged over the function WebSocket.
+// The first client message carries the call (serviceName, functionName,
+// args); later ones carry values for the function's receive channel.
+type socketMessage struct {
+	Type         string          `json:"type"` // "call", "message", "close", "end", "error"
+	ServiceName  string          `json:"serviceName,omitempty"`
+	FunctionName string          `json:"functionName,omitempty"`
+	Args         []interface{}   `json:"args,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// IsBidiFunction reports whether a function has the bidirectional streaming
+// shape func([ctx,] in <-chan T, args...) (<-chan U[, error])
+func IsBidiFunction(fnType reflect.Type) bool {
+	inIndex := 0
+	if fnType.NumIn() > 0 && fnType.In(0).String() == "context.Context" {
+		inIndex = 1
+	}
+	if fnType.NumIn() <= inIndex || !isRecvChan(fnType.In(inIndex)) {
+		return false
+	}
+
+	switch fnType.NumOut() {
+	case 1:
+		return isRecvChan(fnType.Out(0))
+	case 2:
+		return isRecvChan(fnType.Out(0)) && fnType.Out(1).String() == "error"
+	default:
+		return false
+	}
+}
+
+// isRecvChan reports whether t is a channel the holder can receive from
+func isRecvChan(t reflect.Type) bool {
+	return t.Kind() == reflect.Chan && t.ChanDir()&reflect.RecvDir != 0
+}
+
+// CallFunctionBidi starts a bidirectional streaming function. JSON values read
+// from incoming are decoded into the function's receive channel, which is
+// closed when incoming closes. Values the function sends are returned as JSON
+// on the result channel, which closes when the function closes its channel.
+func (r *Registry) CallFunctionBidi(ctx context.Context, serviceName, functionName string, args []*anypb.Any, incoming <-chan []byte) (<-chan []byte, error) {
+	meta, exists := r.GetFunction(serviceName, functionName)
+	if !exists {
+		return nil, fmt.Errorf("function %s.%s not found", serviceName, functionName)
+	}
+	if !meta.Function.IsValid() || !IsBidiFunction(meta.Type) {
+		return nil, fmt.Errorf("function %s.%s is not a bidirectional streaming function", serviceName, functionName)
+	}
+
+	info := &CallInfo{
+		ServiceName:  serviceName,
+		FunctionName: functionName,
+		Args:         args,
+		Meta:         meta,
+		Streaming:    true,
+	}
+
+	// The chain gates the call; the function's channel is handed back here
+	var outgoing <-chan []byte
+	_, err := r.intercept(ctx, info, func(ctx context.Context, info *CallInfo) (*anypb.Any, error) {
+		var err error
+		outgoing, err = r.startBidi(ctx, meta, info.Args, incoming)
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outgoing, nil
+}
+
+// startBidi calls a bidirectional function and bridges its channels
+func (r *Registry) startBidi(ctx context.Context, meta *FunctionMeta, args []*anypb.Any, incoming <-chan []byte) (<-chan []byte, error) {
+	serviceName, functionName := meta.ServiceName, meta.Name
+	fnType := meta.Type
+	var callArgs []reflect.Value
+
+	inIndex := 0
+	if fnType.In(0).String() == "context.Context" {
+		callArgs = append(callArgs, reflect.ValueOf(ctx))
+		inIndex = 1
+	}
+
+	// Bridge raw JSON messages into a typed channel for the function
+	elemType := fnType.In(inIndex).Elem()
+	in := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, elemType), bidiBuffer)
+	callArgs = append(callArgs, in)
+
+	expected := fnType.NumIn() - inIndex - 1
+	if len(args) != expected {
+		return nil, fmt.Errorf("expected %d arguments, got %d", expected, len(args))
+	}
+	for i, arg := range args {
+		value, err := r.convertAnyToValue(arg, fnType.In(inIndex+1+i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert argument %d: %w", i, err)
+		}
+		callArgs = append(callArgs, value)
+	}
+
+	results, err := r.callRecovered(ctx, meta, callArgs)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 2 && !results[1].IsNil() {
+		return nil, results[1].Interface().(error)
+	}
+	out := results[0]
+	if out.IsNil() {
+		return nil, fmt.Errorf("function %s.%s returned a nil channel", serviceName, functionName)
+	}
+
+	go func() {
+		defer in.Close()
+		for {
+			select {
+			case raw, ok := <-incoming:
+				if !ok {
+					return
+				}
+				value := reflect.New(elemType)
+				if err := json.Unmarshal(raw, value.Interface()); err != nil {
+					r.logFor(ctx).Warn("dropping invalid stream message", "function", serviceName+"."+functionName, "error", err)
+					continue
+				}
+				chosen, _, _ := reflect.Select([]reflect.SelectCase{
+					{Dir: reflect.SelectSend, Chan: in, Send: value.Elem()},
+					{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+				})
+				if chosen == 1 {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	outgoing := make(chan []byte, bidiBuffer)
+	go func() {
+		defer close(outgoing)
+		for {
+			chosen, value, ok := reflect.Select([]reflect.SelectCase{
+				{Dir: reflect.SelectRecv, Chan: out},
+				{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			})
+			if chosen == 1 || !ok {
+				return
+			}
+			data, err := json.Marshal(value.Interface())
+			if err != nil {
+				r.logFor(ctx).Warn("dropping unserializable stream value", "function", serviceName+"."+functionName, "error", err)
+				continue
+			}
+			select {
+			case outgoing <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return outgoing, nil
+}
+
+// WebSocketHandler serves /api/functions/ws, bridging a persistent socket to
+// a bidirectional streaming server function
+func (s *GRPCServer) WebSocketHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			s.registry.log().Warn("could not upgrade to websocket", "path", r.URL.Path, "error", err)
+			return
+		}
+		defer conn.Close(websocket.StatusInternalError, "internal error")
+
+		ctx, cancel := requestContext(r)
+		defer cancel()
+
+		// The first message selects the function to run
+		var call socketMessage
+		if err := readSocketMessage(ctx, conn, &call); err != nil || call.Type != "call" {
+			conn.Close(websocket.StatusPolicyViolation, "expected call message")
+			return
+		}
+
+		protoArgs, err := argsToAny(call.Args)
+		if err != nil {
+			writeSocketMessage(ctx, conn, socketMessage{Type: "error", Error: err.Error()})
+			return
+		}
+
+		incoming := make(chan []byte, bidiBuffer)
+		outgoing, err := s.registry.CallFunctionBidi(ctx, call.ServiceName, call.FunctionName, protoArgs, incoming)
+		if err != nil {
+			close(incoming)
+			writeSocketMessage(ctx, conn, socketMessage{Type: "error", Error: err.Error()})
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+
+		// Forward client messages until the client half-closes or disconnects
+		go func() {
+			defer close(incoming)
+			for {
+				var msg socketMessage
+				if err := readSocketMessage(ctx, conn, &msg); err != nil {
+					cancel()
+					return
+				}
+				switch msg.Type {
+				case "message":
+					if err := s.registry.gateMessage(ctx); err != nil {
+						writeSocketMessage(ctx, conn, socketMessage{Type: "error", Error: err.Error()})
+						conn.Close(websocket.StatusPolicyViolation, ToError(err).Code)
+						cancel()
+						return
+					}
+					select {
+					case incoming <- msg.Data:
+					case <-ctx.Done():
+						return
+					}
+				case "close":
+					return
+				}
+			}
+		}()
+
+		for data := range outgoing {
+			if err := writeSocketMessage(ctx, conn, socketMessage{Type: "message", Data: data}); err != nil {
+				return
+			}
+		}
+
+		writeSocketMessage(ctx, conn, socketMessage{Type: "end"})
+		conn.Close(websocket.StatusNormalClosure, "")
+	}
+}
+
+// readSocketMessage reads and decodes a single JSON message
+func readSocketMessage(ctx context.Context, conn *websocket.Conn, msg *socketMessage) error {
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, msg)
+}
+
+// writeSocketMessage encodes and writes a single JSON message
+func writeSocketMessage(ctx context.Context, conn *websocket.Conn, msg socketMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.Write(ctx, websocket.MessageText, data)
+}
+
+// argsToAny converts decoded JSON arguments to protobuf Any values
+func argsToAny(args []interface{}) ([]*anypb.Any, error) {
+	var protoArgs []*anypb.Any
+	for _, arg := range args {
+		argBytes, err := json.Marshal(arg)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to serialize argument")
+		}
+		protoArgs = append(protoArgs, &anypb.Any{
+			TypeUrl: "type.googleapis.com/google.protobuf.Value",
+			Value:   argBytes,
+		})
+	}
+	return protoArgs, nil
+}
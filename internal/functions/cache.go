@@ -0,0 +1,124 @@
+package functions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// maxCacheEntries bounds a function's result cache before expired entries
+// are swept
+const maxCacheEntries = 10000
+
+// resultCache holds the cached results of one function. It is shared by
+// every registry the function is copied into, so invalidation through the
+// global registry reaches the running server.
+type resultCache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	value   *anypb.Any
+	expires time.Time
+}
+
+// WithCache caches the function's results for ttl, keyed by its arguments.
+// Only use it for pure functions whose result depends on nothing but their
+// arguments: the caller's identity and metadata are not part of the key.
+func WithCache(ttl time.Duration) RegisterOption {
+	return func(meta *FunctionMeta) {
+		meta.cache = &resultCache{ttl: ttl, entries: make(map[string]cachedResult)}
+	}
+}
+
+// InvalidateCache removes the cached result of one call. args are the Go
+// values the function was called with.
+func (r *Registry) InvalidateCache(serviceName, functionName string, args ...interface{}) error {
+	meta, exists := r.GetFunction(serviceName, functionName)
+	if !exists || meta.cache == nil {
+		return nil
+	}
+
+	protoArgs, err := argsToAny(args)
+	if err != nil {
+		return fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	meta.cache.mutex.Lock()
+	defer meta.cache.mutex.Unlock()
+	delete(meta.cache.entries, argsHash(protoArgs))
+	return nil
+}
+
+// InvalidateFunctionCache removes every cached result of a function
+func (r *Registry) InvalidateFunctionCache(serviceName, functionName string) {
+	meta, exists := r.GetFunction(serviceName, functionName)
+	if !exists || meta.cache == nil {
+		return
+	}
+
+	meta.cache.mutex.Lock()
+	defer meta.cache.mutex.Unlock()
+	meta.cache.entries = make(map[string]cachedResult)
+}
+
+// get returns a cached, unexpired result
+func (c *resultCache) get(key string) (*anypb.Any, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// put stores a result
+func (c *resultCache) put(key string, value *anypb.Any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxCacheEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) < maxCacheEntries {
+		c.entries[key] = cachedResult{value: value, expires: now.Add(c.ttl)}
+	}
+}
+
+// argsHash hashes the JSON encoded arguments of a call. Arguments are
+// re-encoded first so whitespace and object key order don't matter.
+func argsHash(args []*anypb.Any) string {
+	hash := sha256.New()
+	for _, arg := range args {
+		encoded := arg.GetValue()
+
+		var decoded interface{}
+		if err := json.Unmarshal(encoded, &decoded); err == nil {
+			if canonical, err := json.Marshal(decoded); err == nil {
+				encoded = canonical
+			}
+		}
+
+		hash.Write(encoded)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,56 @@
+package functions
+
+import (
+	"context"
+	"reflect"
+)
+
+// returnsChannel reports whether a function's first result is a receivable
+// channel, e.g. func(ctx context.Context, q string) (<-chan Row, error)
+func returnsChannel(fnType reflect.Type) bool {
+	return fnType.NumOut() > 0 && fnType.Out(0).Kind() == reflect.Chan && fnType.Out(0).ChanDir()&reflect.RecvDir != 0
+}
+
+// drainChannel receives the values of the channel a function returned until
+// it is closed or ctx is done. Streaming callers get each value as a partial
+// result and a null final result; other callers get all values as an array.
+// Producers should stop sending once the function's ctx is done.
+func (r *Registry) drainChannel(ctx context.Context, results []reflect.Value) ([]reflect.Value, error) {
+	if len(results) == 2 && !results[1].IsNil() {
+		return results, nil // the function failed; convertResult reports it
+	}
+
+	emit, streaming := ctx.Value(emitterKey{}).(emitter)
+	collected := []interface{}{}
+
+	channel := results[0]
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: channel},
+	}
+	for !channel.IsNil() {
+		chosen, value, ok := reflect.Select(cases)
+		if chosen == 0 {
+			return nil, ctx.Err()
+		}
+		if !ok {
+			break
+		}
+
+		if !streaming {
+			collected = append(collected, value.Interface())
+			continue
+		}
+		if err := emit(value.Interface()); err != nil {
+			return nil, err
+		}
+	}
+
+	drained := append([]reflect.Value(nil), results...)
+	if streaming {
+		drained[0] = reflect.Zero(reflect.TypeOf((*interface{})(nil)).Elem())
+	} else {
+		drained[0] = reflect.ValueOf(collected)
+	}
+	return drained, nil
+}
@@ -3,16 +3,18 @@ package functions
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"log"
+	"log/slog"
+	"math"
 	"os"
-	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"google.golang.org/protobuf/types/known/anypb"
 
@@ -28,11 +30,27 @@ func init() {
 }
 
 // RegisterGlobalFunction allows user packages to register their functions
-func RegisterGlobalFunction(serviceName, functionName string, fn interface{}) error {
+func RegisterGlobalFunction(serviceName, functionName string, fn interface{}, opts ...RegisterOption) error {
 	globalMutex.Lock()
 	defer globalMutex.Unlock()
 
-	return globalRegistry.RegisterFunction(serviceName, functionName, fn)
+	return globalRegistry.RegisterFunction(serviceName, functionName, fn, opts...)
+}
+
+// SetGlobalIdentityResolver sets the identity resolver of the global registry
+func SetGlobalIdentityResolver(resolver IdentityResolver) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	globalRegistry.SetIdentityResolver(resolver)
+}
+
+// UseGlobalInterceptor adds interceptors to the global registry
+func UseGlobalInterceptor(interceptors ...Interceptor) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	globalRegistry.Use(interceptors...)
 }
 
 // GetGlobalRegistry returns the global registry with all registered functions
@@ -45,15 +63,32 @@ func GetGlobalRegistry() *Registry {
 	for key, meta := range globalRegistry.functions {
 		registry.functions[key] = meta
 	}
+	registry.interceptors = append(registry.interceptors, globalRegistry.interceptors...)
+	registry.identityResolver = globalRegistry.identityResolver
+	registry.sessionStore = globalRegistry.sessionStore
+	for name, check := range globalRegistry.healthChecks {
+		registry.AddHealthCheck(name, check)
+	}
 
 	return registry
 }
 
 // Registry holds all discovered server functions
 type Registry struct {
-	functions map[string]*FunctionMeta
-	packages  map[string]interface{} // Package instances
-	mutex     sync.RWMutex
+	functions    map[string]*FunctionMeta
+	packages     map[string]interface{} // Package instances
+	interceptors []Interceptor
+	gates        []MessageGate
+	mutex        sync.RWMutex
+
+	identityResolver IdentityResolver
+	sessionStore     SessionStore
+	healthChecks     map[string]HealthCheck
+	logger           *slog.Logger
+	counters         map[string]*functionCounters
+	limits           map[string]Limits
+	slots            map[string]chan struct{}
+	idempotency      *idempotencyStore
 }
 
 // FunctionMeta contains metadata about a server function
@@ -66,13 +101,21 @@ type FunctionMeta struct {
 	ArgTypes    []string
 	ReturnType  string
 	Description string
+	Auth        *AuthRequirement // nil if anyone may call the function
+	Limits      Limits
+
+	cache *resultCache // set by WithCache
 }
 
 // NewRegistry creates a new function registry
 func NewRegistry() *Registry {
 	return &Registry{
-		functions: make(map[string]*FunctionMeta),
-		packages:  make(map[string]interface{}),
+		functions:   make(map[string]*FunctionMeta),
+		packages:    make(map[string]interface{}),
+		counters:    make(map[string]*functionCounters),
+		limits:      make(map[string]Limits),
+		slots:       make(map[string]chan struct{}),
+		idempotency: newIdempotencyStore(),
 	}
 }
 
@@ -117,7 +160,7 @@ func (r *Registry) RegisterPackage(packageName string, pkg interface{}) error {
 }
 
 // RegisterFunction registers a single function
-func (r *Registry) RegisterFunction(serviceName, functionName string, fn interface{}) error {
+func (r *Registry) RegisterFunction(serviceName, functionName string, fn interface{}, opts ...RegisterOption) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -139,11 +182,28 @@ func (r *Registry) RegisterFunction(serviceName, functionName string, fn interfa
 	}
 
 	key := fmt.Sprintf("%s.%s", serviceName, functionName)
+
+	// Keep what source discovery found, e.g. docs and //golem:auth
+	if discovered, exists := r.functions[key]; exists {
+		meta.Description = discovered.Description
+		meta.Auth = discovered.Auth
+	}
+	for _, opt := range opts {
+		opt(meta)
+	}
+
 	r.functions[key] = meta
 
 	return nil
 }
 
+// WithDescription sets the documentation shown for a function
+func WithDescription(description string) RegisterOption {
+	return func(meta *FunctionMeta) {
+		meta.Description = description
+	}
+}
+
 // DiscoverFunctions automatically discovers functions from source files
 func (r *Registry) DiscoverFunctions(serverDir string) error {
 	// Parse Go files in the server directory
@@ -181,7 +241,8 @@ func (r *Registry) parseFileForFunctions(packageName string, file *ast.File) {
 					Name:        fn.Name.Name,
 					ServiceName: packageName,
 					Package:     packageName,
-					Description: r.extractDocString(fn.Doc),
+					Description: extractDocString(fn.Doc),
+					Auth:        extractAuth(fn.Doc),
 				}
 
 				// For now, we'll register the metadata
@@ -189,8 +250,10 @@ func (r *Registry) parseFileForFunctions(packageName string, file *ast.File) {
 				key := fmt.Sprintf("%s.%s", packageName, fn.Name.Name)
 
 				r.mutex.Lock()
-				if _, exists := r.functions[key]; !exists {
+				if existing, exists := r.functions[key]; !exists {
 					r.functions[key] = meta
+				} else if meta.Auth != nil && existing.Auth == nil {
+					existing.Auth = meta.Auth
 				}
 				r.mutex.Unlock()
 			}
@@ -199,7 +262,7 @@ func (r *Registry) parseFileForFunctions(packageName string, file *ast.File) {
 }
 
 // extractDocString extracts documentation from comments
-func (r *Registry) extractDocString(commentGroup *ast.CommentGroup) string {
+func extractDocString(commentGroup *ast.CommentGroup) string {
 	if commentGroup == nil {
 		return ""
 	}
@@ -210,6 +273,9 @@ func (r *Registry) extractDocString(commentGroup *ast.CommentGroup) string {
 		text = strings.TrimPrefix(text, "/*")
 		text = strings.TrimSuffix(text, "*/")
 		text = strings.TrimSpace(text)
+		if strings.HasPrefix(comment.Text, "//golem:") {
+			continue // directives are not documentation
+		}
 		if text != "" {
 			doc.WriteString(text)
 			doc.WriteString(" ")
@@ -219,6 +285,20 @@ func (r *Registry) extractDocString(commentGroup *ast.CommentGroup) string {
 	return strings.TrimSpace(doc.String())
 }
 
+// extractAuth reads a //golem:auth directive from a doc comment
+func extractAuth(commentGroup *ast.CommentGroup) *AuthRequirement {
+	if commentGroup == nil {
+		return nil
+	}
+
+	for _, comment := range commentGroup.List {
+		if requirement, ok := parseAuthDirective(strings.TrimPrefix(comment.Text, "//")); ok {
+			return requirement
+		}
+	}
+	return nil
+}
+
 // extractArgTypes extracts argument types from function type
 func (r *Registry) extractArgTypes(fnType reflect.Type) []string {
 	var argTypes []string
@@ -265,24 +345,119 @@ func (r *Registry) CallFunction(ctx context.Context, serviceName, functionName s
 	r.mutex.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("function %s not found", key)
+		return nil, Errorf(CodeNotFound, "function %s not found", key)
 	}
 
 	if !meta.Function.IsValid() {
-		return nil, fmt.Errorf("function %s not properly registered", key)
+		return nil, Errorf(CodeInternal, "function %s not properly registered", key)
 	}
 
-	// Convert protobuf Any arguments to Go values
-	callArgs, err := r.convertArgs(ctx, meta.Type, args)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert arguments: %w", err)
+	_, streaming := ctx.Value(emitterKey{}).(emitter)
+	info := &CallInfo{
+		ServiceName:  serviceName,
+		FunctionName: functionName,
+		Args:         args,
+		Meta:         meta,
+		Streaming:    streaming,
 	}
 
-	// Call the function
-	results := meta.Function.Call(callArgs)
+	start := time.Now()
+	ctx, span := startCallSpan(ctx, key, info)
+	result, err := r.intercept(ctx, info, r.deduplicated(key, func(parent context.Context, info *CallInfo) (*anypb.Any, error) {
+		limits := r.limitsFor(key, meta)
+		ctx, cancel := withTimeout(parent, limits)
+		defer cancel()
 
-	// Handle function results
-	return r.convertResult(results)
+		// Convert protobuf Any arguments to Go values
+		callArgs, err := r.convertArgs(ctx, meta.Type, info.Args)
+		if err != nil {
+			return nil, Errorf(CodeInvalidArgument, "failed to convert arguments: %v", err)
+		}
+
+		// Reject invalid input before running user code
+		if err := validateArgs(info, callArgs); err != nil {
+			return nil, err
+		}
+
+		// Pure functions may be served from their result cache
+		var cacheKey string
+		if meta.cache != nil && !info.Streaming {
+			cacheKey = argsHash(info.Args)
+			if cached, ok := meta.cache.get(cacheKey); ok {
+				return cached, nil
+			}
+		}
+
+		release, err := r.acquire(key, limits)
+		if err != nil {
+			return nil, err
+		}
+
+		// Call the function
+		results, err := r.invoke(ctx, meta, callArgs, release)
+		if err == nil && returnsChannel(meta.Type) {
+			results, err = r.drainChannel(ctx, results)
+		}
+		if err != nil {
+			return nil, timeoutError(parent, key, limits, err)
+		}
+
+		// Handle function results
+		result, err := r.convertResult(results)
+		if err == nil && cacheKey != "" {
+			meta.cache.put(cacheKey, result)
+		}
+		return result, err
+	}))
+	r.record(ctx, key, start, err)
+	endCallSpan(span, err)
+
+	return result, err
+}
+
+// invoke runs a function and returns early when ctx is cancelled, e.g. when
+// the browser aborts the request. Functions accepting a context.Context see
+// the same cancellation through ctx.Done(). release is called once the
+// function has actually returned.
+func (r *Registry) invoke(ctx context.Context, meta *FunctionMeta, callArgs []reflect.Value, release func()) ([]reflect.Value, error) {
+	type outcome struct {
+		results []reflect.Value
+		err     error
+	}
+
+	call := func() outcome {
+		defer release()
+		results, err := r.callRecovered(ctx, meta, callArgs)
+		return outcome{results: results, err: err}
+	}
+
+	// Contexts that can never be cancelled don't need a watcher goroutine
+	if ctx.Done() == nil {
+		out := call()
+		return out.results, out.err
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		done <- call()
+	}()
+
+	select {
+	case out := <-done:
+		return out.results, out.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// callRecovered calls a function, converting a panic into an error
+func (r *Registry) callRecovered(ctx context.Context, meta *FunctionMeta, callArgs []reflect.Value) (results []reflect.Value, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = r.recovered(ctx, meta.ServiceName+"."+meta.Name, value)
+		}
+	}()
+	return meta.Function.Call(callArgs), nil
 }
 
 // convertArgs converts protobuf Any arguments to Go reflect.Values
@@ -325,23 +500,64 @@ func (r *Registry) convertArgs(ctx context.Context, fnType reflect.Type, args []
 	return callArgs, nil
 }
 
-// convertAnyToValue converts a protobuf Any to a Go reflect.Value
+// convertAnyToValue converts a protobuf Any to a Go reflect.Value of exactly
+// targetType, decoding structs, pointers, slices, maps and time.Time from JSON
 func (r *Registry) convertAnyToValue(any *anypb.Any, targetType reflect.Type) (reflect.Value, error) {
+	// Byte slices and protobuf messages may be sent as raw protobuf
+	if value, ok, err := decodeBinaryArg(any, targetType); ok {
+		return value, err
+	}
+
 	// Extract JSON data from the Any message
 	jsonData := any.GetValue()
 
 	// Create a new instance of the target type
-	value := reflect.New(targetType).Interface()
+	value := reflect.New(targetType)
 
 	// Unmarshal JSON to the target type
-	if err := json.Unmarshal(jsonData, value); err != nil {
-		// Try direct type conversion for primitive types
-		return r.convertPrimitive(jsonData, targetType)
+	err := json.Unmarshal(jsonData, value.Interface())
+	if err == nil {
+		return value.Elem(), nil
 	}
 
-	return reflect.ValueOf(value).Elem(), nil
+	// Try direct type conversion for primitive types, e.g. 1e3 for an int
+	converted, convErr := r.convertPrimitive(jsonData, targetType)
+	if convErr == nil {
+		return converted, nil
+	}
+	if errors.Is(convErr, errNotWhole) || errors.Is(convErr, errOutOfRange) {
+		return reflect.Value{}, convErr
+	}
+
+	return reflect.Value{}, describeDecodeError(err, targetType)
 }
 
+// describeDecodeError explains why JSON could not be decoded into targetType
+func describeDecodeError(err error, targetType reflect.Type) error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var timeErr *time.ParseError
+
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Errorf("field %q of %s: expected %s, got JSON %s", typeErr.Field, targetType, typeErr.Type, typeErr.Value)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("expected %s, got JSON %s", targetType, typeErr.Value)
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("invalid JSON for %s at offset %d: %v", targetType, syntaxErr.Offset, err)
+	case errors.As(err, &timeErr):
+		return fmt.Errorf("invalid time for %s, expected RFC 3339: %v", targetType, err)
+	default:
+		return fmt.Errorf("cannot decode %s: %v", targetType, err)
+	}
+}
+
+// Reasons a JSON number does not fit an integer parameter
+var (
+	errNotWhole   = errors.New("not a whole number")
+	errOutOfRange = errors.New("out of range")
+)
+
 // convertPrimitive handles primitive type conversions
 func (r *Registry) convertPrimitive(jsonData []byte, targetType reflect.Type) (reflect.Value, error) {
 	var rawValue interface{}
@@ -353,11 +569,31 @@ func (r *Registry) convertPrimitive(jsonData []byte, targetType reflect.Type) (r
 	switch targetType.Kind() {
 	case reflect.String:
 		if str, ok := rawValue.(string); ok {
-			return reflect.ValueOf(str), nil
+			return reflect.ValueOf(str).Convert(targetType), nil
 		}
-	case reflect.Int, reflect.Int32, reflect.Int64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if num, ok := rawValue.(float64); ok {
-			return reflect.ValueOf(int64(num)).Convert(targetType), nil
+			value := reflect.New(targetType).Elem()
+			if num != math.Trunc(num) {
+				return reflect.Value{}, fmt.Errorf("expected %s, got %v: %w", targetType, num, errNotWhole)
+			}
+			if num < math.MinInt64 || num >= math.MaxInt64 || value.OverflowInt(int64(num)) {
+				return reflect.Value{}, fmt.Errorf("expected %s, got %v: %w", targetType, num, errOutOfRange)
+			}
+			value.SetInt(int64(num))
+			return value, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if num, ok := rawValue.(float64); ok {
+			value := reflect.New(targetType).Elem()
+			if num != math.Trunc(num) {
+				return reflect.Value{}, fmt.Errorf("expected %s, got %v: %w", targetType, num, errNotWhole)
+			}
+			if num < 0 || num >= math.MaxUint64 || value.OverflowUint(uint64(num)) {
+				return reflect.Value{}, fmt.Errorf("expected %s, got %v: %w", targetType, num, errOutOfRange)
+			}
+			value.SetUint(uint64(num))
+			return value, nil
 		}
 	case reflect.Float32, reflect.Float64:
 		if num, ok := rawValue.(float64); ok {
@@ -365,7 +601,12 @@ func (r *Registry) convertPrimitive(jsonData []byte, targetType reflect.Type) (r
 		}
 	case reflect.Bool:
 		if b, ok := rawValue.(bool); ok {
-			return reflect.ValueOf(b), nil
+			return reflect.ValueOf(b).Convert(targetType), nil
+		}
+	case reflect.Struct:
+		// JavaScript timestamps are milliseconds since the epoch
+		if num, ok := rawValue.(float64); ok && targetType == reflect.TypeOf(time.Time{}) {
+			return reflect.ValueOf(time.UnixMilli(int64(num))), nil
 		}
 	}
 
@@ -453,72 +694,6 @@ func (r *Registry) GetFunction(serviceName, functionName string) (*FunctionMeta,
 	return meta, exists
 }
 
-// BuildAndImportServerPackages dynamically builds and imports server packages
-// to trigger their init() functions and register their functions
-func (r *Registry) BuildAndImportServerPackages(serverDir string) error {
-	// Find all .go files in the server directory
-	goFiles, err := r.findGoFiles(serverDir)
-	if err != nil {
-		return fmt.Errorf("failed to find Go files in %s: %w", serverDir, err)
-	}
-
-	if len(goFiles) == 0 {
-		log.Printf("No Go files found in %s", serverDir)
-		return nil
-	}
-
-	// Generate import statements and create a temporary file to trigger init() functions
-	return r.generateAndBuildImports(serverDir, goFiles)
-}
-
-// findGoFiles recursively finds all .go files in a directory
-func (r *Registry) findGoFiles(dir string) ([]string, error) {
-	var goFiles []string
-
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() && strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go") {
-			goFiles = append(goFiles, path)
-		}
-
-		return nil
-	})
-
-	return goFiles, err
-}
-
-// generateAndBuildImports creates import statements for server packages
-func (r *Registry) generateAndBuildImports(serverDir string, goFiles []string) error {
-	// Parse the go.mod file to get the module name
-	moduleName, err := r.getModuleName()
-	if err != nil {
-		log.Printf("Warning: Could not determine module name: %v", err)
-		return nil
-	}
-
-	// Group files by package
-	packages := make(map[string]bool)
-	for _, file := range goFiles {
-		// Get the directory of the file relative to the current directory
-		dir := filepath.Dir(file)
-		if dir != "." {
-			// Convert file path to import path
-			importPath := filepath.Join(moduleName, dir)
-			packages[importPath] = true
-		}
-	}
-
-	// Create temporary import file
-	if len(packages) > 0 {
-		return r.createImportFile(packages)
-	}
-
-	return nil
-}
-
 // GetModuleName reads the module name from go.mod
 func GetModuleName() (string, error) {
 	data, err := os.ReadFile("go.mod")
@@ -537,48 +712,6 @@ func GetModuleName() (string, error) {
 	return "", fmt.Errorf("module name not found in go.mod")
 }
 
-// getModuleName reads the module name from go.mod (private method)
-func (r *Registry) getModuleName() (string, error) {
-	return GetModuleName()
-}
-
-// createImportFile creates a temporary file that imports all server packages
-func (r *Registry) createImportFile(packages map[string]bool) error {
-	// Create .golem directory if it doesn't exist
-	if err := os.MkdirAll(".golem", 0755); err != nil {
-		return err
-	}
-
-	// Generate import file content
-	var imports []string
-	for pkg := range packages {
-		imports = append(imports, fmt.Sprintf(`_ "%s"`, pkg))
-	}
-
-	content := fmt.Sprintf(`// Auto-generated file to import server packages
-// This file triggers init() functions in server packages
-package main
-
-import (
-%s
-)
-
-func init() {
-	// Server packages imported above will have their init() functions called
-	// which should register their functions with the global registry
-}
-`, strings.Join(imports, "\n\t"))
-
-	// Write the import file
-	importFile := ".golem/server_imports.go"
-	if err := os.WriteFile(importFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write import file: %w", err)
-	}
-
-	log.Printf("Generated server import file with %d packages", len(packages))
-	return nil
-}
-
 // RegisterFromGlobal copies all functions from the global registry to this registry
 func (r *Registry) RegisterFromGlobal() error {
 	r.mutex.Lock()
@@ -591,7 +724,13 @@ func (r *Registry) RegisterFromGlobal() error {
 	for key, meta := range globalRegistry.functions {
 		r.functions[key] = meta
 	}
+	r.interceptors = append([]Interceptor(nil), globalRegistry.interceptors...)
+	r.identityResolver = globalRegistry.identityResolver
+	r.sessionStore = globalRegistry.sessionStore
+	r.healthChecks = make(map[string]HealthCheck, len(globalRegistry.healthChecks))
+	for name, check := range globalRegistry.healthChecks {
+		r.healthChecks[name] = check
+	}
 
-	log.Printf("Copied %d functions from global registry", len(globalRegistry.functions))
 	return nil
 }
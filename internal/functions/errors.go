@@ -0,0 +1,150 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Error codes shared by the server and the WASM client
+const (
+	CodeUnknown            = "unknown"
+	CodeInvalidArgument    = "invalid_argument"
+	CodeNotFound           = "not_found"
+	CodeAlreadyExists      = "already_exists"
+	CodePermissionDenied   = "permission_denied"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeResourceExhausted  = "resource_exhausted"
+	CodeFailedPrecondition = "failed_precondition"
+	CodeUnavailable        = "unavailable"
+	CodeDeadlineExceeded   = "deadline_exceeded"
+	CodeCanceled           = "canceled"
+	CodeInternal           = "internal"
+)
+
+// Error is the structured error envelope sent to clients. Server functions
+// may return one to give the UI a code and details it can branch on; any
+// other error is reported with CodeUnknown.
+type Error struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Retryable bool                   `json:"retryable"`
+}
+
+// NewError creates a structured error
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf creates a structured error with a formatted message
+func Errorf(code, format string, args ...interface{}) *Error {
+	return NewError(code, fmt.Sprintf(format, args...))
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// WithDetail adds a detail entry and returns the error
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// WithRetryable marks whether the client may safely retry the call
+func (e *Error) WithRetryable(retryable bool) *Error {
+	e.Retryable = retryable
+	return e
+}
+
+// ToError converts any error into a structured error
+func ToError(err error) *Error {
+	var structured *Error
+	if errors.As(err, &structured) {
+		return structured
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeDeadlineExceeded, Message: err.Error(), Retryable: true}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled, Message: err.Error()}
+	default:
+		return &Error{Code: CodeUnknown, Message: err.Error()}
+	}
+}
+
+// HTTPStatus returns the HTTP status code used to report the error
+func (e *Error) HTTPStatus() int {
+	switch e.Code {
+	case CodeInvalidArgument, CodeFailedPrecondition:
+		return http.StatusBadRequest
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeAlreadyExists:
+		return http.StatusConflict
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// envelope returns the JSON response body for a failed call
+func (e *Error) envelope() map[string]interface{} {
+	body := map[string]interface{}{
+		"success":   false,
+		"error":     e.Message,
+		"code":      e.Code,
+		"retryable": e.Retryable,
+	}
+	if len(e.Details) > 0 {
+		body["details"] = e.Details
+	}
+	return body
+}
+
+// metadata encodes the error for gRPC response metadata
+func (e *Error) metadata() map[string]string {
+	md := map[string]string{
+		"code":      e.Code,
+		"retryable": strconv.FormatBool(e.Retryable),
+	}
+	if len(e.Details) > 0 {
+		if details, err := json.Marshal(e.Details); err == nil {
+			md["details"] = string(details)
+		}
+	}
+	return md
+}
+
+// writeError writes a failed call as a structured JSON response
+func writeError(w http.ResponseWriter, err error) {
+	structured := ToError(err)
+	w.WriteHeader(structured.HTTPStatus())
+	json.NewEncoder(w).Encode(structured.envelope())
+}
+
+// WriteErrorStatus writes err as the JSON response of a failed call, with
+// status rather than the one of its code, e.g. 413 for a body too large
+func WriteErrorStatus(w http.ResponseWriter, status int, err *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(err.envelope())
+}
@@ -0,0 +1,190 @@
+package functions
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GeneratedFile is the registration file written into every server package
+const GeneratedFile = "golem_functions_gen.go"
+
+// HostMainFile is the generated main package of the server binary
+const HostMainFile = ".golem/server/main.go"
+
+const generatedHeader = "// Code generated by golem generate functions. DO NOT EDIT.\n\n"
+
+// GeneratedPackage describes the registrations generated for one package
+type GeneratedPackage struct {
+	ImportPath string
+	Dir        string
+	Functions  []string
+}
+
+// GenerateRegistrations writes a registration file calling functions.Register
+// for every exported function of each package under serverDir, and a main
+// package importing them that runs the server. It replaces discovering
+// functions from source at runtime: the binary registers them when it starts.
+func GenerateRegistrations(serverDir string) ([]GeneratedPackage, error) {
+	moduleName, err := GetModuleName()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine module name: %w", err)
+	}
+
+	var packages []GeneratedPackage
+	err = filepath.WalkDir(serverDir, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		name := entry.Name()
+		if dir != serverDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+			return filepath.SkipDir
+		}
+
+		pkg, err := generatePackage(dir)
+		if err != nil || pkg == nil {
+			return err
+		}
+		pkg.ImportPath = path.Join(moduleName, filepath.ToSlash(filepath.Clean(dir)))
+		packages = append(packages, *pkg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := WriteHostMain(packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// generatePackage writes the registration file of the package in dir. It
+// returns nil for directories without a non-main package.
+func generatePackage(dir string) (*GeneratedPackage, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseDir(fset, dir, func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != GeneratedFile
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", dir, err)
+	}
+
+	for packageName, pkg := range parsed {
+		if packageName == "main" {
+			continue
+		}
+
+		var decls []*ast.FuncDecl
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if ok && fn.Recv == nil && fn.Name.IsExported() && fn.Type.TypeParams == nil {
+					decls = append(decls, fn)
+				}
+			}
+		}
+		if len(decls) == 0 {
+			continue
+		}
+		sort.Slice(decls, func(i, j int) bool { return decls[i].Name.Name < decls[j].Name.Name })
+
+		var body bytes.Buffer
+		body.WriteString(generatedHeader)
+		fmt.Fprintf(&body, "package %s\n\n", packageName)
+		body.WriteString("import \"github.com/Nu11ified/golem/functions\"\n\n")
+		body.WriteString("func init() {\n")
+
+		generated := &GeneratedPackage{Dir: dir}
+		for _, fn := range decls {
+			args := []string{strconv.Quote(packageName), strconv.Quote(fn.Name.Name), fn.Name.Name}
+			args = append(args, registerOptions(fn.Doc)...)
+			fmt.Fprintf(&body, "\tfunctions.Register(%s)\n", strings.Join(args, ", "))
+			generated.Functions = append(generated.Functions, fn.Name.Name)
+		}
+		body.WriteString("}\n")
+
+		if err := writeGoFile(filepath.Join(dir, GeneratedFile), body.Bytes()); err != nil {
+			return nil, err
+		}
+		return generated, nil
+	}
+	return nil, nil
+}
+
+// registerOptions returns the option expressions for a function's doc comment
+func registerOptions(doc *ast.CommentGroup) []string {
+	var options []string
+	if description := extractDocString(doc); description != "" {
+		options = append(options, fmt.Sprintf("functions.WithDescription(%s)", strconv.Quote(description)))
+	}
+
+	if requirement := extractAuth(doc); requirement != nil {
+		switch {
+		case len(requirement.Roles) == 0 && len(requirement.Permissions) == 0:
+			options = append(options, "functions.RequireAuthenticated()")
+		default:
+			if len(requirement.Roles) > 0 {
+				options = append(options, fmt.Sprintf("functions.RequireRoles(%s)", quoteAll(requirement.Roles)))
+			}
+			if len(requirement.Permissions) > 0 {
+				options = append(options, fmt.Sprintf("functions.RequirePermissions(%s)", quoteAll(requirement.Permissions)))
+			}
+		}
+	}
+	return options
+}
+
+// WriteHostMain writes the main package of the server binary, linking in
+// the registrations of packages
+func WriteHostMain(packages []GeneratedPackage) error {
+	var body bytes.Buffer
+	body.WriteString(generatedHeader)
+	body.WriteString("package main\n\nimport (\n\t\"github.com/Nu11ified/golem/functions/host\"\n")
+	for _, pkg := range packages {
+		fmt.Fprintf(&body, "\t_ %s\n", strconv.Quote(pkg.ImportPath))
+	}
+	body.WriteString(")\n\nfunc main() {\n\thost.Main()\n}\n")
+
+	if err := os.MkdirAll(filepath.Dir(HostMainFile), 0755); err != nil {
+		return err
+	}
+	return writeGoFile(HostMainFile, body.Bytes())
+}
+
+// writeGoFile formats and writes generated Go source, leaving the file
+// untouched when its content is unchanged so builds stay cached
+func writeGoFile(filename string, source []byte) error {
+	formatted, err := format.Source(source)
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", filename, err)
+	}
+	if existing, err := os.ReadFile(filename); err == nil && bytes.Equal(existing, formatted) {
+		return nil
+	}
+	if err := os.WriteFile(filename, formatted, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -3,10 +3,15 @@ package functions
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	pb "github.com/Nu11ified/golem/proto/gen/proto"
@@ -27,16 +32,15 @@ func NewGRPCServer(registry *Registry) *GRPCServer {
 
 // Call implements the Call RPC method
 func (s *GRPCServer) Call(ctx context.Context, req *pb.FunctionRequest) (*pb.FunctionResponse, error) {
-	log.Printf("gRPC Call: %s.%s with %d args", req.ServiceName, req.FunctionName, len(req.Args))
-
 	// Call the function through the registry
+	ctx = grpcContext(ctx, pb.FunctionService_Call_FullMethodName)
 	result, err := s.registry.CallFunction(ctx, req.ServiceName, req.FunctionName, req.Args)
 	if err != nil {
-		log.Printf("Function call error: %v", err)
+		structured := ToError(err)
 		return &pb.FunctionResponse{
 			Success:  false,
-			Error:    err.Error(),
-			Metadata: make(map[string]string),
+			Error:    structured.Message,
+			Metadata: structured.metadata(),
 		}, nil
 	}
 
@@ -47,32 +51,8 @@ func (s *GRPCServer) Call(ctx context.Context, req *pb.FunctionRequest) (*pb.Fun
 	}, nil
 }
 
-// CallStream implements the streaming Call RPC method
-func (s *GRPCServer) CallStream(req *pb.FunctionRequest, stream pb.FunctionService_CallStreamServer) error {
-	// For now, just call the function once and send the result
-	// This could be extended for true streaming functionality
-	ctx := stream.Context()
-
-	result, err := s.registry.CallFunction(ctx, req.ServiceName, req.FunctionName, req.Args)
-	if err != nil {
-		return stream.Send(&pb.FunctionResponse{
-			Success:  false,
-			Error:    err.Error(),
-			Metadata: make(map[string]string),
-		})
-	}
-
-	return stream.Send(&pb.FunctionResponse{
-		Success:  true,
-		Result:   result,
-		Metadata: make(map[string]string),
-	})
-}
-
 // ListFunctions implements the ListFunctions RPC method
 func (s *GRPCServer) ListFunctions(ctx context.Context, req *pb.ListFunctionsRequest) (*pb.ListFunctionsResponse, error) {
-	log.Printf("gRPC ListFunctions for service: %s", req.ServiceName)
-
 	functions := s.registry.ListFunctions(req.ServiceName)
 
 	return &pb.ListFunctionsResponse{
@@ -83,17 +63,6 @@ func (s *GRPCServer) ListFunctions(ctx context.Context, req *pb.ListFunctionsReq
 // HTTPHandler provides a simple HTTP endpoint for testing
 func (s *GRPCServer) HTTPHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "OPTIONS" {
-			// Handle CORS preflight
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Content-Type", "application/json")
 
 		if r.Method != "POST" {
@@ -102,41 +71,34 @@ func (s *GRPCServer) HTTPHandler() func(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		// Parse request
-		var reqData struct {
-			FunctionName string        `json:"functionName"`
-			ServiceName  string        `json:"serviceName"`
-			Args         []interface{} `json:"args"`
+		// Binary payloads carry a protobuf FunctionRequest
+		if isProtobufRequest(r) {
+			s.serveProtobuf(w, r)
+			return
 		}
 
-		if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"})
+		// Parse request
+		reqData, protoArgs, err := decodeHTTPCall(r)
+		if err != nil {
+			writeError(w, NewError(CodeInvalidArgument, err.Error()))
 			return
 		}
 
-		// Convert args to protobuf Any
-		var protoArgs []*anypb.Any
-		for _, arg := range reqData.Args {
-			argBytes, err := json.Marshal(arg)
-			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]string{"error": "Failed to serialize argument"})
-				return
-			}
-
-			anyArg := &anypb.Any{
-				TypeUrl: "type.googleapis.com/google.protobuf.Value",
-				Value:   argBytes,
-			}
-			protoArgs = append(protoArgs, anyArg)
+		// Call function, bounded by the client's deadline
+		ctx, cancel := requestContext(r)
+		defer cancel()
+		result, err := s.registry.CallFunction(ctx, reqData.ServiceName, reqData.FunctionName, protoArgs)
+		if err != nil {
+			writeError(w, err)
+			return
 		}
 
-		// Call function
-		result, err := s.registry.CallFunction(r.Context(), reqData.ServiceName, reqData.FunctionName, protoArgs)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		// Clients accepting protobuf get the binary response
+		if acceptsProtobuf(r) {
+			if meta, ok := s.registry.GetFunction(reqData.ServiceName, reqData.FunctionName); ok {
+				result, err = binaryResult(meta, result)
+			}
+			s.writeCallResponse(w, r, result, err)
 			return
 		}
 
@@ -156,11 +118,71 @@ func (s *GRPCServer) HTTPHandler() func(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// TimeoutHeader carries the client's remaining deadline in milliseconds
+const TimeoutHeader = "X-Golem-Timeout"
+
+// requestContext returns the request context carrying the call metadata,
+// request and request ID, bounded by the deadline the client sent in TimeoutHeader so
+// abandoned calls stop server work
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	id := httpRequestID(r)
+	ctx := WithRequest(WithMetadata(WithRequestID(r.Context(), id), NewMetadata(r.Header)), newHTTPRequest(r, id))
+
+	ms, err := strconv.ParseInt(r.Header.Get(TimeoutHeader), 10, 64)
+	if err != nil || ms <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+// httpCall is the JSON body accepted by the HTTP function endpoints
+type httpCall struct {
+	FunctionName string        `json:"functionName"`
+	ServiceName  string        `json:"serviceName"`
+	Args         []interface{} `json:"args"`
+}
+
+// decodeHTTPCall parses an HTTP function call and converts its args to
+// protobuf Any. Calls to /api/functions/{service}/{function} send only the
+// args array as the body.
+func decodeHTTPCall(r *http.Request) (*httpCall, []*anypb.Any, error) {
+	var reqData httpCall
+	if serviceName, functionName, ok := callPath(r.URL.Path); ok {
+		reqData.ServiceName, reqData.FunctionName = serviceName, functionName
+		if err := json.NewDecoder(r.Body).Decode(&reqData.Args); err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("Invalid JSON")
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		return nil, nil, fmt.Errorf("Invalid JSON")
+	}
+
+	protoArgs, err := argsToAny(reqData.Args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &reqData, protoArgs, nil
+}
+
+// callPath extracts the function of a /api/functions/{service}/{function} path
+func callPath(path string) (serviceName, functionName string, ok bool) {
+	rest, found := strings.CutPrefix(path, "/api/functions/")
+	if !found {
+		return "", "", false
+	}
+	serviceName, functionName, found = strings.Cut(rest, "/")
+	if !found || serviceName == "" || functionName == "" || strings.Contains(functionName, "/") {
+		return "", "", false
+	}
+	return serviceName, functionName, true
+}
+
 // CreateGRPCServer creates and configures a gRPC server
-func CreateGRPCServer(registry *Registry) *grpc.Server {
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor),
-	)
+func CreateGRPCServer(registry *Registry, opts ...grpc.ServerOption) *grpc.Server {
+	grpcServer := grpc.NewServer(append(opts,
+		grpc.UnaryInterceptor(registry.loggingInterceptor),
+		grpc.StreamInterceptor(tracingStreamInterceptor),
+	)...)
 
 	functionServer := NewGRPCServer(registry)
 	pb.RegisterFunctionServiceServer(grpcServer, functionServer)
@@ -168,16 +190,16 @@ func CreateGRPCServer(registry *Registry) *grpc.Server {
 	return grpcServer
 }
 
-// loggingInterceptor logs all gRPC calls
-func loggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	log.Printf("gRPC call: %s", info.FullMethod)
+// loggingInterceptor assigns every unary gRPC call its request ID, traces
+// it in a server span and logs it once it finished, like the HTTP server's
+// request log
+func (r *Registry) loggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx = WithRequestID(ctx, grpcRequestID(ctx, metadataFromGRPC(ctx)))
+	ctx, span := startGRPCSpan(ctx, info.FullMethod)
 
 	resp, err := handler(ctx, req)
-	if err != nil {
-		log.Printf("gRPC call %s failed: %v", info.FullMethod, err)
-	} else {
-		log.Printf("gRPC call %s succeeded", info.FullMethod)
-	}
-
+	endGRPCSpan(span, err)
+	r.logFor(ctx).Info("grpc request", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start))
 	return resp, err
 }
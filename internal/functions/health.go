@@ -0,0 +1,93 @@
+package functions
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// HealthCheck reports whether a dependency of the server functions, such as
+// a database, is usable; a nil error means healthy. It should return once
+// ctx is done.
+type HealthCheck func(ctx context.Context) error
+
+// AddHealthCheck registers a named readiness check. Checks added under the
+// same name replace each other.
+func (r *Registry) AddHealthCheck(name string, check HealthCheck) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.healthChecks == nil {
+		r.healthChecks = make(map[string]HealthCheck)
+	}
+	r.healthChecks[name] = check
+}
+
+// AddGlobalHealthCheck registers a readiness check with the global registry
+func AddGlobalHealthCheck(name string, check HealthCheck) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	globalRegistry.AddHealthCheck(name, check)
+}
+
+// HealthCheckNames returns the names of the registered checks, sorted
+func (r *Registry) HealthCheckNames() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.healthChecks))
+	for name := range r.healthChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckHealth runs the registered checks concurrently and returns the
+// result of each by name. A check that panics fails.
+func (r *Registry) CheckHealth(ctx context.Context) map[string]error {
+	r.mutex.RLock()
+	checks := make(map[string]HealthCheck, len(r.healthChecks))
+	for name, check := range r.healthChecks {
+		checks[name] = check
+	}
+	r.mutex.RUnlock()
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		results = make(map[string]error, len(checks))
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+			err := runHealthCheck(ctx, check)
+			mutex.Lock()
+			results[name] = err
+			mutex.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return results
+}
+
+// runHealthCheck runs check, failing when it panics or outlives ctx
+func runHealthCheck(ctx context.Context, check HealthCheck) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if value := recover(); value != nil {
+				done <- Errorf(CodeInternal, "health check panicked: %v", value)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,172 @@
+package functions
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// IdempotencyKeyHeader carries a client-chosen key identifying one logical
+// call. Repeats of the call with the same key, function and arguments within
+// the idempotency window return the first result instead of running again.
+// Failed calls are not remembered, so retries run the function again.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyWindow is how long results are remembered by default
+const DefaultIdempotencyWindow = 10 * time.Minute
+
+// DefaultIdempotencyCapacity is how many results are remembered at most by
+// default
+const DefaultIdempotencyCapacity = 10000
+
+// idempotencyStore remembers the outcome of calls made with an idempotency key
+type idempotencyStore struct {
+	mutex     sync.Mutex
+	window    time.Duration
+	capacity  int
+	entries   map[string]*idempotentCall
+	completed *list.List // keys of the remembered results, oldest first
+	nextSweep time.Time
+}
+
+type idempotentCall struct {
+	done    chan struct{}
+	result  *anypb.Any
+	err     error
+	expires time.Time
+	element *list.Element // in completed once the call succeeded
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{
+		window:    DefaultIdempotencyWindow,
+		capacity:  DefaultIdempotencyCapacity,
+		entries:   make(map[string]*idempotentCall),
+		completed: list.New(),
+	}
+}
+
+// SetIdempotencyWindow sets how long results of calls carrying an
+// Idempotency-Key are remembered; zero disables deduplication
+func (r *Registry) SetIdempotencyWindow(window time.Duration) {
+	r.idempotency.mutex.Lock()
+	defer r.idempotency.mutex.Unlock()
+	r.idempotency.window = window
+}
+
+// SetIdempotencyCapacity sets how many results of calls carrying an
+// Idempotency-Key are remembered at most; the oldest are forgotten first
+func (r *Registry) SetIdempotencyCapacity(capacity int) {
+	r.idempotency.mutex.Lock()
+	defer r.idempotency.mutex.Unlock()
+	r.idempotency.capacity = capacity
+	r.idempotency.evict(capacity)
+}
+
+// deduplicate runs call once per idempotency key. Concurrent repeats wait for
+// the first call; later repeats get its remembered result when it succeeded.
+func (s *idempotencyStore) deduplicate(ctx context.Context, key string, call func() (*anypb.Any, error)) (*anypb.Any, error) {
+	s.mutex.Lock()
+	if s.window <= 0 {
+		s.mutex.Unlock()
+		return call()
+	}
+
+	now := time.Now()
+	if entry, exists := s.entries[key]; exists {
+		if entry.expires.IsZero() || now.Before(entry.expires) {
+			s.mutex.Unlock()
+			select {
+			case <-entry.done:
+				return entry.result, entry.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		s.forget(key, entry)
+	}
+
+	if !now.Before(s.nextSweep) {
+		s.sweep(now)
+		s.nextSweep = now.Add(s.window)
+	}
+	// Calls still running cannot be forgotten, so when they fill the store
+	// the call runs without being remembered
+	s.evict(s.capacity - 1)
+	if len(s.entries) >= s.capacity {
+		s.mutex.Unlock()
+		return call()
+	}
+
+	entry := &idempotentCall{done: make(chan struct{})}
+	s.entries[key] = entry
+	s.mutex.Unlock()
+
+	entry.result, entry.err = call()
+
+	s.mutex.Lock()
+	if entry.err != nil {
+		// Clients retry failures with the same key, such as an unavailable
+		// server or a caller that gave up, so a retry runs the function again
+		s.forget(key, entry)
+	} else {
+		entry.expires = time.Now().Add(s.window)
+		entry.element = s.completed.PushBack(key)
+	}
+	s.mutex.Unlock()
+	close(entry.done)
+
+	return entry.result, entry.err
+}
+
+// sweep forgets the results expired at now. Results expire in the order
+// they completed while the window is unchanged, so only the oldest are
+// looked at.
+func (s *idempotencyStore) sweep(now time.Time) {
+	for element := s.completed.Front(); element != nil; element = s.completed.Front() {
+		key := element.Value.(string)
+		entry := s.entries[key]
+		if now.Before(entry.expires) {
+			return
+		}
+		s.forget(key, entry)
+	}
+}
+
+// evict forgets the oldest results until at most capacity entries remain
+// or only calls still running are left
+func (s *idempotencyStore) evict(capacity int) {
+	for len(s.entries) > capacity && s.completed.Len() > 0 {
+		key := s.completed.Front().Value.(string)
+		s.forget(key, s.entries[key])
+	}
+}
+
+// forget removes the entry of key
+func (s *idempotencyStore) forget(key string, entry *idempotentCall) {
+	if entry.element != nil {
+		s.completed.Remove(entry.element)
+		entry.element = nil
+	}
+	if s.entries[key] == entry {
+		delete(s.entries, key)
+	}
+}
+
+// deduplicated wraps a handler so calls carrying an Idempotency-Key run once
+func (r *Registry) deduplicated(key string, handler Handler) Handler {
+	return func(ctx context.Context, info *CallInfo) (*anypb.Any, error) {
+		idempotencyKey := MetadataFromContext(ctx).Get(IdempotencyKeyHeader)
+		if idempotencyKey == "" || info.Streaming {
+			return handler(ctx, info)
+		}
+
+		scope := key + "\x00" + idempotencyKey + "\x00" + argsHash(info.Args)
+		return r.idempotency.deduplicate(ctx, scope, func() (*anypb.Any, error) {
+			return handler(ctx, info)
+		})
+	}
+}
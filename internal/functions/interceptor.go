@@ -0,0 +1,85 @@
+package functions
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// CallInfo describes a function call passing through the interceptor chain.
+// Interceptors may modify Args before calling next.
+type CallInfo struct {
+	ServiceName  string
+	FunctionName string
+	Args         []*anypb.Any
+	Meta         *FunctionMeta
+	Streaming    bool // the call streams partial results or messages
+}
+
+// Handler executes a function call
+type Handler func(ctx context.Context, info *CallInfo) (*anypb.Any, error)
+
+// Interceptor wraps every function call made through the registry, whether
+// it arrives over gRPC, the HTTP bridge, a stream or a WebSocket. It must
+// call next to run the function and may inspect or replace the result.
+type Interceptor func(ctx context.Context, info *CallInfo, next Handler) (*anypb.Any, error)
+
+// Use adds interceptors; they run in the order they were added
+func (r *Registry) Use(interceptors ...Interceptor) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.interceptors = append(r.interceptors, interceptors...)
+}
+
+// MessageGate decides whether a client may send one more message to a
+// bidirectional function over a WebSocket. An error ends the socket.
+type MessageGate func(ctx context.Context) error
+
+// GateMessages adds gates that every message streamed to a bidirectional
+// function over a WebSocket must pass, in the order they were added
+func (r *Registry) GateMessages(gates ...MessageGate) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.gates = append(r.gates, gates...)
+}
+
+// gateMessage runs the gates for a message sent on a call with ctx
+func (r *Registry) gateMessage(ctx context.Context) error {
+	r.mutex.RLock()
+	gates := r.gates
+	r.mutex.RUnlock()
+
+	for _, gate := range gates {
+		if err := gate(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// intercept runs info through the interceptor chain, ending with final
+func (r *Registry) intercept(ctx context.Context, info *CallInfo, final Handler) (*anypb.Any, error) {
+	r.mutex.RLock()
+	interceptors := r.interceptors
+	r.mutex.RUnlock()
+
+	// Authorization runs last so interceptors may establish the identity
+	authorized := func(ctx context.Context, info *CallInfo) (*anypb.Any, error) {
+		ctx, err := r.authorize(ctx, info)
+		if err != nil {
+			return nil, err
+		}
+		return final(ctx, info)
+	}
+
+	var chain func(index int) Handler
+	chain = func(index int) Handler {
+		if index >= len(interceptors) {
+			return authorized
+		}
+		return func(ctx context.Context, info *CallInfo) (*anypb.Any, error) {
+			return interceptors[index](ctx, info, chain(index+1))
+		}
+	}
+	return chain(0)(ctx, info)
+}
@@ -0,0 +1,92 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Limits bound the execution of a function. Zero values mean unlimited.
+type Limits struct {
+	Timeout       time.Duration // maximum execution time of one call
+	MaxConcurrent int           // maximum number of calls running at once
+}
+
+// WithTimeout limits how long one call of the function may run
+func WithTimeout(timeout time.Duration) RegisterOption {
+	return func(meta *FunctionMeta) {
+		meta.Limits.Timeout = timeout
+	}
+}
+
+// WithMaxConcurrency limits how many calls of the function may run at once;
+// further calls fail with CodeResourceExhausted instead of queueing
+func WithMaxConcurrency(n int) RegisterOption {
+	return func(meta *FunctionMeta) {
+		meta.Limits.MaxConcurrent = n
+	}
+}
+
+// SetLimits overrides the limits declared at registration, e.g. from the
+// project config. Non-zero fields replace the registered values; the
+// function does not need to be registered yet.
+func (r *Registry) SetLimits(serviceName, functionName string, limits Limits) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.limits[serviceName+"."+functionName] = limits
+}
+
+// limitsFor returns the effective limits of a function
+func (r *Registry) limitsFor(key string, meta *FunctionMeta) Limits {
+	r.mutex.RLock()
+	override, exists := r.limits[key]
+	r.mutex.RUnlock()
+
+	limits := meta.Limits
+	if exists && override.Timeout > 0 {
+		limits.Timeout = override.Timeout
+	}
+	if exists && override.MaxConcurrent > 0 {
+		limits.MaxConcurrent = override.MaxConcurrent
+	}
+	return limits
+}
+
+// acquire reserves a concurrency slot, returning the function that frees it
+func (r *Registry) acquire(key string, limits Limits) (func(), error) {
+	if limits.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+
+	r.mutex.Lock()
+	slots, exists := r.slots[key]
+	if !exists || cap(slots) != limits.MaxConcurrent {
+		slots = make(chan struct{}, limits.MaxConcurrent)
+		r.slots[key] = slots
+	}
+	r.mutex.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	default:
+		return nil, Errorf(CodeResourceExhausted, "function %s is at its limit of %d concurrent calls", key, limits.MaxConcurrent).WithRetryable(true)
+	}
+}
+
+// withTimeout bounds ctx by the function's timeout
+func withTimeout(ctx context.Context, limits Limits) (context.Context, context.CancelFunc) {
+	if limits.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, limits.Timeout)
+}
+
+// timeoutError reports a call stopped by the function's own timeout rather
+// than by the caller
+func timeoutError(parent context.Context, key string, limits Limits, err error) error {
+	if limits.Timeout > 0 && errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil {
+		return Errorf(CodeDeadlineExceeded, "function %s exceeded its %v timeout", key, limits.Timeout)
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package functions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata is the request metadata of a function call: headers sent by the
+// client (including Authorization and per-call metadata) and cookies
+type Metadata struct {
+	header http.Header
+}
+
+type metadataKey struct{}
+
+// WithMetadata returns a context carrying call metadata
+func WithMetadata(ctx context.Context, md *Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext returns the metadata of the current call. It never
+// returns nil; outside of a call the metadata is empty.
+func MetadataFromContext(ctx context.Context) *Metadata {
+	if md, ok := ctx.Value(metadataKey{}).(*Metadata); ok {
+		return md
+	}
+	return &Metadata{header: make(http.Header)}
+}
+
+// NewMetadata creates metadata from HTTP-style headers
+func NewMetadata(header http.Header) *Metadata {
+	return &Metadata{header: header.Clone()}
+}
+
+// metadataFromGRPC converts incoming gRPC metadata
+func metadataFromGRPC(ctx context.Context) *Metadata {
+	header := make(http.Header)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			for _, value := range values {
+				header.Add(key, value)
+			}
+		}
+	}
+	return &Metadata{header: header}
+}
+
+// Get returns the first value for key
+func (m *Metadata) Get(key string) string {
+	return m.header.Get(key)
+}
+
+// Values returns all values for key
+func (m *Metadata) Values(key string) []string {
+	return m.header.Values(key)
+}
+
+// Header returns a copy of all metadata as HTTP headers
+func (m *Metadata) Header() http.Header {
+	return m.header.Clone()
+}
+
+// Cookies returns the cookies sent with the call
+func (m *Metadata) Cookies() []*http.Cookie {
+	return (&http.Request{Header: http.Header{"Cookie": m.header.Values("Cookie")}}).Cookies()
+}
+
+// Cookie returns the named cookie's value
+func (m *Metadata) Cookie(name string) (string, bool) {
+	for _, cookie := range m.Cookies() {
+		if cookie.Name == name {
+			return cookie.Value, true
+		}
+	}
+	return "", false
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header
+func (m *Metadata) BearerToken() string {
+	scheme, token, found := strings.Cut(m.header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
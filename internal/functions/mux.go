@@ -0,0 +1,184 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// muxMessage is the JSON envelope of the multiplexed function socket. Calls
+// carry a client-chosen ID that their result or error echoes, so many calls
+// can be in flight on one connection; push messages may arrive at any time.
+type muxMessage struct {
+	ID           uint64                 `json:"id,omitempty"`
+	Type         string                 `json:"type"` // "call", "cancel", "result", "error", "push"
+	ServiceName  string                 `json:"serviceName,omitempty"`
+	FunctionName string                 `json:"functionName,omitempty"`
+	Args         []interface{}          `json:"args,omitempty"`
+	Metadata     map[string]string      `json:"metadata,omitempty"`
+	Timeout      int64                  `json:"timeout,omitempty"` // milliseconds, like TimeoutHeader
+	Topic        string                 `json:"topic,omitempty"`
+	Result       json.RawMessage        `json:"result,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+	Code         string                 `json:"code,omitempty"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+	Retryable    bool                   `json:"retryable,omitempty"`
+}
+
+// Pusher sends messages to a client connected over the multiplexed socket.
+// Functions may keep it after returning to push updates until Done closes.
+type Pusher struct {
+	conn *websocket.Conn
+	ctx  context.Context
+}
+
+type pusherKey struct{}
+
+// PusherFromContext returns the pusher of the connection the current call
+// arrived on. It is absent for calls that did not use the multiplexed socket.
+func PusherFromContext(ctx context.Context) (*Pusher, bool) {
+	pusher, ok := ctx.Value(pusherKey{}).(*Pusher)
+	return pusher, ok
+}
+
+// Push sends data to the client's handlers for topic
+func (p *Pusher) Push(topic string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal push message: %w", err)
+	}
+	return writeMuxMessage(p.ctx, p.conn, muxMessage{Type: "push", Topic: topic, Result: encoded})
+}
+
+// Done is closed when the connection closes
+func (p *Pusher) Done() <-chan struct{} {
+	return p.ctx.Done()
+}
+
+// MuxHandler serves /api/functions/mux, a persistent socket over which a
+// client makes any number of concurrent function calls and receives pushed
+// messages. It saves the per-call connection and header overhead of HTTP.
+func (s *GRPCServer) MuxHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			s.registry.log().Warn("could not upgrade to websocket", "path", r.URL.Path, "error", err)
+			return
+		}
+		defer conn.Close(websocket.StatusInternalError, "internal error")
+
+		ctx, cancel := context.WithCancel(WithRequestID(r.Context(), httpRequestID(r)))
+		defer cancel()
+		pusher := &Pusher{conn: conn, ctx: ctx}
+
+		var (
+			wg     sync.WaitGroup
+			mutex  sync.Mutex
+			calls  = make(map[uint64]context.CancelFunc)
+			finish = func(id uint64) {
+				mutex.Lock()
+				delete(calls, id)
+				mutex.Unlock()
+			}
+		)
+		defer wg.Wait()
+
+		for {
+			var msg muxMessage
+			if _, data, err := conn.Read(ctx); err != nil {
+				cancel()
+				return
+			} else if err := json.Unmarshal(data, &msg); err != nil {
+				conn.Close(websocket.StatusPolicyViolation, "invalid message")
+				return
+			}
+
+			switch msg.Type {
+			case "call":
+				callCtx, callCancel := s.muxCallContext(ctx, r, msg)
+				mutex.Lock()
+				calls[msg.ID] = callCancel
+				mutex.Unlock()
+
+				wg.Add(1)
+				go func(msg muxMessage) {
+					defer wg.Done()
+					defer finish(msg.ID)
+					defer callCancel()
+					writeMuxMessage(ctx, conn, s.runMuxCall(context.WithValue(callCtx, pusherKey{}, pusher), msg))
+				}(msg)
+			case "cancel":
+				mutex.Lock()
+				if callCancel, ok := calls[msg.ID]; ok {
+					callCancel()
+				}
+				mutex.Unlock()
+			}
+		}
+	}
+}
+
+// muxCallContext returns the context of one call on the socket, carrying the
+// upgrade request's headers overlaid with the call's own metadata. Calls
+// share the request ID of the socket unless their metadata sets one.
+func (s *GRPCServer) muxCallContext(ctx context.Context, r *http.Request, msg muxMessage) (context.Context, context.CancelFunc) {
+	req := newHTTPRequest(r, RequestIDFromContext(ctx))
+	req.Transport = "websocket"
+	for key, value := range msg.Metadata {
+		req.Header.Set(key, value)
+	}
+	if id := req.Header.Get(RequestIDHeader); ValidRequestID(id) {
+		req.ID = id
+	}
+	ctx = WithRequest(WithMetadata(WithRequestID(ctx, req.ID), NewMetadata(req.Header)), req)
+
+	if msg.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(msg.Timeout)*time.Millisecond)
+}
+
+// runMuxCall executes one call received on the socket and returns its reply
+func (s *GRPCServer) runMuxCall(ctx context.Context, msg muxMessage) muxMessage {
+	failed := func(err error) muxMessage {
+		structured := ToError(err)
+		return muxMessage{
+			ID:        msg.ID,
+			Type:      "error",
+			Error:     structured.Message,
+			Code:      structured.Code,
+			Details:   structured.Details,
+			Retryable: structured.Retryable,
+		}
+	}
+
+	protoArgs, err := argsToAny(msg.Args)
+	if err != nil {
+		return failed(NewError(CodeInvalidArgument, err.Error()))
+	}
+
+	result, err := s.registry.CallFunction(ctx, msg.ServiceName, msg.FunctionName, protoArgs)
+	if err != nil {
+		return failed(err)
+	}
+
+	data := json.RawMessage(result.GetValue())
+	if !json.Valid(data) {
+		return failed(NewError(CodeInternal, "Failed to deserialize result"))
+	}
+	return muxMessage{ID: msg.ID, Type: "result", Result: data}
+}
+
+// writeMuxMessage encodes and writes a single JSON message
+func writeMuxMessage(ctx context.Context, conn *websocket.Conn, msg muxMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.Write(ctx, websocket.MessageText, data)
+}
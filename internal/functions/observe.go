@@ -0,0 +1,143 @@
+package functions
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DurationBuckets are the upper bounds of the call duration histogram of
+// FunctionStats, in seconds, the default buckets of Prometheus
+var DurationBuckets = [...]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// FunctionStats are the call counters of a function
+type FunctionStats struct {
+	Calls         uint64
+	Errors        uint64
+	Panics        uint64
+	TotalDuration time.Duration
+	Durations     []uint64 // calls that took at most each of DurationBuckets, cumulative
+}
+
+// functionCounters accumulates FunctionStats without locking
+type functionCounters struct {
+	calls     atomic.Uint64
+	errors    atomic.Uint64
+	panics    atomic.Uint64
+	duration  atomic.Int64
+	durations [len(DurationBuckets)]atomic.Uint64 // calls per bucket, not cumulative
+}
+
+// SetLogger sets the structured logger used for call logs; by default
+// slog.Default() is used
+func (r *Registry) SetLogger(logger *slog.Logger) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.logger = logger
+}
+
+// Stats returns the call counters of every function that has been called
+func (r *Registry) Stats() map[string]FunctionStats {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stats := make(map[string]FunctionStats, len(r.counters))
+	for key, counters := range r.counters {
+		stats[key] = FunctionStats{
+			Calls:         counters.calls.Load(),
+			Errors:        counters.errors.Load(),
+			Panics:        counters.panics.Load(),
+			TotalDuration: time.Duration(counters.duration.Load()),
+			Durations:     counters.cumulativeDurations(),
+		}
+	}
+	return stats
+}
+
+// log returns the registry's logger
+func (r *Registry) log() *slog.Logger {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if r.logger != nil {
+		return r.logger
+	}
+	return slog.Default()
+}
+
+// logFor returns the registry's logger, adding the request ID and trace ID
+// of ctx to every record
+func (r *Registry) logFor(ctx context.Context) *slog.Logger {
+	logger := r.log()
+	if id := RequestIDFromContext(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		logger = logger.With("trace_id", span.TraceID().String())
+	}
+	return logger
+}
+
+// countersFor returns the counters of a function, creating them on first use
+func (r *Registry) countersFor(key string) *functionCounters {
+	r.mutex.RLock()
+	counters, exists := r.counters[key]
+	r.mutex.RUnlock()
+	if exists {
+		return counters
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if counters, exists = r.counters[key]; !exists {
+		counters = &functionCounters{}
+		r.counters[key] = counters
+	}
+	return counters
+}
+
+// cumulativeDurations returns the calls that took at most each of
+// DurationBuckets
+func (c *functionCounters) cumulativeDurations() []uint64 {
+	durations := make([]uint64, len(DurationBuckets))
+	var total uint64
+	for i := range DurationBuckets {
+		total += c.durations[i].Load()
+		durations[i] = total
+	}
+	return durations
+}
+
+// record counts and logs a finished call
+func (r *Registry) record(ctx context.Context, key string, start time.Time, err error) {
+	elapsed := time.Since(start)
+
+	counters := r.countersFor(key)
+	counters.calls.Add(1)
+	counters.duration.Add(int64(elapsed))
+	for i, bound := range DurationBuckets {
+		if elapsed.Seconds() <= bound {
+			counters.durations[i].Add(1)
+			break
+		}
+	}
+
+	if err != nil {
+		counters.errors.Add(1)
+		r.logFor(ctx).Warn("function call failed", "function", key, "duration", elapsed, "code", ToError(err).Code, "error", err)
+		return
+	}
+	r.logFor(ctx).Debug("function call", "function", key, "duration", elapsed)
+}
+
+// recovered converts a recovered panic into an internal error, logging the
+// panic value and stack trace. The client only sees that the call panicked.
+func (r *Registry) recovered(ctx context.Context, key string, value interface{}) error {
+	r.countersFor(key).panics.Add(1)
+	r.logFor(ctx).Error("function panicked", "function", key, "panic", value, "stack", string(debug.Stack()))
+	return Errorf(CodeInternal, "function %s panicked", key)
+}
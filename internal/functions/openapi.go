@@ -0,0 +1,343 @@
+package functions
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPI returns an OpenAPI 3.1 document describing every registered
+// function as POST /api/functions/{service}/{function}, whose body is the
+// JSON array of arguments
+func (r *Registry) OpenAPI(title, version string) map[string]interface{} {
+	r.mutex.RLock()
+	metas := make([]*FunctionMeta, 0, len(r.functions))
+	for _, meta := range r.functions {
+		if meta.Function.IsValid() {
+			metas = append(metas, meta)
+		}
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].ServiceName+"."+metas[i].Name < metas[j].ServiceName+"."+metas[j].Name
+	})
+
+	schemas := newSchemaBuilder()
+	paths := make(map[string]interface{})
+	for _, meta := range metas {
+		paths["/api/functions/"+meta.ServiceName+"/"+meta.Name] = map[string]interface{}{
+			"post": r.operation(meta, schemas),
+		}
+	}
+
+	schemas.components["Error"] = map[string]interface{}{
+		"type":     "object",
+		"required": []string{"success", "error", "code"},
+		"properties": map[string]interface{}{
+			"success":   map[string]interface{}{"const": false},
+			"error":     map[string]interface{}{"type": "string"},
+			"code":      map[string]interface{}{"type": "string", "enum": errorCodes},
+			"details":   map[string]interface{}{"type": "object"},
+			"retryable": map[string]interface{}{"type": "boolean"},
+		},
+	}
+
+	if version == "" {
+		version = "0.0.0"
+	}
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info":    map[string]interface{}{"title": title, "version": version},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+var errorCodes = []string{
+	CodeUnknown, CodeInvalidArgument, CodeNotFound, CodeAlreadyExists,
+	CodePermissionDenied, CodeUnauthenticated, CodeResourceExhausted,
+	CodeFailedPrecondition, CodeUnavailable, CodeDeadlineExceeded,
+	CodeCanceled, CodeInternal,
+}
+
+// operation describes the call of one function
+func (r *Registry) operation(meta *FunctionMeta, schemas *schemaBuilder) map[string]interface{} {
+	var args []interface{}
+	for i := 0; i < meta.Type.NumIn(); i++ {
+		argType := meta.Type.In(i)
+		if argType.String() == "context.Context" {
+			continue
+		}
+		args = append(args, schemas.schema(argType))
+	}
+
+	body := map[string]interface{}{"type": "array", "minItems": len(args), "maxItems": len(args)}
+	if len(args) > 0 {
+		body["prefixItems"] = args
+	}
+
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"},
+				},
+			},
+		}
+	}
+
+	responses := map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "The function's result",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{
+						"type":     "object",
+						"required": []string{"success"},
+						"properties": map[string]interface{}{
+							"success": map[string]interface{}{"const": true},
+							"result":  schemas.schema(resultType(meta.Type)),
+						},
+					},
+				},
+			},
+		},
+		"400":     errorResponse("Invalid arguments"),
+		"default": errorResponse("The function failed"),
+	}
+
+	op := map[string]interface{}{
+		"operationId": meta.ServiceName + "." + meta.Name,
+		"tags":        []string{meta.ServiceName},
+		"summary":     meta.Name,
+		"requestBody": map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": body},
+			},
+		},
+		"responses": responses,
+	}
+	if meta.Description != "" {
+		op["description"] = meta.Description
+	}
+	if meta.Auth != nil {
+		op["security"] = []interface{}{map[string]interface{}{"bearerAuth": []string{}}}
+		responses["401"] = errorResponse("The caller is not authenticated")
+		responses["403"] = errorResponse("The caller lacks a required role or permission")
+	}
+
+	limits := r.limitsFor(meta.ServiceName+"."+meta.Name, meta)
+	if limits.MaxConcurrent > 0 {
+		responses["429"] = errorResponse("Too many concurrent calls")
+	}
+	if limits.Timeout > 0 {
+		responses["504"] = errorResponse("The call timed out")
+	}
+	return op
+}
+
+// resultType returns the type of a function's result, nil for none
+func resultType(fnType reflect.Type) reflect.Type {
+	if fnType.NumOut() == 0 || fnType.Out(0) == errorType {
+		return nil
+	}
+	return fnType.Out(0)
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	timeType  = reflect.TypeOf(time.Time{})
+)
+
+// schemaBuilder converts Go types to JSON schemas, collecting named structs
+// as reusable components
+type schemaBuilder struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: make(map[string]interface{}),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+var componentName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// schema returns the JSON schema of values of t as encoding/json sees them
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{"type": "null"}
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Ptr:
+		return map[string]interface{}{"anyOf": []interface{}{b.schema(t.Elem()), map[string]interface{}{"type": "null"}}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Chan:
+		// Channel results are delivered as an array, or streamed value by value
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name, seen := b.names[t]
+		if !seen {
+			name = componentName.ReplaceAllString(t.String(), "_")
+			b.names[t] = name
+			b.components[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]interface{}{} // interface{}: any value
+	}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// structSchema describes a struct's exported fields, including the
+// constraints of their validate tags
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		name := fieldName(field)
+		property := b.schema(field.Type)
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			switch rule {
+			case "required":
+				required = append(required, name)
+			case "min", "max", "len":
+				applyBound(property, field.Type, rule, param)
+			case "oneof":
+				property["enum"] = strings.Fields(param)
+			case "email":
+				property["format"] = "email"
+			}
+		}
+		properties[name] = property
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// applyBound adds the schema keyword matching a min, max or len rule
+func applyBound(property map[string]interface{}, t reflect.Type, rule, param string) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var prefix string
+	switch t.Kind() {
+	case reflect.String:
+		prefix = "Length"
+	case reflect.Slice, reflect.Array:
+		prefix = "Items"
+	case reflect.Map:
+		prefix = "Properties"
+	default:
+		switch rule {
+		case "min":
+			property["minimum"] = limit
+		case "max":
+			property["maximum"] = limit
+		case "len":
+			property["const"] = limit
+		}
+		return
+	}
+
+	switch rule {
+	case "min":
+		property["min"+prefix] = int(limit)
+	case "max":
+		property["max"+prefix] = int(limit)
+	case "len":
+		property["min"+prefix] = int(limit)
+		property["max"+prefix] = int(limit)
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document of the registry's functions
+func (s *GRPCServer) OpenAPIHandler(title, version string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.registry.OpenAPI(title, version))
+	}
+}
+
+// DocsHandler serves an interactive page for reading and trying the API
+// described by the OpenAPI document at specURL
+func DocsHandler(specURL string) func(w http.ResponseWriter, r *http.Request) {
+	page := `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>Server Functions API</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+    <div id="docs"></div>
+    <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+    <script>
+        SwaggerUIBundle({ url: ` + strconv.Quote(specURL) + `, dom_id: "#docs" });
+    </script>
+</body>
+</html>`
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}
+}
@@ -0,0 +1,171 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	pb "github.com/Nu11ified/golem/proto/gen/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ProtobufContentType selects the binary payload mode of /api/functions: the
+// body is a FunctionRequest and the response a FunctionResponse, encoded as
+// protobuf. Byte slices and protobuf messages travel as raw protobuf instead
+// of base64 or JSON inside Any.Value.
+const ProtobufContentType = "application/x-protobuf"
+
+// Type URLs of the argument and result encodings
+const (
+	jsonValueURL  = "type.googleapis.com/google.protobuf.Value"
+	bytesValueURL = "type.googleapis.com/google.protobuf.BytesValue"
+)
+
+// maxProtobufBody bounds the size of a binary request body
+const maxProtobufBody = 32 << 20
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// isProtobufRequest reports whether r uses the binary payload mode
+func isProtobufRequest(r *http.Request) bool {
+	mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	return strings.TrimSpace(mediaType) == ProtobufContentType
+}
+
+// acceptsProtobuf reports whether the client wants a binary response: it
+// asked for one, or sent a binary request without asking for JSON
+func acceptsProtobuf(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, ProtobufContentType) {
+		return true
+	}
+	return isProtobufRequest(r) && !strings.Contains(accept, "application/json")
+}
+
+// decodeBinaryArg decodes an argument sent as raw protobuf. ok is false for
+// arguments to decode as JSON.
+func decodeBinaryArg(arg *anypb.Any, targetType reflect.Type) (value reflect.Value, ok bool, err error) {
+	switch {
+	case arg.GetTypeUrl() == "" || arg.GetTypeUrl() == jsonValueURL:
+		return reflect.Value{}, false, nil
+	case arg.GetTypeUrl() == bytesValueURL:
+		var wrapped wrapperspb.BytesValue
+		if err := arg.UnmarshalTo(&wrapped); err != nil {
+			return reflect.Value{}, true, fmt.Errorf("invalid bytes for %s: %v", targetType, err)
+		}
+		switch {
+		case targetType.Kind() == reflect.Slice && targetType.Elem().Kind() == reflect.Uint8:
+			return reflect.ValueOf(wrapped.Value).Convert(targetType), true, nil
+		case targetType.Kind() == reflect.String:
+			return reflect.ValueOf(string(wrapped.Value)).Convert(targetType), true, nil
+		}
+		return reflect.Value{}, true, fmt.Errorf("expected %s, got bytes", targetType)
+	case targetType.Implements(protoMessageType) && targetType.Kind() == reflect.Ptr:
+		message := reflect.New(targetType.Elem())
+		if err := arg.UnmarshalTo(message.Interface().(proto.Message)); err != nil {
+			return reflect.Value{}, true, fmt.Errorf("invalid %s: %v", targetType, err)
+		}
+		return message, true, nil
+	default:
+		return reflect.Value{}, false, nil // JSON under another type URL
+	}
+}
+
+// binaryResult re-encodes a JSON result of a function returning []byte or a
+// protobuf message as raw protobuf. Other results are returned unchanged.
+func binaryResult(meta *FunctionMeta, result *anypb.Any) (*anypb.Any, error) {
+	resultType := resultType(meta.Type)
+	switch {
+	case resultType == nil || result.GetTypeUrl() != jsonValueURL:
+		return result, nil
+	case resultType.Kind() == reflect.Slice && resultType.Elem().Kind() == reflect.Uint8:
+		var data []byte
+		if err := json.Unmarshal(result.GetValue(), &data); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		return anypb.New(wrapperspb.Bytes(data))
+	case resultType.Implements(protoMessageType) && resultType.Kind() == reflect.Ptr:
+		if string(result.GetValue()) == "null" {
+			return result, nil
+		}
+		message := reflect.New(resultType.Elem()).Interface().(proto.Message)
+		if err := json.Unmarshal(result.GetValue(), message); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		return anypb.New(message)
+	default:
+		return result, nil
+	}
+}
+
+// serveProtobuf answers an /api/functions call in the binary payload mode
+func (s *GRPCServer) serveProtobuf(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxProtobufBody+1))
+	if err != nil || len(body) > maxProtobufBody {
+		s.writeCallResponse(w, r, nil, NewError(CodeInvalidArgument, "Invalid request body"))
+		return
+	}
+
+	var req pb.FunctionRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		s.writeCallResponse(w, r, nil, NewError(CodeInvalidArgument, "Invalid protobuf"))
+		return
+	}
+	if serviceName, functionName, ok := callPath(r.URL.Path); ok {
+		req.ServiceName, req.FunctionName = serviceName, functionName
+	}
+	for key, value := range req.Metadata {
+		r.Header.Set(key, value)
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	result, err := s.registry.CallFunction(ctx, req.ServiceName, req.FunctionName, req.Args)
+	if err == nil && acceptsProtobuf(r) {
+		if meta, ok := s.registry.GetFunction(req.ServiceName, req.FunctionName); ok {
+			result, err = binaryResult(meta, result)
+		}
+	}
+	s.writeCallResponse(w, r, result, err)
+}
+
+// writeCallResponse writes the outcome of a call as a FunctionResponse or,
+// for clients that did not accept protobuf, as the JSON envelope
+func (s *GRPCServer) writeCallResponse(w http.ResponseWriter, r *http.Request, result *anypb.Any, err error) {
+	if !acceptsProtobuf(r) {
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var resultData interface{}
+		if err := json.Unmarshal(result.GetValue(), &resultData); err != nil {
+			writeError(w, NewError(CodeInternal, "Failed to deserialize result"))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": resultData})
+		return
+	}
+
+	resp := &pb.FunctionResponse{Success: true, Result: result, Metadata: make(map[string]string)}
+	status := http.StatusOK
+	if err != nil {
+		structured := ToError(err)
+		resp = &pb.FunctionResponse{Error: structured.Message, Metadata: structured.metadata()}
+		status = structured.HTTPStatus()
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		s.registry.logFor(r.Context()).Error("failed to marshal response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ProtobufContentType)
+	w.WriteHeader(status)
+	w.Write(data)
+}
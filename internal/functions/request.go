@@ -0,0 +1,129 @@
+package functions
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/peer"
+)
+
+// Request describes the transport request a function call arrived with, for
+// audit logging and geo or locale decisions
+type Request struct {
+	ID         string // request ID, see RequestIDHeader
+	Transport  string // "http" or "grpc"
+	Method     string // HTTP method, empty for gRPC
+	Path       string // URL path or full gRPC method
+	Host       string
+	RemoteAddr string // address of the connected peer, host:port
+	UserAgent  string
+	Header     http.Header
+}
+
+type requestKey struct{}
+
+// WithRequest returns a context carrying the call's transport request
+func WithRequest(ctx context.Context, req *Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+// RequestFromContext returns the transport request of the current call. It
+// is absent for calls made directly on the registry.
+func RequestFromContext(ctx context.Context) (*Request, bool) {
+	req, ok := ctx.Value(requestKey{}).(*Request)
+	return req, ok
+}
+
+// newHTTPRequest describes an HTTP request with the request ID id
+func newHTTPRequest(r *http.Request, id string) *Request {
+	return &Request{
+		ID:         id,
+		Transport:  "http",
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+		Header:     r.Header.Clone(),
+	}
+}
+
+// grpcContext adds the metadata and request of an incoming gRPC call to ctx
+func grpcContext(ctx context.Context, method string) context.Context {
+	md := metadataFromGRPC(ctx)
+	id := grpcRequestID(ctx, md)
+	req := &Request{
+		ID:        id,
+		Transport: "grpc",
+		Path:      method,
+		Host:      md.Get(":authority"),
+		UserAgent: md.Get("User-Agent"),
+		Header:    md.Header(),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteAddr = p.Addr.String()
+	}
+	return WithRequest(WithMetadata(WithRequestID(ctx, id), md), req)
+}
+
+// RemoteIP returns the IP address of the connected peer
+func (r *Request) RemoteIP() string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ClientIP returns the address of the client as reported by a reverse proxy
+// in X-Forwarded-For or X-Real-IP, falling back to RemoteIP. Those headers
+// can be forged by clients, so only rely on them behind a trusted proxy.
+func (r *Request) ClientIP() string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		if ip := strings.TrimSpace(first); ip != "" {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	return r.RemoteIP()
+}
+
+// Languages returns the languages of the Accept-Language header, most
+// preferred first
+func (r *Request) Languages() []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			languages = append(languages, language{tag, quality})
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].quality > languages[j].quality })
+
+	tags := make([]string, len(languages))
+	for i, l := range languages {
+		tags[i] = l.tag
+	}
+	return tags
+}
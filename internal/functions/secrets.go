@@ -0,0 +1,36 @@
+package functions
+
+import (
+	"os"
+	"sync"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+var (
+	secretsMutex  sync.RWMutex
+	secretsConfig *config.Config
+)
+
+// SetSecrets makes Secret read the secrets of cfg, the configuration the
+// server binary was started with
+func SetSecrets(cfg *config.Config) {
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+	secretsConfig = cfg
+}
+
+// Secret returns the secret name: the environment variable of that name,
+// or else its value in the secrets files of the configuration set with
+// SetSecrets. It returns "" for secrets that are not set.
+func Secret(name string) string {
+	secretsMutex.RLock()
+	cfg := secretsConfig
+	secretsMutex.RUnlock()
+
+	if cfg == nil {
+		return os.Getenv(name)
+	}
+	value, _ := cfg.Secret(name)
+	return value
+}
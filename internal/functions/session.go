@@ -0,0 +1,381 @@
+package functions
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSessionCommitted is returned when a session changes after the response
+// carrying its cookie began, e.g. in calls over the WebSocket
+var ErrSessionCommitted = errors.New("the session can no longer change, its response was already sent")
+
+// ErrNoSession is returned by SetSession in calls without a session, such
+// as native gRPC calls
+var ErrNoSession = errors.New("the call has no session, sessions are only kept for HTTP calls")
+
+// Session holds the values the server keeps for one browser across calls,
+// e.g. the signed in user. Its ID travels in a signed cookie, the values
+// stay in a SessionStore. Values are stored as JSON.
+type Session struct {
+	manager *SessionManager
+	request *http.Request
+	mutex   sync.Mutex
+
+	loaded    bool
+	err       error // of loading the session
+	id        string
+	staleID   string // ID dropped by Renew or Destroy, deleted on commit
+	values    map[string]json.RawMessage
+	changed   bool
+	destroyed bool
+	committed bool
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context carrying the session of the call
+func WithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the session of the current call. It is absent
+// for calls that did not arrive over HTTP.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*Session)
+	return session, ok
+}
+
+// load reads the session of the request's cookie on first use, starting a
+// new one without a valid cookie. The caller holds the mutex.
+func (s *Session) load() error {
+	if s.loaded {
+		return s.err
+	}
+	s.loaded = true
+	s.values = make(map[string]json.RawMessage)
+
+	cookie, err := s.request.Cookie(s.manager.cookieName)
+	if err != nil {
+		return nil
+	}
+	id, ok := s.manager.verify(cookie.Value)
+	if !ok {
+		return nil
+	}
+	data, found, err := s.manager.store.Load(s.request.Context(), id)
+	if err != nil {
+		s.err = fmt.Errorf("failed to load session: %w", err)
+		return s.err
+	}
+	if !found {
+		return nil
+	}
+	if err := json.Unmarshal(data, &s.values); err != nil {
+		s.values = make(map[string]json.RawMessage)
+		return nil
+	}
+	s.id = id
+	return nil
+}
+
+// change applies update to the values of a session that may still change
+func (s *Session) change(update func()) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.committed {
+		return ErrSessionCommitted
+	}
+	if err := s.load(); err != nil {
+		return err
+	}
+	update()
+	s.changed = true
+	s.destroyed = false
+	return nil
+}
+
+// ID returns the ID of the session, empty until it is first stored
+func (s *Session) ID() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.load()
+	return s.id
+}
+
+// Get decodes the value stored under key into dst, reporting whether there
+// is one
+func (s *Session) Get(key string, dst interface{}) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.load(); err != nil {
+		return false, err
+	}
+	value, ok := s.values[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(value, dst); err != nil {
+		return false, fmt.Errorf("failed to decode session value %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// Set stores value under key, encoded as JSON
+func (s *Session) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode session value %q: %w", key, err)
+	}
+	return s.change(func() { s.values[key] = data })
+}
+
+// Delete removes the value stored under key
+func (s *Session) Delete(key string) error {
+	return s.change(func() { delete(s.values, key) })
+}
+
+// Renew moves the values to a new session ID. Call it when the user signs
+// in, so an ID planted in the browser before cannot take over the session.
+func (s *Session) Renew() error {
+	return s.change(s.dropID)
+}
+
+// Destroy removes the session and its cookie, e.g. when the user signs out
+func (s *Session) Destroy() error {
+	if err := s.change(func() {
+		s.values = make(map[string]json.RawMessage)
+		s.dropID()
+	}); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	s.destroyed = true
+	s.mutex.Unlock()
+	return nil
+}
+
+// dropID lets the session get a new ID as it is committed, deleting the
+// current one from the store
+func (s *Session) dropID() {
+	if s.id != "" && s.staleID == "" {
+		s.staleID = s.id
+	}
+	s.id = ""
+}
+
+// commit stores a changed session and sets its cookie on w, once, before
+// the response headers are written
+func (s *Session) commit(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.committed {
+		return
+	}
+	s.committed = true
+	if !s.changed {
+		return
+	}
+
+	m := s.manager
+	ctx := s.request.Context()
+	if s.staleID != "" {
+		if err := m.store.Delete(ctx, s.staleID); err != nil {
+			m.logger.Error("failed to delete session", "error", err)
+		}
+	}
+	if s.destroyed {
+		http.SetCookie(w, m.cookie(s.request, "", -1))
+		return
+	}
+
+	if s.id == "" {
+		s.id = newSessionID()
+	}
+	data, err := json.Marshal(s.values)
+	if err == nil {
+		err = m.store.Save(ctx, s.id, data, m.maxAge)
+	}
+	if err != nil {
+		m.logger.Error("failed to save session", "error", err)
+		return
+	}
+	http.SetCookie(w, m.cookie(s.request, m.sign(s.id), int(m.maxAge/time.Second)))
+}
+
+// SessionOptions configures the cookie of a SessionManager
+type SessionOptions struct {
+	CookieName string        // default "golem_session"
+	Secret     []byte        // signs the cookie, at least 32 bytes
+	MaxAge     time.Duration // sessions expire this long after they last changed, default 24h
+	Secure     *bool         // send the cookie over HTTPS only, default whether the request was
+	SameSite   http.SameSite // default http.SameSiteLaxMode
+	Logger     *slog.Logger  // logs failing stores, default slog.Default()
+}
+
+// Session defaults
+const (
+	DefaultSessionCookie = "golem_session"
+	DefaultSessionMaxAge = 24 * time.Hour
+)
+
+// SessionManager gives HTTP requests their session, kept in a store under
+// the ID of a signed cookie
+type SessionManager struct {
+	store      SessionStore
+	secret     []byte
+	cookieName string
+	maxAge     time.Duration
+	secure     *bool
+	sameSite   http.SameSite
+	logger     *slog.Logger
+}
+
+// NewSessionManager returns a manager keeping sessions in store
+func NewSessionManager(store SessionStore, options SessionOptions) (*SessionManager, error) {
+	if len(options.Secret) < 32 {
+		return nil, fmt.Errorf("the session secret must be at least 32 bytes, got %d", len(options.Secret))
+	}
+	m := &SessionManager{
+		store:      store,
+		secret:     options.Secret,
+		cookieName: options.CookieName,
+		maxAge:     options.MaxAge,
+		secure:     options.Secure,
+		sameSite:   options.SameSite,
+		logger:     options.Logger,
+	}
+	if m.cookieName == "" {
+		m.cookieName = DefaultSessionCookie
+	}
+	if m.maxAge <= 0 {
+		m.maxAge = DefaultSessionMaxAge
+	}
+	if m.sameSite == 0 {
+		m.sameSite = http.SameSiteLaxMode
+	}
+	if m.logger == nil {
+		m.logger = slog.Default()
+	}
+	return m, nil
+}
+
+// Middleware gives the /api/ requests to next a session, which server
+// functions find in their context. A changed session is stored, and its
+// cookie set, as the response headers are written.
+func (m *SessionManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session := &Session{manager: m, request: r}
+		sw := &sessionWriter{ResponseWriter: w, session: session}
+		next.ServeHTTP(sw, r.WithContext(WithSession(r.Context(), session)))
+		session.commit(w)
+	})
+}
+
+// cookie returns the session cookie carrying value for r
+func (m *SessionManager) cookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	secure := r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+	if m.secure != nil {
+		secure = *m.secure
+	}
+	return &http.Cookie{
+		Name:     m.cookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: m.sameSite,
+	}
+}
+
+// sign returns the cookie value of the session id
+func (m *SessionManager) sign(id string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify returns the session ID of a cookie value signed by this manager
+func (m *SessionManager) verify(value string) (string, bool) {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok || !validSessionID(id) {
+		return "", false
+	}
+	return id, hmac.Equal([]byte(m.sign(id)), []byte(value))
+}
+
+// newSessionID returns 256 random bits, URL-safe
+func newSessionID() string {
+	var b [32]byte
+	rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// validSessionID reports whether id has the form of newSessionID, so stores
+// may use it as a file name or key
+func validSessionID(id string) bool {
+	if len(id) != 43 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionWriter commits the session of a response before its headers
+type sessionWriter struct {
+	http.ResponseWriter
+	session *Session
+}
+
+func (sw *sessionWriter) WriteHeader(status int) {
+	sw.session.commit(sw.ResponseWriter)
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *sessionWriter) Write(data []byte) (int, error) {
+	sw.session.commit(sw.ResponseWriter)
+	return sw.ResponseWriter.Write(data)
+}
+
+// Flush commits the session, then flushes the response, e.g. of a stream
+func (sw *sessionWriter) Flush() {
+	sw.session.commit(sw.ResponseWriter)
+	http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+// Hijack commits the session before the connection is taken over, e.g. by
+// a WebSocket, whose upgrade response still carries the cookie
+func (sw *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	sw.session.commit(sw.ResponseWriter)
+	return http.NewResponseController(sw.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (sw *sessionWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,260 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionStore keeps the data of sessions by ID. Data expires ttl after it
+// was last saved.
+type SessionStore interface {
+	Load(ctx context.Context, id string) (data []byte, found bool, err error)
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// SetSessionStore sets where the sessions of the registry's functions are
+// kept, e.g. a RedisSessionStore, over the store of the session config
+func (r *Registry) SetSessionStore(store SessionStore) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sessionStore = store
+}
+
+// SessionStore returns the store set with SetSessionStore, or nil
+func (r *Registry) SessionStore() SessionStore {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.sessionStore
+}
+
+// SetGlobalSessionStore sets the session store of the global registry
+func SetGlobalSessionStore(store SessionStore) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	globalRegistry.SetSessionStore(store)
+}
+
+// MemorySessionStore keeps sessions in memory, so they are lost when the
+// server restarts and not shared between replicas
+type MemorySessionStore struct {
+	mutex     sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemorySessionStore returns an empty memory store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+// Load returns the data of session id unless it expired
+func (s *MemorySessionStore) Load(ctx context.Context, id string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || time.Now().After(session.expires) {
+		return nil, false, nil
+	}
+	return session.data, true, nil
+}
+
+// Save stores the data of session id, removing expired sessions now and
+// then
+func (s *MemorySessionStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for key, session := range s.sessions {
+			if now.After(session.expires) {
+				delete(s.sessions, key)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.sessions[id] = memorySession{data: data, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete removes session id
+func (s *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// FileSessionStore keeps every session in a file of a directory, so they
+// survive restarts of a single server
+type FileSessionStore struct {
+	dir       string
+	mutex     sync.Mutex // guards lastSweep
+	lastSweep time.Time
+}
+
+// fileSession is the content of a session file
+type fileSession struct {
+	Expires time.Time       `json:"expires"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// NewFileSessionStore returns a store keeping sessions in dir, which is
+// created when missing
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// path returns the file of session id
+func (s *FileSessionStore) path(id string) (string, error) {
+	if !validSessionID(id) {
+		return "", fmt.Errorf("invalid session ID")
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// Load returns the data of session id unless it expired
+func (s *FileSessionStore) Load(ctx context.Context, id string) ([]byte, bool, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, false, err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var session fileSession
+	if err := json.Unmarshal(content, &session); err != nil || time.Now().After(session.Expires) {
+		os.Remove(path)
+		return nil, false, nil
+	}
+	return session.Data, true, nil
+}
+
+// Save writes the data of session id, replacing its file at once, and
+// removes expired sessions now and then
+func (s *FileSessionStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	s.sweep()
+
+	content, err := json.Marshal(fileSession{Expires: time.Now().Add(ttl), Data: data})
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the file of session id
+func (s *FileSessionStore) Delete(ctx context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// sweep removes the files of expired sessions, at most once an hour
+func (s *FileSessionStore) sweep() {
+	s.mutex.Lock()
+	if time.Since(s.lastSweep) < time.Hour {
+		s.mutex.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.mutex.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !validSessionID(id) {
+			continue
+		}
+		s.Load(context.Background(), id)
+	}
+}
+
+// RedisClient is the part of a Redis client a RedisSessionStore needs.
+// Adapt the client of your choice, e.g. go-redis, to it; Get reports a
+// missing key with found false rather than an error.
+type RedisClient interface {
+	Get(ctx context.Context, key string) (value string, found bool, err error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// RedisSessionStore keeps sessions in Redis, shared by every replica of the
+// server, under keys of Prefix
+type RedisSessionStore struct {
+	Client RedisClient
+	Prefix string // of the keys, "golem:session:" with NewRedisSessionStore
+}
+
+// NewRedisSessionStore returns a store keeping sessions with client
+func NewRedisSessionStore(client RedisClient) *RedisSessionStore {
+	return &RedisSessionStore{Client: client, Prefix: "golem:session:"}
+}
+
+// Load returns the data of session id
+func (s *RedisSessionStore) Load(ctx context.Context, id string) ([]byte, bool, error) {
+	value, found, err := s.Client.Get(ctx, s.Prefix+id)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return []byte(value), true, nil
+}
+
+// Save sets the data of session id, which Redis expires after ttl
+func (s *RedisSessionStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return s.Client.Set(ctx, s.Prefix+id, string(data), ttl)
+}
+
+// Delete removes session id
+func (s *RedisSessionStore) Delete(ctx context.Context, id string) error {
+	return s.Client.Del(ctx, s.Prefix+id)
+}
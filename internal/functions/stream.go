@@ -0,0 +1,144 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"google.golang.org/protobuf/types/known/anypb"
+
+	pb "github.com/Nu11ified/golem/proto/gen/proto"
+)
+
+// ErrNotStreaming is returned by Emit when the caller did not request a stream
+var ErrNotStreaming = errors.New("function was not called as a stream")
+
+// emitterKey is the context key holding the stream emitter
+type emitterKey struct{}
+
+// emitter delivers a partial result to the streaming caller
+type emitter func(value interface{}) error
+
+// Emit pushes a partial result (progress, a chunk of rows, ...) to the caller
+// of a streaming call. The function's return value is still delivered as the
+// final message. Functions called without streaming get ErrNotStreaming and
+// can simply ignore it.
+func Emit(ctx context.Context, value interface{}) error {
+	emit, ok := ctx.Value(emitterKey{}).(emitter)
+	if !ok {
+		return ErrNotStreaming
+	}
+	return emit(value)
+}
+
+// CallFunctionStream calls a function while forwarding every value it emits
+// to send. The function's own return value is returned as usual.
+func (r *Registry) CallFunctionStream(ctx context.Context, serviceName, functionName string, args []*anypb.Any, send func(*anypb.Any) error) (*anypb.Any, error) {
+	var mutex sync.Mutex
+	emit := emitter(func(value interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		anyValue, err := r.valueToAny(value)
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		return send(anyValue)
+	})
+
+	return r.CallFunction(context.WithValue(ctx, emitterKey{}, emit), serviceName, functionName, args)
+}
+
+// StreamHandler serves POST /api/functions/stream. Partial results are sent
+// as Server-Sent Events ("message"), followed by a "result" or "error" event.
+func (s *GRPCServer) StreamHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+			return
+		}
+
+		reqData, protoArgs, err := decodeHTTPCall(r)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Streaming not supported"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		writeEvent := func(event string, data []byte) error {
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+				return err
+			}
+			flusher.Flush()
+			return nil
+		}
+
+		ctx, cancel := requestContext(r)
+		defer cancel()
+
+		result, err := s.registry.CallFunctionStream(ctx, reqData.ServiceName, reqData.FunctionName, protoArgs,
+			func(partial *anypb.Any) error {
+				return writeEvent("message", partial.GetValue())
+			})
+		if err != nil {
+			errData, _ := json.Marshal(ToError(err).envelope())
+			writeEvent("error", errData)
+			return
+		}
+
+		writeEvent("result", result.GetValue())
+	}
+}
+
+// CallStream implements the streaming Call RPC method. Values emitted by the
+// function are sent with metadata partial=true, the return value last.
+func (s *GRPCServer) CallStream(req *pb.FunctionRequest, stream pb.FunctionService_CallStreamServer) error {
+	ctx := grpcContext(stream.Context(), pb.FunctionService_CallStream_FullMethodName)
+
+	result, err := s.registry.CallFunctionStream(ctx, req.ServiceName, req.FunctionName, req.Args,
+		func(partial *anypb.Any) error {
+			return stream.Send(&pb.FunctionResponse{
+				Success:  true,
+				Result:   partial,
+				Metadata: map[string]string{"partial": "true"},
+			})
+		})
+	if err != nil {
+		structured := ToError(err)
+		return stream.Send(&pb.FunctionResponse{
+			Success:  false,
+			Error:    structured.Message,
+			Metadata: structured.metadata(),
+		})
+	}
+
+	return stream.Send(&pb.FunctionResponse{
+		Success:  true,
+		Result:   result,
+		Metadata: make(map[string]string),
+	})
+}
@@ -0,0 +1,110 @@
+package functions
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TracerName identifies the OpenTelemetry spans of golem
+const TracerName = "github.com/Nu11ified/golem"
+
+// tracer returns the tracer of the global OpenTelemetry provider, which
+// records nothing until the server configures tracing
+func tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// startCallSpan starts the span of a call of the function key
+func startCallSpan(ctx context.Context, key string, info *CallInfo) (context.Context, trace.Span) {
+	return tracer().Start(ctx, key, trace.WithAttributes(
+		attribute.String("golem.function", key),
+		attribute.String("golem.service", info.ServiceName),
+		attribute.Bool("golem.streaming", info.Streaming),
+	))
+}
+
+// endCallSpan ends the span of a call that returned err
+func endCallSpan(span trace.Span, err error) {
+	if err != nil {
+		structured := ToError(err)
+		span.SetAttributes(attribute.String("golem.error_code", structured.Code))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, structured.Message)
+	}
+	span.End()
+}
+
+// startGRPCSpan starts the server span of a gRPC call, continuing the trace
+// of the W3C trace context in its metadata
+func startGRPCSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return tracer().Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", method),
+		))
+}
+
+// endGRPCSpan ends the server span of a gRPC call that returned err
+func endGRPCSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if err != nil {
+		span.SetStatus(codes.Error, status.Convert(err).Message())
+	}
+	span.End()
+}
+
+// tracingStreamInterceptor traces every streaming gRPC call in a server
+// span, as loggingInterceptor does for unary ones
+func tracingStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startGRPCSpan(stream.Context(), info.FullMethod)
+	err := handler(srv, &tracedStream{ServerStream: stream, ctx: ctx})
+	endGRPCSpan(span, err)
+	return err
+}
+
+// tracedStream is a server stream whose context carries its span
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier reads and writes the trace context of gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
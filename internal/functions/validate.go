@@ -0,0 +1,172 @@
+package functions
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Validator is implemented by argument types with custom validation. It is
+// called after the struct tags have been checked.
+type Validator interface {
+	Validate() error
+}
+
+// validateArgs checks every argument against its `validate` struct tags,
+// e.g. `validate:"required,min=1"`, before the function is invoked. Failures
+// are reported as one CodeInvalidArgument error listing each field.
+func validateArgs(info *CallInfo, args []reflect.Value) error {
+	problems := make(map[string]interface{})
+	for i, arg := range args {
+		if arg.Type().String() == "context.Context" {
+			continue
+		}
+		validateValue(fmt.Sprintf("arg%d", i), arg, problems)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	fields := make([]string, 0, len(problems))
+	for field := range problems {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	err := Errorf(CodeInvalidArgument, "invalid arguments for %s.%s: %s", info.ServiceName, info.FunctionName, strings.Join(fields, ", "))
+	return err.WithDetail("fields", problems)
+}
+
+// validateValue validates a value, recursing into structs, pointers and slices
+func validateValue(path string, value reflect.Value, problems map[string]interface{}) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			fieldPath := path + "." + fieldName(field)
+			fieldValue := value.Field(i)
+			if rules := field.Tag.Get("validate"); rules != "" && rules != "-" {
+				if problem := checkRules(fieldValue, rules); problem != "" {
+					problems[fieldPath] = problem
+					continue
+				}
+			}
+			validateValue(fieldPath, fieldValue, problems)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateValue(fmt.Sprintf("%s[%d]", path, i), value.Index(i), problems)
+		}
+	}
+
+	if value.CanAddr() {
+		value = value.Addr()
+	}
+	if validator, ok := value.Interface().(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			problems[path] = err.Error()
+		}
+	}
+}
+
+// checkRules returns a description of the first rule value violates
+func checkRules(value reflect.Value, rules string) string {
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "required":
+			if value.IsZero() {
+				return "is required"
+			}
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return fmt.Sprintf("has invalid rule %q", rule)
+			}
+			if problem := checkBound(value, name, limit); problem != "" {
+				return problem
+			}
+		case "oneof":
+			if !value.IsZero() && !slices.Contains(strings.Fields(param), fmt.Sprint(indirect(value).Interface())) {
+				return fmt.Sprintf("must be one of %s", param)
+			}
+		case "email":
+			if s := indirect(value); s.Kind() == reflect.String && s.String() != "" {
+				if _, err := mail.ParseAddress(s.String()); err != nil {
+					return "must be a valid email address"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// checkBound compares numbers by value and strings, slices and maps by length
+func checkBound(value reflect.Value, rule string, limit float64) string {
+	value = indirect(value)
+
+	var measure float64
+	unit := ""
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		measure = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		measure = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		measure = value.Float()
+	case reflect.String:
+		measure, unit = float64(len([]rune(value.String()))), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		measure, unit = float64(value.Len()), " items"
+	default:
+		return ""
+	}
+
+	limitText := strconv.FormatFloat(limit, 'f', -1, 64)
+	switch {
+	case rule == "min" && measure < limit:
+		return fmt.Sprintf("must be at least %s%s", limitText, unit)
+	case rule == "max" && measure > limit:
+		return fmt.Sprintf("must be at most %s%s", limitText, unit)
+	case rule == "len" && measure != limit:
+		return fmt.Sprintf("must be exactly %s%s", limitText, unit)
+	}
+	return ""
+}
+
+// indirect dereferences pointers, returning the zero value for nil
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Zero(value.Type().Elem())
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// fieldName returns the JSON name of a struct field
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
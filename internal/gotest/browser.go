@@ -0,0 +1,198 @@
+package gotest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BrowserEnv names the browser binary the browser runner starts
+const BrowserEnv = "GOLEM_BROWSER"
+
+// browserNames are the browsers looked for on the PATH, in order
+var browserNames = []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome", "msedge"}
+
+// browserPaths are where browsers install outside the PATH
+var browserPaths = map[string][]string{
+	"darwin": {
+		"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		"/Applications/Chromium.app/Contents/MacOS/Chromium",
+		"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+	},
+	"windows": {
+		`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files (x86)\Microsoft\Edge\Application\msedge.exe`,
+	},
+}
+
+// findBrowser returns the path of the browser named by GOLEM_BROWSER or of
+// the first Chrome, Chromium or Edge installed
+func findBrowser() (string, error) {
+	if browser := os.Getenv(BrowserEnv); browser != "" {
+		path, err := exec.LookPath(browser)
+		if err != nil {
+			return "", fmt.Errorf("%s=%s: %v", BrowserEnv, browser, err)
+		}
+		return path, nil
+	}
+	for _, name := range browserNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	for _, path := range browserPaths[runtime.GOOS] {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no Chrome, Chromium or Edge found, install one or set %s to its path", BrowserEnv)
+}
+
+// browserPage runs the test binary with the argv and env of /run and sends
+// its output and exit code back, in order
+const browserPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><script src="/wasm_exec.js"></script></head>
+<body>
+<script>
+let queue = Promise.resolve();
+const send = (path, body) => {
+	queue = queue.then(() => fetch(path, {method: "POST", body}).catch(() => {}));
+};
+const fail = (err) => send("/exit?code=1", String(err && err.stack || err) + "\n");
+
+globalThis.fs.writeSync = (fd, buf) => {
+	send("/output?fd=" + fd, buf.slice());
+	return buf.length;
+};
+addEventListener("error", (event) => fail(event.error || event.message));
+addEventListener("unhandledrejection", (event) => fail(event.reason));
+
+(async () => {
+	const run = await (await fetch("/run")).json();
+	const go = new Go();
+	go.argv = run.argv;
+	go.env = run.env;
+	go.exit = (code) => send("/exit?code=" + code, "");
+	const result = await WebAssembly.instantiateStreaming(fetch("/test.wasm"), go.importObject);
+	await go.run(result.instance);
+})().catch(fail);
+</script>
+</body>
+</html>
+`
+
+// execBrowser runs the test binary in a headless browser. The page it
+// loads from a local server streams the output of the tests to stdout and
+// stderr. The tests have no file system in the browser, so tests reading
+// testdata should run in Node.
+func execBrowser(args []string) (int, error) {
+	browser, err := findBrowser()
+	if err != nil {
+		return 1, err
+	}
+	dir, err := wasmDir()
+	if err != nil {
+		return 1, err
+	}
+
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+	run, err := json.Marshal(map[string]any{"argv": args, "env": env})
+	if err != nil {
+		return 1, err
+	}
+
+	exit := make(chan int, 1)
+	var once sync.Once
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, browserPage)
+	})
+	mux.HandleFunc("/wasm_exec.js", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(dir, "wasm_exec.js"))
+	})
+	mux.HandleFunc("/test.wasm", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/wasm")
+		http.ServeFile(w, r, args[0])
+	})
+	mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(run)
+	})
+	mux.HandleFunc("/output", func(w http.ResponseWriter, r *http.Request) {
+		out := os.Stdout
+		if r.URL.Query().Get("fd") == "2" {
+			out = os.Stderr
+		}
+		io.Copy(out, r.Body)
+	})
+	mux.HandleFunc("/exit", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(os.Stderr, r.Body)
+		code, err := strconv.Atoi(r.URL.Query().Get("code"))
+		if err != nil {
+			code = 1
+		}
+		once.Do(func() { exit <- code })
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 1, err
+	}
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	profile, err := os.MkdirTemp("", "golem-test-browser-")
+	if err != nil {
+		return 1, err
+	}
+	defer os.RemoveAll(profile)
+
+	browserArgs := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--user-data-dir=" + profile,
+	}
+	// Chrome refuses to run as root with its sandbox, as in containers
+	if os.Geteuid() == 0 {
+		browserArgs = append(browserArgs, "--no-sandbox")
+	}
+	cmd := exec.Command(browser, append(browserArgs, "http://"+listener.Addr().String()+"/")...)
+	if err := cmd.Start(); err != nil {
+		return 1, fmt.Errorf("starting %s: %v", browser, err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case code := <-exit:
+		cmd.Process.Kill()
+		<-exited
+		return code, nil
+	case err := <-exited:
+		if err == nil {
+			err = errors.New("exited")
+		}
+		return 1, fmt.Errorf("%s stopped before the tests finished: %v", browser, err)
+	}
+}
@@ -0,0 +1,272 @@
+// Package gotest runs the tests of a project: those of the server and the
+// other packages with go test, and those that only build for js/wasm, such
+// as tests of dom and state code, compiled to WebAssembly and run by Node
+// or a headless browser.
+package gotest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Runners of the WebAssembly tests
+const (
+	RunnerNode    = "node"
+	RunnerBrowser = "browser"
+)
+
+// Options selects the tests Run runs and how
+type Options struct {
+	Args   []string // packages, then go test flags, e.g. ["./dom", "-run", "TestRender"]
+	Runner string   // runner of the WebAssembly tests, RunnerNode or RunnerBrowser; "" picks Node when installed
+	Native bool     // run the tests built for this machine
+	Wasm   bool     // run the tests built for js/wasm
+	Exec   string   // command go test runs the WebAssembly test binaries with, e.g. "golem test --exec=node"
+}
+
+// Run runs the tests of opts, printing their output as go test does, and
+// reports which of the runs failed
+func Run(opts Options) error {
+	packages, flags := SplitArgs(opts.Args)
+	var failed []string
+
+	if opts.Native {
+		fmt.Println("🧪 Running Go tests...")
+		if err := goTest(nil, append(flags, packages...)); err != nil {
+			failed = append(failed, "Go")
+		}
+	}
+
+	if opts.Wasm {
+		native, err := listTests(nil, flags, packages)
+		if err != nil {
+			return err
+		}
+		wasm, err := listTests(wasmEnv, flags, packages)
+		if err != nil {
+			return err
+		}
+		wasmPackages := WasmOnly(native, wasm)
+		switch {
+		case len(wasmPackages) == 0 && !opts.Native:
+			fmt.Println("No tests built only for js/wasm")
+		case len(wasmPackages) > 0:
+			runner, err := PickRunner(opts.Runner)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("🌐 Running WebAssembly tests in %s...\n", runner)
+			args := append([]string{"-exec", opts.Exec + "=" + runner}, flags...)
+			if err := goTest(wasmEnv, append(args, wasmPackages...)); err != nil {
+				failed = append(failed, "WebAssembly")
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%s tests failed", strings.Join(failed, " and "))
+	}
+	return nil
+}
+
+// wasmEnv builds for js/wasm
+var wasmEnv = []string{"GOOS=js", "GOARCH=wasm"}
+
+// goTest runs go test with args and env added to the environment
+func goTest(env, args []string) error {
+	cmd := exec.Command("go", append([]string{"test"}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// valueFlags are the go test flags followed by a separate value
+var valueFlags = map[string]bool{
+	"asmflags": true, "bench": true, "benchtime": true, "blockprofile": true, "blockprofilerate": true,
+	"count": true, "covermode": true, "coverpkg": true, "coverprofile": true, "cpu": true,
+	"cpuprofile": true, "exec": true, "fuzz": true, "fuzzminimizetime": true, "fuzztime": true,
+	"gcflags": true, "ldflags": true, "list": true, "memprofile": true, "memprofilerate": true,
+	"mod": true, "modfile": true, "mutexprofile": true, "mutexprofilefraction": true, "o": true,
+	"outputdir": true, "overlay": true, "p": true, "parallel": true, "pkgdir": true, "run": true,
+	"shuffle": true, "skip": true, "tags": true, "timeout": true, "toolexec": true, "trace": true,
+	"vet": true,
+}
+
+// SplitArgs separates the package patterns from the go test flags of
+// args; packages default to ./...
+func SplitArgs(args []string) (packages, flags []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			packages = append(packages, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		name := strings.TrimLeft(arg, "-")
+		if !strings.Contains(name, "=") && valueFlags[name] && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	return packages, flags
+}
+
+// buildFlags returns the flags of go test that change which files build
+func buildFlags(flags []string) []string {
+	var build []string
+	for i := 0; i < len(flags); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(flags[i], "-"), "=")
+		if name != "tags" && name != "mod" && name != "modfile" {
+			continue
+		}
+		build = append(build, flags[i])
+		if !hasValue && i+1 < len(flags) {
+			i++
+			build = append(build, flags[i])
+		}
+	}
+	return build
+}
+
+// listTests returns the test files of the packages, by import path, when
+// built with env
+func listTests(env, flags, packages []string) (map[string][]string, error) {
+	args := append([]string{"list", "-e", "-f", "{{.ImportPath}} {{join .TestGoFiles \",\"}} {{join .XTestGoFiles \",\"}}"}, buildFlags(flags)...)
+	cmd := exec.Command("go", append(args, packages...)...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v\n%s", err, stderr.String())
+	}
+
+	tests := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for _, files := range fields[1:] {
+			tests[fields[0]] = append(tests[fields[0]], strings.Split(files, ",")...)
+		}
+	}
+	return tests, nil
+}
+
+// WasmOnly returns the packages, sorted, with test files in wasm, the test
+// files built for js/wasm, that are not in native, those built for this
+// machine. The tests of the other packages run with go test already.
+func WasmOnly(native, wasm map[string][]string) []string {
+	var packages []string
+	for pkg, files := range wasm {
+		built := make(map[string]bool, len(native[pkg]))
+		for _, file := range native[pkg] {
+			built[file] = true
+		}
+		for _, file := range files {
+			if !built[file] {
+				packages = append(packages, pkg)
+				break
+			}
+		}
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// PickRunner returns runner, checking it is installed, or for "" Node when
+// it is installed and else a headless browser
+func PickRunner(runner string) (string, error) {
+	switch runner {
+	case "":
+		if _, err := exec.LookPath("node"); err == nil {
+			return RunnerNode, nil
+		}
+		if _, err := findBrowser(); err == nil {
+			return RunnerBrowser, nil
+		}
+		return "", errors.New("WebAssembly tests need Node.js or Chrome, install one of them or set GOLEM_BROWSER")
+	case RunnerNode:
+		if _, err := exec.LookPath("node"); err != nil {
+			return "", errors.New("node not found, install Node.js or use --runner browser")
+		}
+		return runner, nil
+	case RunnerBrowser:
+		if _, err := findBrowser(); err != nil {
+			return "", err
+		}
+		return runner, nil
+	default:
+		return "", fmt.Errorf("unknown runner %q, expected %s or %s", runner, RunnerNode, RunnerBrowser)
+	}
+}
+
+// Exec runs the WebAssembly test binary args[0] with the test flags of
+// args[1:] in runner, the way go test runs test binaries with -exec, and
+// returns its exit code
+func Exec(runner string, args []string) (int, error) {
+	if len(args) == 0 {
+		return 1, errors.New("no test binary to run")
+	}
+	switch runner {
+	case RunnerNode:
+		return execNode(args)
+	case RunnerBrowser:
+		return execBrowser(args)
+	default:
+		return 1, fmt.Errorf("unknown runner %q, expected %s or %s", runner, RunnerNode, RunnerBrowser)
+	}
+}
+
+// execNode runs the test binary with the Node.js support files of the Go
+// installation, as go_js_wasm_exec does
+func execNode(args []string) (int, error) {
+	dir, err := wasmDir()
+	if err != nil {
+		return 1, err
+	}
+	// V8's default stack is too small for some tests
+	cmd := exec.Command("node", append([]string{"--stack-size=8192", filepath.Join(dir, "wasm_exec_node.js")}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+// wasmDir returns the directory of wasm_exec.js in the Go installation,
+// which must match the compiler of the test binaries
+func wasmDir() (string, error) {
+	output, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOROOT: %v", err)
+	}
+	goRoot := strings.TrimSpace(string(output))
+	for _, dir := range []string{
+		filepath.Join(goRoot, "lib", "wasm"),  // Go 1.21+
+		filepath.Join(goRoot, "misc", "wasm"), // Go < 1.21
+	} {
+		if _, err := os.Stat(filepath.Join(dir, "wasm_exec.js")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("wasm_exec.js not found in Go installation at %s", goRoot)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// PortEnv overrides the HTTP port of the production server. Hosting
+// platforms such as Heroku or Cloud Run set it to the port they route to.
+const PortEnv = "PORT"
+
+// HTTPAddress returns the address the production HTTP server binds,
+// server.host with the port of PORT, server.port or HTTPPort, and which of
+// them set the port
+func HTTPAddress(cfg *config.Config) (addr, source string, err error) {
+	port, source := cfg.Server.Port, "server.port"
+	if port == 0 {
+		port, source = HTTPPort, "default"
+	}
+	if value := os.Getenv(PortEnv); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 65535 {
+			return "", "", fmt.Errorf("invalid %s %q, expected a port number", PortEnv, value)
+		}
+		port, source = parsed, PortEnv
+	}
+	if port < 0 || port > 65535 {
+		return "", "", fmt.Errorf("invalid server.port %d", port)
+	}
+	return net.JoinHostPort(cfg.Server.Host, strconv.Itoa(port)), source, nil
+}
+
+// GRPCAddress returns the address the production gRPC server binds,
+// server.host with server.grpc.port or GRPCPort
+func GRPCAddress(cfg *config.Config) string {
+	port := cfg.Server.GRPC.Port
+	if port == 0 {
+		port = GRPCPort
+	}
+	return net.JoinHostPort(cfg.Server.Host, strconv.Itoa(port))
+}
+
+// displayAddress returns addr as a host the browser reaches, localhost
+// for every interface
+func displayAddress(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// describeAddress names the interfaces addr binds, for the startup log
+func describeAddress(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil && host == "" {
+		return addr + " on every interface"
+	}
+	return addr
+}
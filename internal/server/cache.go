@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// AssetManifestFile lists the content-hashed assets of a build, which the
+// production server lets browsers cache forever
+const AssetManifestFile = "asset-manifest.json"
+
+// Default Cache-Control values of the production server
+const (
+	CacheImmutable = "public, max-age=31536000, immutable"
+	CacheNoCache   = "no-cache"
+	CacheNoStore   = "no-store"
+)
+
+// CacheControlFS sets the Cache-Control header of the responses of next,
+// which serves the build output in fsys and the server functions:
+// content-hashed assets are cached forever, pages and other files are
+// revalidated on every use, and function responses are never stored.
+// cache overrides each value. Handlers setting the header themselves, such
+// as the SPA fallback, have the last word.
+func CacheControlFS(fsys fs.FS, cache config.CacheConfig, next http.Handler) http.Handler {
+	hashed := make(map[string]bool)
+	if data, err := fs.ReadFile(fsys, AssetManifestFile); err == nil {
+		var assets map[string]string
+		if json.Unmarshal(data, &assets) == nil {
+			for _, name := range assets {
+				hashed["/"+name] = true
+			}
+		}
+	}
+
+	// Longer patterns are more specific, so they are tried first
+	patterns := make([]string, 0, len(cache.Paths))
+	for pattern := range cache.Paths {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool { return len(patterns[i]) > len(patterns[j]) })
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlPath := path.Clean("/" + r.URL.Path)
+		value := ""
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, urlPath); matched {
+				value = cache.Paths[pattern]
+				break
+			}
+		}
+		if value == "" {
+			switch {
+			case strings.HasPrefix(urlPath, "/api/") || urlPath == "/health" || urlPath == LivenessPath || urlPath == ReadinessPath:
+				value = orDefault(cache.API, CacheNoStore)
+			case hashed[urlPath]:
+				value = orDefault(cache.Assets, CacheImmutable)
+			case path.Ext(urlPath) == "" || path.Ext(urlPath) == ".html":
+				value = orDefault(cache.Pages, CacheNoCache)
+			default:
+				value = orDefault(cache.Files, CacheNoCache)
+			}
+		}
+		w.Header().Set("Cache-Control", value)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// orDefault returns value, or fallback when it is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the smallest response Compress compresses; below it,
+// the headers and checksums of the encodings outweigh the savings
+const compressMinSize = 1024
+
+// incompressibleTypes are media types whose content is compressed already
+var incompressibleTypes = map[string]bool{
+	"application/gzip":         true,
+	"application/zip":          true,
+	"application/octet-stream": true,
+	"font/woff":                true,
+	"font/woff2":               true,
+	"text/event-stream":        true, // events are small and have to arrive at once
+}
+
+// brotliLevel is the quality of on the fly brotli, fast enough for every
+// response while still smaller than gzip
+const brotliLevel = 5
+
+// encoder is a compressing writer of an encoding
+type encoder interface {
+	io.Writer
+	Reset(w io.Writer)
+	Flush() error
+	Close() error
+}
+
+// compressEncoding is an encoding Compress applies, with a pool reusing its
+// writers, which allocate their tables up front
+type compressEncoding struct {
+	name     string
+	encoders *sync.Pool
+}
+
+// compressEncodings are the encodings of Compress, the preferred first
+var compressEncodings = []compressEncoding{
+	{"br", &sync.Pool{New: func() interface{} {
+		return brotli.NewWriterLevel(nil, brotliLevel)
+	}}},
+	{"gzip", &sync.Pool{New: func() interface{} {
+		writer, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return writer
+	}}},
+}
+
+// Compress compresses the responses of next for clients accepting brotli
+// or gzip, such as the JSON of server function calls, preferring brotli.
+// Responses that are small, already encoded, like the precompressed files
+// of PrecompressedFS, or of media types that are compressed already pass
+// through, as do WebSocket upgrades.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, encoding := range compressEncodings {
+			if acceptsEncoding(r.Header.Get("Accept-Encoding"), encoding.name) {
+				cw := &compressWriter{ResponseWriter: w, request: r, encoding: encoding}
+				defer cw.Close()
+				next.ServeHTTP(cw, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// compress it
+type compressWriter struct {
+	http.ResponseWriter
+	request  *http.Request
+	encoding compressEncoding
+	status   int
+	buffer   []byte
+	decided  bool
+	encoder  encoder // nil when the response passes through
+}
+
+// WriteHeader holds the status back until the response is known to be
+// compressed or not
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = status
+	}
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified || cw.request.Method == http.MethodHead {
+		cw.decide(false) // no body to compress
+	}
+}
+
+// Write buffers data until the response is large enough to compress
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if !cw.decided {
+		cw.buffer = append(cw.buffer, data...)
+		if len(cw.buffer) < compressMinSize {
+			return len(data), nil
+		}
+		cw.decide(cw.compressible())
+		if err := cw.writeBuffer(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(data)
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+// Flush sends what was written so far, compressing it when the response
+// is compressible, so streamed responses keep arriving
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(cw.compressible())
+		cw.writeBuffer()
+	}
+	if cw.encoder != nil {
+		cw.encoder.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes the rest of the response and the end of the compressed
+// stream
+func (cw *compressWriter) Close() {
+	if !cw.decided {
+		cw.decide(false) // everything fit below compressMinSize
+		cw.writeBuffer()
+	}
+	if cw.encoder != nil {
+		cw.encoder.Close()
+		cw.encoding.encoders.Put(cw.encoder)
+		cw.encoder = nil
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible reports whether the headers of the response allow
+// compressing it
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buffer)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || incompressibleTypes[mediaType] {
+		return false
+	}
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+	for _, prefix := range []string{"image/", "video/", "audio/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// decide sends the headers, with those of the encoding when compress is
+// set. Caches have to keep the encodings of compressible responses
+// apart, even of those too small to compress.
+func (cw *compressWriter) decide(compress bool) {
+	cw.decided = true
+	header := cw.Header()
+	if cw.compressible() {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if compress {
+		header.Set("Content-Encoding", cw.encoding.name)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		cw.encoder = cw.encoding.encoders.Get().(encoder)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+// writeBuffer writes the buffered start of the response
+func (cw *compressWriter) writeBuffer() error {
+	if len(cw.buffer) == 0 {
+		return nil
+	}
+	buffer := cw.buffer
+	cw.buffer = nil
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buffer)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buffer)
+	return err
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// defaultCORSMethods are the methods other origins may use without
+// cors.methods; the function endpoints only need these
+var defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+
+// defaultCORSMaxAge is how long browsers may cache a preflight without
+// cors.maxAge, in seconds
+const defaultCORSMaxAge = 600
+
+// CORS applies the cross-origin policy of cors to the /api/ endpoints of
+// next. Requests from the listed origins get the Access-Control headers,
+// and their preflight requests are answered directly; other origins get
+// none, so browsers keep them from reading the responses. Without
+// origins, only the app's own origin may call the API.
+func CORS(cors config.CORSConfig, next http.Handler) (http.Handler, error) {
+	if err := validateCORS(cors); err != nil {
+		return nil, err
+	}
+	if len(cors.Origins) == 0 {
+		return next, nil
+	}
+
+	methods := cors.Methods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	maxAge := cors.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultCORSMaxAge
+	}
+	anyOrigin := false
+	for _, origin := range cors.Origins {
+		anyOrigin = anyOrigin || origin == "*"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && (anyOrigin || corsOriginAllowed(cors.Origins, origin))
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if allowed {
+			if anyOrigin && !cors.Credentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cors.Credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		if !preflight {
+			if allowed {
+				header.Set("Access-Control-Expose-Headers", functions.RequestIDHeader)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			if len(cors.Headers) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(cors.Headers, ", "))
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				// Function calls send their metadata as headers of any name
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+			header.Set("Access-Control-Max-Age", strconv.Itoa(maxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}), nil
+}
+
+// validateCORS checks that every origin of cors is "*", or a scheme and
+// host, optionally with a "*." subdomain wildcard, and that credentials are
+// not shared with any origin
+func validateCORS(cors config.CORSConfig) error {
+	for _, origin := range cors.Origins {
+		if origin == "*" {
+			if cors.Credentials {
+				return fmt.Errorf("cors: credentials cannot be allowed for every origin, list the origins instead of \"*\"")
+			}
+			continue
+		}
+		parsed, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" {
+			return fmt.Errorf("cors: invalid origin %q, expected a scheme and host such as https://app.example.com", origin)
+		}
+		if parsed.Path == "/" {
+			return fmt.Errorf("cors: origin %q must not end in a slash", origin)
+		}
+	}
+	if cors.MaxAge < 0 {
+		return fmt.Errorf("cors: invalid maxAge %d, expected seconds", cors.MaxAge)
+	}
+	return nil
+}
+
+// corsOriginAllowed reports whether origin is one of origins, which may
+// allow every subdomain of a host as https://*.example.com
+func corsOriginAllowed(origins []string, origin string) bool {
+	for _, allowed := range origins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+		scheme, domain, ok := strings.Cut(allowed, "://*.")
+		if ok && len(origin) > len(scheme)+3+len(domain) &&
+			strings.EqualFold(origin[:len(scheme)+3], scheme+"://") &&
+			strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(domain)) {
+			return true
+		}
+	}
+	return false
+}
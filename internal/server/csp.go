@@ -0,0 +1,32 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// CSPFile holds the Content-Security-Policy golem build generates with
+// build.csp, which the production server sends as a header
+const CSPFile = "csp-header.txt"
+
+// ContentSecurityPolicyFS sends the policy of fsys's CSPFile with every
+// response of next, and hides the file itself. Without the file, it
+// returns next.
+func ContentSecurityPolicyFS(fsys fs.FS, next http.Handler) http.Handler {
+	data, err := fs.ReadFile(fsys, CSPFile)
+	if err != nil {
+		return next
+	}
+	policy := strings.TrimSpace(string(data))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if urlPath := path.Clean("/" + r.URL.Path); urlPath == "/"+CSPFile || strings.HasPrefix(urlPath, "/"+CSPFile+".") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Security-Policy", policy)
+		next.ServeHTTP(w, r)
+	})
+}
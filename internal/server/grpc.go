@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewGRPCServer creates the gRPC server of a registry configured from the
+// project's gRPC settings
+func NewGRPCServer(registry *functions.Registry, cfg config.GRPCConfig) (*grpc.Server, error) {
+	opts, err := GRPCServerOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	grpcServer := functions.CreateGRPCServer(registry, opts...)
+	if cfg.Reflection {
+		reflection.Register(grpcServer)
+	}
+	return grpcServer, nil
+}
+
+// GRPCServerOptions converts the gRPC settings into server options
+func GRPCServerOptions(cfg config.GRPCConfig) ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	if cfg.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize))
+	}
+	if cfg.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(cfg.MaxSendMsgSize))
+	}
+
+	if ka := cfg.Keepalive; ka != nil {
+		var params keepalive.ServerParameters
+		var policy keepalive.EnforcementPolicy
+		durations := []struct {
+			name  string
+			value string
+			dest  *time.Duration
+		}{
+			{"time", ka.Time, &params.Time},
+			{"timeout", ka.Timeout, &params.Timeout},
+			{"maxConnectionIdle", ka.MaxConnectionIdle, &params.MaxConnectionIdle},
+			{"minTime", ka.MinTime, &policy.MinTime},
+		}
+		for _, d := range durations {
+			if d.value == "" {
+				continue
+			}
+			parsed, err := time.ParseDuration(d.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid server.grpc.keepalive.%s: %w", d.name, err)
+			}
+			*d.dest = parsed
+		}
+		policy.PermitWithoutStream = ka.PermitWithoutStream
+
+		opts = append(opts, grpc.KeepaliveParams(params), grpc.KeepaliveEnforcementPolicy(policy))
+	}
+
+	if cfg.TLS != nil {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load gRPC TLS credentials: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	return opts, nil
+}
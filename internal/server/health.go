@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Probe endpoints of the production server, e.g. for Kubernetes
+const (
+	LivenessPath  = "/healthz" // the process serves requests
+	ReadinessPath = "/readyz"  // the server and the checks of its functions are ready for traffic
+)
+
+// healthCheckTimeout bounds the registered checks of a readiness probe
+const healthCheckTimeout = 2 * time.Second
+
+// healthReport is the JSON body of the probes
+type healthReport struct {
+	Status string                 `json:"status"` // "ok" or "unavailable"
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// checkResult is the outcome of one readiness check
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// liveness answers LivenessPath: a server able to answer is alive, even
+// while it drains, so restarting it would not help
+func (s *Server) liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, healthReport{Status: "ok"})
+}
+
+// readiness answers ReadinessPath, ready once the functions are registered
+// and the gRPC server listens, until the server shuts down, and while every
+// check registered with the functions passes
+func (s *Server) readiness(w http.ResponseWriter, r *http.Request) {
+	report := healthReport{Status: "ok", Checks: make(map[string]checkResult)}
+	add := func(name string, err error) {
+		if err != nil {
+			report.Status = "unavailable"
+			report.Checks[name] = checkResult{Status: "error", Error: err.Error()}
+			return
+		}
+		report.Checks[name] = checkResult{Status: "ok"}
+	}
+
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+	for name, err := range s.registry.CheckHealth(ctx) {
+		add(name, err)
+	}
+	if !s.registryReady.Load() {
+		add("registry", errors.New("functions are not registered yet"))
+	} else {
+		add("registry", nil)
+	}
+	if !s.grpcReady.Load() {
+		add("grpc", errors.New("the gRPC server is not listening yet"))
+	} else {
+		add("grpc", nil)
+	}
+	if stopped {
+		add("shutdown", errors.New("the server is shutting down"))
+	}
+	writeHealth(w, report)
+}
+
+// writeHealth writes report, with 503 Service Unavailable unless it is ok
+func writeHealth(w http.ResponseWriter, report healthReport) {
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
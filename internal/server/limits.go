@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// ApplyFunctionLimits applies the per-function limits of the project config
+// to a registry
+func ApplyFunctionLimits(registry *functions.Registry, limits map[string]config.FunctionLimits) error {
+	for key, limit := range limits {
+		serviceName, functionName, found := strings.Cut(key, ".")
+		if !found {
+			return fmt.Errorf("invalid function %q in server.limits, expected service.function", key)
+		}
+
+		var timeout time.Duration
+		if limit.Timeout != "" {
+			parsed, err := time.ParseDuration(limit.Timeout)
+			if err != nil {
+				return fmt.Errorf("invalid timeout for %s: %w", key, err)
+			}
+			timeout = parsed
+		}
+
+		registry.SetLimits(serviceName, functionName, functions.Limits{
+			Timeout:       timeout,
+			MaxConcurrent: limit.MaxConcurrent,
+		})
+	}
+	return nil
+}
+
+// ApplyIdempotencyWindow sets how long the registry remembers results of
+// calls made with an Idempotency-Key; empty keeps the default
+func ApplyIdempotencyWindow(registry *functions.Registry, window string) error {
+	if window == "" {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(window)
+	if err != nil {
+		return fmt.Errorf("invalid server.idempotencyWindow: %w", err)
+	}
+	registry.SetIdempotencyWindow(parsed)
+	return nil
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// Log formats of server.log.format
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// NewLogger returns a structured logger writing to w in the format and from
+// the level of cfg
+func NewLogger(cfg config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, fmt.Errorf("invalid server.log.level %q, expected debug, info, warn or error", cfg.Level)
+		}
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	switch cfg.Format {
+	case "", LogFormatText:
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("invalid server.log.format %q, expected %s or %s", cfg.Format, LogFormatText, LogFormatJSON)
+}
+
+// initLogger sets up the logger of server.log for the server, its function
+// registry and the log package, so the logs of functions share its format
+func (s *Server) initLogger() error {
+	if s.logger != nil {
+		return nil
+	}
+	logger, err := NewLogger(s.config.Server.Log, os.Stdout)
+	if err != nil {
+		return err
+	}
+	s.logger = logger
+	s.registry.SetLogger(logger)
+	slog.SetDefault(logger)
+	return nil
+}
+
+// logRequests wraps the HTTP handler of the server with request IDs and,
+// unless server.log.requests is off, the request log
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	if requests := s.config.Server.Log.Requests; requests == nil || *requests {
+		next = LogRequests(s.logger, next)
+	}
+	return RequestID(next)
+}
+
+// RequestID assigns every request to next an ID, the X-Request-ID it came
+// with, e.g. from a load balancer, or a new one. The ID is sent back in the
+// response and carried by the request context, where server functions and
+// their logs find it.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(functions.RequestIDHeader)
+		if !functions.ValidRequestID(id) {
+			id = functions.NewRequestID()
+		}
+		w.Header().Set(functions.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(functions.WithRequestID(r.Context(), id)))
+	})
+}
+
+// LogRequests logs every request to next once it was answered, with its
+// request ID, status, size and duration. Server errors are logged at the
+// error level.
+func LogRequests(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &logWriter{ResponseWriter: w}
+		next.ServeHTTP(lw, r)
+
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(r.Context(), level, "http request",
+			slog.String("request_id", functions.RequestIDFromContext(r.Context())),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("bytes", lw.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote", r.RemoteAddr),
+		)
+	})
+}
+
+// logWriter records the status and size of a response for LogRequests
+// and Metrics
+type logWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the status of the response
+func (lw *logWriter) WriteHeader(status int) {
+	if lw.status == 0 && status >= http.StatusOK {
+		lw.status = status
+	}
+	lw.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the bytes of the response body
+func (lw *logWriter) Write(data []byte) (int, error) {
+	if lw.status == 0 {
+		lw.status = http.StatusOK
+	}
+	n, err := lw.ResponseWriter.Write(data)
+	lw.bytes += int64(n)
+	return n, err
+}
+
+// Flush sends what was written so far, for streamed responses
+func (lw *logWriter) Flush() {
+	if flusher, ok := lw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over to WebSocket handlers
+func (lw *logWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := lw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the response writer cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && lw.status == 0 {
+		lw.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (lw *logWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
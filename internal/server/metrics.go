@@ -0,0 +1,248 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// MetricsPath is where the production server serves its metrics when
+// server.metrics is set
+const MetricsPath = "/metrics"
+
+// metricsContentType is the Prometheus text exposition format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metrics counts the requests of the HTTP server and serves them, with the
+// call counters of a function registry, in the Prometheus text format
+type Metrics struct {
+	registry   *functions.Registry
+	info       [][2]string // labels of golem_build_info
+	websockets atomic.Int64
+	mu         sync.Mutex
+	requests   map[requestLabels]*histogram
+}
+
+// requestLabels identify the series of an HTTP request
+type requestLabels struct {
+	method, route, status string
+}
+
+// histogram counts observations into functions.DurationBuckets
+type histogram struct {
+	buckets [len(functions.DurationBuckets)]uint64 // not cumulative
+	count   uint64
+	sum     float64
+}
+
+// NewMetrics returns the metrics of a server running the app of cfg with
+// the functions of registry
+func NewMetrics(registry *functions.Registry, cfg *config.Config) *Metrics {
+	golemVersion := "unknown"
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, module := range append([]*debug.Module{&build.Main}, build.Deps...) {
+			if module.Path == "github.com/Nu11ified/golem" {
+				golemVersion = module.Version
+			}
+		}
+	}
+	return &Metrics{
+		registry: registry,
+		info: [][2]string{
+			{"app", cfg.ProjectName},
+			{"version", cfg.Version},
+			{"golem_version", golemVersion},
+			{"go_version", runtime.Version()},
+		},
+		requests: make(map[requestLabels]*histogram),
+	}
+}
+
+// Instrument counts the requests to next, which has to be the server's
+// ServeMux: requests are labeled with the pattern they matched rather than
+// their path, which would give every deep link and asset a series of its
+// own. WebSocket connections are counted while they are open.
+func (m *Metrics) Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			m.websockets.Add(1)
+			defer m.websockets.Add(-1)
+		}
+
+		start := time.Now()
+		lw := &logWriter{ResponseWriter: w}
+		next.ServeHTTP(lw, r)
+
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		m.observe(requestLabels{method: metricsMethod(r.Method), route: route, status: strconv.Itoa(status)}, time.Since(start))
+	})
+}
+
+// observe records a request that took elapsed
+func (m *Metrics) observe(labels requestLabels, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.requests[labels]
+	if !ok {
+		h = &histogram{}
+		m.requests[labels] = h
+	}
+	h.count++
+	h.sum += elapsed.Seconds()
+	for i, bound := range functions.DurationBuckets {
+		if elapsed.Seconds() <= bound {
+			h.buckets[i]++
+			break
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+
+	m.mu.Lock()
+	keys := make([]requestLabels, 0, len(m.requests))
+	for labels := range m.requests {
+		keys = append(keys, labels)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		x, y := keys[i], keys[j]
+		if x.route != y.route {
+			return x.route < y.route
+		}
+		if x.method != y.method {
+			return x.method < y.method
+		}
+		return x.status < y.status
+	})
+	requests := make([]histogram, len(keys))
+	for i, labels := range keys {
+		requests[i] = *m.requests[labels]
+	}
+	m.mu.Unlock()
+
+	metricHeader(&b, "golem_http_requests_total", "counter", "HTTP requests answered, by method, route pattern and status.")
+	for i, labels := range keys {
+		sample(&b, "golem_http_requests_total", requestLabelPairs(labels), float64(requests[i].count))
+	}
+	metricHeader(&b, "golem_http_request_duration_seconds", "histogram", "Time taken to answer HTTP requests, by method, route pattern and status.")
+	for i, labels := range keys {
+		h := requests[i]
+		var cumulative uint64
+		buckets := make([]uint64, len(h.buckets))
+		for j, count := range h.buckets {
+			cumulative += count
+			buckets[j] = cumulative
+		}
+		writeHistogram(&b, "golem_http_request_duration_seconds", requestLabelPairs(labels), buckets, h.count, h.sum)
+	}
+
+	// Every registered function has series, so calls show up as increases
+	names := make([]string, 0)
+	for _, info := range m.registry.ListFunctions("") {
+		names = append(names, info.ServiceName+"."+info.Name)
+	}
+	sort.Strings(names)
+	stats := m.registry.Stats()
+	for _, metric := range []struct {
+		name, help string
+		value      func(functions.FunctionStats) uint64
+	}{
+		{"golem_function_calls_total", "Server function calls, by function.", func(s functions.FunctionStats) uint64 { return s.Calls }},
+		{"golem_function_errors_total", "Server function calls that failed, by function.", func(s functions.FunctionStats) uint64 { return s.Errors }},
+		{"golem_function_panics_total", "Server function calls that panicked, by function.", func(s functions.FunctionStats) uint64 { return s.Panics }},
+	} {
+		metricHeader(&b, metric.name, "counter", metric.help)
+		for _, name := range names {
+			sample(&b, metric.name, [][2]string{{"function", name}}, float64(metric.value(stats[name])))
+		}
+	}
+	metricHeader(&b, "golem_function_duration_seconds", "histogram", "Time taken by server function calls, by function.")
+	for _, name := range names {
+		s := stats[name]
+		buckets := s.Durations
+		if buckets == nil {
+			buckets = make([]uint64, len(functions.DurationBuckets))
+		}
+		writeHistogram(&b, "golem_function_duration_seconds", [][2]string{{"function", name}}, buckets, s.Calls, s.TotalDuration.Seconds())
+	}
+
+	metricHeader(&b, "golem_websocket_connections", "gauge", "Open WebSocket connections.")
+	sample(&b, "golem_websocket_connections", nil, float64(m.websockets.Load()))
+	metricHeader(&b, "golem_build_info", "gauge", "Versions of the app, golem and Go; always 1.")
+	sample(&b, "golem_build_info", m.info, 1)
+
+	w.Header().Set("Content-Type", metricsContentType)
+	io.WriteString(w, b.String())
+}
+
+// requestLabelPairs returns the labels of a request series
+func requestLabelPairs(labels requestLabels) [][2]string {
+	return [][2]string{{"method", labels.method}, {"route", labels.route}, {"status", labels.status}}
+}
+
+// metricsMethod returns method, or OTHER for non-standard methods, which
+// clients choose freely
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// metricHeader writes the HELP and TYPE lines of a metric
+func metricHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeHistogram writes the series of a histogram, buckets being
+// cumulative counts for functions.DurationBuckets
+func writeHistogram(b *strings.Builder, name string, labels [][2]string, buckets []uint64, count uint64, sum float64) {
+	for i, bound := range functions.DurationBuckets {
+		sample(b, name+"_bucket", append(labels[:len(labels):len(labels)], [2]string{"le", strconv.FormatFloat(bound, 'g', -1, 64)}), float64(buckets[i]))
+	}
+	sample(b, name+"_bucket", append(labels[:len(labels):len(labels)], [2]string{"le", "+Inf"}), float64(count))
+	sample(b, name+"_sum", labels, sum)
+	sample(b, name+"_count", labels, float64(count))
+}
+
+// sample writes one sample line
+func sample(b *strings.Builder, name string, labels [][2]string, value float64) {
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", label[0], labelEscaper.Replace(label[1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(b, " %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// labelEscaper escapes label values for the text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
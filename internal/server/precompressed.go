@@ -0,0 +1,110 @@
+package server
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// encodings are the precompressed variants golem build writes, in order of
+// preference, with the file extension of each
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Precompressed serves the .br or .gz variant of a file in dir, written by
+// golem build, to clients accepting that encoding. Other requests are
+// passed to next.
+func Precompressed(dir string, next http.Handler) http.Handler {
+	return PrecompressedFS(os.DirFS(dir), next)
+}
+
+// PrecompressedFS is Precompressed for the files of fsys, e.g. a build
+// embedded into the binary
+func PrecompressedFS(fsys fs.FS, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		urlPath := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") {
+			urlPath = path.Join(urlPath, "index.html")
+		}
+		file := strings.TrimPrefix(urlPath, "/")
+
+		varied := false
+		for _, encoding := range encodings {
+			info, err := fs.Stat(fsys, file+encoding.ext)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if !varied {
+				// Caches must keep the variants apart
+				w.Header().Add("Vary", "Accept-Encoding")
+				varied = true
+			}
+			if !acceptsEncoding(r.Header.Get("Accept-Encoding"), encoding.name) {
+				continue
+			}
+
+			opened, err := fsys.Open(file + encoding.ext)
+			if err != nil {
+				continue
+			}
+			defer opened.Close()
+			variant, ok := opened.(io.ReadSeeker)
+			if !ok {
+				continue
+			}
+
+			if contentType := mime.TypeByExtension(path.Ext(urlPath)); contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.Header().Set("Content-Encoding", encoding.name)
+			if r.Header.Get("Range") == "" {
+				// ServeContent leaves the length of encoded content out, which
+				// browsers need to show the download's progress
+				w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+			}
+			http.ServeContent(w, r, urlPath, info.ModTime(), variant)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows an
+// encoding, honoring q=0 and the * wildcard
+func acceptsEncoding(header, encoding string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encoding && name != "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == encoding {
+			return q > 0 // an explicit entry overrides the wildcard
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// pageTag and tagAttr find the <link> and <base> tags of a page and their
+// attributes
+var (
+	pageTag = regexp.MustCompile(`(?is)<(link|base)\b[^>]*>`)
+	tagAttr = regexp.MustCompile(`(?s)([a-zA-Z-]+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+))?`)
+)
+
+// PreloadFS sends the <link rel="preload"> tags of the pages in fsys as Link
+// headers with the responses of next to page requests, so browsers start
+// fetching app.wasm and wasm_exec.js before they parse the HTML. With
+// earlyHints, page requests first get the headers in a 103 Early Hints
+// response, while the page itself is still on its way. It returns next when
+// the pages preload nothing.
+func PreloadFS(fsys fs.FS, cfg *config.Config, earlyHints bool, next http.Handler) http.Handler {
+	apps := cfg.AppPaths()
+	links := make(map[string][]string)
+	for _, page := range append([]string{"/"}, apps...) {
+		file := fallbackPage(page, apps)
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			continue
+		}
+		if values := preloadLinks(string(data), "/"+strings.TrimSuffix(file, "index.html")); len(values) > 0 {
+			links[file] = values
+		}
+	}
+	if len(links) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values, ok := links[fallbackPage(r.URL.Path, apps)]
+		if !ok || !pageRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, value := range values {
+			w.Header().Add("Link", value)
+		}
+		if earlyHints && r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusEarlyHints)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// pageRequest reports whether r likely loads a page: a GET or HEAD of a
+// path without an extension, or of an .html file, outside of the API
+func pageRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	urlPath := path.Clean("/" + r.URL.Path)
+	if strings.HasPrefix(urlPath, "/api/") || urlPath == "/health" || urlPath == LivenessPath || urlPath == ReadinessPath || urlPath == MetricsPath {
+		return false
+	}
+	ext := path.Ext(urlPath)
+	return ext == "" || ext == ".html"
+}
+
+// preloadLinks returns the Link header values of the preload tags of html,
+// a page served under dir. Their URLs are resolved against its <base>, since
+// the URLs of a header are relative to the request instead.
+func preloadLinks(html, dir string) []string {
+	base, based := &url.URL{Path: dir}, false
+	var tags []map[string]string
+	for _, match := range pageTag.FindAllStringSubmatch(html, -1) {
+		attrs := make(map[string]string)
+		for _, attr := range tagAttr.FindAllStringSubmatch(match[0][len(match[1])+1:], -1) {
+			attrs[strings.ToLower(attr[1])] = strings.Trim(attr[2], `"'`)
+		}
+		if !strings.EqualFold(match[1], "base") {
+			tags = append(tags, attrs)
+			continue
+		}
+		// The first <base> applies to the whole page
+		if href, err := url.Parse(attrs["href"]); err == nil && attrs["href"] != "" && !based {
+			base, based = base.ResolveReference(href), true
+		}
+	}
+
+	var links []string
+	for _, attrs := range tags {
+		if !strings.EqualFold(attrs["rel"], "preload") || attrs["href"] == "" {
+			continue
+		}
+		href, err := url.Parse(attrs["href"])
+		if err != nil {
+			continue
+		}
+
+		link := "<" + base.ResolveReference(href).String() + ">; rel=preload"
+		if as := attrs["as"]; as != "" {
+			link += "; as=" + as
+		}
+		if typ := attrs["type"]; typ != "" {
+			link += `; type="` + typ + `"`
+		}
+		if crossorigin, ok := attrs["crossorigin"]; ok {
+			if crossorigin == "use-credentials" {
+				link += "; crossorigin=use-credentials"
+			} else {
+				link += "; crossorigin"
+			}
+		}
+		if integrity := attrs["integrity"]; integrity != "" {
+			link += `; integrity="` + integrity + `"`
+		}
+		links = append(links, link)
+	}
+	return links
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// defaultProxyTimeout is how long an upstream of server.proxy gets to
+// answer without a timeout
+const defaultProxyTimeout = 30 * time.Second
+
+// reservedPaths are served by golem itself, so no proxy rule may take them
+var reservedPaths = []string{"/api/functions", "/api/openapi.json", "/api/docs", "/health", LivenessPath, ReadinessPath, MetricsPath}
+
+// RegisterProxies serves the requests under the path of each rule from its
+// target on mux. Rules fail when their target is not an http(s) URL or
+// their path is one golem serves; a broader prefix, such as /api/, leaves
+// the paths of golem to it.
+func RegisterProxies(mux *http.ServeMux, rules []config.ProxyRule, logger *slog.Logger) error {
+	for _, rule := range rules {
+		prefix := strings.TrimSuffix(rule.Path, "/")
+		if !strings.HasPrefix(rule.Path, "/") || prefix == "" {
+			return fmt.Errorf("invalid server.proxy path %q, expected a prefix such as /auth/", rule.Path)
+		}
+		for _, reserved := range reservedPaths {
+			if prefix == reserved || strings.HasPrefix(prefix, reserved+"/") {
+				return fmt.Errorf("invalid server.proxy path %q, %s is served by golem", rule.Path, reserved)
+			}
+		}
+		handler, err := NewProxy(rule, logger)
+		if err != nil {
+			return err
+		}
+		mux.Handle(prefix, handler)
+		mux.Handle(prefix+"/", handler)
+	}
+	return nil
+}
+
+// NewProxy returns a reverse proxy forwarding requests to rule.Target,
+// without the rule.Path prefix with rule.StripPrefix. The upstream decides
+// how its responses are cached, and a failing one is answered with 502 Bad
+// Gateway.
+func NewProxy(rule config.ProxyRule, logger *slog.Logger) (http.Handler, error) {
+	target, err := url.Parse(rule.Target)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid server.proxy target %q, expected a URL such as http://localhost:9000", rule.Target)
+	}
+	timeout := defaultProxyTimeout
+	if rule.Timeout != "" {
+		if timeout, err = time.ParseDuration(rule.Timeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid server.proxy timeout %q for %s, expected a duration such as 10s", rule.Timeout, rule.Path)
+		}
+	}
+	prefix := strings.TrimSuffix(rule.Path, "/")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			if rule.StripPrefix {
+				r.Out.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(r.In.URL.Path, prefix), "/")
+				r.Out.URL.RawPath = ""
+				r.Out.Header.Set("X-Forwarded-Prefix", prefix)
+			}
+			r.SetURL(target)
+			r.SetXForwarded()
+			// The upstream logs the request under the ID of this server
+			if id := functions.RequestIDFromContext(r.In.Context()); id != "" {
+				r.Out.Header.Set(functions.RequestIDHeader, id)
+			}
+			// and continues its trace
+			otel.GetTextMapPropagator().Inject(r.In.Context(), propagation.HeaderCarrier(r.Out.Header))
+		},
+		ModifyResponse: func(res *http.Response) error {
+			if res.Request.Header.Get(functions.RequestIDHeader) != "" {
+				res.Header.Del(functions.RequestIDHeader)
+			}
+			return nil
+		},
+		Transport:     transport,
+		FlushInterval: -1, // stream SSE and other long responses as they are written
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Error("proxy request failed", "path", r.URL.Path, "target", rule.Target, "error", err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Del("Cache-Control")
+		proxy.ServeHTTP(w, r)
+	}), nil
+}
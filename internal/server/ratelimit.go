@@ -0,0 +1,279 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// DefaultMaxBodySize bounds the /api/ request bodies without
+// server.maxBodySize, matching the default gRPC message size
+const DefaultMaxBodySize = 4 << 20
+
+// defaultRateLimitWindow is the window of server.rateLimit without one
+const defaultRateLimitWindow = time.Minute
+
+// LimitBodySize answers /api/ requests whose body exceeds max bytes with
+// 413 Request Entity Too Large before they reach next. Bodies are read
+// ahead, so a function call never starts on a truncated body. max is
+// DefaultMaxBodySize when 0, and -1 disables the limit.
+func LimitBodySize(max int64, next http.Handler) http.Handler {
+	if max == 0 {
+		max = DefaultMaxBodySize
+	}
+	if max < 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tooLarge := func() {
+			functions.WriteErrorStatus(w, http.StatusRequestEntityTooLarge,
+				functions.Errorf(functions.CodeResourceExhausted, "request body exceeds %d bytes", max))
+		}
+		if r.ContentLength > max {
+			tooLarge()
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, max))
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			tooLarge()
+			return
+		}
+		if err != nil {
+			functions.WriteErrorStatus(w, http.StatusBadRequest, functions.NewError(functions.CodeInvalidArgument, "failed to read the request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimit limits the function calls each client IP makes to
+// /api/functions to rate.requests per rate.window, with bursts of up to
+// rate.burst calls. Every call is charged: a batch costs one call per
+// entry, and a socket to /api/functions/mux or /api/functions/ws one per
+// call or message sent on it. Single calls over the limit are answered
+// with 429 Too Many Requests and a Retry-After header, the others fail
+// with resource_exhausted, as does a socket sending too many messages.
+// Batch and socket calls are charged by an interceptor added to registry.
+func RateLimit(rate config.RateLimitConfig, registry *functions.Registry, next http.Handler) (http.Handler, error) {
+	limiter, err := NewRateLimiter(rate)
+	if err != nil {
+		return nil, err
+	}
+	limiter.Install(registry)
+	return limiter.Handler(next), nil
+}
+
+// RateLimiter charges the function calls of each client IP against
+// server.rateLimit, as RateLimit describes
+type RateLimiter struct {
+	limiter    *rateLimiter
+	trustProxy bool
+}
+
+// NewRateLimiter returns the limiter of rate, nil when rate.requests is 0
+func NewRateLimiter(rate config.RateLimitConfig) (*RateLimiter, error) {
+	if rate.Requests < 0 || rate.Burst < 0 {
+		return nil, fmt.Errorf("invalid server.rateLimit, requests and burst cannot be negative")
+	}
+	if rate.Requests == 0 {
+		return nil, nil
+	}
+	window := defaultRateLimitWindow
+	if rate.Window != "" {
+		parsed, err := time.ParseDuration(rate.Window)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid server.rateLimit.window %q, expected a duration such as 1m", rate.Window)
+		}
+		window = parsed
+	}
+	burst := rate.Burst
+	if burst == 0 {
+		burst = rate.Requests
+	}
+
+	return &RateLimiter{
+		limiter:    newRateLimiter(float64(rate.Requests)/window.Seconds(), float64(burst)),
+		trustProxy: rate.TrustProxy,
+	}, nil
+}
+
+// Install charges the batch and socket calls of registry, with an
+// interceptor and a message gate. Each call added to a registry charges its
+// calls once more, so a server installs its limiter once.
+func (l *RateLimiter) Install(registry *functions.Registry) {
+	if l == nil {
+		return
+	}
+	registry.Use(func(ctx context.Context, info *functions.CallInfo, next functions.Handler) (*anypb.Any, error) {
+		ctx, err := l.limiter.charge(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, info)
+	})
+	registry.GateMessages(func(ctx context.Context) error {
+		_, err := l.limiter.charge(ctx)
+		return err
+	})
+}
+
+// Handler charges the single calls to /api/functions before next runs
+// them, and marks the requests of the others for the calls to be charged
+// as they run
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	limiter := l.limiter
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/functions") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := rateLimitClient{ip: clientIP(r, l.trustProxy)}
+		switch r.URL.Path {
+		case "/api/functions/batch", "/api/functions/mux", "/api/functions/ws":
+			// These carry many calls, charged as each one runs
+		default:
+			wait := limiter.take(client.ip, time.Now())
+			if wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter(wait)))
+				functions.WriteErrorStatus(w, http.StatusTooManyRequests, rateLimitError(wait))
+				return
+			}
+			client.charged = true
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), rateLimitKey{}, client)))
+	})
+}
+
+// rateLimitKey carries the rateLimitClient of a request in its context
+type rateLimitKey struct{}
+
+// rateLimitClient is who the calls of a request are charged to, and
+// whether its single call was charged already
+type rateLimitClient struct {
+	ip      string
+	charged bool
+}
+
+// charge takes a token for a call with ctx, returning the context of the
+// calls it makes, which are not charged again. Calls not made through an
+// /api/functions request, such as those over gRPC, are free.
+func (l *rateLimiter) charge(ctx context.Context) (context.Context, error) {
+	client, ok := ctx.Value(rateLimitKey{}).(rateLimitClient)
+	if !ok || client.charged {
+		return ctx, nil
+	}
+	if wait := l.take(client.ip, time.Now()); wait > 0 {
+		return ctx, rateLimitError(wait)
+	}
+	return context.WithValue(ctx, rateLimitKey{}, rateLimitClient{ip: client.ip, charged: true}), nil
+}
+
+// rateLimitError reports a call over the limit, which may be retried after
+// wait
+func rateLimitError(wait time.Duration) *functions.Error {
+	return functions.NewError(functions.CodeResourceExhausted, "rate limit exceeded, retry later").
+		WithRetryable(true).
+		WithDetail("retryAfter", retryAfter(wait))
+}
+
+// retryAfter rounds wait up to whole seconds
+func retryAfter(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+// clientIP returns the IP a rate limit applies to: the peer's, or with
+// trustProxy the last address of X-Forwarded-For, which the proxy in front
+// of the server appended. Earlier entries come from the client and can be
+// forged.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		forwarded := r.Header.Values("X-Forwarded-For")
+		if len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter keeps a token bucket per client
+type rateLimiter struct {
+	rate      float64 // tokens added per second
+	burst     float64 // tokens a bucket holds
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket holds the calls a client may make right away
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter refilling buckets of burst tokens at
+// rate tokens per second
+func newRateLimiter(rate, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: burst, buckets: make(map[string]*tokenBucket)}
+}
+
+// take spends a token of client's bucket at now, returning 0 when it had
+// one or how long until it has
+func (l *rateLimiter) take(client string, now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Full buckets are the same as new ones, so they can be dropped
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) > refill {
+		for key, bucket := range l.buckets {
+			if now.Sub(bucket.last) > refill {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	return time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+}
@@ -1,24 +1,51 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/Nu11ified/golem/internal/config"
 	"github.com/Nu11ified/golem/internal/functions"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
 )
 
+// HTTPPort and GRPCPort are the default ports of the production server;
+// server.port and server.grpc.port override them
+const (
+	HTTPPort = 8080
+	GRPCPort = 50051
+)
+
 // Server represents the production server
 type Server struct {
 	config     *config.Config
 	httpServer *http.Server
 	grpcServer *grpc.Server
 	registry   *functions.Registry
+	logger     *slog.Logger
+	assets     fs.FS      // build output, embedded by single binaries; config.Output when nil
+	mu         sync.Mutex // guards httpServer, grpcServer, stopped and tracerProvider, set as the servers start
+	stopped    bool       // Shutdown was called, so servers starting late stay down
+
+	tracerProvider *sdktrace.TracerProvider // exports spans with server.tracing, flushed on Shutdown
+
+	registryInit sync.Once    // registers the functions and installs rateLimiter, once for every start
+	rateLimiter  *RateLimiter // nil without server.rateLimit
+	rateLimitErr error
+
+	registryReady atomic.Bool // the functions are registered
+	grpcReady     atomic.Bool // the gRPC server listens
 }
 
 // NewServer creates a new production server
@@ -29,12 +56,53 @@ func NewServer(config *config.Config) *Server {
 	}
 }
 
+// SetAssets serves the build output from assets instead of config.Output
+func (s *Server) SetAssets(assets fs.FS) *Server {
+	s.assets = assets
+	return s
+}
+
 // Start starts the production server (both HTTP and gRPC)
 func (s *Server) Start() error {
+	if err := s.initLogger(); err != nil {
+		return err
+	}
+	if err := s.initTracing(); err != nil {
+		return err
+	}
+	httpAddr, portSource, err := HTTPAddress(s.config)
+	if err != nil {
+		return err
+	}
+	grpcAddr := GRPCAddress(s.config)
+	shutdownTimeout, err := ShutdownTimeout(s.config.Server.ShutdownTimeout)
+	if err != nil {
+		return err
+	}
+
 	// Initialize the function registry
 	if err := s.initializeFunctionRegistry(); err != nil {
 		return fmt.Errorf("failed to initialize function registry: %w", err)
 	}
+	s.registryReady.Store(true)
+
+	static := s.config.Output
+	if s.assets != nil {
+		static = "embedded in the binary"
+	}
+	routing := "hash"
+	if s.config.Router.IsHistoryMode() {
+		routing = "history"
+	}
+	s.logger.Info("server configuration",
+		"http", describeAddress(httpAddr),
+		"port_from", portSource,
+		"grpc", describeAddress(grpcAddr),
+		"static", static,
+		"routing", routing,
+		"fallback", s.config.FallbackEnabled(),
+		"shutdown_timeout", shutdownTimeout,
+	)
 
 	// Start both servers concurrently
 	var wg sync.WaitGroup
@@ -44,7 +112,7 @@ func (s *Server) Start() error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := s.startHTTPServer(); err != nil {
+		if err := s.startHTTPServer(httpAddr); err != nil {
 			errChan <- fmt.Errorf("HTTP server error: %w", err)
 		}
 	}()
@@ -53,7 +121,7 @@ func (s *Server) Start() error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := s.startGRPCServer(); err != nil {
+		if err := s.startGRPCServer(grpcAddr); err != nil {
 			errChan <- fmt.Errorf("gRPC server error: %w", err)
 		}
 	}()
@@ -69,59 +137,88 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) initializeFunctionRegistry() error {
-	// Discover functions from the server directory
-	serverDir := s.config.Server.Functions
-	if serverDir == "" {
-		serverDir = "src/server"
+	// Register the functions and install the rate limiter once: registering
+	// again resets the interceptors, and a second limiter would charge every
+	// call again
+	s.registryInit.Do(func() {
+		if err := s.registerUserFunctions(); err != nil {
+			s.logger.Warn("failed to initialize user functions", "error", err)
+		}
+		s.rateLimiter, s.rateLimitErr = NewRateLimiter(s.config.Server.RateLimit)
+		s.rateLimiter.Install(s.registry)
+	})
+	if s.rateLimitErr != nil {
+		return s.rateLimitErr
 	}
 
-	if err := s.registry.DiscoverFunctions(serverDir); err != nil {
-		log.Printf("Warning: Failed to discover functions from %s: %v", serverDir, err)
+	if err := ApplyFunctionLimits(s.registry, s.config.Server.Limits); err != nil {
+		return err
 	}
-
-	// Initialize user function registry
-	if err := s.registerUserFunctions(); err != nil {
-		log.Printf("Warning: Failed to initialize user functions: %v", err)
+	if err := ApplyIdempotencyWindow(s.registry, s.config.Server.IdempotencyWindow); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// registerUserFunctions registers the functions compiled into this binary by
+// "golem generate functions"
 func (s *Server) registerUserFunctions() error {
-	// This would load actual user-defined functions from their server directory
-	// For now, just log that we're ready to accept function registrations
-	log.Printf("Function registry ready for user function registration")
+	if err := s.registry.RegisterFromGlobal(); err != nil {
+		return fmt.Errorf("failed to register functions from global registry: %w", err)
+	}
+	s.logger.Info("function registry ready", "functions", len(s.registry.ListFunctions("")))
 	return nil
 }
 
-func (s *Server) startHTTPServer() error {
+func (s *Server) startHTTPServer(addr string) error {
 	mux := http.NewServeMux()
 
 	// Serve static files from build directory
-	fs := http.FileServer(http.Dir(s.config.Output))
-	mux.Handle("/", fs)
-
-	// Health check endpoint
+	assets := s.assets
+	if assets == nil {
+		assets = os.DirFS(s.config.Output)
+	}
+	var static http.Handler = PrecompressedFS(assets, http.FileServerFS(assets))
+	// Deep links to client-side routes must load the page of their app
+	static = FallbackFS(assets, s.config, static)
+	static = ContentSecurityPolicyFS(assets, static)
+	mux.Handle("/", static)
+
+	// Health check endpoints: /health for plain uptime checks, the probes
+	// with JSON details
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	mux.HandleFunc(LivenessPath, s.liveness)
+	mux.HandleFunc(ReadinessPath, s.readiness)
 
 	// API endpoint for function calls (HTTP bridge to gRPC)
 	grpcServer := functions.NewGRPCServer(s.registry)
 	mux.HandleFunc("/api/functions", grpcServer.HTTPHandler())
+	mux.HandleFunc("/api/functions/batch", grpcServer.BatchHandler())
+	mux.HandleFunc("/api/functions/stream", grpcServer.StreamHandler())
+	mux.HandleFunc("/api/functions/ws", grpcServer.WebSocketHandler())
+	mux.HandleFunc("/api/functions/mux", grpcServer.MuxHandler())
+	mux.HandleFunc("/api/functions/", grpcServer.HTTPHandler())
+
+	// API documentation, opt-in since it lists every function
+	if s.config.Server.Docs {
+		mux.HandleFunc("/api/openapi.json", grpcServer.OpenAPIHandler(s.config.ProjectName, s.config.Version))
+		mux.HandleFunc("/api/docs", functions.DocsHandler("/api/openapi.json"))
+	}
+
+	// Prometheus metrics, opt-in like the docs
+	var handler http.Handler = mux
+	if s.config.Server.Metrics {
+		metrics := NewMetrics(s.registry, s.config)
+		mux.Handle(MetricsPath, metrics)
+		handler = metrics.Instrument(mux)
+	}
 
 	// List functions endpoint
 	mux.HandleFunc("/api/functions/list", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "OPTIONS" {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Content-Type", "application/json")
 
 		functions := s.registry.ListFunctions("")
@@ -131,55 +228,111 @@ func (s *Server) startHTTPServer() error {
 		})
 	})
 
-	port := 8080 // Default HTTP port for production
-	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: mux,
+	// Other backends served under a path prefix
+	if err := RegisterProxies(mux, s.config.Server.Proxy, s.logger); err != nil {
+		return err
 	}
 
-	fmt.Printf("🚀 Production HTTP server running at http://localhost:%d\n", port)
-	fmt.Printf("📁 Serving static files from: %s\n", s.config.Output)
-	fmt.Printf("🔗 API endpoints available at: http://localhost:%d/api/\n", port)
-
-	return s.httpServer.ListenAndServe()
-}
-
-func (s *Server) startGRPCServer() error {
-	port := s.config.Server.GRPC.Port
-	if port == 0 {
-		port = 50051 // Default gRPC port
+	// Sessions of the functions, kept under a signed cookie
+	sessions, err := NewSessions(s.config.Session, s.registry, s.logger)
+	if err != nil {
+		return err
 	}
+	handler = sessions.Middleware(handler)
+
+	// Bound what clients may send to the functions
+	handler = LimitBodySize(s.config.Server.MaxBodySize, handler)
+	handler = s.rateLimiter.Handler(handler)
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	// Other origins may only call the API as cors allows
+	handler, err = CORS(s.config.CORS, handler)
 	if err != nil {
-		return fmt.Errorf("failed to create gRPC listener: %w", err)
+		return err
 	}
 
-	s.grpcServer = functions.CreateGRPCServer(s.registry)
+	// Function results and files without a precompressed variant are
+	// compressed on the fly
+	handler = s.logRequests(Compress(CacheControlFS(assets, s.config.Server.Cache, handler)))
+	handler = Trace(handler)
 
-	fmt.Printf("🔧 gRPC server running at localhost:%d\n", port)
-	fmt.Printf("🎯 Available functions: %d\n", len(s.registry.ListFunctions("")))
+	// Pages name the assets to fetch early, first of all so an early hint
+	// goes out before any other handler answers
+	if preload := s.config.Server.Preload; preload == nil || *preload {
+		handler = PreloadFS(assets, s.config, s.config.Server.EarlyHints, handler)
+	}
+	if s.config.Server.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
 
-	return s.grpcServer.Serve(listener)
-}
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+	}
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.httpServer = httpServer
+	s.mu.Unlock()
 
-// Stop gracefully stops both servers
-func (s *Server) Stop() error {
-	var errors []error
+	// Listen first, so the URLs are only printed once they work
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	// HTTPS serves HTTP/2 to the browsers supporting it
+	scheme := "http"
+	if tls := s.config.Server.TLS; tls != nil {
+		scheme = "https"
+	}
+	s.logger.Info("HTTP server running", "url", scheme+"://"+displayAddress(addr), "api", scheme+"://"+displayAddress(addr)+"/api/", "h2c", s.config.Server.H2C)
 
-	if s.httpServer != nil {
-		if err := s.httpServer.Close(); err != nil {
-			errors = append(errors, fmt.Errorf("HTTP server close error: %w", err))
-		}
+	if tls := s.config.Server.TLS; tls != nil {
+		err = httpServer.ServeTLS(listener, tls.CertFile, tls.KeyFile)
+	} else {
+		err = httpServer.Serve(listener)
 	}
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
 
-	if s.grpcServer != nil {
-		s.grpcServer.GracefulStop()
+func (s *Server) startGRPCServer(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to create gRPC listener: %w", err)
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("server shutdown errors: %v", errors)
+	grpcServer, err := NewGRPCServer(s.registry, s.config.Server.GRPC)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		listener.Close()
+		return nil
 	}
+	s.grpcServer = grpcServer
+	s.mu.Unlock()
+	s.grpcReady.Store(true)
 
-	return nil
+	s.logger.Info("gRPC server running", "address", displayAddress(addr), "functions", len(s.registry.ListFunctions("")), "reflection", s.config.Server.GRPC.Reflection)
+
+	return grpcServer.Serve(listener)
+}
+
+// Stop gracefully stops both servers, giving in-flight requests up to
+// server.shutdownTimeout to finish
+func (s *Server) Stop() error {
+	timeout, err := ShutdownTimeout(s.config.Server.ShutdownTimeout)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Shutdown(ctx)
 }
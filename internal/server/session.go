@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// SessionSecretEnv holds the secret signing session cookies, so it can stay
+// out of golem.config.json
+const SessionSecretEnv = "GOLEM_SESSION_SECRET"
+
+// DefaultSessionDir is where the file store keeps sessions without
+// session.dir
+const DefaultSessionDir = ".golem/sessions"
+
+// NewSessions returns the session manager the config describes, keeping
+// sessions in the store set on registry if any. Without a secret, one is
+// generated, so sessions end when the server restarts.
+func NewSessions(cfg config.SessionConfig, registry *functions.Registry, logger *slog.Logger) (*functions.SessionManager, error) {
+	options := functions.SessionOptions{CookieName: cfg.Cookie, Secure: cfg.Secure, Logger: logger}
+
+	if cfg.MaxAge != "" {
+		maxAge, err := time.ParseDuration(cfg.MaxAge)
+		if err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("invalid session.maxAge %q, expected a duration such as 24h", cfg.MaxAge)
+		}
+		options.MaxAge = maxAge
+	}
+
+	switch strings.ToLower(cfg.SameSite) {
+	case "", "lax":
+		options.SameSite = http.SameSiteLaxMode
+	case "strict":
+		options.SameSite = http.SameSiteStrictMode
+	case "none":
+		options.SameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("invalid session.sameSite %q, expected lax, strict or none", cfg.SameSite)
+	}
+
+	secret := cfg.Secret
+	if secret == "" {
+		secret = os.Getenv(SessionSecretEnv)
+	}
+	if secret == "" {
+		generated := make([]byte, 32)
+		rand.Read(generated)
+		options.Secret = generated
+		logger.Info("no session secret, sessions end when the server restarts", "env", SessionSecretEnv)
+	} else {
+		options.Secret = []byte(secret)
+	}
+
+	store := registry.SessionStore()
+	if store == nil {
+		switch cfg.Store {
+		case "", "memory":
+			store = functions.NewMemorySessionStore()
+		case "file":
+			dir := cfg.Dir
+			if dir == "" {
+				dir = DefaultSessionDir
+			}
+			fileStore, err := functions.NewFileSessionStore(dir)
+			if err != nil {
+				return nil, err
+			}
+			store = fileStore
+		default:
+			return nil, fmt.Errorf("invalid session.store %q, expected memory or file, or set a store with functions.SetSessionStore", cfg.Store)
+		}
+	}
+
+	sessions, err := functions.NewSessionManager(store, options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session.secret: %w", err)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long in-flight requests get to finish when
+// the server shuts down, unless server.shutdownTimeout says otherwise
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ShutdownTimeout parses server.shutdownTimeout, DefaultShutdownTimeout
+// when empty
+func ShutdownTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultShutdownTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid server.shutdownTimeout %q, expected a duration such as 30s", value)
+	}
+	return timeout, nil
+}
+
+// Run starts s and serves until SIGINT or SIGTERM, then shuts it down
+// gracefully: the servers stop accepting connections, and in-flight HTTP
+// requests and gRPC calls get server.shutdownTimeout to finish before
+// their connections are closed.
+func (s *Server) Run() error {
+	timeout, err := ShutdownTimeout(s.config.Server.ShutdownTimeout)
+	if err != nil {
+		return err
+	}
+
+	if err := s.initLogger(); err != nil {
+		return err
+	}
+
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 1)
+	go func() { errs <- s.Start() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-signals.Done():
+	}
+
+	s.logger.Info("shutting down, waiting for in-flight requests", "timeout", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	shutdownErr := s.Shutdown(ctx)
+	if err := <-errs; err != nil && shutdownErr == nil {
+		shutdownErr = err
+	}
+	if shutdownErr == nil {
+		s.logger.Info("server stopped")
+	}
+	flushLogs()
+	return shutdownErr
+}
+
+// Shutdown stops both servers gracefully: they stop accepting connections
+// and wait for in-flight HTTP requests and gRPC calls to finish. Once ctx
+// is done, the remaining connections are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	httpServer, grpcServer, tracerProvider := s.httpServer, s.grpcServer, s.tracerProvider
+	s.mu.Unlock()
+
+	// gRPC drains alongside HTTP
+	drained := make(chan struct{})
+	if grpcServer != nil {
+		go func() {
+			grpcServer.GracefulStop()
+			close(drained)
+		}()
+	} else {
+		close(drained)
+	}
+
+	var errors []error
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			httpServer.Close()
+			errors = append(errors, fmt.Errorf("HTTP requests still running were cancelled: %w", err))
+		}
+	}
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		select {
+		case <-drained:
+		default:
+			grpcServer.Stop()
+			<-drained
+			errors = append(errors, fmt.Errorf("gRPC calls still running were cancelled: %w", ctx.Err()))
+		}
+	}
+
+	// The spans of the last requests still go out
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(context.WithoutCancel(ctx)); err != nil {
+			errors = append(errors, fmt.Errorf("failed to export the last spans: %w", err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("server shutdown errors: %v", errors)
+	}
+	return nil
+}
+
+// flushLogs writes out what the process logged before it exits
+func flushLogs() {
+	os.Stdout.Sync()
+	os.Stderr.Sync()
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// SPAFallback wraps a static file handler so that unknown, non-asset paths are
+// answered with dir/index.html. This lets history-mode routes such as
+// /users/42 load the app on a hard refresh or deep link instead of a 404.
+// Paths under one of apps, URL prefixes like "/admin/", are answered with
+// the index.html of that app's directory instead.
+func SPAFallback(dir string, next http.Handler, apps ...string) http.Handler {
+	return SPAFallbackFS(os.DirFS(dir), next, apps...)
+}
+
+// SPAFallbackFS is SPAFallback for the files of fsys, e.g. a build embedded
+// into the binary
+func SPAFallbackFS(fsys fs.FS, next http.Handler, apps ...string) http.Handler {
+	return spaFallback(fsys, next, apps, nil)
+}
+
+// FallbackFS applies the fallback of cfg to next, which serves the files of
+// fsys: with server.fallback enabled, or history routing by default, deep
+// links load the page of their app, except under the excluded paths. next
+// is returned as is when the fallback is off.
+func FallbackFS(fsys fs.FS, cfg *config.Config, next http.Handler) http.Handler {
+	if !cfg.FallbackEnabled() {
+		return next
+	}
+	return spaFallback(fsys, next, cfg.AppPaths(), cfg.Server.Fallback.Exclude)
+}
+
+// spaFallback answers requests for client-side routes with the page of
+// their app, unless their path matches one of exclude
+func spaFallback(fsys fs.FS, next http.Handler, apps, exclude []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !shouldFallback(fsys, r) || excluded(r.URL.Path, exclude) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeFileFS(w, r, fsys, fallbackPage(r.URL.Path, apps))
+	})
+}
+
+// fallbackPage returns the page loading the app a deep link belongs to: the
+// one of the longest app prefix of urlPath, or the main app's
+func fallbackPage(urlPath string, apps []string) string {
+	urlPath = path.Clean("/"+urlPath) + "/"
+	page, longest := "index.html", 0
+	for _, prefix := range apps {
+		prefix = "/" + strings.Trim(prefix, "/") + "/"
+		if prefix != "//" && strings.HasPrefix(urlPath, prefix) && len(prefix) > longest {
+			page, longest = strings.TrimPrefix(prefix, "/")+"index.html", len(prefix)
+		}
+	}
+	return page
+}
+
+// excluded reports whether urlPath is under a prefix of exclude ending in a
+// slash, or matches one of its patterns
+func excluded(urlPath string, exclude []string) bool {
+	urlPath = path.Clean("/" + urlPath)
+	for _, pattern := range exclude {
+		if strings.HasSuffix(pattern, "/") {
+			if strings.HasPrefix(urlPath+"/", pattern) {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, urlPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// assetExtensions are the extensions of the files apps fetch. Missing files
+// with one are answered with 404 rather than a page.
+var assetExtensions = map[string]bool{
+	".wasm": true, ".js": true, ".mjs": true, ".map": true, ".css": true, ".json": true,
+	".html": true, ".htm": true, ".xml": true, ".txt": true, ".webmanifest": true,
+	".ico": true, ".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".avif": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".ogg": true, ".wav": true,
+	".pdf": true, ".zip": true, ".gz": true, ".br": true, ".csv": true,
+}
+
+// shouldFallback reports whether a request targets a client-side route
+// rather than a file that exists in fsys
+func shouldFallback(fsys fs.FS, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	urlPath := path.Clean("/" + r.URL.Path)
+	if urlPath == "/" || strings.HasPrefix(urlPath, "/api/") {
+		return false
+	}
+
+	// Requests for assets (app.wasm, wasm_exec.js, ...) must 404 when
+	// missing, while routes may have dots, as in /users/john.doe
+	if assetExtensions[strings.ToLower(path.Ext(urlPath))] {
+		return false
+	}
+
+	name := strings.TrimPrefix(urlPath, "/")
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return true
+	}
+	if info.IsDir() {
+		_, err := fs.Stat(fsys, path.Join(name, "index.html"))
+		return err != nil
+	}
+	return false
+}
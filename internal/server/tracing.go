@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracerProvider returns a provider exporting the spans of cfg's
+// server.tracing to its OTLP collector in batches
+func NewTracerProvider(ctx context.Context, cfg *config.Config) (*sdktrace.TracerProvider, error) {
+	tracing := cfg.Server.Tracing
+
+	ratio := 1.0
+	if tracing.SampleRatio != nil {
+		ratio = *tracing.SampleRatio
+		if ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid server.tracing.sampleRatio %v, expected a share between 0 and 1", ratio)
+		}
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(tracing.Protocol) {
+	case "", "http/protobuf", "http":
+		var options []otlptracehttp.Option
+		if tracing.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(tracing.Endpoint))
+		}
+		if len(tracing.Headers) > 0 {
+			options = append(options, otlptracehttp.WithHeaders(tracing.Headers))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case "grpc":
+		var options []otlptracegrpc.Option
+		if tracing.Endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpointURL(tracing.Endpoint))
+		}
+		if len(tracing.Headers) > 0 {
+			options = append(options, otlptracegrpc.WithHeaders(tracing.Headers))
+		}
+		exporter, err = otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("invalid server.tracing.protocol %q, expected http/protobuf or grpc", tracing.Protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	name := tracing.ServiceName
+	if name == "" {
+		name = cfg.ProjectName
+	}
+	attributes := []attribute.KeyValue{attribute.String("service.name", name)}
+	if cfg.Version != "" {
+		attributes = append(attributes, attribute.String("service.version", cfg.Version))
+	}
+	res, err := resource.New(ctx, resource.WithFromEnv(), resource.WithTelemetrySDK(), resource.WithAttributes(attributes...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the service for tracing: %w", err)
+	}
+
+	// Clients decide whether the traces they started are recorded
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	), nil
+}
+
+// initTracing propagates W3C trace context through the server and, with
+// server.tracing enabled, exports the spans of requests and function calls
+func (s *Server) initTracing() error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !s.config.Server.Tracing.Enabled {
+		return nil
+	}
+
+	provider, err := NewTracerProvider(context.Background(), s.config)
+	if err != nil {
+		return err
+	}
+	otel.SetTracerProvider(provider)
+	s.mu.Lock()
+	s.tracerProvider = provider
+	s.mu.Unlock()
+	s.logger.Info("tracing enabled", "protocol", orDefault(s.config.Server.Tracing.Protocol, "http/protobuf"), "endpoint", orDefault(s.config.Server.Tracing.Endpoint, "default"))
+	return nil
+}
+
+// Trace records every request to next in a server span named after its
+// route, continuing the trace of the traceparent header it came with.
+// Function calls and proxied requests of the request join its trace.
+func Trace(next http.Handler) http.Handler {
+	tracer := otel.Tracer(functions.TracerName)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("client.address", clientIP(r, false)),
+				attribute.String("user_agent.original", r.UserAgent()),
+			))
+		defer span.End()
+
+		lw := &logWriter{ResponseWriter: w}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(lw, r)
+
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if r.Pattern != "" {
+			span.SetName(r.Method + " " + r.Pattern)
+			span.SetAttributes(attribute.String("http.route", r.Pattern))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
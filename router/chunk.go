@@ -0,0 +1,112 @@
+//go:build js && wasm
+
+package router
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// chunksGlobal is the JS object holding the render function of every loaded
+// chunk, keyed by chunk name
+const chunksGlobal = "golemChunks"
+
+// chunkURLsGlobal maps chunk names to their content-hashed file names. The
+// production page defines it; without it chunks load from <name>.wasm.
+const chunkURLsGlobal = "golemChunkURLs"
+
+// chunkIntegrityGlobal maps chunk names to the integrity values the
+// browser checks them against, defined by the production page
+const chunkIntegrityGlobal = "golemChunkIntegrity"
+
+// ServeChunk runs the main function of a chunk: it makes r render the
+// routes of the chunk whenever the app's router navigates to a route with
+// Chunk set to name, and then blocks. r is not started; the app's router
+// owns the URL and calls r with each path of the chunk.
+func ServeChunk(name string, r *Router) {
+	chunks := js.Global().Get(chunksGlobal)
+	if chunks.IsUndefined() {
+		chunks = js.Global().Get("Object").New()
+		js.Global().Set(chunksGlobal, chunks)
+	}
+
+	chunks.Set(name, js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if err := r.navigate(args[0].String(), nil, historyNone); err != nil {
+			return err.Error()
+		}
+		return nil
+	}))
+	select {}
+}
+
+// renderChunk renders a route served by a chunk, loading the chunk's
+// WebAssembly module on its first visit. The route's Component, if any,
+// is shown while the module loads. It reports whether the chunk rendered
+// the route, false while the module loads.
+func (r *Router) renderChunk(ctx *RouteContext) bool {
+	name := ctx.To.Chunk
+	if chunks := js.Global().Get(chunksGlobal); chunks.Truthy() && chunks.Get(name).Truthy() {
+		if result := chunks.Get(name).Invoke(ctx.Path); result.Truthy() {
+			r.renderError(fmt.Errorf("chunk %s failed to render %s: %s", name, ctx.Path, result.String()))
+		}
+		return true
+	}
+
+	if ctx.To.Component != nil {
+		r.renderComponent(ctx.To.Component(ctx.Params))
+	}
+	if r.chunksLoading[name] {
+		return false // the current route is rendered once the module is running
+	}
+	r.chunksLoading[name] = true
+
+	url := name + ".wasm"
+	if urls := js.Global().Get(chunkURLsGlobal); urls.Truthy() && urls.Get(name).Truthy() {
+		url = urls.Get(name).String()
+	}
+
+	goRuntime := js.Global().Get("Go").New()
+	var loaded, failed js.Func
+	release := func() {
+		loaded.Release()
+		failed.Release()
+	}
+	loaded = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		defer release()
+		delete(r.chunksLoading, name)
+
+		// The chunk's main registers its render function before it blocks,
+		// so the chunk can render as soon as run returns
+		goRuntime.Call("run", args[0].Get("instance"))
+		if r.currentRoute != nil && r.currentRoute.Chunk == name {
+			r.renderChunk(&RouteContext{Router: r, To: r.currentRoute, Path: r.getCurrentPath(), Params: r.currentParams})
+			if fragment := r.navigations.DeferredFragment(); fragment != "" {
+				r.scrollToFragment(fragment)
+			}
+		}
+		return nil
+	})
+	failed = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		defer release()
+		delete(r.chunksLoading, name)
+		r.renderError(fmt.Errorf("failed to load chunk %s from %s: %s", name, url, args[0].Call("toString").String()))
+		return nil
+	})
+
+	options := js.Global().Get("Object").New()
+	if integrity := js.Global().Get(chunkIntegrityGlobal); integrity.Truthy() && integrity.Get(name).Truthy() {
+		options.Set("integrity", integrity.Get(name))
+	}
+	js.Global().Get("WebAssembly").Call("instantiateStreaming", js.Global().Call("fetch", url, options), goRuntime.Get("importObject")).
+		Call("then", loaded, failed)
+	return false
+}
+
+// renderError shows an error through the error handler, or logs it
+func (r *Router) renderError(err error) {
+	if r.errorHandler != nil {
+		r.renderComponent(r.errorHandler(err))
+		return
+	}
+	fmt.Println(err)
+}
@@ -0,0 +1,251 @@
+package router
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// routeParamPattern matches :param segments in a route path
+var routeParamPattern = regexp.MustCompile(`:([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// ParamEnumerator returns every set of params a dynamic route should be
+// expanded to, e.g. one entry per blog post for /posts/:slug
+type ParamEnumerator func() ([]map[string]string, error)
+
+// SitemapOptions configures sitemap and route manifest generation
+type SitemapOptions struct {
+	// BaseURL is the absolute origin of the deployed app, e.g. https://example.com
+	BaseURL string
+
+	// Enumerators expand dynamic routes, keyed by route path (e.g. "/posts/:slug")
+	Enumerators map[string]ParamEnumerator
+}
+
+// RouteManifest describes the registered route table
+type RouteManifest struct {
+	Mode   string          `json:"mode"`
+	Base   string          `json:"base,omitempty"`
+	Routes []ManifestRoute `json:"routes"`
+}
+
+// ManifestRoute describes a single route and its concrete URLs
+type ManifestRoute struct {
+	Path     string                 `json:"path"`
+	Name     string                 `json:"name,omitempty"`
+	Params   []string               `json:"params,omitempty"`
+	Redirect string                 `json:"redirect,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	URLs     []string               `json:"urls,omitempty"`
+}
+
+// Manifest walks the route table and builds a route manifest. Static routes
+// resolve to themselves; dynamic routes are expanded through enumerators.
+func (r *Router) Manifest(opts SitemapOptions) (*RouteManifest, error) {
+	manifest := &RouteManifest{
+		Mode:   "hash",
+		Base:   r.baseURL,
+		Routes: make([]ManifestRoute, 0, len(r.routes)),
+	}
+	if r.mode == HistoryMode {
+		manifest.Mode = "history"
+	}
+
+	for _, route := range r.routes {
+		entry := ManifestRoute{
+			Path:     route.Path,
+			Name:     route.Name,
+			Params:   routeParams(route.Path),
+			Redirect: route.Redirect,
+			Meta:     route.Meta,
+		}
+
+		urls, err := r.expandRoute(route, entry.Params, opts)
+		if err != nil {
+			return nil, err
+		}
+		entry.URLs = urls
+
+		manifest.Routes = append(manifest.Routes, entry)
+	}
+
+	return manifest, nil
+}
+
+// expandRoute lists the concrete paths a route can be reached at
+func (r *Router) expandRoute(route *Route, params []string, opts SitemapOptions) ([]string, error) {
+	paramSets, err := expandParams(route, params, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(paramSets))
+	for _, set := range paramSets {
+		urls = append(urls, fillEscapedParams(route.Path, set))
+	}
+	return urls, nil
+}
+
+// fillEscapedParams substitutes :param segments with the given values
+// escaped for a URL path, so a value with "/", "?" or "#" stays one segment
+func fillEscapedParams(path string, params map[string]string) string {
+	escaped := make(map[string]string, len(params))
+	for name, value := range params {
+		escaped[name] = url.PathEscape(value)
+	}
+	return fillParams(path, escaped)
+}
+
+// expandParams lists the params of each concrete path a route can be
+// reached at: one empty set for a static route, the enumerated sets for a
+// dynamic one, and none for wildcards or dynamic routes without enumerator
+func expandParams(route *Route, params []string, opts SitemapOptions) ([]map[string]string, error) {
+	if strings.Contains(route.Path, "*") {
+		return nil, nil
+	}
+	if len(params) == 0 {
+		return []map[string]string{{}}, nil
+	}
+
+	enumerate, ok := opts.Enumerators[route.Path]
+	if !ok {
+		return nil, nil
+	}
+
+	paramSets, err := enumerate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to enumerate params for %s: %w", route.Path, err)
+	}
+	for _, set := range paramSets {
+		if path := fillParams(route.Path, set); routeParamPattern.MatchString(path) {
+			return nil, fmt.Errorf("enumerator for %s left params unset in %s", route.Path, path)
+		}
+	}
+	return paramSets, nil
+}
+
+// sitemapURLSet is the root element of sitemap.xml
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+// Sitemap renders sitemap.xml for all reachable routes. Redirects and routes
+// with Meta["sitemap"] == false are skipped; Meta["changefreq"] and
+// Meta["priority"] are copied into the entry.
+func (r *Router) Sitemap(opts SitemapOptions) ([]byte, error) {
+	manifest, err := r.Manifest(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	urlSet := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	seen := make(map[string]bool)
+
+	for _, route := range manifest.Routes {
+		if route.Redirect != "" || route.Meta["sitemap"] == false {
+			continue
+		}
+
+		for _, path := range route.URLs {
+			loc := r.absoluteURL(opts.BaseURL, path)
+			if seen[loc] {
+				continue
+			}
+			seen[loc] = true
+
+			entry := sitemapURL{Loc: loc}
+			if freq, ok := route.Meta["changefreq"].(string); ok {
+				entry.ChangeFreq = freq
+			}
+			if priority, ok := route.Meta["priority"]; ok {
+				entry.Priority = fmt.Sprintf("%v", priority)
+			}
+			urlSet.URLs = append(urlSet.URLs, entry)
+		}
+	}
+
+	sort.Slice(urlSet.URLs, func(i, j int) bool { return urlSet.URLs[i].Loc < urlSet.URLs[j].Loc })
+
+	data, err := xml.MarshalIndent(urlSet, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// WriteRouteFiles writes sitemap.xml and routes.json into dir, and
+// prerender.json when the build prerenders the routes
+func (r *Router) WriteRouteFiles(dir string, opts SitemapOptions) error {
+	manifest, err := r.Manifest(opts)
+	if err != nil {
+		return err
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal route manifest: %w", err)
+	}
+
+	sitemap, err := r.Sitemap(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "routes.json"), manifestData, 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sitemap.xml"), sitemap, 0644); err != nil {
+		return err
+	}
+
+	// golem build --static asks for the rendered pages as well
+	if os.Getenv(PrerenderEnv) != "" {
+		return r.writePrerender(dir, opts)
+	}
+	return nil
+}
+
+// absoluteURL joins the site origin, base path and route path for the router mode
+func (r *Router) absoluteURL(origin, path string) string {
+	origin = strings.TrimSuffix(origin, "/")
+	if r.mode == HistoryMode {
+		return origin + r.baseURL + path
+	}
+	return origin + "/#" + path
+}
+
+// routeParams returns the :param names of a route path in order
+func routeParams(path string) []string {
+	var params []string
+	for _, match := range routeParamPattern.FindAllStringSubmatch(path, -1) {
+		params = append(params, match[1])
+	}
+	return params
+}
+
+// fillParams substitutes :param segments with the given values
+func fillParams(path string, params map[string]string) string {
+	return routeParamPattern.ReplaceAllStringFunc(path, func(segment string) string {
+		if value, ok := params[segment[1:]]; ok {
+			return value
+		}
+		return segment
+	})
+}
@@ -0,0 +1,73 @@
+package router
+
+// Navigations sequences the navigations of a router. Each runs its
+// middleware chain and commits once the whole chain has called next, which
+// may happen asynchronously, unless a newer navigation started meanwhile.
+type Navigations struct {
+	current     int    // incremented per navigation to drop stale async commits
+	fragment    string // #fragment waiting for the chunk of its route to render
+	fragmentFor int    // navigation of fragment
+}
+
+// Run starts a navigation running chain in order with ctx. When the last
+// middleware calls next, commit is called, with late set when Run had
+// already returned, unless a newer navigation started since. Run returns
+// the error of commit, or ErrNavigationIncomplete when the chain did not
+// complete before the middleware returned.
+func (n *Navigations) Run(ctx *RouteContext, chain []Middleware, commit func(late bool) error) error {
+	n.current++
+	id := n.current
+	returned, committed := false, false
+	var commitErr error
+
+	var step func(index int) func()
+	step = func(index int) func() {
+		called := false
+		return func() {
+			// Ignore repeated next() calls from the same middleware
+			if called {
+				return
+			}
+			called = true
+
+			if index < len(chain) {
+				chain[index](ctx, step(index+1))
+				return
+			}
+			if id != n.current {
+				return // superseded by a newer navigation
+			}
+			if returned {
+				commit(true)
+				return
+			}
+			committed = true
+			commitErr = commit(false)
+		}
+	}
+	step(0)()
+	returned = true
+
+	if !committed {
+		return ErrNavigationIncomplete
+	}
+	return commitErr
+}
+
+// DeferFragment keeps the #fragment of the navigation committing until the
+// chunk rendering its route has loaded, as the element it names does not
+// exist before
+func (n *Navigations) DeferFragment(fragment string) {
+	n.fragment, n.fragmentFor = fragment, n.current
+}
+
+// DeferredFragment returns the #fragment deferred and forgets it. It
+// returns "" when there is none or a newer navigation started since.
+func (n *Navigations) DeferredFragment() string {
+	fragment := n.fragment
+	n.fragment = ""
+	if n.fragmentFor != n.current {
+		return ""
+	}
+	return fragment
+}
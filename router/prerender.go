@@ -0,0 +1,69 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Nu11ified/golem/dom"
+)
+
+// PrerenderEnv is set by golem build --static when it runs the route
+// generator, which then also writes PrerenderFile
+const PrerenderEnv = "GOLEM_PRERENDER"
+
+// PrerenderFile holds the markup of every route URL, keyed by URL path
+const PrerenderFile = "prerender.json"
+
+// Prerender renders the component of every route at each of its URLs, as
+// listed in the route manifest. Redirects, wildcard routes and dynamic
+// routes without enumerator are skipped.
+func (r *Router) Prerender(opts SitemapOptions) (map[string]string, error) {
+	pages := make(map[string]string)
+	for _, route := range r.routes {
+		if route.Component == nil || route.Redirect != "" {
+			continue
+		}
+
+		paramSets, err := expandParams(route, routeParams(route.Path), opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, params := range paramSets {
+			path := fillParams(route.Path, params)
+			if _, seen := pages[path]; seen {
+				continue // the first matching route wins, as in the browser
+			}
+			markup, err := renderRoute(route, params)
+			if err != nil {
+				return nil, fmt.Errorf("failed to prerender %s: %w", path, err)
+			}
+			pages[path] = markup
+		}
+	}
+	return pages, nil
+}
+
+// renderRoute renders a route's component, turning a panic into an error
+func renderRoute(route *Route, params map[string]string) (markup string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("component panicked: %v", recovered)
+		}
+	}()
+	return dom.RenderHTML(route.Component(params)), nil
+}
+
+// writePrerender writes the prerendered pages into dir
+func (r *Router) writePrerender(dir string, opts SitemapOptions) error {
+	pages, err := r.Prerender(opts)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(pages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal prerendered pages: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, PrerenderFile), data, 0644)
+}
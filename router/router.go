@@ -3,12 +3,17 @@
 package router
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net/url"
 	"regexp"
 	"strings"
 	"syscall/js"
 
 	"github.com/Nu11ified/golem/dom"
+	"github.com/Nu11ified/golem/state"
 )
 
 // Route represents a single route
@@ -22,23 +27,71 @@ type Route struct {
 	Redirect   string
 	Regex      *regexp.Regexp
 	ParamNames []string
+	Middleware []Middleware
+
+	// ReuseComponent keeps the rendered component when this route re-matches
+	// with different params or query; changes are delivered through
+	// ParamsObservable and QueryObservable instead of a re-render
+	ReuseComponent bool
+
+	// Chunk names the WebAssembly chunk rendering this route, built from
+	// an entry of build.chunks and loaded on the first visit. Component, if
+	// set, is shown while the chunk loads.
+	Chunk string
 }
 
 // Guard represents a route guard
 type Guard func(to *Route, from *Route, params map[string]string) bool
 
+// RouteContext describes a navigation in progress
+type RouteContext struct {
+	Router   *Router
+	To       *Route
+	From     *Route
+	Path     string
+	Params   map[string]string
+	Query    map[string]string
+	Fragment string
+	State    interface{}
+}
+
+// Middleware wraps a navigation. Calling next continues the chain and,
+// after the last middleware, commits the navigation. Returning without
+// calling next aborts it. next may also be called later from a goroutine
+// for asynchronous checks such as fetching a session or feature flags.
+type Middleware func(ctx *RouteContext, next func())
+
+// ErrNavigationBlocked is returned when a guard rejects a navigation
+var ErrNavigationBlocked = errors.New("navigation blocked by guard")
+
+// ErrNavigationIncomplete is returned when a middleware returned without
+// calling next. The navigation is aborted, unless the middleware calls next
+// later from a goroutine: it is committed then, and errors committing it go
+// to the OnError handler.
+var ErrNavigationIncomplete = errors.New("navigation not completed by middleware")
+
 // Router manages client-side routing
 type Router struct {
 	routes          []*Route
 	currentRoute    *Route
+	currentPath     string // path of the current route, with its query and fragment
 	currentParams   map[string]string
+	currentQuery    map[string]string
+	currentState    interface{}
+	paramsObs       *state.Observable[map[string]string]
+	queryObs        *state.Observable[map[string]string]
 	beforeEach      []Guard
 	afterEach       []func(*Route, *Route)
+	middleware      []Middleware
+	navigations     Navigations
 	notFoundHandler func() *dom.Element
 	errorHandler    func(error) *dom.Element
 	baseURL         string
 	mode            RouterMode
 	container       string // CSS selector for router outlet
+	scrollOffset    int    // pixels subtracted when scrolling to a fragment
+	chunksLoading   map[string]bool
+	started         bool // Start was called, so the router owns the URL
 }
 
 // RouterMode defines routing modes
@@ -49,15 +102,32 @@ const (
 	HistoryMode                   // /path (requires server support)
 )
 
+// historyAction describes how a navigation updates the browser history
+type historyAction int
+
+const (
+	historyPush    historyAction = iota // add a new history entry
+	historyReplace                      // overwrite the current history entry
+	historyNone                         // browser already moved (back/forward, manual URL edit)
+)
+
+// historyStateKey is the property of history.state holding the serialized payload
+const historyStateKey = "golemState"
+
 // NewRouter creates a new router instance
 func NewRouter() *Router {
 	return &Router{
 		routes:        make([]*Route, 0),
 		currentParams: make(map[string]string),
+		currentQuery:  make(map[string]string),
+		paramsObs:     state.NewObservable(make(map[string]string)),
+		queryObs:      state.NewObservable(make(map[string]string)),
 		beforeEach:    make([]Guard, 0),
 		afterEach:     make([]func(*Route, *Route), 0),
+		middleware:    make([]Middleware, 0),
 		mode:          HashMode,
 		container:     "#router-outlet",
+		chunksLoading: make(map[string]bool),
 	}
 }
 
@@ -73,6 +143,13 @@ func (r *Router) SetContainer(selector string) *Router {
 	return r
 }
 
+// SetScrollOffset sets the offset applied when scrolling to a #fragment,
+// e.g. the height of a fixed header
+func (r *Router) SetScrollOffset(offset int) *Router {
+	r.scrollOffset = offset
+	return r
+}
+
 // SetBaseURL sets the base URL for history mode
 func (r *Router) SetBaseURL(baseURL string) *Router {
 	r.baseURL = strings.TrimSuffix(baseURL, "/")
@@ -113,6 +190,19 @@ func (r *Router) RouteGroup(prefix string, guards []Guard, routes []*Route) *Rou
 	return r
 }
 
+// MiddlewareGroup creates a route group whose routes run the given
+// middleware before their own
+func (r *Router) MiddlewareGroup(prefix string, middleware []Middleware, routes []*Route) *Router {
+	for _, route := range routes {
+		route.Path = prefix + route.Path
+		chain := make([]Middleware, 0, len(middleware)+len(route.Middleware))
+		chain = append(chain, middleware...)
+		route.Middleware = append(chain, route.Middleware...)
+		r.AddRoute(route)
+	}
+	return r
+}
+
 // compileRoute compiles route path to regex
 func (r *Router) compileRoute(route *Route) {
 	if route.Path == "" {
@@ -148,6 +238,13 @@ func (r *Router) BeforeEach(guard Guard) *Router {
 	return r
 }
 
+// Use adds global middleware that runs for every navigation, before any
+// route-specific middleware
+func (r *Router) Use(middleware ...Middleware) *Router {
+	r.middleware = append(r.middleware, middleware...)
+	return r
+}
+
 // AfterEach adds a global after hook
 func (r *Router) AfterEach(hook func(*Route, *Route)) *Router {
 	r.afterEach = append(r.afterEach, hook)
@@ -168,6 +265,8 @@ func (r *Router) OnError(handler func(error) *dom.Element) *Router {
 
 // Start initializes the router
 func (r *Router) Start() {
+	r.started = true
+
 	// Listen for browser navigation events
 	r.setupEventListeners()
 
@@ -205,7 +304,7 @@ func (r *Router) getCurrentPath() string {
 		if r.baseURL != "" {
 			pathname = strings.TrimPrefix(pathname, r.baseURL)
 		}
-		return pathname
+		return pathname + location.Get("search").String() + location.Get("hash").String()
 	} else {
 		hash := location.Get("hash").String()
 		if hash == "" {
@@ -218,12 +317,18 @@ func (r *Router) getCurrentPath() string {
 // handleCurrentLocation handles the current location
 func (r *Router) handleCurrentLocation() {
 	path := r.getCurrentPath()
-	r.Navigate(path)
+	r.navigate(path, r.readHistoryState(), historyNone)
 }
 
 // Navigate navigates to a path
 func (r *Router) Navigate(path string) error {
-	route, params := r.matchRoute(path)
+	return r.navigate(path, nil, historyPush)
+}
+
+// navigate resolves a path, runs guards, updates history and renders the matched route
+func (r *Router) navigate(path string, state interface{}, action historyAction) error {
+	routePath, query, fragment := splitLocation(path)
+	route, params := r.matchRoute(routePath)
 
 	if route == nil {
 		if r.notFoundHandler != nil {
@@ -235,28 +340,105 @@ func (r *Router) Navigate(path string) error {
 
 	// Check guards
 	if !r.checkGuards(route, r.currentRoute, params) {
-		return fmt.Errorf("navigation blocked by guard")
+		r.restoreURL(action)
+		return ErrNavigationBlocked
 	}
 
 	// Handle redirect
 	if route.Redirect != "" {
-		return r.Navigate(route.Redirect)
+		return r.navigate(route.Redirect, state, action)
 	}
 
+	ctx := &RouteContext{
+		Router:   r,
+		To:       route,
+		From:     r.currentRoute,
+		Path:     path,
+		Params:   params,
+		Query:    query,
+		Fragment: fragment,
+		State:    state,
+	}
+
+	// Run global then route middleware; the navigation is committed once
+	// the whole chain has called next, which may happen asynchronously
+	chain := make([]Middleware, 0, len(r.middleware)+len(route.Middleware))
+	chain = append(chain, r.middleware...)
+	chain = append(chain, route.Middleware...)
+	restored := false
+	err := r.navigations.Run(ctx, chain, func(late bool) error {
+		if !late {
+			return r.commit(ctx, action)
+		}
+
+		// navigate already returned, so nobody gets the error. The URL it
+		// restored moves to the route again.
+		if restored {
+			action = historyPush
+		}
+		if err := r.commit(ctx, action); err != nil {
+			r.renderError(err)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrNavigationIncomplete) {
+		restored = r.restoreURL(action)
+	}
+	return err
+}
+
+// restoreURL puts back the URL of the current route after the browser moved
+// to one whose navigation did not commit, as on back/forward. It reports
+// whether it did.
+func (r *Router) restoreURL(action historyAction) bool {
+	if action != historyNone || !r.started || r.currentRoute == nil {
+		return false
+	}
+	return r.updateURL(r.currentPath, r.currentState, historyReplace) == nil
+}
+
+// commit updates the URL and router state, renders the route and runs after hooks
+func (r *Router) commit(ctx *RouteContext, action historyAction) error {
+	route := ctx.To
+
 	// Update browser URL
-	r.updateURL(path)
+	if err := r.updateURL(ctx.Path, ctx.State, action); err != nil {
+		return err
+	}
 
 	// Update current route
 	previousRoute := r.currentRoute
 	r.currentRoute = route
-	r.currentParams = params
-
-	// Render component
-	if route.Component != nil {
-		component := route.Component(params)
+	r.currentPath = ctx.Path
+	r.currentParams = ctx.Params
+	r.currentQuery = ctx.Query
+	r.currentState = ctx.State
+
+	// Render component, unless it opted into staying mounted across re-matches
+	reused := route.ReuseComponent && route == previousRoute
+	rendered := true
+	if route.Chunk != "" && !reused {
+		rendered = r.renderChunk(ctx)
+	} else if route.Component != nil && !reused {
+		component := route.Component(ctx.Params)
 		r.renderComponent(component)
 	}
 
+	// Notify subscribers only when values actually changed
+	if !maps.Equal(r.paramsObs.Get(), ctx.Params) {
+		r.paramsObs.Set(ctx.Params)
+	}
+	if !maps.Equal(r.queryObs.Get(), ctx.Query) {
+		r.queryObs.Set(ctx.Query)
+	}
+
+	// A chunk still loading scrolls to the fragment once it has rendered
+	if !rendered {
+		r.navigations.DeferFragment(ctx.Fragment)
+	} else if ctx.Fragment != "" {
+		r.scrollToFragment(ctx.Fragment)
+	}
+
 	// Run after hooks
 	for _, hook := range r.afterEach {
 		hook(route, previousRoute)
@@ -265,6 +447,49 @@ func (r *Router) Navigate(path string) error {
 	return nil
 }
 
+// splitLocation separates the route path from its query string and #fragment
+func splitLocation(path string) (string, map[string]string, string) {
+	query := make(map[string]string)
+
+	path, fragment, _ := strings.Cut(path, "#")
+	routePath, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path, query, fragment
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		fmt.Printf("Ignoring malformed query string %q: %v\n", rawQuery, err)
+		return routePath, query, fragment
+	}
+	for key := range values {
+		query[key] = values.Get(key)
+	}
+	return routePath, query, fragment
+}
+
+// scrollToFragment scrolls the element with the given id into view,
+// leaving room for the configured scroll offset
+func (r *Router) scrollToFragment(fragment string) {
+	id, err := url.PathUnescape(fragment)
+	if err != nil {
+		id = fragment
+	}
+
+	target := js.Global().Get("document").Call("getElementById", id)
+	if target.IsNull() || target.IsUndefined() {
+		return
+	}
+
+	window := js.Global().Get("window")
+	top := target.Call("getBoundingClientRect").Get("top").Float() +
+		window.Get("scrollY").Float() - float64(r.scrollOffset)
+
+	options := js.Global().Get("Object").New()
+	options.Set("top", top)
+	window.Call("scrollTo", options)
+}
+
 // matchRoute finds a matching route for the path
 func (r *Router) matchRoute(path string) (*Route, map[string]string) {
 	for _, route := range r.routes {
@@ -309,16 +534,64 @@ func (r *Router) checkGuards(to *Route, from *Route, params map[string]string) b
 	return true
 }
 
-// updateURL updates the browser URL
-func (r *Router) updateURL(path string) {
-	history := js.Global().Get("history")
+// updateURL updates the browser URL and stores the navigation state in history.state
+func (r *Router) updateURL(path string, state interface{}, action historyAction) error {
+	if action == historyNone {
+		return nil
+	}
+
+	historyState, err := encodeHistoryState(state)
+	if err != nil {
+		return err
+	}
 
+	url := "#" + path
 	if r.mode == HistoryMode {
-		url := r.baseURL + path
-		history.Call("pushState", nil, "", url)
+		url = r.baseURL + path
+	}
+
+	history := js.Global().Get("history")
+	if action == historyReplace {
+		history.Call("replaceState", historyState, "", url)
 	} else {
-		js.Global().Get("location").Set("hash", "#"+path)
+		history.Call("pushState", historyState, "", url)
 	}
+
+	return nil
+}
+
+// encodeHistoryState serializes a state payload into a value accepted by history.pushState
+func encodeHistoryState(state interface{}) (interface{}, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("history state is not JSON-serializable: %w", err)
+	}
+
+	return map[string]interface{}{historyStateKey: string(data)}, nil
+}
+
+// readHistoryState decodes the payload stored in history.state, if any
+func (r *Router) readHistoryState() interface{} {
+	historyState := js.Global().Get("history").Get("state")
+	if historyState.Type() != js.TypeObject || historyState.IsNull() {
+		return nil
+	}
+
+	raw := historyState.Get(historyStateKey)
+	if raw.Type() != js.TypeString {
+		return nil
+	}
+
+	var state interface{}
+	if err := json.Unmarshal([]byte(raw.String()), &state); err != nil {
+		fmt.Printf("Ignoring invalid history state: %v\n", err)
+		return nil
+	}
+	return state
 }
 
 // renderComponent renders a component in the router outlet
@@ -344,44 +617,23 @@ func (r *Router) renderComponent(component *dom.Element) {
 	outlet.Call("appendChild", renderedElement)
 }
 
-// Push navigates to a new route
-func (r *Router) Push(path string) error {
-	return r.Navigate(path)
+// Push navigates to a new route. An optional JSON-serializable state payload
+// is stored in history.state and restored when the entry is revisited.
+func (r *Router) Push(path string, state ...interface{}) error {
+	return r.navigate(path, firstState(state), historyPush)
 }
 
-// Replace replaces the current route
-func (r *Router) Replace(path string) error {
-	route, params := r.matchRoute(path)
-
-	if route == nil {
-		return fmt.Errorf("route not found: %s", path)
-	}
-
-	// Check guards
-	if !r.checkGuards(route, r.currentRoute, params) {
-		return fmt.Errorf("navigation blocked by guard")
-	}
-
-	// Update browser URL (replace instead of push)
-	history := js.Global().Get("history")
-	if r.mode == HistoryMode {
-		url := r.baseURL + path
-		history.Call("replaceState", nil, "", url)
-	} else {
-		js.Global().Get("location").Call("replace", "#"+path)
-	}
-
-	// Update current route
-	r.currentRoute = route
-	r.currentParams = params
+// Replace replaces the current route, optionally with a new state payload
+func (r *Router) Replace(path string, state ...interface{}) error {
+	return r.navigate(path, firstState(state), historyReplace)
+}
 
-	// Render component
-	if route.Component != nil {
-		component := route.Component(params)
-		r.renderComponent(component)
+// firstState returns the optional state argument of Push/Replace
+func firstState(state []interface{}) interface{} {
+	if len(state) == 0 {
+		return nil
 	}
-
-	return nil
+	return state[0]
 }
 
 // Go navigates back/forward in history
@@ -410,15 +662,46 @@ func (r *Router) GetCurrentParams() map[string]string {
 	return r.currentParams
 }
 
+// GetCurrentQuery returns the query parameters of the current URL
+func (r *Router) GetCurrentQuery() map[string]string {
+	return r.currentQuery
+}
+
+// ParamsObservable returns an observable of the current route parameters.
+// Combined with Route.ReuseComponent, components can react to /users/1 ->
+// /users/2 without being re-created.
+func (r *Router) ParamsObservable() *state.Observable[map[string]string] {
+	return r.paramsObs
+}
+
+// QueryObservable returns an observable of the current query parameters
+func (r *Router) QueryObservable() *state.Observable[map[string]string] {
+	return r.queryObs
+}
+
+// GetCurrentState returns the history state payload of the current route
+func (r *Router) GetCurrentState() interface{} {
+	return r.currentState
+}
+
+// DecodeState unmarshals the current history state payload into target
+func (r *Router) DecodeState(target interface{}) error {
+	if r.currentState == nil {
+		return fmt.Errorf("no history state for current route")
+	}
+
+	data, err := json.Marshal(r.currentState)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
 // GenerateURL generates a URL for a named route
 func (r *Router) GenerateURL(routeName string, params map[string]string) string {
 	for _, route := range r.routes {
 		if route.Name == routeName {
-			path := route.Path
-			for paramName, paramValue := range params {
-				path = strings.Replace(path, ":"+paramName, paramValue, -1)
-			}
-			return path
+			return fillParams(route.Path, params)
 		}
 	}
 	return ""
@@ -508,6 +791,13 @@ func (t *Transition) Execute(to *Route, from *Route, callback func()) {
 	next()
 }
 
+// Middleware adapts the transition hooks into a single route middleware
+func (t *Transition) Middleware() Middleware {
+	return func(ctx *RouteContext, next func()) {
+		t.Execute(ctx.To, ctx.From, next)
+	}
+}
+
 // Common route guards
 type Guards struct{}
 
@@ -557,8 +847,8 @@ func Navigate(path string) error {
 	return DefaultRouter.Navigate(path)
 }
 
-func Push(path string) error {
-	return DefaultRouter.Push(path)
+func Push(path string, state ...interface{}) error {
+	return DefaultRouter.Push(path, state...)
 }
 
 func Back() {
@@ -3,10 +3,13 @@
 package router
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/Nu11ified/golem/dom"
+	"github.com/Nu11ified/golem/state"
 )
 
 // Stub implementations for non-WASM builds
@@ -20,21 +23,50 @@ type Route struct {
 	Redirect   string
 	Regex      *regexp.Regexp
 	ParamNames []string
+	Middleware []Middleware
+
+	ReuseComponent bool
+	Chunk          string
 }
 
 type Guard func(to *Route, from *Route, params map[string]string) bool
 
+type RouteContext struct {
+	Router   *Router
+	To       *Route
+	From     *Route
+	Path     string
+	Params   map[string]string
+	Query    map[string]string
+	Fragment string
+	State    interface{}
+}
+
+type Middleware func(ctx *RouteContext, next func())
+
+var (
+	ErrNavigationBlocked    = errors.New("navigation blocked by guard")
+	ErrNavigationIncomplete = errors.New("navigation not completed by middleware")
+)
+
 type Router struct {
 	routes          []*Route
 	currentRoute    *Route
 	currentParams   map[string]string
+	currentQuery    map[string]string
+	currentState    interface{}
+	paramsObs       *state.Observable[map[string]string]
+	queryObs        *state.Observable[map[string]string]
 	beforeEach      []Guard
 	afterEach       []func(*Route, *Route)
+	middleware      []Middleware
+	navigations     Navigations
 	notFoundHandler func() *dom.Element
 	errorHandler    func(error) *dom.Element
 	baseURL         string
 	mode            RouterMode
 	container       string
+	scrollOffset    int
 }
 
 type RouterMode int
@@ -64,28 +96,62 @@ func NewRouter() *Router {
 	return &Router{
 		routes:        make([]*Route, 0),
 		currentParams: make(map[string]string),
+		currentQuery:  make(map[string]string),
+		paramsObs:     state.NewObservable(make(map[string]string)),
+		queryObs:      state.NewObservable(make(map[string]string)),
 		beforeEach:    make([]Guard, 0),
 		afterEach:     make([]func(*Route, *Route), 0),
+		middleware:    make([]Middleware, 0),
 		mode:          HashMode,
 		container:     "#router-outlet",
 	}
 }
 
-func (r *Router) SetMode(mode RouterMode) *Router      { return r }
+func (r *Router) SetMode(mode RouterMode) *Router {
+	r.mode = mode
+	return r
+}
+
 func (r *Router) SetContainer(selector string) *Router { return r }
-func (r *Router) SetBaseURL(baseURL string) *Router    { return r }
-func (r *Router) AddRoute(route *Route) *Router        { return r }
-func (r *Router) AddSimpleRoute(path string, component func(params map[string]string) *dom.Element) *Router {
+
+func (r *Router) SetBaseURL(baseURL string) *Router {
+	r.baseURL = strings.TrimSuffix(baseURL, "/")
+	return r
+}
+
+func (r *Router) SetScrollOffset(offset int) *Router { return r }
+
+// Routes are recorded outside WebAssembly so route tables can be shared with
+// host-side tooling such as sitemap generation
+func (r *Router) AddRoute(route *Route) *Router {
+	r.routes = append(r.routes, route)
 	return r
 }
+
+func (r *Router) AddSimpleRoute(path string, component func(params map[string]string) *dom.Element) *Router {
+	return r.AddRoute(&Route{Path: path, Component: component})
+}
+
 func (r *Router) RouteWithName(name, path string, component func(params map[string]string) *dom.Element) *Router {
+	return r.AddRoute(&Route{Name: name, Path: path, Component: component})
+}
+
+func (r *Router) RouteGroup(prefix string, guards []Guard, routes []*Route) *Router {
+	for _, route := range routes {
+		route.Path = prefix + route.Path
+		r.AddRoute(route)
+	}
 	return r
 }
-func (r *Router) RouteGroup(prefix string, guards []Guard, routes []*Route) *Router { return r }
-func (r *Router) BeforeEach(guard Guard) *Router                                    { return r }
-func (r *Router) AfterEach(hook func(*Route, *Route)) *Router                       { return r }
-func (r *Router) NotFound(handler func() *dom.Element) *Router                      { return r }
-func (r *Router) OnError(handler func(error) *dom.Element) *Router                  { return r }
+
+func (r *Router) BeforeEach(guard Guard) *Router                   { return r }
+func (r *Router) AfterEach(hook func(*Route, *Route)) *Router      { return r }
+func (r *Router) NotFound(handler func() *dom.Element) *Router     { return r }
+func (r *Router) OnError(handler func(error) *dom.Element) *Router { return r }
+func (r *Router) Use(middleware ...Middleware) *Router             { return r }
+func (r *Router) MiddlewareGroup(prefix string, middleware []Middleware, routes []*Route) *Router {
+	return r.RouteGroup(prefix, nil, routes)
+}
 
 func (r *Router) Start() {
 	fmt.Println("Router only available in WebAssembly build")
@@ -95,20 +161,34 @@ func (r *Router) Navigate(path string) error {
 	return fmt.Errorf("routing only available in WebAssembly build")
 }
 
-func (r *Router) Push(path string) error {
+func (r *Router) Push(path string, state ...interface{}) error {
 	return fmt.Errorf("routing only available in WebAssembly build")
 }
 
-func (r *Router) Replace(path string) error {
+func (r *Router) Replace(path string, state ...interface{}) error {
 	return fmt.Errorf("routing only available in WebAssembly build")
 }
 
-func (r *Router) Go(delta int)                                                  {}
-func (r *Router) Back()                                                         {}
-func (r *Router) Forward()                                                      {}
-func (r *Router) GetCurrentRoute() *Route                                       { return nil }
-func (r *Router) GetCurrentParams() map[string]string                           { return make(map[string]string) }
-func (r *Router) GenerateURL(routeName string, params map[string]string) string { return "" }
+func (r *Router) Go(delta int)                                           {}
+func (r *Router) Back()                                                  {}
+func (r *Router) Forward()                                               {}
+func (r *Router) GetCurrentRoute() *Route                                { return nil }
+func (r *Router) GetCurrentParams() map[string]string                    { return make(map[string]string) }
+func (r *Router) GetCurrentQuery() map[string]string                     { return make(map[string]string) }
+func (r *Router) ParamsObservable() *state.Observable[map[string]string] { return r.paramsObs }
+func (r *Router) QueryObservable() *state.Observable[map[string]string]  { return r.queryObs }
+func (r *Router) GetCurrentState() interface{}                           { return nil }
+func (r *Router) DecodeState(target interface{}) error {
+	return fmt.Errorf("routing only available in WebAssembly build")
+}
+func (r *Router) GenerateURL(routeName string, params map[string]string) string {
+	for _, route := range r.routes {
+		if route.Name == routeName {
+			return fillParams(route.Path, params)
+		}
+	}
+	return ""
+}
 
 func (l *LinkComponent) Render() *dom.Element {
 	return dom.A(dom.Text(l.Text))
@@ -125,6 +205,9 @@ func RouterLinkWithClass(router *Router, to, text, class string) *dom.Element {
 func NewTransition() *Transition                                      { return &Transition{} }
 func (t *Transition) AddHook(hook TransitionHook)                     {}
 func (t *Transition) Execute(to *Route, from *Route, callback func()) { callback() }
+func (t *Transition) Middleware() Middleware {
+	return func(ctx *RouteContext, next func()) { next() }
+}
 
 var CommonGuards = &Guards{}
 
@@ -142,13 +225,18 @@ func (g *Guards) ConfirmLeave(message string) Guard {
 
 var DefaultRouter = NewRouter()
 
-func AddRoute(path string, component func(params map[string]string) *dom.Element) {}
-func Navigate(path string) error                                                  { return fmt.Errorf("routing only available in WebAssembly build") }
-func Push(path string) error                                                      { return fmt.Errorf("routing only available in WebAssembly build") }
-func Back()                                                                       {}
-func Forward()                                                                    {}
-func Start()                                                                      { fmt.Println("Router only available in WebAssembly build") }
-func CreateLink(to, text string) *dom.Element                                     { return dom.A(dom.Text(text)) }
+func AddRoute(path string, component func(params map[string]string) *dom.Element) {
+	DefaultRouter.AddSimpleRoute(path, component)
+}
+func Navigate(path string) error { return fmt.Errorf("routing only available in WebAssembly build") }
+func Push(path string, state ...interface{}) error {
+	return fmt.Errorf("routing only available in WebAssembly build")
+}
+func Back()                                   {}
+func Forward()                                {}
+func Start()                                  { fmt.Println("Router only available in WebAssembly build") }
+func ServeChunk(name string, r *Router)       { fmt.Println("Chunks only available in WebAssembly build") }
+func CreateLink(to, text string) *dom.Element { return dom.A(dom.Text(text)) }
 func CreateLinkWithClass(to, text, class string) *dom.Element {
 	return dom.A(dom.Class(class), dom.Text(text))
 }
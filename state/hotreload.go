@@ -0,0 +1,62 @@
+//go:build js && wasm
+
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"syscall/js"
+)
+
+// hotReloadPrefix namespaces hot reload snapshots in sessionStorage
+const hotReloadPrefix = "golem:hot-reload:"
+
+var (
+	preserved      = make(map[string]Snapshotter)
+	preservedMutex sync.Mutex
+	snapshotHook   sync.Once
+)
+
+// Preserve keeps state across dev hot reloads: before the page reloads its
+// snapshot is saved to sessionStorage, and when Preserve is called again
+// with the same key after the new build boots, the snapshot is restored.
+// Keys must be unique and stable between builds. Ordinary page loads are
+// not affected.
+func Preserve(key string, state Snapshotter) {
+	snapshotHook.Do(installSnapshotHook)
+
+	preservedMutex.Lock()
+	preserved[key] = state
+	preservedMutex.Unlock()
+
+	storage := js.Global().Get("sessionStorage")
+	item := storage.Call("getItem", hotReloadPrefix+key)
+	if item.IsNull() {
+		return
+	}
+	storage.Call("removeItem", hotReloadPrefix+key)
+	if err := state.Restore(json.RawMessage(item.String())); err != nil {
+		fmt.Printf("⚠️ Could not restore %s after hot reload: %v\n", key, err)
+	}
+}
+
+// installSnapshotHook exposes __golemSnapshot, which the dev server's reload
+// script calls right before reloading the page
+func installSnapshotHook() {
+	js.Global().Set("__golemSnapshot", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		preservedMutex.Lock()
+		defer preservedMutex.Unlock()
+
+		storage := js.Global().Get("sessionStorage")
+		for key, state := range preserved {
+			data, err := state.Snapshot()
+			if err != nil {
+				fmt.Printf("⚠️ Could not save %s before hot reload: %v\n", key, err)
+				continue
+			}
+			storage.Call("setItem", hotReloadPrefix+key, string(data))
+		}
+		return nil
+	}))
+}
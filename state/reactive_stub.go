@@ -242,3 +242,5 @@ func CreateComponent(renderFn func() *dom.Element) *Component {
 func CreatePersistence() *Persistence {
 	return NewPersistence()
 }
+
+func Preserve(key string, state Snapshotter) {}
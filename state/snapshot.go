@@ -0,0 +1,88 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Snapshotter is state that can be saved and restored, e.g. across a dev
+// hot reload. Observables and stores implement it.
+type Snapshotter interface {
+	Snapshot() (json.RawMessage, error)
+	Restore(data json.RawMessage) error
+}
+
+// Snapshot encodes the current value as JSON
+func (o *Observable[T]) Snapshot() (json.RawMessage, error) {
+	return json.Marshal(o.Get())
+}
+
+// Restore sets the value decoded from a snapshot, notifying observers
+func (o *Observable[T]) Restore(data json.RawMessage) error {
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("failed to restore observable: %w", err)
+	}
+	o.Set(value)
+	return nil
+}
+
+// Snapshot encodes the state of every key as JSON
+func (s *Store) Snapshot() (json.RawMessage, error) {
+	return json.Marshal(s.GetAllState())
+}
+
+// Restore replaces the state of the keys the store already has with their
+// snapshot, decoded into the type of the current state so reducers see the
+// values they expect, and notifies their observers
+func (s *Store) Restore(data json.RawMessage) error {
+	var snapshot map[string]json.RawMessage
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to restore store: %w", err)
+	}
+
+	s.mutex.Lock()
+	oldState := make(map[string]interface{})
+	for key, encoded := range snapshot {
+		current, exists := s.state[key]
+		if !exists {
+			continue
+		}
+		restored, err := decodeLike(current, encoded)
+		if err != nil {
+			s.mutex.Unlock()
+			return fmt.Errorf("failed to restore store key %s: %w", key, err)
+		}
+		oldState[key] = current
+		s.state[key] = restored
+	}
+
+	observersToNotify := make(map[string][]StoreObserver)
+	for key := range oldState {
+		observersToNotify[key] = append([]StoreObserver(nil), s.observers[key]...)
+	}
+	s.mutex.Unlock()
+
+	for key, observers := range observersToNotify {
+		newState := s.GetState(key)
+		for _, observer := range observers {
+			observer(newState, oldState[key])
+		}
+	}
+	return nil
+}
+
+// decodeLike decodes JSON into a value of the same type as current
+func decodeLike(current interface{}, data json.RawMessage) (interface{}, error) {
+	if current == nil {
+		var value interface{}
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+	value := reflect.New(reflect.TypeOf(current))
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface(), nil
+}
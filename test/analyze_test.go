@@ -0,0 +1,98 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/build"
+)
+
+// wasmSection encodes a section of a WebAssembly binary
+func wasmSection(id byte, content []byte) []byte {
+	return append([]byte{id, byte(len(content))}, content...)
+}
+
+// wasmName encodes a name of a WebAssembly binary
+func wasmName(name string) []byte {
+	return append([]byte{byte(len(name))}, name...)
+}
+
+func TestParseWasmSizes(t *testing.T) {
+	// One imported function, then two functions of 4 and 6 bytes with their
+	// length prefix
+	imports := append([]byte{1}, wasmName("go")...)
+	imports = append(append(imports, wasmName("debug")...), 0, 0)
+	code := []byte{2, 3, 0, 0x41, 0x0b, 5, 0, 0x41, 0x00, 0x1a, 0x0b}
+	names := []byte{2, 1}
+	names = append(append(names, wasmName("github.com_x_y.__T_.M")...), 2)
+	names = append(names, wasmName("fmt.Sprintf")...)
+	name := append(wasmName("name"), append([]byte{1, byte(len(names))}, names...)...)
+
+	binary := []byte("\x00asm\x01\x00\x00\x00")
+	binary = append(binary, wasmSection(2, imports)...)
+	binary = append(binary, wasmSection(10, code)...)
+	binary = append(binary, wasmSection(0, name)...)
+
+	sizes, err := build.ParseWasmSizes(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sizes.Sections["code"] != int64(2+len(code)) || sizes.Sections["import"] != int64(2+len(imports)) || sizes.Sections["custom name"] != int64(2+len(name)) {
+		t.Errorf("Sections = %v", sizes.Sections)
+	}
+	if want := map[string]int64{"github.com_x_y.__T_.M": 4, "fmt.Sprintf": 6}; !reflect.DeepEqual(sizes.Functions, want) {
+		t.Errorf("Functions = %v, want %v", sizes.Functions, want)
+	}
+
+	if _, err := build.ParseWasmSizes([]byte("not wasm")); err == nil {
+		t.Error("ParseWasmSizes accepted a file that is not WebAssembly")
+	}
+}
+
+func TestPackageSizes(t *testing.T) {
+	functions := map[string]int64{
+		"github.com_x_y.__T_.M":        10,
+		"github.com_x_y.New":           5,
+		"github.com_x_y_z.F":           3,
+		"fmt.Sprintf":                  7,
+		"main.main":                    2,
+		"wasm_pc_f_loop":               1,
+		"internal_runtime_maps.NewMap": 4,
+	}
+	packages := []string{"fmt", "github.com/x/y", "github.com/x/y/z", "internal/runtime/maps", "main"}
+	want := map[string]int64{
+		"github.com/x/y":        15,
+		"github.com/x/y/z":      3,
+		"fmt":                   7,
+		"main":                  2,
+		"internal/runtime/maps": 4,
+		build.OtherPackage:      1,
+	}
+	if got := build.PackageSizes(functions, packages); !reflect.DeepEqual(got, want) {
+		t.Errorf("PackageSizes() = %v, want %v", got, want)
+	}
+}
+
+func TestCompareReports(t *testing.T) {
+	previous := &build.Report{
+		Wasm:   []build.WasmReport{{Name: "app.wasm", Packages: map[string]int64{"fmt": 100, "encoding/xml": 50}}},
+		Assets: []build.Asset{{Name: "app.wasm", Size: 1000}, {Name: "old.js", Size: 20}},
+	}
+	current := &build.Report{
+		Wasm:   []build.WasmReport{{Name: "app.wasm", Packages: map[string]int64{"fmt": 100, "net/http": 400}}},
+		Assets: []build.Asset{{Name: "app.wasm", Size: 1300}, {Name: "index.html", Size: 10}},
+	}
+
+	var got []string
+	changes := build.CompareReports(previous, current)
+	for _, change := range changes {
+		got = append(got, change.Name)
+	}
+	want := []string{"app.wasm net/http", "app.wasm", "total", "app.wasm encoding/xml", "old.js", "index.html"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareReports() changed %q, want %q", got, want)
+	}
+	if changes[2].Before != 1020 || changes[2].After != 1310 || changes[4].After != 0 {
+		t.Errorf("CompareReports() = %+v", changes)
+	}
+}
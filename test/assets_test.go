@@ -0,0 +1,34 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/build"
+)
+
+// TestHashedName verifies asset names change with their content only
+func TestHashedName(t *testing.T) {
+	name := build.HashedName("app.wasm", []byte("build 1"))
+	if !regexp.MustCompile(`^app\.[0-9a-f]{8}\.wasm$`).MatchString(name) {
+		t.Errorf("Expected app.<hash>.wasm, got %q", name)
+	}
+	if again := build.HashedName("app.wasm", []byte("build 1")); again != name {
+		t.Errorf("Expected the same content to keep its name, got %q and %q", name, again)
+	}
+	if changed := build.HashedName("app.wasm", []byte("build 2")); changed == name {
+		t.Errorf("Expected new content to get a new name, got %q twice", name)
+	}
+	if js := build.HashedName("wasm_exec.js", []byte("js")); !regexp.MustCompile(`^wasm_exec\.[0-9a-f]{8}\.js$`).MatchString(js) {
+		t.Errorf("Expected wasm_exec.<hash>.js, got %q", js)
+	}
+}
+
+// TestIntegrity verifies integrity values follow the subresource integrity format
+func TestIntegrity(t *testing.T) {
+	// Example from the Subresource Integrity specification
+	expected := "sha384-H8BRh8j48O9oYatfu5AZzq6A9RINhZO5H16dQZngK7T62em8MUt1FLm52t+eX6xO"
+	if got := build.Integrity([]byte("alert('Hello, world.');")); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
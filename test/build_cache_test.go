@@ -0,0 +1,47 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/build"
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+func TestStepKeyCoversEntryPackage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module shop\n\ngo 1.23\n",
+		"lib/lib.go":      "package lib\n\nfunc Name() string { return \"shop\" }\n",
+		"src/app/main.go": "package main\n\nimport \"shop/lib\"\n\nfunc main() { println(lib.Name()) }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{ProjectName: "shop"}
+	app := filepath.Join(dir, "src", "app")
+	key := build.StepKey(cfg, app, os.Environ(), ".")
+	if key == "" {
+		t.Fatal("StepKey() = \"\", want a key")
+	}
+	if again := build.StepKey(cfg, app, os.Environ(), "."); again != key {
+		t.Errorf("StepKey() changed without changes: %s, then %s", key, again)
+	}
+
+	// The package built is the last one go list prints
+	main := filepath.Join(app, "main.go")
+	if err := os.WriteFile(main, []byte(files["src/app/main.go"]+"\nvar version = \"2\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed := build.StepKey(cfg, app, os.Environ(), "."); changed == key {
+		t.Error("StepKey() ignores changes to the package built")
+	}
+}
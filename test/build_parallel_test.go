@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/build"
+)
+
+// TestRunParallel verifies every step runs, at most jobs at once
+func TestRunParallel(t *testing.T) {
+	var running, peak, ran atomic.Int32
+	step := func(ctx context.Context) error {
+		now := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		ran.Add(1)
+		return nil
+	}
+	steps := make([]build.Step, 6)
+	for i := range steps {
+		steps[i] = build.Step{Name: "step", Run: step}
+	}
+
+	if err := build.RunParallel(2, steps); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ran.Load() != 6 {
+		t.Errorf("Expected 6 steps to run, %d did", ran.Load())
+	}
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 steps at once, %d ran", peak.Load())
+	}
+}
+
+// TestRunParallelFailure verifies the errors of the failing steps are
+// returned together, the steps running are cancelled without their errors
+// reported, and those not started are skipped
+func TestRunParallelFailure(t *testing.T) {
+	compileError := errors.New("compile error")
+	typeError := errors.New("type error")
+	cancelled := make(chan bool, 1)
+	var queued atomic.Bool
+
+	// Both failing steps start before either fails
+	var started sync.WaitGroup
+	started.Add(2)
+	fail := func(err error) func(context.Context) error {
+		return func(context.Context) error {
+			started.Done()
+			started.Wait()
+			return err
+		}
+	}
+
+	steps := []build.Step{
+		{Name: "slow", Run: func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				cancelled <- true
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				cancelled <- false
+				return nil
+			}
+		}},
+		{Name: "app.wasm", Run: fail(compileError)},
+		{Name: "server", Run: fail(typeError)},
+		{Name: "queued", Run: func(ctx context.Context) error {
+			queued.Store(true)
+			return nil
+		}},
+	}
+
+	start := time.Now()
+	err := build.RunParallel(3, steps)
+	if !errors.Is(err, compileError) || !errors.Is(err, typeError) {
+		t.Fatalf("Expected the errors of both failing steps, got %v", err)
+	}
+	if err.Error() != "app.wasm: compile error\nserver: type error" {
+		t.Errorf("Expected the errors to name their steps, without the cancelled one, got %q", err)
+	}
+	if !<-cancelled {
+		t.Error("Expected the running step to be cancelled")
+	}
+	if queued.Load() {
+		t.Error("Expected the queued step not to start")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the build to stop at once, took %v", elapsed)
+	}
+}
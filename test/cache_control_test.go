@@ -0,0 +1,46 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+func TestCacheControl(t *testing.T) {
+	fsys := fstest.MapFS{
+		"asset-manifest.json":   {Data: []byte(`{"app.wasm": "app.1a2b3c4d.wasm"}`)},
+		"app.1a2b3c4d.wasm":     {Data: []byte("wasm")},
+		"index.html":            {Data: []byte("<html></html>")},
+		"favicon.ico":           {Data: []byte("icon")},
+		"fonts/inter.woff2":     {Data: []byte("font")},
+		"fonts/inter-bold.woff": {Data: []byte("font")},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		path  string
+		cache config.CacheConfig
+		want  string
+	}{
+		{"/app.1a2b3c4d.wasm", config.CacheConfig{}, server.CacheImmutable},
+		{"/", config.CacheConfig{}, server.CacheNoCache},
+		{"/index.html", config.CacheConfig{}, server.CacheNoCache},
+		{"/users/42", config.CacheConfig{}, server.CacheNoCache},
+		{"/favicon.ico", config.CacheConfig{}, server.CacheNoCache},
+		{"/api/functions/Hello", config.CacheConfig{}, server.CacheNoStore},
+		{"/favicon.ico", config.CacheConfig{Files: "public, max-age=3600"}, "public, max-age=3600"},
+		{"/fonts/inter.woff2", config.CacheConfig{Paths: map[string]string{"/fonts/*": "public, max-age=604800"}}, "public, max-age=604800"},
+		{"/", config.CacheConfig{Pages: "public, max-age=60"}, "public, max-age=60"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.CacheControlFS(fsys, tt.cache, ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := rec.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s with %+v: Cache-Control = %q, want %q", tt.path, tt.cache, got, tt.want)
+		}
+	}
+}
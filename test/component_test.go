@@ -0,0 +1,94 @@
+package test
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/build"
+)
+
+// TestCompileComponent verifies a .golem component compiles into a Go
+// function building its template with dom
+func TestCompileComponent(t *testing.T) {
+	source := `<script>
+import "strings"
+
+loud := strings.ToUpper(title)
+</script>
+
+<template props="title string, items []string">
+  <div class="card">
+    <h2 g-if={loud != ""}>{loud}!</h2>
+    <li g-for={_, item := range items}>{item}</li>
+    <button @click={func() {}} disabled>Go</button>
+    <Badge label="new" />
+  </div>
+</template>
+
+<style scoped>
+.card h2, p::before { color: red; }
+</style>
+`
+	code, err := build.CompileComponent("src/components/user-card.golem", "components", []byte(source))
+	if err != nil {
+		t.Fatalf("Failed to compile component: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", code, 0); err != nil {
+		t.Fatalf("Generated code does not parse: %v\n%s", err, code)
+	}
+
+	for _, want := range []string{
+		"package components",
+		"func UserCard(title string, items []string) *dom.Element {",
+		"//line user-card.golem:4\n",
+		`dom.NewElement("div"`,
+		`dom.Attribute{Name: "class", Value: "card"}`,
+		`dom.On("click", /*line user-card.golem:11:21*/func() {})`,
+		`dom.Attribute{Name: "disabled", Value: true}`,
+		"for /*line user-card.golem:10:16*/_, item := range items {",
+		`Badge("new")`,
+		`fmt.Sprint( /*line user-card.golem:9:28*/loud)+"!"`,
+		"css.InjectOnce(",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("Expected the generated code to contain %q, got:\n%s", want, code)
+		}
+	}
+
+	// Scoped styles apply to the elements of the template only
+	attr := strings.SplitN(strings.SplitN(string(code), `"data-g-`, 2)[1], `"`, 2)[0]
+	if want := ".card h2[data-g-" + attr + "], p[data-g-" + attr + "]::before"; !strings.Contains(string(code), want) {
+		t.Errorf("Expected the scoped selector %q, got:\n%s", want, code)
+	}
+}
+
+// TestComponentErrors verifies parse errors point into the component
+func TestComponentErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"<template><div><span></div></template>", "card.golem:1:22: expected </span>, found </div>"},
+		{"<template>\n  <div>{a +}</div>\n</template>", "card.golem:2:12: expected operand"},
+		{"<template><div g-fi={x}></div></template>", "card.golem:1:16: unknown directive g-fi"},
+		{"<template><div></div><p></p></template>", "card.golem:1:1: <template> needs exactly one root element, found 2"},
+		{"<template><p g-else>x</p></template>", "card.golem:1:14: the root element cannot have g-else"},
+		{"<script>\nx := 1 +\n</script><template><p></p></template>", "card.golem:3:1: expected operand"},
+		{"<div></div>", "card.golem:1:1: unknown block <div>"},
+		{"<script></script>", "card.golem:1:1: component has no <template>"},
+	}
+	for _, tt := range tests {
+		_, err := build.CompileComponent("card.golem", "components", []byte(tt.source))
+		var parseErr *build.ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Expected a parse error for %q, got %v", tt.source, err)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("Expected %q for %q, got %q", tt.want, tt.source, err.Error())
+		}
+	}
+}
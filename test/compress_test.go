@@ -0,0 +1,135 @@
+package test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/server"
+	"github.com/andybalholm/brotli"
+)
+
+// decoder returns a reader decompressing body of encoding
+func decoder(encoding string, body io.Reader) (io.Reader, error) {
+	if encoding == "br" {
+		return brotli.NewReader(body), nil
+	}
+	return gzip.NewReader(body)
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"items":"` + strings.Repeat("golem ", 500) + `"}`
+	handler := server.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, large)
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"ok":true}`)
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, large)
+		case "/png":
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, large)
+		}
+	}))
+
+	tests := []struct {
+		path, accept string
+		wantEncoding string
+	}{
+		{"/large", "gzip, deflate, br", "br"},
+		{"/large", "gzip", "gzip"},
+		{"/large", "br;q=0, gzip", "gzip"},
+		{"/large", "identity", ""},
+		{"/large", "gzip;q=0", ""},
+		{"/small", "gzip, br", ""},
+		{"/png", "gzip, br", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		encoding := rec.Header().Get("Content-Encoding")
+		if encoding != tt.wantEncoding {
+			t.Errorf("%s with %q: Content-Encoding = %q, want %q", tt.path, tt.accept, encoding, tt.wantEncoding)
+			continue
+		}
+		if encoding == "" {
+			continue
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: Vary = %q", tt.path, rec.Header().Get("Vary"))
+		}
+		reader, err := decoder(encoding, rec.Body)
+		if err != nil {
+			t.Fatalf("%s: invalid %s: %v", tt.path, encoding, err)
+		}
+		body, _ := io.ReadAll(reader)
+		if string(body) != large {
+			t.Errorf("%s: decompressed body differs", tt.path)
+		}
+	}
+
+	// Encoded responses pass through as they are
+	req := httptest.NewRequest(http.MethodGet, "/encoded", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "br" || rec.Body.String() != large {
+		t.Errorf("/encoded: expected the response untouched, got Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+// TestCompressFlush verifies streamed responses reach the client as they
+// are flushed, with both encodings
+func TestCompressFlush(t *testing.T) {
+	for _, encoding := range []string{"gzip", "br"} {
+		t.Run(encoding, func(t *testing.T) {
+			testCompressFlush(t, encoding)
+		})
+	}
+}
+
+func testCompressFlush(t *testing.T, encoding string) {
+	flushed := make(chan struct{})
+	ts := httptest.NewServer(server.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, "{\"n\":1}\n")
+		w.(http.Flusher).Flush()
+		<-flushed
+		io.WriteString(w, "{\"n\":2}\n")
+	})))
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Accept-Encoding", encoding)
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != encoding {
+		t.Fatalf("Content-Encoding = %q, want %s", resp.Header.Get("Content-Encoding"), encoding)
+	}
+	reader, err := decoder(encoding, resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := make([]byte, 8)
+	if _, err := io.ReadFull(reader, first); err != nil || string(first) != "{\"n\":1}\n" {
+		t.Fatalf("first line = %q (%v) before the handler finished", first, err)
+	}
+	close(flushed)
+	rest, _ := io.ReadAll(reader)
+	if string(rest) != "{\"n\":2}\n" {
+		t.Errorf("rest = %q", rest)
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// TestConfigEnvName verifies the variables of settings are their JSON path
+// in upper snake case
+func TestConfigEnvName(t *testing.T) {
+	tests := map[string]string{
+		"dev.port":                   "GOLEM_DEV_PORT",
+		"server.grpc.port":           "GOLEM_SERVER_GRPC_PORT",
+		"output":                     "GOLEM_OUTPUT",
+		"projectName":                "GOLEM_PROJECT_NAME",
+		"server.h2c":                 "GOLEM_SERVER_H2C",
+		"server.grpc.maxRecvMsgSize": "GOLEM_SERVER_GRPC_MAX_RECV_MSG_SIZE",
+		"session.secret":             "GOLEM_SESSION_SECRET",
+	}
+	for path, want := range tests {
+		if got := config.EnvName(path); got != want {
+			t.Errorf("EnvName(%q) = %q, expected %q", path, got, want)
+		}
+	}
+}
+
+// TestConfigEnvOverrides verifies GOLEM_ variables override the settings of
+// the file
+func TestConfigEnvOverrides(t *testing.T) {
+	t.Setenv("GOLEM_DEV_PORT", "4000")
+	t.Setenv("GOLEM_SERVER_GRPC_PORT", "6000")
+	t.Setenv("GOLEM_OUTPUT", "dist")
+	t.Setenv("GOLEM_DEV_HOT_RELOAD", "false")
+	t.Setenv("GOLEM_CORS_ORIGINS", "https://a.example.com, https://b.example.com")
+	t.Setenv("GOLEM_SERVER_TRACING_HEADERS", "x-api-key=secret,x-team=shop")
+	t.Setenv("GOLEM_SERVER_PRELOAD", "false")
+	t.Setenv("GOLEM_SERVER_TLS_CERT_FILE", "cert.pem")
+	t.Setenv("GOLEM_SERVER_TLS_KEY_FILE", "key.pem")
+	t.Setenv("GOLEM_SERVER_LOG_LEVEL", "")
+
+	cfg, err := config.Parse([]byte(`{
+  "projectName": "shop",
+  "dev": { "port": 3000, "hotReload": true },
+  "server": { "log": { "level": "warn" } }
+}`))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if cfg.Dev.Port != 4000 || cfg.Server.GRPC.Port != 6000 || cfg.Output != "dist" || cfg.Dev.HotReload {
+		t.Errorf("Unexpected settings: dev.port %d, server.grpc.port %d, output %q, dev.hotReload %v", cfg.Dev.Port, cfg.Server.GRPC.Port, cfg.Output, cfg.Dev.HotReload)
+	}
+	if strings.Join(cfg.CORS.Origins, " ") != "https://a.example.com https://b.example.com" {
+		t.Errorf("Unexpected cors.origins %q", cfg.CORS.Origins)
+	}
+	if cfg.Server.Tracing.Headers["x-api-key"] != "secret" || cfg.Server.Tracing.Headers["x-team"] != "shop" {
+		t.Errorf("Unexpected server.tracing.headers %v", cfg.Server.Tracing.Headers)
+	}
+	if cfg.Server.Preload == nil || *cfg.Server.Preload {
+		t.Errorf("Expected server.preload to be false, got %v", cfg.Server.Preload)
+	}
+	if cfg.Server.TLS == nil || cfg.Server.TLS.CertFile != "cert.pem" || cfg.Server.TLS.KeyFile != "key.pem" {
+		t.Errorf("Unexpected server.tls %+v", cfg.Server.TLS)
+	}
+	if cfg.Server.GRPC.TLS != nil || cfg.Build.PWA != nil {
+		t.Error("Expected sections without variables to stay unset")
+	}
+	if cfg.Server.Log.Level != "warn" {
+		t.Errorf("Expected an empty variable to be ignored, got server.log.level %q", cfg.Server.Log.Level)
+	}
+	if !cfg.FromEnv("dev.port") || !cfg.FromEnv("server.tls.certFile") || cfg.FromEnv("server.log.level") || cfg.FromEnv("projectName") {
+		t.Error("Expected FromEnv to report the settings the variables overrode")
+	}
+}
+
+// TestConfigEnvInvalid verifies invalid variables are reported with the
+// setting they override
+func TestConfigEnvInvalid(t *testing.T) {
+	t.Setenv("GOLEM_SERVER_PORT", "http")
+	t.Setenv("GOLEM_DEV_PORT", "70000")
+	t.Setenv("GOLEM_SERVER_PROXY", "/auth/=http://localhost:9000")
+
+	_, err := config.Parse([]byte(`{"projectName": "shop"}`))
+	if err == nil {
+		t.Fatal("Expected the variables to be rejected")
+	}
+	for _, want := range []string{
+		`server.port: GOLEM_SERVER_PORT="http" is not an integer`,
+		"dev.port: 70000 is out of range",
+		"server.proxy: GOLEM_SERVER_PROXY",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+}
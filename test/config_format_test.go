@@ -0,0 +1,138 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// TestLoadFormats verifies YAML and TOML configurations load like their
+// JSON equivalent
+func TestLoadFormats(t *testing.T) {
+	files := map[string]string{
+		"golem.config.json": `{
+  "projectName": "shop",
+  "dev": { "port": 4000, "watch": ["src/**/*.go"] },
+  "server": {
+    "grpc": { "port": 6000, "reflection": true },
+    "proxy": [{ "path": "/auth/", "target": "http://localhost:9000", "stripPrefix": true }],
+    "limits": { "users.Get": { "timeout": "5s" } },
+    "tracing": { "sampleRatio": 0.5 }
+  }
+}`,
+		"golem.config.yaml": `# The shop
+projectName: shop
+dev:
+  port: 4000
+  watch: ["src/**/*.go"]
+server:
+  grpc:
+    port: 6000
+    reflection: true # for grpcurl
+  proxy:
+    - path: /auth/
+      target: http://localhost:9000
+      stripPrefix: true
+  limits:
+    users.Get:
+      timeout: 5s
+  tracing:
+    sampleRatio: 0.5
+`,
+		"golem.config.toml": `# The shop
+projectName = "shop"
+
+[dev]
+port = 4000
+watch = ["src/**/*.go"]
+
+[server.grpc]
+port = 6000
+reflection = true # for grpcurl
+
+[[server.proxy]]
+path = "/auth/"
+target = "http://localhost:9000"
+stripPrefix = true
+
+[server.limits."users.Get"]
+timeout = "5s"
+
+[server.tracing]
+sampleRatio = 0.5
+`,
+	}
+
+	configs := make(map[string]*config.Config)
+	for name, data := range files {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", name, err)
+		}
+		configs[name] = cfg
+	}
+
+	want := configs["golem.config.json"]
+	if want.Server.GRPC.Port != 6000 || len(want.Server.Proxy) != 1 || want.Server.Limits["users.Get"].Timeout != "5s" {
+		t.Fatalf("Unexpected JSON config %+v", want.Server)
+	}
+	wantJSON, _ := json.Marshal(want)
+	for _, name := range []string{"golem.config.yaml", "golem.config.toml"} {
+		if got, _ := json.Marshal(configs[name]); string(got) != string(wantJSON) {
+			t.Errorf("Expected %s to load like golem.config.json, got %s", name, got)
+		}
+	}
+}
+
+// TestLoadFormatErrors verifies the problems of YAML and TOML files are
+// reported like those of JSON ones
+func TestLoadFormatErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"golem.config.yaml", "projectName: shop\ndev:\n  prot: 3000\n", `dev.prot: unknown field, did you mean "port"?`},
+		{"golem.config.yaml", "projectName: shop\ndev:\n  port: [\n", "line 3"},
+		{"golem.config.toml", "projectName = \"shop\"\n[server.grpc]\nport = 70000\n", "server.grpc.port: 70000 is out of range"},
+		{"golem.config.toml", "projectName = \"shop\"\n[dev\n", "toml: line"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), tt.name)
+		if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := config.Load(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), tt.name) {
+			t.Errorf("Expected an error of %s containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// TestFindConfig verifies Find picks the one configuration of a project
+func TestFindConfig(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := config.Find(dir); !errors.Is(err, config.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	yamlPath := filepath.Join(dir, "golem.config.yaml")
+	os.WriteFile(yamlPath, []byte("projectName: shop\n"), 0644)
+	if path, err := config.Find(dir); err != nil || path != yamlPath {
+		t.Errorf("Expected %s, got %s, %v", yamlPath, path, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "golem.config.json"), []byte(`{"projectName": "shop"}`), 0644)
+	if _, err := config.Find(dir); err == nil || !strings.Contains(err.Error(), "keep only one") {
+		t.Errorf("Expected several configurations to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// TestLoadProjectEnvironment verifies the file of an environment is deep
+// merged over the base configuration: objects key by key, arrays and other
+// values replaced, and null resetting a setting to its default
+func TestLoadProjectEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("golem.config.json", `{
+  "projectName": "shop",
+  "dev": { "port": 4000, "hotReload": true },
+  "server": {
+    "grpc": { "port": 6000, "reflection": true },
+    "log": { "format": "text", "level": "debug" },
+    "limits": { "users.Get": { "timeout": "5s", "maxConcurrent": 10 } }
+  },
+  "cors": { "origins": ["http://localhost:3000", "https://staging.example.com"] }
+}`)
+	write("golem.config.production.yaml", `
+server:
+  grpc:
+    reflection: false
+  log:
+    level: null
+  limits:
+    users.Get:
+      timeout: 2s
+cors:
+  origins: [https://example.com]
+dev: null
+`)
+
+	cfg, err := config.LoadProject(dir, "production")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if cfg.Server.GRPC.Port != 6000 || cfg.Server.GRPC.Reflection {
+		t.Errorf("Expected server.grpc merged, got %+v", cfg.Server.GRPC)
+	}
+	if cfg.Server.Log.Format != "text" || cfg.Server.Log.Level != "" {
+		t.Errorf("Expected server.log.level removed by null, got %+v", cfg.Server.Log)
+	}
+	if limits := cfg.Server.Limits["users.Get"]; limits.Timeout != "2s" || limits.MaxConcurrent != 10 {
+		t.Errorf("Expected the limits merged, got %+v", limits)
+	}
+	if strings.Join(cfg.CORS.Origins, " ") != "https://example.com" {
+		t.Errorf("Expected cors.origins replaced, got %q", cfg.CORS.Origins)
+	}
+	if cfg.Dev.Port != config.DefaultDevPort || cfg.Dev.HotReload {
+		t.Errorf("Expected the dev section reset to its defaults, got %+v", cfg.Dev)
+	}
+	if files := cfg.Files(); len(files) != 2 || filepath.Base(files[1]) != "golem.config.production.yaml" || cfg.Environment() != "production" {
+		t.Errorf("Unexpected files %q of environment %q", files, cfg.Environment())
+	}
+
+	// Environments without a file use the base configuration
+	cfg, err = config.LoadProject(dir, "staging")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if !cfg.Server.GRPC.Reflection || len(cfg.Files()) != 1 {
+		t.Errorf("Expected the base configuration alone, got files %q", cfg.Files())
+	}
+
+	// Problems of the merged configuration name both files
+	write("golem.config.staging.json", `{"server": {"grpc": {"port": 70000}}}`)
+	_, err = config.LoadProject(dir, "staging")
+	if err == nil || !strings.Contains(err.Error(), "golem.config.staging.json") || !strings.Contains(err.Error(), "server.grpc.port") {
+		t.Errorf("Expected the invalid port of the staging file, got %v", err)
+	}
+
+	if _, err := config.LoadProject(dir, "../production"); err == nil {
+		t.Error("Expected an environment name with a path to be rejected")
+	}
+}
+
+// TestConfigEnvironment verifies the --env flag beats GOLEM_ENV, which
+// beats the command's default
+func TestConfigEnvironment(t *testing.T) {
+	t.Setenv(config.EnvVar, "")
+	if env := config.Environment("", "development"); env != "development" {
+		t.Errorf("Expected the default, got %q", env)
+	}
+	t.Setenv(config.EnvVar, "staging")
+	if env := config.Environment("", "development"); env != "staging" {
+		t.Errorf("Expected GOLEM_ENV, got %q", env)
+	}
+	if env := config.Environment("production", "development"); env != "production" {
+		t.Errorf("Expected the flag, got %q", env)
+	}
+}
+
+// TestConfigChanges verifies Changes names the settings that differ
+// between two configurations, comparing lists as a whole
+func TestConfigChanges(t *testing.T) {
+	old, err := config.Parse([]byte(`{"projectName": "shop", "dev": {"watch": ["src/**/*.go"]}, "server": {"limits": {"users.Get": {"timeout": "5s"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := config.Changes(old, old); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %q", changes)
+	}
+
+	new, err := config.Parse([]byte(`{
+  "projectName": "shop",
+  "dev": {"port": 4000, "watch": ["src/**/*.go", "assets/**/*"]},
+  "server": {"limits": {"users.Get": {"timeout": "2s"}}, "proxy": [{"path": "/auth/", "target": "http://localhost:9000"}]}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dev.port dev.watch server.limits.users.Get.timeout server.proxy"
+	if changes := strings.Join(config.Changes(old, new), " "); changes != want {
+		t.Errorf("Expected %q, got %q", want, changes)
+	}
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// i18nSettings are the settings of a plugin kept under plugins.i18n
+type i18nSettings struct {
+	Locales  []string `json:"locales"`
+	Fallback string   `json:"fallback"`
+	Strict   bool     `json:"strict"`
+}
+
+// TestDecodeSection verifies plugins decode their section of the
+// configuration into their own types, layered like the other settings
+func TestDecodeSection(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "golem.config.json"), []byte(`{
+  "projectName": "shop",
+  "plugins": {
+    "i18n": { "locales": ["en", "fr"], "fallback": "en" },
+    "sitemap": { "host": "https://shop.example.com" }
+  }
+}`), 0644)
+	os.WriteFile(filepath.Join(dir, "golem.config.production.yaml"), []byte("plugins:\n  i18n:\n    strict: true\n"), 0644)
+
+	cfg, err := config.LoadProject(dir, "production")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	var settings i18nSettings
+	if err := cfg.DecodeSection("i18n", &settings); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if strings.Join(settings.Locales, " ") != "en fr" || settings.Fallback != "en" || !settings.Strict {
+		t.Errorf("Unexpected settings %+v", settings)
+	}
+
+	// Sections the configuration lacks leave the defaults alone
+	defaults := i18nSettings{Fallback: "de"}
+	if err := cfg.DecodeSection("translations", &defaults); err != nil || defaults.Fallback != "de" {
+		t.Errorf("Expected the defaults to be kept, got %+v, %v", defaults, err)
+	}
+
+	if err := cfg.DecodeSection("i18n", settings); err == nil {
+		t.Error("Expected a target that is not a pointer to be rejected")
+	}
+}
+
+// TestDecodeSectionErrors verifies the problems of a plugin's section are
+// reported at their path
+func TestDecodeSectionErrors(t *testing.T) {
+	cfg, err := config.Parse([]byte(`{
+  "projectName": "shop",
+  "plugins": { "i18n": { "locales": "en", "fallbak": "en" } }
+}`))
+	if err != nil {
+		t.Fatalf("Expected the sections of plugins to be left to them, got %v", err)
+	}
+
+	var settings i18nSettings
+	err = cfg.DecodeSection("i18n", &settings)
+	for _, want := range []string{
+		`plugins.i18n.fallbak: unknown field, did you mean "fallback"?`,
+		`plugins.i18n.locales: expected an array, got "en"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q, got %v", want, err)
+		}
+	}
+}
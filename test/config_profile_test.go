@@ -0,0 +1,47 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// TestApplyProfile verifies a build profile overrides only the settings it sets
+func TestApplyProfile(t *testing.T) {
+	minify := false
+	cfg := &config.Config{
+		Output: ".golem/build",
+		Build: config.BuildConfig{
+			Minify: true,
+			Target: "es2020",
+			Define: map[string]string{"main.Version": "1.0.0", "main.Channel": "stable"},
+		},
+		Profiles: map[string]config.BuildProfile{
+			"staging": {
+				Output: ".golem/staging",
+				Minify: &minify,
+				Define: map[string]string{"main.Channel": "beta"},
+				Server: "https://staging.example.com",
+			},
+		},
+	}
+
+	if err := cfg.ApplyProfile("staging"); err != nil {
+		t.Fatalf("Failed to apply profile: %v", err)
+	}
+	if cfg.Output != ".golem/staging" || cfg.Build.Minify || cfg.Build.Target != "es2020" {
+		t.Errorf("Unexpected settings: output %q, minify %v, target %q", cfg.Output, cfg.Build.Minify, cfg.Build.Target)
+	}
+	if cfg.Build.Define["main.Version"] != "1.0.0" || cfg.Build.Define["main.Channel"] != "beta" {
+		t.Errorf("Expected profile defines merged over build.define, got %v", cfg.Build.Define)
+	}
+	if cfg.Build.Server != "https://staging.example.com" || cfg.Build.Profile != "staging" {
+		t.Errorf("Unexpected server %q or profile %q", cfg.Build.Server, cfg.Build.Profile)
+	}
+
+	err := cfg.ApplyProfile("prod")
+	if err == nil || !strings.Contains(err.Error(), "staging") {
+		t.Errorf("Expected an error listing the known profiles, got %v", err)
+	}
+}
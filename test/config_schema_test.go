@@ -0,0 +1,72 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// TestSchemaFile verifies golem.schema.json, which editors download, is
+// the schema the config is validated against; regenerate it with
+// "golem config schema > golem.schema.json"
+func TestSchemaFile(t *testing.T) {
+	published, err := os.ReadFile("../golem.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	encoder := json.NewEncoder(&want)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(config.Schema()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(published, want.Bytes()) {
+		t.Error("golem.schema.json is out of date, regenerate it with golem config schema")
+	}
+}
+
+// TestSchema verifies the schema describes the settings with their
+// constraints and documentation
+func TestSchema(t *testing.T) {
+	schema := config.Schema()
+	if len(schema.Required) != 1 || schema.Required[0] != "projectName" {
+		t.Errorf("Expected projectName to be required, got %q", schema.Required)
+	}
+
+	server := schema.Properties["server"]
+	if server == nil || server.Properties["grpc"] == nil {
+		t.Fatal("Expected server.grpc in the schema")
+	}
+	port := server.Properties["grpc"].Properties["port"]
+	if port.Type != "integer" || port.Minimum == nil || port.Maximum == nil || *port.Maximum != 65535 {
+		t.Errorf("Expected server.grpc.port to be a port, got %+v", port)
+	}
+	if level := server.Properties["log"].Properties["level"]; strings.Join(level.Enum, " ") != "debug info warn error" {
+		t.Errorf("Expected the log levels, got %q", level.Enum)
+	}
+	if timeout := server.Properties["shutdownTimeout"]; timeout.Format != "duration" || timeout.Description == "" {
+		t.Errorf("Expected a documented duration, got %+v", timeout)
+	}
+
+	data, err := json.Marshal(schema.Properties["dev"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"additionalProperties":false`) {
+		t.Errorf("Expected sections to reject unknown keys, got %s", data)
+	}
+}
+
+// TestSchemaKey verifies files naming their schema for editors are valid
+func TestSchemaKey(t *testing.T) {
+	data := `{"$schema": "` + config.SchemaURL + `", "projectName": "shop", "router": {"mode": "tabs"}}`
+	_, err := config.Parse([]byte(data))
+	if err == nil || strings.Contains(err.Error(), "$schema") || !strings.Contains(err.Error(), `router.mode: "tabs" is not one of hash, history`) {
+		t.Errorf("Expected only the router mode to be reported, got %v", err)
+	}
+}
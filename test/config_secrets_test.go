@@ -0,0 +1,95 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// TestConfigSecrets verifies settings referencing secrets as ${NAME} get
+// their values from the secrets files, those of the environment winning,
+// and from the environment variables, which win over both
+func TestConfigSecrets(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("golem.config.json", `{
+  "projectName": "shop",
+  "session": { "secret": "${SESSION_SECRET}" },
+  "server": { "tracing": { "headers": { "authorization": "Bearer ${TRACING_TOKEN}" } } }
+}`)
+	write(config.SecretsFile, "SESSION_SECRET=development-secret-of-32-characters\nTRACING_TOKEN=dev-token\n")
+	write("golem.secrets.production.env", "TRACING_TOKEN='production token'\n")
+
+	cfg, err := config.LoadProject(dir, "production")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if cfg.Session.Secret != "development-secret-of-32-characters" {
+		t.Errorf("Expected the secret of golem.secrets.env, got %q", cfg.Session.Secret)
+	}
+	if got := cfg.Server.Tracing.Headers["authorization"]; got != "Bearer production token" {
+		t.Errorf("Expected the secret of the production file, got %q", got)
+	}
+	if err := cfg.RequireSecrets(); err != nil {
+		t.Errorf("Expected every secret to be set, got %v", err)
+	}
+
+	// Written out, the settings reference their secrets again
+	written, err := cfg.WithoutSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.Session.Secret != "${SESSION_SECRET}" || written.Server.Tracing.Headers["authorization"] != "Bearer ${TRACING_TOKEN}" {
+		t.Errorf("Expected the references, got %q and %q", written.Session.Secret, written.Server.Tracing.Headers["authorization"])
+	}
+	if cfg.Session.Secret != "development-secret-of-32-characters" {
+		t.Error("Expected WithoutSecrets to leave the configuration alone")
+	}
+
+	t.Setenv("TRACING_TOKEN", "from-the-environment")
+	cfg, err = config.LoadProject(dir, "production")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if got := cfg.Server.Tracing.Headers["authorization"]; got != "Bearer from-the-environment" {
+		t.Errorf("Expected the environment variable to win, got %q", got)
+	}
+	if value, ok := cfg.Secret("SESSION_SECRET"); !ok || value != "development-secret-of-32-characters" {
+		t.Errorf("Expected Secret to read the secrets files, got %q", value)
+	}
+}
+
+// TestConfigSecretProblems verifies missing secrets are only reported when
+// the server requires them, and secrets cannot reach the app
+func TestConfigSecretProblems(t *testing.T) {
+	t.Setenv("MISSING_SECRET", "")
+	os.Unsetenv("MISSING_SECRET")
+
+	cfg, err := config.Parse([]byte(`{"projectName": "shop", "session": {"secret": "${MISSING_SECRET}"}}`))
+	if err != nil {
+		t.Fatalf("Expected a missing secret to load, got %v", err)
+	}
+	err = cfg.RequireSecrets()
+	if err == nil || !strings.Contains(err.Error(), "session.secret: secret MISSING_SECRET is not set") {
+		t.Errorf("Expected the missing secret to be reported, got %v", err)
+	}
+
+	_, err = config.Parse([]byte(`{"projectName": "shop", "build": {"define": {"main.APIKey": "${API_KEY}"}}}`))
+	if err == nil || !strings.Contains(err.Error(), `build.define["main.APIKey"]: secrets can only be referenced from the server and session settings`) {
+		t.Errorf("Expected a secret in build.define to be rejected, got %v", err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "golem.config.json"), []byte(`{"projectName": "shop"}`), 0644)
+	os.WriteFile(filepath.Join(dir, config.SecretsFile), []byte("GOLEM_PUBLIC_API_KEY=abc\n"), 0644)
+	if _, err := config.LoadProject(dir, ""); err == nil || !strings.Contains(err.Error(), "GOLEM_PUBLIC_API_KEY is compiled into the app") {
+		t.Errorf("Expected a public variable in the secrets file to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// TestParseDefaults verifies settings left out get their documented defaults
+func TestParseDefaults(t *testing.T) {
+	cfg, err := config.Parse([]byte(`{"projectName": "shop", "dev": {"hotReload": true}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if cfg.Output != config.DefaultOutput || cfg.Dev.Port != config.DefaultDevPort || cfg.Server.GRPC.Port != config.DefaultGRPCPort {
+		t.Errorf("Unexpected defaults: output %q, dev.port %d, server.grpc.port %d", cfg.Output, cfg.Dev.Port, cfg.Server.GRPC.Port)
+	}
+	if !cfg.Dev.HotReload {
+		t.Error("Expected dev.hotReload to be kept")
+	}
+}
+
+// TestParseValidation verifies every problem of a configuration is
+// reported at its JSON path
+func TestParseValidation(t *testing.T) {
+	_, err := config.Parse([]byte(`{
+  "projectName": "shop",
+  "dev": { "prot": 3000, "hotReload": "yes" },
+  "server": {
+    "port": 8080,
+    "grpc": { "port": 70000 },
+    "shutdownTimeout": "soon",
+    "proxy": [{ "path": "/auth/", "target": "localhost:9000" }],
+    "limits": { "users.Get": { "timeout": "5x" } }
+  },
+  "router": { "mode": "memory" },
+  "bulid": {}
+}`))
+	var validation *config.ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Expected a *config.ValidationError, got %v", err)
+	}
+
+	want := map[string]string{
+		"bulid":                              `did you mean "build"`,
+		"dev.prot":                           `did you mean "port"`,
+		"dev.hotReload":                      "expected true or false",
+		"server.grpc.port":                   "out of range",
+		"server.shutdownTimeout":             "not a duration",
+		"server.proxy[0].target":             "not a URL",
+		`server.limits["users.Get"].timeout`: "not a duration",
+		"router.mode":                        "not one of hash, history",
+	}
+	for _, problem := range validation.Errors {
+		message, ok := want[problem.Path]
+		if !ok {
+			t.Errorf("Unexpected problem %v", problem)
+			continue
+		}
+		if !strings.Contains(problem.Message, message) {
+			t.Errorf("Expected %s to be reported with %q, got %q", problem.Path, message, problem.Message)
+		}
+		delete(want, problem.Path)
+	}
+	for path := range want {
+		t.Errorf("Expected a problem with %s", path)
+	}
+}
+
+// TestParseErrors verifies syntax errors are located and required settings
+// enforced
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"syntax", "{\n  \"projectName\": \"shop\",\n  \"dev\": {,}\n}", "line 3, column 11"},
+		{"required", `{"version": "1.0.0"}`, "projectName: required"},
+		{"wrong type", `{"projectName": "shop", "server": {"port": "80"}}`, `server.port: expected an integer, got "80"`},
+		{"case-insensitive keys", `{"ProjectName": "shop", "Dev": {"Port": 4000}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Parse([]byte(tt.data))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
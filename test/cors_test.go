@@ -0,0 +1,90 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestCORS verifies the cors policy decides which origins may call /api/
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name        string
+		cors        config.CORSConfig
+		method      string
+		path        string
+		origin      string
+		wantStatus  int
+		wantOrigin  string
+		wantCreds   string
+		wantMethods string
+	}{
+		{"same origin only by default", config.CORSConfig{}, http.MethodPost, "/api/functions", "https://evil.example", http.StatusTeapot, "", "", ""},
+		{"listed origin", config.CORSConfig{Origins: []string{"https://app.example.com"}}, http.MethodPost, "/api/functions", "https://app.example.com", http.StatusTeapot, "https://app.example.com", "", ""},
+		{"unlisted origin", config.CORSConfig{Origins: []string{"https://app.example.com"}}, http.MethodPost, "/api/functions", "https://evil.example", http.StatusTeapot, "", "", ""},
+		{"subdomain wildcard", config.CORSConfig{Origins: []string{"https://*.example.com"}}, http.MethodPost, "/api/functions", "https://shop.example.com", http.StatusTeapot, "https://shop.example.com", "", ""},
+		{"subdomain wildcard excludes the domain", config.CORSConfig{Origins: []string{"https://*.example.com"}}, http.MethodPost, "/api/functions", "https://example.com", http.StatusTeapot, "", "", ""},
+		{"any origin", config.CORSConfig{Origins: []string{"*"}}, http.MethodPost, "/api/functions", "https://anywhere.example", http.StatusTeapot, "*", "", ""},
+		{"credentials", config.CORSConfig{Origins: []string{"https://app.example.com"}, Credentials: true}, http.MethodPost, "/api/functions", "https://app.example.com", http.StatusTeapot, "https://app.example.com", "true", ""},
+		{"preflight", config.CORSConfig{Origins: []string{"https://app.example.com"}}, http.MethodOptions, "/api/functions", "https://app.example.com", http.StatusNoContent, "https://app.example.com", "", "GET, POST, OPTIONS"},
+		{"preflight of an unlisted origin", config.CORSConfig{Origins: []string{"https://app.example.com"}}, http.MethodOptions, "/api/functions", "https://evil.example", http.StatusNoContent, "", "", ""},
+		{"configured methods", config.CORSConfig{Origins: []string{"*"}, Methods: []string{"POST"}}, http.MethodOptions, "/api/functions", "https://app.example.com", http.StatusNoContent, "*", "", "POST"},
+		{"outside /api/", config.CORSConfig{Origins: []string{"*"}}, http.MethodGet, "/app.wasm", "https://app.example.com", http.StatusTeapot, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := server.CORS(tt.cors, next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				req.Header.Set("Access-Control-Request-Headers", "content-type, x-trace-id")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			header := rec.Header()
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status: expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := header.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin: expected %q, got %q", tt.wantOrigin, got)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Access-Control-Allow-Credentials: expected %q, got %q", tt.wantCreds, got)
+			}
+			if got := header.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods: expected %q, got %q", tt.wantMethods, got)
+			}
+			if tt.wantMethods != "" && header.Get("Access-Control-Allow-Headers") != "content-type, x-trace-id" {
+				t.Errorf("Expected the requested headers to be allowed, got %q", header.Get("Access-Control-Allow-Headers"))
+			}
+		})
+	}
+}
+
+// TestCORSValidation verifies invalid cors sections are rejected
+func TestCORSValidation(t *testing.T) {
+	for _, cors := range []config.CORSConfig{
+		{Origins: []string{"*"}, Credentials: true},
+		{Origins: []string{"https://app.example.com/"}},
+		{Origins: []string{"app.example.com"}},
+		{Origins: []string{"https://app.example.com/path"}},
+		{Origins: []string{"https://app.example.com"}, MaxAge: -1},
+	} {
+		if _, err := server.CORS(cors, http.NotFoundHandler()); err == nil {
+			t.Errorf("%+v was accepted", cors)
+		}
+	}
+}
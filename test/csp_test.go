@@ -0,0 +1,60 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Nu11ified/golem/internal/build"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestContentSecurityPolicy verifies the generated policy allows the page's
+// inline scripts by hash, WebAssembly and the configured server
+func TestContentSecurityPolicy(t *testing.T) {
+	html := `<head><style>body { margin: 0; }</style></head>
+<body><script src="wasm_exec.js"></script><script>new Go();</script></body>`
+	policy := build.ContentSecurityPolicy(html, "https://api.example.com/base", map[string][]string{
+		"img-src":    {"https://cdn.example.com"},
+		"worker-src": {"'self'"},
+	})
+
+	for _, want := range []string{
+		"script-src 'self' 'wasm-unsafe-eval' 'sha256-",
+		"style-src 'self' 'sha256-",
+		"connect-src 'self' https://api.example.com wss://api.example.com;",
+		"img-src 'self' data: https://cdn.example.com;",
+		"; worker-src 'self'",
+	} {
+		if !strings.Contains(policy, want) {
+			t.Errorf("Expected the policy to contain %q, got %q", want, policy)
+		}
+	}
+	if count := strings.Count(policy, "'sha256-"); count != 2 {
+		t.Errorf("Expected 2 hashes for the inline script and style, got %d in %q", count, policy)
+	}
+}
+
+// TestContentSecurityPolicyHeader verifies the production server sends the
+// policy golem build wrote and hides its file
+func TestContentSecurityPolicyHeader(t *testing.T) {
+	assets := fstest.MapFS{
+		"index.html":   {Data: []byte("<html>app</html>")},
+		server.CSPFile: {Data: []byte("default-src 'self'\n")},
+	}
+	handler := server.ContentSecurityPolicyFS(assets, http.FileServerFS(assets))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if got := rec.Header().Get("Content-Security-Policy"); got != "default-src 'self'" {
+		t.Errorf("Expected the policy header, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/"+server.CSPFile, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected %s to be hidden, got status %d", server.CSPFile, rec.Code)
+	}
+}
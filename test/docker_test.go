@@ -0,0 +1,37 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/build"
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+func TestDockerfile(t *testing.T) {
+	cfg := &config.Config{ProjectName: "shop", Version: "1.2.0", Output: "dist"}
+	cfg.Server.GRPC.Port = 9090
+
+	dockerfile := build.Dockerfile(cfg, "1.23", "staging")
+	for _, want := range []string{
+		"FROM golang:1.23 AS build",
+		"golem build --single-binary --profile staging",
+		"FROM " + build.DefaultDockerRuntime,
+		"COPY --from=build /app/dist/server /server",
+		"EXPOSE 8080 9090",
+		`ENTRYPOINT ["/server"]`,
+	} {
+		if !strings.Contains(dockerfile, want) {
+			t.Errorf("Dockerfile lacks %q:\n%s", want, dockerfile)
+		}
+	}
+
+	cfg.Build.Docker.Runtime = "alpine:3.20"
+	if dockerfile := build.Dockerfile(cfg, "", ""); !strings.Contains(dockerfile, "FROM alpine:3.20") || !strings.Contains(dockerfile, "FROM golang:1 AS build") {
+		t.Errorf("Dockerfile ignores the configured images:\n%s", dockerfile)
+	}
+
+	if image := build.DockerImage(cfg); image != "shop:1.2.0" {
+		t.Errorf("DockerImage() = %q, want shop:1.2.0", image)
+	}
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/doctor"
+)
+
+// findCheck returns the check named name
+func findCheck(t *testing.T, checks []doctor.Check, name string) doctor.Check {
+	t.Helper()
+	for _, check := range checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("no %s check in %+v", name, checks)
+	return doctor.Check{}
+}
+
+func TestDoctor(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	dir := t.TempDir()
+	config := `{"projectName": "shop", "server": {"host": "127.0.0.1", "port": ` + strconv.Itoa(port) + `}}`
+	if err := os.WriteFile(filepath.Join(dir, "golem.config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "golem.config.staging.json"), []byte(`{"server": {"port": "http"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := doctor.Run(dir)
+	if check := findCheck(t, checks, "Go"); check.Status != doctor.OK {
+		t.Errorf("Go check = %+v", check)
+	}
+	if check := findCheck(t, checks, "Config"); check.Status != doctor.OK {
+		t.Errorf("Config check = %+v", check)
+	}
+	if check := findCheck(t, checks, "Config staging"); check.Status != doctor.Fail || !strings.Contains(check.Detail, "server.port") || check.Fix == "" {
+		t.Errorf("the invalid staging config passed: %+v", check)
+	}
+	if check := findCheck(t, checks, "Server port"); check.Status != doctor.Warn || check.Fix == "" {
+		t.Errorf("the taken server port passed: %+v", check)
+	}
+	if !doctor.Failed(checks) {
+		t.Error("Failed() = false with an invalid config")
+	}
+}
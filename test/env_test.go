@@ -0,0 +1,68 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/build"
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+// TestParseEnv verifies .env values are unquoted and comments are dropped
+func TestParseEnv(t *testing.T) {
+	values, err := build.ParseEnv(`
+# API settings
+export GOLEM_PUBLIC_API_URL=https://api.example.com # production API
+DATABASE_URL="postgres://localhost/app?sslmode=disable"
+GREETING="Hello,\nworld # not a comment"
+RAW='keep \n as is'
+EMPTY=
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse env: %v", err)
+	}
+
+	expected := map[string]string{
+		"GOLEM_PUBLIC_API_URL": "https://api.example.com",
+		"DATABASE_URL":         "postgres://localhost/app?sslmode=disable",
+		"GREETING":             "Hello,\nworld # not a comment",
+		"RAW":                  `keep \n as is`,
+		"EMPTY":                "",
+	}
+	for key, value := range expected {
+		if values[key] != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, values[key])
+		}
+	}
+
+	if _, err := build.ParseEnv("NOT A VARIABLE"); err == nil {
+		t.Errorf("Expected an error for a line without =")
+	}
+}
+
+// TestGenerateEnv verifies only GOLEM_PUBLIC_ variables are compiled into
+// the generated env package
+func TestGenerateEnv(t *testing.T) {
+	t.Setenv("GOLEM_PUBLIC_API_URL", "https://api.example.com")
+	t.Setenv("GOLEM_SECRET_TOKEN", "secret")
+
+	cfg := &config.Config{Build: config.BuildConfig{Env: filepath.Join(t.TempDir(), "env")}}
+	file, err := build.GenerateEnv(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate env package: %v", err)
+	}
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", file, err)
+	}
+	generated := string(source)
+	if !strings.Contains(generated, "package env") || !strings.Contains(generated, `ApiUrl = "https://api.example.com"`) {
+		t.Errorf("Expected the public variable as a constant, got:\n%s", generated)
+	}
+	if strings.Contains(generated, "secret") {
+		t.Errorf("Expected variables without the public prefix to be left out, got:\n%s", generated)
+	}
+}
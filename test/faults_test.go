@@ -0,0 +1,165 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/dev"
+	"github.com/Nu11ified/golem/internal/functions"
+)
+
+// TestFaultInjectorParse verifies dev.faults entries are validated, naming
+// the entry at fault
+func TestFaultInjectorParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		faults  map[string]config.FaultConfig
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"delay and jitter", map[string]config.FaultConfig{"*": {Delay: "500ms", Jitter: "1s"}}, ""},
+		{"error rate bounds", map[string]config.FaultConfig{"users.Get": {ErrorRate: 1}}, ""},
+		{"invalid delay", map[string]config.FaultConfig{"users.Get": {Delay: "soon"}}, "invalid dev.faults entry users.Get: invalid delay"},
+		{"invalid jitter", map[string]config.FaultConfig{"*": {Jitter: "10"}}, "invalid dev.faults entry *: invalid jitter"},
+		{"negative error rate", map[string]config.FaultConfig{"*": {ErrorRate: -0.1}}, "errorRate must be between 0 and 1"},
+		{"error rate above 1", map[string]config.FaultConfig{"*": {ErrorRate: 1.5}}, "errorRate must be between 0 and 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := dev.FaultInjector(tt.faults)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if injector, err := dev.FaultInjector(nil); injector != nil || err != nil {
+		t.Errorf("Expected no interceptor without faults, got %v, %v", injector != nil, err)
+	}
+}
+
+// faultRegistry returns a registry with users.Get and users.List whose
+// calls go through the faults
+func faultRegistry(t *testing.T, faults map[string]config.FaultConfig) *functions.Registry {
+	t.Helper()
+	injector, err := dev.FaultInjector(faults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := functions.NewRegistry()
+	registry.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	registry.Use(injector)
+	for _, name := range []string{"Get", "List"} {
+		if err := registry.RegisterFunction("users", name, func() string { return "ok" }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+// TestFaultInjectorSelection verifies a function's own entry replaces the
+// "*" entry, with the error code and delay of the entry applied
+func TestFaultInjectorSelection(t *testing.T) {
+	registry := faultRegistry(t, map[string]config.FaultConfig{
+		"*":         {ErrorRate: 1},
+		"users.Get": {Delay: "30ms", ErrorRate: 1, ErrorCode: functions.CodeResourceExhausted},
+	})
+
+	tests := []struct {
+		function  string
+		wantCode  string
+		wantDelay time.Duration
+	}{
+		{"Get", functions.CodeResourceExhausted, 30 * time.Millisecond},
+		{"List", functions.CodeUnavailable, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			start := time.Now()
+			_, err := registry.CallFunction(context.Background(), "users", tt.function, nil)
+			elapsed := time.Since(start)
+
+			structured := functions.ToError(err)
+			if err == nil || structured.Code != tt.wantCode {
+				t.Fatalf("Expected a %s error, got %v", tt.wantCode, err)
+			}
+			if !structured.Retryable {
+				t.Error("Expected injected failures to be retryable")
+			}
+			if elapsed < tt.wantDelay {
+				t.Errorf("Expected a delay of at least %v, took %v", tt.wantDelay, elapsed)
+			}
+		})
+	}
+}
+
+// TestFaultInjectorDelay verifies calls wait for the delay plus up to the
+// jitter, and stop waiting when their context ends
+func TestFaultInjectorDelay(t *testing.T) {
+	registry := faultRegistry(t, map[string]config.FaultConfig{"users.Get": {Delay: "20ms", Jitter: "20ms"}})
+
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		result, err := registry.CallFunction(context.Background(), "users", "Get", nil)
+		elapsed := time.Since(start)
+		if err != nil || string(result.GetValue()) != `"ok"` {
+			t.Fatalf("Expected the call to succeed after the delay, got %v", err)
+		}
+		if elapsed < 20*time.Millisecond || elapsed > time.Second {
+			t.Errorf("Expected a delay of 20ms to 40ms, took %v", elapsed)
+		}
+	}
+
+	// Functions without an entry run at once
+	start := time.Now()
+	if _, err := registry.CallFunction(context.Background(), "users", "List", nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+		t.Errorf("Expected users.List without delay, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	slow := faultRegistry(t, map[string]config.FaultConfig{"*": {Delay: "1h"}})
+	if _, err := slow.CallFunction(ctx, "users", "Get", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the delay to end with the context, got %v", err)
+	}
+}
+
+// TestFaultInjectorErrorRate verifies the fraction of calls failing follows
+// errorRate
+func TestFaultInjectorErrorRate(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		min, max int // failures expected of 1000 calls
+	}{
+		{0, 0, 0},
+		{1, 1000, 1000},
+		{0.5, 400, 600},
+	}
+	for _, tt := range tests {
+		registry := faultRegistry(t, map[string]config.FaultConfig{"*": {ErrorRate: tt.rate}})
+		failures := 0
+		for i := 0; i < 1000; i++ {
+			if _, err := registry.CallFunction(context.Background(), "users", "Get", nil); err != nil {
+				failures++
+			}
+		}
+		if failures < tt.min || failures > tt.max {
+			t.Errorf("errorRate %v: %d of 1000 calls failed, want %d to %d", tt.rate, failures, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/gotest"
+)
+
+func TestTestArgs(t *testing.T) {
+	packages, flags := gotest.SplitArgs([]string{"./dom", "-run", "TestRender", "-v", "-count=1", "./state"})
+	if want := []string{"./dom", "./state"}; !reflect.DeepEqual(packages, want) {
+		t.Errorf("packages = %q, want %q", packages, want)
+	}
+	if want := []string{"-run", "TestRender", "-v", "-count=1"}; !reflect.DeepEqual(flags, want) {
+		t.Errorf("flags = %q, want %q", flags, want)
+	}
+
+	if packages, _ := gotest.SplitArgs([]string{"-short"}); !reflect.DeepEqual(packages, []string{"./..."}) {
+		t.Errorf("packages default to %q, want ./...", packages)
+	}
+}
+
+func TestWasmOnlyPackages(t *testing.T) {
+	native := map[string][]string{
+		"app/server": {"server_test.go"},
+		"app/dom":    {"html_test.go"},
+	}
+	wasm := map[string][]string{
+		"app/server": {"server_test.go"},
+		"app/dom":    {"html_test.go", "element_test.go"},
+		"app/state":  {"reactive_test.go"},
+	}
+	if got, want := gotest.WasmOnly(native, wasm), []string{"app/dom", "app/state"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WasmOnly() = %q, want %q", got, want)
+	}
+}
+
+func TestPickRunner(t *testing.T) {
+	if _, err := gotest.PickRunner("deno"); err == nil {
+		t.Error("PickRunner accepted an unknown runner")
+	}
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nu11ified/golem/grpc"
+)
+
+// TestClientCache verifies TTL, stale-while-revalidate and invalidation
+func TestClientCache(t *testing.T) {
+	cache := grpc.NewCache(grpc.CacheOptions{
+		TTL:                  50 * time.Millisecond,
+		StaleWhileRevalidate: time.Second,
+	})
+	interceptor := cache.Interceptor()
+
+	calls := 0
+	revalidated := make(chan struct{}, 1)
+	next := func(ctx context.Context, info *grpc.CallInfo) (interface{}, error) {
+		calls++
+		if calls > 1 {
+			defer func() { revalidated <- struct{}{} }()
+		}
+		return calls, nil
+	}
+	call := func(ctx context.Context) interface{} {
+		info := &grpc.CallInfo{ServiceName: "server", FunctionName: "GetUserProfile", Args: []interface{}{1}}
+		result, err := interceptor(ctx, info, next)
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		return result
+	}
+
+	if result := call(context.Background()); result != 1 {
+		t.Fatalf("Expected first result 1, got %v", result)
+	}
+	if result := call(context.Background()); result != 1 || calls != 1 {
+		t.Fatalf("Expected cached result, got %v after %d calls", result, calls)
+	}
+
+	// A stale entry is served while a fresh one is fetched in the background
+	time.Sleep(60 * time.Millisecond)
+	if result := call(context.Background()); result != 1 {
+		t.Fatalf("Expected stale result 1, got %v", result)
+	}
+	select {
+	case <-revalidated:
+	case <-time.After(time.Second):
+		t.Fatal("Stale entry was not revalidated")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if result, ok := cache.Get("server", "GetUserProfile", 1); !ok || result != 2 {
+		t.Fatalf("Expected revalidated result 2, got %v", result)
+	}
+
+	cache.Invalidate("server", "GetUserProfile", 1)
+	if _, ok := cache.Get("server", "GetUserProfile", 1); ok {
+		t.Fatal("Expected entry to be invalidated")
+	}
+
+	if result := call(grpc.WithoutCache(context.Background())); result != 3 {
+		t.Fatalf("Expected uncached result 3, got %v", result)
+	}
+}
@@ -1,8 +1,12 @@
 package test
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,6 +14,11 @@ import (
 	"time"
 
 	"github.com/Nu11ified/golem/internal/functions"
+	pb "github.com/Nu11ified/golem/proto/gen/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"nhooyr.io/websocket"
 )
 
 // Server functions to demonstrate the functionality
@@ -218,6 +227,666 @@ func TestGRPCFunctionCalling(t *testing.T) {
 	})
 }
 
+// TestStreamingFunctionCall verifies emitted partial results reach the client as SSE
+func TestStreamingFunctionCall(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	countdown := func(ctx context.Context, from int) (string, error) {
+		for i := from; i > 0; i-- {
+			if err := functions.Emit(ctx, i); err != nil {
+				return "", err
+			}
+		}
+		return "liftoff", nil
+	}
+
+	if err := registry.RegisterFunction("server", "Countdown", countdown); err != nil {
+		t.Fatalf("Failed to register Countdown function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.StreamHandler()))
+	defer server.Close()
+
+	body := `{"serviceName":"server","functionName":"Countdown","args":[3]}`
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read stream: %v", err)
+	}
+
+	expected := "event: message\ndata: 3\n\n" +
+		"event: message\ndata: 2\n\n" +
+		"event: message\ndata: 1\n\n" +
+		"event: result\ndata: \"liftoff\"\n\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected stream:\n%s", data)
+	}
+
+	// Emit outside a stream reports ErrNotStreaming
+	if err := functions.Emit(context.Background(), 1); err != functions.ErrNotStreaming {
+		t.Errorf("Expected ErrNotStreaming, got %v", err)
+	}
+}
+
+// TestChannelFunctionStream verifies functions returning a channel stream its
+// values, or return them as an array when called without streaming
+func TestChannelFunctionStream(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	stopped := make(chan struct{})
+	ticks := func(ctx context.Context, n int) (<-chan int, error) {
+		values := make(chan int)
+		go func() {
+			defer close(values)
+			for i := 1; n <= 0 || i <= n; i++ {
+				select {
+				case values <- i:
+				case <-ctx.Done():
+					close(stopped)
+					return
+				}
+			}
+		}()
+		return values, nil
+	}
+	if err := registry.RegisterFunction("server", "Ticks", ticks); err != nil {
+		t.Fatalf("Failed to register Ticks function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.StreamHandler()))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"serviceName":"server","functionName":"Ticks","args":[2]}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	expected := "event: message\ndata: 1\n\n" +
+		"event: message\ndata: 2\n\n" +
+		"event: result\ndata: null\n\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected stream:\n%s", data)
+	}
+
+	result, err := registry.CallFunction(context.Background(), "server", "Ticks", []*anypb.Any{{Value: []byte("3")}})
+	if err != nil || string(result.GetValue()) != "[1,2,3]" {
+		t.Errorf("Expected collected values, got %s (%v)", result.GetValue(), err)
+	}
+
+	// Cancelling the call stops an endless producer
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	_, err = registry.CallFunctionStream(ctx, "server", "Ticks", []*anypb.Any{{Value: []byte("0")}}, func(*anypb.Any) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancellation, got %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("Expected the producer to stop after cancellation")
+	}
+}
+
+// TestBatchFunctionCalls verifies several calls can share one request
+func TestBatchFunctionCalls(t *testing.T) {
+	registry := functions.NewRegistry()
+	if err := registry.RegisterFunction("server", "Hello", Hello); err != nil {
+		t.Fatalf("Failed to register Hello function: %v", err)
+	}
+	if err := registry.RegisterFunction("math", "Calculate", Calculate); err != nil {
+		t.Fatalf("Failed to register Calculate function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.BatchHandler()))
+	defer server.Close()
+
+	for _, parallel := range []bool{false, true} {
+		body := fmt.Sprintf(`{"parallel":%t,"calls":[
+			{"serviceName":"server","functionName":"Hello","args":["Batch"]},
+			{"serviceName":"math","functionName":"Calculate","args":[1,0,"divide"]},
+			{"serviceName":"math","functionName":"Calculate","args":[6,7,"multiply"]}
+		]}`, parallel)
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var batch struct {
+			Success bool                   `json:"success"`
+			Result  []FunctionCallResponse `json:"result"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		if !batch.Success || len(batch.Result) != 3 {
+			t.Fatalf("Unexpected batch response: %+v", batch)
+		}
+		if !batch.Result[0].Success || batch.Result[0].Result != "Hello, Batch! This message is from the Go server via gRPC." {
+			t.Errorf("Unexpected first result: %+v", batch.Result[0])
+		}
+		if batch.Result[1].Success || !strings.Contains(batch.Result[1].Error, "division by zero") {
+			t.Errorf("Expected division error, got %+v", batch.Result[1])
+		}
+		if !batch.Result[2].Success || batch.Result[2].Result != float64(42) {
+			t.Errorf("Unexpected third result: %+v", batch.Result[2])
+		}
+	}
+}
+
+// TestStructuredErrors verifies errors reach the client with code and details
+func TestStructuredErrors(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	findUser := func(id int) (map[string]interface{}, error) {
+		return nil, functions.Errorf(functions.CodeNotFound, "user %d not found", id).WithDetail("id", id)
+	}
+	if err := registry.RegisterFunction("server", "FindUser", findUser); err != nil {
+		t.Fatalf("Failed to register FindUser function: %v", err)
+	}
+	if err := registry.RegisterFunction("math", "Calculate", Calculate); err != nil {
+		t.Fatalf("Failed to register Calculate function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.HTTPHandler()))
+	defer server.Close()
+
+	tests := []struct {
+		body    string
+		status  int
+		code    string
+		message string
+	}{
+		{`{"serviceName":"server","functionName":"FindUser","args":[7]}`, http.StatusNotFound, functions.CodeNotFound, "user 7 not found"},
+		{`{"serviceName":"math","functionName":"Calculate","args":[1,0,"divide"]}`, http.StatusInternalServerError, functions.CodeUnknown, "division by zero"},
+		{`{"serviceName":"math","functionName":"Missing","args":[]}`, http.StatusNotFound, functions.CodeNotFound, "function math.Missing not found"},
+		{`{"serviceName":"math","functionName":"Calculate","args":[1]}`, http.StatusBadRequest, functions.CodeInvalidArgument, "failed to convert arguments: expected 3 arguments, got 1"},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var envelope struct {
+			Success   bool                   `json:"success"`
+			Error     string                 `json:"error"`
+			Code      string                 `json:"code"`
+			Details   map[string]interface{} `json:"details"`
+			Retryable bool                   `json:"retryable"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&envelope)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		if resp.StatusCode != tt.status || envelope.Code != tt.code || envelope.Error != tt.message || envelope.Success {
+			t.Errorf("Expected %d %s %q, got %d %+v", tt.status, tt.code, tt.message, resp.StatusCode, envelope)
+		}
+		if tt.code == functions.CodeNotFound && strings.Contains(tt.body, "FindUser") && envelope.Details["id"] != float64(7) {
+			t.Errorf("Expected id detail, got %v", envelope.Details)
+		}
+	}
+}
+
+// TestDeadlinePropagation verifies the client timeout header bounds server work
+func TestDeadlinePropagation(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	stopped := make(chan error, 1)
+	slow := func(ctx context.Context) (string, error) {
+		select {
+		case <-ctx.Done():
+			stopped <- ctx.Err()
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "done", nil
+		}
+	}
+	if err := registry.RegisterFunction("server", "Slow", slow); err != nil {
+		t.Fatalf("Failed to register Slow function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.HTTPHandler()))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader(`{"serviceName":"server","functionName":"Slow","args":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(functions.TimeoutHeader, "50")
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Deadline was not applied, call took %v", elapsed)
+	}
+
+	select {
+	case err := <-stopped:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected DeadlineExceeded in function, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Function did not observe the deadline")
+	}
+}
+
+// TestRequestMetadata verifies headers and cookies reach server functions
+func TestRequestMetadata(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	whoAmI := func(ctx context.Context) map[string]string {
+		md := functions.MetadataFromContext(ctx)
+		session, _ := md.Cookie("session")
+		return map[string]string{
+			"token":   md.BearerToken(),
+			"session": session,
+			"tenant":  md.Get("X-Tenant"),
+		}
+	}
+	if err := registry.RegisterFunction("server", "WhoAmI", whoAmI); err != nil {
+		t.Fatalf("Failed to register WhoAmI function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.HTTPHandler()))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader(`{"serviceName":"server","functionName":"WhoAmI","args":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Tenant", "acme")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc123"})
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response FunctionCallResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	result, _ := response.Result.(map[string]interface{})
+	if result["token"] != "secret-token" || result["session"] != "abc123" || result["tenant"] != "acme" {
+		t.Errorf("Unexpected metadata: %+v", response)
+	}
+}
+
+// TestRequestContext verifies functions can read the HTTP request they serve
+func TestRequestContext(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	audit := func(ctx context.Context) map[string]interface{} {
+		req, ok := functions.RequestFromContext(ctx)
+		if !ok {
+			return nil
+		}
+		return map[string]interface{}{
+			"transport": req.Transport,
+			"remoteIP":  req.RemoteIP(),
+			"clientIP":  req.ClientIP(),
+			"userAgent": req.UserAgent,
+			"languages": req.Languages(),
+		}
+	}
+	if err := registry.RegisterFunction("server", "Audit", audit); err != nil {
+		t.Fatalf("Failed to register Audit function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.HTTPHandler()))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader(`{"serviceName":"server","functionName":"Audit","args":[]}`))
+	req.Header.Set("User-Agent", "golem-test")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	req.Header.Set("Accept-Language", "de;q=0.5, fr-CH, en;q=0.8")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response FunctionCallResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	result, _ := response.Result.(map[string]interface{})
+	if result["transport"] != "http" || result["remoteIP"] != "127.0.0.1" || result["clientIP"] != "203.0.113.7" ||
+		result["userAgent"] != "golem-test" || fmt.Sprint(result["languages"]) != "[fr-CH en de]" {
+		t.Errorf("Unexpected request info: %+v", response)
+	}
+
+	// Direct registry calls carry no request
+	direct, err := registry.CallFunction(context.Background(), "server", "Audit", nil)
+	if err != nil || string(direct.GetValue()) != "null" {
+		t.Errorf("Expected no request for direct calls, got %s (%v)", direct.GetValue(), err)
+	}
+}
+
+// TestBidiStreamingFunction verifies a channel-based function can be driven over WebSocket
+func TestBidiStreamingFunction(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	echo := func(ctx context.Context, in <-chan string, prefix string) (<-chan string, error) {
+		out := make(chan string)
+		go func() {
+			defer close(out)
+			for msg := range in {
+				out <- prefix + msg
+			}
+		}()
+		return out, nil
+	}
+
+	if err := registry.RegisterFunction("chat", "Echo", echo); err != nil {
+		t.Fatalf("Failed to register Echo function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.WebSocketHandler()))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	send := func(msg string) {
+		if err := conn.Write(ctx, websocket.MessageText, []byte(msg)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	receive := func() map[string]interface{} {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("Failed to decode: %v", err)
+		}
+		return msg
+	}
+
+	send(`{"type":"call","serviceName":"chat","functionName":"Echo","args":["> "]}`)
+	send(`{"type":"message","data":"hello"}`)
+	if msg := receive(); msg["data"] != "> hello" {
+		t.Errorf("Expected echoed message, got %v", msg)
+	}
+
+	send(`{"type":"message","data":"again"}`)
+	if msg := receive(); msg["data"] != "> again" {
+		t.Errorf("Expected echoed message, got %v", msg)
+	}
+
+	send(`{"type":"close"}`)
+	if msg := receive(); msg["type"] != "end" {
+		t.Errorf("Expected end message, got %v", msg)
+	}
+}
+
+// TestMultiplexedSocket verifies calls share one socket, are matched to
+// their results by ID, carry per-call metadata and can push to the client
+func TestMultiplexedSocket(t *testing.T) {
+	registry := functions.NewRegistry()
+	if err := registry.RegisterFunction("server", "Hello", Hello); err != nil {
+		t.Fatalf("Failed to register Hello function: %v", err)
+	}
+
+	notify := func(ctx context.Context, topic string) (string, error) {
+		pusher, ok := functions.PusherFromContext(ctx)
+		if !ok {
+			return "", errors.New("no pusher")
+		}
+		if err := pusher.Push(topic, map[string]string{"user": functions.MetadataFromContext(ctx).Get("X-User")}); err != nil {
+			return "", err
+		}
+		return "sent", nil
+	}
+	if err := registry.RegisterFunction("server", "Notify", notify); err != nil {
+		t.Fatalf("Failed to register Notify function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.MuxHandler()))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	calls := []string{
+		`{"id":1,"type":"call","serviceName":"server","functionName":"Hello","args":["Mux"]}`,
+		`{"id":2,"type":"call","serviceName":"server","functionName":"Missing"}`,
+		`{"id":3,"type":"call","serviceName":"server","functionName":"Notify","args":["alerts"],"metadata":{"X-User":"ada"}}`,
+	}
+	for _, call := range calls {
+		if err := conn.Write(ctx, websocket.MessageText, []byte(call)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+
+	replies := make(map[string]map[string]interface{})
+	for len(replies) < 4 {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("Failed to decode: %v", err)
+		}
+		if msg["type"] == "push" {
+			replies["push"] = msg
+		} else {
+			replies[fmt.Sprint(msg["id"])] = msg
+		}
+	}
+
+	if reply := replies["1"]; reply["type"] != "result" || !strings.Contains(fmt.Sprint(reply["result"]), "Hello, Mux!") {
+		t.Errorf("Expected Hello result, got %v", reply)
+	}
+	if reply := replies["2"]; reply["type"] != "error" || reply["code"] == "" {
+		t.Errorf("Expected structured error, got %v", reply)
+	}
+	if reply := replies["3"]; reply["result"] != "sent" {
+		t.Errorf("Expected Notify result, got %v", reply)
+	}
+	push := replies["push"]
+	if push["topic"] != "alerts" || push["result"].(map[string]interface{})["user"] != "ada" {
+		t.Errorf("Expected pushed message with call metadata, got %v", push)
+	}
+}
+
+// TestProtobufPayloads verifies the binary payload mode carries bytes and
+// protobuf messages without JSON, and still answers JSON clients
+func TestProtobufPayloads(t *testing.T) {
+	registry := functions.NewRegistry()
+	reverse := func(data []byte) []byte {
+		reversed := make([]byte, len(data))
+		for i, b := range data {
+			reversed[len(data)-1-i] = b
+		}
+		return reversed
+	}
+	describe := func(info *pb.FunctionInfo, suffix string) string {
+		return info.Name + suffix
+	}
+	if err := registry.RegisterFunction("files", "Reverse", reverse); err != nil {
+		t.Fatalf("Failed to register Reverse function: %v", err)
+	}
+	if err := registry.RegisterFunction("files", "Describe", describe); err != nil {
+		t.Fatalf("Failed to register Describe function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	server := httptest.NewServer(http.HandlerFunc(grpcServer.HTTPHandler()))
+	defer server.Close()
+
+	call := func(accept string, req *pb.FunctionRequest) (*http.Response, []byte) {
+		body, err := proto.Marshal(req)
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+		httpReq, _ := http.NewRequest("POST", server.URL, bytes.NewReader(body))
+		httpReq.Header.Set("Content-Type", functions.ProtobufContentType)
+		if accept != "" {
+			httpReq.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(httpReq)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, data
+	}
+
+	payload, _ := anypb.New(wrapperspb.Bytes([]byte{1, 2, 3}))
+	resp, data := call("", &pb.FunctionRequest{ServiceName: "files", FunctionName: "Reverse", Args: []*anypb.Any{payload}})
+	if resp.Header.Get("Content-Type") != functions.ProtobufContentType {
+		t.Fatalf("Expected protobuf response, got %s", resp.Header.Get("Content-Type"))
+	}
+	var response pb.FunctionResponse
+	if err := proto.Unmarshal(data, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	var reversed wrapperspb.BytesValue
+	if err := response.Result.UnmarshalTo(&reversed); err != nil || !bytes.Equal(reversed.Value, []byte{3, 2, 1}) {
+		t.Errorf("Expected raw reversed bytes, got %v (%v)", reversed.Value, err)
+	}
+
+	message, _ := anypb.New(&pb.FunctionInfo{Name: "Hello"})
+	resp, data = call("application/json", &pb.FunctionRequest{ServiceName: "files", FunctionName: "Describe", Args: []*anypb.Any{message, {Value: []byte(`"!"`)}}})
+	var jsonResponse map[string]interface{}
+	if err := json.Unmarshal(data, &jsonResponse); err != nil || jsonResponse["result"] != "Hello!" {
+		t.Errorf("Expected JSON result for JSON client, got %s (%v)", data, err)
+	}
+
+	resp, data = call("", &pb.FunctionRequest{ServiceName: "files", FunctionName: "Missing"})
+	response.Reset()
+	if err := proto.Unmarshal(data, &response); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if response.Success || response.Metadata["code"] != functions.CodeNotFound || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected structured not found error, got %d %v", resp.StatusCode, &response)
+	}
+}
+
+// TestOpenAPIDocument verifies functions are documented with their argument
+// and result schemas and callable at their documented paths
+func TestOpenAPIDocument(t *testing.T) {
+	registry := functions.NewRegistry()
+	signup := func(ctx context.Context, form signupForm) (map[string]string, error) {
+		return map[string]string{"email": form.Email}, nil
+	}
+	if err := registry.RegisterFunction("accounts", "Signup", signup, functions.WithDescription("Signup creates an account"), functions.RequireAuthenticated()); err != nil {
+		t.Fatalf("Failed to register Signup function: %v", err)
+	}
+	if err := registry.RegisterFunction("server", "Hello", Hello); err != nil {
+		t.Fatalf("Failed to register Hello function: %v", err)
+	}
+
+	grpcServer := functions.NewGRPCServer(registry)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/functions/", grpcServer.HTTPHandler())
+	mux.HandleFunc("/api/openapi.json", grpcServer.OpenAPIHandler("test-app", "1.0.0"))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatalf("Failed to fetch document: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var doc struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	signupOp := doc.Paths["/api/functions/accounts/Signup"]["post"]
+	if doc.OpenAPI != "3.1.0" || signupOp == nil || signupOp["description"] != "Signup creates an account" || signupOp["security"] == nil {
+		t.Fatalf("Unexpected Signup operation: %+v", signupOp)
+	}
+	if responses, _ := signupOp["responses"].(map[string]interface{}); responses["401"] == nil || responses["400"] == nil {
+		t.Errorf("Expected error responses, got %+v", responses)
+	}
+
+	form := doc.Components.Schemas["test.signupForm"]
+	properties, _ := form["properties"].(map[string]interface{})
+	email, _ := properties["email"].(map[string]interface{})
+	password, _ := properties["password"].(map[string]interface{})
+	if email["format"] != "email" || password["minLength"] != float64(8) || fmt.Sprint(form["required"]) != "[email password]" {
+		t.Errorf("Unexpected form schema: %+v", form)
+	}
+
+	call, err := http.Post(server.URL+"/api/functions/server/Hello", "application/json", strings.NewReader(`["Ada"]`))
+	if err != nil {
+		t.Fatalf("Failed to call documented path: %v", err)
+	}
+	defer call.Body.Close()
+
+	var response FunctionCallResponse
+	if err := json.NewDecoder(call.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Success || !strings.Contains(fmt.Sprint(response.Result), "Hello, Ada!") {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
 // FunctionCallResponse represents the response from a function call
 type FunctionCallResponse struct {
 	Success bool        `json:"success"`
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// TestGRPCReflection verifies the reflection service is exposed when enabled
+// in the config so tools like grpcurl can introspect the server
+func TestGRPCReflection(t *testing.T) {
+	grpcServer, err := server.NewGRPCServer(functions.NewRegistry(), config.GRPCConfig{
+		Reflection:     true,
+		MaxRecvMsgSize: 1 << 20,
+		Keepalive:      &config.GRPCKeepalive{Time: "1m", MinTime: "10s"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create gRPC server: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open reflection stream: %v", err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to list services: %v", err)
+	}
+
+	found := false
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		found = found || service.GetName() == "golem.functions.FunctionService"
+	}
+	if !found {
+		t.Errorf("Expected FunctionService to be listed, got %v", resp.GetListServicesResponse().GetService())
+	}
+
+	if _, err := server.NewGRPCServer(functions.NewRegistry(), config.GRPCConfig{Keepalive: &config.GRPCKeepalive{Time: "soon"}}); err == nil {
+		t.Error("Expected an invalid keepalive duration to be rejected")
+	}
+}
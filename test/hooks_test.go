@@ -0,0 +1,49 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/build"
+	"github.com/Nu11ified/golem/internal/config"
+)
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+	output := t.TempDir()
+	cfg := &config.Config{Output: output}
+	cfg.Build.Hooks = map[string]config.BuildHooks{
+		"public": {
+			Pre:  []config.BuildHook{{Run: `echo "$GOLEM_HOOK_STAGE $GOLEM_HOOK_PHASE" > "$GOLEM_OUTPUT/icons.txt"`}},
+			Post: []config.BuildHook{{Run: "exit 3"}},
+		},
+	}
+
+	if err := build.RunHooks(cfg, "public", "pre"); err != nil {
+		t.Fatalf("pre hook failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(output, "icons.txt"))
+	if err != nil || strings.TrimSpace(string(data)) != "pre public" {
+		t.Errorf("hook wrote %q (%v), want \"pre public\"", data, err)
+	}
+
+	if err := build.RunHooks(cfg, "public", "post"); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("failing hook returned %v", err)
+	}
+	if err := build.RunHooks(cfg, "compress", "pre"); err != nil {
+		t.Errorf("phase without hooks returned %v", err)
+	}
+}
+
+func TestBuildRejectsUnknownHookPhase(t *testing.T) {
+	cfg := &config.Config{Output: t.TempDir()}
+	cfg.Build.Hooks = map[string]config.BuildHooks{"assets": {Pre: []config.BuildHook{{Run: "true"}}}}
+	if err := build.NewBuilder(cfg).Build(); err == nil || !strings.Contains(err.Error(), `unknown build hook phase "assets"`) {
+		t.Errorf("Build() = %v, want an unknown phase error", err)
+	}
+}
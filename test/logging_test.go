@@ -0,0 +1,117 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestRequestID verifies the request ID reaches server functions and comes
+// back in the response, whether the client sent one or not
+func TestRequestID(t *testing.T) {
+	registry := functions.NewRegistry()
+	whoami := func(ctx context.Context) (string, error) {
+		return functions.RequestIDFromContext(ctx), nil
+	}
+	if err := registry.RegisterFunction("server", "RequestID", whoami); err != nil {
+		t.Fatalf("Failed to register RequestID function: %v", err)
+	}
+	ts := httptest.NewServer(server.RequestID(http.HandlerFunc(functions.NewGRPCServer(registry).HTTPHandler())))
+	defer ts.Close()
+
+	for _, sent := range []string{"lb-7f3a:42", "", "bad id; drop", strings.Repeat("a", 129)} {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"serviceName":"server","functionName":"RequestID","args":[]}`))
+		req.Header.Set("Content-Type", "application/json")
+		if sent != "" {
+			req.Header.Set(functions.RequestIDHeader, sent)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var envelope struct {
+			Result string `json:"result"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&envelope)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		id := resp.Header.Get(functions.RequestIDHeader)
+		if functions.ValidRequestID(sent) && id != sent {
+			t.Errorf("%q: expected the client's ID back, got %q", sent, id)
+		}
+		if !functions.ValidRequestID(sent) && (len(id) != 32 || id == sent) {
+			t.Errorf("%q: expected a generated ID, got %q", sent, id)
+		}
+		if envelope.Result != id {
+			t.Errorf("%q: function saw request ID %q, response has %q", sent, envelope.Result, id)
+		}
+	}
+}
+
+// TestLogRequests verifies requests are logged as JSON with their request ID
+func TestLogRequests(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := server.NewLogger(config.LogConfig{Format: server.LogFormatJSON}, &logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.RequestID(server.LogRequests(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "short and stout")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/pot?brew=1", nil)
+	req.Header.Set(functions.RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("Expected one JSON record, got %q: %v", logs.String(), err)
+	}
+	want := map[string]interface{}{
+		"level":      "INFO",
+		"msg":        "http request",
+		"request_id": "req-1",
+		"method":     "GET",
+		"path":       "/pot",
+		"status":     float64(http.StatusTeapot),
+		"bytes":      float64(len("short and stout")),
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s: expected %v, got %v", key, value, record[key])
+		}
+	}
+}
+
+// TestNewLogger verifies server.log is validated
+func TestNewLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := server.NewLogger(config.LogConfig{Level: "warn"}, &logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown", "key", "value")
+	if out := logs.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "level=WARN msg=shown key=value") {
+		t.Errorf("Unexpected text log %q", out)
+	}
+
+	for _, cfg := range []config.LogConfig{{Format: "xml"}, {Level: "loud"}} {
+		if _, err := server.NewLogger(cfg, &logs); err == nil {
+			t.Errorf("%+v was accepted", cfg)
+		}
+	}
+}
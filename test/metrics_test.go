@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestMetrics verifies requests and function calls show up at /metrics,
+// labeled by route pattern and function
+func TestMetrics(t *testing.T) {
+	registry := functions.NewRegistry()
+	if err := registry.RegisterFunction("math", "Add", func(a, b int) (int, error) { return a + b, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterFunction("math", "Fail", func() (int, error) { return 0, functions.NewError(functions.CodeInternal, "failed") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterFunction("math", "Unused", func() (int, error) { return 0, nil }); err != nil {
+		t.Fatal(err)
+	}
+	registry.CallFunction(context.Background(), "math", "Fail", nil)
+
+	metrics := server.NewMetrics(registry, &config.Config{ProjectName: "shop", Version: "1.2.0"})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) })
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "OK") })
+	mux.Handle(server.MetricsPath, metrics)
+	ts := httptest.NewServer(metrics.Instrument(mux))
+	defer ts.Close()
+
+	for _, path := range []string{"/health", "/health", "/users/42", "/users/43"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(ts.URL + server.MetricsPath)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	for _, want := range []string{
+		"# TYPE golem_http_requests_total counter",
+		`golem_http_requests_total{method="GET",route="/health",status="200"} 2`,
+		`golem_http_requests_total{method="GET",route="/",status="404"} 2`,
+		`golem_http_request_duration_seconds_bucket{method="GET",route="/health",status="200",le="+Inf"} 2`,
+		`golem_http_request_duration_seconds_count{method="GET",route="/",status="404"} 2`,
+		`golem_function_calls_total{function="math.Fail"} 1`,
+		`golem_function_errors_total{function="math.Fail"} 1`,
+		`golem_function_calls_total{function="math.Unused"} 0`,
+		`golem_function_duration_seconds_bucket{function="math.Fail",le="10"} 1`,
+		`golem_function_duration_seconds_count{function="math.Add"} 0`,
+		"golem_websocket_connections 0",
+		`golem_build_info{app="shop",version="1.2.0",`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Metrics lack %s:\n%s", want, body)
+		}
+	}
+}
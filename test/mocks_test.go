@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/dev"
+	"github.com/Nu11ified/golem/internal/functions"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// mockRegistry returns a registry with users.Get and users.List answering
+// "real", whose calls go through the fixtures written to a temporary
+// directory
+func mockRegistry(t *testing.T, fixtures map[string]string) *functions.Registry {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range fixtures {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry := functions.NewRegistry()
+	registry.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	registry.Use(dev.MockInterceptor(dir))
+	if err := registry.RegisterFunction("users", "Get", func(name string) string { return "real" }); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterFunction("users", "List", func() string { return "real" }); err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+// TestMockInterceptor verifies calls are answered by the first fixture
+// matching their arguments, and go on to the function when none does
+func TestMockInterceptor(t *testing.T) {
+	registry := mockRegistry(t, map[string]string{
+		"users.Get.json": `[
+			{ "args": ["admin"], "error": { "code": "permission_denied", "message": "Not allowed" } },
+			{ "args": [{ "name": "*" }], "result": "any name" },
+			{ "args": ["alice"], "result": "fixture", "delay": "20ms" }
+		]`,
+	})
+
+	tests := []struct {
+		name      string
+		function  string
+		arg       string
+		want      string
+		wantCode  string
+		wantDelay time.Duration
+	}{
+		{"matching fixture", "Get", `"alice"`, `"fixture"`, "", 20 * time.Millisecond},
+		{"error fixture", "Get", `"admin"`, "", functions.CodePermissionDenied, 0},
+		{"object with wildcard", "Get", `{"name":"bob","age":3}`, `"any name"`, "", 0},
+		{"no matching fixture", "Get", `"bob"`, `"real"`, "", 0},
+		{"no fixture file", "List", "", `"real"`, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []*anypb.Any
+			if tt.arg != "" {
+				args = []*anypb.Any{{Value: []byte(tt.arg)}}
+			}
+			start := time.Now()
+			result, err := registry.CallFunction(context.Background(), "users", tt.function, args)
+			elapsed := time.Since(start)
+
+			if tt.wantCode != "" {
+				if structured := functions.ToError(err); err == nil || structured.Code != tt.wantCode {
+					t.Fatalf("Expected a %s error, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := string(result.GetValue()); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			if elapsed < tt.wantDelay {
+				t.Errorf("Expected a delay of at least %v, took %v", tt.wantDelay, elapsed)
+			}
+		})
+	}
+}
+
+// TestMockInterceptorFixtureFile verifies a file holding one fixture answers
+// every call, and an invalid file fails the calls of its function
+func TestMockInterceptorFixtureFile(t *testing.T) {
+	registry := mockRegistry(t, map[string]string{
+		"users.List.json": `{ "result": ["ada","alan"] }`,
+		"users.Get.json":  `[{ "args": ["ada"] `,
+	})
+
+	result, err := registry.CallFunction(context.Background(), "users", "List", nil)
+	if err != nil || string(result.GetValue()) != `["ada","alan"]` {
+		t.Errorf("Expected the fixture result, got %s, %v", result.GetValue(), err)
+	}
+
+	_, err = registry.CallFunction(context.Background(), "users", "Get", []*anypb.Any{{Value: []byte(`"ada"`)}})
+	if structured := functions.ToError(err); err == nil || structured.Code != functions.CodeInternal {
+		t.Errorf("Expected an internal error for the invalid fixture, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Nu11ified/golem/grpc"
+)
+
+// offlineServer records the calls replayed to it, with their idempotency
+// keys, and answers them with fail when set
+type offlineServer struct {
+	calls []string
+	keys  []string
+	fail  func(call string) error
+}
+
+func (s *offlineServer) invoke(ctx context.Context, info *grpc.CallInfo) (interface{}, error) {
+	call := info.FunctionName
+	s.calls = append(s.calls, call)
+	s.keys = append(s.keys, grpc.IdempotencyKey(ctx))
+	if s.fail != nil {
+		if err := s.fail(call); err != nil {
+			return nil, err
+		}
+	}
+	return call + " done", nil
+}
+
+// newOfflineQueue returns a queue replaying to server, online as online says
+func newOfflineQueue(server *offlineServer, online *atomic.Bool, options grpc.OfflineOptions) *grpc.OfflineQueue {
+	options.Online = online.Load
+	return grpc.NewOfflineQueue(server.invoke, options)
+}
+
+// queueCall makes a call through the queue's interceptor, as a client does
+func queueCall(ctx context.Context, q *grpc.OfflineQueue, server *offlineServer, function string) (interface{}, error) {
+	return q.Interceptor(ctx, &grpc.CallInfo{ServiceName: "todos", FunctionName: function}, server.invoke)
+}
+
+// TestOfflineQueueing verifies mutations made offline are queued in order
+// while other calls go through
+func TestOfflineQueueing(t *testing.T) {
+	server := &offlineServer{}
+	var online atomic.Bool
+	q := newOfflineQueue(server, &online, grpc.OfflineOptions{})
+
+	ctx := context.Background()
+	for _, function := range []string{"Add", "Rename", "Delete"} {
+		if _, err := queueCall(ctx, q, server, function); !errors.Is(err, grpc.ErrQueued) {
+			t.Fatalf("Expected %s to be queued offline, got %v", function, err)
+		}
+	}
+	if result, err := queueCall(grpc.WithIdempotent(ctx), q, server, "List"); err != nil || result != "List done" {
+		t.Errorf("Expected an idempotent call to go through, got %v, %v", result, err)
+	}
+
+	var pending []string
+	for _, call := range q.Pending() {
+		pending = append(pending, call.FunctionName)
+		if call.IdempotencyKey == "" {
+			t.Errorf("Expected %s to be queued with an idempotency key", call.FunctionName)
+		}
+	}
+	if len(pending) != 3 || pending[0] != "Add" || pending[1] != "Rename" || pending[2] != "Delete" {
+		t.Errorf("Expected Add, Rename, Delete queued in order, got %v", pending)
+	}
+
+	q.Clear()
+	if len(q.Pending()) != 0 {
+		t.Error("Expected Clear to drop the queued calls")
+	}
+}
+
+// TestOfflineReplay verifies queued calls replay in order with the
+// idempotency key they were queued with, also when a replay is repeated
+// after its response was lost
+func TestOfflineReplay(t *testing.T) {
+	lost := true
+	server := &offlineServer{fail: func(call string) error {
+		if call == "Rename" && lost {
+			lost = false
+			return &grpc.TransportError{Message: "connection reset"}
+		}
+		return nil
+	}}
+	var online atomic.Bool
+	var replayed []interface{}
+	q := newOfflineQueue(server, &online, grpc.OfflineOptions{
+		OnReplay: func(call grpc.QueuedCall, result interface{}) { replayed = append(replayed, result) },
+	})
+
+	ctx := grpc.WithIdempotencyKey(context.Background(), "add-key")
+	queueCall(ctx, q, server, "Add")
+	queueCall(context.Background(), q, server, "Rename")
+	renameKey := q.Pending()[1].IdempotencyKey
+
+	online.Store(true)
+	if err := q.Replay(context.Background()); err == nil {
+		t.Fatal("Expected the replay to stop at the lost response")
+	}
+	if pending := q.Pending(); len(pending) != 1 || pending[0].FunctionName != "Rename" {
+		t.Fatalf("Expected Rename to stay queued, got %v", pending)
+	}
+	if err := q.Replay(context.Background()); err != nil {
+		t.Fatalf("Expected the second replay to succeed, got %v", err)
+	}
+
+	wantCalls := []string{"Add", "Rename", "Rename"}
+	wantKeys := []string{"add-key", renameKey, renameKey}
+	for i := range wantCalls {
+		if i >= len(server.calls) || server.calls[i] != wantCalls[i] || server.keys[i] != wantKeys[i] {
+			t.Fatalf("Expected calls %v with keys %v, got %v with %v", wantCalls, wantKeys, server.calls, server.keys)
+		}
+	}
+	if len(replayed) != 2 || replayed[0] != "Add done" || replayed[1] != "Rename done" {
+		t.Errorf("Expected OnReplay with both results, got %v", replayed)
+	}
+	if len(q.Pending()) != 0 {
+		t.Errorf("Expected an empty queue, got %v", q.Pending())
+	}
+}
+
+// TestOfflineConflicts verifies rejected replays are dropped or kept at the
+// head of the queue as OnConflict decides
+func TestOfflineConflicts(t *testing.T) {
+	rejected := errors.New("version conflict")
+	tests := []struct {
+		name        string
+		resolution  grpc.ConflictResolution
+		wantCalls   int
+		wantPending []string
+	}{
+		{"discard", grpc.ConflictDiscard, 3, nil},
+		{"retry", grpc.ConflictRetry, 2, []string{"Rename", "Delete"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &offlineServer{fail: func(call string) error {
+				if call == "Rename" {
+					return rejected
+				}
+				return nil
+			}}
+			var online atomic.Bool
+			var conflicts []error
+			q := newOfflineQueue(server, &online, grpc.OfflineOptions{
+				OnConflict: func(call grpc.QueuedCall, err error) grpc.ConflictResolution {
+					conflicts = append(conflicts, err)
+					return tt.resolution
+				},
+			})
+			for _, function := range []string{"Add", "Rename", "Delete"} {
+				queueCall(context.Background(), q, server, function)
+			}
+
+			online.Store(true)
+			err := q.Replay(context.Background())
+			if (tt.resolution == grpc.ConflictRetry) != (err != nil) {
+				t.Errorf("Unexpected replay error %v", err)
+			}
+			if len(conflicts) != 1 || !errors.Is(conflicts[0], rejected) {
+				t.Errorf("Expected OnConflict once with the rejection, got %v", conflicts)
+			}
+			if len(server.calls) != tt.wantCalls {
+				t.Errorf("Expected %d calls replayed, got %v", tt.wantCalls, server.calls)
+			}
+			var pending []string
+			for _, call := range q.Pending() {
+				pending = append(pending, call.FunctionName)
+			}
+			if len(pending) != len(tt.wantPending) || (len(pending) > 0 && pending[0] != tt.wantPending[0]) {
+				t.Errorf("Expected %v still queued, got %v", tt.wantPending, pending)
+			}
+		})
+	}
+}
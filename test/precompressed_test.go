@@ -0,0 +1,78 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestPrecompressed verifies the precompressed variant matching the
+// client's Accept-Encoding is served with the original content type
+func TestPrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.wasm":        "wasm",
+		"app.wasm.gz":     "gzip bytes",
+		"app.wasm.br":     "brotli bytes",
+		"index.html":      "<html>app</html>",
+		"index.html.gz":   "gzip html",
+		"wasm_exec.js":    "js",
+		"robots.txt":      "robots",
+		"images/logo.svg": "<svg/>",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	handler := server.Precompressed(dir, http.FileServer(http.Dir(dir)))
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+		wantBody       string
+		wantType       string
+	}{
+		{"/app.wasm", "gzip, deflate, br", "br", "brotli bytes", "application/wasm"},
+		{"/app.wasm", "gzip", "gzip", "gzip bytes", "application/wasm"},
+		{"/app.wasm", "br;q=0, gzip;q=0.8", "gzip", "gzip bytes", "application/wasm"},
+		{"/app.wasm", "*;q=0", "", "wasm", "application/wasm"},
+		{"/app.wasm", "", "", "wasm", "application/wasm"},
+		{"/", "gzip", "gzip", "gzip html", "text/html; charset=utf-8"},
+		{"/wasm_exec.js", "gzip, br", "", "js", ""},
+		{"/images/logo.svg", "gzip, br", "", "<svg/>", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, got)
+			}
+			if tt.wantType != "" && rec.Header().Get("Content-Type") != tt.wantType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.wantType, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
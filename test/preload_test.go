@@ -0,0 +1,97 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestPreloadFS verifies page responses carry the preload tags of their
+// app's page as Link headers, resolved against its <base>
+func TestPreloadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`<html><head><base href="/">
+    <link rel="preload" href="app.1a2b3c4d.wasm" as="fetch" type="application/wasm" integrity="sha384-abc" crossorigin="anonymous">
+    <link rel="preload" href="wasm_exec.5e6f7a8b.js" as="script" integrity="sha384-def" crossorigin="anonymous">
+    <link rel="manifest" href="manifest.webmanifest">
+</head></html>`)},
+		"admin/index.html": {Data: []byte(`<html><head>
+    <link rel="preload" href="../admin.9c0d1e2f.wasm" as="fetch" type="application/wasm" crossorigin>
+</head></html>`)},
+	}
+	cfg := &config.Config{Apps: map[string]config.AppConfig{"admin": {}}}
+	handler := server.PreloadFS(fsys, cfg, false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	mainLinks := []string{
+		`</app.1a2b3c4d.wasm>; rel=preload; as=fetch; type="application/wasm"; crossorigin; integrity="sha384-abc"`,
+		`</wasm_exec.5e6f7a8b.js>; rel=preload; as=script; crossorigin; integrity="sha384-def"`,
+	}
+	tests := []struct {
+		method string
+		path   string
+		want   []string
+	}{
+		{http.MethodGet, "/", mainLinks},
+		{http.MethodGet, "/users/42", mainLinks},
+		{http.MethodHead, "/index.html", mainLinks},
+		{http.MethodGet, "/admin/settings", []string{`</admin.9c0d1e2f.wasm>; rel=preload; as=fetch; type="application/wasm"; crossorigin`}},
+		{http.MethodGet, "/app.1a2b3c4d.wasm", nil},
+		{http.MethodGet, "/api/functions/list", nil},
+		{http.MethodPost, "/", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			got := rec.Header().Values("Link")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected Link headers %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestEarlyHints verifies page requests get a 103 Early Hints response with
+// the Link headers before the page
+func TestEarlyHints(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`<link rel="preload" href="app.wasm" as="fetch" crossorigin>`)},
+	}
+	ts := httptest.NewServer(server.PreloadFS(fsys, &config.Config{}, true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	})))
+	defer ts.Close()
+
+	var hints []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				hints = append(hints, header.Values("Link")...)
+			}
+			return nil
+		},
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/", nil)
+	resp, err := http.DefaultClient.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	want := "</app.wasm>; rel=preload; as=fetch; crossorigin"
+	if len(hints) != 1 || hints[0] != want {
+		t.Errorf("Expected an early hint of %q, got %q", want, hints)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Link") != want {
+		t.Errorf("Expected the page with its Link header, got %d %q", resp.StatusCode, resp.Header.Get("Link"))
+	}
+}
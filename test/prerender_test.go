@@ -0,0 +1,45 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/Nu11ified/golem/dom"
+	"github.com/Nu11ified/golem/router"
+)
+
+// TestPrerender verifies every route URL renders to static markup
+func TestPrerender(t *testing.T) {
+	r := router.NewRouter().SetMode(router.HistoryMode)
+	r.AddSimpleRoute("/", func(params map[string]string) *dom.Element {
+		return dom.Div(dom.Class("home"), dom.H1("Fish & Chips"), dom.Input(dom.Disabled(true)))
+	})
+	r.AddSimpleRoute("/posts/:slug", func(params map[string]string) *dom.Element {
+		return dom.P(dom.Text("Post " + params["slug"]))
+	})
+	r.AddRoute(&router.Route{Path: "/old", Redirect: "/"})
+	r.AddSimpleRoute("/files/*", func(params map[string]string) *dom.Element { return dom.Div() })
+
+	pages, err := r.Prerender(router.SitemapOptions{
+		Enumerators: map[string]router.ParamEnumerator{
+			"/posts/:slug": func() ([]map[string]string, error) {
+				return []map[string]string{{"slug": "hello"}}, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to prerender: %v", err)
+	}
+
+	expected := map[string]string{
+		"/":            `<div class="home"><h1>Fish &amp; Chips</h1><input disabled></div>`,
+		"/posts/hello": `<p>Post hello</p>`,
+	}
+	if len(pages) != len(expected) {
+		t.Fatalf("Expected %d pages, got %v", len(expected), pages)
+	}
+	for path, want := range expected {
+		if pages[path] != want {
+			t.Errorf("Expected %s to render %q, got %q", path, want, pages[path])
+		}
+	}
+}
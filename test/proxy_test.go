@@ -0,0 +1,90 @@
+package test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestProxy verifies server.proxy forwards the requests under a prefix to
+// their upstream, and only those
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" prefix="+r.Header.Get("X-Forwarded-Prefix"))
+	}))
+	defer upstream.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "static") })
+	mux.HandleFunc("/api/functions/", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "functions") })
+	err := server.RegisterProxies(mux, []config.ProxyRule{
+		{Path: "/auth/", Target: upstream.URL, StripPrefix: true},
+		{Path: "/api", Target: upstream.URL + "/v1"},
+		{Path: "/down/", Target: closed.URL},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.CacheControlFS(fstest.MapFS{}, config.CacheConfig{}, mux))
+	defer ts.Close()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/auth/login?next=%2F", http.StatusOK, "GET /login?next=%2F prefix=/auth"},
+		{"/auth", http.StatusOK, "GET / prefix=/auth"},
+		{"/api/users/42", http.StatusOK, "GET /v1/api/users/42 prefix="},
+		{"/api/functions/math/Add", http.StatusOK, "functions"},
+		{"/authors", http.StatusOK, "static"},
+		{"/down/page", http.StatusBadGateway, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if tt.wantBody != "" && string(body) != tt.wantBody {
+				t.Errorf("Expected %q, got %q", tt.wantBody, body)
+			}
+		})
+	}
+
+	resp, err := http.Get(ts.URL + "/auth/login")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if values := resp.Header.Values("Cache-Control"); len(values) != 1 || values[0] != "private, max-age=60" {
+		t.Errorf("Expected the Cache-Control of the upstream, got %q", values)
+	}
+
+	for _, rule := range []config.ProxyRule{
+		{Path: "auth", Target: upstream.URL},
+		{Path: "/", Target: upstream.URL},
+		{Path: "/api/functions/", Target: upstream.URL},
+		{Path: "/healthz", Target: upstream.URL},
+		{Path: "/auth/", Target: "localhost:9000"},
+		{Path: "/auth/", Target: upstream.URL, Timeout: "soon"},
+	} {
+		if err := server.RegisterProxies(http.NewServeMux(), []config.ProxyRule{rule}, slog.Default()); err == nil {
+			t.Errorf("Expected %+v to be rejected", rule)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+	"nhooyr.io/websocket"
+)
+
+// TestRateLimit verifies each client IP may only make rateLimit calls to
+// /api/functions, and gets 429 with Retry-After beyond them
+func TestRateLimit(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler, err := server.RateLimit(config.RateLimitConfig{Requests: 2, Window: "1h", TrustProxy: true}, functions.NewRegistry(), next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(path, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "10.0.0.1:4321"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := call("/api/functions/math/Add", "203.0.113.7"); rec.Code != http.StatusOK {
+			t.Fatalf("Call %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	rec := call("/api/functions/math/Add", "203.0.113.7")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 beyond the limit, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+	if !strings.Contains(rec.Body.String(), `"resource_exhausted"`) {
+		t.Errorf("Expected a resource_exhausted error, got %s", rec.Body.String())
+	}
+
+	// The proxy appends the client's address, earlier entries can be forged
+	if rec := call("/api/functions/math/Add", "198.51.100.1, 203.0.113.7"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a forged X-Forwarded-For entry to be ignored, got %d", rec.Code)
+	}
+	if rec := call("/api/functions/math/Add", "203.0.113.8"); rec.Code != http.StatusOK {
+		t.Errorf("Expected another client to have its own limit, got %d", rec.Code)
+	}
+	if rec := call("/app.wasm", "203.0.113.7"); rec.Code != http.StatusOK {
+		t.Errorf("Expected paths outside /api/functions to be unlimited, got %d", rec.Code)
+	}
+
+	if _, err := server.RateLimit(config.RateLimitConfig{Requests: 1, Window: "soon"}, functions.NewRegistry(), next); err == nil {
+		t.Error("Expected an invalid window to be rejected")
+	}
+}
+
+// rateLimitedAPI serves the function endpoints of a registry with Echo
+// registered, limited to requests calls per hour
+func rateLimitedAPI(t *testing.T, requests int) *httptest.Server {
+	t.Helper()
+	registry := functions.NewRegistry()
+	if err := registry.RegisterFunction("api", "Echo", func(s string) string { return s }); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterFunction("api", "Chat", func(in <-chan string) <-chan string { return in }); err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := functions.NewGRPCServer(registry)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/functions/batch", grpcServer.BatchHandler())
+	mux.HandleFunc("/api/functions/mux", grpcServer.MuxHandler())
+	mux.HandleFunc("/api/functions/ws", grpcServer.WebSocketHandler())
+	mux.HandleFunc("/api/functions/", grpcServer.HTTPHandler())
+
+	handler, err := server.RateLimit(config.RateLimitConfig{Requests: requests, Window: "1h"}, registry, mux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := httptest.NewServer(handler)
+	t.Cleanup(api.Close)
+	return api
+}
+
+// TestRateLimitBatch verifies each call of a batch is charged, so a batch
+// cannot run more calls than the limit allows
+func TestRateLimitBatch(t *testing.T) {
+	api := rateLimitedAPI(t, 3)
+
+	body := `{"calls":[` + strings.Repeat(`{"serviceName":"api","functionName":"Echo","args":["hi"]},`, 4) +
+		`{"serviceName":"api","functionName":"Echo","args":["hi"]}]}`
+	resp, err := http.Post(api.URL+"/api/functions/batch", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var batch struct {
+		Result []struct {
+			Success bool   `json:"success"`
+			Code    string `json:"code"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Result) != 5 {
+		t.Fatalf("Expected 5 results, got %d", len(batch.Result))
+	}
+	for i, result := range batch.Result {
+		if i < 3 && !result.Success {
+			t.Errorf("Call %d: expected it within the limit, got %s", i+1, result.Code)
+		}
+		if i >= 3 && result.Code != functions.CodeResourceExhausted {
+			t.Errorf("Call %d: expected resource_exhausted beyond the limit, got success=%v code=%q", i+1, result.Success, result.Code)
+		}
+	}
+
+	// The batch used up the limit of single calls too
+	resp, err = http.Post(api.URL+"/api/functions/api/Echo", "application/json", strings.NewReader(`["hi"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after the batch, got %d", resp.StatusCode)
+	}
+}
+
+// TestRateLimitMux verifies each call made on a mux socket is charged,
+// while the socket stays open for calls once the limit refills
+func TestRateLimitMux(t *testing.T) {
+	api := rateLimitedAPI(t, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(api.URL, "http")+"/api/functions/mux", nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	codes := make(map[string]string)
+	for id := 1; id <= 3; id++ {
+		call := fmt.Sprintf(`{"id":%d,"type":"call","serviceName":"api","functionName":"Echo","args":["hi"]}`, id)
+		if err := conn.Write(ctx, websocket.MessageText, []byte(call)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		var reply struct {
+			ID   int    `json:"id"`
+			Type string `json:"type"`
+			Code string `json:"code"`
+		}
+		if err := json.Unmarshal(data, &reply); err != nil {
+			t.Fatalf("Failed to decode: %v", err)
+		}
+		codes[fmt.Sprint(reply.ID)] = reply.Type + " " + reply.Code
+	}
+
+	if codes["1"] != "result " || codes["2"] != "result " {
+		t.Errorf("Expected the calls within the limit to succeed, got %v", codes)
+	}
+	if codes["3"] != "error "+functions.CodeResourceExhausted {
+		t.Errorf("Expected the call beyond the limit to fail with resource_exhausted, got %q", codes["3"])
+	}
+}
+
+// TestRateLimitWebSocket verifies the messages sent to a bidirectional
+// function are charged like calls, ending the socket beyond the limit
+func TestRateLimitWebSocket(t *testing.T) {
+	api := rateLimitedAPI(t, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(api.URL, "http")+"/api/functions/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	for _, msg := range []string{
+		`{"type":"call","serviceName":"api","functionName":"Chat"}`,
+		`{"type":"message","data":"one"}`,
+	} {
+		if err := conn.Write(ctx, websocket.MessageText, []byte(msg)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+
+	var replies []string
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			if websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+				t.Errorf("Expected the socket to close with a policy violation, got %v", err)
+			}
+			break
+		}
+		replies = append(replies, string(data))
+	}
+	// The call took the only token, so its first message is over the limit
+	if len(replies) != 1 || !strings.Contains(replies[0], "rate limit exceeded") {
+		t.Errorf("Expected the message to be rejected, got %v", replies)
+	}
+}
+
+// TestLimitBodySize verifies /api/ bodies over maxBodySize get 413, while
+// smaller ones still reach the handler
+func TestLimitBodySize(t *testing.T) {
+	handler := server.LimitBodySize(8, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{"small body", "/api/functions/math/Add", "[1,2]", false, http.StatusOK},
+		{"body too large", "/api/functions/math/Add", "[1,2,3,4,5]", false, http.StatusRequestEntityTooLarge},
+		{"chunked body too large", "/api/functions/math/Add", "[1,2,3,4,5]", true, http.StatusRequestEntityTooLarge},
+		{"outside /api/", "/upload", "[1,2,3,4,5]", false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != tt.body {
+				t.Errorf("Expected the handler to read %q, got %q", tt.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestRateLimitRestart verifies a server started twice charges each call
+// and message once, against one limit for both starts
+func TestRateLimitRestart(t *testing.T) {
+	if err := functions.RegisterGlobalFunction("restart", "Echo", func(s string) string { return s }); err != nil {
+		t.Fatal(err)
+	}
+	if err := functions.RegisterGlobalFunction("restart", "Chat", func(in <-chan string) <-chan string { return in }); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(server.PortEnv, "")
+	cfg := &config.Config{Output: t.TempDir()}
+	cfg.Server.RateLimit = config.RateLimitConfig{Requests: 4, Window: "1h"}
+	srv := server.NewServer(cfg)
+	defer srv.Shutdown(context.Background())
+
+	start := func() string {
+		cfg.Server.Host, cfg.Server.Port, cfg.Server.GRPC.Port = "127.0.0.1", freePort(t), freePort(t)
+		base := "http://127.0.0.1:" + strconv.Itoa(cfg.Server.Port)
+		go srv.Start()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			resp, err := http.Get(base + "/health")
+			if err == nil {
+				resp.Body.Close()
+				return base
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("server did not start: %v", err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	first := start()
+	second := start()
+
+	// The call and its message take two tokens
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(second, "http")+"/api/functions/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	for _, msg := range []string{
+		`{"type":"call","serviceName":"restart","functionName":"Chat"}`,
+		`{"type":"message","data":"one"}`,
+	} {
+		if err := conn.Write(ctx, websocket.MessageText, []byte(msg)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	if _, data, err := conn.Read(ctx); err != nil || strings.Contains(string(data), "rate limit exceeded") {
+		t.Fatalf("Expected the message within the limit to be echoed, got %s, %v", data, err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	call := func(base string) int {
+		resp, err := http.Post(base+"/api/functions/restart/Echo", "application/json", strings.NewReader(`["hi"]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for i, base := range []string{first, second} {
+		if code := call(base); code != http.StatusOK {
+			t.Fatalf("Call %d: expected 200 within the limit, got %d", i+1, code)
+		}
+	}
+	if code := call(first); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once both starts used up the limit, got %d", code)
+	}
+}
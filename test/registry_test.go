@@ -0,0 +1,595 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/functions"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// TestCallFunctionCancellation verifies cancelled calls return promptly and
+// the function observes ctx.Done()
+func TestCallFunctionCancellation(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	observed := make(chan struct{})
+	slow := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		close(observed)
+		return "", ctx.Err()
+	}
+	if err := registry.RegisterFunction("server", "Slow", slow); err != nil {
+		t.Fatalf("Failed to register Slow function: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := registry.CallFunction(ctx, "server", "Slow", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+
+	select {
+	case <-observed:
+	case <-time.After(time.Second):
+		t.Fatal("Function did not observe cancellation")
+	}
+}
+
+// TestCallFunctionPanic verifies a panicking function is reported as an error
+func TestCallFunctionPanic(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	boom := func() string { panic("boom") }
+	if err := registry.RegisterFunction("server", "Boom", boom); err != nil {
+		t.Fatalf("Failed to register Boom function: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var logs bytes.Buffer
+	registry.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	if _, err := registry.CallFunction(ctx, "server", "Boom", nil); err == nil {
+		t.Fatal("Expected panic to be reported as an error")
+	} else if functions.ToError(err).Code != functions.CodeInternal || strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected an internal error hiding the panic value, got %v", err)
+	}
+
+	if stats := registry.Stats()["server.Boom"]; stats.Calls != 1 || stats.Errors != 1 || stats.Panics != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if !strings.Contains(logs.String(), "panic=boom") || !strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("Expected panic value and stack in logs, got %s", logs.String())
+	}
+}
+
+type denyKey struct{}
+
+// TestRegistryInterceptors verifies interceptors wrap calls in order and can
+// reject them before the function runs
+func TestRegistryInterceptors(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	called := false
+	hello := func() string {
+		called = true
+		return "hello"
+	}
+	if err := registry.RegisterFunction("server", "Hello", hello); err != nil {
+		t.Fatalf("Failed to register Hello function: %v", err)
+	}
+
+	var order []string
+	registry.Use(
+		func(ctx context.Context, info *functions.CallInfo, next functions.Handler) (*anypb.Any, error) {
+			order = append(order, "outer:"+info.ServiceName+"."+info.FunctionName)
+			return next(ctx, info)
+		},
+		func(ctx context.Context, info *functions.CallInfo, next functions.Handler) (*anypb.Any, error) {
+			order = append(order, "inner")
+			if ctx.Value(denyKey{}) != nil {
+				return nil, functions.NewError(functions.CodePermissionDenied, "denied")
+			}
+			return next(ctx, info)
+		},
+	)
+
+	result, err := registry.CallFunction(context.Background(), "server", "Hello", nil)
+	if err != nil || string(result.GetValue()) != `"hello"` {
+		t.Fatalf("Unexpected result %v, %v", result, err)
+	}
+	if len(order) != 2 || order[0] != "outer:server.Hello" || order[1] != "inner" {
+		t.Errorf("Unexpected interceptor order: %v", order)
+	}
+
+	called = false
+	_, err = registry.CallFunction(context.WithValue(context.Background(), denyKey{}, true), "server", "Hello", nil)
+	if functions.ToError(err).Code != functions.CodePermissionDenied || called {
+		t.Errorf("Expected call to be rejected before running, got %v", err)
+	}
+}
+
+// TestFunctionAuthorization verifies declared roles are enforced using the
+// identity from the context or the registry's resolver
+func TestFunctionAuthorization(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	deleteUser := func(ctx context.Context, id int) string { return "deleted" }
+	if err := registry.RegisterFunction("admin", "DeleteUser", deleteUser, functions.RequireRoles("admin")); err != nil {
+		t.Fatalf("Failed to register DeleteUser function: %v", err)
+	}
+
+	// Source discovery picks up //golem:auth directives
+	dir := t.TempDir()
+	source := "package reports\n\n// Export exports reports\n//golem:auth permission=reports.export\nfunc Export() string { return \"ok\" }\n"
+	if err := os.WriteFile(filepath.Join(dir, "reports.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.DiscoverFunctions(dir); err != nil {
+		t.Fatalf("Failed to discover functions: %v", err)
+	}
+	if err := registry.RegisterFunction("reports", "Export", func() string { return "ok" }); err != nil {
+		t.Fatalf("Failed to register Export function: %v", err)
+	}
+	if meta, _ := registry.GetFunction("reports", "Export"); meta.Auth == nil || meta.Description != "Export exports reports" {
+		t.Fatalf("Expected directive to be discovered, got %+v", meta)
+	}
+
+	registry.SetIdentityResolver(func(ctx context.Context, md *functions.Metadata) (*functions.Identity, error) {
+		if md.BearerToken() == "editor-token" {
+			return &functions.Identity{Subject: "ed", Roles: []string{"editor"}, Permissions: []string{"reports.export"}}, nil
+		}
+		return nil, nil
+	})
+
+	args := []*anypb.Any{{Value: []byte("1")}}
+	admin := functions.WithIdentity(context.Background(), &functions.Identity{Subject: "root", Roles: []string{"admin"}})
+	editor := functions.WithMetadata(context.Background(), functions.NewMetadata(http.Header{"Authorization": {"Bearer editor-token"}}))
+
+	tests := []struct {
+		ctx      context.Context
+		function string
+		args     []*anypb.Any
+		code     string
+	}{
+		{context.Background(), "DeleteUser", args, functions.CodeUnauthenticated},
+		{editor, "DeleteUser", args, functions.CodePermissionDenied},
+		{admin, "DeleteUser", args, ""},
+		{admin, "Export", nil, functions.CodePermissionDenied},
+		{editor, "Export", nil, ""},
+	}
+	for _, tt := range tests {
+		service := "admin"
+		if tt.function == "Export" {
+			service = "reports"
+		}
+		_, err := registry.CallFunction(tt.ctx, service, tt.function, tt.args)
+		if tt.code == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.function, err)
+		}
+		if tt.code != "" && (err == nil || functions.ToError(err).Code != tt.code) {
+			t.Errorf("%s: expected %s, got %v", tt.function, tt.code, err)
+		}
+	}
+}
+
+// TestStructParameters verifies arguments decode into exact parameter types
+func TestStructParameters(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	type Address struct {
+		City string `json:"city"`
+	}
+	type User struct {
+		Name      string    `json:"name"`
+		Age       int       `json:"age"`
+		Address   *Address  `json:"address"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	describe := func(owner *User, members []User, since time.Time) string {
+		return fmt.Sprintf("%s/%s/%d/%s/%d", owner.Name, owner.Address.City, len(members), members[1].Name, since.Year())
+	}
+	if err := registry.RegisterFunction("server", "Describe", describe); err != nil {
+		t.Fatalf("Failed to register Describe function: %v", err)
+	}
+
+	arg := func(raw string) *anypb.Any { return &anypb.Any{Value: []byte(raw)} }
+
+	result, err := registry.CallFunction(context.Background(), "server", "Describe", []*anypb.Any{
+		arg(`{"name":"Ada","age":36,"address":{"city":"London"},"createdAt":"2024-01-02T03:04:05Z"}`),
+		arg(`[{"name":"Bob"},{"name":"Cy"}]`),
+		arg(`1700000000000`),
+	})
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if got := string(result.GetValue()); got != `"Ada/London/2/Cy/2023"` {
+		t.Errorf("Unexpected result %s", got)
+	}
+
+	_, err = registry.CallFunction(context.Background(), "server", "Describe", []*anypb.Any{
+		arg(`{"name":"Ada","age":"old"}`), arg(`[]`), arg(`"2024-01-02T03:04:05Z"`),
+	})
+	if err == nil || !strings.Contains(err.Error(), `field "age"`) {
+		t.Errorf("Expected a field error, got %v", err)
+	}
+}
+
+type signupForm struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Password string   `json:"password" validate:"required,min=8"`
+	Plan     string   `json:"plan" validate:"oneof=free pro"`
+	Tags     []string `json:"tags" validate:"max=2"`
+}
+
+// TestArgumentValidation verifies invalid arguments are rejected before the
+// function runs, with one detail per field
+func TestArgumentValidation(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	called := false
+	signup := func(form signupForm) string {
+		called = true
+		return form.Email
+	}
+	if err := registry.RegisterFunction("server", "Signup", signup); err != nil {
+		t.Fatalf("Failed to register Signup function: %v", err)
+	}
+
+	valid := `{"email":"ada@example.com","password":"correct horse","plan":"pro"}`
+	if _, err := registry.CallFunction(context.Background(), "server", "Signup", []*anypb.Any{{Value: []byte(valid)}}); err != nil || !called {
+		t.Fatalf("Expected valid call to succeed, got %v", err)
+	}
+
+	called = false
+	invalid := `{"email":"nope","password":"short","plan":"gold","tags":["a","b","c"]}`
+	_, err := registry.CallFunction(context.Background(), "server", "Signup", []*anypb.Any{{Value: []byte(invalid)}})
+	structured := functions.ToError(err)
+	if structured.Code != functions.CodeInvalidArgument || called {
+		t.Fatalf("Expected invalid_argument before invocation, got %v", err)
+	}
+
+	fields, _ := structured.Details["fields"].(map[string]interface{})
+	for _, field := range []string{"arg0.email", "arg0.password", "arg0.plan", "arg0.tags"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("Expected a problem for %s, got %v", field, fields)
+		}
+	}
+}
+
+// TestFunctionLimits verifies per-function timeouts and concurrency limits
+// fail fast with typed errors
+func TestFunctionLimits(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	busy := func(ctx context.Context) string {
+		started <- struct{}{}
+		<-release
+		return "done"
+	}
+	sleepy := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	if err := registry.RegisterFunction("server", "Busy", busy, functions.WithMaxConcurrency(1)); err != nil {
+		t.Fatalf("Failed to register Busy function: %v", err)
+	}
+	if err := registry.RegisterFunction("server", "Sleepy", sleepy, functions.WithTimeout(time.Hour)); err != nil {
+		t.Fatalf("Failed to register Sleepy function: %v", err)
+	}
+	// Config overrides the registered timeout
+	registry.SetLimits("server", "Sleepy", functions.Limits{Timeout: 20 * time.Millisecond})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := registry.CallFunction(context.Background(), "server", "Busy", nil)
+		done <- err
+	}()
+	<-started
+
+	_, err := registry.CallFunction(context.Background(), "server", "Busy", nil)
+	if structured := functions.ToError(err); structured.Code != functions.CodeResourceExhausted || !structured.Retryable {
+		t.Errorf("Expected retryable resource_exhausted, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("First call failed: %v", err)
+	}
+	if _, err := registry.CallFunction(context.Background(), "server", "Busy", nil); err != nil {
+		t.Errorf("Expected slot to be freed, got %v", err)
+	}
+
+	start := time.Now()
+	_, err = registry.CallFunction(context.Background(), "server", "Sleepy", nil)
+	if functions.ToError(err).Code != functions.CodeDeadlineExceeded || time.Since(start) > time.Second {
+		t.Errorf("Expected the 20ms timeout to apply, got %v after %v", err, time.Since(start))
+	}
+}
+
+// TestFunctionResultCache verifies cached functions run once per arguments
+// until their entry expires or is invalidated
+func TestFunctionResultCache(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	type query struct {
+		Name string `json:"name"`
+		Page int    `json:"page"`
+	}
+
+	runs := 0
+	lookup := func(q query) int {
+		runs++
+		return runs
+	}
+	if err := registry.RegisterFunction("server", "Lookup", lookup, functions.WithCache(time.Hour)); err != nil {
+		t.Fatalf("Failed to register Lookup function: %v", err)
+	}
+
+	call := func(raw string) string {
+		result, err := registry.CallFunction(context.Background(), "server", "Lookup", []*anypb.Any{{Value: []byte(raw)}})
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		return string(result.GetValue())
+	}
+
+	if first, second := call(`{"name":"ada","page":1}`), call(`{ "page": 1, "name": "ada" }`); first != "1" || second != "1" {
+		t.Errorf("Expected cached result, got %s then %s", first, second)
+	}
+	if other := call(`{"name":"bob","page":1}`); other != "2" {
+		t.Errorf("Expected a separate entry per arguments, got %s", other)
+	}
+
+	if err := registry.InvalidateCache("server", "Lookup", query{Name: "ada", Page: 1}); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	if fresh := call(`{"name":"ada","page":1}`); fresh != "3" {
+		t.Errorf("Expected invalidated entry to be recomputed, got %s", fresh)
+	}
+
+	registry.InvalidateFunctionCache("server", "Lookup")
+	if fresh := call(`{"name":"bob","page":1}`); fresh != "4" {
+		t.Errorf("Expected cleared cache to be recomputed, got %s", fresh)
+	}
+}
+
+// TestIntegerParameters verifies JSON numbers decode into integer
+// parameters only when they are whole and in range
+func TestIntegerParameters(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	type Order struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := registry.RegisterFunction("server", "Small", func(n int8) int8 { return n }); err != nil {
+		t.Fatalf("Failed to register Small function: %v", err)
+	}
+	if err := registry.RegisterFunction("server", "Count", func(n uint) uint { return n }); err != nil {
+		t.Fatalf("Failed to register Count function: %v", err)
+	}
+	if err := registry.RegisterFunction("server", "Place", func(order Order) int { return order.Quantity }); err != nil {
+		t.Fatalf("Failed to register Place function: %v", err)
+	}
+
+	tests := []struct {
+		function string
+		arg      string
+		want     string // result, or text of the error
+		wantErr  bool
+	}{
+		{"Small", `12`, `12`, false},
+		{"Small", `1e2`, `100`, false},
+		{"Small", `-128`, `-128`, false},
+		{"Small", `1.5`, "expected int8, got 1.5: not a whole number", true},
+		{"Small", `300`, "expected int8, got 300: out of range", true},
+		{"Count", `1e20`, "expected uint, got 1e+20: out of range", true},
+		{"Count", `-1`, "expected uint, got -1: out of range", true},
+		{"Place", `{"quantity":2}`, `2`, false},
+		{"Place", `{"quantity":1.5}`, `field "quantity"`, true},
+	}
+	for _, tt := range tests {
+		result, err := registry.CallFunction(context.Background(), "server", tt.function, []*anypb.Any{{Value: []byte(tt.arg)}})
+		if !tt.wantErr {
+			if err != nil || string(result.GetValue()) != tt.want {
+				t.Errorf("%s(%s): expected %s, got %s, %v", tt.function, tt.arg, tt.want, result.GetValue(), err)
+			}
+			continue
+		}
+		if functions.ToError(err).Code != functions.CodeInvalidArgument || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s(%s): expected an invalid_argument error containing %q, got %v", tt.function, tt.arg, tt.want, err)
+		}
+	}
+}
+
+// TestIdempotencyKeys verifies repeated keyed calls run the function once
+func TestIdempotencyKeys(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	charges := 0
+	charge := func(amount int) int {
+		charges++
+		return charges
+	}
+	if err := registry.RegisterFunction("server", "Charge", charge); err != nil {
+		t.Fatalf("Failed to register Charge function: %v", err)
+	}
+
+	call := func(key, amount string) string {
+		ctx := context.Background()
+		if key != "" {
+			ctx = functions.WithMetadata(ctx, functions.NewMetadata(http.Header{"Idempotency-Key": {key}}))
+		}
+		result, err := registry.CallFunction(ctx, "server", "Charge", []*anypb.Any{{Value: []byte(amount)}})
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		return string(result.GetValue())
+	}
+
+	if first, retry := call("key-1", "100"), call("key-1", "100"); first != "1" || retry != "1" {
+		t.Errorf("Expected retry to return the first result, got %s then %s", first, retry)
+	}
+	if other := call("key-2", "100"); other != "2" {
+		t.Errorf("Expected a new key to run the function, got %s", other)
+	}
+	if unkeyed := call("", "100"); unkeyed != "3" {
+		t.Errorf("Expected unkeyed call to run the function, got %s", unkeyed)
+	}
+
+	registry.SetIdempotencyWindow(0)
+	if disabled := call("key-1", "100"); disabled != "4" {
+		t.Errorf("Expected disabled window to run the function, got %s", disabled)
+	}
+}
+
+// TestIdempotencyRetryAfterError verifies a keyed call that failed runs
+// again when the client retries it with the same key
+func TestIdempotencyRetryAfterError(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	calls := 0
+	reserve := func(seat int) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, functions.NewError(functions.CodeUnavailable, "busy").WithRetryable(true)
+		}
+		return calls, nil
+	}
+	if err := registry.RegisterFunction("server", "Reserve", reserve); err != nil {
+		t.Fatalf("Failed to register Reserve function: %v", err)
+	}
+
+	ctx := functions.WithMetadata(context.Background(), functions.NewMetadata(http.Header{"Idempotency-Key": {"key-1"}}))
+	args := []*anypb.Any{{Value: []byte("7")}}
+	if _, err := registry.CallFunction(ctx, "server", "Reserve", args); err == nil {
+		t.Fatal("Expected the first call to fail")
+	}
+
+	result, err := registry.CallFunction(ctx, "server", "Reserve", args)
+	if err != nil {
+		t.Fatalf("Expected the retry to run the function again, got %v", err)
+	}
+	if string(result.GetValue()) != "2" || calls != 2 {
+		t.Errorf("Expected the retry's result 2 after 2 calls, got %s after %d", result.GetValue(), calls)
+	}
+
+	if again, err := registry.CallFunction(ctx, "server", "Reserve", args); err != nil || string(again.GetValue()) != "2" || calls != 2 {
+		t.Errorf("Expected the successful result to be remembered, got %s, %v after %d calls", again.GetValue(), err, calls)
+	}
+}
+
+// TestIdempotencyEviction verifies remembered results are forgotten once
+// their window ends or the store is full, the oldest first
+func TestIdempotencyEviction(t *testing.T) {
+	registry := functions.NewRegistry()
+
+	calls := 0
+	count := func() int {
+		calls++
+		return calls
+	}
+	if err := registry.RegisterFunction("server", "Count", count); err != nil {
+		t.Fatalf("Failed to register Count function: %v", err)
+	}
+	call := func(key string) string {
+		ctx := functions.WithMetadata(context.Background(), functions.NewMetadata(http.Header{"Idempotency-Key": {key}}))
+		result, err := registry.CallFunction(ctx, "server", "Count", nil)
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		return string(result.GetValue())
+	}
+
+	registry.SetIdempotencyCapacity(2)
+	call("key-1")
+	call("key-2")
+	call("key-3")
+	if repeat := call("key-3"); repeat != "3" {
+		t.Errorf("Expected the newest result to be remembered, got %s", repeat)
+	}
+	if repeat := call("key-2"); repeat != "2" {
+		t.Errorf("Expected the second result to be remembered, got %s", repeat)
+	}
+	if repeat := call("key-1"); repeat != "4" {
+		t.Errorf("Expected the oldest result to be forgotten, got %s", repeat)
+	}
+
+	registry.SetIdempotencyCapacity(functions.DefaultIdempotencyCapacity)
+	registry.SetIdempotencyWindow(20 * time.Millisecond)
+	first := call("key-5")
+	time.Sleep(30 * time.Millisecond)
+	if expired := call("key-5"); expired == first {
+		t.Errorf("Expected an expired result to run the function again, got %s twice", first)
+	}
+}
+
+// TestGenerateRegistrations verifies registration code is generated for the
+// exported functions of server packages
+func TestGenerateRegistrations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                        "module example.com/app\n",
+		"src/server/users.go":           "package server\n\n// GetUser returns a user\n//golem:auth role=admin\nfunc GetUser(id int) string { return \"\" }\n\nfunc helper() {}\n\ntype Store struct{}\n\nfunc (Store) Save() {}\n",
+		"src/server/billing/billing.go": "package billing\n\nfunc Charge(amount int) error { return nil }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	packages, err := functions.GenerateRegistrations("src/server")
+	if err != nil {
+		t.Fatalf("Failed to generate registrations: %v", err)
+	}
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %+v", packages)
+	}
+
+	generated, err := os.ReadFile(filepath.Join("src/server", functions.GeneratedFile))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	want := `functions.Register("server", "GetUser", GetUser, functions.WithDescription("GetUser returns a user"), functions.RequireRoles("admin"))`
+	if !strings.Contains(string(generated), want) || strings.Contains(string(generated), "helper") || strings.Contains(string(generated), "Save") {
+		t.Errorf("Unexpected registrations:\n%s", generated)
+	}
+
+	host, err := os.ReadFile(functions.HostMainFile)
+	if err != nil {
+		t.Fatalf("Failed to read host main: %v", err)
+	}
+	for _, importPath := range []string{`"example.com/app/src/server"`, `"example.com/app/src/server/billing"`} {
+		if !strings.Contains(string(host), importPath) {
+			t.Errorf("Expected host main to import %s:\n%s", importPath, host)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/router"
+)
+
+// TestRouteManifest verifies the route table expands into a manifest and sitemap
+func TestRouteManifest(t *testing.T) {
+	r := router.NewRouter().SetMode(router.HistoryMode)
+	r.AddSimpleRoute("/", nil)
+	r.RouteWithName("post", "/posts/:slug", nil)
+	r.AddRoute(&router.Route{Path: "/admin", Meta: map[string]interface{}{"sitemap": false}})
+	r.AddRoute(&router.Route{Path: "/old", Redirect: "/"})
+	r.AddSimpleRoute("/files/*", nil)
+
+	opts := router.SitemapOptions{
+		BaseURL: "https://example.com/",
+		Enumerators: map[string]router.ParamEnumerator{
+			"/posts/:slug": func() ([]map[string]string, error) {
+				return []map[string]string{{"slug": "hello"}, {"slug": "world"}, {"slug": "q&a: why/how? #1"}}, nil
+			},
+		},
+	}
+
+	manifest, err := r.Manifest(opts)
+	if err != nil {
+		t.Fatalf("Failed to build manifest: %v", err)
+	}
+
+	if manifest.Mode != "history" {
+		t.Errorf("Expected history mode, got %q", manifest.Mode)
+	}
+	if len(manifest.Routes) != 5 {
+		t.Fatalf("Expected 5 routes, got %d", len(manifest.Routes))
+	}
+
+	post := manifest.Routes[1]
+	if len(post.Params) != 1 || post.Params[0] != "slug" {
+		t.Errorf("Expected params [slug], got %v", post.Params)
+	}
+	if len(post.URLs) != 3 || post.URLs[0] != "/posts/hello" {
+		t.Errorf("Expected expanded post URLs, got %v", post.URLs)
+	}
+	if escaped := post.URLs[2]; escaped != "/posts/q&a:%20why%2Fhow%3F%20%231" {
+		t.Errorf("Expected the param value escaped as one path segment, got %q", escaped)
+	}
+
+	sitemap, err := r.Sitemap(opts)
+	if err != nil {
+		t.Fatalf("Failed to build sitemap: %v", err)
+	}
+
+	xml := string(sitemap)
+	for _, want := range []string{"<loc>https://example.com/</loc>", "<loc>https://example.com/posts/world</loc>", "<loc>https://example.com/posts/q&amp;a:%20why%2Fhow%3F%20%231</loc>"} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Expected sitemap to contain %s", want)
+		}
+	}
+	for _, unwanted := range []string{"/admin", "/old", "/files"} {
+		if strings.Contains(xml, unwanted) {
+			t.Errorf("Expected sitemap to skip %s", unwanted)
+		}
+	}
+
+	if got := r.GenerateURL("post", map[string]string{"slug": "hi"}); got != "/posts/hi" {
+		t.Errorf("Expected /posts/hi, got %q", got)
+	}
+}
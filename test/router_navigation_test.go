@@ -0,0 +1,138 @@
+package test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Nu11ified/golem/router"
+)
+
+// recordMiddleware returns a middleware appending name to calls, then
+// calling next
+func recordMiddleware(calls *[]string, name string) router.Middleware {
+	return func(ctx *router.RouteContext, next func()) {
+		*calls = append(*calls, name)
+		next()
+	}
+}
+
+// TestNavigationMiddleware verifies the chain runs in order and commits at
+// its end, and that a middleware returning without next stops it
+func TestNavigationMiddleware(t *testing.T) {
+	failed := errors.New("render failed")
+	tests := []struct {
+		name       string
+		chain      func(calls *[]string) []router.Middleware
+		commitErr  error
+		wantCalls  []string
+		wantCommit bool
+		wantErr    error
+	}{
+		{"empty chain", func(calls *[]string) []router.Middleware { return nil }, nil, nil, true, nil},
+		{"in order", func(calls *[]string) []router.Middleware {
+			return []router.Middleware{recordMiddleware(calls, "auth"), recordMiddleware(calls, "flags"), recordMiddleware(calls, "route")}
+		}, nil, []string{"auth", "flags", "route"}, true, nil},
+		{"commit error", func(calls *[]string) []router.Middleware {
+			return []router.Middleware{recordMiddleware(calls, "auth")}
+		}, failed, []string{"auth"}, true, failed},
+		{"short-circuit", func(calls *[]string) []router.Middleware {
+			return []router.Middleware{
+				recordMiddleware(calls, "auth"),
+				func(ctx *router.RouteContext, next func()) { *calls = append(*calls, "deny") },
+				recordMiddleware(calls, "route"),
+			}
+		}, nil, []string{"auth", "deny"}, false, router.ErrNavigationIncomplete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var navigations router.Navigations
+			var calls []string
+			committed := false
+			err := navigations.Run(&router.RouteContext{Path: "/"}, tt.chain(&calls), func(late bool) error {
+				committed = true
+				if late {
+					t.Error("Expected a synchronous commit")
+				}
+				return tt.commitErr
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("Expected middleware %v to run, got %v", tt.wantCalls, calls)
+			}
+			if committed != tt.wantCommit {
+				t.Errorf("Expected commit %v, got %v", tt.wantCommit, committed)
+			}
+		})
+	}
+}
+
+// TestNavigationAsyncNext verifies a middleware calling next after Run
+// returned commits the navigation late, once, unless a newer navigation
+// started meanwhile
+func TestNavigationAsyncNext(t *testing.T) {
+	var navigations router.Navigations
+	var pending []func()
+	async := func(ctx *router.RouteContext, next func()) { pending = append(pending, next) }
+
+	var commits []string
+	commit := func(path string) func(late bool) error {
+		return func(late bool) error {
+			if !late {
+				t.Errorf("Expected %s to commit late", path)
+			}
+			commits = append(commits, path)
+			return nil
+		}
+	}
+
+	err := navigations.Run(&router.RouteContext{Path: "/account"}, []router.Middleware{async}, commit("/account"))
+	if !errors.Is(err, router.ErrNavigationIncomplete) {
+		t.Fatalf("Expected ErrNavigationIncomplete while next is pending, got %v", err)
+	}
+	pending[0]()
+	pending[0]()
+	if !reflect.DeepEqual(commits, []string{"/account"}) {
+		t.Errorf("Expected one late commit, got %v", commits)
+	}
+
+	// A navigation superseded by a newer one never commits
+	commits = nil
+	navigations.Run(&router.RouteContext{Path: "/slow"}, []router.Middleware{async}, commit("/slow"))
+	navigations.Run(&router.RouteContext{Path: "/fast"}, []router.Middleware{async}, commit("/fast"))
+	pending[1]()
+	pending[2]()
+	if !reflect.DeepEqual(commits, []string{"/fast"}) {
+		t.Errorf("Expected only the newest navigation to commit, got %v", commits)
+	}
+}
+
+// TestNavigationDeferredFragment verifies the #fragment of a navigation
+// whose chunk is loading is kept for once it renders, unless a newer
+// navigation started meanwhile
+func TestNavigationDeferredFragment(t *testing.T) {
+	var navigations router.Navigations
+	navigate := func(path, fragment string) {
+		navigations.Run(&router.RouteContext{Path: path}, nil, func(late bool) error {
+			navigations.DeferFragment(fragment)
+			return nil
+		})
+	}
+
+	navigate("/docs#install", "install")
+	if fragment := navigations.DeferredFragment(); fragment != "install" {
+		t.Errorf("Expected the deferred fragment install, got %q", fragment)
+	}
+	if fragment := navigations.DeferredFragment(); fragment != "" {
+		t.Errorf("Expected the fragment to be returned once, got %q again", fragment)
+	}
+
+	navigate("/docs#usage", "usage")
+	navigations.Run(&router.RouteContext{Path: "/pricing"}, []router.Middleware{func(ctx *router.RouteContext, next func()) {}}, func(late bool) error { return nil })
+	if fragment := navigations.DeferredFragment(); fragment != "" {
+		t.Errorf("Expected no fragment after a newer navigation started, got %q", fragment)
+	}
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+func TestHTTPAddress(t *testing.T) {
+	cfg := &config.Config{}
+	t.Setenv(server.PortEnv, "")
+	if addr, source, err := server.HTTPAddress(cfg); err != nil || addr != ":8080" || source != "default" {
+		t.Errorf("default: got %q from %q (%v)", addr, source, err)
+	}
+
+	cfg.Server.Host, cfg.Server.Port = "127.0.0.1", 9000
+	if addr, source, err := server.HTTPAddress(cfg); err != nil || addr != "127.0.0.1:9000" || source != "server.port" {
+		t.Errorf("server.port: got %q from %q (%v)", addr, source, err)
+	}
+
+	t.Setenv(server.PortEnv, "3001")
+	if addr, source, err := server.HTTPAddress(cfg); err != nil || addr != "127.0.0.1:3001" || source != server.PortEnv {
+		t.Errorf("PORT: got %q from %q (%v)", addr, source, err)
+	}
+
+	t.Setenv(server.PortEnv, "http")
+	if _, _, err := server.HTTPAddress(cfg); err == nil {
+		t.Error("invalid PORT was accepted")
+	}
+}
+
+// freePort returns a port nothing listens on
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// TestServerShutdown verifies Start returns cleanly once Shutdown drained
+// the servers
+func TestServerShutdown(t *testing.T) {
+	output := t.TempDir()
+	if err := os.WriteFile(filepath.Join(output, "index.html"), []byte("<html>app</html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(server.PortEnv, "")
+	cfg := &config.Config{Output: output}
+	cfg.Server.Host, cfg.Server.Port, cfg.Server.GRPC.Port = "127.0.0.1", freePort(t), freePort(t)
+
+	srv := server.NewServer(cfg)
+	done := make(chan error, 1)
+	go func() { done <- srv.Start() }()
+
+	url := "http://127.0.0.1:" + strconv.Itoa(cfg.Server.Port) + "/"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start() = %v after shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after Shutdown")
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("server still accepts connections after Shutdown")
+	}
+}
+
+// TestProbes verifies /healthz stays up while /readyz reports the failing
+// check of a function dependency
+func TestProbes(t *testing.T) {
+	var databaseDown atomic.Bool
+	databaseDown.Store(true)
+	functions.AddGlobalHealthCheck("probes-test-database", func(ctx context.Context) error {
+		if databaseDown.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	defer functions.AddGlobalHealthCheck("probes-test-database", func(ctx context.Context) error { return nil })
+
+	t.Setenv(server.PortEnv, "")
+	cfg := &config.Config{Output: t.TempDir()}
+	cfg.Server.Host, cfg.Server.Port, cfg.Server.GRPC.Port = "127.0.0.1", freePort(t), freePort(t)
+	srv := server.NewServer(cfg)
+	go srv.Start()
+	defer srv.Shutdown(context.Background())
+
+	type report struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"checks"`
+	}
+	base := "http://127.0.0.1:" + strconv.Itoa(cfg.Server.Port)
+	probe := func(path string) (int, report) {
+		var body report
+		resp, err := http.Get(base + path)
+		if err != nil {
+			return 0, body
+		}
+		defer resp.Body.Close()
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	// Wait for the gRPC server, the last part to come up
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, body := probe(server.ReadinessPath)
+		if body.Checks["grpc"].Status == "ok" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not get ready: %+v", body)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if status, body := probe(server.LivenessPath); status != http.StatusOK || body.Status != "ok" {
+		t.Errorf("liveness: got %d %+v", status, body)
+	}
+	status, body := probe(server.ReadinessPath)
+	if status != http.StatusServiceUnavailable || body.Status != "unavailable" || body.Checks["probes-test-database"].Error != "connection refused" || body.Checks["registry"].Status != "ok" {
+		t.Errorf("readiness with the database down: got %d %+v", status, body)
+	}
+
+	databaseDown.Store(false)
+	if status, body := probe(server.ReadinessPath); status != http.StatusOK || body.Status != "ok" {
+		t.Errorf("readiness with the database up: got %d %+v", status, body)
+	}
+}
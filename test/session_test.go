@@ -0,0 +1,139 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestSessions verifies server functions keep values across calls in the
+// session of a signed cookie, and can renew and destroy it
+func TestSessions(t *testing.T) {
+	registry := functions.NewRegistry()
+	register := func(name string, fn interface{}) {
+		if err := registry.RegisterFunction("auth", name, fn); err != nil {
+			t.Fatalf("Failed to register %s: %v", name, err)
+		}
+	}
+	register("Login", func(ctx context.Context, user string) (bool, error) {
+		session, _ := functions.SessionFromContext(ctx)
+		if err := session.Renew(); err != nil {
+			return false, err
+		}
+		return true, session.Set("user", user)
+	})
+	register("Whoami", func(ctx context.Context) (string, error) {
+		session, _ := functions.SessionFromContext(ctx)
+		var user string
+		_, err := session.Get("user", &user)
+		return user, err
+	})
+	register("Logout", func(ctx context.Context) (bool, error) {
+		session, _ := functions.SessionFromContext(ctx)
+		return true, session.Destroy()
+	})
+
+	for _, store := range []string{"memory", "file"} {
+		t.Run(store, func(t *testing.T) {
+			cfg := config.SessionConfig{Store: store, Dir: t.TempDir(), Secret: strings.Repeat("s", 32)}
+			sessions, err := server.NewSessions(cfg, registry, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/functions/", functions.NewGRPCServer(registry).HTTPHandler())
+			ts := httptest.NewServer(sessions.Middleware(mux))
+			defer ts.Close()
+
+			jar, _ := cookiejar.New(nil)
+			client := &http.Client{Jar: jar}
+			call := func(client *http.Client, function string, args string) (string, *http.Response) {
+				resp, err := client.Post(ts.URL+"/api/functions/auth/"+function, "application/json", strings.NewReader(args))
+				if err != nil {
+					t.Fatalf("Request failed: %v", err)
+				}
+				defer resp.Body.Close()
+				var envelope struct {
+					Result interface{} `json:"result"`
+					Error  string      `json:"error"`
+				}
+				if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if envelope.Error != "" {
+					t.Fatalf("%s failed: %s", function, envelope.Error)
+				}
+				result, _ := envelope.Result.(string)
+				return result, resp
+			}
+
+			if user, resp := call(client, "Whoami", "[]"); user != "" || len(resp.Cookies()) != 0 {
+				t.Errorf("Expected no session before signing in, got %q and %v", user, resp.Cookies())
+			}
+
+			_, resp := call(client, "Login", `["ann"]`)
+			cookies := resp.Cookies()
+			if len(cookies) != 1 || cookies[0].Name != functions.DefaultSessionCookie || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+				t.Fatalf("Expected an HttpOnly, SameSite=Lax session cookie, got %v", cookies)
+			}
+			first := cookies[0].Value
+			if user, _ := call(client, "Whoami", "[]"); user != "ann" {
+				t.Errorf("Expected the session to hold ann, got %q", user)
+			}
+
+			// Signing in again renews the ID, the old one no longer works
+			_, resp = call(client, "Login", `["bob"]`)
+			if len(resp.Cookies()) != 1 || resp.Cookies()[0].Value == first {
+				t.Errorf("Expected a renewed session ID, got %v", resp.Cookies())
+			}
+			stale := &http.Client{Jar: mustJar(t, ts.URL, &http.Cookie{Name: functions.DefaultSessionCookie, Value: first})}
+			if user, _ := call(stale, "Whoami", "[]"); user != "" {
+				t.Errorf("Expected the stale session to be gone, got %q", user)
+			}
+
+			// A forged signature is ignored
+			tampered := []byte(resp.Cookies()[0].Value)
+			tampered[len(tampered)-1] ^= 1
+			forged := &http.Client{Jar: mustJar(t, ts.URL, &http.Cookie{Name: functions.DefaultSessionCookie, Value: string(tampered)})}
+			if user, _ := call(forged, "Whoami", "[]"); user != "" {
+				t.Errorf("Expected a tampered cookie to be ignored, got %q", user)
+			}
+
+			if user, _ := call(client, "Whoami", "[]"); user != "bob" {
+				t.Errorf("Expected the session to hold bob, got %q", user)
+			}
+			_, resp = call(client, "Logout", "[]")
+			if len(resp.Cookies()) != 1 || resp.Cookies()[0].MaxAge >= 0 {
+				t.Errorf("Expected the session cookie to be removed, got %v", resp.Cookies())
+			}
+			if user, _ := call(client, "Whoami", "[]"); user != "" {
+				t.Errorf("Expected no user after signing out, got %q", user)
+			}
+		})
+	}
+
+	if _, err := server.NewSessions(config.SessionConfig{Secret: "short"}, registry, slog.Default()); err == nil {
+		t.Error("Expected a short secret to be rejected")
+	}
+	if _, err := server.NewSessions(config.SessionConfig{Secret: strings.Repeat("s", 32), SameSite: "loose"}, registry, slog.Default()); err == nil {
+		t.Error("Expected an invalid sameSite to be rejected")
+	}
+}
+
+// mustJar returns a cookie jar holding cookie for rawURL
+func mustJar(t *testing.T, rawURL string, cookie *http.Cookie) http.CookieJar {
+	jar, _ := cookiejar.New(nil)
+	req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+	jar.SetCookies(req.URL, []*http.Cookie{cookie})
+	return jar
+}
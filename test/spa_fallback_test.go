@@ -0,0 +1,144 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestSPAFallback verifies history-mode deep links are served index.html
+func TestSPAFallback(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0644); err != nil {
+		t.Fatalf("Failed to write index.html: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.wasm"), []byte("wasm"), 0644); err != nil {
+		t.Fatalf("Failed to write app.wasm: %v", err)
+	}
+
+	handler := server.SPAFallback(dir, http.FileServer(http.Dir(dir)))
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/", http.StatusOK, "<html>app</html>"},
+		{"/users/42", http.StatusOK, "<html>app</html>"},
+		{"/app.wasm", http.StatusOK, "wasm"},
+		{"/missing.js", http.StatusNotFound, ""},
+		{"/img/missing.PNG", http.StatusNotFound, ""},
+		{"/users/john.doe", http.StatusOK, "<html>app</html>"},
+		{"/v1.2", http.StatusOK, "<html>app</html>"},
+		{"/v1.2/docs", http.StatusOK, "<html>app</html>"},
+		{"/api/unknown", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if tt.wantBody != "" && !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("Expected body to contain %q, got %q", tt.wantBody, body)
+			}
+		})
+	}
+}
+
+// TestSPAFallbackApps verifies deep links into an app load that app's page
+func TestSPAFallbackApps(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"index.html":               "<html>app</html>",
+		"console/admin/index.html": "<html>admin</html>",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := &config.Config{Apps: map[string]config.AppConfig{"admin": {Entry: "src/admin/main.go", Path: "console/admin"}}}
+	handler := server.SPAFallback(dir, http.FileServer(http.Dir(dir)), cfg.AppPaths()...)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"/users/42":                 "<html>app</html>",
+		"/console/admin/users/42":   "<html>admin</html>",
+		"/console/administrators/1": "<html>app</html>",
+		"/console/admin/":           "<html>admin</html>",
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != want {
+			t.Errorf("%s: got %d %q, want %q", path, resp.StatusCode, body, want)
+		}
+	}
+}
+
+// TestFallbackFS verifies server.fallback turns the fallback on and off and
+// keeps excluded paths 404
+func TestFallbackFS(t *testing.T) {
+	assets := fstest.MapFS{"index.html": {Data: []byte("<html>app</html>")}}
+	on, off := true, false
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		path     string
+		wantPage bool
+	}{
+		{"history by default", config.Config{Router: config.RouterConfig{Mode: "history"}}, "/users/42", true},
+		{"hash by default", config.Config{Router: config.RouterConfig{Mode: "hash"}}, "/users/42", false},
+		{"enabled with hash", config.Config{Router: config.RouterConfig{Mode: "hash"}, Server: config.ServerConfig{Fallback: config.FallbackConfig{Enabled: &on}}}, "/users/42", true},
+		{"disabled with history", config.Config{Router: config.RouterConfig{Mode: "history"}, Server: config.ServerConfig{Fallback: config.FallbackConfig{Enabled: &off}}}, "/users/42", false},
+		{"api", config.Config{Router: config.RouterConfig{Mode: "history"}}, "/api/missing", false},
+		{"asset", config.Config{Router: config.RouterConfig{Mode: "history"}}, "/missing.js", false},
+		{"excluded prefix", config.Config{Router: config.RouterConfig{Mode: "history"}, Server: config.ServerConfig{Fallback: config.FallbackConfig{Exclude: []string{"/files/"}}}}, "/files/a/b", false},
+		{"excluded prefix itself", config.Config{Router: config.RouterConfig{Mode: "history"}, Server: config.ServerConfig{Fallback: config.FallbackConfig{Exclude: []string{"/files/"}}}}, "/files", false},
+		{"excluded pattern", config.Config{Router: config.RouterConfig{Mode: "history"}, Server: config.ServerConfig{Fallback: config.FallbackConfig{Exclude: []string{"/docs/*"}}}}, "/docs/intro", false},
+		{"outside the exclusions", config.Config{Router: config.RouterConfig{Mode: "history"}, Server: config.ServerConfig{Fallback: config.FallbackConfig{Exclude: []string{"/files/", "/docs/*"}}}}, "/filesystem", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := server.FallbackFS(assets, &tt.cfg, http.FileServerFS(assets))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			gotPage := rec.Code == http.StatusOK && rec.Body.String() == "<html>app</html>"
+			if gotPage != tt.wantPage {
+				t.Errorf("%s: got %d %q, want page %v", tt.path, rec.Code, rec.Body.String(), tt.wantPage)
+			}
+			if !tt.wantPage && rec.Code != http.StatusNotFound {
+				t.Errorf("%s: expected 404, got %d", tt.path, rec.Code)
+			}
+		})
+	}
+}
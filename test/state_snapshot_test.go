@@ -0,0 +1,46 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/Nu11ified/golem/state"
+)
+
+type todoItem struct {
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+// TestStateSnapshots verifies observables and stores restore their snapshot
+// with the types they were created with
+func TestStateSnapshots(t *testing.T) {
+	todos := state.NewObservable([]todoItem{{Title: "write tests"}})
+	snapshot, err := todos.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to snapshot observable: %v", err)
+	}
+
+	restored := state.NewObservable([]todoItem(nil))
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatalf("Failed to restore observable: %v", err)
+	}
+	if items := restored.Get(); len(items) != 1 || items[0].Title != "write tests" {
+		t.Errorf("Expected restored todos, got %+v", items)
+	}
+
+	store := state.NewStore()
+	store.AddReducer("count", nil, 0)
+	store.AddReducer("user", nil, todoItem{})
+	if err := store.Restore([]byte(`{"count": 7, "user": {"title": "ada"}, "removed": true}`)); err != nil {
+		t.Fatalf("Failed to restore store: %v", err)
+	}
+	if count, ok := store.GetState("count").(int); !ok || count != 7 {
+		t.Errorf("Expected count restored as int 7, got %#v", store.GetState("count"))
+	}
+	if user, ok := store.GetState("user").(todoItem); !ok || user.Title != "ada" {
+		t.Errorf("Expected user restored as todoItem, got %#v", store.GetState("user"))
+	}
+	if _, exists := store.GetAllState()["removed"]; exists {
+		t.Errorf("Expected keys the store no longer has to be skipped")
+	}
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Nu11ified/golem/internal/server"
+)
+
+// TestStaticFS verifies the build output is served from an fs.FS, as
+// embedded by single binaries
+func TestStaticFS(t *testing.T) {
+	assets := fstest.MapFS{
+		"index.html":      {Data: []byte("<html>app</html>")},
+		"app.wasm":        {Data: []byte("wasm")},
+		"app.wasm.gz":     {Data: []byte("gzipped wasm")},
+		"docs/index.html": {Data: []byte("<html>docs</html>")},
+	}
+	handler := server.SPAFallbackFS(assets, server.PrecompressedFS(assets, http.FileServerFS(assets)))
+
+	tests := []struct {
+		path     string
+		encoding string
+		wantBody string
+	}{
+		{"/users/42", "", "<html>app</html>"},
+		{"/docs/", "", "<html>docs</html>"},
+		{"/app.wasm", "", "wasm"},
+		{"/app.wasm", "gzip", "gzipped wasm"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.encoding != "" {
+			req.Header.Set("Accept-Encoding", tt.encoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		body, _ := io.ReadAll(rec.Result().Body)
+		if rec.Code != http.StatusOK || string(body) != tt.wantBody {
+			t.Errorf("GET %s (%s): expected 200 %q, got %d %q", tt.path, tt.encoding, tt.wantBody, rec.Code, body)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/config"
+	"github.com/Nu11ified/golem/internal/functions"
+	"github.com/Nu11ified/golem/internal/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestTracing verifies function calls over HTTP are traced in a request
+// span and a call span, continuing the trace of the client's traceparent
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	registry := functions.NewRegistry()
+	if err := registry.RegisterFunction("users", "Get", func(ctx context.Context, id string) (string, error) {
+		if id == "missing" {
+			return "", functions.NewError(functions.CodeNotFound, "no such user")
+		}
+		return "Ada", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/functions/", functions.NewGRPCServer(registry).HTTPHandler())
+	handler := server.Trace(mux)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	call := func(id string) {
+		req := httptest.NewRequest(http.MethodPost, "/api/functions/users/Get", strings.NewReader(`["`+id+`"]`))
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	call("42")
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected a call span and a request span, got %d spans", len(spans))
+	}
+	callSpan, requestSpan := spans[0], spans[1]
+	if requestSpan.Name != "POST /api/functions/" {
+		t.Errorf("Expected the request span to be named after its route, got %q", requestSpan.Name)
+	}
+	if got := requestSpan.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("Expected the request to continue trace %s, got %s", traceID, got)
+	}
+	if got := requestSpan.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Expected the request span's parent to be the client's span, got %s", got)
+	}
+	if callSpan.Name != "users.Get" {
+		t.Errorf("Expected the call span to be named users.Get, got %q", callSpan.Name)
+	}
+	if callSpan.Parent.SpanID() != requestSpan.SpanContext.SpanID() {
+		t.Errorf("Expected the call span to be a child of the request span")
+	}
+	if callSpan.Status.Code == codes.Error {
+		t.Errorf("Expected a successful call span, got status %v", callSpan.Status)
+	}
+
+	exporter.Reset()
+	call("missing")
+	spans = exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected a call span and a request span, got %d spans", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("Expected the failed call span to have an error status, got %v", spans[0].Status)
+	}
+	var code string
+	for _, attr := range spans[0].Attributes {
+		if attr.Key == attribute.Key("golem.error_code") {
+			code = attr.Value.AsString()
+		}
+	}
+	if code != functions.CodeNotFound {
+		t.Errorf("Expected golem.error_code %q, got %q", functions.CodeNotFound, code)
+	}
+}
+
+// TestNewTracerProviderInvalid verifies invalid server.tracing settings are
+// rejected
+func TestNewTracerProviderInvalid(t *testing.T) {
+	ratio := 1.5
+	for _, tracing := range []config.TracingConfig{
+		{Enabled: true, Protocol: "zipkin"},
+		{Enabled: true, SampleRatio: &ratio},
+	} {
+		cfg := &config.Config{Server: config.ServerConfig{Tracing: tracing}}
+		if _, err := server.NewTracerProvider(context.Background(), cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", tracing)
+		}
+	}
+}
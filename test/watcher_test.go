@@ -0,0 +1,98 @@
+package test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Nu11ified/golem/internal/dev"
+)
+
+// TestMatchGlob verifies "**" matches any number of directories, and the
+// other segments match as path.Match does
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/components/button/button.go", true},
+		{"src/**/*.go", "src/main.golem", false},
+		{"src/**/*.go", "functions/api.go", false},
+		{"src/*.go", "src/components/button.go", false},
+		{"public/**/*", "public/img/logo.png", true},
+		{"public/**/*", "public", false},
+		{"**/*.css", "styles.css", true},
+		{"**/*.css", "src/app/styles.css", true},
+		{"src/**", "src", true},
+		{"./src/app.go", "src/app.go", true},
+		{"src/[ab].go", "src/b.go", true},
+		{"src/?.go", "src/ab.go", false},
+	}
+	for _, test := range tests {
+		if got := dev.MatchGlob(test.pattern, test.name); got != test.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+// TestMatchIgnore verifies ignore patterns match as in .gitignore: names
+// anywhere without a slash, paths from the root with one, and directories
+// only with a trailing slash
+func TestMatchIgnore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*_test.go", "src/app_test.go", true},
+		{"*_test.go", "src/app.go", false},
+		{"node_modules/", "node_modules/", true},
+		{"node_modules/", "web/node_modules/", true},
+		{"node_modules/", "web/node_modules/left-pad/index.js", true},
+		{"node_modules/", "node_modules", false},
+		{"*.swp", "src/.main.go.swp", true},
+		{"tmp", "src/tmp/out.go", true},
+		{"tmp", "src/tmp", true},
+		{"src/generated", "src/generated/api.go", true},
+		{"src/generated", "lib/src/generated/api.go", false},
+		{"src/generated/", "src/generated/", true},
+		{"src/generated/", "src/generated", false},
+		{"docs/*.md", "docs/intro.md", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+	}
+	for _, test := range tests {
+		if got := dev.MatchIgnore(test.pattern, test.name); got != test.want {
+			t.Errorf("MatchIgnore(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+// TestWatchRoots verifies the directories watched are the fixed prefixes of
+// the patterns, without those nested in another or plain file paths
+func TestWatchRoots(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{"defaults", []string{"src/**/*.go", "src/**/*.golem"}, []string{"src"}},
+		{"several", []string{"src/**/*.go", "functions/**/*.go", "public/**/*"}, []string{"functions", "public", "src"}},
+		{"nested", []string{"src/**/*.go", "src/components/*.css"}, []string{"src"}},
+		{"wildcard first", []string{"**/*.go", "src/**/*.go"}, []string{"."}},
+		{"plain files", []string{"golem.config.json", "src/index.html"}, nil},
+		{"prefix of a name", []string{"src/**/*.go", "src2/*.go"}, []string{"src", "src2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var want []string
+			for _, root := range test.want {
+				want = append(want, filepath.FromSlash(root))
+			}
+			if got := dev.WatchRoots(test.patterns); !reflect.DeepEqual(got, want) {
+				t.Errorf("WatchRoots(%q) = %q, want %q", test.patterns, got, want)
+			}
+		})
+	}
+}